@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"math/rand"
 	"os"
@@ -89,6 +90,7 @@ func main() {
 
 	// Initialize WebSocket hub
 	hub := ws.NewHub()
+	hub.SetHeartbeat(cfg.WSPingInterval, cfg.WSPongTimeout)
 	go hub.Run()
 
 	// Initialize WhatsApp device pool
@@ -102,14 +104,22 @@ func main() {
 		devicePool.SetStorage(store)
 	}
 
-	// Load existing devices
 	ctx := context.Background()
-	if err := devicePool.LoadExistingDevices(ctx); err != nil {
-		log.Printf("Warning: Failed to load existing devices: %v", err)
-	}
 
 	// Initialize services
 	services := service.NewServices(repos, devicePool, hub)
+	service.SetPasswordPolicy(service.PasswordPolicy{
+		MinLength:      cfg.PasswordMinLength,
+		RequireClasses: cfg.PasswordRequireClasses,
+		RejectCommon:   cfg.PasswordRejectCommon,
+	})
+
+	// Show connected devices as online while at least one agent has the app open
+	hub.OnAccountPresence(func(accountID uuid.UUID, online bool) {
+		presenceCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		services.Chat.SetAccountPresence(presenceCtx, accountID, online)
+	})
 
 	// Initialize Redis cache
 	var redisCache *cache.Cache
@@ -148,6 +158,7 @@ func main() {
 				ClientID:      cfg.KommoClientID,
 				ClientSecret:  cfg.KommoClientSecret,
 				AccessToken:   cfg.KommoAccessToken,
+				RefreshToken:  cfg.KommoRefreshToken,
 				RedirectURI:   cfg.KommoRedirectURI,
 				WebhookSecret: cfg.KommoWebhookSecret,
 			}); err != nil {
@@ -186,6 +197,14 @@ func main() {
 	// Initialize API server
 	server := api.NewServer(cfg, services, repos, hub, devicePool, store, kommoSyncSvc, kommoManager, redisCache, googleClient, Version)
 
+	// Load existing devices only now: NewServer installs the inbound message
+	// hook (auto-tag rules, webhooks), and messages synced while the devices
+	// reconnect must already go through it.
+	if err := devicePool.LoadExistingDevices(ctx); err != nil {
+		log.Printf("Warning: Failed to load existing devices: %v", err)
+	}
+	devicePool.StartHealthMonitor(whatsapp.DeviceHealthCheckInterval)
+
 	// Initialize and start MCP server (Model Context Protocol) for external clients.
 	mcpServer := clarinMCP.New(repos, services, cfg.JWTSecret, Version)
 	mcpServer.Start("8081")
@@ -194,8 +213,11 @@ func main() {
 	eventSyncCtx, eventSyncCancel := context.WithCancel(context.Background())
 	server.StartEventTagSyncWorker(eventSyncCtx)
 	server.StartLeadTrashPurgeWorker(eventSyncCtx)
+	server.StartLeadScoreRecomputeWorker(eventSyncCtx)
 	server.StartErosRunWorker(eventSyncCtx)
 	server.StartLeadIntelligenceReportWorker(eventSyncCtx)
+	server.StartEventRecurrenceWorker(eventSyncCtx)
+	server.StartWebhookRetryWorker(eventSyncCtx)
 
 	// Start task reminder and overdue workers
 	taskCtx, taskCancel := context.WithCancel(context.Background())
@@ -236,6 +258,42 @@ func main() {
 		}
 	}()
 
+	// Start event participant next-action reminder worker
+	if cfg.EventReminderLookahead > 0 {
+		go func() {
+			for {
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							log.Printf("[Event Reminder Worker] ⚠️ PANIC recovered: %v — restarting in 10s", r)
+							select {
+							case <-taskCtx.Done():
+								return
+							case <-time.After(10 * time.Second):
+							}
+						}
+					}()
+
+					log.Printf("⏰ Event reminder worker started (look-ahead %s)", cfg.EventReminderLookahead)
+					ticker := time.NewTicker(time.Minute)
+					defer ticker.Stop()
+					for {
+						select {
+						case <-taskCtx.Done():
+							log.Println("[Event Reminder Worker] Shutting down")
+							return
+						case <-ticker.C:
+							services.Event.ProcessActionReminders(taskCtx, cfg.EventReminderLookahead, cfg.EventReminderCreateInteraction)
+						}
+					}
+				}()
+				if taskCtx.Err() != nil {
+					return
+				}
+			}
+		}()
+	}
+
 	// Recover orphaned campaigns that were running when the process last died.
 	// Mark them as paused so they can be reviewed/restarted manually.
 	go func() {
@@ -265,16 +323,32 @@ func main() {
 
 	// campaignWorker runs in its own goroutine for a single campaign.
 	campaignWorker := func(cCtx context.Context, campaignID uuid.UUID) {
+		// Campaigns sharing a device take turns one batch at a time so their
+		// sends never interleave; heldDevice is the device whose turn we hold.
+		var heldDevice *uuid.UUID
+		releaseDevice := func() {
+			if heldDevice != nil {
+				services.Campaign.ReleaseDeviceTurn(*heldDevice, campaignID)
+				heldDevice = nil
+			}
+		}
 		defer func() {
 			if r := recover(); r != nil {
 				log.Printf("[Campaign %s] ⚠️ PANIC recovered in worker: %v", campaignID, r)
 			}
+			releaseDevice()
 			activeCampaigns.Delete(campaignID)
+			services.Campaign.ForgetBatchProgress(campaignID)
 			log.Printf("[Campaign %s] Worker stopped", campaignID)
 		}()
 
 		log.Printf("[Campaign %s] Worker started", campaignID)
 
+		// A campaign resumed after a pause continues its saved batch position;
+		// it is read once, on the first cycle that runs.
+		resumeChecked := false
+		var resume *service.CampaignResumeState
+
 		for {
 			// Re-fetch campaign to get fresh status and settings each cycle
 			campaigns, err := services.Campaign.GetRunningCampaigns(cCtx)
@@ -305,7 +379,7 @@ func main() {
 					}
 					continue
 				}
-				if err := services.Campaign.Start(cCtx, campaign.ID, nil); err != nil {
+				if err := services.Campaign.StartScheduled(cCtx, campaign.ID); err != nil {
 					log.Printf("[Campaign %s] Failed to auto-start scheduled: %v", campaignID, err)
 					return
 				}
@@ -313,30 +387,61 @@ func main() {
 				campaign.Status = "running"
 			}
 
-			// Read settings
-			settings := campaign.Settings
-			minDelay := 8
-			maxDelay := 15
-			batchSize := 25
-			batchPauseMin := 2
-
-			readInt := func(keys []string, def int) int {
-				for _, key := range keys {
-					if v, ok := settings[key]; ok {
-						if f, ok := v.(float64); ok {
-							return int(f)
-						}
-					}
+			// Read settings, clamped to the account's current rate limits. A
+			// batch is never sent without them; the cycle is retried instead.
+			pacing, err := services.Campaign.BatchPacing(cCtx, campaign)
+			if err != nil {
+				log.Printf("[Campaign %s] ⚠️ %v, retrying in 30s", campaignID, err)
+				select {
+				case <-cCtx.Done():
+					return
+				case <-time.After(30 * time.Second):
 				}
-				return def
+				continue
+			}
+			minDelay := pacing.MinDelaySeconds
+			maxDelay := pacing.MaxDelaySeconds
+			batchSize := pacing.BatchSize
+			batchPauseMin := pacing.BatchPauseMinutes
+
+			if !resumeChecked {
+				resumeChecked = true
+				if state, err := services.Campaign.TakeResumeState(cCtx, campaign); err != nil {
+					log.Printf("[Campaign %s] ⚠️ Failed to load resume state: %v", campaignID, err)
+				} else {
+					resume = state
+				}
+			}
+			if resume != nil && resume.PauseRemaining > 0 {
+				// Paused during the inter-batch sleep: finish that sleep first.
+				pauseUntil := time.Now().Add(resume.PauseRemaining)
+				services.Campaign.RecordBatchProgress(campaignID, service.CampaignBatchProgress{LastSendAt: resume.LastSendAt, PauseUntil: pauseUntil})
+				log.Printf("[Campaign %s] Resuming batch pause, %v remaining", campaignID, resume.PauseRemaining.Round(time.Second))
+				resume = nil
+				select {
+				case <-cCtx.Done():
+					return
+				case <-time.After(time.Until(pauseUntil)):
+				}
+				services.Campaign.RecordBatchProgress(campaignID, service.CampaignBatchProgress{})
+				continue
 			}
 
-			minDelay = readInt([]string{"min_delay_seconds", "min_delay"}, minDelay)
-			maxDelay = readInt([]string{"max_delay_seconds", "max_delay"}, maxDelay)
-			batchSize = readInt([]string{"batch_size"}, batchSize)
-			batchPauseMin = readInt([]string{"batch_pause_minutes", "batch_pause"}, batchPauseMin)
-			if minDelay > maxDelay {
-				minDelay = maxDelay
+			// Respect the account's quiet hours, re-read with the pacing on every
+			// cycle so a new window or timezone applies; recipients stay pending.
+			if resumeAt, quiet := pacing.QuietHours.ResumeAt(time.Now()); quiet {
+				log.Printf("[Campaign %s] 🌙 Quiet hours, resuming at %s", campaignID, resumeAt.Format(time.RFC3339))
+				// Re-check periodically so a pause or cancel is noticed.
+				wait := time.Until(resumeAt)
+				if wait > 5*time.Minute {
+					wait = 5 * time.Minute
+				}
+				select {
+				case <-cCtx.Done():
+					return
+				case <-time.After(wait):
+				}
+				continue
 			}
 
 			// Verify device is connected
@@ -350,29 +455,67 @@ func main() {
 				continue
 			}
 
-			// Process one batch
+			if err := services.Campaign.AcquireDeviceTurn(cCtx, campaign.DeviceID, campaignID); err != nil {
+				return
+			}
+			deviceID := campaign.DeviceID
+			heldDevice = &deviceID
+
+			// Process one batch, continuing a partially used one after a resume
 			sentInBatch := 0
 			var lastSendTime time.Time
-			for i := 0; i < batchSize; i++ {
+			if resume != nil {
+				sentInBatch = resume.SentInBatch
+				lastSendTime = resume.LastSendAt
+				resume = nil
+				log.Printf("[Campaign %s] Resuming batch with %d/%d already sent", campaignID, sentInBatch, batchSize)
+				// Keep the per-message spacing across a short pause.
+				if !lastSendTime.IsZero() {
+					if gap := time.Duration(minDelay)*time.Second - time.Since(lastSendTime); gap > 0 {
+						select {
+						case <-cCtx.Done():
+							return
+						case <-time.After(gap):
+						}
+					}
+				}
+			}
+			processed := 0
+			finished := false
+			waitingRetry := false
+			quietHours := false
+			for sentInBatch < batchSize {
 				select {
 				case <-cCtx.Done():
 					return
 				default:
 				}
+				if _, quiet := pacing.QuietHours.ResumeAt(time.Now()); quiet {
+					quietHours = true
+					break
+				}
 				var waitTimeMs *int
 				if !lastSendTime.IsZero() {
 					w := int(time.Since(lastSendTime).Milliseconds())
 					waitTimeMs = &w
 				}
 				hasMore, sendErr := services.Campaign.ProcessNextRecipient(cCtx, campaignID, waitTimeMs)
+				if errors.Is(sendErr, service.ErrCampaignRetryPending) {
+					waitingRetry = true
+					break
+				}
 				if !hasMore {
-					if i == 0 && sendErr != nil {
+					if processed == 0 && sendErr != nil {
 						log.Printf("[Campaign %s] ⚠️ ProcessNextRecipient failed: %v", campaignID, sendErr)
 					}
+					// Completed, paused or cancelled: nothing left for this worker.
+					finished = true
 					break
 				}
 				lastSendTime = time.Now()
 				sentInBatch++
+				processed++
+				services.Campaign.RecordBatchProgress(campaignID, service.CampaignBatchProgress{SentInBatch: sentInBatch, LastSendAt: lastSendTime})
 				delayRange := maxDelay - minDelay
 				if delayRange < 0 {
 					delayRange = 0
@@ -390,20 +533,49 @@ func main() {
 				}
 			}
 
-			if sentInBatch == 0 {
-				// No messages were sent (campaign completed or no pending recipients)
+			releaseDevice()
+
+			if quietHours {
+				// Quiet hours began mid-batch: keep the batch position; the
+				// check at the top of the loop waits until they end.
+				if sentInBatch > 0 {
+					resume = &service.CampaignResumeState{SentInBatch: sentInBatch, LastSendAt: lastSendTime}
+				}
+				continue
+			}
+
+			if waitingRetry {
+				// Only recipients in retry backoff remain: keep the batch
+				// position and let other campaigns use the device meanwhile.
+				if sentInBatch > 0 {
+					resume = &service.CampaignResumeState{SentInBatch: sentInBatch, LastSendAt: lastSendTime}
+				}
+				select {
+				case <-cCtx.Done():
+					return
+				case <-time.After(15 * time.Second):
+				}
+				continue
+			}
+
+			if finished || (processed == 0 && sentInBatch < batchSize) {
+				// The campaign stopped running mid-batch, or nothing was sent.
+				// A resumed batch that was already full still owes its pause.
 				return
 			}
 
 			// Pause between batches
 			if batchPauseMin > 0 {
 				log.Printf("[Campaign %s] Batch done: %d sent, pausing %d min", campaignID, sentInBatch, batchPauseMin)
+				pauseUntil := time.Now().Add(time.Duration(batchPauseMin) * time.Minute)
+				services.Campaign.RecordBatchProgress(campaignID, service.CampaignBatchProgress{SentInBatch: sentInBatch, LastSendAt: lastSendTime, PauseUntil: pauseUntil})
 				select {
 				case <-cCtx.Done():
 					return
-				case <-time.After(time.Duration(batchPauseMin) * time.Minute):
+				case <-time.After(time.Until(pauseUntil)):
 				}
 			}
+			services.Campaign.RecordBatchProgress(campaignID, service.CampaignBatchProgress{})
 		}
 	}
 
@@ -428,14 +600,19 @@ func main() {
 				if err != nil || len(campaigns) == 0 {
 					continue
 				}
-				for _, c := range campaigns {
-					if _, loaded := activeCampaigns.Load(c.ID); loaded {
-						continue // Already has a worker
+				// Campaigns on distinct devices run in parallel; within a device,
+				// workers queue for the device oldest campaign first and then
+				// rotate batch by batch.
+				for _, group := range services.Campaign.GroupByDevice(campaigns) {
+					for _, c := range group {
+						if _, loaded := activeCampaigns.Load(c.ID); loaded {
+							continue // Already has a worker
+						}
+						// Spawn a new worker for this campaign
+						workerCtx, workerCancel := context.WithCancel(campaignCtx)
+						activeCampaigns.Store(c.ID, workerCancel)
+						go campaignWorker(workerCtx, c.ID)
 					}
-					// Spawn a new worker for this campaign
-					workerCtx, workerCancel := context.WithCancel(campaignCtx)
-					activeCampaigns.Store(c.ID, workerCancel)
-					go campaignWorker(workerCtx, c.ID)
 				}
 			}
 		}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
+)
+
+type adminAuditAction struct {
+	Action     string
+	TargetType string
+}
+
+// adminAuditActions names the audited admin routes, keyed by method and path
+// below /api/admin. Other mutations are logged under "METHOD path".
+var adminAuditActions = map[string]adminAuditAction{
+	"POST /accounts":                             {"account.create", "account"},
+	"PUT /accounts/:id":                          {"account.update", "account"},
+	"PATCH /accounts/:id/toggle":                 {"account.toggle", "account"},
+	"DELETE /accounts/:id":                       {"account.delete", "account"},
+	"DELETE /accounts/:id/purge":                 {"account.purge", "account"},
+	"POST /accounts/:id/anonymize":               {"account.anonymize", "account"},
+	"PUT /accounts/:id/subscription":             {"account.subscription_update", "account"},
+	"POST /accounts/:id/extend-trial":            {"account.trial_extend", "account"},
+	"POST /accounts/:id/suspend-subscription":    {"account.subscription_suspend", "account"},
+	"POST /accounts/:id/reactivate-subscription": {"account.subscription_reactivate", "account"},
+	"POST /users":                                {"user.create", "user"},
+	"PUT /users/:id":                             {"user.update", "user"},
+	"PATCH /users/:id/toggle":                    {"user.toggle", "user"},
+	"PATCH /users/:id/password":                  {"user.password_reset", "user"},
+	"DELETE /users/:id":                          {"user.delete", "user"},
+	"POST /users/:id/accounts":                   {"user.account_assign", "user"},
+	"DELETE /users/:id/accounts/:account_id":     {"user.account_remove", "user"},
+	"POST /roles":                                {"role.create", "role"},
+	"PUT /roles/:id":                             {"role.update", "role"},
+	"DELETE /roles/:id":                          {"role.delete", "role"},
+}
+
+// resolveAdminAuditAction maps a matched admin route to its audit action.
+func resolveAdminAuditAction(method, routePath string) adminAuditAction {
+	path := strings.TrimSuffix(strings.TrimPrefix(routePath, "/api/admin"), "/")
+	if path == "" {
+		path = "/"
+	}
+	if action, ok := adminAuditActions[method+" "+path]; ok {
+		return action
+	}
+	return adminAuditAction{Action: method + " " + path}
+}
+
+// auditAdminAction records every non-GET request under /api/admin, whatever
+// its outcome, once the handler has run. Request bodies are never stored.
+func (s *Server) auditAdminAction(c *fiber.Ctx) error {
+	err := c.Next()
+	method := c.Method()
+	if method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions {
+		return err
+	}
+
+	status := c.Response().StatusCode()
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		status = fiberErr.Code
+	} else if err != nil {
+		status = fiber.StatusInternalServerError
+	}
+	action := resolveAdminAuditAction(method, c.Route().Path)
+	entry := &domain.AuditLogEntry{
+		Action:     action.Action,
+		TargetType: action.TargetType,
+		TargetID:   c.Params("id"),
+		Method:     method,
+		Path:       c.Path(),
+		Status:     status,
+		IPHash:     hashForLog(clientIP(c)),
+	}
+	if claims, ok := c.Locals("claims").(*service.JWTClaims); ok {
+		entry.ActorUserID = &claims.UserID
+		entry.ActorUsername = claims.Username
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if auditErr := s.repos.AuditLog.Create(ctx, entry); auditErr != nil {
+		log.Printf("[AUDIT] failed to record %s by %s: %v", entry.Action, entry.ActorUsername, auditErr)
+	}
+	return err
+}
+
+// handleAdminListAudit lists audit entries, filtered by actor_id and action.
+func (s *Server) handleAdminListAudit(c *fiber.Ctx) error {
+	filter := domain.AuditLogFilter{
+		Action: strings.TrimSpace(c.Query("action")),
+		Limit:  c.QueryInt("limit", 50),
+		Offset: c.QueryInt("offset", 0),
+	}
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if raw := strings.TrimSpace(c.Query("actor_id")); raw != "" {
+		actorID, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid actor_id"})
+		}
+		filter.ActorUserID = &actorID
+	}
+
+	entries, total, err := s.repos.AuditLog.List(c.Context(), filter)
+	if err != nil {
+		log.Printf("[AUDIT] list: %v", err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to list audit log"})
+	}
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"entries":  entries,
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+		"has_more": filter.Offset+len(entries) < total,
+	})
+}
@@ -0,0 +1,21 @@
+package api
+
+import "testing"
+
+func TestResolveAdminAuditAction(t *testing.T) {
+	cases := []struct {
+		method, path       string
+		action, targetType string
+	}{
+		{"PATCH", "/api/admin/users/:id/password", "user.password_reset", "user"},
+		{"POST", "/api/admin/accounts/", "account.create", "account"},
+		{"DELETE", "/api/admin/accounts/:id", "account.delete", "account"},
+		{"POST", "/api/admin/mcp/clients", "POST /mcp/clients", ""},
+	}
+	for _, tc := range cases {
+		got := resolveAdminAuditAction(tc.method, tc.path)
+		if got.Action != tc.action || got.TargetType != tc.targetType {
+			t.Fatalf("resolveAdminAuditAction(%s %s) = %+v, want %s/%s", tc.method, tc.path, got, tc.action, tc.targetType)
+		}
+	}
+}
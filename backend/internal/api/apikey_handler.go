@@ -5,11 +5,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
 )
 
 // generateAPIKey creates a cryptographically random API key string.
@@ -28,21 +30,101 @@ func hashAPIKey(key string) string {
 	return hex.EncodeToString(h[:])
 }
 
+// apiKeyClaims builds the request claims for an authenticated API key. The
+// key acts as its creator inside the key's account; an agent key only gets
+// the module permissions of its role.
+func apiKeyClaims(key *domain.APIKey, permissions []string) *service.JWTClaims {
+	claims := &service.JWTClaims{
+		AccountID: key.AccountID,
+		SessionID: "apikey:" + key.ID.String(),
+		Username:  "api-key:" + key.Name,
+		Role:      domain.RoleAgent,
+		APIKeyID:  key.ID.String(),
+	}
+	if key.CreatedBy != nil {
+		claims.UserID = *key.CreatedBy
+	}
+	if key.Role == domain.RoleAdmin {
+		claims.IsAdmin = true
+		claims.Role = domain.RoleAdmin
+		claims.Permissions = []string{domain.PermAll}
+		return claims
+	}
+	claims.Permissions = []string{}
+	for _, p := range permissions {
+		if p != domain.PermAll {
+			claims.Permissions = append(claims.Permissions, p)
+		}
+	}
+	return claims
+}
+
+// authenticateAPIKey authenticates a request carrying an X-API-Key header in
+// place of a session token.
+func (s *Server) authenticateAPIKey(c *fiber.Ctx, rawKey string) error {
+	key, permissions, err := s.repos.APIKey.Authenticate(c.Context(), hashAPIKey(rawKey))
+	if err != nil {
+		log.Printf("[API-KEY] Error authenticating API key: %v", err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to validate API key"})
+	}
+	if key == nil || key.CreatedBy == nil {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid API key",
+		})
+	}
+	s.repos.APIKey.TouchLastUsed(c.Context(), key.ID)
+
+	claims := apiKeyClaims(key, permissions)
+	c.Locals("claims", claims)
+	c.Locals("user_id", claims.UserID)
+	c.Locals("account_id", claims.AccountID)
+	return c.Next()
+}
+
 // handleCreateAPIKey creates a new API key for the current account.
-// POST /api/settings/api-keys  { "name": "Legacy integration" }
+// POST /api/settings/api-keys  { "name": "Legacy integration", "role": "agent", "role_id": "..." }
+// role defaults to admin; agent keys are limited to the permissions of role_id.
 // Returns the plaintext key ONCE — it is never stored or shown again.
 func (s *Server) handleCreateAPIKey(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
+	claims, _ := c.Locals("claims").(*service.JWTClaims)
+	if claims == nil || claims.APIKeyID != "" {
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": "API keys cannot create other API keys"})
+	}
 
 	var body struct {
-		Name string `json:"name"`
+		Name   string     `json:"name"`
+		Role   string     `json:"role"`
+		RoleID *uuid.UUID `json:"role_id"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "invalid body"})
 	}
+	body.Name = strings.TrimSpace(body.Name)
 	if body.Name == "" {
 		body.Name = "API Key"
 	}
+	switch body.Role {
+	case "":
+		body.Role = domain.RoleAdmin
+		if body.RoleID != nil {
+			body.Role = domain.RoleAgent
+		}
+	case domain.RoleAdmin, domain.RoleAgent:
+	default:
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "role must be admin or agent"})
+	}
+	if body.Role == domain.RoleAdmin {
+		body.RoleID = nil
+	} else if body.RoleID == nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "role_id is required for agent keys"})
+	} else if role, err := s.repos.Role.GetByID(c.Context(), *body.RoleID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to load role"})
+	} else if role == nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "role not found"})
+	}
+	createdBy := claims.UserID
 
 	rawKey, err := generateAPIKey()
 	if err != nil {
@@ -57,6 +139,9 @@ func (s *Server) handleCreateAPIKey(c *fiber.Ctx) error {
 		KeyHash:     hashAPIKey(rawKey),
 		KeyPrefix:   rawKey[:15] + "...", // "clarin_a1b2c3d..." — visible identifier
 		Permissions: "read",
+		Role:        body.Role,
+		RoleID:      body.RoleID,
+		CreatedBy:   &createdBy,
 		IsActive:    true,
 		CreatedAt:   now,
 		UpdatedAt:   now,
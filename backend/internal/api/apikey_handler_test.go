@@ -0,0 +1,42 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestAPIKeyClaimsAdminKeyGetsFullAccess(t *testing.T) {
+	creator := uuid.New()
+	key := &domain.APIKey{ID: uuid.New(), AccountID: uuid.New(), Name: "ERP", Role: domain.RoleAdmin, CreatedBy: &creator}
+
+	claims := apiKeyClaims(key, nil)
+	if claims.UserID != creator || claims.AccountID != key.AccountID {
+		t.Fatalf("claims must act as the creator in the key's account, got %+v", claims)
+	}
+	if claimsRole(claims) != domain.RoleAdmin || len(claims.Permissions) != 1 || claims.Permissions[0] != domain.PermAll {
+		t.Fatalf("admin key must get admin role and wildcard permission, got %+v", claims)
+	}
+	if claims.APIKeyID != key.ID.String() || !strings.HasPrefix(claims.SessionID, "apikey:") {
+		t.Fatalf("claims must identify the key, got %+v", claims)
+	}
+}
+
+func TestAPIKeyClaimsAgentKeyOnlyGetsRolePermissions(t *testing.T) {
+	creator := uuid.New()
+	key := &domain.APIKey{ID: uuid.New(), AccountID: uuid.New(), Name: "Bot", Role: domain.RoleAgent, CreatedBy: &creator}
+
+	claims := apiKeyClaims(key, []string{"leads", domain.PermAll, "chats"})
+	if claimsRole(claims) != domain.RoleAgent {
+		t.Fatalf("agent key must not be admin, got %+v", claims)
+	}
+	if strings.Join(claims.Permissions, ",") != "leads,chats" {
+		t.Fatalf("agent key permissions = %v, want [leads chats]", claims.Permissions)
+	}
+
+	if claims := apiKeyClaims(key, nil); claims.Permissions == nil || len(claims.Permissions) != 0 {
+		t.Fatalf("agent key without a role must get no permissions, got %v", claims.Permissions)
+	}
+}
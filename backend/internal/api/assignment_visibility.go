@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/ws"
+)
+
+// assignmentScope returns the lead/chat visibility limit of the caller, or
+// nil when the caller sees everything: admins always do, agents unless the
+// account has restrict_to_assigned.
+func (s *Server) assignmentScope(c *fiber.Ctx) *domain.AssignmentScope {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	scope := s.assignmentScopeFor(c.Context(), accountID, userID, false)
+	if scope == nil || s.callerHasRole(c, domain.RoleAdmin) {
+		return nil
+	}
+	return scope
+}
+
+// assignmentScopeFor is assignmentScope for callers outside a request, such
+// as WebSocket upgrades and the cross-account inbox.
+func (s *Server) assignmentScopeFor(ctx context.Context, accountID, userID uuid.UUID, isAdmin bool) *domain.AssignmentScope {
+	if isAdmin {
+		return nil
+	}
+	restrict, seeUnassigned, err := s.repos.Account.GetAssignmentVisibility(ctx, accountID)
+	if err != nil || !restrict {
+		return nil
+	}
+	return &domain.AssignmentScope{UserID: userID, IncludeUnassigned: seeUnassigned}
+}
+
+// requireVisibleChat answers 404 for chats outside the caller's assignment
+// scope, so /chats/:id routes cannot reach them by ID.
+func (s *Server) requireVisibleChat(c *fiber.Ctx) error {
+	scope := s.assignmentScope(c)
+	if scope == nil {
+		return c.Next()
+	}
+	chatID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Next()
+	}
+	assignee, err := s.repos.Chat.GetAssignee(c.Context(), c.Locals("account_id").(uuid.UUID), chatID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Next()
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if !scope.Allows(assignee) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+	}
+	return c.Next()
+}
+
+// requireVisibleLead is requireVisibleChat for /leads/:id routes.
+func (s *Server) requireVisibleLead(c *fiber.Ctx) error {
+	scope := s.assignmentScope(c)
+	if scope == nil {
+		return c.Next()
+	}
+	leadID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Next()
+	}
+	assignee, err := s.repos.Lead.GetAssignee(c.Context(), c.Locals("account_id").(uuid.UUID), leadID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Next()
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if !scope.Allows(assignee) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Lead not found"})
+	}
+	return c.Next()
+}
+
+// visibleLeadIDs drops the lead IDs of a bulk request that fall outside the
+// caller's assignment scope. Unrestricted callers get the IDs back unchanged.
+func (s *Server) visibleLeadIDs(c *fiber.Ctx, ids []uuid.UUID) ([]uuid.UUID, error) {
+	scope := s.assignmentScope(c)
+	if scope == nil || len(ids) == 0 {
+		return ids, nil
+	}
+	return s.repos.Lead.FilterVisible(c.Context(), c.Locals("account_id").(uuid.UUID), ids, scope)
+}
+
+// wsEventRecord picks the chat or lead a WebSocket payload is about.
+type wsEventRecord struct {
+	ChatID  string `json:"chat_id"`
+	ChatJID string `json:"chat_jid"`
+	LeadID  string `json:"lead_id"`
+	Chat    *struct {
+		ID string `json:"id"`
+	} `json:"chat"`
+	Lead *struct {
+		ID string `json:"id"`
+	} `json:"lead"`
+}
+
+// resolveEventAssignee is the hub's AssigneeResolver: it finds the chat or
+// lead an event is about and returns its effective assignee. Events naming
+// no single record, or whose record is gone, are not scoped.
+func (s *Server) resolveEventAssignee(accountID uuid.UUID, data interface{}) (*uuid.UUID, bool) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, false
+	}
+	var rec wsEventRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, false
+	}
+	if rec.Chat != nil && rec.ChatID == "" {
+		rec.ChatID = rec.Chat.ID
+	}
+	if rec.Lead != nil && rec.LeadID == "" {
+		rec.LeadID = rec.Lead.ID
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var assignee *uuid.UUID
+	switch {
+	case rec.ChatID != "":
+		chatID, perr := uuid.Parse(rec.ChatID)
+		if perr != nil {
+			return nil, false
+		}
+		assignee, err = s.repos.Chat.GetAssignee(ctx, accountID, chatID)
+	case rec.LeadID != "":
+		leadID, perr := uuid.Parse(rec.LeadID)
+		if perr != nil {
+			return nil, false
+		}
+		assignee, err = s.repos.Lead.GetAssignee(ctx, accountID, leadID)
+	case rec.ChatJID != "":
+		assignee, err = s.repos.Chat.GetAssigneeByJID(ctx, accountID, rec.ChatJID)
+	default:
+		return nil, false
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false
+		}
+		// Hide the event from scoped clients rather than leak it.
+		log.Printf("[WS] assignee lookup failed for account %s: %v", accountID, err)
+		nobody := uuid.Nil
+		return &nobody, true
+	}
+	return assignee, true
+}
+
+// assignmentScopeCacheSegment keeps cached lists of scoped agents apart from
+// the account-wide ones.
+func assignmentScopeCacheSegment(scope *domain.AssignmentScope) string {
+	if scope == nil {
+		return "all"
+	}
+	return fmt.Sprintf("user=%s/%t", scope.UserID, scope.IncludeUnassigned)
+}
+
+// handleAssignChat sets or clears chats.assigned_to. The assignee must be a
+// member of the account.
+func (s *Server) handleAssignChat(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	chatID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid chat ID"})
+	}
+	var req struct {
+		UserID *uuid.UUID `json:"user_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	if req.UserID != nil {
+		exists, err := s.repos.UserAccount.Exists(c.Context(), *req.UserID, accountID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+		if !exists {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "El usuario no pertenece a esta cuenta"})
+		}
+	}
+	previous, err := s.repos.Chat.SetAssignedTo(c.Context(), accountID, chatID, req.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	s.invalidateChatsCache(accountID)
+	actorID, _ := c.Locals("user_id").(uuid.UUID)
+	s.notifyChatAssigned(accountID, chatID, previous, req.UserID, actorID)
+	return c.JSON(fiber.Map{"success": true, "assigned_to": req.UserID})
+}
+
+// notifyChatAssigned tells the new assignee, and only them, that a chat was
+// assigned to them. Self-assignments and unchanged assignees are silent.
+func (s *Server) notifyChatAssigned(accountID, chatID uuid.UUID, previous, assignee *uuid.UUID, actorID uuid.UUID) {
+	if s.hub == nil || assignee == nil || *assignee == actorID {
+		return
+	}
+	if previous != nil && *previous == *assignee {
+		return
+	}
+	s.hub.SendToUser(accountID, *assignee, ws.EventChatAssigned, map[string]interface{}{
+		"chat_id":     chatID.String(),
+		"assigned_by": actorID.String(),
+	})
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
+	"github.com/naperu/clarin/internal/ws"
+)
+
+// applyAutoTagRules runs the account's keyword rules against an inbound
+// message. It is called from both the WhatsApp Web pool and the Cloud API
+// webhook and never blocks message ingestion.
+func (s *Server) applyAutoTagRules(accountID, chatID uuid.UUID, body string) {
+	if s.services == nil || s.services.AutoTag == nil || strings.TrimSpace(body) == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		contactID, added, err := s.services.AutoTag.Apply(ctx, accountID, chatID, body)
+		if err != nil {
+			log.Printf("[AUTO-TAG] account=%s chat=%s: %v", accountID, chatID, err)
+			return
+		}
+		if contactID == nil || len(added) == 0 {
+			return
+		}
+		if _, err := s.services.Event.ReconcileContactEventMembership(ctx, accountID, *contactID); err != nil {
+			log.Printf("[EVENT-SYNC] Auto-tag reconciliation failed for contact %s: %v", *contactID, err)
+		}
+		if lead, err := s.repos.Lead.GetByContactID(ctx, *contactID); err == nil && lead != nil && lead.AccountID == accountID {
+			if kommoSync := s.kommoForAccount(ctx, accountID); kommoSync != nil {
+				kommoSync.EnqueuePushLeadTags(accountID, lead.ID)
+			}
+			for _, tagID := range added {
+				s.triggerAutomationTagAssigned(accountID, lead.ID, tagID)
+			}
+		}
+		s.invalidateContactTreeCaches(accountID)
+		if s.hub != nil {
+			payload := fiber.Map{"action": "auto_tagged", "contact_id": *contactID, "chat_id": chatID, "tag_ids": added}
+			s.hub.BroadcastToAccount(accountID, ws.EventContactUpdate, payload)
+			s.hub.BroadcastToAccount(accountID, ws.EventChatUpdate, payload)
+		}
+	}()
+}
+
+type autoTagRuleRequest struct {
+	Name      string  `json:"name"`
+	Pattern   string  `json:"pattern"`
+	MatchMode string  `json:"match_mode"`
+	TagID     *string `json:"tag_id"`
+	IsActive  *bool   `json:"is_active"`
+}
+
+// applyAutoTagRuleRequest validates req and copies it onto rule. Omitted fields keep
+// their current value so the same path serves create and update.
+func (s *Server) applyAutoTagRuleRequest(c *fiber.Ctx, accountID uuid.UUID, req autoTagRuleRequest, rule *domain.AutoTagRule) error {
+	if name := strings.TrimSpace(req.Name); name != "" {
+		rule.Name = name
+	}
+	if req.Pattern != "" {
+		rule.Pattern = req.Pattern
+	}
+	if mode := strings.TrimSpace(req.MatchMode); mode != "" {
+		rule.MatchMode = mode
+	}
+	if rule.MatchMode == "" {
+		rule.MatchMode = domain.AutoTagMatchContains
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	if err := service.ValidateAutoTagPattern(rule.MatchMode, rule.Pattern); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if req.TagID != nil {
+		tagID, err := uuid.Parse(*req.TagID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid tag ID")
+		}
+		tag, err := s.repos.Tag.GetByID(c.Context(), tagID)
+		if err != nil || tag == nil || tag.AccountID != accountID {
+			return fiber.NewError(fiber.StatusBadRequest, "Tag not found")
+		}
+		rule.TagID = tagID
+	}
+	if rule.TagID == uuid.Nil {
+		return fiber.NewError(fiber.StatusBadRequest, "tag_id is required")
+	}
+	return nil
+}
+
+func (s *Server) handleListAutoTagRules(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	rules, err := s.repos.AutoTagRule.ListByAccount(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to list auto-tag rules"})
+	}
+	return c.JSON(fiber.Map{"success": true, "rules": rules})
+}
+
+func (s *Server) handleCreateAutoTagRule(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req autoTagRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	rule := &domain.AutoTagRule{AccountID: accountID, IsActive: true}
+	if err := s.applyAutoTagRuleRequest(c, accountID, req, rule); err != nil {
+		e := err.(*fiber.Error)
+		return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+	}
+	if err := s.repos.AutoTagRule.Create(c.Context(), rule); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to create auto-tag rule"})
+	}
+	s.services.AutoTag.Invalidate(accountID)
+	created, _ := s.repos.AutoTagRule.GetByID(c.Context(), accountID, rule.ID)
+	if created != nil {
+		rule = created
+	}
+	return c.Status(201).JSON(fiber.Map{"success": true, "rule": rule})
+}
+
+func (s *Server) handleUpdateAutoTagRule(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid rule ID"})
+	}
+	rule, err := s.repos.AutoTagRule.GetByID(c.Context(), accountID, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if rule == nil {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Rule not found"})
+	}
+	var req autoTagRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	if err := s.applyAutoTagRuleRequest(c, accountID, req, rule); err != nil {
+		e := err.(*fiber.Error)
+		return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+	}
+	if err := s.repos.AutoTagRule.Update(c.Context(), rule); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to update auto-tag rule"})
+	}
+	s.services.AutoTag.Invalidate(accountID)
+	updated, _ := s.repos.AutoTagRule.GetByID(c.Context(), accountID, id)
+	if updated != nil {
+		rule = updated
+	}
+	return c.JSON(fiber.Map{"success": true, "rule": rule})
+}
+
+func (s *Server) handleDeleteAutoTagRule(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid rule ID"})
+	}
+	deleted, err := s.repos.AutoTagRule.Delete(c.Context(), accountID, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to delete auto-tag rule"})
+	}
+	if !deleted {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Rule not found"})
+	}
+	s.services.AutoTag.Invalidate(accountID)
+	return c.JSON(fiber.Map{"success": true})
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/service"
+)
+
+// handleSendChatPresence shows the contact that an agent is typing (or has
+// stopped) in this chat, using the device the chat belongs to.
+func (s *Server) handleSendChatPresence(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	chatID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid chat ID"})
+	}
+	var req struct {
+		State string `json:"state"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	if !service.ValidChatPresence(req.State) {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "state debe ser composing, paused o available"})
+	}
+	chat, err := s.services.Chat.GetByID(c.Context(), chatID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if !chatBelongsToAccount(chat, accountID) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+	}
+	if chat.DeviceID == nil {
+		return c.Status(409).JSON(fiber.Map{"success": false, "error": "El chat no tiene un dispositivo asignado"})
+	}
+	sent, err := s.services.Chat.SendPresence(c.Context(), *chat.DeviceID, chat.JID, req.State)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "sent": sent})
+}
@@ -157,6 +157,9 @@ func (s *Server) contactAvatarCallerHasPermission(c *fiber.Ctx, permission strin
 			return true
 		}
 	}
+	if claims.APIKeyID != "" {
+		return false
+	}
 	permissions, err := s.repos.UserAccount.GetUserPermissions(c.Context(), claims.UserID, claims.AccountID)
 	if err != nil {
 		return false
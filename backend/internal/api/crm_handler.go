@@ -101,6 +101,86 @@ func (s *Server) handleGetPipelineTemplates(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "templates": service.PipelineTemplates()})
 }
 
+// handleGetPipelineFunnel returns, per stage, how many leads entered and the
+// median time they spent there.
+// GET /api/pipelines/:id/funnel
+func (s *Server) handleGetPipelineFunnel(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	pipelineID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Pipeline inválido"})
+	}
+	stages, err := s.repos.Pipeline.Funnel(c.Context(), accountID, pipelineID)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "stages": stages})
+}
+
+// handleGetPipelineBoard returns the pipeline's Kanban columns with their
+// leads already bucketed, paginated per column.
+// GET /api/pipelines/:id/board?per_stage=50&offset=0&assigned_to=<user>
+func (s *Server) handleGetPipelineBoard(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	pipelineID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Pipeline inválido"})
+	}
+	perStage := c.QueryInt("per_stage", 50)
+	if perStage <= 0 || perStage > 200 {
+		perStage = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+	var assignedTo *uuid.UUID
+	if raw := c.Query("assigned_to"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Usuario inválido"})
+		}
+		assignedTo = &id
+	}
+
+	columns, err := s.repos.Lead.Board(c.Context(), accountID, pipelineID, assignedTo, s.assignmentScope(c), perStage, offset)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+
+	contactIDSet := make(map[uuid.UUID]bool)
+	for _, col := range columns {
+		for _, lead := range col.Leads {
+			if lead.ContactID != nil {
+				contactIDSet[*lead.ContactID] = true
+			}
+		}
+	}
+	if len(contactIDSet) > 0 {
+		contactIDs := make([]uuid.UUID, 0, len(contactIDSet))
+		for id := range contactIDSet {
+			contactIDs = append(contactIDs, id)
+		}
+		if tagMap, err := s.repos.Tag.GetByContactsBatch(c.Context(), contactIDs); err != nil {
+			log.Printf("[BOARD] Warning: failed to load tags: %v", err)
+		} else {
+			for _, col := range columns {
+				for _, lead := range col.Leads {
+					if lead.ContactID != nil {
+						lead.StructuredTags = tagMap[*lead.ContactID]
+					}
+				}
+			}
+		}
+	}
+
+	total := 0
+	for _, col := range columns {
+		total += col.Total
+	}
+	return c.JSON(fiber.Map{"success": true, "stages": columns, "total": total, "per_stage": perStage, "offset": offset})
+}
+
 func (s *Server) handleCreatePipelineProfessional(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	var req struct {
@@ -564,6 +644,11 @@ func (s *Server) handleMoveLeadToStage(c *fiber.Ctx) error {
 	if err != nil || lead == nil || lead.AccountID != accountID {
 		return writeCRMError(c, repository.ErrCRMNotFound)
 	}
+	if score, err := s.services.Lead.RecalculateScore(c.Context(), leadID); err != nil {
+		log.Printf("[LeadScore] recalculate lead %s: %v", leadID, err)
+	} else {
+		lead.Score = score
+	}
 	s.invalidateLeadsCache(accountID)
 	s.invalidateLeadDetailCache(accountID, leadID)
 	s.broadcastLeadDelta(accountID, "stage_changed", lead)
@@ -571,6 +656,98 @@ func (s *Server) handleMoveLeadToStage(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "lead": lead})
 }
 
+// recalculateLeadScores rescores leads in the background after a bulk change.
+func (s *Server) recalculateLeadScores(ids []uuid.UUID) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		for _, id := range ids {
+			if _, err := s.services.Lead.RecalculateScore(ctx, id); err != nil {
+				log.Printf("[LeadScore] recalculate lead %s: %v", id, err)
+			}
+		}
+	}()
+}
+
+const maxBulkStageMove = 500
+
+// handleBulkMoveLeadsStage moves many leads to one stage in a single
+// transaction. Leads from another pipeline, trashed ones and those already in
+// the stage are skipped.
+// POST /api/leads/bulk-stage { "ids": ["..."], "stage_id": "...", "close_reason": "" }
+func (s *Server) handleBulkMoveLeadsStage(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		IDs         []uuid.UUID `json:"ids"`
+		StageID     uuid.UUID   `json:"stage_id"`
+		CloseReason string      `json:"close_reason"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Solicitud inválida"})
+	}
+	if req.StageID == uuid.Nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Etapa inválida"})
+	}
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	seen := make(map[uuid.UUID]struct{}, len(req.IDs))
+	for _, id := range req.IDs {
+		if _, duplicate := seen[id]; !duplicate && id != uuid.Nil {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "No hay IDs válidos"})
+	}
+	if len(ids) > maxBulkStageMove {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": fmt.Sprintf("Máximo %d oportunidades por operación", maxBulkStageMove)})
+	}
+	requested := len(ids)
+	ids, err := s.visibleLeadIDs(c, ids)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+	if len(ids) == 0 {
+		return c.JSON(fiber.Map{"success": true, "moved": 0, "skipped": requested, "moved_ids": []uuid.UUID{}})
+	}
+	var userID *uuid.UUID
+	if id, ok := c.Locals("user_id").(uuid.UUID); ok {
+		userID = &id
+	}
+	result, err := s.repos.Lead.MoveLeadsToStage(c.Context(), accountID, ids, req.StageID, req.CloseReason, userID)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+	result.Skipped += requested - len(ids)
+
+	if len(result.Moved) > 0 {
+		if kommoSync := s.kommoForAccount(c.Context(), accountID); kommoSync != nil {
+			moved := result.Moved
+			go func() {
+				for _, leadID := range moved {
+					kommoSync.PushLeadStageChange(accountID, leadID, req.StageID)
+				}
+			}()
+		}
+		s.recalculateLeadScores(result.Moved)
+		s.invalidateLeadsCache(accountID)
+		for _, leadID := range result.Moved {
+			s.invalidateLeadDetailCache(accountID, leadID)
+			s.triggerAutomationLeadStageChanged(accountID, leadID, req.StageID)
+		}
+		leadIDs := make([]string, len(result.Moved))
+		for i, leadID := range result.Moved {
+			leadIDs[i] = leadID.String()
+		}
+		s.hub.BroadcastToAccount(accountID, ws.EventLeadUpdate, map[string]interface{}{
+			"action":   "stage_changed_bulk",
+			"lead_ids": leadIDs,
+			"stage_id": req.StageID.String(),
+		})
+	}
+	return c.JSON(fiber.Map{"success": true, "moved": len(result.Moved), "skipped": result.Skipped, "moved_ids": result.Moved})
+}
+
 func (s *Server) handleTrashLead(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	leadID, err := uuid.Parse(c.Params("id"))
@@ -607,6 +784,9 @@ func (s *Server) handleTrashLeadsBatch(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Solicitud inválida"})
 	}
+	if req.DeleteAll && !s.callerHasRole(c, domain.RoleAdmin) {
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": roleForbiddenMessage})
+	}
 	var userID *uuid.UUID
 	if id, ok := c.Locals("user_id").(uuid.UUID); ok {
 		userID = &id
@@ -631,6 +811,12 @@ func (s *Server) handleTrashLeadsBatch(c *fiber.Ctx) error {
 		if len(ids) == 0 {
 			return c.Status(400).JSON(fiber.Map{"success": false, "error": "No hay IDs válidos"})
 		}
+		if ids, err = s.visibleLeadIDs(c, ids); err != nil {
+			return writeCRMError(c, err)
+		}
+		if len(ids) == 0 {
+			return c.JSON(fiber.Map{"success": true, "count": 0})
+		}
 		count, err = s.repos.Lead.SoftDeleteBatch(c.Context(), accountID, ids, userID, req.Reason)
 	}
 	if err != nil {
@@ -656,6 +842,34 @@ func (s *Server) handleRestoreLead(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "lead": lead})
 }
 
+// trashPage reads limit/offset for trash listings.
+func trashPage(c *fiber.Ctx) (limit, offset int) {
+	limit = c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset = c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// handleGetLeadTrash lists soft-deleted leads; they are purged after 30 days.
+// GET /api/leads/trash?limit=50&offset=0
+func (s *Server) handleGetLeadTrash(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	limit, offset := trashPage(c)
+	leads, total, err := s.repos.Lead.GetTrash(c.Context(), accountID, limit, offset, s.assignmentScope(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if leads == nil {
+		leads = []*domain.Lead{}
+	}
+	return c.JSON(fiber.Map{"success": true, "leads": leads, "total": total})
+}
+
 func (s *Server) handleRejectDirectLeadStatus(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
 		"success": false,
@@ -678,9 +892,9 @@ func (s *Server) handleArchiveLeadSafe(c *fiber.Ctx) error {
 	}
 	var tag pgconn.CommandTag
 	if req.Archive {
-		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=TRUE, archived_at=NOW(), archive_reason=$3, updated_at=NOW() WHERE id=$1 AND account_id=$2 AND deleted_at IS NULL`, leadID, accountID, strings.TrimSpace(req.Reason))
+		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=TRUE, archived_at=NOW(), archive_reason=$3, updated_at=NOW() WHERE id=$1 AND account_id=$2 AND `+repository.NotDeleted(""), leadID, accountID, strings.TrimSpace(req.Reason))
 	} else {
-		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=FALSE, archived_at=NULL, archive_reason='', updated_at=NOW() WHERE id=$1 AND account_id=$2 AND deleted_at IS NULL`, leadID, accountID)
+		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=FALSE, archived_at=NULL, archive_reason='', updated_at=NOW() WHERE id=$1 AND account_id=$2 AND `+repository.NotDeleted(""), leadID, accountID)
 	}
 	if err != nil {
 		return writeCRMError(c, err)
@@ -703,12 +917,18 @@ func (s *Server) handleArchiveLeadsBatchSafe(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil || len(req.IDs) == 0 {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "IDs obligatorios"})
 	}
+	ids, err := s.visibleLeadIDs(c, req.IDs)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+	if len(ids) == 0 {
+		return c.JSON(fiber.Map{"success": true, "count": 0})
+	}
 	var tag pgconn.CommandTag
-	var err error
 	if req.Archive {
-		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=TRUE, archived_at=NOW(), archive_reason=$3, updated_at=NOW() WHERE account_id=$1 AND id=ANY($2) AND deleted_at IS NULL`, accountID, req.IDs, strings.TrimSpace(req.Reason))
+		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=TRUE, archived_at=NOW(), archive_reason=$3, updated_at=NOW() WHERE account_id=$1 AND id=ANY($2) AND `+repository.NotDeleted(""), accountID, ids, strings.TrimSpace(req.Reason))
 	} else {
-		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=FALSE, archived_at=NULL, archive_reason='', updated_at=NOW() WHERE account_id=$1 AND id=ANY($2) AND deleted_at IS NULL`, accountID, req.IDs)
+		tag, err = s.repos.DB().Exec(c.Context(), `UPDATE leads SET is_archived=FALSE, archived_at=NULL, archive_reason='', updated_at=NOW() WHERE account_id=$1 AND id=ANY($2) AND `+repository.NotDeleted(""), accountID, ids)
 	}
 	if err != nil {
 		return writeCRMError(c, err)
@@ -763,11 +983,15 @@ func (s *Server) handleBlockLeadsBatchCompatibility(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil || len(req.IDs) == 0 {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "IDs obligatorios"})
 	}
-	contactIDs := make([]uuid.UUID, 0, len(req.IDs))
+	leadIDs, err := s.visibleLeadIDs(c, req.IDs)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+	contactIDs := make([]uuid.UUID, 0, len(leadIDs))
 	rows, queryErr := s.repos.DB().Query(c.Context(), `
 		SELECT DISTINCT contact_id FROM leads
 		WHERE account_id=$1 AND id=ANY($2) AND contact_id IS NOT NULL
-	`, accountID, req.IDs)
+	`, accountID, leadIDs)
 	if queryErr != nil {
 		return writeCRMError(c, queryErr)
 	}
@@ -811,6 +1035,9 @@ func (s *Server) currentUserIsAccountAdmin(c *fiber.Ctx) bool {
 	if claims.IsAdmin || claims.IsSuperAdmin || claims.Role == domain.RoleAdmin || claims.Role == domain.RoleSuperAdmin {
 		return true
 	}
+	if claims.APIKeyID != "" {
+		return false
+	}
 	var role string
 	err := s.repos.DB().QueryRow(c.Context(), `SELECT role FROM user_accounts WHERE user_id=$1 AND account_id=$2`, claims.UserID, claims.AccountID).Scan(&role)
 	return err == nil && (role == domain.RoleAdmin || role == domain.RoleSuperAdmin)
@@ -1020,7 +1247,11 @@ func (s *Server) StartLeadTrashPurgeWorker(ctx context.Context) {
 			if err != nil {
 				return
 			}
-			if count > 0 {
+			contactCount, err := s.repos.Contact.PurgeExpired(ctx, 30*24*time.Hour)
+			if err != nil {
+				log.Printf("[TRASH] contact purge failed: %v", err)
+			}
+			if count > 0 || contactCount > 0 {
 				s.invalidateAllLeadCachesAfterPurge()
 			}
 		}
@@ -1041,6 +1272,28 @@ func (s *Server) StartLeadTrashPurgeWorker(ctx context.Context) {
 	}()
 }
 
+// leadScoreRecomputeInterval is how often time-window scoring rules are
+// re-evaluated; windows are whole days, so a few hours of lag is harmless.
+const leadScoreRecomputeInterval = 6 * time.Hour
+
+// StartLeadScoreRecomputeWorker periodically rescores leads whose points
+// depend on how recent their activity is.
+func (s *Server) StartLeadScoreRecomputeWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(leadScoreRecomputeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.services.Lead.RecomputeTimeWindowScores(ctx)
+				s.invalidateAllLeadCachesAfterPurge()
+			}
+		}
+	}()
+}
+
 func (s *Server) invalidateAllLeadCachesAfterPurge() {
 	if s.cache != nil {
 		_ = s.cache.DelPattern(context.Background(), "leads:*")
@@ -1109,15 +1362,15 @@ func leadLifecycleWhereClauses(lifecycle string) []string {
 	case leadLifecycleTrash:
 		return []string{"l.deleted_at IS NOT NULL"}
 	case domain.LeadStatusWon, domain.LeadStatusLost:
-		return []string{"l.deleted_at IS NULL", "l.is_archived = FALSE", "l.status = '" + lifecycle + "'"}
+		return []string{repository.NotDeleted("l"), "l.is_archived = FALSE", "l.status = '" + lifecycle + "'"}
 	case leadLifecycleArchived:
-		return []string{"l.deleted_at IS NULL", "l.is_archived = TRUE"}
+		return []string{repository.NotDeleted("l"), "l.is_archived = TRUE"}
 	case leadLifecycleBlocked:
-		return []string{"l.deleted_at IS NULL", "COALESCE(c.do_not_contact, FALSE) = TRUE"}
+		return []string{repository.NotDeleted("l"), "COALESCE(c.do_not_contact, FALSE) = TRUE"}
 	case leadLifecycleAll:
-		return []string{"l.deleted_at IS NULL"}
+		return []string{repository.NotDeleted("l")}
 	default:
-		return []string{"l.deleted_at IS NULL", "l.is_archived = FALSE", "l.status = 'open'"}
+		return []string{repository.NotDeleted("l"), "l.is_archived = FALSE", "l.status = 'open'"}
 	}
 }
 
@@ -1131,6 +1384,21 @@ func addLeadLifecycleWhere(c *fiber.Ctx, whereClauses *[]string) {
 	*whereClauses = append(*whereClauses, leadLifecycleWhereClauses(lifecycle)...)
 }
 
+// addLeadAssignmentWhere limits lead views and counters to the caller's
+// assignment scope. A nil scope (admins, unrestricted accounts) adds nothing.
+func addLeadAssignmentWhere(scope *domain.AssignmentScope, whereClauses *[]string, args *[]interface{}, argIdx *int) {
+	if scope == nil {
+		return
+	}
+	clause := fmt.Sprintf("l.assigned_to = $%d", *argIdx)
+	if scope.IncludeUnassigned {
+		clause = fmt.Sprintf("(l.assigned_to = $%d OR l.assigned_to IS NULL)", *argIdx)
+	}
+	*whereClauses = append(*whereClauses, clause)
+	*args = append(*args, scope.UserID)
+	*argIdx++
+}
+
 // addLeadPipelineWhere keeps pipeline selection consistent across lead views
 // and counters. The pipeline belongs to the lead itself; stage_id is not a
 // reliable substitute because leads can be temporarily unassigned.
@@ -1151,3 +1419,38 @@ func addLeadPipelineWhere(pipelineID string, whereClauses *[]string, args *[]int
 	*args = append(*args, parsed)
 	*argIdx++
 }
+
+// handleGetLeadScoringRules returns the account's lead scoring rules
+// (the defaults when none were saved).
+func (s *Server) handleGetLeadScoringRules(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	rules, err := s.services.Lead.ScoringRules(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "rules": rules})
+}
+
+// handleUpdateLeadScoringRules replaces the account's rules; leads are
+// rescored in the background.
+// PUT /api/leads/scoring-rules { "rules": [{"type": "has_tag", "value": "hot", "points": 30}] }
+func (s *Server) handleUpdateLeadScoringRules(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		Rules []domain.LeadScoringRule `json:"rules"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Solicitud inválida"})
+	}
+	if req.Rules == nil {
+		req.Rules = []domain.LeadScoringRule{}
+	}
+	if err := service.ValidateLeadScoringRules(req.Rules); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if err := s.services.Lead.SetScoringRules(c.Context(), accountID, req.Rules); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	s.invalidateLeadsCache(accountID)
+	return c.JSON(fiber.Map{"success": true, "rules": req.Rules})
+}
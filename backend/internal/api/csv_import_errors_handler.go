@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var csvImportErrorColumns = []string{"fila_original", "error"}
+
+// limitCSVImportFailedRows caps the failed rows returned inline; the full
+// list is available from the errors.csv download.
+func limitCSVImportFailedRows(rows []csvImportRowError) []csvImportRowError {
+	if rows == nil {
+		return []csvImportRowError{}
+	}
+	if len(rows) > csvImportPreviewRowLimit {
+		return rows[:csvImportPreviewRowLimit]
+	}
+	return rows
+}
+
+// handleDownloadCSVImportErrors returns the rows of an import that failed,
+// with their original cells plus the row number and reason, so they can be
+// fixed and uploaded again.
+// GET /api/import/:jobId/errors.csv
+func (s *Server) handleDownloadCSVImportErrors(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	jobID, err := uuid.Parse(c.Params("jobId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Importación inválida"})
+	}
+	var headersJSON, failedRowsJSON []byte
+	err = s.repos.DB().QueryRow(c.Context(), `
+		SELECT headers, failed_rows FROM csv_import_logs WHERE id = $1 AND account_id = $2
+	`, jobID, accountID).Scan(&headersJSON, &failedRowsJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Importación no encontrada"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	var headers []string
+	var rows []csvImportRowError
+	if err := json.Unmarshal(headersJSON, &headers); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if err := json.Unmarshal(failedRowsJSON, &rows); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	var output bytes.Buffer
+	if err := writeCSVImportErrors(&output, headers, rows); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo generar el archivo CSV"})
+	}
+	c.Set("Content-Type", "text/csv; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=import_errors_%s.csv", jobID.String()[:8]))
+	return c.Send(output.Bytes())
+}
+
+// writeCSVImportErrors writes the original header plus fila_original and
+// error columns. Original cells are written untouched so the file imports
+// exactly as the source did; only the generated error column is neutralized.
+func writeCSVImportErrors(w io.Writer, headers []string, rows []csvImportRowError) error {
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(append(append([]string{}, headers...), csvImportErrorColumns...)); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(headers), len(headers)+len(csvImportErrorColumns))
+		copy(record, row.Data)
+		reason := row.Reason
+		if row.Stage != "" && row.Stage != "row" {
+			reason = row.Stage + ": " + reason
+		}
+		record = append(record, strconv.Itoa(row.Row), neutralizeSpreadsheetFormula(reason))
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
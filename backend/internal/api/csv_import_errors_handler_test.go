@@ -0,0 +1,42 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCSVImportErrorsKeepsOriginalCells(t *testing.T) {
+	headers := []string{"nombre", "telefono", "notas"}
+	rows := []csvImportRowError{
+		{Row: 3, Stage: "row", Reason: "Sin teléfono válido", Data: []string{"Ana", "12", "=SUM(A1)"}},
+		{Row: 7, Stage: "lead", Reason: "=boom", Data: []string{"Luis"}},
+	}
+	var out bytes.Buffer
+	if err := writeCSVImportErrors(&out, headers, rows); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"nombre", "telefono", "notas", "fila_original", "error"},
+		{"Ana", "12", "=SUM(A1)", "3", "Sin teléfono válido"},
+		{"Luis", "", "", "7", "lead: =boom"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("records = %q, want %q", records, want)
+	}
+}
+
+func TestLimitCSVImportFailedRows(t *testing.T) {
+	if got := limitCSVImportFailedRows(nil); got == nil || len(got) != 0 {
+		t.Fatalf("nil rows should render as an empty list, got %v", got)
+	}
+	rows := make([]csvImportRowError, csvImportPreviewRowLimit+10)
+	if got := limitCSVImportFailedRows(rows); len(got) != csvImportPreviewRowLimit {
+		t.Fatalf("len = %d, want %d", len(got), csvImportPreviewRowLimit)
+	}
+}
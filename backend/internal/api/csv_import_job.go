@@ -0,0 +1,262 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/naperu/clarin/internal/ws"
+)
+
+// CSV import job states, stored in csv_import_logs.status.
+const (
+	csvImportStatusQueued    = "queued"
+	csvImportStatusRunning   = "running"
+	csvImportStatusCompleted = "completed"
+	csvImportStatusFailed    = "failed"
+)
+
+const (
+	csvImportProgressEvery = 100
+	csvImportJobTimeout    = 2 * time.Hour
+	// A running job renews its lease (updated_at) every heartbeat; one whose
+	// lease expired belonged to an instance that died mid-import.
+	csvImportHeartbeatEvery = time.Minute
+	csvImportLease          = 5 * time.Minute
+	csvImportFinishTimeout  = 30 * time.Second
+)
+
+// csvImportJobProgress is the payload of import_progress events and of
+// GET /api/import/:jobId.
+type csvImportJobProgress struct {
+	JobID      uuid.UUID `json:"job_id"`
+	Status     string    `json:"status"`
+	FileName   string    `json:"file_name"`
+	ImportType string    `json:"import_type"`
+	Total      int       `json:"total"`
+	Processed  int       `json:"processed"`
+	Imported   int       `json:"imported"`
+	Created    int       `json:"created"`
+	Updated    int       `json:"updated"`
+	Skipped    int       `json:"skipped"`
+	ErrorCount int       `json:"error_count"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (s *Server) createCSVImportJob(ctx context.Context, accountID, userID uuid.UUID, importType, fileName string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := s.repos.DB().QueryRow(ctx, `
+		INSERT INTO csv_import_logs (account_id, uploaded_by, import_type, file_name, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, accountID, userID, importType, fileName, csvImportStatusQueued).Scan(&id)
+	return id, err
+}
+
+// runCSVImportJob builds and applies the import plan in the background.
+// rawBytes must already hold the whole upload: the request is gone by now.
+func (s *Server) runCSVImportJob(jobID, accountID, userID uuid.UUID, importType, importTag, existingMode, fileName string, rawBytes []byte, mapping csvImportColumnMapping) {
+	ctx, cancel := context.WithTimeout(context.Background(), csvImportJobTimeout)
+	defer cancel()
+	progress := csvImportJobProgress{JobID: jobID, Status: csvImportStatusRunning, FileName: fileName, ImportType: importType}
+	// The job context may already be expired when the job ends, so the final
+	// status is always written with a fresh one.
+	fail := func(msg string) {
+		progress.Status = csvImportStatusFailed
+		progress.Error = msg
+		failCtx, failCancel := context.WithTimeout(context.Background(), csvImportFinishTimeout)
+		defer failCancel()
+		if _, err := s.repos.DB().Exec(failCtx, `
+			UPDATE csv_import_logs SET status = $2, error = $3, updated_at = NOW() WHERE id = $1
+		`, jobID, csvImportStatusFailed, msg); err != nil {
+			log.Printf("[CSV Import] job %s: failed to record failure: %v", jobID, err)
+		}
+		s.hub.BroadcastToAccount(accountID, ws.EventImportProgress, progress)
+	}
+	go s.renewCSVImportJobLease(ctx, jobID)
+
+	releaseImportLock, err := s.acquireCSVImportLock(ctx, accountID)
+	if err != nil {
+		fail("No se pudo asegurar la importación; inténtalo nuevamente")
+		return
+	}
+	defer releaseImportLock()
+
+	plan, err := s.buildCSVImportPlan(ctx, accountID, importType, importTag, existingMode, fileName, rawBytes, mapping, importType != "contacts")
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	if plan.Summary.NewContacts > 0 {
+		if err := s.enforcePlanLimit(ctx, accountID, "max_contacts", plan.Summary.NewContacts); err != nil {
+			fail(err.Error())
+			return
+		}
+	}
+
+	progress.Total = len(plan.Records)
+	s.updateCSVImportJobProgress(ctx, accountID, &progress, 0, plan.Summary)
+	result := s.executeCSVImportPlan(ctx, accountID, userID, plan, func(processed int, result csvImportSummary) {
+		s.updateCSVImportJobProgress(ctx, accountID, &progress, processed, result)
+	})
+
+	if result.Created > 0 || result.Updated > 0 {
+		s.invalidateLeadsCache(accountID)
+		s.invalidateContactsCache(accountID)
+		s.invalidateTagsCache(accountID)
+	}
+	if result.Created > 0 {
+		go s.services.Event.ReconcileAllAccountEvents(context.Background(), accountID)
+	}
+
+	progress.Processed = progress.Total
+	progress.setCounts(result)
+	finishCtx, finishCancel := context.WithTimeout(context.Background(), csvImportFinishTimeout)
+	defer finishCancel()
+	if err := s.finishCSVImportJob(finishCtx, jobID, plan.Headers, result); err != nil {
+		log.Printf("[CSV Import] job %s: failed to write import log: %v", jobID, err)
+		fail("La importación se aplicó, pero no se pudo guardar su resumen")
+		return
+	}
+	progress.Status = csvImportStatusCompleted
+	s.hub.BroadcastToAccount(accountID, ws.EventImportProgress, progress)
+}
+
+// renewCSVImportJobLease keeps a job's updated_at fresh while it runs so
+// other instances, and this one after a restart, can tell it is alive.
+func (s *Server) renewCSVImportJobLease(ctx context.Context, jobID uuid.UUID) {
+	ticker := time.NewTicker(csvImportHeartbeatEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.repos.DB().Exec(ctx, `
+				UPDATE csv_import_logs SET updated_at = NOW() WHERE id = $1 AND status IN ($2, $3)
+			`, jobID, csvImportStatusQueued, csvImportStatusRunning); err != nil && ctx.Err() == nil {
+				log.Printf("[CSV Import] job %s: failed to renew lease: %v", jobID, err)
+			}
+		}
+	}
+}
+
+func (p *csvImportJobProgress) setCounts(result csvImportSummary) {
+	p.Imported = result.Created + result.Updated
+	p.Created = result.Created
+	p.Updated = result.Updated
+	p.Skipped = result.Skipped
+	p.ErrorCount = result.ErrorCount
+}
+
+func (s *Server) updateCSVImportJobProgress(ctx context.Context, accountID uuid.UUID, progress *csvImportJobProgress, processed int, result csvImportSummary) {
+	progress.Processed = processed
+	progress.setCounts(result)
+	if _, err := s.repos.DB().Exec(ctx, `
+		UPDATE csv_import_logs
+		SET status = $2, total_rows = $3, processed_rows = $4, created_count = $5, updated_count = $6,
+		    skipped_count = $7, error_count = $8, updated_at = NOW()
+		WHERE id = $1
+	`, progress.JobID, progress.Status, progress.Total, processed, result.Created, result.Updated,
+		result.Skipped, result.ErrorCount); err != nil {
+		log.Printf("[CSV Import] job %s: failed to record progress: %v", progress.JobID, err)
+	}
+	s.hub.BroadcastToAccount(accountID, ws.EventImportProgress, *progress)
+}
+
+// finishCSVImportJob stores the final summary, the failed rows and the
+// source headers used by the errors.csv download.
+func (s *Server) finishCSVImportJob(ctx context.Context, jobID uuid.UUID, headers []string, summary csvImportSummary) error {
+	details, _ := json.Marshal(fiber.Map{
+		"incoming_destination":    summary.IncomingDestination,
+		"import_tag":              summary.ImportTag,
+		"safe_mode":               summary.SafeMode,
+		"duplicate_policy":        summary.DuplicatePolicy,
+		"new_opportunities":       summary.NewOpportunities,
+		"existing_kommo":          summary.ExistingKommo,
+		"duplicate_contact_leads": summary.DuplicateContactLeads,
+		"invalid_rows":            summary.InvalidRows,
+		"errors":                  summary.Errors,
+	})
+	if headers == nil {
+		headers = []string{}
+	}
+	failedRows := summary.FailedRows
+	if failedRows == nil {
+		failedRows = []csvImportRowError{}
+	}
+	headersJSON, _ := json.Marshal(headers)
+	failedRowsJSON, _ := json.Marshal(failedRows)
+	summaryJSON, _ := json.Marshal(summary)
+	_, err := s.repos.DB().Exec(ctx, `
+		UPDATE csv_import_logs
+		SET status = $2, source = $3, total_rows = $4, processed_rows = $4,
+		    created_count = $5, updated_count = $6, existing_count = $7, skipped_count = $8,
+		    duplicate_count = $9, error_count = $10, new_contacts_count = $11,
+		    details = $12, headers = $13, failed_rows = $14, summary = $15, updated_at = NOW()
+		WHERE id = $1
+	`, jobID, csvImportStatusCompleted, summary.Source, summary.TotalRows,
+		summary.Created, summary.Updated, summary.Existing, summary.Skipped,
+		summary.Duplicates, summary.ErrorCount, summary.NewContacts,
+		details, headersJSON, failedRowsJSON, summaryJSON)
+	return err
+}
+
+// handleGetCSVImportJob reports a CSV import's progress; once completed it
+// also carries the full summary and the first failed rows.
+// GET /api/import/:jobId
+func (s *Server) handleGetCSVImportJob(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	jobID, err := uuid.Parse(c.Params("jobId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Importación inválida"})
+	}
+	if _, err := s.repos.DB().Exec(c.Context(), `
+		UPDATE csv_import_logs SET status = $3, error = 'interrumpida por reinicio del servidor', updated_at = NOW()
+		WHERE id = $1 AND account_id = $2 AND status IN ($4, $5) AND updated_at < $6
+	`, jobID, accountID, csvImportStatusFailed, csvImportStatusQueued, csvImportStatusRunning, time.Now().Add(-csvImportLease)); err != nil {
+		log.Printf("[CSV Import] job %s: failed to expire stale job: %v", jobID, err)
+	}
+	job := csvImportJobProgress{JobID: jobID}
+	var summaryJSON, failedRowsJSON []byte
+	err = s.repos.DB().QueryRow(c.Context(), `
+		SELECT status, file_name, import_type, total_rows, processed_rows, created_count, updated_count,
+		       skipped_count, error_count, error, summary, failed_rows
+		FROM csv_import_logs WHERE id = $1 AND account_id = $2
+	`, jobID, accountID).Scan(&job.Status, &job.FileName, &job.ImportType, &job.Total, &job.Processed, &job.Created, &job.Updated,
+		&job.Skipped, &job.ErrorCount, &job.Error, &summaryJSON, &failedRowsJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Importación no encontrada"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	job.Imported = job.Created + job.Updated
+
+	response := fiber.Map{
+		"success":     true,
+		"job_id":      job.JobID,
+		"status":      job.Status,
+		"file_name":   job.FileName,
+		"total":       job.Total,
+		"processed":   job.Processed,
+		"imported":    job.Imported,
+		"created":     job.Created,
+		"updated":     job.Updated,
+		"skipped":     job.Skipped,
+		"error_count": job.ErrorCount,
+		"error":       job.Error,
+	}
+	if job.Status == csvImportStatusCompleted {
+		var failedRows []csvImportRowError
+		_ = json.Unmarshal(failedRowsJSON, &failedRows)
+		response["summary"] = json.RawMessage(summaryJSON)
+		response["failed_rows"] = limitCSVImportFailedRows(failedRows)
+	}
+	return c.JSON(response)
+}
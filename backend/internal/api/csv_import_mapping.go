@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// csvImportColumnMapping is the optional column_mapping form field: field
+// name to zero-based column index, e.g. {"phone": 3, "name": 0}. Mapped
+// fields override header detection; phone is required and replaces the
+// phone column heuristics entirely.
+type csvImportColumnMapping map[string]int
+
+var csvImportMappableFields = map[string]bool{
+	"phone": true, "name": true, "lead_name": true, "last_name": true, "email": true,
+	"company": true, "notes": true, "tags": true, "dni": true, "birth_date": true, "kommo_id": true,
+}
+
+func parseCSVImportColumnMapping(value string) (csvImportColumnMapping, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	var mapping csvImportColumnMapping
+	if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+		return nil, fmt.Errorf("column_mapping must be a JSON object of field to column index")
+	}
+	if len(mapping) == 0 {
+		return nil, nil
+	}
+	for field, idx := range mapping {
+		if !csvImportMappableFields[field] {
+			return nil, fmt.Errorf("column_mapping: unknown field %q", field)
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("column_mapping: %s must be a column index >= 0", field)
+		}
+	}
+	if _, ok := mapping["phone"]; !ok {
+		return nil, fmt.Errorf("column_mapping must include phone")
+	}
+	return mapping, nil
+}
+
+// validate checks the indices against the header row and that the phone
+// column holds a phone-like value in the first data row.
+func (m csvImportColumnMapping) validate(headers, firstDataRow []string) error {
+	fields := make([]string, 0, len(m))
+	for field := range m {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if m[field] >= len(headers) {
+			return fmt.Errorf("column_mapping: %s apunta a la columna %d, pero el archivo sólo tiene %d", field, m[field], len(headers))
+		}
+	}
+	if firstValidImportPhone(firstDataRow, []int{m["phone"]}) == "" {
+		return fmt.Errorf("column_mapping: la columna %d (%s) no contiene un teléfono válido en la primera fila", m["phone"], headers[m["phone"]])
+	}
+	return nil
+}
+
+// col returns the mapped index for field, or detected when it is not mapped.
+func (m csvImportColumnMapping) col(field string, detected int) int {
+	if idx, ok := m[field]; ok {
+		return idx
+	}
+	return detected
+}
+
+// cols is col for fields that may read from several columns.
+func (m csvImportColumnMapping) cols(field string, detected []int) []int {
+	if idx, ok := m[field]; ok {
+		return []int{idx}
+	}
+	return detected
+}
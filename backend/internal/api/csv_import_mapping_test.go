@@ -0,0 +1,49 @@
+package api
+
+import "testing"
+
+func TestParseCSVImportColumnMapping(t *testing.T) {
+	mapping, err := parseCSVImportColumnMapping(`{"phone": 3, "name": 0, "email": 5}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping["phone"] != 3 || mapping["name"] != 0 || mapping["email"] != 5 {
+		t.Fatalf("mapping = %v", mapping)
+	}
+	if mapping, err := parseCSVImportColumnMapping(""); err != nil || mapping != nil {
+		t.Fatalf("empty value = %v, %v; want nil, nil", mapping, err)
+	}
+	for _, bad := range []string{`[1,2]`, `{"name": 0}`, `{"phone": -1}`, `{"phone": 1, "color": 2}`} {
+		if _, err := parseCSVImportColumnMapping(bad); err == nil {
+			t.Fatalf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestCSVImportColumnMappingValidate(t *testing.T) {
+	headers := []string{"Nombre", "Codigo", "Movil"}
+	row := []string{"Ana", "12", "+51 999 888 777"}
+	if err := (csvImportColumnMapping{"phone": 2, "name": 0}).validate(headers, row); err != nil {
+		t.Fatal(err)
+	}
+	if err := (csvImportColumnMapping{"phone": 1}).validate(headers, row); err == nil {
+		t.Fatal("expected an error for a column without a phone")
+	}
+	if err := (csvImportColumnMapping{"phone": 2, "email": 7}).validate(headers, row); err == nil {
+		t.Fatal("expected an error for an index past the header")
+	}
+}
+
+func TestCSVImportColumnMappingOverridesDetection(t *testing.T) {
+	var none csvImportColumnMapping
+	if got := none.col("name", 4); got != 4 {
+		t.Fatalf("nil mapping col = %d, want detected 4", got)
+	}
+	mapping := csvImportColumnMapping{"phone": 2, "email": 1}
+	if got := mapping.col("name", 4); got != 4 {
+		t.Fatalf("unmapped col = %d, want 4", got)
+	}
+	if got := mapping.cols("email", []int{5, 6}); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("mapped cols = %v, want [1]", got)
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
+)
+
+// CSV import modes for rows whose lead already exists, sent as the
+// existing_mode form field.
+//
+// skip (default) leaves existing leads untouched apart from the Kommo
+// status/fecha tags. update_existing also matches open leads by the row's
+// phone (JID) and overwrites them with every non-empty incoming value:
+//   - contact: name, last name, email, company, DNI and birth date
+//   - lead: title and notes
+//   - tags from the file are added; existing tags are never removed
+//
+// Phone/JID, the custom display name, pipeline and stage, assignee, status
+// and custom fields are always preserved, and empty cells never clear data.
+const (
+	csvImportExistingSkip   = "skip"
+	csvImportExistingUpdate = "update_existing"
+)
+
+// csvImportUpdatedFields lists, for the preview, what update_existing writes.
+var csvImportUpdatedFields = []string{"nombre", "apellido", "email", "empresa", "DNI", "fecha de nacimiento", "título", "notas", "etiquetas (sólo agrega)"}
+
+func parseCSVImportExistingMode(value string) (string, error) {
+	switch value {
+	case "", csvImportExistingSkip:
+		return csvImportExistingSkip, nil
+	case csvImportExistingUpdate:
+		return csvImportExistingUpdate, nil
+	default:
+		return "", fmt.Errorf("existing_mode must be 'skip' or 'update_existing'")
+	}
+}
+
+// findCSVImportOpenLead returns the most recently updated open lead of a
+// contact, the one update_existing rewrites when the row has no Kommo match.
+func (s *Server) findCSVImportOpenLead(ctx context.Context, accountID, contactID uuid.UUID) (*uuid.UUID, error) {
+	var leadID uuid.UUID
+	err := s.repos.DB().QueryRow(ctx, `
+		SELECT id FROM leads
+		WHERE account_id = $1 AND contact_id = $2
+		  AND status IN ('open', 'new')
+		  AND `+repository.NotDeleted("")+`
+		  AND is_archived = FALSE
+		ORDER BY updated_at DESC LIMIT 1
+	`, accountID, contactID).Scan(&leadID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &leadID, nil
+}
+
+// applyCSVImportUpdate writes the row's non-empty values onto an existing
+// lead and its contact. It reports whether anything changed. Personal data
+// of leads without a contact is left alone; imports always link one.
+func (s *Server) applyCSVImportUpdate(ctx context.Context, accountID, leadID uuid.UUID, record csvImportRecord) (bool, error) {
+	lead, err := s.repos.Lead.GetByID(ctx, leadID)
+	if err != nil {
+		return false, err
+	}
+	if lead == nil || lead.AccountID != accountID {
+		return false, fmt.Errorf("lead no encontrado")
+	}
+	changed := false
+	if lead.ContactID != nil {
+		contact, err := s.repos.Contact.GetByIDForAccount(ctx, accountID, *lead.ContactID)
+		if err != nil {
+			return false, err
+		}
+		if contact != nil && overwriteCSVImportContactFields(contact, record) {
+			if err := s.repos.Contact.Update(ctx, contact); err != nil {
+				return false, err
+			}
+			if err := s.repos.Contact.SyncToParticipants(ctx, contact); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+	}
+	if overwriteCSVImportLeadFields(lead, record) {
+		if err := s.repos.Lead.Update(ctx, lead); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	if len(record.Tags) > 0 {
+		if err := s.repos.Tag.SyncLeadTagsByNames(ctx, accountID, leadID, record.Tags); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+func overwriteCSVImportContactFields(contact *domain.Contact, record csvImportRecord) bool {
+	changed := false
+	overwriteCSVImportString(&contact.Name, record.Name, &changed)
+	overwriteCSVImportString(&contact.LastName, record.LastName, &changed)
+	overwriteCSVImportString(&contact.Email, record.Email, &changed)
+	overwriteCSVImportString(&contact.Company, record.Company, &changed)
+	overwriteCSVImportString(&contact.DNI, record.DNI, &changed)
+	overwriteCSVImportDate(&contact.BirthDate, record.BirthDate, &changed)
+	return changed
+}
+
+func overwriteCSVImportLeadFields(lead *domain.Lead, record csvImportRecord) bool {
+	changed := false
+	if record.LeadTitle != "" && lead.Title != record.LeadTitle {
+		lead.Title = record.LeadTitle
+		changed = true
+	}
+	overwriteCSVImportString(&lead.Notes, record.Notes, &changed)
+	return changed
+}
+
+func overwriteCSVImportString(dst **string, value string, changed *bool) {
+	if value == "" || (*dst != nil && **dst == value) {
+		return
+	}
+	*dst = strPtr(value)
+	*changed = true
+}
+
+func overwriteCSVImportDate(dst **time.Time, value *time.Time, changed *bool) {
+	if value == nil || (*dst != nil && (*dst).Equal(*value)) {
+		return
+	}
+	*dst = value
+	*changed = true
+}
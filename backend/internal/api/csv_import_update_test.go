@@ -0,0 +1,64 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestOverwriteCSVImportContactFieldsKeepsEmptyCells(t *testing.T) {
+	oldBirth := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	newBirth := time.Date(1991, 3, 4, 0, 0, 0, 0, time.UTC)
+	contact := &domain.Contact{
+		Name:       strPtr("Ana"),
+		CustomName: strPtr("Ana VIP"),
+		LastName:   strPtr("Pérez"),
+		Email:      strPtr("ana@old.pe"),
+		Company:    strPtr("Acme"),
+		Phone:      strPtr("51999888777"),
+		BirthDate:  &oldBirth,
+	}
+	record := csvImportRecord{Name: "Ana María", Email: "ana@new.pe", Phone: "51111222333", BirthDate: &newBirth}
+
+	if !overwriteCSVImportContactFields(contact, record) {
+		t.Fatal("expected a change")
+	}
+	if *contact.Name != "Ana María" || *contact.Email != "ana@new.pe" || !contact.BirthDate.Equal(newBirth) {
+		t.Fatalf("incoming values not applied: %+v", contact)
+	}
+	if *contact.LastName != "Pérez" || *contact.Company != "Acme" {
+		t.Fatalf("empty cells must not clear data: %+v", contact)
+	}
+	if *contact.CustomName != "Ana VIP" || *contact.Phone != "51999888777" {
+		t.Fatalf("custom name and phone must be preserved: %+v", contact)
+	}
+	if overwriteCSVImportContactFields(contact, record) {
+		t.Fatal("re-applying the same row should be a no-op")
+	}
+}
+
+func TestOverwriteCSVImportLeadFields(t *testing.T) {
+	lead := &domain.Lead{Title: "Oportunidad", Notes: strPtr("vieja")}
+	if overwriteCSVImportLeadFields(lead, csvImportRecord{}) {
+		t.Fatal("empty row should not change the lead")
+	}
+	if !overwriteCSVImportLeadFields(lead, csvImportRecord{LeadTitle: "Curso", Notes: "nueva"}) {
+		t.Fatal("expected a change")
+	}
+	if lead.Title != "Curso" || *lead.Notes != "nueva" {
+		t.Fatalf("lead = %+v", lead)
+	}
+}
+
+func TestParseCSVImportExistingMode(t *testing.T) {
+	for value, want := range map[string]string{"": csvImportExistingSkip, "skip": csvImportExistingSkip, "update_existing": csvImportExistingUpdate} {
+		got, err := parseCSVImportExistingMode(value)
+		if err != nil || got != want {
+			t.Fatalf("parseCSVImportExistingMode(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := parseCSVImportExistingMode("overwrite"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
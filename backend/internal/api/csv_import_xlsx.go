@@ -0,0 +1,381 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Excel uploads are read sheet by sheet and re-encoded as CSV, so the
+// importer keeps a single parsing path for both formats.
+
+const xlsxMaxPartSize = 200 << 20
+
+var (
+	oleMagic               = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	oleEncryptedPackageTag = utf16LE("EncryptedPackage")
+	errXLSXEncrypted       = errors.New("el archivo Excel está protegido con contraseña; quita la protección y vuelve a subirlo")
+	errXLSLegacy           = errors.New("el formato .xls no está soportado; guarda el archivo como .xlsx o .csv")
+)
+
+// isXLSXUpload reports whether the upload is an Excel workbook, judged by
+// extension, content type or file signature.
+func isXLSXUpload(fileName, contentType string, raw []byte) bool {
+	lowerName := strings.ToLower(fileName)
+	if strings.HasSuffix(lowerName, ".xlsx") || strings.HasSuffix(lowerName, ".xls") {
+		return true
+	}
+	if strings.Contains(contentType, "spreadsheetml") || strings.Contains(contentType, "ms-excel") {
+		return true
+	}
+	return bytes.HasPrefix(raw, oleMagic) || bytes.HasPrefix(raw, []byte("PK\x03\x04"))
+}
+
+// xlsxToCSV converts one worksheet to CSV. sheet selects it by name or by
+// 1-based position; empty means the first sheet.
+func xlsxToCSV(raw []byte, sheet string) ([]byte, error) {
+	rows, err := readXLSXRows(raw, sheet)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		for i, cell := range rows[0] {
+			rows[0][i] = strings.Join(strings.Fields(cell), " ")
+		}
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type xlsxWorkbook struct {
+	WorkbookPr struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []xlsxSheetRef `xml:"sheets>sheet"`
+}
+
+type xlsxSheetRef struct {
+	Name string `xml:"name,attr"`
+	RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		T    string `xml:"t"`
+		Runs []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Style  int    `xml:"s,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				T    string `xml:"t"`
+				Runs []struct {
+					T string `xml:"t"`
+				} `xml:"r"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSXRows(raw []byte, sheet string) ([][]string, error) {
+	if bytes.HasPrefix(raw, oleMagic) {
+		if bytes.Contains(raw, oleEncryptedPackageTag) {
+			return nil, errXLSXEncrypted
+		}
+		return nil, errXLSLegacy
+	}
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el archivo Excel")
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(parts, "xl/workbook.xml", &workbook, true); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("el archivo Excel no tiene hojas")
+	}
+	sheetIdx, err := selectXLSXSheet(workbook.Sheets, sheet)
+	if err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := decodeXLSXPart(parts, "xl/_rels/workbook.xml.rels", &rels, true); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Items {
+		if rel.ID == workbook.Sheets[sheetIdx].RID {
+			sheetPath = resolveXLSXTarget(rel.Target)
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("no se encontró la hoja %q en el archivo Excel", workbook.Sheets[sheetIdx].Name)
+	}
+
+	var shared xlsxSharedStrings
+	if err := decodeXLSXPart(parts, "xl/sharedStrings.xml", &shared, false); err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(shared.Items))
+	for i, si := range shared.Items {
+		strs[i] = si.T
+		for _, run := range si.Runs {
+			strs[i] += run.T
+		}
+	}
+	var styles xlsxStyles
+	if err := decodeXLSXPart(parts, "xl/styles.xml", &styles, false); err != nil {
+		return nil, err
+	}
+	dateStyles := xlsxDateStyles(styles)
+
+	var data xlsxSheet
+	if err := decodeXLSXPart(parts, sheetPath, &data, true); err != nil {
+		return nil, err
+	}
+	date1904 := workbook.WorkbookPr.Date1904 == "1" || workbook.WorkbookPr.Date1904 == "true"
+	rows := make([][]string, 0, len(data.Rows))
+	for _, row := range data.Rows {
+		values := []string{}
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				col = xlsxColumnIndex(cell.Ref)
+			}
+			if col < 0 {
+				continue
+			}
+			var value string
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+				if err == nil && idx >= 0 && idx < len(strs) {
+					value = strs[idx]
+				}
+			case "inlineStr":
+				value = cell.Inline.T
+				for _, run := range cell.Inline.Runs {
+					value += run.T
+				}
+			case "b":
+				value = "FALSE"
+				if cell.Value == "1" {
+					value = "TRUE"
+				}
+			case "str", "e":
+				value = cell.Value
+			default:
+				value = xlsxNumber(cell.Value, dateStyles[cell.Style], date1904)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = value
+		}
+		if !rowIsEmpty(values) {
+			rows = append(rows, values)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("la hoja %q está vacía", workbook.Sheets[sheetIdx].Name)
+	}
+	return rows, nil
+}
+
+func decodeXLSXPart(parts map[string]*zip.File, name string, v interface{}, required bool) error {
+	f, ok := parts[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("el archivo Excel está incompleto (falta %s)", name)
+		}
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el archivo Excel")
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, xlsxMaxPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("no se pudo leer el archivo Excel (%s)", name)
+	}
+	return nil
+}
+
+func selectXLSXSheet(sheets []xlsxSheetRef, sheet string) (int, error) {
+	sheet = strings.TrimSpace(sheet)
+	if sheet == "" {
+		return 0, nil
+	}
+	for i, s := range sheets {
+		if strings.EqualFold(strings.TrimSpace(s.Name), sheet) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(sheet); err == nil && n >= 1 && n <= len(sheets) {
+		return n - 1, nil
+	}
+	names := make([]string, len(sheets))
+	for i, s := range sheets {
+		names[i] = s.Name
+	}
+	return 0, fmt.Errorf("no existe la hoja %q; hojas disponibles: %s", sheet, strings.Join(names, ", "))
+}
+
+func resolveXLSXTarget(target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join("xl", target)
+}
+
+// xlsxMaxColumns is the sheet width limit of Excel (column XFD).
+const xlsxMaxColumns = 16384
+
+// xlsxColumnIndex turns the letters of a cell reference ("AB12") into a
+// zero-based column index, or -1 when there are none or the column lies
+// past XFD.
+func xlsxColumnIndex(ref string) int {
+	idx := 0
+	n := 0
+	for _, ch := range ref {
+		if ch >= 'a' && ch <= 'z' {
+			ch -= 'a' - 'A'
+		}
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		idx = idx*26 + int(ch-'A'+1)
+		if idx > xlsxMaxColumns {
+			return -1
+		}
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return idx - 1
+}
+
+// xlsxDateStyles marks the cell styles whose number format is a date, so
+// serial numbers can be written as dates the importer understands.
+func xlsxDateStyles(styles xlsxStyles) map[int]bool {
+	custom := map[int]string{}
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = f.Code
+	}
+	dates := map[int]bool{}
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58) {
+			dates[i] = true
+			continue
+		}
+		if code, ok := custom[id]; ok && isXLSXDateFormat(code) {
+			dates[i] = true
+		}
+	}
+	return dates
+}
+
+func isXLSXDateFormat(code string) bool {
+	var b strings.Builder
+	inQuote, inBracket, escaped := false, false, false
+	for _, ch := range code {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case ch == '[':
+			inBracket = true
+		case ch == ']':
+			inBracket = false
+		case inBracket:
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "ymdh")
+}
+
+func xlsxNumber(value string, isDate, date1904 bool) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	if isDate {
+		base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+		if date1904 {
+			base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		days, frac := math.Modf(f)
+		t := base.AddDate(0, 0, int(days)).Add(time.Duration(math.Round(frac*86400)) * time.Second)
+		if frac == 0 {
+			return t.Format("2006-01-02")
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+	if strings.ContainsAny(value, "eE") {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return value
+}
+
+func utf16LE(s string) []byte {
+	out := make([]byte, 0, len(s)*2)
+	for _, ch := range s {
+		out = append(out, byte(ch), byte(ch>>8))
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testXLSX(t *testing.T) []byte {
+	t.Helper()
+	raw, err := zipParts(map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Leads" sheetId="1" r:id="rId1"/><sheet name="Otros" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Nombre</t></si><si><r><t>Tel</t></r><r><t>éfono</t></r></si><si><t>Ana</t></si></sst>`,
+		"xl/styles.xml":        `<styleSheet><numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/></numFmts><cellXfs><xf numFmtId="0"/><xf numFmtId="164"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="inlineStr"><is><t>Fecha
+nacimiento</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>5.1999888777E10</v></c><c r="D2" s="1"><v>32874</v></c></row>
+<row r="3"></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>otra</t></is></c></row></sheetData></worksheet>`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestXLSXToCSVReadsFirstSheet(t *testing.T) {
+	out, err := xlsxToCSV(testXLSX(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Nombre", "Teléfono", "", "Fecha nacimiento"},
+		{"Ana", "51999888777", "", "1990-01-01"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("records = %q, want %q", records, want)
+	}
+}
+
+func TestXLSXToCSVSelectsSheet(t *testing.T) {
+	for _, sheet := range []string{"otros", "2"} {
+		out, err := xlsxToCSV(testXLSX(t), sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(out)) != "otra" {
+			t.Fatalf("sheet %q = %q", sheet, out)
+		}
+	}
+	if _, err := xlsxToCSV(testXLSX(t), "Ventas"); err == nil || !strings.Contains(err.Error(), "Leads, Otros") {
+		t.Fatalf("unknown sheet error = %v", err)
+	}
+}
+
+func TestXLSXToCSVRejectsEncryptedWorkbook(t *testing.T) {
+	raw := append(append([]byte{}, oleMagic...), utf16LE("EncryptedPackage")...)
+	if _, err := xlsxToCSV(raw, ""); err != errXLSXEncrypted {
+		t.Fatalf("err = %v, want errXLSXEncrypted", err)
+	}
+	if !isXLSXUpload("datos.xlsx", "", nil) || isXLSXUpload("datos.csv", "text/csv", []byte("a,b")) {
+		t.Fatal("unexpected upload detection")
+	}
+}
+
+func TestXLSXColumnIndexBounds(t *testing.T) {
+	cases := map[string]int{"A1": 0, "ab12": 27, "XFD1": 16383, "XFE1": -1, "AAAAAAAAAAAAAAAA1": -1, "12": -1}
+	for ref, want := range cases {
+		if got := xlsxColumnIndex(ref); got != want {
+			t.Fatalf("xlsxColumnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5"
 
 	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
 	"github.com/naperu/clarin/internal/service"
 )
 
@@ -229,8 +230,10 @@ func dashboardHasPermission(claims *service.JWTClaims, module string) bool {
 	return false
 }
 
-func dashboardClaimsAreAdmin(claims *service.JWTClaims) bool {
-	return claims != nil && (claims.IsAdmin || claims.IsSuperAdmin || claims.Role == domain.RoleAdmin || claims.Role == domain.RoleSuperAdmin)
+// dashboardCallerIsAdmin also checks the caller's current role in the
+// account, since a promotion isn't reflected in tokens issued before it.
+func (s *Server) dashboardCallerIsAdmin(c *fiber.Ctx) bool {
+	return s.callerHasRole(c, domain.RoleAdmin)
 }
 
 func (s *Server) handleGetDashboardSummary(c *fiber.Ctx) error {
@@ -247,15 +250,7 @@ func (s *Server) handleGetDashboardSummary(c *fiber.Ctx) error {
 		})
 	}
 
-	isAdmin := dashboardClaimsAreAdmin(claims)
-	if !isAdmin {
-		var currentRole string
-		if roleErr := s.repos.DB().QueryRow(c.Context(), `
-			SELECT role FROM user_accounts WHERE user_id=$1 AND account_id=$2
-		`, claims.UserID, claims.AccountID).Scan(&currentRole); roleErr == nil {
-			isAdmin = currentRole == domain.RoleAdmin || currentRole == domain.RoleSuperAdmin
-		}
-	}
+	isAdmin := s.dashboardCallerIsAdmin(c)
 	sections := dashboardSections{
 		Leads:   isAdmin || dashboardHasPermission(claims, domain.PermLeads),
 		Chats:   isAdmin || dashboardHasPermission(claims, domain.PermChats),
@@ -316,13 +311,13 @@ func (s *Server) getDashboardLeadSummary(c *fiber.Ctx, accountID uuid.UUID, peri
 	var open, currentNew, previousNew, currentWon, previousWon, currentLost, previousLost int
 	err := s.repos.DB().QueryRow(c.Context(), `
 		SELECT
-			COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.is_archived=FALSE AND l.status='open'),
-			COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.created_at >= $2 AND l.created_at < $3),
-			COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.created_at >= $4 AND l.created_at < $5),
-			COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.is_archived=FALSE AND l.status='won' AND l.closed_at >= $2 AND l.closed_at < $3),
-			COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.is_archived=FALSE AND l.status='won' AND l.closed_at >= $4 AND l.closed_at < $5),
-			COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.is_archived=FALSE AND l.status='lost' AND l.closed_at >= $2 AND l.closed_at < $3),
-			COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.is_archived=FALSE AND l.status='lost' AND l.closed_at >= $4 AND l.closed_at < $5)
+			COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.is_archived=FALSE AND l.status='open'),
+			COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.created_at >= $2 AND l.created_at < $3),
+			COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.created_at >= $4 AND l.created_at < $5),
+			COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.is_archived=FALSE AND l.status='won' AND l.closed_at >= $2 AND l.closed_at < $3),
+			COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.is_archived=FALSE AND l.status='won' AND l.closed_at >= $4 AND l.closed_at < $5),
+			COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.is_archived=FALSE AND l.status='lost' AND l.closed_at >= $2 AND l.closed_at < $3),
+			COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.is_archived=FALSE AND l.status='lost' AND l.closed_at >= $4 AND l.closed_at < $5)
 		FROM leads l
 		JOIN contacts contact ON contact.id=l.contact_id AND contact.account_id=l.account_id
 		WHERE l.account_id=$1 AND l.contact_id IS NOT NULL
@@ -366,7 +361,7 @@ func (s *Server) getDashboardLeadSummary(c *fiber.Ctx, accountID uuid.UUID, peri
 			SELECT (l.created_at AT TIME ZONE $4)::date AS day, COUNT(*)::int AS new_count, 0::int AS won_count, 0::int AS lost_count
 			FROM leads l
 			JOIN contacts contact ON contact.id=l.contact_id AND contact.account_id=l.account_id
-			WHERE l.account_id=$1 AND l.contact_id IS NOT NULL AND l.deleted_at IS NULL AND l.created_at >= $2 AND l.created_at < $3
+			WHERE l.account_id=$1 AND l.contact_id IS NOT NULL AND `+repository.NotDeleted("l")+` AND l.created_at >= $2 AND l.created_at < $3
 			GROUP BY day
 			UNION ALL
 			SELECT (l.closed_at AT TIME ZONE $4)::date AS day, 0::int AS new_count,
@@ -374,7 +369,7 @@ func (s *Server) getDashboardLeadSummary(c *fiber.Ctx, accountID uuid.UUID, peri
 				COUNT(*) FILTER (WHERE l.status='lost')::int AS lost_count
 			FROM leads l
 			JOIN contacts contact ON contact.id=l.contact_id AND contact.account_id=l.account_id
-			WHERE l.account_id=$1 AND l.contact_id IS NOT NULL AND l.deleted_at IS NULL AND l.is_archived=FALSE
+			WHERE l.account_id=$1 AND l.contact_id IS NOT NULL AND `+repository.NotDeleted("l")+` AND l.is_archived=FALSE
 				AND l.status IN ('won','lost') AND l.closed_at >= $2 AND l.closed_at < $3
 			GROUP BY day
 		) activity
@@ -432,7 +427,7 @@ func (s *Server) getDashboardPipeline(c *fiber.Ctx, accountID uuid.UUID) (*dashb
 		SELECT ps.id, ps.name, COALESCE(ps.color, '#94a3b8'), COUNT(l.id) FILTER (WHERE contact.id IS NOT NULL)::int
 		FROM pipeline_stages ps
 		LEFT JOIN leads l ON l.stage_id=ps.id AND l.account_id=$1 AND l.pipeline_id=$2
-			AND l.deleted_at IS NULL AND l.is_archived=FALSE AND l.status='open'
+			AND `+repository.NotDeleted("l")+` AND l.is_archived=FALSE AND l.status='open'
 		LEFT JOIN contacts contact ON contact.id=l.contact_id AND contact.account_id=l.account_id
 		WHERE ps.pipeline_id=$2
 		GROUP BY ps.id, ps.name, ps.color, ps.position
@@ -458,7 +453,7 @@ func (s *Server) getDashboardPipeline(c *fiber.Ctx, accountID uuid.UUID) (*dashb
 		FROM leads l
 		JOIN contacts contact ON contact.id=l.contact_id AND contact.account_id=l.account_id
 		WHERE l.account_id=$1 AND l.pipeline_id=$2 AND l.stage_id IS NULL
-			AND l.contact_id IS NOT NULL AND l.deleted_at IS NULL AND l.is_archived=FALSE AND l.status='open'
+			AND l.contact_id IS NOT NULL AND `+repository.NotDeleted("l")+` AND l.is_archived=FALSE AND l.status='open'
 	`, accountID, pipeline.ID).Scan(&pipeline.UnassignedCount)
 	if err != nil {
 		return nil, err
@@ -72,3 +72,15 @@ func TestDashboardHasPermission(t *testing.T) {
 		t.Fatal("wildcard permission should grant every dashboard section")
 	}
 }
+
+func TestStatsSeriesDatesUsesLimaCalendarDays(t *testing.T) {
+	// 03:00 UTC is still the previous day in Lima.
+	now := time.Date(2026, time.July, 15, 3, 0, 0, 0, time.UTC)
+	dates, from := statsSeriesDates(now, 7)
+	if len(dates) != 7 || dates[0] != "2026-07-08" || dates[6] != "2026-07-14" {
+		t.Fatalf("dates = %v", dates)
+	}
+	if got, want := from.Format(time.RFC3339), "2026-07-08T00:00:00-05:00"; got != want {
+		t.Fatalf("from = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/whatsapp"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	deviceQRStreamTimeout   = 5 * time.Minute
+	deviceQRStreamKeepAlive = 15 * time.Second
+)
+
+// accountDevice loads the :id device and checks it belongs to the caller's
+// account, writing the error response when it does not.
+func (s *Server) accountDevice(c *fiber.Ctx) (*domain.Device, error) {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	deviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
+	}
+	device, err := s.services.Device.GetByID(c.Context(), deviceID)
+	if err != nil {
+		return nil, c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if device == nil || device.AccountID != accountID {
+		return nil, c.Status(404).JSON(fiber.Map{"success": false, "error": "Device not found"})
+	}
+	return device, nil
+}
+
+// handleGetDeviceQR renders the current pairing code as a PNG. ?size sets
+// the width in pixels (128-1024, default 256).
+func (s *Server) handleGetDeviceQR(c *fiber.Ctx) error {
+	device, err := s.accountDevice(c)
+	if device == nil {
+		return err
+	}
+	code := ""
+	if s.pool != nil {
+		code = s.pool.GetQRCodeRaw(device.ID)
+	}
+	if code == "" {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "El dispositivo no tiene un código QR activo"})
+	}
+	size := c.QueryInt("size", 256)
+	if size < 128 || size > 1024 {
+		size = 256
+	}
+	png, err := qrcode.Encode(code, qrcode.Medium, size)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo generar el código QR"})
+	}
+	c.Set("Content-Type", "image/png")
+	c.Set("Cache-Control", "no-store")
+	return c.Send(png)
+}
+
+// handleStreamDeviceQR is a server-sent event stream of pairing codes. Each
+// rotation is pushed as a "qr" event; a final "status" event is sent and the
+// stream closes once the device connects or pairing ends.
+func (s *Server) handleStreamDeviceQR(c *fiber.Ctx) error {
+	device, err := s.accountDevice(c)
+	if device == nil {
+		return err
+	}
+	if s.pool == nil {
+		return c.Status(503).JSON(fiber.Map{"success": false, "error": "WhatsApp no está disponible"})
+	}
+	updates, cancel, ok := s.pool.SubscribeQR(device.ID)
+	if !ok {
+		return c.Status(409).JSON(fiber.Map{"success": false, "error": "El dispositivo no está conectándose; inicia la conexión primero"})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		timeout := time.NewTimer(deviceQRStreamTimeout)
+		keepAlive := time.NewTicker(deviceQRStreamKeepAlive)
+		defer timeout.Stop()
+		defer keepAlive.Stop()
+		for {
+			select {
+			case update := <-updates:
+				if err := writeDeviceQREvent(w, update); err != nil || w.Flush() != nil || update.Done() {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := w.WriteString(": ping\n\n"); err != nil || w.Flush() != nil {
+					return
+				}
+			case <-timeout.C:
+				_ = writeSSEEvent(w, "timeout", fiber.Map{"status": "timeout"})
+				_ = w.Flush()
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func writeDeviceQREvent(w io.Writer, update whatsapp.QRUpdate) error {
+	if update.Done() {
+		return writeSSEEvent(w, "status", fiber.Map{"status": update.Status})
+	}
+	return writeSSEEvent(w, "qr", fiber.Map{"code": update.Code, "qr_code": update.Image, "status": update.Status})
+}
+
+func writeSSEEvent(w io.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/whatsapp"
+)
+
+func TestWriteDeviceQREvent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeDeviceQREvent(&buf, whatsapp.QRUpdate{Code: "2@abc", Image: "data:image/png;base64,x", Status: domain.DeviceStatusConnecting}); err != nil {
+		t.Fatal(err)
+	}
+	want := "event: qr\ndata: {\"code\":\"2@abc\",\"qr_code\":\"data:image/png;base64,x\",\"status\":\"connecting\"}\n\n"
+	if buf.String() != want {
+		t.Fatalf("qr event = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := writeDeviceQREvent(&buf, whatsapp.QRUpdate{Status: domain.DeviceStatusConnected}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "event: status\ndata: {\"status\":\"connected\"}\n\n"; buf.String() != want {
+		t.Fatalf("status event = %q, want %q", buf.String(), want)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/repository"
 	"github.com/naperu/clarin/internal/service"
 )
 
@@ -39,12 +40,12 @@ func (s *Server) quickLeadDataQualityScoped(ctx context.Context, accountID uuid.
 		(SELECT COUNT(*) FROM (
 			SELECT regexp_replace(COALESCE(dc.phone,''),'\D','','g') normalized_phone
 			FROM leads dl JOIN contacts dc ON dc.id=dl.contact_id AND dc.account_id=dl.account_id
-			WHERE dl.account_id=$1 AND dl.deleted_at IS NULL`+duplicateWhere+`
+			WHERE dl.account_id=$1 AND `+repository.NotDeleted("dl")+duplicateWhere+`
 			  AND NULLIF(regexp_replace(COALESCE(dc.phone,''),'\D','','g'),'') IS NOT NULL
 			GROUP BY normalized_phone HAVING COUNT(DISTINCT dc.id)>1
 		) duplicates)
 		FROM leads l LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
-		WHERE l.account_id=$1 AND l.deleted_at IS NULL`+pipelineWhere, args...).Scan(&missingPhone, &missingEmail, &missingContact, &duplicateGroups)
+		WHERE l.account_id=$1 AND `+repository.NotDeleted("l")+pipelineWhere, args...).Scan(&missingPhone, &missingEmail, &missingContact, &duplicateGroups)
 	result["missing_phone"], result["missing_email"] = missingPhone, missingEmail
 	result["missing_contact"], result["duplicate_phone_groups"] = missingContact, duplicateGroups
 	return result, err
@@ -138,7 +139,7 @@ func (s *Server) quickFollowupPriority(ctx context.Context, accountID uuid.UUID,
 			FROM tasks t
 			WHERE t.account_id=l.account_id AND (t.lead_id=l.id OR (t.lead_id IS NULL AND t.contact_id=l.contact_id))
 		) task_stats ON TRUE
-		WHERE l.account_id=$1 AND l.deleted_at IS NULL AND NOT l.is_archived AND l.status='open'
+		WHERE l.account_id=$1 AND `+repository.NotDeleted("l")+` AND NOT l.is_archived AND l.status='open'
 		  AND NOT COALESCE(c.do_not_contact,false)`+pipelineWhere+`
 		ORDER BY priority_score DESC,
 		         COALESCE(GREATEST(chat_stats.last_message_at,interaction_stats.last_interaction_at),l.created_at) ASC,
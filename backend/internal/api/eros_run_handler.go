@@ -879,12 +879,12 @@ func (s *Server) quickLeadCycleSummary(ctx context.Context, accountID uuid.UUID,
 	result := map[string]any{"as_of": time.Now().UTC()}
 	var open, won, lost, archived, trash, blocked int
 	err := s.repos.DB().QueryRow(ctx, `SELECT
-		COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.status='open' AND NOT l.is_archived),
-		COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.status='won' AND NOT l.is_archived),
-		COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.status='lost' AND NOT l.is_archived),
-		COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND l.is_archived),
+		COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.status='open' AND NOT l.is_archived),
+		COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.status='won' AND NOT l.is_archived),
+		COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.status='lost' AND NOT l.is_archived),
+		COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND l.is_archived),
 		COUNT(*) FILTER (WHERE l.deleted_at IS NOT NULL),
-		COUNT(*) FILTER (WHERE l.deleted_at IS NULL AND COALESCE(c.do_not_contact,false))
+		COUNT(*) FILTER (WHERE `+repository.NotDeleted("l")+` AND COALESCE(c.do_not_contact,false))
 		FROM leads l LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id WHERE `+where, args...).Scan(&open, &won, &lost, &archived, &trash, &blocked)
 	result["open"], result["won"], result["lost"] = open, won, lost
 	result["archived"], result["trash"], result["blocked"] = archived, trash, blocked
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
+	"github.com/naperu/clarin/internal/ws"
+)
+
+const (
+	// maxEventParticipantImportRows caps one participant CSV upload.
+	maxEventParticipantImportRows = 5000
+	// eventParticipantImportBatch matches the per-request limit of the
+	// bulk participant endpoint.
+	eventParticipantImportBatch = 500
+)
+
+// eventParticipantCSVRow is one usable row of a participant import.
+type eventParticipantCSVRow struct {
+	Row      int
+	Name     string
+	LastName string
+	Phone    string
+	Email    string
+}
+
+// eventParticipantImportIssue reports a row that did not become a participant.
+type eventParticipantImportIssue struct {
+	Row    int    `json:"row"`
+	Phone  string `json:"phone,omitempty"`
+	Email  string `json:"email,omitempty"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// parseEventParticipantCSV reads participant rows using the same header
+// detection (or column_mapping) as the lead/contact import. Rows without a
+// valid phone or email, and rows repeating a phone or email seen earlier in
+// the file, are returned as issues.
+func parseEventParticipantCSV(rawBytes []byte, mapping csvImportColumnMapping) ([]eventParticipantCSVRow, []eventParticipantImportIssue, error) {
+	rawContent := strings.TrimPrefix(string(rawBytes), "\ufeff")
+	headerLine, dataContent := splitCSVHeader(rawContent)
+	if strings.TrimSpace(headerLine) == "" || strings.TrimSpace(dataContent) == "" {
+		return nil, nil, fmt.Errorf("CSV file must have at least a header and one data row")
+	}
+	headers, err := readCSVRecord(headerLine, detectCSVSeparator(headerLine))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse CSV headers")
+	}
+	firstDataRow, firstDataLine := firstCSVDataRow(dataContent)
+	if len(firstDataRow) == 0 {
+		return nil, nil, fmt.Errorf("CSV file must have at least one data row")
+	}
+
+	colMap := make(map[string]int)
+	for i, h := range headers {
+		if key := normalizeImportHeader(h); key != "" {
+			colMap[key] = i
+		}
+	}
+	var phoneCols []int
+	if mapping != nil {
+		if err := mapping.validate(headers, firstDataRow); err != nil {
+			return nil, nil, err
+		}
+		phoneCols = []int{mapping["phone"]}
+	} else {
+		phoneCols = importPhoneColumns(headers, colMap, firstDataRow)
+	}
+	emailCols := mapping.cols("email", importEmailColumns(colMap))
+	if len(phoneCols) == 0 && len(emailCols) == 0 {
+		return nil, nil, fmt.Errorf("CSV must have a phone/telefono/celular or email column")
+	}
+	nameCol := mapping.col("name", findCol(colMap, "nombre completo", "contacto principal", "nombre contacto", "nombre de contacto", "name", "nombre", "nombre_completo"))
+	lastNameCol := mapping.col("last_name", findCol(colMap, "last_name", "apellido", "apellidos"))
+
+	reader := csv.NewReader(strings.NewReader(dataContent))
+	reader.Comma = detectCSVSeparator(firstDataLine)
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	var rows []eventParticipantCSVRow
+	var issues []eventParticipantImportIssue
+	seenPhones := map[string]bool{}
+	seenEmails := map[string]bool{}
+	rowNum := 1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNum++
+		if err != nil {
+			issues = append(issues, eventParticipantImportIssue{Row: rowNum, Code: "invalid_row", Reason: "No se pudo leer la fila"})
+			continue
+		}
+		if rowIsEmpty(row) {
+			continue
+		}
+		if len(rows)+len(issues) >= maxEventParticipantImportRows {
+			return nil, nil, fmt.Errorf("el archivo supera el máximo de %d filas", maxEventParticipantImportRows)
+		}
+		record := eventParticipantCSVRow{
+			Row:      rowNum,
+			Name:     cleanCSVValue(safeCol(row, nameCol)),
+			LastName: cleanCSVValue(safeCol(row, lastNameCol)),
+			Phone:    firstValidImportPhone(row, phoneCols),
+			Email:    strings.ToLower(firstValidImportEmail(row, emailCols)),
+		}
+		if len(record.Phone) < 6 {
+			record.Phone = ""
+		}
+		if record.Phone == "" && record.Email == "" {
+			issues = append(issues, eventParticipantImportIssue{Row: rowNum, Code: "invalid_contact", Reason: "Sin teléfono ni email válido"})
+			continue
+		}
+		if (record.Phone != "" && seenPhones[record.Phone]) || (record.Email != "" && seenEmails[record.Email]) {
+			issues = append(issues, eventParticipantImportIssue{Row: rowNum, Phone: record.Phone, Email: record.Email, Code: "duplicate_in_file", Reason: "Teléfono o email repetido dentro del archivo"})
+			continue
+		}
+		if record.Phone != "" {
+			seenPhones[record.Phone] = true
+		}
+		if record.Email != "" {
+			seenEmails[record.Email] = true
+		}
+		if record.Name == "" {
+			record.Name = record.Phone
+			if record.Name == "" {
+				record.Name = record.Email
+			}
+		}
+		rows = append(rows, record)
+	}
+	return rows, issues, nil
+}
+
+func (r eventParticipantCSVRow) participant() *domain.EventParticipant {
+	p := &domain.EventParticipant{Name: r.Name}
+	if r.LastName != "" {
+		p.LastName = strPtr(r.LastName)
+	}
+	if r.Phone != "" {
+		p.Phone = strPtr(r.Phone)
+	}
+	if r.Email != "" {
+		p.Email = strPtr(r.Email)
+	}
+	return p
+}
+
+// handleImportEventParticipants adds participants from a CSV or Excel upload.
+// Each row is resolved to an existing contact by phone/email, or a new one is
+// created, and added through the same strict path as the bulk endpoint.
+// Contacts already active in the event are reported as duplicates.
+func (s *Server) handleImportEventParticipants(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	userID := c.Locals("user_id").(uuid.UUID)
+	eventID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid event ID"})
+	}
+	event, eventErr := s.services.Event.GetByID(c.Context(), eventID)
+	if eventErr != nil || event == nil || event.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Event not found"})
+	}
+	if eventMembershipFrozen(event.Status) {
+		return writeEventMembershipError(c, repository.ErrEventMembershipFrozen)
+	}
+	hasRules, err := s.eventHasMembershipRules(c.Context(), event)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if hasRules {
+		return c.Status(422).JSON(fiber.Map{
+			"success": false,
+			"code":    "EVENT_CONTACT_REQUIRED_FOR_RULED_EVENT",
+			"error":   "En eventos con reglas los participantes provienen de las reglas; no se puede importar un archivo",
+		})
+	}
+	_, rawBytes, status, errMsg := readCSVUploadFile(c)
+	if errMsg != "" {
+		return c.Status(status).JSON(fiber.Map{"success": false, "error": errMsg})
+	}
+	mapping, err := parseCSVImportColumnMapping(c.FormValue("column_mapping"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	rows, issues, err := parseEventParticipantCSV(rawBytes, mapping)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	// Each batch commits on its own. When one fails after earlier batches
+	// committed, the import stops and reports what was added plus the rows
+	// left unprocessed, rather than an error for a half-applied file.
+	var total repository.EventParticipantAddSummary
+	notProcessed := 0
+	for start := 0; start < len(rows); start += eventParticipantImportBatch {
+		end := start + eventParticipantImportBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+		participants := make([]*domain.EventParticipant, len(batch))
+		for i, row := range batch {
+			participants[i] = row.participant()
+		}
+		summary, err := s.services.Event.AddParticipantsStrict(c.Context(), accountID, eventID, participants, &userID)
+		if err != nil {
+			if start == 0 {
+				return writeEventMembershipError(c, err)
+			}
+			log.Printf("[EVENTS] participant import for event %s stopped at row %d: %v", eventID, batch[0].Row, err)
+			for _, row := range rows[start:] {
+				issues = append(issues, eventParticipantImportIssue{Row: row.Row, Phone: row.Phone, Email: row.Email, Code: "not_processed", Reason: "La importación se interrumpió antes de esta fila"})
+			}
+			notProcessed = len(rows) - start
+			break
+		}
+		total.Created += summary.Created
+		total.Reactivated += summary.Reactivated
+		// AddStrict returns one result per participant, in order.
+		for i, result := range summary.Results {
+			if i >= len(batch) {
+				break
+			}
+			switch {
+			case result.Outcome == repository.ParticipantAddRejected && result.Code == "DUPLICATE_CONTACT":
+				issues = append(issues, eventParticipantImportIssue{Row: batch[i].Row, Phone: batch[i].Phone, Email: batch[i].Email, Code: "duplicate_in_file", Reason: "El contacto está repetido dentro del archivo"})
+			case result.Outcome == repository.ParticipantAddRejected:
+				issues = append(issues, eventParticipantImportIssue{Row: batch[i].Row, Phone: batch[i].Phone, Email: batch[i].Email, Code: strings.ToLower(result.Code), Reason: result.Error})
+			case result.Outcome == repository.ParticipantAddAlreadyActive:
+				issues = append(issues, eventParticipantImportIssue{Row: batch[i].Row, Phone: batch[i].Phone, Email: batch[i].Email, Code: "already_participant", Reason: "Ya es participante del evento"})
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Row < issues[j].Row })
+	duplicates, invalid := 0, 0
+	for _, issue := range issues {
+		switch issue.Code {
+		case "duplicate_in_file", "already_participant":
+			duplicates++
+		case "not_processed":
+			// counted by notProcessed
+		default:
+			invalid++
+		}
+	}
+	if total.Changed() > 0 {
+		s.invalidateEventsCache(accountID)
+		if s.hub != nil {
+			s.hub.BroadcastToAccount(accountID, ws.EventEventParticipantUpdate, map[string]interface{}{
+				"event_id":    eventID.String(),
+				"action":      "membership_imported",
+				"created":     total.Created,
+				"reactivated": total.Reactivated,
+			})
+		}
+	}
+	result := fiber.Map{
+		"success":       true,
+		"added":         total.Changed(),
+		"created":       total.Created,
+		"reactivated":   total.Reactivated,
+		"duplicates":    duplicates,
+		"skipped":       invalid,
+		"not_processed": notProcessed,
+		"partial":       notProcessed > 0,
+		"issues":        limitEventParticipantImportIssues(issues),
+	}
+	if notProcessed > 0 {
+		result["error"] = fmt.Sprintf("La importación se interrumpió; %d filas no se procesaron", notProcessed)
+	}
+	return c.JSON(result)
+}
+
+// limitEventParticipantImportIssues caps the issues returned for large files;
+// the counts always cover every row.
+func limitEventParticipantImportIssues(issues []eventParticipantImportIssue) []eventParticipantImportIssue {
+	if issues == nil {
+		return []eventParticipantImportIssue{}
+	}
+	if len(issues) > csvImportPreviewRowLimit {
+		return issues[:csvImportPreviewRowLimit]
+	}
+	return issues
+}
@@ -0,0 +1,40 @@
+package api
+
+import "testing"
+
+func TestParseEventParticipantCSV(t *testing.T) {
+	raw := []byte("Nombre,Apellido,Celular,Email\n" +
+		"Ana,Pérez,999 888 777,ana@example.com\n" +
+		"Luis,,51999888777,\n" +
+		",,,luis@example.com\n" +
+		"Sin datos,,,\n" +
+		"Rosa,,,ANA@example.com\n")
+	rows, issues, err := parseEventParticipantCSV(raw, nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v, want 2", rows)
+	}
+	if rows[0].Name != "Ana" || rows[0].LastName != "Pérez" || rows[0].Phone == "" || rows[0].Email != "ana@example.com" {
+		t.Errorf("first row = %+v", rows[0])
+	}
+	if rows[1].Row != 4 || rows[1].Name != "luis@example.com" || rows[1].Phone != "" {
+		t.Errorf("email-only row = %+v, want name falling back to the email", rows[1])
+	}
+	want := map[int]string{3: "duplicate_in_file", 5: "invalid_contact", 6: "duplicate_in_file"}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %+v, want %d", issues, len(want))
+	}
+	for _, issue := range issues {
+		if want[issue.Row] != issue.Code {
+			t.Errorf("row %d code = %q, want %q", issue.Row, issue.Code, want[issue.Row])
+		}
+	}
+}
+
+func TestParseEventParticipantCSVRequiresPhoneOrEmail(t *testing.T) {
+	if _, _, err := parseEventParticipantCSV([]byte("Nombre,Ciudad\nAna,Lima\n"), nil); err == nil {
+		t.Fatal("expected an error for a file without phone or email columns")
+	}
+}
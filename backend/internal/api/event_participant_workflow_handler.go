@@ -182,6 +182,7 @@ func (s *Server) handleGetEventParticipantCandidates(c *fiber.Ctx) error {
 	filter := repository.EventParticipantCandidateFilter{
 		Search:   strings.TrimSpace(c.Query("search")),
 		TagIDs:   tagIDs,
+		TagMatch: parseTagMatch(c),
 		HasPhone: hasPhone,
 		Limit:    c.QueryInt("limit", 50),
 		Offset:   c.QueryInt("offset", 0),
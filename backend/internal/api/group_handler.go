@@ -0,0 +1,89 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// handleListGroups returns the stored WhatsApp groups of the account.
+// ?device_id limits the list to the groups seen by one device. Messages are
+// sent to a group through the regular send endpoint with its JID as "to".
+func (s *Server) handleListGroups(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var deviceID *uuid.UUID
+	if raw := strings.TrimSpace(c.Query("device_id")); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
+		}
+		deviceID = &id
+	}
+	groups, err := s.repos.Group.ListByAccount(c.Context(), accountID, deviceID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "groups": groups})
+}
+
+// handleSyncDeviceGroups reloads the metadata of every group the device
+// belongs to. Groups are also synced automatically when a device connects.
+func (s *Server) handleSyncDeviceGroups(c *fiber.Ctx) error {
+	device, err := s.accountDevice(c)
+	if device == nil {
+		return err
+	}
+	if isCloudAPIDevice(device) {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Este canal usa WhatsApp API Oficial y no admite grupos"})
+	}
+	if s.pool == nil {
+		return c.Status(503).JSON(fiber.Map{"success": false, "error": "WhatsApp no está disponible"})
+	}
+	synced, err := s.pool.SyncGroups(c.Context(), device.ID)
+	if err != nil {
+		return c.Status(409).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	s.invalidateChatCaches(device.AccountID, nil)
+	return c.JSON(fiber.Map{"success": true, "synced": synced})
+}
+
+// handleGetChatParticipants lists the members of a group chat with their
+// matching contact names. ?refresh=true reloads them from WhatsApp.
+func (s *Server) handleGetChatParticipants(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	chatID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid chat ID"})
+	}
+	chat, err := s.services.Chat.GetByID(c.Context(), chatID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if !chatBelongsToAccount(chat, accountID) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+	}
+	if !strings.HasSuffix(chat.JID, "@g.us") {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "El chat no es un grupo"})
+	}
+	if chat.DeviceID == nil {
+		return c.Status(409).JSON(fiber.Map{"success": false, "error": "El grupo no tiene un dispositivo asociado"})
+	}
+
+	group, err := s.services.Chat.GetGroupParticipants(c.Context(), accountID, *chat.DeviceID, chat.JID, c.QueryBool("refresh", false))
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	participants := group.Participants
+	if participants == nil {
+		participants = []domain.WhatsAppGroupParticipant{}
+	}
+	return c.JSON(fiber.Map{
+		"success":           true,
+		"subject":           group.Subject,
+		"participant_count": group.ParticipantCount,
+		"participants":      participants,
+		"updated_at":        group.UpdatedAt,
+	})
+}
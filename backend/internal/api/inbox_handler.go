@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
+)
+
+const (
+	inboxDefaultLimit = 50
+	inboxMaxLimit     = 100
+	// inboxMaxAccounts bounds how many memberships feed the aggregated inbox
+	// and an aggregated WebSocket.
+	inboxMaxAccounts = 50
+)
+
+// inboxAccount is one membership the caller may read chats from, with the
+// permissions granted by the role held in that account.
+type inboxAccount struct {
+	AccountID   uuid.UUID
+	AccountName string
+	Role        string
+	Permissions []string
+}
+
+// inboxAccountsForUser resolves the accounts whose chats the user may see in
+// aggregated mode: memberships whose role grants chats and whose
+// subscription is active.
+func (s *Server) inboxAccountsForUser(ctx context.Context, claims *service.JWTClaims) ([]inboxAccount, error) {
+	memberships, err := s.services.Auth.GetUserAccounts(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]inboxAccount, 0, len(memberships))
+	for _, ua := range memberships {
+		if len(accounts) >= inboxMaxAccounts {
+			break
+		}
+		perms := ua.Permissions
+		if claims.IsSuperAdmin || ua.Role == domain.RoleAdmin || ua.Role == domain.RoleSuperAdmin {
+			perms = []string{domain.PermAll}
+		}
+		canReadChats := false
+		for _, p := range perms {
+			if p == domain.PermAll || p == domain.PermChats {
+				canReadChats = true
+				break
+			}
+		}
+		if !canReadChats {
+			continue
+		}
+		if !claims.IsSuperAdmin {
+			decision, accessErr := s.services.Subscription.CheckAccess(ctx, ua.AccountID)
+			if accessErr != nil {
+				log.Printf("[INBOX] subscription check failed for account %s: %v", ua.AccountID, accessErr)
+				continue
+			}
+			if decision != nil && !decision.Allowed {
+				continue
+			}
+		}
+		accounts = append(accounts, inboxAccount{
+			AccountID:   ua.AccountID,
+			AccountName: ua.AccountName,
+			Role:        ua.Role,
+			Permissions: perms,
+		})
+	}
+	return accounts, nil
+}
+
+// inboxAccountScope returns the assignment scope of the user in one of the
+// inbox accounts, nil when every chat there is visible. Unlike
+// assignmentScopeFor it reports lookup failures, so an account whose
+// restrict_to_assigned setting cannot be read is never shown unscoped.
+func (s *Server) inboxAccountScope(ctx context.Context, claims *service.JWTClaims, a inboxAccount) (*domain.AssignmentScope, error) {
+	if claims.IsSuperAdmin || roleAllowed(a.Role, domain.RoleAdmin) {
+		return nil, nil
+	}
+	restrict, seeUnassigned, err := s.repos.Account.GetAssignmentVisibility(ctx, a.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if !restrict {
+		return nil, nil
+	}
+	return &domain.AssignmentScope{UserID: claims.UserID, IncludeUnassigned: seeUnassigned}, nil
+}
+
+// handleGetMyInbox lists unread chats across every account the user belongs
+// to, labelled with the account they come from.
+func (s *Server) handleGetMyInbox(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*service.JWTClaims)
+	if !ok || claims == nil {
+		return c.Status(401).JSON(fiber.Map{"success": false, "error": "Unauthorized"})
+	}
+
+	limit := c.QueryInt("limit", inboxDefaultLimit)
+	if limit <= 0 {
+		limit = inboxDefaultLimit
+	}
+	if limit > inboxMaxLimit {
+		limit = inboxMaxLimit
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	accounts, err := s.inboxAccountsForUser(c.Context(), claims)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	accountIDs := make([]uuid.UUID, 0, len(accounts))
+	accountsList := make([]fiber.Map, 0, len(accounts))
+	scopes := make(map[uuid.UUID]*domain.AssignmentScope)
+	for _, a := range accounts {
+		accountIDs = append(accountIDs, a.AccountID)
+		scope, err := s.inboxAccountScope(c.Context(), claims, a)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+		if scope != nil {
+			scopes[a.AccountID] = scope
+		}
+		accountsList = append(accountsList, fiber.Map{
+			"account_id":   a.AccountID,
+			"account_name": a.AccountName,
+			"role":         a.Role,
+		})
+	}
+
+	chats, total, err := s.repos.Chat.ListUnreadAcrossAccounts(c.Context(), accountIDs, scopes, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"chats":    chats,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+		"accounts": accountsList,
+	})
+}
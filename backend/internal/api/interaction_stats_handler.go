@@ -0,0 +1,180 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/naperu/clarin/internal/service"
+)
+
+const (
+	interactionStatsDefaultDays = 30
+	interactionStatsMaxDays     = 366
+	// interactionOutcomeNone groups calls logged without an outcome.
+	interactionOutcomeNone = "none"
+)
+
+type interactionStatsPeriod struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type interactionAgentStats struct {
+	UserID       *uuid.UUID     `json:"user_id"`
+	Name         string         `json:"name"`
+	Total        int            `json:"total"`
+	ByType       map[string]int `json:"by_type"`
+	CallOutcomes map[string]int `json:"call_outcomes"`
+}
+
+type interactionStats struct {
+	Timezone     string                   `json:"timezone"`
+	Period       interactionStatsPeriod   `json:"period"`
+	Total        int                      `json:"total"`
+	ByType       map[string]int           `json:"by_type"`
+	CallOutcomes map[string]int           `json:"call_outcomes"`
+	Agents       []*interactionAgentStats `json:"agents"`
+}
+
+// interactionStatsRow is one (creator, type, outcome) group of the query.
+type interactionStatsRow struct {
+	UserID  *uuid.UUID
+	Name    string
+	Type    string
+	Outcome string
+	Count   int
+}
+
+// resolveInteractionStatsPeriod parses the from/to dates (YYYY-MM-DD, in the
+// dashboard timezone, both inclusive). It defaults to the last 30 days.
+func resolveInteractionStatsPeriod(fromRaw, toRaw string, now time.Time) (interactionStatsPeriod, error) {
+	location := dashboardLocation()
+	localNow := now.In(location)
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, location)
+
+	to := today
+	if raw := strings.TrimSpace(toRaw); raw != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", raw, location)
+		if err != nil {
+			return interactionStatsPeriod{}, errors.New("to debe tener el formato AAAA-MM-DD")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -(interactionStatsDefaultDays - 1))
+	if raw := strings.TrimSpace(fromRaw); raw != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", raw, location)
+		if err != nil {
+			return interactionStatsPeriod{}, errors.New("from debe tener el formato AAAA-MM-DD")
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return interactionStatsPeriod{}, errors.New("from no puede ser posterior a to")
+	}
+	end := to.AddDate(0, 0, 1)
+	if end.Sub(from) > interactionStatsMaxDays*24*time.Hour {
+		return interactionStatsPeriod{}, errors.New("el rango no puede superar un año")
+	}
+	return interactionStatsPeriod{From: from, To: end}, nil
+}
+
+// buildInteractionStats folds the grouped rows into account totals and one
+// entry per agent, busiest first. Outcomes are only tallied for calls.
+func buildInteractionStats(period interactionStatsPeriod, rows []interactionStatsRow) *interactionStats {
+	stats := &interactionStats{
+		Timezone:     dashboardTimezone,
+		Period:       period,
+		ByType:       map[string]int{},
+		CallOutcomes: map[string]int{},
+		Agents:       []*interactionAgentStats{},
+	}
+	agents := map[uuid.UUID]*interactionAgentStats{}
+	var unknown *interactionAgentStats
+	for _, row := range rows {
+		var agent *interactionAgentStats
+		if row.UserID == nil {
+			if unknown == nil {
+				unknown = &interactionAgentStats{ByType: map[string]int{}, CallOutcomes: map[string]int{}}
+				stats.Agents = append(stats.Agents, unknown)
+			}
+			agent = unknown
+		} else if agent = agents[*row.UserID]; agent == nil {
+			userID := *row.UserID
+			agent = &interactionAgentStats{UserID: &userID, Name: row.Name, ByType: map[string]int{}, CallOutcomes: map[string]int{}}
+			agents[userID] = agent
+			stats.Agents = append(stats.Agents, agent)
+		}
+		agent.Total += row.Count
+		agent.ByType[row.Type] += row.Count
+		stats.Total += row.Count
+		stats.ByType[row.Type] += row.Count
+		if row.Type == "call" {
+			outcome := row.Outcome
+			if outcome == "" {
+				outcome = interactionOutcomeNone
+			}
+			agent.CallOutcomes[outcome] += row.Count
+			stats.CallOutcomes[outcome] += row.Count
+		}
+	}
+	sort.SliceStable(stats.Agents, func(i, j int) bool {
+		if stats.Agents[i].Total != stats.Agents[j].Total {
+			return stats.Agents[i].Total > stats.Agents[j].Total
+		}
+		return stats.Agents[i].Name < stats.Agents[j].Name
+	})
+	return stats
+}
+
+// handleGetInteractionStats reports interactions logged in the caller's
+// account per agent, type and call outcome. Admins only.
+// GET /api/stats/interactions?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (s *Server) handleGetInteractionStats(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*service.JWTClaims)
+	if !ok || claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"success": false, "error": "Unauthorized"})
+	}
+	if !s.dashboardCallerIsAdmin(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"success": false, "error": "Solo los administradores pueden ver estas estadísticas"})
+	}
+	period, err := resolveInteractionStatsPeriod(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	// Reminder and check-in notes are logged by the system, not by agent work.
+	dbRows, err := s.repos.DB().Query(c.Context(), `
+		SELECT i.created_by, COALESCE(NULLIF(BTRIM(u.display_name),''), u.email, ''), i.type,
+			CASE WHEN i.type='call' THEN COALESCE(i.outcome,'') ELSE '' END, COUNT(*)
+		FROM interactions i
+		LEFT JOIN users u ON u.id=i.created_by
+		WHERE i.account_id=$1 AND i.created_at >= $2 AND i.created_at < $3
+			AND i.source_label NOT IN ('reminder', 'checkin')
+		GROUP BY 1, 2, 3, 4
+	`, claims.AccountID, period.From, period.To)
+	if err != nil {
+		log.Printf("interaction stats query failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar las estadísticas"})
+	}
+	defer dbRows.Close()
+	var rows []interactionStatsRow
+	for dbRows.Next() {
+		var row interactionStatsRow
+		if err := dbRows.Scan(&row.UserID, &row.Name, &row.Type, &row.Outcome, &row.Count); err != nil {
+			log.Printf("interaction stats scan failed: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar las estadísticas"})
+		}
+		rows = append(rows, row)
+	}
+	if err := dbRows.Err(); err != nil {
+		log.Printf("interaction stats query failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar las estadísticas"})
+	}
+	return c.JSON(fiber.Map{"success": true, "stats": buildInteractionStats(period, rows)})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestResolveInteractionStatsPeriod(t *testing.T) {
+	now := time.Date(2026, time.July, 14, 16, 30, 0, 0, time.UTC)
+	period, err := resolveInteractionStatsPeriod("", "", now)
+	if err != nil {
+		t.Fatalf("default period: %v", err)
+	}
+	if got, want := period.From.Format(time.RFC3339), "2026-06-15T00:00:00-05:00"; got != want {
+		t.Fatalf("from = %s, want %s", got, want)
+	}
+	if got, want := period.To.Format(time.RFC3339), "2026-07-15T00:00:00-05:00"; got != want {
+		t.Fatalf("to = %s, want %s", got, want)
+	}
+
+	period, err = resolveInteractionStatsPeriod("2026-07-01", "2026-07-01", now)
+	if err != nil || period.To.Sub(period.From) != 24*time.Hour {
+		t.Fatalf("single day = %+v, %v", period, err)
+	}
+	for _, tc := range [][2]string{{"2026-07-02", "2026-07-01"}, {"2025-01-01", "2026-07-01"}, {"01/07/2026", ""}} {
+		if _, err := resolveInteractionStatsPeriod(tc[0], tc[1], now); err == nil {
+			t.Errorf("resolveInteractionStatsPeriod(%q, %q) accepted an invalid range", tc[0], tc[1])
+		}
+	}
+}
+
+func TestResolveInteractionStatsPeriodCountsBackFromTo(t *testing.T) {
+	now := time.Date(2026, time.July, 14, 16, 30, 0, 0, time.UTC)
+	period, err := resolveInteractionStatsPeriod("", "2026-03-31", now)
+	if err != nil {
+		t.Fatalf("to only: %v", err)
+	}
+	if got, want := period.From.Format(time.RFC3339), "2026-03-02T00:00:00-05:00"; got != want {
+		t.Fatalf("from = %s, want %s", got, want)
+	}
+	if got, want := period.To.Format(time.RFC3339), "2026-04-01T00:00:00-05:00"; got != want {
+		t.Fatalf("to = %s, want %s", got, want)
+	}
+}
+
+func TestBuildInteractionStats(t *testing.T) {
+	ana, luis := uuid.New(), uuid.New()
+	stats := buildInteractionStats(interactionStatsPeriod{}, []interactionStatsRow{
+		{UserID: &ana, Name: "Ana", Type: "call", Outcome: "answered", Count: 3},
+		{UserID: &ana, Name: "Ana", Type: "call", Outcome: "", Count: 1},
+		{UserID: &ana, Name: "Ana", Type: "note", Count: 2},
+		{UserID: &luis, Name: "Luis", Type: "call", Outcome: "no_answer", Count: 7},
+		{Type: "note", Count: 1},
+	})
+	if stats.Total != 14 || stats.ByType["call"] != 11 || stats.ByType["note"] != 3 {
+		t.Fatalf("totals = %d %v", stats.Total, stats.ByType)
+	}
+	if stats.CallOutcomes["answered"] != 3 || stats.CallOutcomes["no_answer"] != 7 || stats.CallOutcomes[interactionOutcomeNone] != 1 {
+		t.Fatalf("call outcomes = %v", stats.CallOutcomes)
+	}
+	if len(stats.Agents) != 3 || stats.Agents[0].Name != "Luis" || stats.Agents[1].Name != "Ana" || stats.Agents[2].UserID != nil {
+		t.Fatalf("agents order = %+v", stats.Agents)
+	}
+	if _, ok := stats.Agents[1].CallOutcomes["note"]; ok || stats.Agents[1].Total != 6 {
+		t.Fatalf("ana = %+v", stats.Agents[1])
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/naperu/clarin/internal/kommo"
+)
+
+// handleKommoTestConnection runs a connection test against the Kommo
+// integration assigned to the account and returns a structured diagnostic:
+// token expiry/scopes, account, subdomain and accessible pipelines, with
+// Kommo's own error codes on failure.
+// POST /api/kommo/test
+func (s *Server) handleKommoTestConnection(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	if !kommo.APICommunicationEnabled {
+		return c.JSON(fiber.Map{"success": true, "diagnostic": kommoUnavailableDiagnostic("KOMMO_API_DISABLED", "La comunicación con la API de Kommo está deshabilitada en esta instalación")})
+	}
+	kommoSync := s.kommoForAccount(c.Context(), accountID)
+	if kommoSync == nil {
+		return c.JSON(fiber.Map{"success": true, "diagnostic": kommoUnavailableDiagnostic("KOMMO_NOT_CONFIGURED", "La cuenta no tiene una integración de Kommo asignada")})
+	}
+	return c.JSON(fiber.Map{"success": true, "diagnostic": kommoSync.GetClient().Diagnose()})
+}
+
+// kommoUnavailableDiagnostic reports why no request was sent to Kommo.
+func kommoUnavailableDiagnostic(code, message string) *kommo.ConnectionDiagnostic {
+	return &kommo.ConnectionDiagnostic{
+		Pipelines: []kommo.DiagnosticPipeline{},
+		Checks:    []kommo.DiagnosticCheck{{Name: "configuration", Code: code, Message: message}},
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/naperu/clarin/internal/kommo"
+)
+
+const maxKommoFieldMappings = 100
+
+// normalizeKommoFieldMappings trims and validates the mappings of a PUT
+// request. Keys and Kommo field IDs must be unique.
+func normalizeKommoFieldMappings(mappings []kommo.FieldMapping) ([]kommo.FieldMapping, error) {
+	if len(mappings) > maxKommoFieldMappings {
+		return nil, fmt.Errorf("máximo %d campos mapeados", maxKommoFieldMappings)
+	}
+	keys := map[string]bool{}
+	fieldIDs := map[int]bool{}
+	normalized := make([]kommo.FieldMapping, 0, len(mappings))
+	for i, m := range mappings {
+		m.ClarinKey = strings.TrimSpace(m.ClarinKey)
+		m.FieldType = strings.ToLower(strings.TrimSpace(m.FieldType))
+		switch {
+		case m.ClarinKey == "" || len(m.ClarinKey) > 100:
+			return nil, fmt.Errorf("mapeo %d: clarin_key es obligatorio (máx. 100 caracteres)", i+1)
+		case m.KommoFieldID <= 0:
+			return nil, fmt.Errorf("mapeo %d: kommo_field_id inválido", i+1)
+		case !kommo.IsMappableFieldType(m.FieldType):
+			return nil, fmt.Errorf("mapeo %d: field_type debe ser uno de %s", i+1, strings.Join(kommo.MappableFieldTypes, ", "))
+		case keys[m.ClarinKey]:
+			return nil, fmt.Errorf("mapeo %d: clarin_key %q está repetido", i+1, m.ClarinKey)
+		case fieldIDs[m.KommoFieldID]:
+			return nil, fmt.Errorf("mapeo %d: kommo_field_id %d está repetido", i+1, m.KommoFieldID)
+		}
+		keys[m.ClarinKey] = true
+		fieldIDs[m.KommoFieldID] = true
+		normalized = append(normalized, m)
+	}
+	return normalized, nil
+}
+
+// handleGetKommoFieldMappings lists the custom field mappings of the account.
+// GET /api/kommo/field-mappings
+func (s *Server) handleGetKommoFieldMappings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	mappings, err := kommo.LoadFieldMappings(c.Context(), s.repos.DB(), accountID)
+	if err != nil {
+		log.Printf("[API] Failed to load Kommo field mappings for account %s: %v", accountID, err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar los mapeos"})
+	}
+	return c.JSON(fiber.Map{"success": true, "mappings": mappings, "field_types": kommo.MappableFieldTypes})
+}
+
+// handleUpdateKommoFieldMappings replaces the custom field mappings of the
+// account. Body: {"mappings": [{"clarin_key", "kommo_field_id", "field_type"}]}
+// PUT /api/kommo/field-mappings
+func (s *Server) handleUpdateKommoFieldMappings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		Mappings []kommo.FieldMapping `json:"mappings"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	mappings, err := normalizeKommoFieldMappings(req.Mappings)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if err := kommo.ReplaceFieldMappings(c.Context(), s.repos.DB(), accountID, mappings); err != nil {
+		log.Printf("[API] Failed to save Kommo field mappings for account %s: %v", accountID, err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudieron guardar los mapeos"})
+	}
+	return c.JSON(fiber.Map{"success": true, "mappings": mappings})
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/naperu/clarin/internal/kommo"
+)
+
+func TestNormalizeKommoFieldMappings(t *testing.T) {
+	got, err := normalizeKommoFieldMappings([]kommo.FieldMapping{
+		{ClarinKey: " carrera ", KommoFieldID: 10, FieldType: "Select"},
+		{ClarinKey: "monto", KommoFieldID: 11, FieldType: "numeric"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got[0].ClarinKey != "carrera" || got[0].FieldType != "select" {
+		t.Fatalf("mapping not normalized: %+v", got[0])
+	}
+
+	invalid := [][]kommo.FieldMapping{
+		{{ClarinKey: "", KommoFieldID: 1, FieldType: "text"}},
+		{{ClarinKey: "a", KommoFieldID: 0, FieldType: "text"}},
+		{{ClarinKey: "a", KommoFieldID: 1, FieldType: "date"}},
+		{{ClarinKey: "a", KommoFieldID: 1, FieldType: "text"}, {ClarinKey: "a", KommoFieldID: 2, FieldType: "text"}},
+		{{ClarinKey: "a", KommoFieldID: 1, FieldType: "text"}, {ClarinKey: "b", KommoFieldID: 1, FieldType: "text"}},
+	}
+	for i, mappings := range invalid {
+		if _, err := normalizeKommoFieldMappings(mappings); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+const leadExportPageSize = 500
+
+// leadExportHeaders are the column names the CSV importer recognizes, so an
+// exported file can be imported again as is.
+var leadExportHeaders = []string{
+	"nombre", "apellido", "nombre del lead", "telefono", "email", "empresa", "dni", "fecha_nacimiento", "etapa", "etiquetas", "notas",
+}
+
+// handleExportLeadsCSV streams every lead matching the GET /api/leads
+// filters as CSV, in id order. limit/offset/sort are ignored: the export
+// walks the whole result with an id cursor, so leads created or deleted
+// meanwhile cannot shift later pages.
+// GET /api/leads/export?search=&stage_id=&tag_ids=...
+func (s *Server) handleExportLeadsCSV(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	filter := parseLeadFilter(c)
+	filter.Limit = leadExportPageSize
+	filter.Offset = 0
+	filter.AfterID = &uuid.Nil
+	filter.Visibility = s.assignmentScope(c)
+
+	// Fail before streaming so errors still reach the client as JSON.
+	first, _, err := s.services.Lead.GetByAccountIDWithFilters(c.Context(), accountID, filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	c.Set("Content-Type", "text/csv; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=leads_%s.csv", time.Now().Format("20060102_150405")))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		if err := s.writeLeadsCSV(ctx, w, accountID, filter, first); err != nil {
+			log.Printf("[LEADS] CSV export for account %s stopped: %v", accountID, err)
+		}
+	})
+	return nil
+}
+
+// writeLeadsCSV writes the header, the already loaded first page and every
+// following page until the filter is exhausted.
+func (s *Server) writeLeadsCSV(ctx context.Context, w io.Writer, accountID uuid.UUID, filter domain.LeadFilter, page []*domain.Lead) error {
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(leadExportHeaders); err != nil {
+		return err
+	}
+	for {
+		if err := s.attachLeadExportTags(ctx, page); err != nil {
+			return err
+		}
+		for _, lead := range page {
+			if err := csvWriter.Write(leadExportRecord(lead)); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		if len(page) < filter.Limit {
+			return nil
+		}
+		filter.AfterID = &page[len(page)-1].ID
+		var err error
+		if page, _, err = s.services.Lead.GetByAccountIDWithFilters(ctx, accountID, filter); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) attachLeadExportTags(ctx context.Context, leads []*domain.Lead) error {
+	var contactIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, lead := range leads {
+		if lead.ContactID != nil && !seen[*lead.ContactID] {
+			seen[*lead.ContactID] = true
+			contactIDs = append(contactIDs, *lead.ContactID)
+		}
+	}
+	if len(contactIDs) == 0 {
+		return nil
+	}
+	tagMap, err := s.repos.Tag.GetByContactsBatch(ctx, contactIDs)
+	if err != nil {
+		return err
+	}
+	for _, lead := range leads {
+		if lead.ContactID != nil {
+			lead.StructuredTags = tagMap[*lead.ContactID]
+		}
+	}
+	return nil
+}
+
+// leadExportRecord renders one lead in leadExportHeaders order. Tags are
+// joined with commas, the separator splitImportTags expects, so commas inside
+// a tag name become spaces.
+func leadExportRecord(lead *domain.Lead) []string {
+	deref := func(v *string) string {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	birthDate := ""
+	if lead.BirthDate != nil {
+		birthDate = lead.BirthDate.Format("2006-01-02")
+	}
+	stage := ""
+	if lead.StageName != nil {
+		stage = *lead.StageName
+	}
+
+	tags := make([]string, 0, len(lead.StructuredTags)+len(lead.Tags))
+	seen := map[string]bool{}
+	addTag := func(name string) {
+		name = strings.Join(strings.Fields(strings.ReplaceAll(name, ",", " ")), " ")
+		if key := strings.ToLower(name); name != "" && !seen[key] {
+			seen[key] = true
+			tags = append(tags, name)
+		}
+	}
+	for _, t := range lead.StructuredTags {
+		if t != nil {
+			addTag(t.Name)
+		}
+	}
+	for _, t := range lead.Tags {
+		addTag(t)
+	}
+
+	record := []string{
+		deref(lead.Name), deref(lead.LastName), lead.Title, deref(lead.Phone), deref(lead.Email), deref(lead.Company),
+		deref(lead.DNI), birthDate, stage, strings.Join(tags, ", "), deref(lead.Notes),
+	}
+	for i, value := range record {
+		record[i] = neutralizeSpreadsheetFormula(value)
+	}
+	return record
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestLeadExportRecordRoundTripsThroughImporter(t *testing.T) {
+	name, phone, email, stage, notes := "Ana", "51999888777", "ana@example.com", "Interesado", "=cmd"
+	birth := time.Date(1990, 4, 2, 0, 0, 0, 0, time.UTC)
+	lead := &domain.Lead{
+		Name:           &name,
+		Phone:          &phone,
+		Email:          &email,
+		StageName:      &stage,
+		Notes:          &notes,
+		BirthDate:      &birth,
+		StructuredTags: []*domain.Tag{{Name: "hot"}, {Name: "Lima, Norte"}},
+		Tags:           []string{"HOT", "referido"},
+	}
+	exported := leadExportRecord(lead)
+	if len(exported) != len(leadExportHeaders) {
+		t.Fatalf("record has %d cells, headers %d", len(exported), len(leadExportHeaders))
+	}
+	notasCol := len(leadExportHeaders) - 1
+	if exported[notasCol] != "'=cmd" {
+		t.Fatalf("formula cell not neutralized: %q", exported[notasCol])
+	}
+	// The importer reverses the neutralization when it reads each row.
+	record := make([]string, len(exported))
+	for i, value := range exported {
+		record[i] = restoreSpreadsheetFormula(value)
+	}
+
+	colMap := map[string]int{}
+	for i, h := range leadExportHeaders {
+		colMap[normalizeImportHeader(h)] = i
+	}
+	if cols := importPhoneColumns(leadExportHeaders, colMap, record); firstValidImportPhone(record, cols) != phone {
+		t.Fatalf("importer did not read the exported phone from %v", record)
+	}
+	if got := firstValidImportEmail(record, importEmailColumns(colMap)); got != email {
+		t.Fatalf("email = %q, want %q", got, email)
+	}
+	if got := safeCol(record, findCol(colMap, "nombre")); got != name {
+		t.Fatalf("nombre = %q", got)
+	}
+	if got := safeCol(record, findCol(colMap, "etapa")); got != stage {
+		t.Fatalf("etapa = %q", got)
+	}
+	if got := parseImportDate(safeCol(record, findCol(colMap, "fecha_nacimiento"))); got == nil || !got.Equal(birth) {
+		t.Fatalf("fecha_nacimiento = %v", got)
+	}
+	wantTags := []string{"hot", "Lima Norte", "referido"}
+	if got := splitImportTags(safeCol(record, findCol(colMap, "etiquetas"))); !reflect.DeepEqual(got, wantTags) {
+		t.Fatalf("tags = %v, want %v", got, wantTags)
+	}
+	if got := safeCol(record, findCol(colMap, "notas")); got != notes {
+		t.Fatalf("notas = %q, want %q", got, notes)
+	}
+}
+
+func TestRestoreSpreadsheetFormula(t *testing.T) {
+	for _, value := range []string{"=SUM(A1)", "+51999888777", "-5", "@user", " =x", "'quoted", "'=already", "plain", ""} {
+		if got := restoreSpreadsheetFormula(neutralizeSpreadsheetFormula(value)); got != value {
+			t.Fatalf("round trip of %q = %q", value, got)
+		}
+	}
+	if got := restoreSpreadsheetFormula("'hola"); got != "'hola" {
+		t.Fatalf("quote without a formula should be kept, got %q", got)
+	}
+}
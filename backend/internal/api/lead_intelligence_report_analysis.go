@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
 )
 
 const leadIntelligenceAIMaxCandidates = 250
@@ -98,7 +99,7 @@ type leadIntelligenceAnalyzed struct {
 
 func (s *Server) loadLeadIntelligenceFacts(ctx context.Context, accountID uuid.UUID, params leadIntelligenceParameters) ([]leadIntelligenceFact, error) {
 	args := []any{accountID}
-	where := []string{"l.account_id=$1", "l.deleted_at IS NULL"}
+	where := []string{"l.account_id=$1", repository.NotDeleted("l")}
 	addArg := func(value any) string {
 		args = append(args, value)
 		return fmt.Sprintf("$%d", len(args))
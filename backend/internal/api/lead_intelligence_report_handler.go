@@ -15,6 +15,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
 )
 
 type leadIntelligenceRequest struct {
@@ -280,7 +281,7 @@ func (s *Server) handleLeadIntelligenceOptions(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar las etiquetas"})
 	}
-	rows, err := s.repos.DB().Query(c.Context(), `SELECT DISTINCT COALESCE(source,'') FROM leads WHERE account_id=$1 AND deleted_at IS NULL AND BTRIM(COALESCE(source,''))<>'' ORDER BY 1`, accountID)
+	rows, err := s.repos.DB().Query(c.Context(), `SELECT DISTINCT COALESCE(source,'') FROM leads WHERE account_id=$1 AND `+repository.NotDeleted("")+` AND BTRIM(COALESCE(source,''))<>'' ORDER BY 1`, accountID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar las fuentes"})
 	}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
 )
 
 func TestLeadInteractionsCacheKeysStayAccountScoped(t *testing.T) {
@@ -20,3 +22,27 @@ func TestLeadInteractionsCacheKeysStayAccountScoped(t *testing.T) {
 		t.Fatalf("leadInteractionsCachePattern() = %q, want %q", pattern, want)
 	}
 }
+
+func TestLeadListCacheKeyIsInvalidatedAndFilterSpecific(t *testing.T) {
+	accountID := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	stageID := uuid.MustParse("33333333-3333-3333-3333-333333333333")
+
+	base := leadListCacheKey(accountID, domain.LeadFilter{Limit: 50}, false)
+	if !strings.HasPrefix(base, "leads:"+accountID.String()+":") {
+		t.Fatalf("leadListCacheKey() = %q, not under the prefix cleared by invalidateLeadsCache", base)
+	}
+	variants := []string{
+		leadListCacheKey(accountID, domain.LeadFilter{Limit: 50, Offset: 50}, false),
+		leadListCacheKey(accountID, domain.LeadFilter{Limit: 50, StageID: &stageID}, false),
+		leadListCacheKey(accountID, domain.LeadFilter{Limit: 50, SortBy: "score"}, false),
+		leadListCacheKey(accountID, domain.LeadFilter{Limit: 50, Status: "won"}, false),
+		leadListCacheKey(accountID, domain.LeadFilter{Limit: 50}, true),
+		leadListCacheKey(accountID, domain.LeadFilter{Limit: 50, Visibility: &domain.AssignmentScope{UserID: stageID}}, false),
+		leadListCacheKey(accountID, domain.LeadFilter{Limit: 50, Visibility: &domain.AssignmentScope{UserID: stageID, IncludeUnassigned: true}}, false),
+	}
+	for _, key := range variants {
+		if key == base {
+			t.Fatalf("filter variant shares cache key %q", key)
+		}
+	}
+}
@@ -128,3 +128,31 @@ func TestAddLeadPipelineWhereUsesLeadPipelineID(t *testing.T) {
 		})
 	}
 }
+
+func TestAddLeadAssignmentWhere(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		name       string
+		scope      *domain.AssignmentScope
+		wantClause []string
+		wantArgs   []interface{}
+	}{
+		{name: "unrestricted"},
+		{name: "own leads", scope: &domain.AssignmentScope{UserID: userID}, wantClause: []string{"l.assigned_to = $2"}, wantArgs: []interface{}{userID}},
+		{name: "own and unassigned", scope: &domain.AssignmentScope{UserID: userID, IncludeUnassigned: true}, wantClause: []string{"(l.assigned_to = $2 OR l.assigned_to IS NULL)"}, wantArgs: []interface{}{userID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clauses []string
+			var args []interface{}
+			argIdx := 2
+			addLeadAssignmentWhere(tt.scope, &clauses, &args, &argIdx)
+			if !reflect.DeepEqual(clauses, tt.wantClause) || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("clauses=%#v args=%#v", clauses, args)
+			}
+			if want := 2 + len(tt.wantArgs); argIdx != want {
+				t.Fatalf("argIdx = %d, want %d", argIdx, want)
+			}
+		})
+	}
+}
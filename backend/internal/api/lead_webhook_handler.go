@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/kommo"
+)
+
+const leadWebhookTokenPrefix = "cllw_"
+
+// generateLeadWebhookToken creates a random lead intake token.
+// Format: cllw_<64 random hex chars>
+func generateLeadWebhookToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return leadWebhookTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// handleCreateLeadWebhookToken mints a token for POST /api/webhooks/leads/:token.
+// POST /api/webhooks/lead-tokens { "label": "Landing verano" }
+// Returns the plaintext token ONCE — only its hash is stored.
+func (s *Server) handleCreateLeadWebhookToken(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		Label string `json:"label"`
+	}
+	_ = c.BodyParser(&req)
+
+	rawToken, err := generateLeadWebhookToken()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to generate token"})
+	}
+	token := &domain.LeadWebhookToken{
+		AccountID:   accountID,
+		Label:       strings.TrimSpace(req.Label),
+		TokenHash:   hashAPIKey(rawToken),
+		TokenPrefix: rawToken[:13] + "...",
+	}
+	var userID *uuid.UUID
+	if uid, ok := c.Locals("user_id").(uuid.UUID); ok {
+		userID = &uid
+		token.CreatedBy = &uid
+	}
+	if err := s.repos.LeadWebhookToken.Create(c.Context(), token); err != nil {
+		log.Printf("[LEAD-WEBHOOK] Error creating token: %v", err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to create token"})
+	}
+	s.recordSecurityEventWithRefs(c.Context(), "lead_webhook_token_created", token.ID.String(), c, &accountID, userID, map[string]interface{}{
+		"label": token.Label,
+	})
+	return c.Status(201).JSON(fiber.Map{
+		"success":            true,
+		"token":              rawToken,
+		"url_path":           "/api/webhooks/leads/" + rawToken,
+		"lead_webhook_token": token,
+	})
+}
+
+// handleListLeadWebhookTokens lists the account's lead intake tokens (never the secrets).
+// GET /api/webhooks/lead-tokens
+func (s *Server) handleListLeadWebhookTokens(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	tokens, err := s.repos.LeadWebhookToken.ListByAccount(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to list tokens"})
+	}
+	return c.JSON(fiber.Map{"success": true, "lead_webhook_tokens": tokens})
+}
+
+// handleRevokeLeadWebhookToken stops a token from creating more leads.
+// DELETE /api/webhooks/lead-tokens/:tokenId
+func (s *Server) handleRevokeLeadWebhookToken(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	tokenID, err := uuid.Parse(c.Params("tokenId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "invalid token id"})
+	}
+	revoked, err := s.repos.LeadWebhookToken.Revoke(c.Context(), accountID, tokenID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to revoke token"})
+	}
+	if !revoked {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Token not found"})
+	}
+	var userID *uuid.UUID
+	if uid, ok := c.Locals("user_id").(uuid.UUID); ok {
+		userID = &uid
+	}
+	s.recordSecurityEventWithRefs(c.Context(), "lead_webhook_token_revoked", tokenID.String(), c, &accountID, userID, nil)
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handleInboundLeadWebhook creates a lead in the token's account from an
+// external form. The lead goes to the account's incoming pipeline stage and is
+// pushed to Kommo like a manually created one.
+// POST /api/webhooks/leads/:token { "name": "Ana", "phone": "+51 987 654 321", "email": "", "source": "landing" }
+func (s *Server) handleInboundLeadWebhook(c *fiber.Ctx) error {
+	rawToken := strings.TrimSpace(c.Params("token"))
+	if !strings.HasPrefix(rawToken, leadWebhookTokenPrefix) {
+		return c.Status(401).JSON(fiber.Map{"success": false, "error": "Token inválido"})
+	}
+	tokenHash := hashAPIKey(rawToken)
+	if err := s.checkAbuseLimits(c, "lead_webhook_rate_limited", tokenHash, []abuseLimit{
+		{Key: "abuse:lead-webhook:ip:minute:" + hashForLog(clientIP(c)), Max: 60, Window: time.Minute},
+		{Key: "abuse:lead-webhook:token:minute:" + tokenHash, Max: 30, Window: time.Minute},
+		{Key: "abuse:lead-webhook:token:hour:" + tokenHash, Max: 600, Window: time.Hour},
+	}); err != nil {
+		e := err.(*fiber.Error)
+		return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+	}
+
+	var req struct {
+		Name   string `json:"name"`
+		Phone  string `json:"phone"`
+		Email  string `json:"email"`
+		Source string `json:"source"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+
+	ctx := c.Context()
+	token, err := s.repos.LeadWebhookToken.GetByHash(ctx, tokenHash)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo validar el token"})
+	}
+	if token == nil || token.RevokedAt != nil {
+		var accountID *uuid.UUID
+		metadata := map[string]interface{}{"reason": "unknown"}
+		if token != nil {
+			accountID = &token.AccountID
+			metadata["reason"] = "revoked"
+			metadata["token_id"] = token.ID.String()
+		}
+		s.recordSecurityEventWithRefs(ctx, "lead_webhook_rejected", tokenHash, c, accountID, nil, metadata)
+		return c.Status(401).JSON(fiber.Map{"success": false, "error": "Token inválido o revocado"})
+	}
+	accountID := token.AccountID
+	account, err := s.repos.Account.GetByID(ctx, accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo validar el token"})
+	}
+	if account == nil || !account.IsActive {
+		s.recordSecurityEventWithRefs(ctx, "lead_webhook_rejected", tokenHash, c, &accountID, nil, map[string]interface{}{
+			"reason":   "account_inactive",
+			"token_id": token.ID.String(),
+		})
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": "Cuenta inactiva"})
+	}
+
+	phone := kommo.NormalizePhone(req.Phone)
+	if phone == "" {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "phone is required"})
+	}
+	jid := phone + "@s.whatsapp.net"
+	if existing, _ := s.services.Lead.GetByJID(ctx, accountID, jid); existing != nil {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("ya existe un lead con el teléfono %s", phone),
+		})
+	}
+
+	name := strings.TrimSpace(req.Name)
+	source := strings.TrimSpace(req.Source)
+	if source == "" {
+		source = "webhook"
+	}
+	lead := &domain.Lead{
+		AccountID: accountID,
+		JID:       jid,
+		Name:      nilIfEmpty(name),
+		Phone:     strPtr(phone),
+		Email:     nilIfEmpty(strings.TrimSpace(req.Email)),
+		Source:    strPtr(source),
+		Status:    strPtr(domain.LeadStatusNew),
+	}
+	pipelineID, stageID, err := s.repos.Pipeline.ResolveIncomingLeadDestination(ctx, accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo crear el lead"})
+	}
+	lead.PipelineID = pipelineID
+	lead.StageID = stageID
+
+	if err := s.persistNewLead(ctx, accountID, lead, phone, name, "webhook", nil); err != nil {
+		log.Printf("[LEAD-WEBHOOK] create failed account=%s token=%s: %v", accountID, token.ID, err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo crear el lead"})
+	}
+	s.repos.LeadWebhookToken.MarkUsed(context.Background(), token.ID)
+	return c.Status(201).JSON(fiber.Map{"success": true, "lead_id": lead.ID})
+}
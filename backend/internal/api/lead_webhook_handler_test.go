@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestGenerateLeadWebhookTokenFormat(t *testing.T) {
+	token, err := generateLeadWebhookToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, leadWebhookTokenPrefix) || len(token) != len(leadWebhookTokenPrefix)+64 {
+		t.Fatalf("unexpected token %q", token)
+	}
+	other, _ := generateLeadWebhookToken()
+	if other == token {
+		t.Fatal("tokens must be random")
+	}
+}
+
+func TestInboundLeadWebhookRejectsForeignTokens(t *testing.T) {
+	s := &Server{}
+	app := fiber.New()
+	app.Post("/api/webhooks/leads/:token", s.handleInboundLeadWebhook)
+	for _, token := range []string{"clrt_abc", "abc"} {
+		req := httptest.NewRequest("POST", "/api/webhooks/leads/"+token, strings.NewReader(`{"phone":"987654321"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Fatalf("token %q: status %d", token, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import "testing"
+
+func TestStorageInlineSafe(t *testing.T) {
+	cases := map[string]bool{
+		"image/jpeg":                true,
+		"video/mp4":                 true,
+		"audio/ogg; codecs=opus":    true,
+		"application/pdf":           true,
+		"text/plain; charset=utf-8": true,
+		"text/csv":                  true,
+		"application/octet-stream":  true,
+		"image/svg+xml":             false,
+		"text/html; charset=utf-8":  false,
+		"application/xhtml+xml":     false,
+		"text/xml":                  false,
+		"application/xml":           false,
+		"text/javascript":           false,
+		"application/javascript":    false,
+		"":                          false,
+	}
+	for contentType, want := range cases {
+		if got := storageInlineSafe(contentType); got != want {
+			t.Fatalf("storageInlineSafe(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
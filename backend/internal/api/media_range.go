@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// mediaRangeChunk caps open-ended ranges ("bytes=N-") so players stream in
+// chunks instead of pulling the rest of a large video in one response.
+const mediaRangeChunk = 1024 * 1024
+
+var (
+	// errInvalidRange marks a Range header that cannot be parsed; per RFC 9110
+	// it is ignored and the full object is served.
+	errInvalidRange = errors.New("invalid range")
+	// errUnsatisfiableRange marks a well-formed header none of whose ranges
+	// overlap the object; it is answered with 416.
+	errUnsatisfiableRange = errors.New("range not satisfiable")
+)
+
+type byteRange struct {
+	start, end int64 // inclusive
+}
+
+func (r byteRange) length() int64 {
+	return r.end - r.start + 1
+}
+
+// parseByteRange parses a Range header against an object of size bytes,
+// accepting "start-end", open-ended "start-" and suffix "-length" forms.
+// Several ranges are coalesced into the single span covering them all, which
+// RFC 9110 allows in place of a multipart response.
+func parseByteRange(header string, size int64) (byteRange, error) {
+	unit, spec, ok := strings.Cut(strings.TrimSpace(header), "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+		return byteRange{}, errInvalidRange
+	}
+	var (
+		result      byteRange
+		satisfiable bool
+		openEnded   bool
+		count       int
+	)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		count++
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return byteRange{}, errInvalidRange
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		var r byteRange
+		switch {
+		case first == "":
+			// Suffix range: the final N bytes.
+			n, err := parseRangeInt(last)
+			if err != nil {
+				return byteRange{}, err
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			r = byteRange{start: max(0, size-n), end: size - 1}
+		default:
+			start, err := parseRangeInt(first)
+			if err != nil {
+				return byteRange{}, err
+			}
+			end := size - 1
+			if last == "" {
+				openEnded = true
+			} else {
+				if end, err = parseRangeInt(last); err != nil {
+					return byteRange{}, err
+				}
+				if end < start {
+					return byteRange{}, errInvalidRange
+				}
+			}
+			if start >= size {
+				continue
+			}
+			r = byteRange{start: start, end: min(end, size-1)}
+		}
+		if !satisfiable {
+			result, satisfiable = r, true
+			continue
+		}
+		result.start = min(result.start, r.start)
+		result.end = max(result.end, r.end)
+	}
+	if count == 0 {
+		return byteRange{}, errInvalidRange
+	}
+	if !satisfiable {
+		return byteRange{}, errUnsatisfiableRange
+	}
+	if openEnded && count == 1 && result.length() > mediaRangeChunk {
+		result.end = result.start + mediaRangeChunk - 1
+	}
+	return result, nil
+}
+
+func parseRangeInt(value string) (int64, error) {
+	if value == "" || strings.TrimLeft(value, "0123456789") != "" {
+		return 0, errInvalidRange
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errInvalidRange
+	}
+	return n, nil
+}
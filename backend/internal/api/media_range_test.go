@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseByteRange(t *testing.T) {
+	const size = 10_000
+	cases := []struct {
+		header string
+		want   byteRange
+	}{
+		{"bytes=0-499", byteRange{0, 499}},
+		{"bytes=9500-", byteRange{9500, 9999}},
+		{"bytes=-500", byteRange{9500, 9999}},
+		{"bytes=-20000", byteRange{0, 9999}},
+		{"bytes=9000-20000", byteRange{9000, 9999}},
+		{"bytes=0-99, 200-299", byteRange{0, 299}},
+		{"bytes=20000-30000, -100", byteRange{9900, 9999}},
+		{"Bytes= 5-9 ", byteRange{5, 9}},
+	}
+	for _, tc := range cases {
+		got, err := parseByteRange(tc.header, size)
+		if err != nil {
+			t.Fatalf("parseByteRange(%q) error = %v", tc.header, err)
+		}
+		if got != tc.want {
+			t.Fatalf("parseByteRange(%q) = %+v, want %+v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestParseByteRangeCapsOpenEndedRanges(t *testing.T) {
+	got, err := parseByteRange("bytes=100-", 50*mediaRangeChunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.start != 100 || got.length() != mediaRangeChunk {
+		t.Fatalf("open-ended range = %+v, want %d bytes from 100", got, mediaRangeChunk)
+	}
+}
+
+func TestParseByteRangeRejectsMalformedHeaders(t *testing.T) {
+	for _, header := range []string{
+		"", "0-10", "items=0-10", "bytes=", "bytes=abc", "bytes=1-a",
+		"bytes=-", "bytes=10-5", "bytes=+1-5", "bytes=0-10,x",
+	} {
+		if _, err := parseByteRange(header, 1000); !errors.Is(err, errInvalidRange) {
+			t.Fatalf("parseByteRange(%q) error = %v, want errInvalidRange", header, err)
+		}
+	}
+}
+
+func TestParseByteRangeUnsatisfiable(t *testing.T) {
+	for _, header := range []string{"bytes=1000-", "bytes=2000-3000", "bytes=-0"} {
+		if _, err := parseByteRange(header, 1000); !errors.Is(err, errUnsatisfiableRange) {
+			t.Fatalf("parseByteRange(%q) error = %v, want errUnsatisfiableRange", header, err)
+		}
+	}
+	if _, err := parseByteRange("bytes=-10", 0); !errors.Is(err, errUnsatisfiableRange) {
+		t.Fatalf("suffix range of an empty object error = %v, want errUnsatisfiableRange", err)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/mcp"
+)
+
+// signedMediaURLTTL is how long a signed media proxy URL returned by the
+// upload handlers stays valid.
+const signedMediaURLTTL = 24 * time.Hour
+
+// publicMediaFolders hold media shown on anonymous pages (dynamic links), so
+// the proxy serves them without a signature or session.
+var publicMediaFolders = map[string]bool{
+	"dynamics": true,
+}
+
+func signMediaKey(secret []byte, objectKey string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("media:" + objectKey + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validMediaSignature checks an exp/sig pair produced by signMediaKey.
+func validMediaSignature(secret []byte, objectKey, exp, sig string, now time.Time) bool {
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || sig == "" || now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(signMediaKey(secret, objectKey, expires)))
+}
+
+func (s *Server) mediaSigningSecret() []byte {
+	return []byte(s.cfg.MediaURLSecret)
+}
+
+// signedMediaProxyURL returns the proxy URL of objectKey carrying an
+// expiring signature, for consumers without a session such as links shared
+// outside the app.
+func (s *Server) signedMediaProxyURL(objectKey string, ttl time.Duration) string {
+	expires := time.Now().Add(ttl).Unix()
+	query := url.Values{}
+	query.Set("exp", strconv.FormatInt(expires, 10))
+	query.Set("sig", signMediaKey(s.mediaSigningSecret(), objectKey, expires))
+	return mediaProxyURLFromObjectKey(objectKey) + "?" + query.Encode()
+}
+
+func isPublicMediaObjectKey(objectKey string) bool {
+	parts := strings.SplitN(objectKey, "/", 3)
+	return len(parts) == 3 && publicMediaFolders[parts[1]]
+}
+
+// mediaObjectAccount returns the account that owns objectKey.
+func mediaObjectAccount(objectKey string) (uuid.UUID, bool) {
+	prefix, _, ok := strings.Cut(objectKey, "/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	accountID, err := uuid.Parse(prefix)
+	return accountID, err == nil
+}
+
+// authorizeMediaRequest decides whether the proxy may serve objectKey: public
+// folders always, otherwise a valid signature or credentials of the object's
+// account — a user session, an API key of the account or an active MCP
+// client allowed on it.
+func (s *Server) authorizeMediaRequest(c *fiber.Ctx, objectKey string) bool {
+	if !s.cfg.MediaProxyRequireAuth || isPublicMediaObjectKey(objectKey) {
+		return true
+	}
+	if validMediaSignature(s.mediaSigningSecret(), objectKey, c.Query("exp"), c.Query("sig"), time.Now()) {
+		return true
+	}
+	accountID, ok := mediaObjectAccount(objectKey)
+	if !ok {
+		return false
+	}
+	token := strings.TrimSpace(c.Cookies("auth-token"))
+	if token == "" {
+		authHeader := strings.TrimSpace(c.Get("Authorization"))
+		if strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
+			token = strings.TrimSpace(authHeader[7:])
+		}
+	}
+	if token == "" {
+		if apiKey := strings.TrimSpace(c.Get("X-API-Key")); apiKey != "" {
+			key, _, err := s.repos.APIKey.Authenticate(c.Context(), hashAPIKey(apiKey))
+			return err == nil && key != nil && key.CreatedBy != nil && key.AccountID == accountID
+		}
+		return false
+	}
+	claims, err := s.services.Auth.ValidateToken(token, s.cfg.JWTSecret)
+	if err != nil {
+		// Not a session: MCP clients send their own bearer tokens.
+		client, lookupErr := mcp.ClientForToken(c.Context(), s.repos, token)
+		if lookupErr != nil || client == nil || client.Status != domain.MCPStatusActive {
+			return false
+		}
+		for _, allowedID := range client.AllowedAccountIDs {
+			if allowedID == accountID {
+				return true
+			}
+		}
+		return false
+	}
+	if s.services.Auth.IsUserSessionInvalidated(claims) {
+		return false
+	}
+	if claims.IsSuperAdmin || claims.AccountID == accountID {
+		return true
+	}
+	member, err := s.repos.UserAccount.Exists(c.Context(), claims.UserID, accountID)
+	return err == nil && member
+}
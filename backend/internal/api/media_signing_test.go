@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/pkg/config"
+)
+
+func TestSignedMediaProxyURLRoundTrip(t *testing.T) {
+	s := &Server{cfg: &config.Config{MediaURLSecret: "secret"}}
+	key := uuid.NewString() + "/uploads/photo.jpg"
+	signed := s.signedMediaProxyURL(key, time.Hour)
+	path, rawQuery, ok := strings.Cut(signed, "?")
+	if !ok || path != mediaProxyURLFromObjectKey(key) {
+		t.Fatalf("signed URL = %q", signed)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := s.mediaSigningSecret()
+	now := time.Now()
+	if !validMediaSignature(secret, key, query.Get("exp"), query.Get("sig"), now) {
+		t.Fatal("fresh signature was rejected")
+	}
+	if validMediaSignature(secret, uuid.NewString()+"/uploads/photo.jpg", query.Get("exp"), query.Get("sig"), now) {
+		t.Fatal("signature accepted for another key")
+	}
+	if validMediaSignature(secret, key, query.Get("exp"), query.Get("sig"), now.Add(2*time.Hour)) {
+		t.Fatal("expired signature was accepted")
+	}
+	if validMediaSignature([]byte("other"), key, query.Get("exp"), query.Get("sig"), now) {
+		t.Fatal("signature accepted under another secret")
+	}
+	if validMediaSignature(secret, key, "not-a-number", query.Get("sig"), now) {
+		t.Fatal("malformed expiry was accepted")
+	}
+}
+
+func TestMediaObjectKeyScopes(t *testing.T) {
+	accountID := uuid.New()
+	if got, ok := mediaObjectAccount(accountID.String() + "/media/jpg/a.jpg"); !ok || got != accountID {
+		t.Fatalf("mediaObjectAccount = %v, %v", got, ok)
+	}
+	if _, ok := mediaObjectAccount("not-an-account/media/a.jpg"); ok {
+		t.Fatal("non-account prefix was accepted")
+	}
+	if !isPublicMediaObjectKey(accountID.String() + "/dynamics/" + uuid.NewString() + "/a.jpg") {
+		t.Fatal("dynamics media should stay public")
+	}
+	if isPublicMediaObjectKey(accountID.String() + "/media/jpg/a.jpg") {
+		t.Fatal("chat media was treated as public")
+	}
+}
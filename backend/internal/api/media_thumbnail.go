@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/naperu/clarin/internal/imageutil"
+)
+
+const (
+	// thumbnailMaxSide bounds the longest side of generated thumbnails.
+	thumbnailMaxSide = 320
+	// thumbnailSuffix is appended to the original object key, so a thumbnail
+	// always sits next to its source and is cleaned up with it.
+	thumbnailSuffix = ".thumb.jpg"
+	// thumbnailVideoTimeout bounds the ffmpeg first-frame grab.
+	thumbnailVideoTimeout = 20 * time.Second
+)
+
+func thumbnailObjectKey(objectKey string) string {
+	return objectKey + thumbnailSuffix
+}
+
+// thumbnailSourceKey returns the original object of a thumbnail key, or ""
+// when objectKey is not a thumbnail.
+func thumbnailSourceKey(objectKey string) string {
+	if !strings.HasSuffix(objectKey, thumbnailSuffix) {
+		return ""
+	}
+	return strings.TrimSuffix(objectKey, thumbnailSuffix)
+}
+
+// ensureUploadThumbnail stores a thumbnail next to an uploaded image and
+// returns its proxy URL. Video frames need ffmpeg, so they are grabbed in the
+// background and "" is returned until the thumbnail exists. Thumbnails are
+// best-effort: unsupported formats and generation failures return "" and the
+// original is used instead.
+func (s *Server) ensureUploadThumbnail(ctx context.Context, objectKey string, data []byte, contentType string) string {
+	if s.storage == nil {
+		return ""
+	}
+	contentType = strings.ToLower(contentType)
+	if !strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "video/") {
+		return ""
+	}
+	thumbURL := mediaProxyURLFromObjectKey(objectKey) + "?thumb=1"
+	thumbKey := thumbnailObjectKey(objectKey)
+	if _, err := s.storage.GetFileInfo(ctx, thumbKey); err == nil {
+		return thumbURL
+	}
+
+	if strings.HasPrefix(contentType, "video/") {
+		s.queueVideoThumbnail(objectKey, data)
+		return ""
+	}
+	thumb, err := imageutil.Thumbnail(data, thumbnailMaxSide)
+	if err != nil {
+		log.Printf("[Storage] No thumbnail for %s: %v", objectKey, err)
+		return ""
+	}
+	if _, err := s.storage.UploadObject(ctx, thumbKey, thumb, "image/jpeg"); err != nil {
+		log.Printf("[Storage] Failed to store thumbnail %s: %v", thumbKey, err)
+		return ""
+	}
+	return thumbURL
+}
+
+// queueVideoThumbnail grabs the first frame of an uploaded video off the
+// request path. At most cap(thumbnailSem) ffmpeg runs at a time; uploads
+// arriving while every slot is busy go without a thumbnail.
+func (s *Server) queueVideoThumbnail(objectKey string, data []byte) {
+	select {
+	case s.thumbnailSem <- struct{}{}:
+	default:
+		log.Printf("[Storage] Skipping video thumbnail for %s: thumbnail workers busy", objectKey)
+		return
+	}
+	go func() {
+		defer func() { <-s.thumbnailSem }()
+		ctx := context.Background()
+		thumb, err := videoThumbnail(ctx, data)
+		if err != nil {
+			log.Printf("[Storage] No thumbnail for %s: %v", objectKey, err)
+			return
+		}
+		thumbKey := thumbnailObjectKey(objectKey)
+		if _, err := s.storage.UploadObject(ctx, thumbKey, thumb, "image/jpeg"); err != nil {
+			log.Printf("[Storage] Failed to store thumbnail %s: %v", thumbKey, err)
+		}
+	}()
+}
+
+// videoThumbnail grabs the first frame of a video with ffmpeg.
+func videoThumbnail(ctx context.Context, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, thumbnailVideoTimeout)
+	defer cancel()
+	tempDir, err := os.MkdirTemp("", "clarin-thumb-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+	inputPath := filepath.Join(tempDir, "input")
+	outputPath := filepath.Join(tempDir, "frame.jpg")
+	if err := os.WriteFile(inputPath, data, 0o600); err != nil {
+		return nil, err
+	}
+	command := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-loglevel", "error", "-nostdin", "-y",
+		"-i", inputPath, "-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", thumbnailMaxSide, thumbnailMaxSide),
+		outputPath,
+	)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	frame, err := os.ReadFile(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(frame) == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no frame")
+	}
+	return frame, nil
+}
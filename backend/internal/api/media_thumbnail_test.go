@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os/exec"
+	"testing"
+
+	"github.com/naperu/clarin/internal/imageutil"
+)
+
+func TestThumbnailObjectKeyRoundTrip(t *testing.T) {
+	key := "acc/uploads/hash-id.png"
+	thumb := thumbnailObjectKey(key)
+	if thumb != key+".thumb.jpg" {
+		t.Fatalf("thumbnailObjectKey = %q", thumb)
+	}
+	if got := thumbnailSourceKey(thumb); got != key {
+		t.Fatalf("thumbnailSourceKey = %q, want %q", got, key)
+	}
+	if got := thumbnailSourceKey(key); got != "" {
+		t.Fatalf("thumbnailSourceKey of an original = %q, want empty", got)
+	}
+}
+
+func TestImageThumbnailFitsMaxSide(t *testing.T) {
+	source := image.NewRGBA(image.Rect(0, 0, 1200, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 1200; x++ {
+			source.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 80, A: 255})
+		}
+	}
+	var input bytes.Buffer
+	if err := png.Encode(&input, source); err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := imageutil.Thumbnail(input.Bytes(), thumbnailMaxSide)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if cfg.Width != thumbnailMaxSide || cfg.Height != thumbnailMaxSide/2 {
+		t.Fatalf("thumbnail is %dx%d, want %dx%d", cfg.Width, cfg.Height, thumbnailMaxSide, thumbnailMaxSide/2)
+	}
+}
+
+func TestImageThumbnailRejectsOversizedHeaders(t *testing.T) {
+	var input bytes.Buffer
+	if err := gif.Encode(&input, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := input.Bytes()
+	// Claim a 65535x65535 logical screen in the GIF header.
+	copy(data[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := imageutil.Thumbnail(data, thumbnailMaxSide); !errors.Is(err, imageutil.ErrTooManyPixels) {
+		t.Fatalf("Thumbnail error = %v, want ErrTooManyPixels", err)
+	}
+}
+
+func TestVideoThumbnailRejectsInvalidInput(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	if _, err := videoThumbnail(context.Background(), []byte("not a video")); err == nil {
+		t.Fatal("expected an error for invalid video data")
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/service"
+)
+
+// handleSendContactCard shares a contact card, either from a saved contact
+// (contact_id) or from a raw name and phone.
+func (s *Server) handleSendContactCard(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		DeviceID  string `json:"device_id"`
+		To        string `json:"to"`
+		ContactID string `json:"contact_id"`
+		Name      string `json:"name"`
+		Phone     string `json:"phone"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+
+	deviceID, err := uuid.Parse(req.DeviceID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
+	}
+	var contactID *uuid.UUID
+	if req.ContactID != "" {
+		id, err := uuid.Parse(req.ContactID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid contact ID"})
+		}
+		contactID = &id
+	} else if req.Phone == "" {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "contact_id or phone is required"})
+	}
+	if dev, _ := s.services.Device.GetByID(c.Context(), deviceID); dev == nil || dev.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Device not found"})
+	}
+	if err := s.ensureOutboundContactAllowed(c.Context(), accountID, req.To); err != nil {
+		if apiErr, ok := err.(*fiber.Error); ok {
+			return c.Status(apiErr.Code).JSON(fiber.Map{"success": false, "error": apiErr.Message, "code": "do_not_contact"})
+		}
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	message, err := s.services.Chat.SendContactCard(c.Context(), accountID, deviceID, req.To, contactID, req.Name, req.Phone)
+	switch {
+	case errors.Is(err, service.ErrContactCardNotFound):
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": err.Error()})
+	case errors.Is(err, service.ErrContactCardInvalid):
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	if message != nil {
+		s.invalidateChatCaches(accountID, &message.ChatID)
+	} else {
+		s.invalidateChatCaches(accountID, nil)
+	}
+
+	return c.JSON(fiber.Map{"success": true, "message": message})
+}
+
+// handleAddContactFromCard saves the person in a received contact card.
+func (s *Server) handleAddContactFromCard(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid message ID"})
+	}
+	contact, err := s.services.Chat.AddContactFromCard(c.Context(), accountID, messageID)
+	if errors.Is(err, service.ErrContactCardInvalid) {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if contact == nil {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Message not found"})
+	}
+	return c.JSON(fiber.Map{"success": true, "contact": contact})
+}
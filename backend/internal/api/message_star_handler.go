@@ -0,0 +1,68 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/ws"
+)
+
+func (s *Server) handleStarMessage(c *fiber.Ctx) error {
+	return s.setMessageStarred(c, true)
+}
+
+func (s *Server) handleUnstarMessage(c *fiber.Ctx) error {
+	return s.setMessageStarred(c, false)
+}
+
+// setMessageStarred flags a message so agents can find it again from the
+// chat's starred list.
+func (s *Server) setMessageStarred(c *fiber.Ctx, starred bool) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	messageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid message ID"})
+	}
+	message, err := s.services.Chat.SetMessageStarred(c.Context(), accountID, messageID, starred)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if message == nil {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Message not found"})
+	}
+	s.invalidateMessagesCache(accountID, &message.ChatID)
+	s.hub.BroadcastToAccount(accountID, ws.EventMessageStarred, map[string]interface{}{
+		"chat_id":    message.ChatID.String(),
+		"id":         message.ID.String(),
+		"message_id": message.MessageID,
+		"starred":    message.Starred,
+	})
+	return c.JSON(fiber.Map{"success": true, "message": message})
+}
+
+// handleGetStarredMessages lists a chat's starred messages, newest first.
+func (s *Server) handleGetStarredMessages(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	chatID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid chat ID"})
+	}
+	chat, err := s.services.Chat.GetByID(c.Context(), chatID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if !chatBelongsToAccount(chat, accountID) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 {
+		limit = 50
+	} else if limit > 100 {
+		limit = 100
+	}
+	offset := c.QueryInt("offset", 0)
+	messages, total, err := s.services.Chat.GetStarredMessages(c.Context(), accountID, chatID, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "messages": messages, "total": total})
+}
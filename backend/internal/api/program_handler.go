@@ -1120,17 +1120,19 @@ func (s *Server) handleCreateCampaignFromProgram(c *fiber.Ctx) error {
 		recipients = append(recipients, rec)
 	}
 
+	var added service.CampaignRecipientAddResult
 	if len(recipients) > 0 {
-		if err := s.services.Campaign.AddRecipients(c.Context(), recipients); err != nil {
+		if added, err = s.services.Campaign.AddRecipients(c.Context(), recipients); err != nil {
 			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 		}
 	}
 	s.invalidateCampaignsCache(accountID)
 
 	return c.Status(201).JSON(fiber.Map{
-		"success":          true,
-		"campaign":         campaign,
-		"recipients_count": len(recipients),
+		"success":            true,
+		"campaign":           campaign,
+		"recipients_count":   added.Added,
+		"duplicates_skipped": added.DuplicatesSkipped,
 	})
 }
 
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+const replyTokenPrefix = "clrt_"
+
+// generateReplyToken creates a random chat reply token.
+// Format: clrt_<64 random hex chars>
+func generateReplyToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return replyTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// replyTokenTTL clamps a requested lifetime (in minutes) to the configured
+// maximum, falling back to the default when none is requested.
+func replyTokenTTL(requestedMinutes int, defaultTTL, maxTTL time.Duration) time.Duration {
+	ttl := defaultTTL
+	if requestedMinutes > 0 {
+		ttl = time.Duration(requestedMinutes) * time.Minute
+	}
+	if maxTTL > 0 && ttl > maxTTL {
+		ttl = maxTTL
+	}
+	return ttl
+}
+
+// replyTokenFromRequest reads the token from the Authorization header, or
+// from the JSON body for clients that cannot set headers.
+func replyTokenFromRequest(c *fiber.Ctx, bodyToken string) string {
+	if auth := strings.TrimSpace(c.Get("Authorization")); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return strings.TrimSpace(bodyToken)
+}
+
+func (s *Server) loadAccountChat(c *fiber.Ctx) (*domain.Chat, error) {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	chatID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid chat ID")
+	}
+	chat, err := s.services.Chat.GetByID(c.Context(), chatID)
+	if err != nil || !chatBelongsToAccount(chat, accountID) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Chat not found")
+	}
+	return chat, nil
+}
+
+// handleCreateReplyToken mints a token that can only reply to this chat.
+// POST /api/chats/:id/reply-tokens { "label": "Bot soporte", "ttl_minutes": 60 }
+// Returns the plaintext token ONCE — only its hash is stored.
+func (s *Server) handleCreateReplyToken(c *fiber.Ctx) error {
+	chat, err := s.loadAccountChat(c)
+	if err != nil {
+		e := err.(*fiber.Error)
+		return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+	}
+	var req struct {
+		Label      string `json:"label"`
+		TTLMinutes int    `json:"ttl_minutes"`
+	}
+	_ = c.BodyParser(&req)
+
+	rawToken, err := generateReplyToken()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to generate token"})
+	}
+	token := &domain.ChatReplyToken{
+		AccountID:   chat.AccountID,
+		ChatID:      chat.ID,
+		Label:       strings.TrimSpace(req.Label),
+		TokenHash:   hashAPIKey(rawToken),
+		TokenPrefix: rawToken[:13] + "...",
+		ExpiresAt:   time.Now().Add(replyTokenTTL(req.TTLMinutes, s.cfg.ReplyTokenDefaultTTL, s.cfg.ReplyTokenMaxTTL)),
+	}
+	var userID *uuid.UUID
+	if uid, ok := c.Locals("user_id").(uuid.UUID); ok {
+		userID = &uid
+		token.CreatedBy = &uid
+	}
+	if err := s.repos.ReplyToken.Create(c.Context(), token); err != nil {
+		log.Printf("[REPLY-TOKEN] Error creating token: %v", err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to create token"})
+	}
+	s.recordSecurityEventWithRefs(c.Context(), "reply_token_created", token.ID.String(), c, &chat.AccountID, userID, map[string]interface{}{
+		"chat_id":    chat.ID.String(),
+		"expires_at": token.ExpiresAt,
+	})
+	return c.Status(201).JSON(fiber.Map{"success": true, "token": rawToken, "reply_token": token})
+}
+
+// handleListReplyTokens lists tokens minted for a chat (never the secrets).
+// GET /api/chats/:id/reply-tokens
+func (s *Server) handleListReplyTokens(c *fiber.Ctx) error {
+	chat, err := s.loadAccountChat(c)
+	if err != nil {
+		e := err.(*fiber.Error)
+		return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+	}
+	tokens, err := s.repos.ReplyToken.ListByChat(c.Context(), chat.AccountID, chat.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to list tokens"})
+	}
+	return c.JSON(fiber.Map{"success": true, "reply_tokens": tokens})
+}
+
+// handleRevokeReplyToken revokes a token before it expires.
+// DELETE /api/chats/:id/reply-tokens/:tokenId
+func (s *Server) handleRevokeReplyToken(c *fiber.Ctx) error {
+	chat, err := s.loadAccountChat(c)
+	if err != nil {
+		e := err.(*fiber.Error)
+		return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+	}
+	tokenID, err := uuid.Parse(c.Params("tokenId"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "invalid token id"})
+	}
+	revoked, err := s.repos.ReplyToken.Revoke(c.Context(), chat.AccountID, chat.ID, tokenID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "failed to revoke token"})
+	}
+	if !revoked {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Token not found"})
+	}
+	var userID *uuid.UUID
+	if uid, ok := c.Locals("user_id").(uuid.UUID); ok {
+		userID = &uid
+	}
+	s.recordSecurityEventWithRefs(c.Context(), "reply_token_revoked", tokenID.String(), c, &chat.AccountID, userID, map[string]interface{}{
+		"chat_id": chat.ID.String(),
+	})
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handleInboundReply lets an external bot answer the single chat its token is
+// bound to. The token is validated (exists, not revoked, not expired, chat
+// still in the account) before anything is sent.
+// POST /api/inbound/reply { "token": "clrt_...", "body": "Hola" }
+func (s *Server) handleInboundReply(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+		Body  string `json:"body"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	rawToken := replyTokenFromRequest(c, req.Token)
+	if !strings.HasPrefix(rawToken, replyTokenPrefix) {
+		return c.Status(401).JSON(fiber.Map{"success": false, "error": "Token inválido"})
+	}
+	tokenHash := hashAPIKey(rawToken)
+	if err := s.checkAbuseLimits(c, "inbound_reply_rate_limited", tokenHash, []abuseLimit{
+		{Key: "abuse:inbound-reply:ip:minute:" + hashForLog(clientIP(c)), Max: 60, Window: time.Minute},
+		{Key: "abuse:inbound-reply:token:minute:" + tokenHash, Max: 20, Window: time.Minute},
+	}); err != nil {
+		e := err.(*fiber.Error)
+		return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "body is required"})
+	}
+
+	ctx := c.Context()
+	token, err := s.repos.ReplyToken.GetByHash(ctx, tokenHash)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo validar el token"})
+	}
+	if reason := replyTokenRejection(token, time.Now()); reason != "" {
+		var accountID *uuid.UUID
+		metadata := map[string]interface{}{"reason": reason}
+		if token != nil {
+			accountID = &token.AccountID
+			metadata["token_id"] = token.ID.String()
+		}
+		s.recordSecurityEventWithRefs(ctx, "inbound_reply_rejected", tokenHash, c, accountID, nil, metadata)
+		return c.Status(401).JSON(fiber.Map{"success": false, "error": "Token inválido o expirado"})
+	}
+
+	chat, err := s.services.Chat.GetByID(ctx, token.ChatID)
+	if err != nil || !chatBelongsToAccount(chat, token.AccountID) || chat.DeviceID == nil {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+	}
+	if _, err := s.requireManualDeviceForAccount(ctx, token.AccountID, *chat.DeviceID); err != nil {
+		if e, ok := err.(*fiber.Error); ok {
+			return c.Status(e.Code).JSON(fiber.Map{"success": false, "error": e.Message})
+		}
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if err := s.ensureOutboundContactAllowed(ctx, token.AccountID, chat.JID); err != nil {
+		if apiErr, ok := err.(*fiber.Error); ok {
+			return c.Status(apiErr.Code).JSON(fiber.Map{"success": false, "error": apiErr.Message, "code": "do_not_contact"})
+		}
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	message, err := s.services.Chat.SendMessage(ctx, *chat.DeviceID, chat.JID, body)
+	if err != nil {
+		log.Printf("[InboundReply] send failed account=%s chat=%s token=%s: %v", token.AccountID, chat.ID, token.ID, err)
+		return c.Status(502).JSON(fiber.Map{"success": false, "error": "No se pudo enviar el mensaje"})
+	}
+	s.repos.ReplyToken.MarkUsed(context.Background(), token.ID)
+	s.recordSecurityEventWithRefs(ctx, "inbound_reply_sent", token.ID.String(), c, &token.AccountID, nil, map[string]interface{}{
+		"chat_id":  chat.ID.String(),
+		"token_id": token.ID.String(),
+	})
+	s.invalidateChatCaches(token.AccountID, &chat.ID)
+	return c.JSON(fiber.Map{"success": true, "message": message})
+}
+
+// replyTokenRejection returns why a token cannot be used, or "" when valid.
+func replyTokenRejection(token *domain.ChatReplyToken, now time.Time) string {
+	switch {
+	case token == nil:
+		return "unknown"
+	case token.RevokedAt != nil:
+		return "revoked"
+	case !now.Before(token.ExpiresAt):
+		return "expired"
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestReplyTokenTTLClampsToMaximum(t *testing.T) {
+	if got := replyTokenTTL(0, time.Hour, 24*time.Hour); got != time.Hour {
+		t.Fatalf("default ttl = %v", got)
+	}
+	if got := replyTokenTTL(30, time.Hour, 24*time.Hour); got != 30*time.Minute {
+		t.Fatalf("requested ttl = %v", got)
+	}
+	if got := replyTokenTTL(60*48, time.Hour, 24*time.Hour); got != 24*time.Hour {
+		t.Fatalf("clamped ttl = %v", got)
+	}
+}
+
+func TestReplyTokenRejection(t *testing.T) {
+	now := time.Now()
+	revokedAt := now.Add(-time.Minute)
+	cases := map[string]*domain.ChatReplyToken{
+		"unknown": nil,
+		"revoked": {ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+		"expired": {ExpiresAt: now},
+		"":        {ExpiresAt: now.Add(time.Minute)},
+	}
+	for want, token := range cases {
+		if got := replyTokenRejection(token, now); got != want {
+			t.Fatalf("rejection = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestGenerateReplyTokenFormat(t *testing.T) {
+	token, err := generateReplyToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, replyTokenPrefix) || len(token) != len(replyTokenPrefix)+64 {
+		t.Fatalf("unexpected token format %q", token)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
+)
+
+// responseTimeChatLimit caps the per-chat list, slowest chats first.
+const responseTimeChatLimit = 50
+
+type responseTimeChat struct {
+	ChatID        uuid.UUID `json:"chat_id"`
+	Name          string    `json:"name"`
+	MedianSeconds *float64  `json:"median_seconds"`
+	Responded     int       `json:"responded"`
+	Unanswered    int       `json:"unanswered"`
+}
+
+type responseTimeStats struct {
+	Timezone      string                 `json:"timezone"`
+	Period        interactionStatsPeriod `json:"period"`
+	MedianSeconds *float64               `json:"median_seconds"`
+	Responded     int                    `json:"responded"`
+	Unanswered    int                    `json:"unanswered"`
+	Chats         []responseTimeChat     `json:"chats"`
+}
+
+// handleGetResponseTimes reports how long the business takes to answer
+// customers. A wait starts at the first inbound message after an outbound one
+// (or the chat's first message) and ends at the next outbound message, so a
+// burst of inbound messages counts once and chats the business opened only
+// count from the customer's first reply. Waits still open are reported as
+// unanswered and left out of the medians. Group, newsletter and broadcast
+// chats are ignored.
+// GET /api/stats/response-times?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (s *Server) handleGetResponseTimes(c *fiber.Ctx) error {
+	claims, ok := c.Locals("claims").(*service.JWTClaims)
+	if !ok || claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"success": false, "error": "Unauthorized"})
+	}
+	if !dashboardHasPermission(claims, domain.PermChats) && !s.dashboardCallerIsAdmin(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"success": false, "error": "No tienes permiso para ver estas estadísticas"})
+	}
+	period, err := resolveInteractionStatsPeriod(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	// Messages from the day before the range give the first inbound message
+	// of the range its context, so it isn't mistaken for the start of a wait.
+	rows, err := s.repos.DB().Query(c.Context(), `
+		WITH scoped AS (
+			SELECT m.chat_id, m.timestamp, COALESCE(m.is_from_me,FALSE) AS from_me
+			FROM messages m
+			JOIN chats ch ON ch.id=m.chat_id AND ch.account_id=m.account_id
+			WHERE m.account_id=$1 AND m.timestamp >= $2::timestamptz - INTERVAL '1 day'
+				AND NOT COALESCE(m.is_revoked,FALSE)
+				AND ch.jid NOT LIKE '%@g.us' AND ch.jid NOT LIKE '%@newsletter' AND ch.jid NOT LIKE '%@broadcast'
+		), ordered AS (
+			SELECT chat_id, timestamp, from_me,
+				LAG(from_me) OVER (PARTITION BY chat_id ORDER BY timestamp) AS prev_from_me,
+				MIN(CASE WHEN from_me THEN timestamp END) OVER (
+					PARTITION BY chat_id ORDER BY timestamp ROWS BETWEEN 1 FOLLOWING AND UNBOUNDED FOLLOWING
+				) AS reply_at
+			FROM scoped
+		), waits AS (
+			SELECT chat_id, EXTRACT(EPOCH FROM reply_at - timestamp)::float8 AS seconds
+			FROM ordered
+			WHERE NOT from_me AND COALESCE(prev_from_me,TRUE) AND timestamp >= $2 AND timestamp < $3
+		), agg AS (
+			SELECT chat_id, GROUPING(chat_id) AS overall,
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds) FILTER (WHERE seconds IS NOT NULL) AS median_seconds,
+				COUNT(seconds)::int AS responded,
+				COUNT(*) FILTER (WHERE seconds IS NULL)::int AS unanswered
+			FROM waits
+			GROUP BY GROUPING SETS ((chat_id), ())
+		), ranked AS (
+			SELECT agg.*, ROW_NUMBER() OVER (PARTITION BY overall ORDER BY median_seconds DESC NULLS LAST, chat_id) AS rn
+			FROM agg
+		)
+		SELECT ranked.overall, ranked.chat_id,
+			CASE WHEN ch.contact_id IS NULL
+			  THEN COALESCE(NULLIF(BTRIM(ch.name),''),'Sin nombre')
+			  ELSE COALESCE(NULLIF(BTRIM(contact.custom_name),''),NULLIF(BTRIM(contact.name),''),NULLIF(BTRIM(contact.push_name),''),NULLIF(BTRIM(contact.phone),''),'Sin nombre')
+			END,
+			ranked.median_seconds, ranked.responded, ranked.unanswered
+		FROM ranked
+		LEFT JOIN chats ch ON ch.id=ranked.chat_id
+		LEFT JOIN contacts contact ON contact.id=ch.contact_id AND contact.account_id=$1
+		WHERE ranked.overall=1 OR ranked.rn <= $4
+		ORDER BY ranked.overall DESC, ranked.rn
+	`, claims.AccountID, period.From, period.To, responseTimeChatLimit)
+	if err != nil {
+		log.Printf("response time query failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar los tiempos de respuesta"})
+	}
+	defer rows.Close()
+
+	stats := &responseTimeStats{Timezone: dashboardTimezone, Period: period, Chats: make([]responseTimeChat, 0)}
+	for rows.Next() {
+		var overall int
+		var chatID *uuid.UUID
+		var name *string
+		var median *float64
+		var responded, unanswered int
+		if err := rows.Scan(&overall, &chatID, &name, &median, &responded, &unanswered); err != nil {
+			log.Printf("response time scan failed: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar los tiempos de respuesta"})
+		}
+		if overall == 1 {
+			stats.MedianSeconds, stats.Responded, stats.Unanswered = median, responded, unanswered
+			continue
+		}
+		if chatID == nil {
+			continue
+		}
+		chat := responseTimeChat{ChatID: *chatID, MedianSeconds: median, Responded: responded, Unanswered: unanswered}
+		if name != nil {
+			chat.Name = *name
+		}
+		stats.Chats = append(stats.Chats, chat)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("response time query failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar los tiempos de respuesta"})
+	}
+	return c.JSON(fiber.Map{"success": true, "stats": stats})
+}
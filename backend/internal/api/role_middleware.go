@@ -0,0 +1,69 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
+)
+
+const roleForbiddenMessage = "Solo un administrador puede realizar esta acción"
+
+// claimsRole returns the highest role carried by the token. The legacy
+// IsAdmin/IsSuperAdmin flags count as their roles.
+func claimsRole(claims *service.JWTClaims) string {
+	switch {
+	case claims == nil:
+		return ""
+	case claims.IsSuperAdmin || claims.Role == domain.RoleSuperAdmin:
+		return domain.RoleSuperAdmin
+	case claims.IsAdmin || claims.Role == domain.RoleAdmin:
+		return domain.RoleAdmin
+	}
+	return claims.Role
+}
+
+// roleAllowed reports whether role satisfies any of roles, higher roles
+// included.
+func roleAllowed(role string, roles ...string) bool {
+	for _, required := range roles {
+		if domain.RoleAtLeast(role, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// callerHasRole checks the caller's token role and, when it falls short, the
+// current role in the account, since a promotion isn't reflected in tokens
+// issued before it.
+func (s *Server) callerHasRole(c *fiber.Ctx, roles ...string) bool {
+	claims, _ := c.Locals("claims").(*service.JWTClaims)
+	if claims == nil {
+		return false
+	}
+	if roleAllowed(claimsRole(claims), roles...) {
+		return true
+	}
+	if claims.APIKeyID != "" {
+		return false
+	}
+	var dbRole string
+	err := s.repos.DB().QueryRow(c.Context(),
+		`SELECT role FROM user_accounts WHERE user_id = $1 AND account_id = $2`,
+		claims.UserID, claims.AccountID).Scan(&dbRole)
+	return err == nil && roleAllowed(dbRole, roles...)
+}
+
+// requireRole returns a middleware that only lets through callers holding
+// one of roles or a higher one.
+func (s *Server) requireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("claims").(*service.JWTClaims); !ok {
+			return c.Status(401).JSON(fiber.Map{"success": false, "error": "Unauthorized"})
+		}
+		if !s.callerHasRole(c, roles...) {
+			return c.Status(403).JSON(fiber.Map{"success": false, "error": roleForbiddenMessage})
+		}
+		return c.Next()
+	}
+}
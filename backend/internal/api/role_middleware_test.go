@@ -0,0 +1,40 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
+)
+
+func TestClaimsRoleHonorsLegacyFlags(t *testing.T) {
+	cases := []struct {
+		claims *service.JWTClaims
+		want   string
+	}{
+		{nil, ""},
+		{&service.JWTClaims{Role: domain.RoleAgent}, domain.RoleAgent},
+		{&service.JWTClaims{Role: domain.RoleAgent, IsAdmin: true}, domain.RoleAdmin},
+		{&service.JWTClaims{Role: domain.RoleAdmin, IsSuperAdmin: true}, domain.RoleSuperAdmin},
+	}
+	for _, tc := range cases {
+		if got := claimsRole(tc.claims); got != tc.want {
+			t.Fatalf("claimsRole(%+v) = %q, want %q", tc.claims, got, tc.want)
+		}
+	}
+}
+
+func TestRoleAllowedFollowsHierarchy(t *testing.T) {
+	if roleAllowed(domain.RoleAgent, domain.RoleAdmin) {
+		t.Fatal("agent must not satisfy admin")
+	}
+	if !roleAllowed(domain.RoleSuperAdmin, domain.RoleAdmin) {
+		t.Fatal("super_admin must satisfy admin")
+	}
+	if !roleAllowed(domain.RoleAdmin, domain.RoleSuperAdmin, domain.RoleAdmin) {
+		t.Fatal("admin must satisfy a list containing admin")
+	}
+	if roleAllowed("", domain.RoleAgent) || roleAllowed("viewer", domain.RoleAgent) {
+		t.Fatal("unknown roles must not satisfy agent")
+	}
+}
@@ -148,6 +148,17 @@ func (s *Server) checkLoginAbuseLimit(c *fiber.Ctx, username string) error {
 	return s.checkAbuseLimits(c, "login_rate_limited", username, limits)
 }
 
+// checkAccountSettingsAbuseLimit throttles self-service credential/profile
+// mutations per user, independently from the global per-IP limiter.
+func (s *Server) checkAccountSettingsAbuseLimit(c *fiber.Ctx, action string, userID uuid.UUID) error {
+	userKey := hashForLog(userID.String())
+	limits := []abuseLimit{
+		{Key: "abuse:settings:" + action + ":user:minute:" + userKey, Max: 5, Window: time.Minute},
+		{Key: "abuse:settings:" + action + ":user:hour:" + userKey, Max: 20, Window: time.Hour},
+	}
+	return s.checkAbuseLimits(c, "settings_"+action+"_rate_limited", userID.String(), limits)
+}
+
 func (s *Server) checkAbuseLimits(c *fiber.Ctx, eventType, subject string, limits []abuseLimit) error {
 	for _, limit := range limits {
 		count, err := s.incrementAbuseCounter(c.Context(), limit.Key, limit.Window)
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -41,6 +42,7 @@ import (
 	"github.com/naperu/clarin/pkg/cache"
 	"github.com/naperu/clarin/pkg/config"
 	"github.com/naperu/clarin/pkg/database"
+	"github.com/naperu/clarin/pkg/webhook"
 	"go.mau.fi/whatsmeow/types"
 )
 
@@ -49,6 +51,14 @@ func strPtr(s string) *string {
 	return &s
 }
 
+// nilIfEmpty is strPtr that leaves empty strings unset.
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 type Server struct {
 	app            *fiber.App
 	cfg            *config.Config
@@ -61,12 +71,23 @@ type Server struct {
 	kommoManager   *kommo.Manager
 	cache          *cache.Cache
 	abuseLimiter   *inMemoryAbuseLimiter
+	webhooks       *webhook.Verifier
 	googleClient   *googleclient.Client
 	version        string
 	changelog      string
 	erosRunMu      sync.Mutex
 	erosRunCancels map[uuid.UUID]context.CancelFunc
 	erosRunSem     chan struct{}
+	thumbnailSem   chan struct{}
+}
+
+// webhookNonceStore shares replay protection across instances through Redis
+// when it is configured.
+func webhookNonceStore(c *cache.Cache) webhook.NonceStore {
+	if c == nil {
+		return webhook.NewMemoryNonceStore()
+	}
+	return webhook.NewSharedNonceStore(c)
 }
 
 func NewServer(cfg *config.Config, services *service.Services, repos *repository.Repositories, hub *ws.Hub, pool *whatsapp.DevicePool, store *storage.Storage, kommoSyncSvc *kommo.SyncService, kommoManager *kommo.Manager, c *cache.Cache, gc *googleclient.Client, version string) *Server {
@@ -125,9 +146,11 @@ func NewServer(cfg *config.Config, services *service.Services, repos *repository
 		SkipFailedRequests:     false,
 		SkipSuccessfulRequests: false,
 		Next: func(c *fiber.Ctx) bool {
-			// Skip rate limiting for media file endpoints and websocket
+			// Skip rate limiting for media file endpoints and websocket. Lead
+			// intake webhooks carry their own per-token limits.
 			path := c.Path()
-			return strings.HasPrefix(path, "/api/media/file/") || strings.HasPrefix(path, "/ws")
+			return strings.HasPrefix(path, "/api/media/file/") || strings.HasPrefix(path, "/ws") ||
+				strings.HasPrefix(path, "/api/webhooks/leads/")
 		},
 	}))
 
@@ -164,11 +187,13 @@ func NewServer(cfg *config.Config, services *service.Services, repos *repository
 		kommoManager:   kommoManager,
 		cache:          c,
 		abuseLimiter:   newInMemoryAbuseLimiter(),
+		webhooks:       webhook.NewVerifier(cfg.WebhookTimestampTolerance, webhookNonceStore(c)),
 		googleClient:   gc,
 		version:        version,
 		changelog:      changelogContent,
 		erosRunCancels: make(map[uuid.UUID]context.CancelFunc),
 		erosRunSem:     make(chan struct{}, 2),
+		thumbnailSem:   make(chan struct{}, 2),
 	}
 
 	app.Use(server.validateBrowserOrigin)
@@ -179,6 +204,12 @@ func NewServer(cfg *config.Config, services *service.Services, repos *repository
 		return c.Next()
 	})
 
+	if pool != nil {
+		pool.SetInboundMessageHook(server.onInboundMessage)
+	}
+	if hub != nil && repos != nil {
+		hub.SetAssigneeResolver(server.resolveEventAssignee)
+	}
 	server.setupRoutes()
 	server.startSurveyUploadCleanupWorker()
 	// Retention is an invariant of persisted status data, not a publishing
@@ -308,12 +339,19 @@ func (s *Server) setupRoutes() {
 	api.Get("/whatsapp/cloud/webhook", s.handleWhatsAppCloudVerify)
 	api.Post("/whatsapp/cloud/webhook", s.handleWhatsAppCloudWebhook)
 
+	// Bot replies authenticated by a chat-scoped, short-lived reply token.
+	api.Post("/inbound/reply", s.handleInboundReply)
+
+	// Lead intake from external forms, authenticated by an account token in the path.
+	api.Post("/webhooks/leads/:token", s.handleInboundLeadWebhook)
+
 	// Protected routes
 	protected := api.Group("", s.authMiddleware)
 
 	// User routes
 	protected.Get("/me", s.handleGetMe)
 	protected.Get("/me/accounts", s.handleGetMyAccounts)
+	protected.Get("/me/inbox", s.handleGetMyInbox)
 	protected.Post("/auth/logout", s.handleLogout)
 	protected.Post("/auth/activity", s.handleAuthActivity)
 	protected.Post("/auth/switch-account", s.handleSwitchAccount)
@@ -336,9 +374,10 @@ func (s *Server) setupRoutes() {
 	protected.Get("/account/users", s.handleGetAccountUsers)
 
 	// API Key management routes
-	protected.Post("/settings/api-keys", s.handleCreateAPIKey)
-	protected.Get("/settings/api-keys", s.handleListAPIKeys)
-	protected.Delete("/settings/api-keys/:id", s.handleDeleteAPIKey)
+	apiKeyAdmin := s.requireRole(domain.RoleAdmin)
+	protected.Post("/settings/api-keys", apiKeyAdmin, s.handleCreateAPIKey)
+	protected.Get("/settings/api-keys", apiKeyAdmin, s.handleListAPIKeys)
+	protected.Delete("/settings/api-keys/:id", apiKeyAdmin, s.handleDeleteAPIKey)
 
 	protected.Use(s.subscriptionAccessMiddleware)
 
@@ -353,9 +392,15 @@ func (s *Server) setupRoutes() {
 	devices.Put("/:id", s.handleUpdateDevice)
 	devices.Post("/:id/connect", s.handleConnectDevice)
 	devices.Post("/:id/disconnect", s.handleDisconnectDevice)
+	devices.Post("/:id/logout", s.handleLogoutDevice)
 	devices.Post("/:id/reset", s.handleResetDevice)
 	devices.Delete("/:id", s.handleDeleteDevice)
 	devices.Get("/health/all", s.handleDeviceHealth)
+	devices.Get("/:id/health", s.handleGetDeviceHealth)
+	devices.Get("/:id/events", s.handleGetDeviceEvents)
+	devices.Get("/:id/qr", s.handleGetDeviceQR)
+	devices.Get("/:id/qr/stream", s.handleStreamDeviceQR)
+	devices.Post("/:id/groups/sync", s.handleSyncDeviceGroups)
 
 	// Reporting routes — cross-functional read access is controlled independently.
 	reports := protected.Group("/reports", s.requirePermission(domain.PermReports))
@@ -374,20 +419,35 @@ func (s *Server) setupRoutes() {
 	chats.Get("/", s.handleGetChats)
 	chats.Get("/resolve-whatsapp/:phone", s.handleResolveWhatsAppChat)
 	chats.Get("/find-by-phone/:phone", s.handleFindChatByPhone)
+	chats.Get("/unread-summary", s.handleGetUnreadSummary)
+	chats.Get("/groups", s.handleListGroups)
 	// Chat operators need a phone-only contact lookup even when their role does
 	// not grant access to the full Contacts module.
 	chats.Get("/contacts/search", s.handleSearchChatContacts)
 	chats.Post("/new", s.handleCreateNewChat)
 	chats.Delete("/batch", s.handleDeleteChatsBatch)
-	chats.Post("/:id/contact", s.handleLinkChatContact)
-	chats.Get("/:id/opportunities/:opportunityId", s.handleGetChatOpportunity)
-	chats.Get("/:id", s.handleGetChatDetails)
-	chats.Get("/:id/messages/search", s.handleSearchMessages)
-	chats.Get("/:id/messages/:messageId/context", s.handleGetMessageContext)
-	chats.Get("/:id/messages", s.handleGetMessages)
-	chats.Post("/:id/read", s.handleMarkAsRead)
-	chats.Post("/:id/sync-history", s.handleRequestHistorySync)
-	chats.Delete("/:id", s.handleDeleteChat)
+	// Agents limited to their assigned chats cannot open others by ID.
+	visibleChat := s.requireVisibleChat
+	chats.Post("/:id/contact", visibleChat, s.handleLinkChatContact)
+	chats.Put("/:id/assign", s.requireRole(domain.RoleAdmin), s.handleAssignChat)
+	chats.Get("/:id/opportunities/:opportunityId", visibleChat, s.handleGetChatOpportunity)
+	chats.Get("/:id", visibleChat, s.handleGetChatDetails)
+	chats.Get("/:id/participants", visibleChat, s.handleGetChatParticipants)
+	chats.Get("/:id/messages/search", visibleChat, s.handleSearchMessages)
+	chats.Get("/:id/messages/:messageId/context", visibleChat, s.handleGetMessageContext)
+	chats.Get("/:id/messages", visibleChat, s.handleGetMessages)
+	chats.Get("/:id/starred", visibleChat, s.handleGetStarredMessages)
+	chats.Post("/:id/read", visibleChat, s.handleMarkAsRead)
+	chats.Post("/:id/archive", visibleChat, s.handleArchiveChat)
+	chats.Post("/:id/unarchive", visibleChat, s.handleUnarchiveChat)
+	chats.Post("/:id/pin", visibleChat, s.handlePinChat)
+	chats.Post("/:id/unpin", visibleChat, s.handleUnpinChat)
+	chats.Post("/:id/presence", visibleChat, s.handleSendChatPresence)
+	chats.Get("/:id/reply-tokens", visibleChat, s.handleListReplyTokens)
+	chats.Post("/:id/reply-tokens", visibleChat, s.handleCreateReplyToken)
+	chats.Delete("/:id/reply-tokens/:tokenId", visibleChat, s.handleRevokeReplyToken)
+	chats.Post("/:id/sync-history", visibleChat, s.handleRequestHistorySync)
+	chats.Delete("/:id", visibleChat, s.handleDeleteChat)
 
 	// Official Cloud API inbox. It intentionally has its own route surface so
 	// provider capabilities cannot leak into the legacy WhatsApp Web controls.
@@ -395,15 +455,17 @@ func (s *Server) setupRoutes() {
 	chatAPI.Get("/channels", s.handleListChatAPIChannels)
 	chatAPI.Get("/templates", s.handleListChatAPITemplates)
 	chatAPI.Get("/chats", s.handleGetChatAPIChats)
-	chatAPI.Get("/chats/:id", s.requireChatAPIConversation, s.handleGetChatDetails)
-	chatAPI.Get("/chats/:id/messages", s.requireChatAPIConversation, s.handleGetMessages)
-	chatAPI.Post("/chats/:id/read", s.requireChatAPIConversation, s.handleMarkChatAPIRead)
+	chatAPI.Get("/chats/:id", s.requireChatAPIConversation, s.requireVisibleChat, s.handleGetChatDetails)
+	chatAPI.Get("/chats/:id/messages", s.requireChatAPIConversation, s.requireVisibleChat, s.handleGetMessages)
+	chatAPI.Post("/chats/:id/read", s.requireChatAPIConversation, s.requireVisibleChat, s.handleMarkChatAPIRead)
 	chatAPI.Post("/messages/send", s.handleSendWhatsAppCloudMessage)
 
 	// Message routes
 	messages := protected.Group("/messages", s.requirePermission(domain.PermChats))
 	messages.Post("/send", s.handleSendMessage)
 	messages.Post("/send-contact", s.handleSendContact)
+	messages.Post("/contact-card", s.handleSendContactCard)
+	messages.Post("/location", s.handleSendLocation)
 	messages.Post("/forward", s.handleForwardMessage)
 	messages.Post("/react", s.handleSendReaction)
 	messages.Post("/poll", s.handleSendPoll)
@@ -411,7 +473,11 @@ func (s *Server) setupRoutes() {
 	messages.Post("/typing", s.handleSendTyping)
 	messages.Post("/read-receipt", s.handleSendReadReceipt)
 	messages.Post("/delete", s.handleDeleteMessage)
+	messages.Post("/delete-for-everyone", s.handleDeleteMessage)
 	messages.Post("/edit", s.handleEditMessage)
+	messages.Post("/:id/star", s.handleStarMessage)
+	messages.Delete("/:id/star", s.handleUnstarMessage)
+	messages.Post("/:id/add-to-contacts", s.requirePermission(domain.PermContacts), s.handleAddContactFromCard)
 
 	// WhatsApp utilities
 	protected.Post("/contacts/check-whatsapp", s.requirePermission(domain.PermChats), s.handleCheckWhatsApp)
@@ -436,6 +502,7 @@ func (s *Server) setupRoutes() {
 	media := protected.Group("/media")
 	media.Get("/upload-url", s.handleGetUploadURL)
 	media.Post("/upload", s.handleDirectUpload)
+	media.Get("/download-url", s.handleGetDownloadURL)
 
 	// Lead routes
 	leads := protected.Group("/leads", s.requirePermission(domain.PermLeads))
@@ -444,48 +511,64 @@ func (s *Server) setupRoutes() {
 	leads.Get("/list-paginated", s.handleGetLeadsListPaginated)
 	leads.Get("/counts", s.handleGetLeadCounts)
 	leads.Get("/by-stage/:stageId", s.handleGetLeadsByStage)
+	leads.Get("/trash", s.handleGetLeadTrash)
+	leads.Get("/export", s.handleExportLeadsCSV)
+	leads.Get("/scoring-rules", s.handleGetLeadScoringRules)
+	leads.Put("/scoring-rules", s.handleUpdateLeadScoringRules)
 	leads.Post("/", s.handleCreateLeadProfessional)
 	leads.Post("/from-contacts", s.handleCreateLeadsFromContacts)
 	leads.Delete("/batch", s.handleTrashLeadsBatch)
+	leads.Post("/bulk-stage", s.handleBulkMoveLeadsStage)
 	leads.Post("/observations/batch", s.handleBatchLeadObservations)
 	leads.Patch("/batch/archive", s.handleArchiveLeadsBatchSafe)
 	leads.Patch("/batch/block", s.handleBlockLeadsBatchCompatibility)
-	leads.Patch("/:id/restore", s.handleRestoreLead)
-	leads.Delete("/:id/purge", s.handlePurgeLead)
-	leads.Get("/:id", s.handleGetLead)
-	leads.Put("/:id", s.handleUpdateLead)
-	leads.Delete("/:id", s.handleTrashLead)
-	leads.Patch("/:id/status", s.handleRejectDirectLeadStatus)
-	leads.Patch("/:id/stage", s.handleMoveLeadToStage)
-	leads.Get("/:id/interactions", s.handleGetLeadInteractions)
+	visibleLead := s.requireVisibleLead
+	leads.Patch("/:id/restore", visibleLead, s.handleRestoreLead)
+	leads.Post("/:id/restore", visibleLead, s.handleRestoreLead)
+	leads.Delete("/:id/purge", visibleLead, s.handlePurgeLead)
+	leads.Get("/:id", visibleLead, s.handleGetLead)
+	leads.Put("/:id", visibleLead, s.handleUpdateLead)
+	leads.Delete("/:id", visibleLead, s.handleTrashLead)
+	leads.Patch("/:id/status", visibleLead, s.handleRejectDirectLeadStatus)
+	leads.Patch("/:id/stage", visibleLead, s.handleMoveLeadToStage)
+	leads.Get("/:id/interactions", visibleLead, s.handleGetLeadInteractions)
+	leads.Get("/:id/timeline", visibleLead, s.handleGetLeadTimeline)
 	if kommo.APICommunicationEnabled {
-		leads.Post("/:id/sync-kommo", s.requirePlanFeature("kommo_sync"), s.handleSyncLeadFromKommo)
+		leads.Post("/:id/sync-kommo", visibleLead, s.requirePlanFeature("kommo_sync"), s.handleSyncLeadFromKommo)
 	}
-	leads.Patch("/:id/archive", s.handleArchiveLeadSafe)
-	leads.Patch("/:id/block", s.handleBlockLeadCompatibility)
+	leads.Patch("/:id/archive", visibleLead, s.handleArchiveLeadSafe)
+	leads.Patch("/:id/block", visibleLead, s.handleBlockLeadCompatibility)
 
 	// Pipeline routes
 	protected.Get("/pipeline-templates", s.requirePermission(domain.PermLeads), s.handleGetPipelineTemplates)
 	pipelines := protected.Group("/pipelines", s.requirePermission(domain.PermLeads))
 	pipelines.Get("/", s.handleGetPipelines)
-	pipelines.Post("/", s.handleCreatePipelineProfessional)
-	pipelines.Put("/:id", s.handleUpdatePipeline)
-	pipelines.Delete("/:id", s.handleDeletePipeline)
-	pipelines.Post("/:id/stages", s.handleCreatePipelineStageSafe)
-	pipelines.Put("/:id/stages/layout", s.handleSavePipelineStageLayout)
-	pipelines.Put("/:id/stages/reorder", s.handleReorderPipelineStagesSafe)
-	pipelines.Put("/:id/stages/:stageId", s.handleUpdatePipelineStageSafe)
-	pipelines.Delete("/:id/stages/:stageId", s.handleDeletePipelineStageSafe)
+	// Changing the pipeline structure is admin-only; agents work leads within it.
+	pipelineAdmin := s.requireRole(domain.RoleAdmin)
+	pipelines.Post("/", pipelineAdmin, s.handleCreatePipelineProfessional)
+	pipelines.Put("/:id", pipelineAdmin, s.handleUpdatePipeline)
+	pipelines.Get("/:id/funnel", s.handleGetPipelineFunnel)
+	pipelines.Get("/:id/board", s.handleGetPipelineBoard)
+	pipelines.Delete("/:id", pipelineAdmin, s.handleDeletePipeline)
+	pipelines.Post("/:id/stages", pipelineAdmin, s.handleCreatePipelineStageSafe)
+	pipelines.Put("/:id/stages/layout", pipelineAdmin, s.handleSavePipelineStageLayout)
+	pipelines.Put("/:id/stages/reorder", pipelineAdmin, s.handleReorderPipelineStagesSafe)
+	pipelines.Put("/:id/stages/:stageId", pipelineAdmin, s.handleUpdatePipelineStageSafe)
+	pipelines.Delete("/:id/stages/:stageId", pipelineAdmin, s.handleDeletePipelineStageSafe)
 
 	// Tag routes
 	tags := protected.Group("/tags", s.requirePermission(domain.PermTags))
 	tags.Get("/", s.handleGetTags)
 	tags.Post("/", s.handleCreateTag)
+	tags.Get("/usage", s.handleGetTagUsage)
 	tags.Put("/:id", s.handleUpdateTag)
 	tags.Delete("/batch", s.handleDeleteTagsBatch)
 	tags.Delete("/:id", s.handleDeleteTag)
 	tags.Post("/assign", s.handleAssignTag)
 	tags.Post("/remove", s.handleRemoveTag)
+	tags.Post("/assign-bulk", s.handleAssignTagBulk)
+	tags.Post("/remove-bulk", s.handleRemoveTagBulk)
+	tags.Post("/merge", s.handleMergeTags)
 	tags.Get("/entity/:type/:id", s.handleGetEntityTags)
 
 	// Campaign routes
@@ -568,12 +651,17 @@ func (s *Server) setupRoutes() {
 	campaigns.Post("/:id/pause", s.handlePauseCampaign)
 	campaigns.Post("/:id/cancel", s.handleCancelCampaign)
 	campaigns.Post("/:id/duplicate", s.handleDuplicateCampaign)
+	campaigns.Post("/:id/validate", s.handleValidateCampaign)
+	campaigns.Post("/:id/preview", s.handlePreviewCampaign)
+	campaigns.Get("/:id/analytics", s.handleGetCampaignAnalytics)
 	campaigns.Post("/:id/recipients/:rid/retry", s.handleRetryCampaignRecipient)
 	campaigns.Put("/:id/attachments", s.handleUpdateCampaignAttachments)
 
 	// Import CSV route
 	protected.Post("/import/csv/preview", s.handlePreviewImportCSV)
 	protected.Post("/import/csv", s.handleImportCSV)
+	protected.Get("/import/:jobId", s.handleGetCSVImportJob)
+	protected.Get("/import/:jobId/errors.csv", s.handleDownloadCSVImportErrors)
 
 	// Contact routes
 	contacts := protected.Group("/contacts", s.requirePermission(domain.PermContacts))
@@ -585,6 +673,8 @@ func (s *Server) setupRoutes() {
 	contacts.Post("/merge/preview", s.handlePreviewMergeContacts)
 	contacts.Post("/merge", s.handleMergeContacts)
 	contacts.Delete("/batch", s.handleDeleteContactsBatch)
+	contacts.Get("/trash", s.handleGetContactTrash)
+	contacts.Post("/:id/restore", s.handleRestoreContact)
 	contacts.Get("/:id", s.handleGetContact)
 	contacts.Get("/:id/leads", s.handleGetContactLeads)
 	contacts.Patch("/:id/do-not-contact", s.handleSetContactDoNotContact)
@@ -649,6 +739,7 @@ func (s *Server) setupRoutes() {
 	events.Post("/:id/duplicate", s.handleDuplicateEvent)
 	events.Patch("/:id/move-folder", s.handleMoveEventToFolder)
 	// Event tag auto-sync
+	events.Get("/:id/stats", s.handleGetEventStats)
 	events.Get("/:id/tags", s.handleGetEventTags)
 	events.Post("/:id/tags/preview", s.handlePreviewEventTags)
 	events.Put("/:id/tags", s.handleSetEventTags)
@@ -662,11 +753,14 @@ func (s *Server) setupRoutes() {
 	events.Get("/:id/participants", s.handleGetEventParticipants)
 	events.Post("/:id/participants", s.handleAddEventParticipant)
 	events.Post("/:id/participants/bulk", s.handleBulkAddEventParticipants)
+	events.Post("/:id/participants/import", s.handleImportEventParticipants)
 	events.Patch("/:id/participants/bulk-status", s.handleBulkUpdateEventParticipantStatus)
 	events.Patch("/:id/participants/bulk-stage", s.handleBulkUpdateEventParticipantStage)
+	events.Post("/:id/checkin-bulk", s.handleBulkCheckInEventParticipants)
 	events.Get("/:id/participants/:pid", s.handleGetEventParticipant)
 	events.Put("/:id/participants/:pid", s.handleUpdateEventParticipant)
 	events.Patch("/:id/participants/:pid/status", s.handleUpdateEventParticipantStatus)
+	events.Post("/:id/participants/:pid/checkin", s.handleCheckInEventParticipant)
 	events.Patch("/:id/participants/:pid/stage", s.handleUpdateEventParticipantStage)
 	events.Delete("/:id/participants/:pid", s.handleDeleteEventParticipant)
 	events.Post("/:id/participants/:pid/check-tag-impact", s.handleCheckTagImpact)
@@ -730,19 +824,49 @@ func (s *Server) setupRoutes() {
 	docTemplates.Delete("/:id", s.handleDeleteDocumentTemplate)
 	docTemplates.Post("/:id/duplicate", s.handleDuplicateDocumentTemplate)
 
+	// Keyword auto-tag rules applied to inbound messages
+	autoTagRules := protected.Group("/auto-tag-rules", s.requirePermission(domain.PermTags))
+	autoTagRules.Get("/", s.handleListAutoTagRules)
+	autoTagRules.Post("/", s.handleCreateAutoTagRule)
+	autoTagRules.Put("/:id", s.handleUpdateAutoTagRule)
+	autoTagRules.Delete("/:id", s.handleDeleteAutoTagRule)
+
 	// Quick replies (canned responses)
 	quickReplies := protected.Group("/quick-replies", s.requirePermission(domain.PermChats))
 	quickReplies.Get("/", s.handleGetQuickReplies)
 	quickReplies.Post("/", s.handleCreateQuickReply)
 	quickReplies.Put("/:id", s.handleUpdateQuickReply)
 	quickReplies.Delete("/:id", s.handleDeleteQuickReply)
+	quickReplies.Get("/:id/render", s.handleRenderQuickReply)
+	quickReplies.Post("/:id/used", s.handleMarkQuickReplyUsed)
 
 	// Legacy per-account Kommo configuration routes are disabled. Kommo is now
 	// administered centrally through /admin/integrations and assigned to account groups.
+	// Only the per-account field mappings and the connection test remain here.
 	kommoGroup := protected.Group("/kommo")
+	kommoGroup.Get("/field-mappings", s.requirePermission(domain.PermIntegrations), s.handleGetKommoFieldMappings)
+	kommoGroup.Put("/field-mappings", s.requirePermission(domain.PermIntegrations), s.handleUpdateKommoFieldMappings)
+	kommoGroup.Post("/test", s.requirePermission(domain.PermIntegrations), s.handleKommoTestConnection)
 	kommoGroup.All("/", s.handleKommoLegacyDisabled)
 	kommoGroup.All("/*", s.handleKommoLegacyDisabled)
 
+	// Optional SMS fallback for campaign recipients without WhatsApp
+	smsGroup := protected.Group("/sms", s.requirePermission(domain.PermIntegrations))
+	smsGroup.Get("/settings", s.handleGetSMSSettings)
+	smsGroup.Put("/settings", s.handleUpdateSMSSettings)
+	smsGroup.Delete("/settings", s.handleDeleteSMSSettings)
+
+	// Outbound webhooks (signed event notifications)
+	webhooksGroup := protected.Group("/webhooks", s.requirePermission(domain.PermIntegrations))
+	webhooksGroup.Get("/settings", s.handleGetWebhookSettings)
+	webhooksGroup.Put("/settings", s.handleUpdateWebhookSettings)
+	webhooksGroup.Delete("/settings", s.handleDeleteWebhookSettings)
+	webhooksGroup.Post("/test", s.handleTestWebhook)
+	webhooksGroup.Get("/deliveries", s.handleGetWebhookDeliveries)
+	webhooksGroup.Get("/lead-tokens", s.handleListLeadWebhookTokens)
+	webhooksGroup.Post("/lead-tokens", s.handleCreateLeadWebhookToken)
+	webhooksGroup.Delete("/lead-tokens/:tokenId", s.handleRevokeLeadWebhookToken)
+
 	// Google Contacts integration routes
 	googleGroup := protected.Group("/google", s.requirePermission(domain.PermIntegrations))
 	googleGroup.Get("/auth-url", s.handleGoogleAuthURL)
@@ -892,6 +1016,8 @@ func (s *Server) setupRoutes() {
 	// Stats
 	protected.Get("/dashboard/summary", s.handleGetDashboardSummary)
 	protected.Get("/stats", s.handleGetStats)
+	protected.Get("/stats/interactions", s.handleGetInteractionStats)
+	protected.Get("/stats/response-times", s.handleGetResponseTimes)
 
 	// Eros Assistant (Codex Bridge + MCP shared tools)
 	protected.Get("/eros/status", s.handleErosStatus)
@@ -918,12 +1044,14 @@ func (s *Server) setupRoutes() {
 	protected.Delete("/ai/conversations/:id", s.handleDeleteErosConversation)
 
 	// Super Admin routes
-	admin := protected.Group("/admin", s.superAdminMiddleware)
+	admin := protected.Group("/admin", s.superAdminMiddleware, s.auditAdminAction)
+	admin.Get("/audit", s.handleAdminListAudit)
 
 	// Account management
 	admin.Get("/plans", s.handleListPlans)
 	admin.Get("/storage/orphans", s.handleAdminStorageOrphans)
 	admin.Post("/storage/orphans/cleanup", s.handleAdminCleanupStorageOrphans)
+	admin.Get("/websocket/clients", s.handleAdminWebSocketClients)
 	adminAccounts := admin.Group("/accounts")
 	adminAccounts.Get("/", s.handleAdminGetAccounts)
 	adminAccounts.Post("/", s.handleAdminCreateAccount)
@@ -937,6 +1065,7 @@ func (s *Server) setupRoutes() {
 	adminAccounts.Patch("/:id/toggle", s.handleAdminToggleAccount)
 	adminAccounts.Get("/:id/purge-preview", s.handleAdminAccountPurgePreview)
 	adminAccounts.Delete("/:id/purge", s.handleAdminPurgeAccount)
+	adminAccounts.Post("/:id/anonymize", s.handleAdminAnonymizeAccount)
 	adminAccounts.Delete("/:id", s.handleAdminDeleteAccount)
 
 	// User management
@@ -989,6 +1118,7 @@ func (s *Server) setupRoutes() {
 	adminIntegrations.Delete("/:id", s.handleAdminDeleteIntegration)
 	adminIntegrations.Post("/:id/accounts", s.handleAdminAssignIntegrationAccount)
 	adminIntegrations.Delete("/:id/accounts/:account_id", s.handleAdminRemoveIntegrationAccount)
+	adminIntegrations.Put("/:id/accounts/:account_id/conflict-policy", s.handleAdminSetKommoConflictPolicy)
 	adminIntegrations.Post("/:id/reload", s.handleAdminReloadIntegrations)
 	adminIntegrations.Get("/:id/monitor", s.handleAdminIntegrationMonitor)
 	adminIntegrations.Get("/:id/health", s.handleAdminIntegrationHealth)
@@ -1005,6 +1135,11 @@ func (s *Server) authMiddleware(c *fiber.Ctx) error {
 			token = strings.TrimSpace(authHeader[7:])
 		}
 	}
+	if token == "" {
+		if apiKey := strings.TrimSpace(c.Get("X-API-Key")); apiKey != "" {
+			return s.authenticateAPIKey(c, apiKey)
+		}
+	}
 	if token == "" {
 		// Try query param (for file downloads)
 		token = c.Query("token")
@@ -1073,6 +1208,14 @@ func (s *Server) requirePermission(module string) fiber.Handler {
 			}
 		}
 
+		// API keys carry their own role, never the creator's
+		if claims.APIKeyID != "" {
+			return c.Status(403).JSON(fiber.Map{
+				"success": false,
+				"error":   "No tienes permiso para acceder a este módulo",
+			})
+		}
+
 		// Fallback: check actual per-account role from DB (handles stale JWTs)
 		var dbRole string
 		err := s.repos.DB().QueryRow(c.Context(),
@@ -1136,6 +1279,39 @@ func (s *Server) wsUpgrade(c *fiber.Ctx) error {
 
 		c.Locals("claims", claims)
 		c.Locals("ws_permissions", permissions)
+		visibility := make(map[uuid.UUID]*domain.AssignmentScope)
+		if scope := s.assignmentScopeFor(c.Context(), claims.AccountID, claims.UserID, isAdmin); scope != nil {
+			visibility[claims.AccountID] = scope
+		}
+
+		// Aggregated inbox mode follows every account the user can read chats
+		// in, each filtered by the role held there.
+		if c.QueryBool("aggregate") {
+			accounts, err := s.inboxAccountsForUser(c.Context(), claims)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve accounts"})
+			}
+			accountPermissions := make(map[uuid.UUID]map[string]bool, len(accounts))
+			for _, a := range accounts {
+				perms := make(map[string]bool, len(a.Permissions))
+				for _, p := range a.Permissions {
+					perms[p] = true
+				}
+				accountPermissions[a.AccountID] = perms
+				if a.AccountID == claims.AccountID {
+					continue
+				}
+				scope, err := s.inboxAccountScope(c.Context(), claims, a)
+				if err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve accounts"})
+				}
+				if scope != nil {
+					visibility[a.AccountID] = scope
+				}
+			}
+			c.Locals("ws_account_permissions", accountPermissions)
+		}
+		c.Locals("ws_visibility", visibility)
 		return c.Next()
 	}
 	return fiber.ErrUpgradeRequired
@@ -1304,6 +1480,9 @@ func (s *Server) handleRefreshToken(c *fiber.Ctx) error {
 
 	newToken, newRefreshToken, err := s.services.Auth.RefreshToken(c.Context(), refreshToken, s.cfg.JWTSecret)
 	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			s.recordSecurityEvent(c.Context(), "refresh_token_reuse", "", c, nil)
+		}
 		s.clearAuthCookies(c)
 		return c.Status(401).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
@@ -1373,7 +1552,13 @@ func (s *Server) handleGetMe(c *fiber.Ctx) error {
 
 	// Compute permissions: admins get wildcard, agents get role-based permissions
 	var permissions []string
-	if isAdmin {
+	role, isSuperAdmin := user.Role, user.IsSuperAdmin
+	if claims.APIKeyID != "" {
+		// An API key reports its own effective role, not its creator's.
+		role, isSuperAdmin = claimsRole(claims), false
+		isAdmin = claims.IsAdmin
+		permissions = claims.Permissions
+	} else if isAdmin {
 		permissions = []string{domain.PermAll}
 	} else {
 		permissions = claims.Permissions
@@ -1426,8 +1611,8 @@ func (s *Server) handleGetMe(c *fiber.Ctx) error {
 			"email":                  user.Email,
 			"display_name":           user.DisplayName,
 			"is_admin":               isAdmin,
-			"is_super_admin":         user.IsSuperAdmin,
-			"role":                   user.Role,
+			"is_super_admin":         isSuperAdmin,
+			"role":                   role,
 			"account_id":             accountID,
 			"account_name":           activeAccountName,
 			"plan":                   plan,
@@ -1513,17 +1698,23 @@ func (s *Server) handleGetSettings(c *fiber.Ctx) error {
 
 	if account != nil {
 		result["account"] = fiber.Map{
-			"id":                        account.ID,
-			"name":                      account.Name,
-			"slug":                      account.Slug,
-			"plan":                      account.Plan,
-			"storage_limit_bytes":       account.StorageLimitBytes,
-			"subscription_status":       account.SubscriptionStatus,
-			"trial_ends_at":             account.TrialEndsAt,
-			"current_period_end":        account.CurrentPeriodEnd,
-			"grace_ends_at":             account.GraceEndsAt,
-			"created_at":                account.CreatedAt,
-			"default_incoming_stage_id": account.DefaultIncomingStageID,
+			"id":                         account.ID,
+			"name":                       account.Name,
+			"slug":                       account.Slug,
+			"plan":                       account.Plan,
+			"storage_limit_bytes":        account.StorageLimitBytes,
+			"subscription_status":        account.SubscriptionStatus,
+			"trial_ends_at":              account.TrialEndsAt,
+			"current_period_end":         account.CurrentPeriodEnd,
+			"grace_ends_at":              account.GraceEndsAt,
+			"created_at":                 account.CreatedAt,
+			"default_incoming_stage_id":  account.DefaultIncomingStageID,
+			"campaign_confirm_threshold": account.CampaignConfirmThreshold,
+			"quiet_hours_start":          account.QuietHoursStart,
+			"quiet_hours_end":            account.QuietHoursEnd,
+			"timezone":                   account.Timezone,
+			"restrict_to_assigned":       account.RestrictToAssigned,
+			"agents_see_unassigned":      account.AgentsSeeUnassigned,
 		}
 	}
 
@@ -1574,6 +1765,9 @@ func (s *Server) handleSetIncomingStage(c *fiber.Ctx) error {
 
 func (s *Server) handleUpdateProfile(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uuid.UUID)
+	if err := s.checkAccountSettingsAbuseLimit(c, "profile", userID); err != nil {
+		return err
+	}
 
 	var req struct {
 		Name  string `json:"name"`
@@ -1606,11 +1800,28 @@ func (s *Server) handleUpdateAccount(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 
 	var req struct {
-		Name string `json:"name"`
+		Name                     string  `json:"name"`
+		CampaignConfirmThreshold *int    `json:"campaign_confirm_threshold"`
+		QuietHoursStart          *string `json:"quiet_hours_start"`
+		QuietHoursEnd            *string `json:"quiet_hours_end"`
+		Timezone                 *string `json:"timezone"`
+		RestrictToAssigned       *bool   `json:"restrict_to_assigned"`
+		AgentsSeeUnassigned      *bool   `json:"agents_see_unassigned"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
+	if req.CampaignConfirmThreshold != nil && *req.CampaignConfirmThreshold < 0 {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "campaign_confirm_threshold must be 0 or greater"})
+	}
+	// The campaign safeguards and lead visibility are account policy: only
+	// admins may change them.
+	quietChanged := req.QuietHoursStart != nil || req.QuietHoursEnd != nil || req.Timezone != nil
+	visibilityChanged := req.RestrictToAssigned != nil || req.AgentsSeeUnassigned != nil
+	policyChanged := req.CampaignConfirmThreshold != nil || quietChanged || visibilityChanged
+	if policyChanged && !s.callerHasRole(c, domain.RoleAdmin) {
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": roleForbiddenMessage})
+	}
 
 	account, err := s.services.Account.GetByID(c.Context(), accountID)
 	if err != nil || account == nil {
@@ -1620,16 +1831,58 @@ func (s *Server) handleUpdateAccount(c *fiber.Ctx) error {
 	if req.Name != "" {
 		account.Name = req.Name
 	}
+	// Quiet hours and timezone are validated together; "" clears a bound.
+	if quietChanged {
+		if req.QuietHoursStart != nil {
+			account.QuietHoursStart = stringPtr(strings.TrimSpace(*req.QuietHoursStart))
+		}
+		if req.QuietHoursEnd != nil {
+			account.QuietHoursEnd = stringPtr(strings.TrimSpace(*req.QuietHoursEnd))
+		}
+		if req.Timezone != nil {
+			account.Timezone = strings.TrimSpace(*req.Timezone)
+		}
+		if account.Timezone == "" {
+			account.Timezone = service.DefaultAccountTimezone
+		}
+		if err := service.ValidateAccountQuietHours(account.QuietHoursStart, account.QuietHoursEnd, account.Timezone); err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+	}
 
 	if err := s.services.Account.Update(c.Context(), account); err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to update account"})
 	}
+	if req.CampaignConfirmThreshold != nil {
+		if err := s.repos.Account.SetCampaignConfirmThreshold(c.Context(), accountID, *req.CampaignConfirmThreshold); err != nil {
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to update account"})
+		}
+	}
+	if quietChanged {
+		if err := s.repos.Account.SetQuietHours(c.Context(), accountID, account.QuietHoursStart, account.QuietHoursEnd, account.Timezone); err != nil {
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to update account"})
+		}
+	}
+	if visibilityChanged {
+		if req.RestrictToAssigned != nil {
+			account.RestrictToAssigned = *req.RestrictToAssigned
+		}
+		if req.AgentsSeeUnassigned != nil {
+			account.AgentsSeeUnassigned = *req.AgentsSeeUnassigned
+		}
+		if err := s.repos.Account.SetAssignmentVisibility(c.Context(), accountID, account.RestrictToAssigned, account.AgentsSeeUnassigned); err != nil {
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to update account"})
+		}
+	}
 
 	return c.JSON(fiber.Map{"success": true})
 }
 
 func (s *Server) handleChangePassword(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uuid.UUID)
+	if err := s.checkAccountSettingsAbuseLimit(c, "password", userID); err != nil {
+		return err
+	}
 
 	var req struct {
 		CurrentPassword string `json:"currentPassword"`
@@ -1642,10 +1895,19 @@ func (s *Server) handleChangePassword(c *fiber.Ctx) error {
 	if err := s.services.Auth.ChangePassword(c.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
+	// A password change must end every other session: a stolen cookie or
+	// refresh token would otherwise keep working with the old credentials.
+	keepSessionID := ""
+	if claims, ok := c.Locals("claims").(*service.JWTClaims); ok && claims != nil {
+		keepSessionID = claims.SessionID
+	}
+	revoked := s.services.Auth.RevokeOtherSessions(c.Context(), userID, keepSessionID)
 	accountID, _ := c.Locals("account_id").(uuid.UUID)
-	s.recordSecurityEventWithRefs(c.Context(), "password_changed", "", c, &accountID, &userID, nil)
+	s.recordSecurityEventWithRefs(c.Context(), "password_changed", "", c, &accountID, &userID, map[string]interface{}{
+		"revoked_sessions": revoked,
+	})
 
-	return c.JSON(fiber.Map{"success": true})
+	return c.JSON(fiber.Map{"success": true, "revoked_sessions": revoked})
 }
 
 // --- Device Handlers ---
@@ -1799,7 +2061,40 @@ func (s *Server) handleDisconnectDevice(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{"success": true, "message": "Device disconnected"})
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"message":     "Device disconnected",
+		"status":      domain.DeviceStatusDisconnected,
+		"requires_qr": false,
+	})
+}
+
+// handleLogoutDevice ends the WhatsApp session. Unlike disconnect, the stored
+// credentials are deleted, so the response tells the UI to ask for a new scan.
+func (s *Server) handleLogoutDevice(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	deviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
+	}
+	dev, _ := s.services.Device.GetByID(c.Context(), deviceID)
+	if dev == nil || dev.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Device not found"})
+	}
+	if isCloudAPIDevice(dev) {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Este canal usa WhatsApp API Oficial y no tiene sesión QR"})
+	}
+
+	if err := s.services.Device.Logout(c.Context(), deviceID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"message":     "Device logged out. Connect and scan a new QR code to pair it again.",
+		"status":      domain.DeviceStatusLoggedOut,
+		"requires_qr": true,
+	})
 }
 
 func (s *Server) handleResetDevice(c *fiber.Ctx) error {
@@ -1917,12 +2212,14 @@ func (s *Server) handleGetChatsForProvider(c *fiber.Ctx, provider string) error
 
 	// Parse filters
 	filter := domain.ChatFilter{
-		Provider:   provider,
-		UnreadOnly: c.QueryBool("unread_only", false),
-		Archived:   c.QueryBool("archived", false),
-		Search:     c.Query("search", ""),
-		Limit:      c.QueryInt("limit", 50),
-		Offset:     c.QueryInt("offset", 0),
+		Provider:      provider,
+		UnreadOnly:    c.QueryBool("unread_only", false),
+		Archived:      c.QueryBool("archived", false),
+		IncludeGroups: c.QueryBool("include_groups", false),
+		Search:        c.Query("search", ""),
+		Limit:         c.QueryInt("limit", 50),
+		Offset:        c.QueryInt("offset", 0),
+		Visibility:    s.assignmentScope(c),
 	}
 
 	// Parse device_ids filter (supports both comma-separated and repeated params)
@@ -1944,6 +2241,7 @@ func (s *Server) handleGetChatsForProvider(c *fiber.Ctx, provider string) error
 			}
 		}
 	}
+	filter.TagMatch = parseTagMatch(c)
 
 	// Parse reaction filter
 	filter.HasReaction = c.QueryBool("has_reaction", false)
@@ -1988,10 +2286,10 @@ func (s *Server) handleGetChatsForProvider(c *fiber.Ctx, provider string) error
 	}
 
 	// Redis cache for default load (no search/filters) — 15s TTL
-	isDefaultLoad := filter.Search == "" && !filter.UnreadOnly && !filter.Archived && len(filter.DeviceIDs) == 0 && len(filter.TagIDs) == 0 && !filter.HasReaction && filter.Offset == 0
+	isDefaultLoad := filter.Search == "" && !filter.UnreadOnly && !filter.Archived && !filter.IncludeGroups && len(filter.DeviceIDs) == 0 && len(filter.TagIDs) == 0 && !filter.HasReaction && filter.Offset == 0
 	cacheKey := ""
 	if isDefaultLoad && s.cache != nil {
-		cacheKey = fmt.Sprintf("chats:%s:%s:%d", accountID.String(), provider, filter.Limit)
+		cacheKey = fmt.Sprintf("chats:%s:%s:%d:%s", accountID.String(), provider, filter.Limit, assignmentScopeCacheSegment(filter.Visibility))
 		if cached, err := s.cache.Get(c.Context(), cacheKey); err == nil && cached != nil {
 			c.Set("Content-Type", "application/json")
 			return c.Send(cached)
@@ -2191,9 +2489,9 @@ func (s *Server) handleSearchChatContacts(c *fiber.Ctx) error {
 	normalized := normalizeWhatsAppPhone(search)
 	phonePattern := "%" + normalized + "%"
 
-	const predicate = `
+	predicate := `
 		FROM contacts c
-		WHERE c.account_id=$1 AND c.is_group=FALSE
+		WHERE c.account_id=$1 AND c.is_group=FALSE AND ` + repository.NotDeleted("c") + `
 		  AND (
 			REGEXP_REPLACE(COALESCE(c.phone,''), '[^0-9]', '', 'g') <> ''
 			OR EXISTS (SELECT 1 FROM contact_phones cp WHERE cp.contact_id=c.id AND REGEXP_REPLACE(COALESCE(cp.phone,''), '[^0-9]', '', 'g') <> '')
@@ -2862,6 +3160,17 @@ func (s *Server) handleSearchMessages(c *fiber.Ctx) error {
 	})
 }
 
+// handleGetUnreadSummary returns the unread badge totals without loading the
+// chat list itself.
+func (s *Server) handleGetUnreadSummary(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	summary, err := s.services.Chat.GetUnreadSummary(c.Context(), accountID, s.assignmentScope(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "summary": summary})
+}
+
 func (s *Server) handleMarkAsRead(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	chatID, err := uuid.Parse(c.Params("id"))
@@ -2882,9 +3191,75 @@ func (s *Server) handleMarkAsRead(c *fiber.Ctx) error {
 	}
 
 	s.invalidateChatCaches(accountID, &chatID)
+	s.pool.BroadcastUnreadSummary(accountID)
 	return c.JSON(fiber.Map{"success": true})
 }
 
+func (s *Server) handleArchiveChat(c *fiber.Ctx) error {
+	return s.updateChatState(c, func(ctx context.Context, chat *domain.Chat) (bool, error) {
+		return s.services.Chat.SetArchived(ctx, chat, true)
+	})
+}
+
+func (s *Server) handleUnarchiveChat(c *fiber.Ctx) error {
+	return s.updateChatState(c, func(ctx context.Context, chat *domain.Chat) (bool, error) {
+		return s.services.Chat.SetArchived(ctx, chat, false)
+	})
+}
+
+func (s *Server) handlePinChat(c *fiber.Ctx) error {
+	return s.updateChatState(c, func(ctx context.Context, chat *domain.Chat) (bool, error) {
+		return s.services.Chat.SetPinned(ctx, chat, true)
+	})
+}
+
+func (s *Server) handleUnpinChat(c *fiber.Ctx) error {
+	return s.updateChatState(c, func(ctx context.Context, chat *domain.Chat) (bool, error) {
+		return s.services.Chat.SetPinned(ctx, chat, false)
+	})
+}
+
+// updateChatState applies an archive/pin change to the :id chat and replies
+// with its new flags. "synced" tells whether the phone was updated too.
+func (s *Server) updateChatState(c *fiber.Ctx, apply func(ctx context.Context, chat *domain.Chat) (bool, error)) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	chatID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid chat ID"})
+	}
+
+	chat, err := s.services.Chat.GetByID(c.Context(), chatID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if !chatBelongsToAccount(chat, accountID) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+	}
+
+	synced, err := apply(c.Context(), chat)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	updated, err := s.services.Chat.GetByID(c.Context(), chatID)
+	if err != nil || updated == nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo leer el chat"})
+	}
+
+	s.invalidateChatCaches(accountID, &chatID)
+	s.pool.BroadcastUnreadSummary(accountID)
+	s.hub.BroadcastToAccount(accountID, ws.EventChatUpdate, map[string]interface{}{
+		"chat_id":     chatID.String(),
+		"is_archived": updated.IsArchived,
+		"is_pinned":   updated.IsPinned,
+	})
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"is_archived": updated.IsArchived,
+		"is_pinned":   updated.IsPinned,
+		"synced":      synced,
+	})
+}
+
 func (s *Server) handleDeleteChat(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	chatID, err := uuid.Parse(c.Params("id"))
@@ -2944,6 +3319,10 @@ func (s *Server) handleDeleteChatsBatch(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
 
+	if req.DeleteAll && !s.callerHasRole(c, domain.RoleAdmin) {
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": roleForbiddenMessage})
+	}
+
 	if req.DeleteAll {
 		if err := s.services.Chat.DeleteAll(c.Context(), accountID); err != nil {
 			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
@@ -2992,7 +3371,7 @@ func (s *Server) handleSendMessage(c *fiber.Ctx) error {
 		To              string `json:"to"`
 		Body            string `json:"body"`
 		MediaURL        string `json:"media_url,omitempty"`
-		MediaType       string `json:"media_type,omitempty"` // image, video, gif, audio, document, sticker
+		MediaType       string `json:"media_type,omitempty"` // image, video, gif, audio, ptt, document, sticker
 		MediaFilename   string `json:"media_filename,omitempty"`
 		ChatID          string `json:"chat_id,omitempty"`
 		QuotedMessageID string `json:"quoted_message_id,omitempty"`
@@ -3084,6 +3463,9 @@ func (s *Server) handleSendMessage(c *fiber.Ctx) error {
 				"code":    "whatsapp_rejected_463",
 			})
 		}
+		if tooLarge, ok := whatsapp.IsMediaTooLarge(err); ok {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(mediaTooLargeResponse(tooLarge))
+		}
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
 
@@ -3096,6 +3478,17 @@ func (s *Server) handleSendMessage(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "message": message})
 }
 
+func mediaTooLargeResponse(err *whatsapp.MediaTooLargeError) fiber.Map {
+	return fiber.Map{
+		"success":     false,
+		"error":       err.Error(),
+		"code":        "media_too_large",
+		"media_type":  err.MediaType,
+		"size_bytes":  err.SizeBytes,
+		"limit_bytes": err.LimitBytes,
+	}
+}
+
 func (s *Server) validateAccountStickerMedia(ctx context.Context, accountID uuid.UUID, mediaURL string) (string, error) {
 	objectKey := objectKeyFromMediaURL(mediaURL)
 	if objectKey == "" || !strings.HasPrefix(objectKey, accountID.String()+"/") {
@@ -3258,6 +3651,55 @@ func (s *Server) handleSendContact(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "message": message})
 }
 
+// handleSendLocation shares a map pin, such as a meeting point, in a chat.
+func (s *Server) handleSendLocation(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		DeviceID  string   `json:"device_id"`
+		To        string   `json:"to"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		Name      string   `json:"name"`
+		Address   string   `json:"address"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+
+	deviceID, err := uuid.Parse(req.DeviceID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
+	}
+	if dev, _ := s.services.Device.GetByID(c.Context(), deviceID); dev == nil || dev.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Device not found"})
+	}
+	if err := s.ensureOutboundContactAllowed(c.Context(), accountID, req.To); err != nil {
+		if apiErr, ok := err.(*fiber.Error); ok {
+			return c.Status(apiErr.Code).JSON(fiber.Map{"success": false, "error": apiErr.Message, "code": "do_not_contact"})
+		}
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if req.Latitude == nil || req.Longitude == nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "latitude and longitude are required"})
+	}
+	if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Coordenadas fuera de rango"})
+	}
+
+	message, err := s.services.Chat.SendLocation(c.Context(), deviceID, req.To, *req.Latitude, *req.Longitude, strings.TrimSpace(req.Name), strings.TrimSpace(req.Address))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	if message != nil {
+		s.invalidateChatCaches(accountID, &message.ChatID)
+	} else {
+		s.invalidateChatCaches(accountID, nil)
+	}
+
+	return c.JSON(fiber.Map{"success": true, "message": message})
+}
+
 func (s *Server) handleForwardMessage(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	var req struct {
@@ -3307,7 +3749,10 @@ func (s *Server) handleForwardMessage(c *fiber.Ctx) error {
 	}
 
 	// Forward it
-	message, err := s.services.Chat.ForwardMessage(c.Context(), deviceID, req.To, originalMsg)
+	message, mediaMissing, err := s.services.Chat.ForwardMessage(c.Context(), deviceID, req.To, originalMsg)
+	if errors.Is(err, whatsapp.ErrMediaGone) {
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"success": false, "error": "El archivo original ya no está disponible y el mensaje no tiene texto para reenviar"})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
@@ -3318,7 +3763,11 @@ func (s *Server) handleForwardMessage(c *fiber.Ctx) error {
 		s.invalidateChatCaches(accountID, nil)
 	}
 
-	return c.JSON(fiber.Map{"success": true, "message": message})
+	response := fiber.Map{"success": true, "message": message}
+	if mediaMissing {
+		response["warning"] = "El archivo original ya no está disponible; se reenvió solo el texto"
+	}
+	return c.JSON(response)
 }
 
 func (s *Server) handleSendReaction(c *fiber.Ctx) error {
@@ -3467,11 +3916,14 @@ func (s *Server) handleSendReadReceipt(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// handleDeleteMessage revokes one of our own messages for everyone and keeps
+// the local row, without its content, as a revoked tombstone.
 func (s *Server) handleDeleteMessage(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	var req struct {
 		DeviceID  string `json:"device_id"`
 		ChatJID   string `json:"chat_jid"`
+		To        string `json:"to"`
 		SenderJID string `json:"sender_jid"`
 		MessageID string `json:"message_id"`
 		IsFromMe  bool   `json:"is_from_me"`
@@ -3479,6 +3931,9 @@ func (s *Server) handleDeleteMessage(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
+	if req.ChatJID == "" {
+		req.ChatJID = req.To
+	}
 
 	deviceID, err := uuid.Parse(req.DeviceID)
 	if err != nil {
@@ -3546,12 +4001,21 @@ func (s *Server) handleEditMessage(c *fiber.Ctx) error {
 	var req struct {
 		DeviceID  string `json:"device_id"`
 		ChatJID   string `json:"chat_jid"`
+		To        string `json:"to"`
 		MessageID string `json:"message_id"`
 		NewBody   string `json:"new_body"`
+		Body      string `json:"body"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
+	// "to" and "body" mirror the send endpoint's field names.
+	if req.ChatJID == "" {
+		req.ChatJID = req.To
+	}
+	if req.NewBody == "" {
+		req.NewBody = req.Body
+	}
 
 	deviceID, err := uuid.Parse(req.DeviceID)
 	if err != nil {
@@ -3595,6 +4059,7 @@ func (s *Server) handleEditMessage(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"success": false, "error": "WhatsApp no pudo editar el mensaje", "code": "provider_edit_failed"})
 	}
 
+	editedAt := time.Now()
 	persisted := true
 	warning := ""
 	if err := s.repos.Message.UpdateBody(c.Context(), accountID, chat.JID, message.MessageID, req.NewBody); err != nil {
@@ -3610,9 +4075,10 @@ func (s *Server) handleEditMessage(c *fiber.Ctx) error {
 		"message_id": message.MessageID,
 		"new_body":   req.NewBody,
 		"is_from_me": true,
+		"edited_at":  editedAt,
 	})
 
-	return c.JSON(fiber.Map{"success": true, "persisted": persisted, "warning": warning})
+	return c.JSON(fiber.Map{"success": true, "persisted": persisted, "warning": warning, "edited_at": editedAt})
 }
 
 func (s *Server) handleCheckWhatsApp(c *fiber.Ctx) error {
@@ -4552,6 +5018,37 @@ func (s *Server) handleGetUploadURL(c *fiber.Ctx) error {
 	})
 }
 
+// mediaDownloadURLTTL bounds how long a presigned download link stays valid.
+const mediaDownloadURLTTL = 5 * time.Minute
+
+// handleGetDownloadURL returns a short-lived presigned URL that downloads one
+// of the caller's account objects straight from storage, bypassing the
+// media proxy for large files.
+func (s *Server) handleGetDownloadURL(c *fiber.Ctx) error {
+	if s.storage == nil {
+		return c.Status(503).JSON(fiber.Map{"success": false, "error": "Storage not configured"})
+	}
+	accountID := c.Locals("account_id").(uuid.UUID)
+	objectKey := strings.TrimPrefix(strings.TrimSpace(c.Query("key")), mediaProxyURLFromObjectKey(""))
+	if decoded, err := url.PathUnescape(objectKey); err == nil {
+		objectKey = decoded
+	}
+	// Keys outside the caller's account answer like missing files so other
+	// tenants' objects cannot be probed.
+	if !storage.IsAccountDownloadableObjectKey(accountID, objectKey) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "File not found"})
+	}
+	if _, err := s.storage.GetFileInfo(c.Context(), objectKey); err != nil {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "File not found"})
+	}
+	expiresAt := time.Now().Add(mediaDownloadURLTTL)
+	downloadURL, err := s.storage.GetPresignedDownloadURL(c.Context(), objectKey, filepath.Base(objectKey), mediaDownloadURLTTL)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "url": downloadURL, "expires_at": expiresAt})
+}
+
 // handleDirectUpload handles direct file upload through the backend
 func (s *Server) handleDirectUpload(c *fiber.Ctx) error {
 	if s.storage == nil {
@@ -4575,6 +5072,19 @@ func (s *Server) handleDirectUpload(c *fiber.Ctx) error {
 	if file.Size > 50*1024*1024 {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "File too large (max 50MB)"})
 	}
+	// Uploads meant for a WhatsApp send are checked against WhatsApp's own
+	// per-type limit up front. Images are exempt when the send path can
+	// downscale them.
+	if waMediaType := c.FormValue("whatsapp_media_type"); waMediaType != "" {
+		limits := whatsapp.MediaLimitsFromConfig(s.cfg)
+		limit := limits.For(waMediaType)
+		downscalable := waMediaType == domain.MessageTypeImage && limits.DownscaleImage
+		if limit > 0 && file.Size > limit && !downscalable {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(mediaTooLargeResponse(&whatsapp.MediaTooLargeError{
+				MediaType: waMediaType, SizeBytes: file.Size, LimitBytes: limit,
+			}))
+		}
+	}
 
 	// Open the file
 	src, err := file.Open()
@@ -4585,16 +5095,24 @@ func (s *Server) handleDirectUpload(c *fiber.Ctx) error {
 
 	cleanFilename := filepath.Base(file.Filename)
 
-	// Detect content type
-	contentType := file.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-
 	data, err := io.ReadAll(src)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to read file"})
 	}
+	// The stored type comes from the bytes; the client's header is not trusted.
+	contentType, err := parseUploadPolicies(s.cfg.UploadAllowedTypes).checkUpload(folder, cleanFilename, data)
+	if err != nil {
+		var typeErr *uploadTypeError
+		if errors.As(err, &typeErr) {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"success":       false,
+				"error":         typeErr.Error(),
+				"code":          "file_type_not_allowed",
+				"allowed_types": typeErr.Allowed,
+			})
+		}
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
 	hashBytes := sha256.Sum256(data)
 	rawContentHash := fmt.Sprintf("%x", hashBytes[:])
 	existing, contentHash, lookupErr := s.findNonStatusMediaAsset(c.Context(), accountID, rawContentHash)
@@ -4616,6 +5134,8 @@ func (s *Server) handleDirectUpload(c *fiber.Ctx) error {
 			"success":        true,
 			"public_url":     s.storage.GetPublicURL(existing.ObjectKey),
 			"proxy_url":      proxyURL,
+			"signed_url":     s.signedMediaProxyURL(existing.ObjectKey, signedMediaURLTTL),
+			"thumbnail_url":  s.ensureUploadThumbnail(c.Context(), existing.ObjectKey, data, existing.ContentType),
 			"filename":       existing.Filename,
 			"media_asset_id": existing.ID,
 			"content_hash":   existing.ContentHash,
@@ -4677,6 +5197,8 @@ func (s *Server) handleDirectUpload(c *fiber.Ctx) error {
 		"success":        true,
 		"public_url":     publicURL,
 		"proxy_url":      proxyURL,
+		"signed_url":     s.signedMediaProxyURL(objectKey, signedMediaURLTTL),
+		"thumbnail_url":  s.ensureUploadThumbnail(c.Context(), objectKey, data, contentType),
 		"filename":       uniqueFilename,
 		"media_asset_id": mediaAssetID,
 		"content_hash":   contentHash,
@@ -4684,6 +5206,38 @@ func (s *Server) handleDirectUpload(c *fiber.Ctx) error {
 	})
 }
 
+// sniffStorageContentType resolves the type of an object whose extension is
+// unknown: the type stored with the object when it is specific, otherwise
+// detection from its first 512 bytes.
+func (s *Server) sniffStorageContentType(ctx context.Context, objectKey, storedType string) string {
+	if storedType != "" && !strings.HasPrefix(storedType, "application/octet-stream") && storedType != "binary/octet-stream" {
+		return storedType
+	}
+	head, err := s.storage.GetFileRange(ctx, objectKey, 0, 512)
+	if err != nil || len(head) == 0 {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(head)
+}
+
+// storageInlineSafe reports whether a stored object of contentType can be
+// rendered inline on the app origin. Only types able to run script (HTML,
+// SVG, XML and JavaScript) are forced to download; PDFs, text and media stay
+// inline.
+func storageInlineSafe(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	switch {
+	case mediaType == "":
+		return false
+	case mediaType == "text/html", mediaType == "text/xml", mediaType == "application/xml",
+		strings.HasSuffix(mediaType, "+xml"), strings.Contains(mediaType, "svg"):
+		return false
+	case strings.Contains(mediaType, "javascript"), strings.Contains(mediaType, "ecmascript"):
+		return false
+	}
+	return true
+}
+
 func sanitizeUploadFolder(raw, fallback string) (string, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -4729,12 +5283,25 @@ func (s *Server) handleMediaProxy(c *fiber.Ctx) error {
 	if decoded, err := url.PathUnescape(objectKey); err == nil {
 		objectKey = decoded
 	}
-	if storage.IsProtectedStatusObjectKey(objectKey) {
+	if storage.IsProtectedStatusObjectKey(objectKey) || !s.authorizeMediaRequest(c, objectKey) {
 		c.Set("Cache-Control", "private, no-store, max-age=0")
 		c.Set("Vary", "Cookie, Authorization")
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"success": false, "error": "File not found"})
 	}
-	return s.serveStorageObject(c, objectKey, "public, max-age=31536000")
+	cacheControl := "public, max-age=31536000"
+	if s.cfg.MediaProxyRequireAuth && !isPublicMediaObjectKey(objectKey) {
+		// Shared caches must not hand account media to other visitors.
+		cacheControl = "private, max-age=31536000"
+	}
+	// ?thumb=1 serves the upload-time thumbnail when one exists and falls
+	// back to the original otherwise.
+	if c.QueryBool("thumb") && s.storage != nil {
+		thumbKey := thumbnailObjectKey(objectKey)
+		if _, err := s.storage.GetFileInfo(c.Context(), thumbKey); err == nil {
+			objectKey = thumbKey
+		}
+	}
+	return s.serveStorageObject(c, objectKey, cacheControl)
 }
 
 func (s *Server) serveStorageObject(c *fiber.Ctx, objectKey, cacheControl string) error {
@@ -4786,6 +5353,14 @@ func (s *Server) serveStorageObject(c *fiber.Ctx, objectKey, cacheControl string
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"success": false, "error": "File not found"})
 	}
+	if contentType == "application/octet-stream" {
+		contentType = s.sniffStorageContentType(c.Context(), objectKey, info.ContentType)
+	}
+	disposition := "inline"
+	if !storageInlineSafe(contentType) {
+		contentType = "application/octet-stream"
+		disposition = "attachment"
+	}
 	etagSeed := fmt.Sprintf("%s:%d:%d", objectKey, info.Size, info.LastModified.UnixNano())
 	etagHash := sha256.Sum256([]byte(etagSeed))
 	etag := fmt.Sprintf("\"%x\"", etagHash[:])
@@ -4797,7 +5372,8 @@ func (s *Server) serveStorageObject(c *fiber.Ctx, objectKey, cacheControl string
 		if strings.Contains(cacheControl, "private") {
 			c.Set("Vary", "Cookie, Authorization")
 		}
-		c.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filepath.Base(objectKey)))
+		c.Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, filepath.Base(objectKey)))
+		c.Set("X-Content-Type-Options", "nosniff")
 	}
 
 	ifNoneMatch := c.Get("If-None-Match")
@@ -4806,56 +5382,44 @@ func (s *Server) serveStorageObject(c *fiber.Ctx, objectKey, cacheControl string
 		return c.SendStatus(fiber.StatusNotModified)
 	}
 
-	// Check for Range header (needed for video streaming)
-	rangeHeader := c.Get("Range")
-	if rangeHeader != "" {
+	// Check for Range header (needed for video streaming). Malformed headers
+	// are ignored and the whole file is served.
+	if rangeHeader := c.Get("Range"); rangeHeader != "" {
 		totalSize := info.Size
-
-		// Parse range header: "bytes=start-end"
-		rangeHeader = strings.TrimPrefix(rangeHeader, "bytes=")
-		parts := strings.SplitN(rangeHeader, "-", 2)
-		var start, end int64
-		if parts[0] != "" {
-			fmt.Sscanf(parts[0], "%d", &start)
-		}
-		if len(parts) > 1 && parts[1] != "" {
-			fmt.Sscanf(parts[1], "%d", &end)
-		} else {
-			// Serve chunks of 1MB max for streaming
-			end = start + 1024*1024 - 1
-			if end >= totalSize {
-				end = totalSize - 1
-			}
+		byteRange, err := parseByteRange(rangeHeader, totalSize)
+		if errors.Is(err, errUnsatisfiableRange) {
+			c.Set("Content-Range", fmt.Sprintf("bytes */%d", totalSize))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
 		}
-		if end >= totalSize {
-			end = totalSize - 1
-		}
-
-		length := end - start + 1
-		data, err := s.storage.GetFileRange(c.Context(), objectKey, start, length)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to read file"})
+		if err == nil {
+			return s.serveStorageRange(c, objectKey, contentType, byteRange, totalSize, setMediaCacheHeaders)
 		}
-
-		c.Set("Content-Type", contentType)
-		c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, totalSize))
-		c.Set("Accept-Ranges", "bytes")
-		c.Set("Content-Length", fmt.Sprintf("%d", len(data)))
-		setMediaCacheHeaders()
-		return c.Status(206).Send(data)
 	}
 
-	// Full file download
-	data, err := s.storage.GetFile(c.Context(), objectKey)
+	// Full file download, streamed so large media is never held in memory
+	reader, err := s.storage.OpenFileRange(c.Context(), objectKey, 0, 0)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"success": false, "error": "File not found"})
 	}
 
 	c.Set("Content-Type", contentType)
 	c.Set("Accept-Ranges", "bytes")
-	c.Set("Content-Length", fmt.Sprintf("%d", len(data)))
 	setMediaCacheHeaders()
-	return c.Send(data)
+	return c.SendStream(reader, int(info.Size))
+}
+
+func (s *Server) serveStorageRange(c *fiber.Ctx, objectKey, contentType string, r byteRange, totalSize int64, setCacheHeaders func()) error {
+	reader, err := s.storage.OpenFileRange(c.Context(), objectKey, r.start, r.length())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "Failed to read file"})
+	}
+
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, totalSize))
+	c.Set("Accept-Ranges", "bytes")
+	setCacheHeaders()
+	// The response writer closes the reader once the range is sent.
+	return c.Status(206).SendStream(reader, int(r.length()))
 }
 
 // --- Lead Handlers ---
@@ -4863,155 +5427,53 @@ func (s *Server) serveStorageObject(c *fiber.Ctx, objectKey, cacheControl string
 func (s *Server) handleGetLeads(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 
-	// Parse optional device_ids filter
-	deviceIDs := c.Context().QueryArgs().PeekMulti("device_ids")
-	var deviceUUIDs []uuid.UUID
-	for _, did := range deviceIDs {
-		if id, err := uuid.Parse(string(did)); err == nil {
-			deviceUUIDs = append(deviceUUIDs, id)
-		}
-	}
+	filter := parseLeadFilter(c)
+	filter.Visibility = s.assignmentScope(c)
+	includeCustomFields := c.QueryBool("include_custom_fields", false)
 
-	// Build cache key including device filter
-	cacheKey := "leads:" + accountID.String()
-	if len(deviceUUIDs) > 0 {
-		for _, d := range deviceUUIDs {
-			cacheKey += ":" + d.String()
+	// Redis cache only for unsearched loads; search terms are too varied to
+	// be worth caching.
+	cacheKey := ""
+	if filter.Search == "" && s.cache != nil {
+		cacheKey = leadListCacheKey(accountID, filter, includeCustomFields)
+		if cached, err := s.cache.Get(c.Context(), cacheKey); err == nil && cached != nil {
+			c.Set("Content-Type", "application/json")
+			return c.Send(cached)
 		}
 	}
 
-	// Try Redis cache first
-	if s.cache != nil {
-		if cached, err := s.cache.Get(c.Context(), cacheKey); err == nil && cached != nil {
-			c.Set("Content-Type", "application/json")
-			return c.Send(cached)
-		}
-	}
-
-	// --- Parallel: load leads + tags simultaneously ---
-	var leads []*domain.Lead
-	var leadsErr error
-	tagMap := make(map[uuid.UUID][]*domain.Tag)
-	var tagsErr error
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	// Goroutine 1: load leads (slim — no notes/custom_fields for list)
-	go func() {
-		defer wg.Done()
-		if len(deviceUUIDs) > 0 {
-			rows, qErr := s.repos.DB().Query(c.Context(), `
-				SELECT l.id, l.account_id, l.contact_id, l.jid,
-				       CASE WHEN l.contact_id IS NULL THEN COALESCE(l.name,'') ELSE COALESCE(c.custom_name,c.name,c.push_name,c.phone,c.jid,'') END,
-				       CASE WHEN l.contact_id IS NULL THEN l.last_name ELSE c.last_name END,
-				       CASE WHEN l.contact_id IS NULL THEN l.short_name ELSE c.short_name END,
-				       CASE WHEN l.contact_id IS NULL THEN l.phone ELSE c.phone END,
-				       CASE WHEN l.contact_id IS NULL THEN l.email ELSE c.email END,
-				       CASE WHEN l.contact_id IS NULL THEN l.company ELSE c.company END,
-				       CASE WHEN l.contact_id IS NULL THEN l.age ELSE c.age END,
-				       CASE WHEN l.contact_id IS NULL THEN l.dni ELSE c.dni END,
-				       CASE WHEN l.contact_id IS NULL THEN l.birth_date ELSE c.birth_date END,
-				       CASE WHEN l.contact_id IS NULL THEN l.address ELSE c.address END,
-				       CASE WHEN l.contact_id IS NULL THEN l.distrito ELSE c.distrito END,
-				       CASE WHEN l.contact_id IS NULL THEN l.ocupacion ELSE c.ocupacion END,
-				       l.status, l.source, l.notes,
-				       l.tags, l.custom_fields, l.assigned_to, l.pipeline_id, l.stage_id, l.created_at, l.updated_at,
-				       ps.name, ps.color, ps.position, l.kommo_id,
-				       l.is_archived,l.archived_at,
-				       CASE WHEN l.contact_id IS NULL THEN COALESCE(l.is_blocked,FALSE) ELSE COALESCE(c.do_not_contact,FALSE) END,
-				       CASE WHEN l.contact_id IS NULL THEN l.blocked_at ELSE c.do_not_contact_at END,
-				       CASE WHEN l.contact_id IS NULL THEN l.block_reason ELSE c.do_not_contact_reason END,
-				       l.kommo_deleted_at,
-				       l.title, l.closed_at, l.closed_by, l.close_reason, l.deleted_at, l.deleted_by, l.delete_reason
-				FROM leads l
-				LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
-				LEFT JOIN pipeline_stages ps ON ps.id = l.stage_id
-				WHERE l.account_id = $1 AND l.deleted_at IS NULL
-				  AND l.jid IN (SELECT DISTINCT jid FROM chats WHERE device_id = ANY($2))
-				ORDER BY l.created_at DESC
-			`, accountID, deviceUUIDs)
-			if qErr != nil {
-				leadsErr = qErr
-				return
-			}
-			defer rows.Close()
-			for rows.Next() {
-				lead := &domain.Lead{}
-				if scanErr := rows.Scan(
-					&lead.ID, &lead.AccountID, &lead.ContactID, &lead.JID, &lead.Name, &lead.LastName, &lead.ShortName, &lead.Phone,
-					&lead.Email, &lead.Company, &lead.Age, &lead.DNI, &lead.BirthDate, &lead.Address, &lead.Distrito, &lead.Ocupacion, &lead.Status, &lead.Source, &lead.Notes, &lead.Tags,
-					&lead.CustomFields, &lead.AssignedTo, &lead.PipelineID, &lead.StageID, &lead.CreatedAt, &lead.UpdatedAt,
-					&lead.StageName, &lead.StageColor, &lead.StagePosition, &lead.KommoID,
-					&lead.IsArchived, &lead.ArchivedAt, &lead.IsBlocked, &lead.BlockedAt, &lead.BlockReason, &lead.KommoDeletedAt,
-					&lead.Title, &lead.ClosedAt, &lead.ClosedBy, &lead.CloseReason, &lead.DeletedAt, &lead.DeletedBy, &lead.DeleteReason,
-				); scanErr != nil {
-					leadsErr = scanErr
-					return
-				}
-				leads = append(leads, lead)
-			}
-		} else {
-			leads, leadsErr = s.services.Lead.GetByAccountID(c.Context(), accountID)
-		}
-	}()
-
-	// Goroutine 2: load all tags for account's leads (via contact_tags)
-	go func() {
-		defer wg.Done()
-		rows, err := s.repos.DB().Query(c.Context(), `
-			SELECT l.id, t.id, t.account_id, t.name, t.color
-			FROM leads l
-			JOIN contact_tags ct ON ct.contact_id = l.contact_id
-			JOIN tags t ON t.id = ct.tag_id
-			WHERE l.account_id = $1
-			ORDER BY t.name
-		`, accountID)
-		if err != nil {
-			tagsErr = err
-			return
-		}
-		defer rows.Close()
-		for rows.Next() {
-			var leadID uuid.UUID
-			t := &domain.Tag{}
-			if err := rows.Scan(&leadID, &t.ID, &t.AccountID, &t.Name, &t.Color); err != nil {
-				continue
-			}
-			tagMap[leadID] = append(tagMap[leadID], t)
-		}
-	}()
-
-	wg.Wait()
-
-	if leadsErr != nil {
-		return c.Status(500).JSON(fiber.Map{"success": false, "error": leadsErr.Error()})
+	leads, total, err := s.services.Lead.GetByAccountIDWithFilters(c.Context(), accountID, filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
-	// tagsErr is non-fatal — leads still returned without tags
-	if tagsErr != nil {
-		log.Printf("[LEADS] Warning: failed to load tags: %v", tagsErr)
+	if leads == nil {
+		leads = []*domain.Lead{}
 	}
 
-	// Assign tags to leads
+	// Tags and custom field values are attached through the linked contact
+	contactIDSet := make(map[uuid.UUID]bool)
 	for _, lead := range leads {
-		lead.StructuredTags = tagMap[lead.ID]
+		if lead.ContactID != nil {
+			contactIDSet[*lead.ContactID] = true
+		}
 	}
-
-	// Optionally load custom field values via contact_id
-	if c.QueryBool("include_custom_fields", false) && len(leads) > 0 {
-		contactIDSet := make(map[uuid.UUID]bool)
-		for _, lead := range leads {
-			if lead.ContactID != nil {
-				contactIDSet[*lead.ContactID] = true
-			}
+	if len(contactIDSet) > 0 {
+		contactIDs := make([]uuid.UUID, 0, len(contactIDSet))
+		for cid := range contactIDSet {
+			contactIDs = append(contactIDs, cid)
 		}
-		if len(contactIDSet) > 0 {
-			contactIDs := make([]uuid.UUID, 0, len(contactIDSet))
-			for cid := range contactIDSet {
-				contactIDs = append(contactIDs, cid)
+		// Tag errors are non-fatal — leads still returned without tags
+		if tagMap, tagsErr := s.repos.Tag.GetByContactsBatch(c.Context(), contactIDs); tagsErr != nil {
+			log.Printf("[LEADS] Warning: failed to load tags: %v", tagsErr)
+		} else {
+			for _, lead := range leads {
+				if lead.ContactID != nil {
+					lead.StructuredTags = tagMap[*lead.ContactID]
+				}
 			}
-			cfMap, cfErr := s.repos.CustomField.GetValuesByContacts(c.Context(), contactIDs)
-			if cfErr == nil {
+		}
+		if includeCustomFields {
+			if cfMap, cfErr := s.repos.CustomField.GetValuesByContacts(c.Context(), contactIDs); cfErr == nil {
 				for _, lead := range leads {
 					if lead.ContactID != nil {
 						lead.CustomFieldValues = cfMap[*lead.ContactID]
@@ -5021,10 +5483,17 @@ func (s *Server) handleGetLeads(c *fiber.Ctx) error {
 		}
 	}
 
-	result := fiber.Map{"success": true, "leads": leads}
+	result := fiber.Map{
+		"success":  true,
+		"leads":    leads,
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+		"has_more": filter.Offset+len(leads) < total,
+	}
 
 	// Store in Redis cache (60s TTL — longer to improve hit rate)
-	if s.cache != nil {
+	if cacheKey != "" {
 		if data, err := json.Marshal(result); err == nil {
 			_ = s.cache.Set(c.Context(), cacheKey, data, 60*time.Second)
 		}
@@ -5033,6 +5502,74 @@ func (s *Server) handleGetLeads(c *fiber.Ctx) error {
 	return c.JSON(result)
 }
 
+// parseLeadFilter reads the GET /api/leads filter and page query params.
+func parseLeadFilter(c *fiber.Ctx) domain.LeadFilter {
+	filter := domain.LeadFilter{
+		Search: strings.TrimSpace(c.Query("search")),
+		Status: c.Query("status"),
+		Limit:  c.QueryInt("limit", 50),
+		Offset: c.QueryInt("offset", 0),
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if c.Query("sort") == "score" {
+		filter.SortBy = "score"
+	}
+	if id, err := uuid.Parse(c.Query("pipeline_id")); err == nil {
+		filter.PipelineID = &id
+	}
+	if id, err := uuid.Parse(c.Query("stage_id")); err == nil {
+		filter.StageID = &id
+	}
+	if id, err := uuid.Parse(c.Query("assigned_to")); err == nil {
+		filter.AssignedTo = &id
+	}
+
+	// Parse device_ids and tag_ids (comma-separated or repeated params)
+	for _, raw := range c.Context().QueryArgs().PeekMulti("device_ids") {
+		for _, idStr := range strings.Split(string(raw), ",") {
+			if id, err := uuid.Parse(strings.TrimSpace(idStr)); err == nil {
+				filter.DeviceIDs = append(filter.DeviceIDs, id)
+			}
+		}
+	}
+	for _, raw := range c.Context().QueryArgs().PeekMulti("tag_ids") {
+		for _, idStr := range strings.Split(string(raw), ",") {
+			if id, err := uuid.Parse(strings.TrimSpace(idStr)); err == nil {
+				filter.TagIDs = append(filter.TagIDs, id)
+			}
+		}
+	}
+	filter.TagMatch = parseTagMatch(c)
+	return filter
+}
+
+// leadListCacheKey keys a GET /api/leads page under the "leads:<account>:"
+// prefix cleared by invalidateLeadsCache.
+func leadListCacheKey(accountID uuid.UUID, filter domain.LeadFilter, includeCustomFields bool) string {
+	optionalID := func(id *uuid.UUID) string {
+		if id == nil {
+			return "-"
+		}
+		return id.String()
+	}
+	joinIDs := func(ids []uuid.UUID) string {
+		parts := make([]string, len(ids))
+		for i, id := range ids {
+			parts[i] = id.String()
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprintf("leads:%s:%s:%s:%s:%s:%s:%s:%s:%d:%d:%t:%s", accountID,
+		filter.Status, optionalID(filter.PipelineID), optionalID(filter.StageID), optionalID(filter.AssignedTo),
+		joinIDs(filter.DeviceIDs), joinIDs(filter.TagIDs)+"/"+filter.TagMatch, filter.SortBy,
+		filter.Limit, filter.Offset, includeCustomFields, assignmentScopeCacheSegment(filter.Visibility))
+}
+
 // invalidateLeadsCache invalidates ALL cached leads keys for an account (base + device-filtered + paginated + detail)
 func (s *Server) invalidateLeadsCache(accountID uuid.UUID) {
 	if s.cache != nil {
@@ -5179,6 +5716,25 @@ func addDateFilter(c *fiber.Ctx, tableAlias string, allowedFields map[string]boo
 var leadDateFields = map[string]bool{"created_at": true, "updated_at": true}
 var participantDateFields = map[string]bool{"created_at": true, "updated_at": true, "invited_at": true, "confirmed_at": true, "attended_at": true}
 
+// parseTagMatch reads the tag_match query param shared by every tag_ids
+// filter: "all" requires every selected tag, anything else keeps the default
+// any-of semantics.
+func parseTagMatch(c *fiber.Ctx) string {
+	if strings.EqualFold(strings.TrimSpace(c.Query("tag_match")), domain.TagMatchAll) {
+		return domain.TagMatchAll
+	}
+	return domain.TagMatchAny
+}
+
+// tagModeFromQuery resolves the tag-name filter mode. tag_match=all is
+// accepted as an alias of tag_mode=AND so both filter styles behave alike.
+func tagModeFromQuery(c *fiber.Ctx) string {
+	if parseTagMatch(c) == domain.TagMatchAll {
+		return "AND"
+	}
+	return strings.ToUpper(c.Query("tag_mode", "OR"))
+}
+
 // buildTagFormulaSQL builds a WHERE sub-clause for formula-based tag filtering.
 // Returns the SQL clause, updated args, and updated argIdx.
 // Supports tag_mode=AND (leads must have ALL tags), OR (any tag), and exclude_tag_names.
@@ -5273,7 +5829,7 @@ func (s *Server) handleGetLeadsPaginated(c *fiber.Ctx) error {
 	pipelineID := c.Query("pipeline_id")
 	search := strings.TrimSpace(c.Query("search"))
 	tagNamesRaw := c.Query("tag_names")
-	tagMode := strings.ToUpper(c.Query("tag_mode", "OR"))
+	tagMode := tagModeFromQuery(c)
 	excludeTagNamesRaw := c.Query("exclude_tag_names")
 	tagFormulaRaw := c.Query("tag_formula")
 	stageIDsRaw := c.Query("stage_ids")
@@ -5311,6 +5867,7 @@ func (s *Server) handleGetLeadsPaginated(c *fiber.Ctx) error {
 	args := []interface{}{accountID}
 	argIdx := 2
 	whereClauses := leadWhereClauses("$1", c.Query("lifecycle"), c.Query("status_filter", "active"))
+	addLeadAssignmentWhere(s.assignmentScope(c), &whereClauses, &args, &argIdx)
 
 	addLeadPipelineWhere(pipelineID, &whereClauses, &args, &argIdx)
 
@@ -5583,6 +6140,7 @@ func (s *Server) handleGetLeadsPaginated(c *fiber.Ctx) error {
 		hIdx := 2
 		hClauses := leadBaseWhereClauses("$1")
 		// NO status filter — count ALL statuses
+		addLeadAssignmentWhere(s.assignmentScope(c), &hClauses, &hArgs, &hIdx)
 		addLeadPipelineWhere(pipelineID, &hClauses, &hArgs, &hIdx)
 		if search != "" {
 			searchPattern := "%" + strings.ToLower(search) + "%"
@@ -5794,7 +6352,7 @@ func (s *Server) handleGetLeadsByStage(c *fiber.Ctx) error {
 	}
 	search := strings.TrimSpace(c.Query("search"))
 	tagNamesRaw := c.Query("tag_names")
-	tagMode := strings.ToUpper(c.Query("tag_mode", "OR"))
+	tagMode := tagModeFromQuery(c)
 	excludeTagNamesRaw := c.Query("exclude_tag_names")
 	tagFormulaRaw2 := c.Query("tag_formula")
 	pipelineID := c.Query("pipeline_id")
@@ -5812,6 +6370,7 @@ func (s *Server) handleGetLeadsByStage(c *fiber.Ctx) error {
 	args := []interface{}{accountID}
 	argIdx := 2
 	whereClauses := leadWhereClauses("$1", c.Query("lifecycle"), c.Query("status_filter", "active"))
+	addLeadAssignmentWhere(s.assignmentScope(c), &whereClauses, &args, &argIdx)
 
 	// Handle stage: "unassigned" or UUID
 	isUnassigned := stageIDParam == "unassigned"
@@ -5985,7 +6544,7 @@ func (s *Server) handleGetLeadsListPaginated(c *fiber.Ctx) error {
 	}
 	search := strings.TrimSpace(c.Query("search"))
 	tagNamesRaw := c.Query("tag_names")
-	tagMode := strings.ToUpper(c.Query("tag_mode", "OR"))
+	tagMode := tagModeFromQuery(c)
 	excludeTagNamesRaw := c.Query("exclude_tag_names")
 	tagFormulaRaw3 := c.Query("tag_formula")
 	stageIDsRaw := c.Query("stage_ids")
@@ -6004,6 +6563,7 @@ func (s *Server) handleGetLeadsListPaginated(c *fiber.Ctx) error {
 	args := []interface{}{accountID}
 	argIdx := 2
 	whereClauses := leadWhereClauses("$1", c.Query("lifecycle"), c.Query("status_filter", "active"))
+	addLeadAssignmentWhere(s.assignmentScope(c), &whereClauses, &args, &argIdx)
 
 	addLeadPipelineWhere(pipelineID, &whereClauses, &args, &argIdx)
 	if search != "" {
@@ -6295,8 +6855,19 @@ func (s *Server) handleCreateLead(c *fiber.Ctx) error {
 		lead.StageID = stageID
 	}
 
+	if err := s.persistNewLead(c.Context(), accountID, lead, phone, req.Name, "manual", req.Tags); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{"success": true, "lead": lead})
+}
+
+// persistNewLead links or creates the lead's contact, stores the lead and
+// runs the usual follow-ups: tags, Kommo push, cache, realtime delta and the
+// lead_created automation. lead must already carry its JID and destination.
+func (s *Server) persistNewLead(ctx context.Context, accountID uuid.UUID, lead *domain.Lead, phone, name, contactSource string, tags []string) error {
 	// Auto-link or auto-create contact by JID
-	contact, _ := s.repos.Contact.GetByJID(c.Context(), accountID, jid)
+	contact, _ := s.repos.Contact.GetByJID(ctx, accountID, lead.JID)
 	if contact != nil {
 		lead.ContactID = &contact.ID
 		// Copy contact fields to lead if lead fields are empty
@@ -6333,47 +6904,47 @@ func (s *Server) handleCreateLead(c *fiber.Ctx) error {
 		if lead.Name != nil && *lead.Name != "" {
 			contact.CustomName = lead.Name
 		}
-		_ = s.repos.Contact.Update(c.Context(), contact)
+		_ = s.repos.Contact.Update(ctx, contact)
 	} else {
 		// Auto-create contact from lead data (for both real phone and manual leads)
 		var contactErr error
-		contact, contactErr = s.repos.Contact.GetOrCreate(c.Context(), accountID, nil, jid, phone, req.Name, "", false)
+		contact, contactErr = s.repos.Contact.GetOrCreate(ctx, accountID, nil, lead.JID, phone, name, "", false)
 		if contactErr != nil {
-			return c.Status(500).JSON(fiber.Map{"success": false, "error": contactErr.Error()})
+			return contactErr
 		}
 		if contact != nil {
 			lead.ContactID = &contact.ID
 			// Update contact with extra fields from lead
 			contact.Email = lead.Email
 			contact.Notes = lead.Notes
-			contact.Source = strPtr("manual")
+			contact.Source = strPtr(contactSource)
 			contact.DNI = lead.DNI
 			contact.BirthDate = lead.BirthDate
 			contact.Address = lead.Address
-			if req.Name != "" {
-				contact.CustomName = &req.Name
+			if name != "" {
+				contact.CustomName = &name
 			}
-			_ = s.repos.Contact.Update(c.Context(), contact)
-			log.Printf("[API] Auto-created contact %s for new lead (jid=%s)", contact.ID, jid)
+			_ = s.repos.Contact.Update(ctx, contact)
+			log.Printf("[API] Auto-created contact %s for new lead (jid=%s)", contact.ID, lead.JID)
 		}
 	}
 	if lead.ContactID == nil {
-		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo asegurar contacto para el lead"})
+		return errors.New("no se pudo asegurar contacto para el lead")
 	}
 
-	if err := s.services.Lead.Create(c.Context(), lead); err != nil {
-		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	if err := s.services.Lead.Create(ctx, lead); err != nil {
+		return err
 	}
 
 	// Assign tags if provided
-	if len(req.Tags) > 0 {
-		if err := s.repos.Tag.SyncLeadTagsByNames(c.Context(), accountID, lead.ID, req.Tags); err != nil {
+	if len(tags) > 0 {
+		if err := s.repos.Tag.SyncLeadTagsByNames(ctx, accountID, lead.ID, tags); err != nil {
 			log.Printf("[API] Failed to sync tags for new lead %s: %v", lead.ID, err)
 		}
 	}
 
 	// Push new lead to Kommo (async, only if pipeline is Kommo-connected)
-	if kommoSync := s.kommoForAccount(c.Context(), accountID); kommoSync != nil {
+	if kommoSync := s.kommoForAccount(ctx, accountID); kommoSync != nil {
 		go kommoSync.PushNewLead(accountID, lead.ID)
 	}
 
@@ -6383,7 +6954,7 @@ func (s *Server) handleCreateLead(c *fiber.Ctx) error {
 	// Fire lead_created automation trigger
 	s.triggerAutomationLeadCreated(accountID, lead.ID)
 
-	return c.Status(201).JSON(fiber.Map{"success": true, "lead": lead})
+	return nil
 }
 
 func (s *Server) handleCreateLeadsFromContacts(c *fiber.Ctx) error {
@@ -6637,6 +7208,7 @@ func (s *Server) handleUpdateLead(c *fiber.Ctx) error {
 	if lead.Name != nil {
 		oldName = *lead.Name
 	}
+	oldAssignee := lead.AssignedTo
 
 	// Parse update request
 	var req struct {
@@ -6889,12 +7461,42 @@ func (s *Server) handleUpdateLead(c *fiber.Ctx) error {
 		}
 	}
 
+	if score, err := s.services.Lead.RecalculateScore(c.Context(), lead.ID); err != nil {
+		log.Printf("[LeadScore] recalculate lead %s: %v", lead.ID, err)
+	} else {
+		lead.Score = score
+	}
+
 	s.invalidateLeadsCache(lead.AccountID)
 	s.invalidateLeadDetailCache(lead.AccountID, lead.ID)
 	s.broadcastLeadDelta(lead.AccountID, "updated", lead)
+	if req.AssignedTo != nil {
+		actorID, _ := c.Locals("user_id").(uuid.UUID)
+		s.notifyLeadAssigned(lead, oldAssignee, actorID)
+	}
 	return c.JSON(fiber.Map{"success": true, "lead": lead})
 }
 
+// notifyLeadAssigned tells the new assignee, and only them, that a lead was
+// assigned to them. Self-assignments and unchanged assignees are silent.
+func (s *Server) notifyLeadAssigned(lead *domain.Lead, previous *uuid.UUID, actorID uuid.UUID) {
+	if s.hub == nil || lead.AssignedTo == nil || *lead.AssignedTo == actorID {
+		return
+	}
+	if previous != nil && *previous == *lead.AssignedTo {
+		return
+	}
+	name := lead.Title
+	if name == "" && lead.Name != nil {
+		name = *lead.Name
+	}
+	s.hub.SendToUser(lead.AccountID, *lead.AssignedTo, ws.EventLeadAssigned, map[string]interface{}{
+		"lead_id":     lead.ID.String(),
+		"title":       name,
+		"assigned_by": actorID.String(),
+	})
+}
+
 func (s *Server) handleUpdateLeadStatus(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	leadID, err := uuid.Parse(c.Params("id"))
@@ -6991,6 +7593,10 @@ func (s *Server) handleDeleteLeadsBatch(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
 
+	if req.DeleteAll && !s.callerHasRole(c, domain.RoleAdmin) {
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": roleForbiddenMessage})
+	}
+
 	if req.DeleteAll {
 		// Transfer all orphaned interactions to their contacts before bulk delete
 		_, _ = s.repos.DB().Exec(c.Context(), `
@@ -7359,6 +7965,7 @@ func (s *Server) handleGetLeadCounts(c *fiber.Ctx) error {
 	pipelineClauses := make([]string, 0, 1)
 	argIdx := len(args) + 1
 	addLeadPipelineWhere(pipelineID, &pipelineClauses, &args, &argIdx)
+	addLeadAssignmentWhere(s.assignmentScope(c), &pipelineClauses, &args, &argIdx)
 	for _, clause := range pipelineClauses {
 		extraWhere += " AND " + clause
 	}
@@ -7420,7 +8027,11 @@ func (s *Server) handleUpdateLeadStage(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid stage ID"})
 	}
 
-	if err := s.services.Lead.UpdateStage(c.Context(), leadID, stageID); err != nil {
+	var userID *uuid.UUID
+	if uid, ok := c.Locals("user_id").(uuid.UUID); ok {
+		userID = &uid
+	}
+	if err := s.services.Lead.UpdateStage(c.Context(), leadID, stageID, userID); err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
 
@@ -7675,10 +8286,28 @@ type csvImportSummary struct {
 	DuplicateContactLeads int                   `json:"duplicate_contact_leads"`
 	InvalidRows           int                   `json:"invalid_rows"`
 	DuplicatePolicy       string                `json:"duplicate_policy"`
+	ExistingMode          string                `json:"existing_mode"`
+	ColumnMapping         map[string]int        `json:"column_mapping,omitempty"`
 	SafeMode              bool                  `json:"safe_mode"`
 	IncomingDestination   string                `json:"incoming_destination,omitempty"`
 	Rows                  []csvImportPreviewRow `json:"rows,omitempty"`
 	Errors                []string              `json:"errors"`
+	// FailedRows keeps the original cells of every row that could not be
+	// imported; it is stored with the import log and served as a CSV.
+	FailedRows []csvImportRowError `json:"-"`
+}
+
+// csvImportRowError is one row that failed to import. Stage tells whether
+// the row itself, its contact or its lead failed.
+type csvImportRowError struct {
+	Row    int      `json:"row"`
+	Stage  string   `json:"stage"`
+	Reason string   `json:"reason"`
+	Data   []string `json:"data"`
+}
+
+func (s *csvImportSummary) addFailedRow(row int, stage, reason string, data []string) {
+	s.FailedRows = append(s.FailedRows, csvImportRowError{Row: row, Stage: stage, Reason: reason, Data: data})
 }
 
 type csvImportRecord struct {
@@ -7709,10 +8338,13 @@ type csvImportRecord struct {
 	ExistingContactID  *uuid.UUID
 	WillCreateContact  bool
 	ExistingLeadIDText string
+	UpdateFields       bool
+	Raw                []string
 }
 
 type csvImportPlan struct {
 	Summary csvImportSummary
+	Headers []string
 	Records []csvImportRecord
 }
 
@@ -7742,13 +8374,23 @@ func (s *Server) handlePreviewImportCSV(c *fiber.Ctx) error {
 	if errMsg != "" {
 		return c.Status(status).JSON(fiber.Map{"success": false, "error": errMsg})
 	}
-	plan, err := s.buildCSVImportPlan(c.Context(), accountID, importType, importTag, fileName, rawBytes, importType != "contacts")
+	existingMode, err := parseCSVImportExistingMode(c.FormValue("existing_mode"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	mapping, err := parseCSVImportColumnMapping(c.FormValue("column_mapping"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	plan, err := s.buildCSVImportPlan(c.Context(), accountID, importType, importTag, existingMode, fileName, rawBytes, mapping, importType != "contacts")
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
 	return c.JSON(fiber.Map{"success": true, "preview": plan.Summary})
 }
 
+// handleImportCSV reads the upload and queues it as a background job; poll
+// GET /api/import/:jobId or listen for import_progress events for the result.
 func (s *Server) handleImportCSV(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	userID, _ := c.Locals("user_id").(uuid.UUID)
@@ -7756,49 +8398,24 @@ func (s *Server) handleImportCSV(c *fiber.Ctx) error {
 	if errMsg != "" {
 		return c.Status(status).JSON(fiber.Map{"success": false, "error": errMsg})
 	}
-	releaseImportLock, err := s.acquireCSVImportLock(c.Context(), accountID)
+	existingMode, err := parseCSVImportExistingMode(c.FormValue("existing_mode"))
 	if err != nil {
-		return c.Status(503).JSON(fiber.Map{"success": false, "error": "No se pudo asegurar la importación; inténtalo nuevamente"})
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
-	defer releaseImportLock()
-
-	plan, err := s.buildCSVImportPlan(c.Context(), accountID, importType, importTag, fileName, rawBytes, importType != "contacts")
+	mapping, err := parseCSVImportColumnMapping(c.FormValue("column_mapping"))
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
-	if plan.Summary.NewContacts > 0 {
-		if err := s.enforcePlanLimit(c.Context(), accountID, "max_contacts", plan.Summary.NewContacts); err != nil {
-			return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{"success": false, "error": err.Error(), "code": "plan_limit_reached", "limit": "max_contacts"})
-		}
-	}
-
-	result := s.executeCSVImportPlan(c.Context(), accountID, userID, plan)
-	s.recordCSVImportLog(c.Context(), accountID, userID, result)
-
-	if result.Created > 0 || result.Updated > 0 {
-		s.invalidateLeadsCache(accountID)
-		s.invalidateContactsCache(accountID)
-		s.invalidateTagsCache(accountID)
-	}
-	if result.Created > 0 {
-		go s.services.Event.ReconcileAllAccountEvents(context.Background(), accountID)
+	jobID, err := s.createCSVImportJob(c.Context(), accountID, userID, importType, fileName)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo iniciar la importación"})
 	}
+	go s.runCSVImportJob(jobID, accountID, userID, importType, importTag, existingMode, fileName, rawBytes, mapping)
 
-	return c.JSON(fiber.Map{
-		"success":                 true,
-		"imported":                result.Created + result.Updated,
-		"created":                 result.Created,
-		"updated":                 result.Updated,
-		"existing":                result.Existing,
-		"skipped":                 result.Skipped,
-		"duplicates":              result.Duplicates,
-		"duplicate_contact_leads": result.DuplicateContactLeads,
-		"existing_kommo":          result.ExistingKommo,
-		"new_opportunities":       result.NewOpportunities,
-		"invalid_rows":            result.InvalidRows,
-		"new_contacts":            result.NewContacts,
-		"errors":                  result.Errors,
-		"summary":                 result,
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"job_id":  jobID,
+		"status":  csvImportStatusQueued,
 	})
 }
 
@@ -7811,20 +8428,36 @@ func readCSVImportUpload(c *fiber.Ctx) (string, string, string, []byte, int, str
 		return "", "", "", nil, fiber.StatusBadRequest, "import_type must be 'leads', 'contacts', or 'both'"
 	}
 	importTag := cleanCSVValue(c.FormValue("import_tag"))
+	fileName, rawBytes, status, errMsg := readCSVUploadFile(c)
+	if errMsg != "" {
+		return "", "", "", nil, status, errMsg
+	}
+	return importType, importTag, fileName, rawBytes, fiber.StatusOK, ""
+}
+
+// readCSVUploadFile reads the "file" form field, converting Excel workbooks
+// (optionally the "sheet" form field) to CSV.
+func readCSVUploadFile(c *fiber.Ctx) (string, []byte, int, string) {
 	file, err := c.FormFile("file")
 	if err != nil {
-		return "", "", "", nil, fiber.StatusBadRequest, "CSV file is required"
+		return "", nil, fiber.StatusBadRequest, "CSV or Excel file is required"
 	}
 	f, err := file.Open()
 	if err != nil {
-		return "", "", "", nil, fiber.StatusInternalServerError, "Cannot read file"
+		return "", nil, fiber.StatusInternalServerError, "Cannot read file"
 	}
 	defer f.Close()
 	rawBytes, err := io.ReadAll(f)
 	if err != nil {
-		return "", "", "", nil, fiber.StatusInternalServerError, "Cannot read file content"
+		return "", nil, fiber.StatusInternalServerError, "Cannot read file content"
+	}
+	if isXLSXUpload(file.Filename, file.Header.Get("Content-Type"), rawBytes) {
+		rawBytes, err = xlsxToCSV(rawBytes, c.FormValue("sheet"))
+		if err != nil {
+			return "", nil, fiber.StatusBadRequest, err.Error()
+		}
 	}
-	return importType, importTag, file.Filename, rawBytes, fiber.StatusOK, ""
+	return file.Filename, rawBytes, fiber.StatusOK, ""
 }
 
 func (s *Server) acquireCSVImportLock(ctx context.Context, accountID uuid.UUID) (func(), error) {
@@ -7845,7 +8478,7 @@ func (s *Server) acquireCSVImportLock(ctx context.Context, accountID uuid.UUID)
 	}, nil
 }
 
-func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, importType, importTag, fileName string, rawBytes []byte, strictKommo bool) (*csvImportPlan, error) {
+func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, importType, importTag, existingMode, fileName string, rawBytes []byte, mapping csvImportColumnMapping, strictKommo bool) (*csvImportPlan, error) {
 	rawContent := strings.TrimPrefix(string(rawBytes), "\ufeff")
 	headerLine, dataContent := splitCSVHeader(rawContent)
 	if strings.TrimSpace(headerLine) == "" || strings.TrimSpace(dataContent) == "" {
@@ -7876,21 +8509,29 @@ func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, im
 		}
 	}
 
-	phoneCols := importPhoneColumns(headers, colMap, firstDataRow)
+	var phoneCols []int
+	if mapping != nil {
+		if err := mapping.validate(headers, firstDataRow); err != nil {
+			return nil, err
+		}
+		phoneCols = []int{mapping["phone"]}
+	} else {
+		phoneCols = importPhoneColumns(headers, colMap, firstDataRow)
+	}
 	if len(phoneCols) == 0 {
 		return nil, fmt.Errorf("CSV must have a phone/telefono/celular column or a Kommo phone column")
 	}
 
-	idCol := findCol(colMap, "id", "kommo id", "kommo_id", "lead id", "id lead")
-	nameCol := findCol(colMap, "nombre completo", "contacto principal", "nombre contacto", "nombre de contacto", "name", "nombre", "nombre_completo")
-	leadNameCol := findCol(colMap, "nombre del lead", "lead name")
-	emailCols := importEmailColumns(colMap)
-	notesCols := importNotesColumns(colMap)
-	tagsCol := findCol(colMap, "tags", "etiquetas", "etiquetas del lead")
-	companyCol := findCol(colMap, "company", "empresa", "compañía", "compania", "compañía del lead", "compania del lead")
-	lastNameCol := findCol(colMap, "last_name", "apellido", "apellidos")
-	dniCol := findCol(colMap, "dni", "documento", "doc_identidad")
-	birthDateCol := findCol(colMap, "fecha_nacimiento", "birth_date", "nacimiento", "cumpleanos", "cumpleaños")
+	idCol := mapping.col("kommo_id", findCol(colMap, "id", "kommo id", "kommo_id", "lead id", "id lead"))
+	nameCol := mapping.col("name", findCol(colMap, "nombre completo", "contacto principal", "nombre contacto", "nombre de contacto", "name", "nombre", "nombre_completo"))
+	leadNameCol := mapping.col("lead_name", findCol(colMap, "nombre del lead", "lead name"))
+	emailCols := mapping.cols("email", importEmailColumns(colMap))
+	notesCols := mapping.cols("notes", importNotesColumns(colMap))
+	tagsCol := mapping.col("tags", findCol(colMap, "tags", "etiquetas", "etiquetas del lead"))
+	companyCol := mapping.col("company", findCol(colMap, "company", "empresa", "compañía", "compania", "compañía del lead", "compania del lead"))
+	lastNameCol := mapping.col("last_name", findCol(colMap, "last_name", "apellido", "apellidos"))
+	dniCol := mapping.col("dni", findCol(colMap, "dni", "documento", "doc_identidad"))
+	birthDateCol := mapping.col("birth_date", findCol(colMap, "fecha_nacimiento", "birth_date", "nacimiento", "cumpleanos", "cumpleaños"))
 	kommoStatusCol := findCol(colMap, "estatus del lead")
 	kommoCampaignCol := findImportHeaderCol(headers, colMap, "campaña", "campania", "campana")
 	kommoFechaTagCol := findImportHeaderCol(headers, colMap, "fecha")
@@ -7909,6 +8550,7 @@ func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, im
 	importNow := time.Now().In(kommoImportLocation())
 
 	plan := &csvImportPlan{
+		Headers: headers,
 		Summary: csvImportSummary{
 			ImportType:      importType,
 			Source:          source,
@@ -7916,6 +8558,8 @@ func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, im
 			ImportTag:       importTag,
 			SafeMode:        true,
 			DuplicatePolicy: csvImportDuplicatePolicy,
+			ExistingMode:    existingMode,
+			ColumnMapping:   mapping,
 		},
 	}
 	if pid, sid, err := s.repos.Pipeline.ResolveIncomingLeadDestination(ctx, accountID); err == nil && pid != nil && sid != nil {
@@ -7946,15 +8590,19 @@ func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, im
 			plan.Summary.ErrorCount++
 			plan.Summary.InvalidRows++
 			plan.Summary.Errors = append(plan.Summary.Errors, fmt.Sprintf("fila %d: no se pudo leer (%v)", rowNum, err))
+			plan.Summary.addFailedRow(rowNum, "row", fmt.Sprintf("No se pudo leer la fila: %v", err), row)
 			plan.addPreview(csvImportPreviewRow{Row: rowNum, Action: "skip", ReasonCode: "invalid_row", Reason: "No se pudo leer la fila"})
 			continue
 		}
 		if rowIsEmpty(row) {
 			continue
 		}
+		for i := range row {
+			row[i] = restoreSpreadsheetFormula(row[i])
+		}
 		plan.Summary.TotalRows++
 
-		record := csvImportRecord{RowNum: rowNum, Action: "create", KommoSync: useKommoFreshWindow}
+		record := csvImportRecord{RowNum: rowNum, Action: "create", KommoSync: useKommoFreshWindow, Raw: row}
 		record.Phone = firstValidImportPhone(row, phoneCols)
 		if record.Phone == "" || len(record.Phone) < 6 {
 			record.Action = "skip"
@@ -7962,6 +8610,7 @@ func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, im
 			record.Reason = "Sin teléfono válido"
 			plan.Summary.Skipped++
 			plan.Summary.InvalidRows++
+			plan.Summary.addFailedRow(rowNum, "row", record.Reason, row)
 			plan.addPreview(record.previewRow())
 			plan.Records = append(plan.Records, record)
 			continue
@@ -8029,11 +8678,30 @@ func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, im
 		record.ExistingContactID = contactID
 		record.ActiveLeadCount = activeLeadCount
 
+		matchedByKommoID := leadID != nil
+		if leadID == nil && existingMode == csvImportExistingUpdate && importType != "contacts" && contactID != nil && activeLeadCount > 0 {
+			leadID, err = s.findCSVImportOpenLead(ctx, accountID, *contactID)
+			if err != nil {
+				record.Action = "skip"
+				record.Reason = err.Error()
+				plan.Summary.Skipped++
+				plan.Summary.ErrorCount++
+				plan.Summary.Errors = append(plan.Summary.Errors, fmt.Sprintf("fila %d: %s", rowNum, err.Error()))
+				plan.addPreview(record.previewRow())
+				plan.Records = append(plan.Records, record)
+				continue
+			}
+			record.ExistingLeadID = leadID
+		}
+
 		if leadID != nil {
+			record.UpdateFields = existingMode == csvImportExistingUpdate
 			record.Action = "update_existing"
 			record.ExistingLeadIDText = leadID.String()
 			plan.Summary.Existing++
-			plan.Summary.ExistingKommo++
+			if matchedByKommoID {
+				plan.Summary.ExistingKommo++
+			}
 		} else {
 			if shouldBlockCSVImportContact(strictKommo, importType, activeLeadCount) {
 				record.Action = "duplicate_contact_lead"
@@ -8070,7 +8738,7 @@ func (s *Server) buildCSVImportPlan(ctx context.Context, accountID uuid.UUID, im
 			}
 		}
 
-		if useKommoFreshWindow && leadID != nil {
+		if useKommoFreshWindow && matchedByKommoID {
 			windowReference, missingReason, staleReason := record.kommoWindowReference()
 			if windowReference == nil {
 				record.Action = "skip"
@@ -8165,6 +8833,14 @@ func (r csvImportRecord) previewReason() string {
 	case "update_existing":
 		parts := []string{"Existente; no moverá etapa/pipeline ni tocará notas, tareas u observaciones"}
 		hasApplicableChange := false
+		if r.UpdateFields {
+			parts[0] = "Existente; actualizará con los valores no vacíos del archivo: " + strings.Join(csvImportUpdatedFields, ", ") +
+				". Conserva teléfono, etapa/pipeline, responsable, estado y campos personalizados"
+			if len(r.Tags) > 0 {
+				parts = append(parts, "agregará etiquetas del Excel: "+strings.Join(r.Tags, ", "))
+			}
+			hasApplicableChange = true
+		}
 		if r.KommoSync {
 			if statusTagName := canonicalKommoStatusTagName(r.KommoStatus); statusTagName != "" {
 				parts = append(parts, "sincronizará etiqueta de estado Kommo: "+statusTagName)
@@ -8198,12 +8874,20 @@ func (r csvImportRecord) kommoWindowReference() (*time.Time, string, string) {
 		"Fuera de ventana 24h desde la creación Kommo"
 }
 
-func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uuid.UUID, plan *csvImportPlan) csvImportSummary {
+// executeCSVImportPlan applies the plan. progress, when set, is called every
+// csvImportProgressEvery records with the number processed so far.
+func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uuid.UUID, plan *csvImportPlan, progress func(processed int, result csvImportSummary)) csvImportSummary {
 	result := plan.Summary
 	result.Created = 0
 	result.Updated = 0
+	result.FailedRows = append([]csvImportRowError(nil), plan.Summary.FailedRows...)
 	syncKommoMetadata := plan.Summary.Source == "kommo_csv"
-	for _, record := range plan.Records {
+	overwriteContacts := plan.Summary.ExistingMode == csvImportExistingUpdate
+	var updatedLeadIDs []uuid.UUID
+	for i, record := range plan.Records {
+		if progress != nil && i > 0 && i%csvImportProgressEvery == 0 {
+			progress(i, result)
+		}
 		if record.Action == "skip" || record.Action == "duplicate_contact_lead" {
 			continue
 		}
@@ -8216,6 +8900,7 @@ func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uui
 						result.Skipped++
 						result.ErrorCount++
 						result.Errors = append(result.Errors, fmt.Sprintf("fila %d: etiqueta de estado: %s", record.RowNum, err.Error()))
+						result.addFailedRow(record.RowNum, "lead", err.Error(), record.Raw)
 						continue
 					}
 					changed = changed || statusChanged
@@ -8224,10 +8909,25 @@ func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uui
 						result.Skipped++
 						result.ErrorCount++
 						result.Errors = append(result.Errors, fmt.Sprintf("fila %d: etiqueta fecha: %s", record.RowNum, err.Error()))
+						result.addFailedRow(record.RowNum, "lead", err.Error(), record.Raw)
 						continue
 					}
 					changed = changed || fechaChanged
 				}
+				if record.UpdateFields {
+					fieldsChanged, err := s.applyCSVImportUpdate(ctx, accountID, *record.ExistingLeadID, record)
+					if err != nil {
+						result.Skipped++
+						result.ErrorCount++
+						result.Errors = append(result.Errors, fmt.Sprintf("fila %d: actualización: %s", record.RowNum, err.Error()))
+						result.addFailedRow(record.RowNum, "lead", err.Error(), record.Raw)
+						continue
+					}
+					if fieldsChanged {
+						updatedLeadIDs = append(updatedLeadIDs, *record.ExistingLeadID)
+					}
+					changed = changed || fieldsChanged
+				}
 				if changed {
 					result.Updated++
 				}
@@ -8240,6 +8940,7 @@ func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uui
 				result.Skipped++
 				result.ErrorCount++
 				result.Errors = append(result.Errors, fmt.Sprintf("fila %d: revalidación de contacto: %s", record.RowNum, err.Error()))
+				result.addFailedRow(record.RowNum, "contact", err.Error(), record.Raw)
 				continue
 			}
 			if activeCount > 0 {
@@ -8247,11 +8948,12 @@ func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uui
 				continue
 			}
 		}
-		contact, contactCreated, _, err := s.ensureCSVImportContact(ctx, accountID, record)
+		contact, contactCreated, _, err := s.ensureCSVImportContact(ctx, accountID, record, overwriteContacts)
 		if err != nil {
 			result.Skipped++
 			result.ErrorCount++
 			result.Errors = append(result.Errors, fmt.Sprintf("fila %d: contacto: %s", record.RowNum, err.Error()))
+			result.addFailedRow(record.RowNum, "contact", err.Error(), record.Raw)
 			continue
 		}
 		if contactCreated {
@@ -8271,6 +8973,7 @@ func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uui
 				result.Skipped++
 				result.ErrorCount++
 				result.Errors = append(result.Errors, fmt.Sprintf("fila %d: validación de duplicado: %s", record.RowNum, err.Error()))
+				result.addFailedRow(record.RowNum, "lead", err.Error(), record.Raw)
 				continue
 			}
 			if activeCount > 0 {
@@ -8282,10 +8985,14 @@ func (s *Server) executeCSVImportPlan(ctx context.Context, accountID, userID uui
 			result.Skipped++
 			result.ErrorCount++
 			result.Errors = append(result.Errors, fmt.Sprintf("fila %d: lead: %s", record.RowNum, err.Error()))
+			result.addFailedRow(record.RowNum, "lead", err.Error(), record.Raw)
 			continue
 		}
 		result.Created++
 	}
+	if len(updatedLeadIDs) > 0 {
+		s.recalculateLeadScores(updatedLeadIDs)
+	}
 	return result
 }
 
@@ -8368,7 +9075,9 @@ func (s *Server) createCSVImportLead(ctx context.Context, accountID, userID uuid
 	return nil
 }
 
-func (s *Server) ensureCSVImportContact(ctx context.Context, accountID uuid.UUID, record csvImportRecord) (*domain.Contact, bool, bool, error) {
+// ensureCSVImportContact finds or creates the row's contact. Existing values
+// are only filled when empty unless overwrite is set (update_existing mode).
+func (s *Server) ensureCSVImportContact(ctx context.Context, accountID uuid.UUID, record csvImportRecord, overwrite bool) (*domain.Contact, bool, bool, error) {
 	contact, err := s.repos.Contact.GetByJID(ctx, accountID, record.JID)
 	if err != nil {
 		return nil, false, false, err
@@ -8381,7 +9090,11 @@ func (s *Server) ensureCSVImportContact(ctx context.Context, accountID uuid.UUID
 		}
 		created = true
 	}
-	updated := fillEmptyContactFields(contact, record)
+	updated := false
+	if overwrite && !created {
+		updated = overwriteCSVImportContactFields(contact, record)
+	}
+	updated = fillEmptyContactFields(contact, record) || updated
 	if updated {
 		if err := s.repos.Contact.Update(ctx, contact); err != nil {
 			return nil, created, false, err
@@ -8521,7 +9234,7 @@ func (s *Server) findCSVImportContactState(ctx context.Context, accountID uuid.U
 		LEFT JOIN leads l ON l.account_id = c.account_id
 			AND l.contact_id = c.id
 			AND l.status IN ('open', 'new')
-			AND l.deleted_at IS NULL
+			AND `+repository.NotDeleted("l")+`
 			AND l.is_archived = FALSE
 		WHERE c.account_id = $1 AND c.jid = $2
 		GROUP BY c.id
@@ -8542,7 +9255,7 @@ func (s *Server) countOpenCSVImportLeads(ctx context.Context, accountID, contact
 		FROM leads
 		WHERE account_id = $1 AND contact_id = $2
 			AND status IN ('open', 'new')
-			AND deleted_at IS NULL
+			AND `+repository.NotDeleted("")+`
 			AND is_archived = FALSE
 	`, accountID, contactID).Scan(&count)
 	return count, err
@@ -8561,33 +9274,6 @@ func validateKommoIquitosV2Headers(headers []string) error {
 	return nil
 }
 
-func (s *Server) recordCSVImportLog(ctx context.Context, accountID, userID uuid.UUID, summary csvImportSummary) {
-	details, _ := json.Marshal(fiber.Map{
-		"incoming_destination":    summary.IncomingDestination,
-		"import_tag":              summary.ImportTag,
-		"safe_mode":               summary.SafeMode,
-		"duplicate_policy":        summary.DuplicatePolicy,
-		"new_opportunities":       summary.NewOpportunities,
-		"existing_kommo":          summary.ExistingKommo,
-		"duplicate_contact_leads": summary.DuplicateContactLeads,
-		"invalid_rows":            summary.InvalidRows,
-		"errors":                  summary.Errors,
-	})
-	_, err := s.repos.DB().Exec(ctx, `
-		INSERT INTO csv_import_logs (
-			account_id, uploaded_by, import_type, source, file_name, total_rows,
-			created_count, updated_count, existing_count, skipped_count,
-			duplicate_count, error_count, new_contacts_count, details
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-	`, accountID, userID, summary.ImportType, summary.Source, summary.FileName, summary.TotalRows,
-		summary.Created, summary.Updated, summary.Existing, summary.Skipped,
-		summary.Duplicates, summary.ErrorCount, summary.NewContacts, details)
-	if err != nil {
-		log.Printf("[CSV Import] failed to write import log: %v", err)
-	}
-}
-
 func splitCSVHeader(rawContent string) (string, string) {
 	rawContent = strings.TrimLeft(rawContent, "\r\n\t ")
 	for i, ch := range rawContent {
@@ -9138,6 +9824,7 @@ func (s *Server) parseContactFilter(c *fiber.Ctx, accountID uuid.UUID) (domain.C
 			}
 		}
 	}
+	filter.TagMatch = parseTagMatch(c)
 
 	// Advanced tag filtering: formula or tag_names
 	tagFormulaRaw := c.Query("tag_formula")
@@ -9175,7 +9862,7 @@ func (s *Server) parseContactFilter(c *fiber.Ctx, accountID uuid.UUID) (domain.C
 		if excludeRaw := c.Query("exclude_tag_names"); excludeRaw != "" {
 			filter.ExcludeTagNames = strings.Split(excludeRaw, ",")
 		}
-		filter.TagMode = strings.ToUpper(c.Query("tag_mode", "OR"))
+		filter.TagMode = tagModeFromQuery(c)
 	}
 
 	// Date filters
@@ -9565,6 +10252,38 @@ func (s *Server) handleDeleteContact(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// handleGetContactTrash lists soft-deleted contacts; they are purged after 30 days.
+// GET /api/contacts/trash?limit=50&offset=0
+func (s *Server) handleGetContactTrash(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	limit, offset := trashPage(c)
+	contacts, total, err := s.services.Contact.GetTrash(c.Context(), accountID, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "contacts": contacts, "total": total})
+}
+
+// handleRestoreContact takes a contact, and the leads deleted with it, out of the trash.
+// POST /api/contacts/:id/restore
+func (s *Server) handleRestoreContact(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "invalid id"})
+	}
+	if err := s.services.Contact.Restore(c.Context(), accountID, id); err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"success": false, "error": "Contact not found in trash"})
+		}
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	s.invalidateContactTreeCaches(accountID)
+	s.invalidateLeadsCache(accountID)
+	contact, _ := s.services.Contact.GetByID(c.Context(), id)
+	return c.JSON(fiber.Map{"success": true, "contact": contact})
+}
+
 func (s *Server) handleGetContactLeads(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	contactID, err := uuid.Parse(c.Params("id"))
@@ -9653,6 +10372,10 @@ func (s *Server) handleDeleteContactsBatch(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "invalid request"})
 	}
 
+	if body.DeleteAll && !s.callerHasRole(c, domain.RoleAdmin) {
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": roleForbiddenMessage})
+	}
+
 	if body.DeleteAll {
 		if err := s.services.Contact.DeleteAll(c.Context(), accountID); err != nil {
 			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
@@ -9674,7 +10397,7 @@ func (s *Server) handleDeleteContactsBatch(c *fiber.Ctx) error {
 
 func (s *Server) handleGetContactDuplicates(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
-	groups, err := s.services.Contact.FindDuplicateGroups(c.Context(), accountID)
+	groups, err := s.services.Contact.FindDuplicateGroups(c.Context(), accountID, c.QueryBool("match_email", false))
 	if err != nil {
 		log.Printf("[contacts] duplicate scan failed for account %s: %v", accountID, err)
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudieron cargar los duplicados"})
@@ -10060,6 +10783,34 @@ func (s *Server) handleGetTags(c *fiber.Ctx) error {
 	return c.JSON(result)
 }
 
+// validTagColor reports whether color is a #RRGGBB hex value, the only form
+// the tag chips render.
+func validTagColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+	for _, ch := range color[1:] {
+		if !(ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// handleGetTagUsage lists every tag with how many contacts, leads, chats and
+// event participants use it, so unused tags can be found and cleaned up.
+func (s *Server) handleGetTagUsage(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	usage, err := s.services.Tag.GetUsage(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if usage == nil {
+		usage = make([]*domain.TagUsage, 0)
+	}
+	return c.JSON(fiber.Map{"success": true, "usage": usage})
+}
+
 func (s *Server) handleCreateTag(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	var req struct {
@@ -10075,6 +10826,9 @@ func (s *Server) handleCreateTag(c *fiber.Ctx) error {
 	if req.Color == "" {
 		req.Color = "#6366f1"
 	}
+	if !validTagColor(req.Color) {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Color must be a hex value like #6366f1"})
+	}
 	tag := &domain.Tag{AccountID: accountID, Name: req.Name, Color: req.Color}
 	if err := s.services.Tag.Create(c.Context(), tag); err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
@@ -10100,6 +10854,11 @@ func (s *Server) handleUpdateTag(c *fiber.Ctx) error {
 	if getErr != nil || existing == nil || existing.AccountID != accountID {
 		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Tag not found"})
 	}
+	if req.Color == "" {
+		req.Color = existing.Color
+	} else if !validTagColor(req.Color) {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Color must be a hex value like #6366f1"})
+	}
 	tag := &domain.Tag{ID: id, AccountID: accountID, Name: req.Name, Color: req.Color}
 	if err := s.services.Tag.Update(c.Context(), tag); err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
@@ -10118,6 +10877,10 @@ func (s *Server) handleDeleteTagsBatch(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
 
+	if body.DeleteAll && !s.callerHasRole(c, domain.RoleAdmin) {
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": roleForbiddenMessage})
+	}
+
 	if body.DeleteAll {
 		if err := s.services.Tag.DeleteAll(c.Context(), accountID); err != nil {
 			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
@@ -10247,7 +11010,120 @@ func (s *Server) handleRemoveTag(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
-func (s *Server) handleGetEntityTags(c *fiber.Ctx) error {
+// maxBulkTagEntities bounds one bulk tag request.
+const maxBulkTagEntities = 1000
+
+func (s *Server) handleAssignTagBulk(c *fiber.Ctx) error {
+	return s.setTagBulk(c, true)
+}
+
+func (s *Server) handleRemoveTagBulk(c *fiber.Ctx) error {
+	return s.setTagBulk(c, false)
+}
+
+// setTagBulk assigns or removes one tag across many entities of a type in a
+// single write, then queues one batched Kommo push for the affected leads.
+func (s *Server) setTagBulk(c *fiber.Ctx, assign bool) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		EntityType string      `json:"entity_type"`
+		EntityIDs  []uuid.UUID `json:"entity_ids"`
+		TagID      string      `json:"tag_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	tagID, err := uuid.Parse(req.TagID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid tag ID"})
+	}
+	if len(req.EntityIDs) == 0 {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "entity_ids is required"})
+	}
+	if len(req.EntityIDs) > maxBulkTagEntities {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": fmt.Sprintf("At most %d entities per request", maxBulkTagEntities)})
+	}
+
+	resolved, contactIDs, changed, err := s.repos.Tag.SetEntitiesTagForAccount(c.Context(), accountID, req.EntityType, req.EntityIDs, tagID, assign)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+
+	if req.EntityType == "lead" && len(resolved) > 0 {
+		// Push tag changes to Kommo (batched via outbox) — only for leads, NOT contacts
+		if kommoSync := s.kommoForAccount(c.Context(), accountID); kommoSync != nil {
+			kommoSync.EnqueuePushLeadsTags(accountID, resolved)
+		}
+		for _, leadID := range resolved {
+			if assign {
+				s.triggerAutomationTagAssigned(accountID, leadID, tagID)
+			} else {
+				s.triggerAutomationTagRemoved(accountID, leadID, tagID)
+			}
+		}
+	}
+	if len(contactIDs) > 0 {
+		// Reconcile synchronously, in one batch, so the event rosters (and
+		// their cache) already reflect the new tags when the response lands.
+		if _, reconcileErr := s.services.Event.ReconcileContactsEventMembership(c.Context(), accountID, contactIDs); reconcileErr != nil {
+			log.Printf("[EVENT-SYNC] Bulk tag reconciliation failed for account %s: %v", accountID, reconcileErr)
+		}
+		s.invalidateEventsCache(accountID)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"matched":  len(resolved),
+		"skipped":  len(req.EntityIDs) - len(resolved),
+		"contacts": len(contactIDs),
+		"changed":  changed,
+	})
+}
+
+// handleMergeTags folds source_id into target_id, keeping every contact,
+// chat, participant and event tagged with the source, then deletes the source.
+func (s *Server) handleMergeTags(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		SourceID string `json:"source_id"`
+		TargetID string `json:"target_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	sourceID, err := uuid.Parse(req.SourceID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid source tag ID"})
+	}
+	targetID, err := uuid.Parse(req.TargetID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid target tag ID"})
+	}
+	if sourceID == targetID {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Source and target must be different tags"})
+	}
+
+	moved, leadIDs, err := s.repos.Tag.MergeTagsForAccount(c.Context(), accountID, sourceID, targetID)
+	if err != nil {
+		return writeCRMError(c, err)
+	}
+
+	if kommoSync := s.kommoForAccount(c.Context(), accountID); kommoSync != nil {
+		kommoSync.EnqueuePushLeadsTags(accountID, leadIDs)
+	}
+	s.invalidateEventsCache(accountID)
+	s.invalidateTagsCache(accountID)
+	go func() {
+		// Rosters change once the reconciliation ends; drop the lists that
+		// were cached in between.
+		s.services.Event.ReconcileAllAccountEvents(context.Background(), accountID)
+		s.invalidateEventsCache(accountID)
+	}()
+
+	return c.JSON(fiber.Map{"success": true, "moved": moved})
+}
+
+func (s *Server) handleGetEntityTags(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	entityType := c.Params("type")
 	entityID, err := uuid.Parse(c.Params("id"))
@@ -10315,6 +11191,8 @@ func (s *Server) handleCreateCampaign(c *fiber.Ctx) error {
 		Settings        map[string]interface{} `json:"settings"`
 		EventID         *string                `json:"event_id"`
 		Source          *string                `json:"source"`
+		TestMode        *bool                  `json:"test_mode"`
+		TestNumbers     []string               `json:"test_numbers"`
 		Attachments     []struct {
 			MediaURL  string `json:"media_url"`
 			MediaType string `json:"media_type"`
@@ -10334,6 +11212,33 @@ func (s *Server) handleCreateCampaign(c *fiber.Ctx) error {
 	if req.MessageTemplate == "" && len(req.Attachments) == 0 {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "message_template or attachments required"})
 	}
+	// test_mode/test_numbers may be sent at the top level or inside settings.
+	if req.TestMode != nil || req.TestNumbers != nil {
+		if req.Settings == nil {
+			req.Settings = map[string]interface{}{}
+		}
+		if req.TestMode != nil {
+			req.Settings["test_mode"] = *req.TestMode
+		}
+		if req.TestNumbers != nil {
+			numbers := make([]interface{}, 0, len(req.TestNumbers))
+			for _, n := range req.TestNumbers {
+				if n = strings.TrimSpace(n); n != "" {
+					numbers = append(numbers, n)
+				}
+			}
+			req.Settings["test_numbers"] = numbers
+		}
+	}
+	if whitelist, testMode := service.CampaignTestNumbers(req.Settings); testMode && len(whitelist) == 0 {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "test_mode requiere al menos un número en test_numbers"})
+	}
+	if _, err := service.ParseCampaignRecurrence(req.Settings); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if err := service.ValidateCampaignMaxRetries(req.Settings); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
 	deviceID, err := uuid.Parse(req.DeviceID)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
@@ -10410,7 +11315,19 @@ func (s *Server) handleGetCampaign(c *fiber.Ctx) error {
 	// Load attachments
 	attachments, _ := s.repos.CampaignAttachment.GetByCampaignID(c.Context(), id)
 	campaign.Attachments = attachments
-	return c.JSON(fiber.Map{"success": true, "campaign": campaign})
+	response := fiber.Map{"success": true, "campaign": campaign}
+	// Test-mode runs report whitelisted sends against skipped recipients.
+	_, testMode := service.CampaignTestNumbers(campaign.Settings)
+	if counts, err := s.repos.Campaign.CountRecipientsByStatus(c.Context(), id); err == nil && (testMode || counts["skipped_test"] > 0) {
+		response["test_summary"] = fiber.Map{
+			"test_mode":    testMode,
+			"test_sent":    counts["sent"],
+			"test_failed":  counts["failed"],
+			"skipped_test": counts["skipped_test"],
+			"pending":      counts["pending"],
+		}
+	}
+	return c.JSON(response)
 }
 
 func (s *Server) handleUpdateCampaign(c *fiber.Ctx) error {
@@ -10435,10 +11352,12 @@ func (s *Server) handleUpdateCampaign(c *fiber.Ctx) error {
 		ScheduledAt     *time.Time             `json:"scheduled_at"`
 		Status          *string                `json:"status"`
 		Settings        map[string]interface{} `json:"settings"`
+		ConfirmCount    *int                   `json:"confirm_count"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
+	wasScheduled := campaign.Status == domain.CampaignStatusScheduled
 	if req.Name != nil {
 		campaign.Name = *req.Name
 	}
@@ -10478,8 +11397,28 @@ func (s *Server) handleUpdateCampaign(c *fiber.Ctx) error {
 		campaign.Status = *req.Status
 	}
 	if req.Settings != nil {
+		if _, err := service.ParseCampaignRecurrence(req.Settings); err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+		if err := service.ValidateCampaignMaxRetries(req.Settings); err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+		if confirmed, ok := campaign.Settings[service.CampaignConfirmedCountKey]; ok {
+			req.Settings[service.CampaignConfirmedCountKey] = confirmed
+		}
 		campaign.Settings = req.Settings
 	}
+	// A scheduled campaign starts unattended, so the recipient guard runs
+	// here, when it is scheduled.
+	if campaign.Status == domain.CampaignStatusScheduled && (!wasScheduled || req.ConfirmCount != nil) {
+		if err := s.services.Campaign.ConfirmSchedule(c.Context(), campaign, req.ConfirmCount); err != nil {
+			var confirmErr *service.CampaignStartConfirmationError
+			if errors.As(err, &confirmErr) {
+				return c.Status(409).JSON(campaignConfirmationRequired(confirmErr))
+			}
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+	}
 	if err := s.services.Campaign.Update(c.Context(), campaign); err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
@@ -10551,12 +11490,21 @@ func (s *Server) handleAddCampaignRecipients(c *fiber.Ctx) error {
 			Phone     *string                `json:"phone"`
 			Metadata  map[string]interface{} `json:"metadata"`
 		} `json:"recipients"`
-		SaveAsContacts bool `json:"save_as_contacts"`
+		SaveAsContacts bool    `json:"save_as_contacts"`
+		CreateLeads    bool    `json:"create_leads"`
+		SourceTagID    *string `json:"source_tag_id"`
+		PipelineID     *string `json:"pipeline_id"`
+		StageID        *string `json:"stage_id"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
+	opts, err := s.resolveCampaignRecipientImportOptions(c.Context(), acctUUID, req.CreateLeads, req.SourceTagID, req.PipelineID, req.StageID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
 	var recipients []*domain.CampaignRecipient
+	var report campaignRecipientImportReport
 	seenContacts := make(map[uuid.UUID]struct{}, len(req.Recipients))
 	for _, r := range req.Recipients {
 		rec := &domain.CampaignRecipient{
@@ -10579,9 +11527,15 @@ func (s *Server) handleAddCampaignRecipients(c *fiber.Ctx) error {
 			if r.Name != nil {
 				name = *r.Name
 			}
+			existing, _ := s.repos.Contact.GetByPhone(c.Context(), acctUUID, phone)
 			contact, err := s.services.Contact.GetOrCreate(c.Context(), acctUUID, nil, jid, phone, name, "", false)
 			if err == nil && contact != nil {
 				rec.ContactID = &contact.ID
+				if existing != nil && existing.ID == contact.ID {
+					report.ContactsLinked++
+				} else {
+					report.ContactsCreated++
+				}
 			}
 		}
 		if rec.ContactID == nil {
@@ -10617,11 +11571,139 @@ func (s *Server) handleAddCampaignRecipients(c *fiber.Ctx) error {
 		}
 		recipients = append(recipients, rec)
 	}
-	if err := s.services.Campaign.AddRecipients(c.Context(), recipients); err != nil {
+	added, err := s.services.Campaign.AddRecipients(c.Context(), recipients)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
+	s.applyCampaignRecipientImportOptions(c.Context(), acctUUID, recipients, opts, &report)
 	s.invalidateCampaignsCache(acctUUID)
-	return c.JSON(fiber.Map{"success": true, "count": len(recipients)})
+	if opts.createLeads || opts.sourceTagID != nil {
+		s.invalidateContactTreeCaches(acctUUID)
+	}
+	return c.JSON(fiber.Map{
+		"success":            true,
+		"count":              added.Added,
+		"added":              added.Added,
+		"duplicates_skipped": added.DuplicatesSkipped,
+		"report":             report,
+	})
+}
+
+// campaignRecipientImportOptions lets a recipient list double as a lead import:
+// recipients' contacts can be tagged and given a lead in a chosen pipeline.
+type campaignRecipientImportOptions struct {
+	createLeads bool
+	sourceTagID *uuid.UUID
+	pipelineID  *uuid.UUID
+	stageID     *uuid.UUID
+}
+
+type campaignRecipientImportReport struct {
+	ContactsCreated int `json:"contacts_created"`
+	ContactsLinked  int `json:"contacts_linked"`
+	LeadsCreated    int `json:"leads_created"`
+	LeadsLinked     int `json:"leads_linked"`
+	LeadsFailed     int `json:"leads_failed"`
+	Tagged          int `json:"tagged"`
+}
+
+func (s *Server) resolveCampaignRecipientImportOptions(ctx context.Context, accountID uuid.UUID, createLeads bool, sourceTagID, pipelineID, stageID *string) (campaignRecipientImportOptions, error) {
+	opts := campaignRecipientImportOptions{createLeads: createLeads}
+	if sourceTagID != nil && *sourceTagID != "" {
+		tagID, err := uuid.Parse(*sourceTagID)
+		if err != nil {
+			return opts, fmt.Errorf("source_tag_id inválido")
+		}
+		tag, err := s.repos.Tag.GetByID(ctx, tagID)
+		if err != nil || tag == nil || tag.AccountID != accountID {
+			return opts, fmt.Errorf("La etiqueta de origen no existe en esta cuenta")
+		}
+		opts.sourceTagID = &tagID
+	}
+	if !createLeads {
+		return opts, nil
+	}
+	if pipelineID == nil || *pipelineID == "" {
+		// Same destination as leads auto-created from incoming messages.
+		opts.pipelineID, opts.stageID, _ = s.repos.Pipeline.ResolveIncomingLeadDestination(ctx, accountID)
+		return opts, nil
+	}
+	pid, err := uuid.Parse(*pipelineID)
+	if err != nil {
+		return opts, fmt.Errorf("pipeline_id inválido")
+	}
+	pipeline, err := s.repos.Pipeline.GetByIDForAccount(ctx, accountID, pid)
+	if err != nil || pipeline == nil {
+		return opts, fmt.Errorf("El pipeline no existe en esta cuenta")
+	}
+	stages, err := s.repos.Pipeline.GetStages(ctx, pid)
+	if err != nil {
+		return opts, err
+	}
+	opts.pipelineID = &pid
+	if stageID != nil && *stageID != "" {
+		sid, err := uuid.Parse(*stageID)
+		if err != nil {
+			return opts, fmt.Errorf("stage_id inválido")
+		}
+		for _, stage := range stages {
+			if stage.ID == sid {
+				opts.stageID = &sid
+				return opts, nil
+			}
+		}
+		return opts, fmt.Errorf("La etapa no pertenece al pipeline")
+	}
+	for _, stage := range stages {
+		if stage.StageType != domain.PipelineStageTypeWon && stage.StageType != domain.PipelineStageTypeLost {
+			id := stage.ID
+			opts.stageID = &id
+			break
+		}
+	}
+	if opts.stageID == nil {
+		return opts, fmt.Errorf("El pipeline no tiene etapas activas")
+	}
+	return opts, nil
+}
+
+// applyCampaignRecipientImportOptions tags recipients' contacts and creates a
+// lead for contacts that have none yet. Failures are counted, not fatal: the
+// recipients are already saved at this point.
+func (s *Server) applyCampaignRecipientImportOptions(ctx context.Context, accountID uuid.UUID, recipients []*domain.CampaignRecipient, opts campaignRecipientImportOptions, report *campaignRecipientImportReport) {
+	for _, rec := range recipients {
+		if rec.ContactID == nil {
+			continue
+		}
+		if opts.sourceTagID != nil {
+			if err := s.repos.Tag.AssignToContact(ctx, *rec.ContactID, *opts.sourceTagID); err == nil {
+				report.Tagged++
+			}
+		}
+		if !opts.createLeads {
+			continue
+		}
+		if existing, _ := s.repos.Lead.GetByJID(ctx, accountID, rec.JID); existing != nil {
+			report.LeadsLinked++
+			continue
+		}
+		lead := &domain.Lead{
+			AccountID:  accountID,
+			ContactID:  rec.ContactID,
+			JID:        rec.JID,
+			Name:       rec.Name,
+			Phone:      rec.Phone,
+			Source:     strPtr("campaign"),
+			PipelineID: opts.pipelineID,
+			StageID:    opts.stageID,
+		}
+		if err := s.services.Lead.Create(ctx, lead); err != nil {
+			log.Printf("[Campaign] lead creation failed for contact %s: %v", *rec.ContactID, err)
+			report.LeadsFailed++
+			continue
+		}
+		report.LeadsCreated++
+	}
 }
 
 // handleAddCampaignRecipientsFromContacts resolves all contacts that match the
@@ -10713,7 +11795,7 @@ func (s *Server) handleAddCampaignRecipientsFromLeads(c *fiber.Ctx) error {
 	// Parse the same filter params used by the leads list endpoint
 	search := strings.TrimSpace(c.Query("search"))
 	tagNamesRaw := c.Query("tag_names")
-	tagMode := strings.ToUpper(c.Query("tag_mode", "OR"))
+	tagMode := tagModeFromQuery(c)
 	excludeTagNamesRaw := c.Query("exclude_tag_names")
 	tagFormulaRaw := c.Query("tag_formula")
 	stageIDsRaw := c.Query("stage_ids")
@@ -10733,6 +11815,7 @@ func (s *Server) handleAddCampaignRecipientsFromLeads(c *fiber.Ctx) error {
 	argIdx := 2
 	whereClauses := []string{"l.account_id = $1", "NULLIF(c.phone,'') IS NOT NULL", "COALESCE(c.do_not_contact,FALSE)=FALSE"}
 	addLeadLifecycleWhere(c, &whereClauses)
+	addLeadAssignmentWhere(s.assignmentScope(c), &whereClauses, &args, &argIdx)
 
 	if pipelineID == "__no_pipeline__" {
 		whereClauses = append(whereClauses, "l.pipeline_id IS NULL")
@@ -10861,13 +11944,19 @@ func (s *Server) handleAddCampaignRecipientsFromLeads(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"success": true, "count": 0, "message": "No leads with phone found matching filters"})
 	}
 
-	if err := s.services.Campaign.AddRecipients(c.Context(), recipients); err != nil {
+	added, err := s.services.Campaign.AddRecipients(c.Context(), recipients)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
 
-	log.Printf("[API] Added %d recipients from leads to campaign %s", len(recipients), campaignID)
+	log.Printf("[API] Added %d recipients from leads to campaign %s (%d duplicates skipped)", added.Added, campaignID, added.DuplicatesSkipped)
 	s.invalidateCampaignsCache(accountID)
-	return c.JSON(fiber.Map{"success": true, "count": len(recipients)})
+	return c.JSON(fiber.Map{
+		"success":            true,
+		"count":              added.Added,
+		"added":              added.Added,
+		"duplicates_skipped": added.DuplicatesSkipped,
+	})
 }
 
 func (s *Server) handleGetCampaignRecipients(c *fiber.Ctx) error {
@@ -10902,7 +11991,7 @@ func (s *Server) handleGetCampaignRecipients(c *fiber.Ctx) error {
 	// Map contact_id → lead_id
 	contactToLead := make(map[uuid.UUID]string)
 	if len(contactIDs) > 0 {
-		rows, err := s.repos.DB().Query(c.Context(), `SELECT DISTINCT ON (contact_id) id, contact_id FROM leads WHERE account_id=$1 AND contact_id = ANY($2) ORDER BY contact_id, (deleted_at IS NULL) DESC, (status='open') DESC, updated_at DESC`, accountID, contactIDs)
+		rows, err := s.repos.DB().Query(c.Context(), `SELECT DISTINCT ON (contact_id) id, contact_id FROM leads WHERE account_id=$1 AND contact_id = ANY($2) ORDER BY contact_id, (`+repository.NotDeleted("")+`) DESC, (status='open') DESC, updated_at DESC`, accountID, contactIDs)
 		if err == nil {
 			defer rows.Close()
 			for rows.Next() {
@@ -10917,7 +12006,7 @@ func (s *Server) handleGetCampaignRecipients(c *fiber.Ctx) error {
 	// Fallback: JID → lead_id (for recipients without contact_id)
 	jidToLead := make(map[string]string)
 	if len(jids) > 0 {
-		rows, err := s.repos.DB().Query(c.Context(), `SELECT DISTINCT ON (l.jid) l.id, l.jid FROM leads l WHERE l.account_id=$1 AND l.jid = ANY($2) ORDER BY l.jid, (l.deleted_at IS NULL) DESC, (l.status='open') DESC, l.updated_at DESC`, accountID, jids)
+		rows, err := s.repos.DB().Query(c.Context(), `SELECT DISTINCT ON (l.jid) l.id, l.jid FROM leads l WHERE l.account_id=$1 AND l.jid = ANY($2) ORDER BY l.jid, (`+repository.NotDeleted("l")+`) DESC, (l.status='open') DESC, l.updated_at DESC`, accountID, jids)
 		if err == nil {
 			defer rows.Close()
 			for rows.Next() {
@@ -10977,7 +12066,21 @@ func (s *Server) handleGetCampaignRecipients(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.JSON(fiber.Map{"success": true, "recipients": enriched})
+	// Per-channel delivery breakdown: recipients reached over the SMS
+	// fallback are reported separately from WhatsApp.
+	channels := map[string]map[string]int{}
+	for _, r := range recipients {
+		channel := r.Channel
+		if channel == "" {
+			channel = domain.CampaignChannelWhatsApp
+		}
+		if channels[channel] == nil {
+			channels[channel] = map[string]int{}
+		}
+		channels[channel][r.Status]++
+	}
+
+	return c.JSON(fiber.Map{"success": true, "recipients": enriched, "channels": channels})
 }
 
 func (s *Server) handleDeleteCampaignRecipient(c *fiber.Ctx) error {
@@ -11037,12 +12140,12 @@ func (s *Server) handleUpdateCampaignRecipient(c *fiber.Ctx) error {
 	result := enrichedRecipient{CampaignRecipient: rec}
 	var lid uuid.UUID
 	if rec.ContactID != nil {
-		if err := s.repos.DB().QueryRow(c.Context(), `SELECT id FROM leads WHERE account_id=$1 AND contact_id=$2 ORDER BY (deleted_at IS NULL) DESC, (status='open') DESC, updated_at DESC LIMIT 1`, accountID, *rec.ContactID).Scan(&lid); err == nil {
+		if err := s.repos.DB().QueryRow(c.Context(), `SELECT id FROM leads WHERE account_id=$1 AND contact_id=$2 ORDER BY (`+repository.NotDeleted("")+`) DESC, (status='open') DESC, updated_at DESC LIMIT 1`, accountID, *rec.ContactID).Scan(&lid); err == nil {
 			lidStr := lid.String()
 			result.LeadID = &lidStr
 		}
 	} else if rec.JID != "" {
-		if err := s.repos.DB().QueryRow(c.Context(), `SELECT id FROM leads WHERE account_id=$1 AND jid=$2 ORDER BY (deleted_at IS NULL) DESC, (status='open') DESC, updated_at DESC LIMIT 1`, accountID, rec.JID).Scan(&lid); err == nil {
+		if err := s.repos.DB().QueryRow(c.Context(), `SELECT id FROM leads WHERE account_id=$1 AND jid=$2 ORDER BY (`+repository.NotDeleted("")+`) DESC, (status='open') DESC, updated_at DESC LIMIT 1`, accountID, rec.JID).Scan(&lid); err == nil {
 			lidStr := lid.String()
 			result.LeadID = &lidStr
 		}
@@ -11051,6 +12154,18 @@ func (s *Server) handleUpdateCampaignRecipient(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "recipient": result})
 }
 
+// campaignConfirmationRequired is the 409 body asking the client to repeat a
+// start or schedule with confirm_count set to the real recipient total.
+func campaignConfirmationRequired(confirmErr *service.CampaignStartConfirmationError) fiber.Map {
+	return fiber.Map{
+		"success":         false,
+		"code":            "confirmation_required",
+		"error":           fmt.Sprintf("La campaña enviará %d mensajes. Confirma enviando confirm_count=%d.", confirmErr.RecipientCount, confirmErr.RecipientCount),
+		"recipient_count": confirmErr.RecipientCount,
+		"threshold":       confirmErr.Threshold,
+	}
+}
+
 func (s *Server) handleStartCampaign(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	id, err := uuid.Parse(c.Params("id"))
@@ -11070,11 +12185,23 @@ func (s *Server) handleStartCampaign(c *fiber.Ctx) error {
 		}
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
+	var req struct {
+		ConfirmCount *int `json:"confirm_count"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request body"})
+		}
+	}
 	var startedBy *uuid.UUID
 	if userID, ok := c.Locals("user_id").(uuid.UUID); ok {
 		startedBy = &userID
 	}
-	if err := s.services.Campaign.Start(c.Context(), id, startedBy); err != nil {
+	if err := s.services.Campaign.Start(c.Context(), id, startedBy, req.ConfirmCount); err != nil {
+		var confirmErr *service.CampaignStartConfirmationError
+		if errors.As(err, &confirmErr) {
+			return c.Status(409).JSON(campaignConfirmationRequired(confirmErr))
+		}
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
 	s.invalidateCampaignsCache(accountID)
@@ -11178,6 +12305,100 @@ func (s *Server) handleDuplicateCampaign(c *fiber.Ctx) error {
 	return c.Status(201).JSON(fiber.Map{"success": true, "campaign": newCampaign})
 }
 
+// handleValidateCampaign reports template variables that would render empty
+// for pending recipients, so missing data can be fixed before the send. For a
+// campaign that has not started it also marks unregistered numbers invalid.
+func (s *Server) handleValidateCampaign(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid campaign ID"})
+	}
+	campaign, err := s.services.Campaign.GetByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if campaign == nil || campaign.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Campaign not found"})
+	}
+	sampleSize := c.QueryInt("sample_size", 5)
+	if sampleSize < 0 {
+		sampleSize = 0
+	} else if sampleSize > 50 {
+		sampleSize = 50
+	}
+	validation, err := s.services.Campaign.ValidateTemplateVariables(c.Context(), campaign, sampleSize)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	resp := fiber.Map{"success": true, "validation": validation, "has_warnings": len(validation.Issues) > 0}
+
+	// Before the campaign starts, drop recipients whose number is not on
+	// WhatsApp so they never cost a send attempt.
+	notStarted := campaign.Status == domain.CampaignStatusDraft || campaign.Status == domain.CampaignStatusScheduled
+	if notStarted && c.QueryBool("check_numbers", true) {
+		numbers, numErr := s.services.Campaign.ValidateRecipientNumbers(c.Context(), campaign, s.services.Chat.CheckNumbersRegistered)
+		if numErr != nil {
+			log.Printf("[API] Campaign %s number validation failed: %v", id, numErr)
+			resp["numbers_error"] = "No se pudieron verificar los números: " + numErr.Error()
+		} else {
+			resp["numbers"] = numbers
+			if numbers.Invalid > 0 {
+				resp["has_warnings"] = true
+				s.invalidateCampaignsCache(accountID)
+			}
+		}
+	}
+	return c.JSON(resp)
+}
+
+// handleGetCampaignAnalytics reports how fast a campaign sent: a per-minute
+// timeline, the average wait between sends and failures grouped by reason.
+func (s *Server) handleGetCampaignAnalytics(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid campaign ID"})
+	}
+	campaign, err := s.services.Campaign.GetByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if campaign == nil || campaign.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Campaign not found"})
+	}
+	analytics, err := s.services.Campaign.GetAnalytics(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "analytics": analytics})
+}
+
+// handlePreviewCampaign renders the campaign message for its first recipient so
+// it can be checked before starting.
+func (s *Server) handlePreviewCampaign(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid campaign ID"})
+	}
+	campaign, err := s.services.Campaign.GetByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if campaign == nil || campaign.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Campaign not found"})
+	}
+	preview, err := s.services.Campaign.Preview(c.Context(), campaign)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if preview == nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "La campaña no tiene destinatarios"})
+	}
+	return c.JSON(fiber.Map{"success": true, "preview": preview})
+}
+
 func (s *Server) handleUpdateCampaignAttachments(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
@@ -11275,7 +12496,7 @@ func (s *Server) handleSearchPeople(c *fiber.Ctx) error {
 	if sourceType == "all" || sourceType == "contact" {
 		q := `SELECT id, COALESCE(custom_name, name, push_name, phone, jid) as display_name,
 		             COALESCE(phone, '') as phone, COALESCE(email, '') as email, 'contact'::text as source_type
-		      FROM contacts WHERE account_id = $1 AND is_group = false`
+		      FROM contacts WHERE account_id = $1 AND is_group = false AND ` + repository.NotDeleted("")
 		if searchArgNum > 0 {
 			q += fmt.Sprintf(` AND (name ILIKE $%d OR custom_name ILIKE $%d OR push_name ILIKE $%d OR phone ILIKE $%d OR email ILIKE $%d)`,
 				searchArgNum, searchArgNum, searchArgNum, searchArgNum, searchArgNum)
@@ -11471,6 +12692,9 @@ func (s *Server) handleCreateEvent(c *fiber.Ctx) error {
 		Color       string     `json:"color"`
 		Status      string     `json:"status"`
 		PipelineID  *string    `json:"pipeline_id"`
+		// Recurrence, e.g. "FREQ=WEEKLY;INTERVAL=1" or "monthly"
+		RecurrenceRule             string `json:"recurrence_rule"`
+		RecurrenceCopyParticipants bool   `json:"recurrence_copy_participants"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
@@ -11478,6 +12702,13 @@ func (s *Server) handleCreateEvent(c *fiber.Ctx) error {
 	if req.Name == "" {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Name is required"})
 	}
+	recurrenceRule, err := service.NormalizeRecurrenceRule(req.RecurrenceRule)
+	if err != nil {
+		return c.Status(422).JSON(fiber.Map{"success": false, "code": "EVENT_RECURRENCE_INVALID", "error": err.Error()})
+	}
+	if recurrenceRule != "" && req.EventDate == nil {
+		return c.Status(422).JSON(fiber.Map{"success": false, "code": "EVENT_RECURRENCE_INVALID", "error": "Un evento recurrente necesita fecha"})
+	}
 	status := strings.ToLower(strings.TrimSpace(req.Status))
 	if status == "" {
 		status = domain.EventStatusActive
@@ -11495,6 +12726,9 @@ func (s *Server) handleCreateEvent(c *fiber.Ctx) error {
 		Color:       req.Color,
 		Status:      status,
 		CreatedBy:   &userID,
+
+		RecurrenceRule:             recurrenceRule,
+		RecurrenceCopyParticipants: req.RecurrenceCopyParticipants,
 	}
 	if req.PipelineID != nil {
 		pid, parseErr := uuid.Parse(*req.PipelineID)
@@ -11543,6 +12777,26 @@ func (s *Server) handleGetEvent(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "event": event})
 }
 
+func (s *Server) handleGetEventStats(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid event ID"})
+	}
+	event, err := s.services.Event.GetByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if event == nil || event.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Event not found"})
+	}
+	stats, err := s.services.Event.GetStats(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "stats": stats})
+}
+
 func (s *Server) handleUpdateEvent(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 	userID := c.Locals("user_id").(uuid.UUID)
@@ -11564,10 +12818,24 @@ func (s *Server) handleUpdateEvent(c *fiber.Ctx) error {
 		Color       *string    `json:"color"`
 		Status      *string    `json:"status"`
 		PipelineID  *string    `json:"pipeline_id"`
+
+		RecurrenceRule             *string `json:"recurrence_rule"`
+		RecurrenceCopyParticipants *bool   `json:"recurrence_copy_participants"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
+	recurrenceChanged := req.RecurrenceRule != nil || req.RecurrenceCopyParticipants != nil
+	if req.RecurrenceRule != nil {
+		normalized, ruleErr := service.NormalizeRecurrenceRule(*req.RecurrenceRule)
+		if ruleErr != nil {
+			return c.Status(422).JSON(fiber.Map{"success": false, "code": "EVENT_RECURRENCE_INVALID", "error": ruleErr.Error()})
+		}
+		event.RecurrenceRule = normalized
+	}
+	if req.RecurrenceCopyParticipants != nil {
+		event.RecurrenceCopyParticipants = *req.RecurrenceCopyParticipants
+	}
 	if req.Name != nil {
 		event.Name = *req.Name
 	}
@@ -11607,6 +12875,9 @@ func (s *Server) handleUpdateEvent(c *fiber.Ctx) error {
 		}
 		event.PipelineID = &pid
 	}
+	if recurrenceChanged && event.RecurrenceRule != "" && event.EventDate == nil {
+		return c.Status(422).JSON(fiber.Map{"success": false, "code": "EVENT_RECURRENCE_INVALID", "error": "Un evento recurrente necesita fecha"})
+	}
 	var membershipImpact *repository.EventMembershipImpact
 	if originalStatus == domain.EventStatusDraft && event.Status == domain.EventStatusActive {
 		impact, activateErr := s.services.Event.ActivateAndReconcile(c.Context(), event, &userID)
@@ -11629,6 +12900,11 @@ func (s *Server) handleUpdateEvent(c *fiber.Ctx) error {
 			return writeEventMembershipError(c, repository.ErrEventStatusConflict)
 		}
 	}
+	if recurrenceChanged {
+		if _, err := s.services.Event.SetRecurrence(c.Context(), event.ID, accountID, event.RecurrenceRule, event.RecurrenceCopyParticipants); err != nil {
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+	}
 	s.invalidateEventsCache(event.AccountID)
 	response := fiber.Map{"success": true, "event": event}
 	if membershipImpact != nil {
@@ -11959,7 +13235,7 @@ func (s *Server) handleGetEventParticipants(c *fiber.Ctx) error {
 	// Filters — same params as paginated endpoint
 	search := strings.TrimSpace(c.Query("search"))
 	tagNamesRaw := c.Query("tag_names")
-	tagMode := strings.ToUpper(c.Query("tag_mode", "OR"))
+	tagMode := tagModeFromQuery(c)
 	excludeTagNamesRaw := c.Query("exclude_tag_names")
 	tagFormulaRaw := c.Query("tag_formula")
 	stageIDsRaw := c.Query("stage_ids")
@@ -12176,7 +13452,7 @@ func (s *Server) handleGetEventParticipantsPaginated(c *fiber.Ctx) error {
 	}
 	search := strings.TrimSpace(c.Query("search"))
 	tagNamesRaw := c.Query("tag_names")
-	tagMode := strings.ToUpper(c.Query("tag_mode", "OR"))
+	tagMode := tagModeFromQuery(c)
 	excludeTagNamesRaw := c.Query("exclude_tag_names")
 	tagFormulaRaw := c.Query("tag_formula")
 	stageIDsRaw := c.Query("stage_ids")
@@ -12663,7 +13939,7 @@ func (s *Server) handleGetEventParticipantsByStage(c *fiber.Ctx) error {
 	}
 	search := strings.TrimSpace(c.Query("search"))
 	tagNamesRaw := c.Query("tag_names")
-	tagMode := strings.ToUpper(c.Query("tag_mode", "OR"))
+	tagMode := tagModeFromQuery(c)
 	excludeTagNamesRaw := c.Query("exclude_tag_names")
 	tagFormulaRaw := c.Query("tag_formula")
 	var hasPhone *bool
@@ -13498,14 +14774,76 @@ func (s *Server) handleBulkUpdateEventParticipantStatus(c *fiber.Ctx) error {
 	if validateErr != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": validateErr.Error()})
 	}
-	if !validParticipants {
-		return c.Status(404).JSON(fiber.Map{"success": false, "error": "One or more participants do not belong to this event or are inactive"})
+	if !validParticipants {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "One or more participants do not belong to this event or are inactive"})
+	}
+	updated, err := s.services.Event.BulkUpdateParticipantStatus(c.Context(), accountID, eventID, ids, req.Status)
+	if err != nil {
+		return writeEventParticipantMutationError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "updated": updated})
+}
+
+// maxBulkCheckInPhones caps one sign-in sheet upload.
+const maxBulkCheckInPhones = 2000
+
+func (s *Server) handleCheckInEventParticipant(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	userID := c.Locals("user_id").(uuid.UUID)
+	eventID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid event ID"})
+	}
+	pid, err := uuid.Parse(c.Params("pid"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid participant ID"})
+	}
+	if allowed, err := s.requireWritableEvent(c, accountID, eventID); !allowed {
+		return err
+	}
+	p, _ := s.services.Event.GetParticipantForEvent(c.Context(), accountID, eventID, pid)
+	if p == nil {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Participant not found"})
+	}
+	if p.MembershipState != "active" {
+		return c.Status(409).JSON(fiber.Map{"success": false, "code": "EVENT_PARTICIPANT_INACTIVE", "error": "El participante ya no está activo en el evento"})
+	}
+	checkedIn, err := s.services.Event.CheckInParticipant(c.Context(), accountID, eventID, p, &userID)
+	if err != nil {
+		return writeEventParticipantMutationError(c, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "checked_in": checkedIn, "already_checked_in": !checkedIn})
+}
+
+func (s *Server) handleBulkCheckInEventParticipants(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	userID := c.Locals("user_id").(uuid.UUID)
+	eventID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid event ID"})
+	}
+	if allowed, err := s.requireWritableEvent(c, accountID, eventID); !allowed {
+		return err
+	}
+	var req struct {
+		Phones []string `json:"phones"`
+	}
+	if err := c.BodyParser(&req); err != nil || len(req.Phones) == 0 {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	if len(req.Phones) > maxBulkCheckInPhones {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": fmt.Sprintf("Too many phones (max %d)", maxBulkCheckInPhones)})
 	}
-	updated, err := s.services.Event.BulkUpdateParticipantStatus(c.Context(), accountID, eventID, ids, req.Status)
+	result, err := s.services.Event.CheckInByPhones(c.Context(), accountID, eventID, req.Phones, &userID)
 	if err != nil {
 		return writeEventParticipantMutationError(c, err)
 	}
-	return c.JSON(fiber.Map{"success": true, "updated": updated})
+	return c.JSON(fiber.Map{
+		"success":            true,
+		"checked_in":         result.CheckedIn,
+		"already_checked_in": result.AlreadyCheckedIn,
+		"unknown":            result.Unknown,
+	})
 }
 
 func (s *Server) handleDeleteEventParticipant(c *fiber.Ctx) error {
@@ -13886,17 +15224,19 @@ func (s *Server) handleCreateCampaignFromEvent(c *fiber.Ctx) error {
 		recipients = append(recipients, rec)
 	}
 
+	var added service.CampaignRecipientAddResult
 	if len(recipients) > 0 {
-		if err := s.services.Campaign.AddRecipients(c.Context(), recipients); err != nil {
+		if added, err = s.services.Campaign.AddRecipients(c.Context(), recipients); err != nil {
 			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 		}
 	}
 	s.invalidateCampaignsCache(accountID)
 
 	return c.Status(201).JSON(fiber.Map{
-		"success":          true,
-		"campaign":         campaign,
-		"recipients_count": len(recipients),
+		"success":            true,
+		"campaign":           campaign,
+		"recipients_count":   added.Added,
+		"duplicates_skipped": added.DuplicatesSkipped,
 	})
 }
 
@@ -15175,6 +16515,40 @@ func (s *Server) handleGetLeadInteractions(c *fiber.Ctx) error {
 	return c.JSON(result)
 }
 
+// handleGetLeadTimeline returns interactions, WhatsApp messages and stage
+// changes of a lead as one feed, newest first. Pass the previous page's
+// next_before and next_before_id to load older items.
+// GET /api/leads/:id/timeline?limit=50&before=2026-01-02T15:04:05Z&before_id=<uuid>
+func (s *Server) handleGetLeadTimeline(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	leadID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid lead ID"})
+	}
+	var before time.Time
+	beforeID := uuid.Max
+	if raw := c.Query("before"); raw != "" {
+		before, err = time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "before must be an RFC 3339 timestamp"})
+		}
+		if rawID := c.Query("before_id"); rawID != "" {
+			if beforeID, err = uuid.Parse(rawID); err != nil {
+				return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid before_id"})
+			}
+		}
+	}
+	var valid bool
+	if err := s.repos.DB().QueryRow(c.Context(), `SELECT EXISTS(SELECT 1 FROM leads WHERE id=$1 AND account_id=$2 AND deleted_at IS NULL)`, leadID, accountID).Scan(&valid); err != nil || !valid {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Lead not found"})
+	}
+	page, err := s.services.Lead.Timeline(c.Context(), accountID, leadID, before, beforeID, c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "items": page.Items, "next_before": page.NextBefore, "next_before_id": page.NextBeforeID})
+}
+
 // handleBatchLeadObservations returns observations for multiple leads in a single request
 func (s *Server) handleBatchLeadObservations(c *fiber.Ctx) error {
 	var req struct {
@@ -15200,6 +16574,10 @@ func (s *Server) handleBatchLeadObservations(c *fiber.Ctx) error {
 			leadUUIDs = append(leadUUIDs, uid)
 		}
 	}
+	leadUUIDs, err := s.visibleLeadIDs(c, leadUUIDs)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
 	if len(leadUUIDs) == 0 {
 		return c.JSON(fiber.Map{"success": true, "observations": map[string]interface{}{}})
 	}
@@ -15257,14 +16635,62 @@ func (s *Server) handleGetContactEvents(c *fiber.Ctx) error {
 
 // --- Stats Handler ---
 
+// statsSeriesDays is the length of the daily series in /stats.
+const statsSeriesDays = 7
+
+type statsMessageDay struct {
+	Date     string `json:"date"`
+	Sent     int    `json:"sent"`
+	Received int    `json:"received"`
+}
+
+type statsLeadDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type statsDevice struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Phone     *string   `json:"phone,omitempty"`
+	Status    string    `json:"status"`
+	Connected bool      `json:"connected"`
+}
+
+type statsDetailed struct {
+	Timezone      string            `json:"timezone"`
+	MessagesByDay []statsMessageDay `json:"messages_by_day"`
+	LeadsByDay    []statsLeadDay    `json:"leads_by_day"`
+	Devices       []statsDevice     `json:"devices"`
+}
+
+// statsSeriesDates returns the last days calendar days up to today in the
+// dashboard timezone, oldest first, plus the start of the first one.
+func statsSeriesDates(now time.Time, days int) ([]string, time.Time) {
+	location := dashboardLocation()
+	localNow := now.In(location)
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, location)
+	from := today.AddDate(0, 0, -(days - 1))
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = from.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	return dates, from
+}
+
 func (s *Server) handleGetStats(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
 
 	var leadCount, contactCount int
-	_ = s.repos.DB().QueryRow(c.Context(),
-		`SELECT COUNT(*) FROM leads WHERE account_id = $1`, accountID).Scan(&leadCount)
-	_ = s.repos.DB().QueryRow(c.Context(),
-		`SELECT COUNT(*) FROM contacts WHERE account_id = $1`, accountID).Scan(&contactCount)
+	_ = s.repos.DB().QueryRow(c.Context(), `
+		SELECT (SELECT COUNT(*) FROM leads WHERE account_id = $1),
+			(SELECT COUNT(*) FROM contacts WHERE account_id = $1)
+	`, accountID).Scan(&leadCount, &contactCount)
+
+	detailed, err := s.getDetailedStats(c, accountID)
+	if err != nil {
+		log.Printf("[STATS] detailed stats for account %s: %v", accountID, err)
+	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -15273,10 +16699,87 @@ func (s *Server) handleGetStats(c *fiber.Ctx) error {
 			"ws_clients":        s.hub.GetClientCount(),
 			"leads":             leadCount,
 			"contacts":          contactCount,
+			"detailed":          detailed,
 		},
 	})
 }
 
+// getDetailedStats loads the daily message and lead series and the account's
+// devices. Days without activity are reported as zero.
+func (s *Server) getDetailedStats(c *fiber.Ctx, accountID uuid.UUID) (*statsDetailed, error) {
+	dates, from := statsSeriesDates(time.Now(), statsSeriesDays)
+	detailed := &statsDetailed{
+		Timezone:      dashboardTimezone,
+		MessagesByDay: make([]statsMessageDay, len(dates)),
+		LeadsByDay:    make([]statsLeadDay, len(dates)),
+		Devices:       make([]statsDevice, 0),
+	}
+	index := make(map[string]int, len(dates))
+	for i, date := range dates {
+		index[date] = i
+		detailed.MessagesByDay[i].Date = date
+		detailed.LeadsByDay[i].Date = date
+	}
+
+	rows, err := s.repos.DB().Query(c.Context(), `
+		SELECT 'messages', to_char(timestamp AT TIME ZONE $3, 'YYYY-MM-DD'),
+			COUNT(*) FILTER (WHERE is_from_me), COUNT(*) FILTER (WHERE NOT COALESCE(is_from_me, FALSE))
+		FROM messages
+		WHERE account_id = $1 AND timestamp >= $2
+		GROUP BY 2
+		UNION ALL
+		SELECT 'leads', to_char(created_at AT TIME ZONE $3, 'YYYY-MM-DD'), COUNT(*), 0
+		FROM leads
+		WHERE account_id = $1 AND created_at >= $2 AND `+repository.NotDeleted("")+`
+		GROUP BY 2
+	`, accountID, from, dashboardTimezone)
+	if err != nil {
+		return detailed, err
+	}
+	for rows.Next() {
+		var kind, date string
+		var first, second int
+		if err := rows.Scan(&kind, &date, &first, &second); err != nil {
+			rows.Close()
+			return detailed, err
+		}
+		i, ok := index[date]
+		if !ok {
+			continue
+		}
+		if kind == "messages" {
+			detailed.MessagesByDay[i].Sent = first
+			detailed.MessagesByDay[i].Received = second
+		} else {
+			detailed.LeadsByDay[i].Count = first
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return detailed, err
+	}
+
+	rows, err = s.repos.DB().Query(c.Context(), `
+		SELECT id, COALESCE(NULLIF(BTRIM(name),''), 'Dispositivo'), phone, COALESCE(status, 'disconnected')
+		FROM devices
+		WHERE account_id = $1
+		ORDER BY name ASC, id ASC
+	`, accountID)
+	if err != nil {
+		return detailed, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var device statsDevice
+		if err := rows.Scan(&device.ID, &device.Name, &device.Phone, &device.Status); err != nil {
+			return detailed, err
+		}
+		device.Connected = s.pool.IsDeviceConnected(device.ID)
+		detailed.Devices = append(detailed.Devices, device)
+	}
+	return detailed, rows.Err()
+}
+
 // --- WebSocket Handler ---
 
 func (s *Server) handleWebSocket(c *websocket.Conn) {
@@ -15297,6 +16800,12 @@ func (s *Server) handleWebSocket(c *websocket.Conn) {
 		Hub:         s.hub,
 		Permissions: permissions,
 	}
+	if accountPermissions, ok := c.Locals("ws_account_permissions").(map[uuid.UUID]map[string]bool); ok {
+		client.AccountPermissions = accountPermissions
+	}
+	if visibility, ok := c.Locals("ws_visibility").(map[uuid.UUID]*domain.AssignmentScope); ok {
+		client.Visibility = visibility
+	}
 
 	s.hub.Register(client)
 
@@ -15340,6 +16849,44 @@ func (s *Server) StartEventTagSyncWorker(ctx context.Context) {
 	}()
 }
 
+// StartEventRecurrenceWorker starts the background worker that creates the
+// next occurrence of recurring events once their date passes.
+func (s *Server) StartEventRecurrenceWorker(ctx context.Context) {
+	go func() {
+		log.Println("[EVENT-RECURRENCE] 🔁 Recurring event worker started (interval: 5m)")
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+			s.runEventRecurrence(ctx)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("[EVENT-RECURRENCE] Worker stopped")
+				return
+			case <-ticker.C:
+				s.runEventRecurrence(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Server) runEventRecurrence(ctx context.Context) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[EVENT-RECURRENCE] ⚠️ PANIC recovered: %v", rec)
+		}
+	}()
+	for _, accountID := range s.services.Event.MaterializeRecurrences(ctx) {
+		s.invalidateEventsCache(accountID)
+	}
+}
+
 func (s *Server) runEventTagSync(ctx context.Context) {
 	defer func() {
 		if rec := recover(); rec != nil {
@@ -15578,10 +17125,19 @@ func (s *Server) handleAdminUpdateAccount(c *fiber.Ctx) error {
 		MaxUsersOverride  *int   `json:"max_users_override"`
 		StorageLimitBytes int64  `json:"storage_limit_bytes"`
 		KommoEnabled      bool   `json:"kommo_enabled"`
+		// Omitted leaves the current limits untouched.
+		CampaignRateLimits *domain.CampaignRateLimits `json:"campaign_rate_limits"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
 	}
+	var rateLimits domain.CampaignRateLimits
+	if req.CampaignRateLimits != nil {
+		rateLimits, err = service.NormalizeCampaignRateLimits(*req.CampaignRateLimits)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+	}
 	if req.Plan == "" {
 		existing, err := s.services.Account.GetByID(c.Context(), id)
 		if err != nil {
@@ -15610,6 +17166,12 @@ func (s *Server) handleAdminUpdateAccount(c *fiber.Ctx) error {
 	if err := s.services.Account.Update(c.Context(), account); err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
+	if req.CampaignRateLimits != nil {
+		if err := s.repos.Account.SetCampaignRateLimits(c.Context(), id, rateLimits); err != nil {
+			return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+		account.CampaignRateLimits = rateLimits
+	}
 	subOverview, err := s.services.Subscription.GetOverview(c.Context(), id)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
@@ -15903,7 +17465,12 @@ func (s *Server) scanStorageOrphans(ctx context.Context, minAgeDays int) (*admin
 			scan.DeletedAccountOrphans = append(scan.DeletedAccountOrphans, item)
 			continue
 		}
-		if _, ok := refs[object.Key]; !ok {
+		key := object.Key
+		if source := thumbnailSourceKey(key); source != "" {
+			// Thumbnails live and die with their original.
+			key = source
+		}
+		if _, ok := refs[key]; !ok {
 			scan.ActiveAccountOrphans = append(scan.ActiveAccountOrphans, item)
 			if minAgeDays == 0 || object.LastModified.Before(cutoff) {
 				scan.ActiveEligibleOrphans = append(scan.ActiveEligibleOrphans, item)
@@ -15957,6 +17524,17 @@ func (s *Server) handleAdminStorageOrphans(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "summary": storageOrphanScanSummary(scan)})
 }
 
+// handleAdminWebSocketClients lists connected sockets with their queue depth
+// and dropped frame counts, to spot tabs that cannot keep up.
+func (s *Server) handleAdminWebSocketClients(c *fiber.Ctx) error {
+	clients := s.hub.ClientStats()
+	var dropped int64
+	for _, client := range clients {
+		dropped += client.DroppedFrames
+	}
+	return c.JSON(fiber.Map{"success": true, "clients": clients, "dropped_frames": dropped})
+}
+
 func (s *Server) handleAdminCleanupStorageOrphans(c *fiber.Ctx) error {
 	var req struct {
 		Scope        string `json:"scope"`
@@ -16112,6 +17690,65 @@ func (s *Server) handleAdminPurgeAccount(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true, "purged": true, "deleted_files": deletedFiles, "summary": summary})
 }
 
+// handleAdminAnonymizeAccount replaces contact/lead personal data in an
+// account with generated fakes so it can serve as a demo. IRREVERSIBLE: the
+// caller must repeat the account name and acknowledge the data loss.
+func (s *Server) handleAdminAnonymizeAccount(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid ID"})
+	}
+	account, err := s.services.Account.GetByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if account == nil {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Account not found"})
+	}
+	var req struct {
+		Confirmation            string `json:"confirmation"`
+		AcknowledgeIrreversible bool   `json:"acknowledge_irreversible"`
+		ScrubMessages           bool   `json:"scrub_messages"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
+	}
+	if req.Confirmation != account.Name || !req.AcknowledgeIrreversible {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"error":   "Operación IRREVERSIBLE: los nombres, teléfonos y correos reales se perderán para siempre. Envía confirmation con el nombre exacto de la cuenta y acknowledge_irreversible=true.",
+		})
+	}
+	// A connected device would keep writing real conversations into the
+	// account while (and after) it is anonymized.
+	var connected int
+	if err := s.repos.DB().QueryRow(c.Context(), `SELECT COUNT(*) FROM devices WHERE account_id = $1 AND status = $2`, id, domain.DeviceStatusConnected).Scan(&connected); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if connected > 0 {
+		return c.Status(409).JSON(fiber.Map{"success": false, "error": "Desconecta los dispositivos de la cuenta antes de anonimizarla"})
+	}
+
+	result, err := s.repos.Account.Anonymize(c.Context(), id, repository.AccountAnonymizeOptions{ScrubMessages: req.ScrubMessages})
+	if err != nil {
+		log.Printf("[Admin] anonymize account %s failed: %v", id, err)
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": "No se pudo anonimizar la cuenta"})
+	}
+
+	var actorID *uuid.UUID
+	if uid, ok := c.Locals("user_id").(uuid.UUID); ok {
+		actorID = &uid
+	}
+	s.recordSecurityEventWithRefs(c.Context(), "account_anonymized", id.String(), c, &id, actorID, map[string]interface{}{
+		"scrub_messages": req.ScrubMessages,
+		"result":         result,
+	})
+	log.Printf("[Admin] account %s anonymized by %v (scrub_messages=%t)", id, actorID, req.ScrubMessages)
+	s.invalidateContactTreeCaches(id)
+	s.invalidateCampaignsCache(id)
+	return c.JSON(fiber.Map{"success": true, "result": result})
+}
+
 func (s *Server) handleAdminGetUsers(c *fiber.Ctx) error {
 	var accountID *uuid.UUID
 	if aid := c.Query("account_id"); aid != "" {
@@ -16585,9 +18222,28 @@ func (s *Server) handleAdminRemoveUserAccount(c *fiber.Ctx) error {
 
 // --- Quick Reply Handlers ---
 
+// quickReplyActor returns the requesting user and whether they administer the
+// account, which lets them manage shared quick replies.
+func (s *Server) quickReplyActor(c *fiber.Ctx) (uuid.UUID, bool) {
+	userID, _ := c.Locals("user_id").(uuid.UUID)
+	return userID, s.callerHasRole(c, domain.RoleAdmin)
+}
+
+// quickReplyError maps QuickReplyService errors to responses.
+func quickReplyError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrQuickReplyNotFound):
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Quick reply not found"})
+	case errors.Is(err, service.ErrQuickReplyForbidden):
+		return c.Status(403).JSON(fiber.Map{"success": false, "error": "Only the owner can change a personal quick reply, and only admins can change shared ones"})
+	}
+	return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+}
+
 func (s *Server) handleGetQuickReplies(c *fiber.Ctx) error {
 	accountID := c.Locals("account_id").(uuid.UUID)
-	replies, err := s.services.QuickReply.GetByAccountID(c.Context(), accountID)
+	userID, _ := c.Locals("user_id").(uuid.UUID)
+	replies, err := s.services.QuickReply.GetByAccountID(c.Context(), accountID, userID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
@@ -16606,6 +18262,7 @@ func (s *Server) handleCreateQuickReply(c *fiber.Ctx) error {
 		MediaURL      string `json:"media_url"`
 		MediaType     string `json:"media_type"`
 		MediaFilename string `json:"media_filename"`
+		Personal      bool   `json:"personal"`
 		Attachments   []struct {
 			MediaURL      string `json:"media_url"`
 			MediaType     string `json:"media_type"`
@@ -16620,6 +18277,13 @@ func (s *Server) handleCreateQuickReply(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Shortcut and body or media are required"})
 	}
 	qr := &domain.QuickReply{AccountID: accountID, Shortcut: req.Shortcut, Title: req.Title, Body: req.Body, MediaURL: req.MediaURL, MediaType: req.MediaType, MediaFilename: req.MediaFilename}
+	if req.Personal {
+		userID, _ := c.Locals("user_id").(uuid.UUID)
+		if userID == uuid.Nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Personal quick replies need a user session"})
+		}
+		qr.OwnerID = &userID
+	}
 	for i, a := range req.Attachments {
 		if i >= 5 {
 			break
@@ -16635,6 +18299,8 @@ func (s *Server) handleCreateQuickReply(c *fiber.Ctx) error {
 }
 
 func (s *Server) handleUpdateQuickReply(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	userID, isAdmin := s.quickReplyActor(c)
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid quick reply ID"})
@@ -16665,23 +18331,80 @@ func (s *Server) handleUpdateQuickReply(c *fiber.Ctx) error {
 			MediaURL: a.MediaURL, MediaType: a.MediaType, MediaFilename: a.MediaFilename, Caption: a.Caption, Position: i,
 		})
 	}
-	if err := s.services.QuickReply.Update(c.Context(), qr); err != nil {
-		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	if err := s.services.QuickReply.Update(c.Context(), accountID, userID, isAdmin, qr); err != nil {
+		return quickReplyError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true, "quick_reply": qr})
 }
 
 func (s *Server) handleDeleteQuickReply(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	userID, isAdmin := s.quickReplyActor(c)
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid quick reply ID"})
 	}
-	if err := s.services.QuickReply.Delete(c.Context(), id); err != nil {
-		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	if err := s.services.QuickReply.Delete(c.Context(), accountID, userID, isAdmin, id); err != nil {
+		return quickReplyError(c, err)
 	}
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// handleRenderQuickReply returns the quick reply with its {{variables}}
+// resolved against the contact of ?chat_id, ready to insert in the composer.
+func (s *Server) handleRenderQuickReply(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid quick reply ID"})
+	}
+	reply, err := s.services.QuickReply.GetByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	userID, _ := c.Locals("user_id").(uuid.UUID)
+	if reply == nil || reply.AccountID != accountID || !service.QuickReplyVisible(reply, userID) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Quick reply not found"})
+	}
+
+	var contact *domain.Contact
+	if rawChatID := c.Query("chat_id"); rawChatID != "" {
+		chatID, err := uuid.Parse(rawChatID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid chat ID"})
+		}
+		chat, err := s.services.Chat.GetByID(c.Context(), chatID)
+		if err != nil || chat == nil || !chatBelongsToAccount(chat, accountID) {
+			return c.Status(404).JSON(fiber.Map{"success": false, "error": "Chat not found"})
+		}
+		if chat.ContactID != nil {
+			if ct, err := s.services.Contact.GetByID(c.Context(), *chat.ContactID); err == nil && ct != nil && ct.AccountID == accountID {
+				contact = ct
+			}
+		}
+	}
+	return c.JSON(fiber.Map{"success": true, "quick_reply": s.services.QuickReply.Render(reply, contact)})
+}
+
+// handleMarkQuickReplyUsed is called by the composer after a quick reply is
+// sent, so the list can put each account's most used replies first.
+func (s *Server) handleMarkQuickReplyUsed(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid quick reply ID"})
+	}
+	userID, _ := c.Locals("user_id").(uuid.UUID)
+	useCount, lastUsedAt, err := s.services.QuickReply.MarkUsed(c.Context(), accountID, userID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Quick reply not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "use_count": useCount, "last_used_at": lastUsedAt})
+}
+
 // --- Kommo Webhook Handler (public, no auth — secret in URL) ---
 
 // handleKommoWebhook processes incoming webhooks from Kommo.
@@ -16693,6 +18416,20 @@ func (s *Server) handleKommoWebhook(c *fiber.Ctx) error {
 	if kommoSync == nil {
 		return c.SendStatus(fiber.StatusNotFound) // Return 404 to not reveal webhook exists
 	}
+	// The secret in the URL authenticates Kommo itself, which cannot sign.
+	// Relayed deliveries that carry the Clarin signature headers must also
+	// pass verification: a stale timestamp or reused nonce is rejected.
+	headers := webhook.Headers{
+		Timestamp: c.Get(webhook.HeaderTimestamp),
+		Nonce:     c.Get(webhook.HeaderNonce),
+		Signature: c.Get(webhook.HeaderSignature),
+	}
+	if headers.Timestamp != "" || headers.Nonce != "" || headers.Signature != "" {
+		if err := s.webhooks.Verify(kommoSync.WebhookSecret, headers, c.Body()); err != nil {
+			log.Printf("[Kommo Webhook] rejected delivery: %v", err)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+	}
 
 	// Kommo sends form-encoded data with bracket notation:
 	// leads[update][0][id]=12345, leads[add][0][id]=12345, etc.
@@ -17431,6 +19168,44 @@ func (s *Server) handleAdminRemoveIntegrationAccount(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"success": true})
 }
 
+// handleAdminSetKommoConflictPolicy sets which side wins when a lead was edited
+// both in Clarin and in Kommo (kommo_wins, clarin_wins or newest_wins).
+func (s *Server) handleAdminSetKommoConflictPolicy(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid integration ID"})
+	}
+	accountID, err := uuid.Parse(c.Params("account_id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid account ID"})
+	}
+	var req struct {
+		Policy kommo.ConflictPolicy `json:"policy"`
+	}
+	if err := c.BodyParser(&req); err != nil || !req.Policy.Valid() {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "policy debe ser kommo_wins, clarin_wins o newest_wins"})
+	}
+	instance, err := s.repos.Integration.GetByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if instance == nil || instance.Provider != domain.IntegrationProviderKommo {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Integration not found"})
+	}
+	tag, err := s.repos.DB().Exec(c.Context(), `
+		UPDATE accounts SET kommo_conflict_policy = $1
+		WHERE id = $2 AND EXISTS (SELECT 1 FROM integration_instance_accounts WHERE integration_instance_id = $3 AND account_id = $2)
+	`, string(req.Policy), accountID, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "La cuenta no está asignada a esta integración"})
+	}
+	log.Printf("[API] Account %s kommo_conflict_policy set to %s", accountID, req.Policy)
+	return c.JSON(fiber.Map{"success": true, "policy": req.Policy})
+}
+
 func (s *Server) handleAdminReloadIntegrations(c *fiber.Ctx) error {
 	if !kommo.APICommunicationEnabled {
 		s.kommoSync = nil
@@ -17714,3 +19489,59 @@ func (s *Server) handleDeviceHealth(c *fiber.Ctx) error {
 	summaries := s.pool.GetHealthSummary()
 	return c.JSON(fiber.Map{"success": true, "devices": summaries})
 }
+
+// handleGetDeviceHealth returns last-seen, reconnect attempts and the current
+// socket state of one device.
+func (s *Server) handleGetDeviceHealth(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	deviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
+	}
+	device, err := s.services.Device.GetByID(c.Context(), deviceID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if device == nil || device.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Device not found"})
+	}
+	health := whatsapp.DeviceHealthSummary{ID: device.ID, Socket: whatsapp.DeviceSocketClosed}
+	if device.JID != nil {
+		health.JID = *device.JID
+	}
+	if device.Status != nil {
+		health.Status = *device.Status
+	}
+	if s.pool != nil {
+		if summary, ok := s.pool.GetDeviceHealth(deviceID); ok {
+			health = summary
+		}
+	}
+	return c.JSON(fiber.Map{"success": true, "health": health})
+}
+
+// handleGetDeviceEvents lists the device's connect/disconnect/qr/logout
+// history, newest first. Entries older than 30 days are pruned.
+func (s *Server) handleGetDeviceEvents(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	deviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid device ID"})
+	}
+	device, err := s.services.Device.GetByID(c.Context(), deviceID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if device == nil || device.AccountID != accountID {
+		return c.Status(404).JSON(fiber.Map{"success": false, "error": "Device not found"})
+	}
+	limit := c.QueryInt("limit", 100)
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	events, err := s.repos.Device.ListEvents(c.Context(), accountID, deviceID, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "events": events})
+}
@@ -596,6 +596,9 @@ func (s *Server) isAccountAdmin(c *fiber.Ctx, accountID, userID uuid.UUID) bool
 		if claims.IsAdmin || claims.IsSuperAdmin || claims.Role == domain.RoleAdmin || claims.Role == domain.RoleSuperAdmin {
 			return true
 		}
+		if claims.APIKeyID != "" {
+			return false
+		}
 	}
 	var role string
 	err := s.repos.DB().QueryRow(c.Context(), `SELECT role FROM user_accounts WHERE user_id = $1 AND account_id = $2`, userID, accountID).Scan(&role)
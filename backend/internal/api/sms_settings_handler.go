@@ -0,0 +1,91 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+var smsFromNumberPattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+func smsSettingsResponse(settings *domain.AccountSMSSettings) fiber.Map {
+	if settings == nil {
+		return fiber.Map{"configured": false, "enabled": false, "provider": domain.SMSProviderTwilio}
+	}
+	return fiber.Map{
+		"configured":         settings.Ready(),
+		"enabled":            settings.Enabled,
+		"provider":           settings.Provider,
+		"twilio_account_sid": settings.TwilioAccountSID,
+		"auth_token_set":     settings.TwilioAuthToken != "",
+		"from_number":        settings.FromNumber,
+		"updated_at":         settings.UpdatedAt,
+	}
+}
+
+func (s *Server) handleGetSMSSettings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	settings, err := s.repos.SMSSettings.Get(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "sms": smsSettingsResponse(settings)})
+}
+
+// handleUpdateSMSSettings saves the account's SMS fallback. A blank
+// twilio_auth_token keeps the stored token so clients never need to echo it.
+func (s *Server) handleUpdateSMSSettings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		Enabled          *bool   `json:"enabled"`
+		TwilioAccountSID *string `json:"twilio_account_sid"`
+		TwilioAuthToken  *string `json:"twilio_auth_token"`
+		FromNumber       *string `json:"from_number"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request body"})
+	}
+
+	settings, err := s.repos.SMSSettings.Get(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if settings == nil {
+		settings = &domain.AccountSMSSettings{AccountID: accountID, Provider: domain.SMSProviderTwilio}
+	}
+	if req.TwilioAccountSID != nil {
+		settings.TwilioAccountSID = strings.TrimSpace(*req.TwilioAccountSID)
+	}
+	if req.TwilioAuthToken != nil && strings.TrimSpace(*req.TwilioAuthToken) != "" {
+		settings.TwilioAuthToken = strings.TrimSpace(*req.TwilioAuthToken)
+	}
+	if req.FromNumber != nil {
+		from := strings.TrimSpace(*req.FromNumber)
+		if from != "" && !smsFromNumberPattern.MatchString(from) {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": "from_number debe estar en formato E.164, por ejemplo +15551234567"})
+		}
+		settings.FromNumber = from
+	}
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if settings.Enabled && (settings.TwilioAccountSID == "" || settings.TwilioAuthToken == "" || settings.FromNumber == "") {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Para activar SMS se requieren twilio_account_sid, twilio_auth_token y from_number"})
+	}
+
+	if err := s.repos.SMSSettings.Upsert(c.Context(), settings); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "sms": smsSettingsResponse(settings)})
+}
+
+func (s *Server) handleDeleteSMSSettings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	if err := s.repos.SMSSettings.Delete(c.Context(), accountID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
@@ -336,11 +336,30 @@ func neutralizeSpreadsheetFormula(value string) string {
 	switch candidate[0] {
 	case '=', '+', '-', '@', '\t', '\r', '\n':
 		return "'" + value
+	case '\'':
+		// Quote the quote too, or the importer would strip it as our guard.
+		if strings.HasPrefix(value, "'") && neutralizeSpreadsheetFormula(value[1:]) != value[1:] {
+			return "'" + value
+		}
+		return value
 	default:
 		return value
 	}
 }
 
+// restoreSpreadsheetFormula undoes neutralizeSpreadsheetFormula, so files
+// exported by Clarin import back with their original cell values. A leading
+// quote that does not guard a formula character is kept.
+func restoreSpreadsheetFormula(value string) string {
+	if !strings.HasPrefix(value, "'") {
+		return value
+	}
+	if rest := value[1:]; neutralizeSpreadsheetFormula(rest) != rest {
+		return rest
+	}
+	return value
+}
+
 func writeSurveyCSV(writer io.Writer, data *domain.SurveyExportData) error {
 	csvWriter := csv.NewWriter(writer)
 	if data == nil {
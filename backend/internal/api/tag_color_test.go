@@ -0,0 +1,22 @@
+package api
+
+import "testing"
+
+func TestValidTagColor(t *testing.T) {
+	cases := map[string]bool{
+		"#6366f1":  true,
+		"#ABCDEF":  true,
+		"#000000":  true,
+		"6366f1":   false,
+		"#6366f":   false,
+		"#6366f1a": false,
+		"#ggg000":  false,
+		"red":      false,
+		"":         false,
+	}
+	for color, want := range cases {
+		if got := validTagColor(color); got != want {
+			t.Errorf("validTagColor(%q) = %v, want %v", color, got, want)
+		}
+	}
+}
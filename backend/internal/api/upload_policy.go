@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// defaultUploadAllowedTypes is the per-folder allowlist used when
+// UPLOAD_ALLOWED_TYPES does not override a folder. Entries are exact mime
+// types, "type/*" wildcards, or ".ext" extensions; extensions only vouch for
+// files whose bytes sniff as a generic container (zip, OLE, plain text), as
+// office documents do. "*" applies to folders without their own entry.
+var defaultUploadAllowedTypes = map[string][]string{
+	"*": {
+		"image/jpeg", "image/png", "image/gif", "image/webp", "image/heic", "image/heif",
+		"video/*", "audio/*", "application/ogg", "application/pdf",
+		".txt", ".csv", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".zip", ".rar",
+	},
+	"document-images":      {"image/jpeg", "image/png", "image/gif", "image/webp"},
+	"document-backgrounds": {"image/jpeg", "image/png", "image/gif", "image/webp"},
+	"document-thumbnails":  {"image/jpeg", "image/png", "image/webp"},
+}
+
+// blockedUploadExtensions are refused whatever their bytes look like.
+var blockedUploadExtensions = map[string]bool{
+	".exe": true, ".dll": true, ".msi": true, ".com": true, ".scr": true, ".bat": true,
+	".cmd": true, ".ps1": true, ".vbs": true, ".js": true, ".jar": true, ".apk": true,
+	".sh": true, ".app": true, ".svg": true, ".html": true, ".htm": true,
+}
+
+// executableSignatures are magic numbers of native executables and scripts.
+var executableSignatures = [][]byte{
+	[]byte("MZ"),             // Windows PE
+	[]byte("\x7fELF"),        // Linux ELF
+	{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit
+	{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit
+	{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal / Java class
+	[]byte("#!"),             // shell scripts
+}
+
+// executableSignatureExceptions share a prefix with executableSignatures but
+// are media files.
+var executableSignatureExceptions = [][]byte{
+	[]byte("#!AMR"), // AMR voice notes
+}
+
+// isoMediaBrands maps ISO base media (ftyp) major brands that
+// http.DetectContentType does not recognize to their content type.
+var isoMediaBrands = map[string]string{
+	"qt  ": "video/quicktime",
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"hevc": "image/heic",
+	"mif1": "image/heif",
+	"msf1": "image/heif",
+	"M4A ": "audio/mp4",
+	"M4V ": "video/mp4",
+	"3gp4": "video/3gpp",
+	"3gp5": "video/3gpp",
+	"3gp6": "video/3gpp",
+	"3g2a": "video/3gpp2",
+}
+
+// uploadMediaExtensionTypes covers media formats without a reliable magic
+// number (raw AAC, AMR, ...): when the bytes sniff as a generic type, the
+// extension names the type that is then checked against the allowlist.
+var uploadMediaExtensionTypes = map[string]string{
+	".aac":  "audio/aac",
+	".amr":  "audio/amr",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".opus": "audio/ogg",
+	".mov":  "video/quicktime",
+	".3gp":  "video/3gpp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+}
+
+// genericSniffedTypes cannot be told apart from their bytes alone; an allowed
+// extension decides for them.
+var genericSniffedTypes = map[string]bool{
+	"application/octet-stream":     true,
+	"application/zip":              true,
+	"application/x-rar-compressed": true,
+	"text/plain":                   true,
+}
+
+// uploadExtensionTypes names the stored content type of extension-vouched
+// uploads, independent of the host's mime tables.
+var uploadExtensionTypes = map[string]string{
+	".txt":  "text/plain; charset=utf-8",
+	".csv":  "text/csv",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".zip":  "application/zip",
+	".rar":  "application/vnd.rar",
+}
+
+// uploadPolicies maps folders to their allowed type entries.
+type uploadPolicies map[string][]string
+
+// parseUploadPolicies overlays a "folder=type,type;folder=type" spec on the
+// defaults.
+func parseUploadPolicies(spec string) uploadPolicies {
+	policies := uploadPolicies{}
+	for folder, types := range defaultUploadAllowedTypes {
+		policies[folder] = types
+	}
+	for _, entry := range strings.Split(spec, ";") {
+		folder, list, ok := strings.Cut(entry, "=")
+		folder = strings.TrimSpace(folder)
+		if !ok || folder == "" {
+			continue
+		}
+		var types []string
+		for _, t := range strings.Split(list, ",") {
+			if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+				types = append(types, t)
+			}
+		}
+		if len(types) > 0 {
+			policies[folder] = types
+		}
+	}
+	return policies
+}
+
+func (p uploadPolicies) allowedFor(folder string) []string {
+	// Nested folders use the policy of their top-level folder.
+	top, _, _ := strings.Cut(folder, "/")
+	if types, ok := p[top]; ok {
+		return types
+	}
+	return p["*"]
+}
+
+// uploadTypeError is returned for files outside a folder's allowlist.
+type uploadTypeError struct {
+	Detected string
+	Allowed  []string
+}
+
+func (e *uploadTypeError) Error() string {
+	return fmt.Sprintf("File type %s is not allowed. Allowed types: %s", e.Detected, strings.Join(e.Allowed, ", "))
+}
+
+// checkUpload validates an upload's bytes against the folder allowlist and
+// returns the content type to store, derived from the bytes rather than the
+// client's header.
+func (p uploadPolicies) checkUpload(folder, filename string, data []byte) (string, error) {
+	allowed := p.allowedFor(folder)
+	ext := strings.ToLower(filepath.Ext(filename))
+	if blockedUploadExtensions[ext] || looksExecutable(data) {
+		return "", &uploadTypeError{Detected: "executable", Allowed: allowed}
+	}
+	sniffed := sniffUploadType(data, ext)
+	for _, entry := range allowed {
+		switch {
+		case strings.HasPrefix(entry, "."):
+			if entry == ext && genericSniffedTypes[sniffed] {
+				if byExt := uploadExtensionTypes[ext]; byExt != "" {
+					return byExt, nil
+				}
+				return sniffed, nil
+			}
+		case strings.HasSuffix(entry, "/*"):
+			if strings.HasPrefix(sniffed, strings.TrimSuffix(entry, "*")) {
+				return sniffed, nil
+			}
+		case entry == sniffed:
+			return sniffed, nil
+		}
+	}
+	return "", &uploadTypeError{Detected: sniffed, Allowed: allowed}
+}
+
+// sniffUploadType detects the content type from the bytes, filling the gaps
+// of http.DetectContentType for ISO media brands and formats identified only
+// by their extension.
+func sniffUploadType(data []byte, ext string) string {
+	sniffed := http.DetectContentType(data)
+	if i := strings.IndexByte(sniffed, ';'); i >= 0 {
+		sniffed = strings.TrimSpace(sniffed[:i])
+	}
+	if !genericSniffedTypes[sniffed] {
+		return sniffed
+	}
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		if byBrand := isoMediaBrands[string(data[8:12])]; byBrand != "" {
+			return byBrand
+		}
+	}
+	if byExt := uploadMediaExtensionTypes[ext]; byExt != "" {
+		return byExt
+	}
+	return sniffed
+}
+
+func looksExecutable(data []byte) bool {
+	for _, exception := range executableSignatureExceptions {
+		if bytes.HasPrefix(data, exception) {
+			return false
+		}
+	}
+	for _, signature := range executableSignatures {
+		if bytes.HasPrefix(data, signature) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestUploadPolicyAcceptsSniffedTypes(t *testing.T) {
+	policies := parseUploadPolicies("")
+	var docx bytes.Buffer
+	zw := zip.NewWriter(&docx)
+	if _, err := zw.Create("word/document.xml"); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+
+	cases := []struct {
+		folder, filename string
+		data             []byte
+		want             string
+	}{
+		{"uploads", "photo.bin", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"uploads", "report.pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"uploads", "contract.docx", docx.Bytes(), "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"uploads", "leads.csv", []byte("name,phone\nAna,51999\n"), "text/csv"},
+		{"campaigns/2026", "clip.mp4", []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), "video/mp4"},
+		{"uploads", "IMG_0001.MOV", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00qt  "), "video/quicktime"},
+		{"uploads", "IMG_0002.HEIC", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), "image/heic"},
+		{"uploads", "voice.aac", []byte("\xff\xf1\x50\x80\x00\x1f\xfc\x21"), "audio/aac"},
+		{"uploads", "voice.amr", []byte("#!AMR\n\x3c\x48"), "audio/amr"},
+	}
+	for _, tc := range cases {
+		got, err := policies.checkUpload(tc.folder, tc.filename, tc.data)
+		if err != nil {
+			t.Fatalf("checkUpload(%s/%s) error = %v", tc.folder, tc.filename, err)
+		}
+		if got != tc.want {
+			t.Fatalf("checkUpload(%s/%s) = %q, want %q", tc.folder, tc.filename, got, tc.want)
+		}
+	}
+}
+
+func TestUploadPolicyRejectsExecutablesAndOffListTypes(t *testing.T) {
+	policies := parseUploadPolicies("")
+	cases := []struct {
+		folder, filename string
+		data             []byte
+	}{
+		{"uploads", "setup.pdf", []byte("MZ\x90\x00\x03\x00\x00\x00")},
+		{"uploads", "run.txt", []byte("#!/bin/sh\nrm -rf /\n")},
+		{"uploads", "tool.exe", []byte("harmless")},
+		{"uploads", "page.html", []byte("<html><script>alert(1)</script></html>")},
+		{"uploads", "logo.png", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")},
+		{"document-images", "scan.pdf", []byte("%PDF-1.7\n")},
+		{"uploads", "archive.docx", []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00")},
+	}
+	for _, tc := range cases {
+		_, err := policies.checkUpload(tc.folder, tc.filename, tc.data)
+		var typeErr *uploadTypeError
+		if !errors.As(err, &typeErr) || len(typeErr.Allowed) == 0 {
+			t.Fatalf("checkUpload(%s/%s) error = %v, want uploadTypeError listing allowed types", tc.folder, tc.filename, err)
+		}
+	}
+}
+
+func TestParseUploadPoliciesOverridesFolders(t *testing.T) {
+	policies := parseUploadPolicies(" avatars = image/* ; broken ; uploads=application/pdf,.DOCX")
+	if got := policies.allowedFor("avatars/team"); len(got) != 1 || got[0] != "image/*" {
+		t.Fatalf("avatars policy = %v", got)
+	}
+	if got := policies.allowedFor("uploads"); len(got) != 2 || got[1] != ".docx" {
+		t.Fatalf("uploads policy = %v", got)
+	}
+	if got := policies.allowedFor("document-images"); len(got) == 0 {
+		t.Fatal("default folder policy was dropped")
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/service"
+)
+
+// onInboundMessage runs for every stored incoming WhatsApp message.
+func (s *Server) onInboundMessage(chat *domain.Chat, msg *domain.Message) {
+	body := ""
+	if msg.Body != nil {
+		body = *msg.Body
+	}
+	isGroup := strings.HasSuffix(chat.JID, "@g.us")
+	// Auto-tag rules tag the chat's contact, and a group contact is not a person.
+	if !isGroup {
+		s.applyAutoTagRules(msg.AccountID, chat.ID, body)
+	}
+	if s.services != nil {
+		s.services.Webhook.Dispatch(msg.AccountID, domain.WebhookEventMessageReceived, fiber.Map{
+			"chat_id":  chat.ID,
+			"chat_jid": chat.JID,
+			"is_group": isGroup,
+			"message":  msg,
+		})
+	}
+}
+
+// webhookRetryInterval is how often pending webhook deliveries are retried.
+const webhookRetryInterval = 10 * time.Second
+
+// StartWebhookRetryWorker retries failed webhook deliveries from the
+// delivery log until ctx is cancelled.
+func (s *Server) StartWebhookRetryWorker(ctx context.Context) {
+	if s.services == nil || s.services.Webhook == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(webhookRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.services.Webhook.RetryDue(ctx)
+			}
+		}
+	}()
+}
+
+func webhookResponse(hook *domain.AccountWebhook) fiber.Map {
+	if hook == nil {
+		return fiber.Map{"configured": false, "enabled": false, "events": []string{}, "available_events": domain.WebhookEvents}
+	}
+	return fiber.Map{
+		"configured":       hook.URL != "",
+		"enabled":          hook.Enabled,
+		"url":              hook.URL,
+		"secret":           hook.Secret,
+		"events":           hook.Events,
+		"available_events": domain.WebhookEvents,
+		"updated_at":       hook.UpdatedAt,
+	}
+}
+
+func (s *Server) handleGetWebhookSettings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	hook, err := s.repos.Webhook.Get(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "webhook": webhookResponse(hook)})
+}
+
+// handleUpdateWebhookSettings saves the account webhook. The signing secret
+// is generated on first save and only replaced when rotate_secret is set.
+func (s *Server) handleUpdateWebhookSettings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	var req struct {
+		URL          *string  `json:"url"`
+		Events       []string `json:"events"`
+		Enabled      *bool    `json:"enabled"`
+		RotateSecret bool     `json:"rotate_secret"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request body"})
+	}
+
+	hook, err := s.repos.Webhook.Get(c.Context(), accountID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	if hook == nil {
+		hook = &domain.AccountWebhook{AccountID: accountID}
+	}
+	if req.URL != nil {
+		hook.URL = strings.TrimSpace(*req.URL)
+		if hook.URL != "" {
+			if err := service.ValidateWebhookURL(hook.URL); err != nil {
+				return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+			}
+		}
+	}
+	if req.Events != nil {
+		if err := service.ValidateWebhookEvents(req.Events); err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
+		hook.Events = req.Events
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if hook.Enabled && hook.URL == "" {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": "Para activar el webhook se requiere url"})
+	}
+	if hook.Secret == "" || req.RotateSecret {
+		hook.Secret = service.GenerateWebhookSecret()
+	}
+
+	if err := s.repos.Webhook.Upsert(c.Context(), hook); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	s.services.Webhook.Forget(accountID)
+	return c.JSON(fiber.Map{"success": true, "webhook": webhookResponse(hook)})
+}
+
+func (s *Server) handleDeleteWebhookSettings(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	if err := s.repos.Webhook.Delete(c.Context(), accountID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	s.services.Webhook.Forget(accountID)
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// handleTestWebhook sends a sample signed payload and reports the outcome.
+func (s *Server) handleTestWebhook(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	delivery, err := s.services.Webhook.SendTest(c.Context(), accountID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": delivery.Status == domain.WebhookDeliveryDelivered, "delivery": delivery})
+}
+
+func (s *Server) handleGetWebhookDeliveries(c *fiber.Ctx) error {
+	accountID := c.Locals("account_id").(uuid.UUID)
+	deliveries, err := s.services.Webhook.ListDeliveries(c.Context(), accountID, c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "deliveries": deliveries})
+}
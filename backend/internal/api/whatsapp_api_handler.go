@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
@@ -20,6 +19,7 @@ import (
 	"github.com/naperu/clarin/internal/domain"
 	"github.com/naperu/clarin/internal/kommo"
 	"github.com/naperu/clarin/internal/ws"
+	"github.com/naperu/clarin/pkg/webhook"
 )
 
 type cloudWebhookPayload struct {
@@ -156,17 +156,7 @@ func (s *Server) handleWhatsAppCloudWebhook(c *fiber.Ctx) error {
 }
 
 func validWhatsAppCloudSignature(appSecret, signatureHeader string, payload []byte) bool {
-	const prefix = "sha256="
-	if appSecret == "" || !strings.HasPrefix(signatureHeader, prefix) {
-		return false
-	}
-	provided, err := hex.DecodeString(strings.TrimPrefix(signatureHeader, prefix))
-	if err != nil || len(provided) != sha256.Size {
-		return false
-	}
-	mac := hmac.New(sha256.New, []byte(appSecret))
-	_, _ = mac.Write(payload)
-	return hmac.Equal(provided, mac.Sum(nil))
+	return webhook.ValidBodySignature(appSecret, signatureHeader, payload)
 }
 
 func (s *Server) processWhatsAppCloudWebhook(ctx context.Context, payload cloudWebhookPayload) error {
@@ -427,6 +417,8 @@ func (s *Server) processCloudAPIMessage(ctx context.Context, device *domain.Devi
 		return fmt.Errorf("failed to save message: %w", err)
 	}
 	_ = s.repos.Chat.UpdateLastMessage(ctx, chat.ID, body, timestamp, true)
+	s.pool.BroadcastUnreadSummary(device.AccountID)
+	s.applyAutoTagRules(device.AccountID, chat.ID, body)
 	_ = s.repos.WhatsAppAPI.UpdateChatServiceWindow(ctx, chat.ID, provider, true, timestamp)
 
 	lead, _ := s.repos.Lead.GetByJID(ctx, device.AccountID, jid)
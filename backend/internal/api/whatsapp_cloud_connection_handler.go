@@ -514,6 +514,7 @@ func (s *Server) handleMarkChatAPIRead(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"success": false, "error": err.Error()})
 	}
 	s.invalidateChatCaches(accountID, &chatID)
+	s.pool.BroadcastUnreadSummary(accountID)
 	messageID, err := s.repos.WhatsAppAPI.LatestInboundCloudMessageID(c.Context(), accountID, device.ID, chatID)
 	if err != nil || messageID == "" {
 		return c.JSON(fiber.Map{"success": true})
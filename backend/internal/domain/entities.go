@@ -31,6 +31,24 @@ type Account struct {
 	GoogleConnectedAt    *time.Time `json:"google_connected_at,omitempty"`
 	GoogleSyncLimit      int        `json:"google_sync_limit"`
 
+	// Pending recipient count above which starting a campaign requires an
+	// explicit confirm_count. 0 disables the guard.
+	CampaignConfirmThreshold int `json:"campaign_confirm_threshold"`
+
+	// Super-admin ceilings applied on top of each campaign's own pacing.
+	CampaignRateLimits CampaignRateLimits `json:"campaign_rate_limits"`
+
+	// Campaigns do not send between QuietHoursStart and QuietHoursEnd
+	// ("HH:MM", account-local). Timezone is an IANA zone name.
+	QuietHoursStart *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string `json:"quiet_hours_end,omitempty"`
+	Timezone        string  `json:"timezone"`
+
+	// When RestrictToAssigned is set, agents only list the leads and chats
+	// assigned to them, plus unassigned ones if AgentsSeeUnassigned.
+	RestrictToAssigned  bool `json:"restrict_to_assigned"`
+	AgentsSeeUnassigned bool `json:"agents_see_unassigned"`
+
 	// Populated on demand
 	UserCount       int `json:"user_count,omitempty"`
 	DeviceCount     int `json:"device_count,omitempty"`
@@ -150,6 +168,19 @@ const (
 	RoleAgent      = "agent"
 )
 
+// roleLevels orders the roles: agent < admin < super_admin.
+var roleLevels = map[string]int{
+	RoleAgent:      1,
+	RoleAdmin:      2,
+	RoleSuperAdmin: 3,
+}
+
+// RoleAtLeast reports whether role is minimum or ranks above it. Unknown
+// roles rank below agent.
+func RoleAtLeast(role, minimum string) bool {
+	return roleLevels[role] >= roleLevels[minimum] && roleLevels[role] > 0
+}
+
 // Permission module constants
 const (
 	PermChats         = "chats"
@@ -314,6 +345,24 @@ const (
 	DeviceStatusLoggedOut    = "logged_out"
 )
 
+// DeviceEvent is one entry of a device's connection history.
+type DeviceEvent struct {
+	ID        uuid.UUID `json:"id"`
+	AccountID uuid.UUID `json:"account_id"`
+	DeviceID  uuid.UUID `json:"device_id"`
+	Type      string    `json:"type"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeviceEvent types
+const (
+	DeviceEventConnected    = "connected"
+	DeviceEventDisconnected = "disconnected"
+	DeviceEventQR           = "qr"
+	DeviceEventLoggedOut    = "logged_out"
+)
+
 // Contact represents a WhatsApp contact
 type Contact struct {
 	ID                 uuid.UUID  `json:"id"`
@@ -353,6 +402,8 @@ type Contact struct {
 	DoNotContactAt     *time.Time `json:"do_not_contact_at,omitempty"`
 	DoNotContactBy     *uuid.UUID `json:"do_not_contact_by,omitempty"`
 	DoNotContactReason string     `json:"do_not_contact_reason,omitempty"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy          *uuid.UUID `json:"deleted_by,omitempty"`
 
 	// Google Contacts sync
 	GoogleSync         bool       `json:"google_sync"`
@@ -455,6 +506,14 @@ func (c *Contact) DisplayName() string {
 	return c.JID
 }
 
+// CampaignRateLimits caps how aggressively an account's campaigns may send.
+// A nil field means no limit.
+type CampaignRateLimits struct {
+	MinDelaySeconds      *int `json:"min_delay_seconds"`
+	MaxBatchSize         *int `json:"max_batch_size"`
+	MinBatchPauseMinutes *int `json:"min_batch_pause_minutes"`
+}
+
 // ContactDeviceName stores the name a device has for a contact
 type ContactDeviceName struct {
 	ID           uuid.UUID `json:"id"`
@@ -469,6 +528,12 @@ type ContactDeviceName struct {
 	DeviceName *string `json:"device_name,omitempty"`
 }
 
+// Tag-id filter semantics shared by contact, lead, chat and participant lists.
+const (
+	TagMatchAny = "any" // entity has at least one of the selected tags (OR)
+	TagMatchAll = "all" // entity has every selected tag (AND)
+)
+
 // ContactFilter defines filter options for listing contacts
 type ContactFilter struct {
 	Search             string
@@ -477,6 +542,7 @@ type ContactFilter struct {
 	IsGroup            bool
 	Tags               []string
 	TagIDs             []uuid.UUID
+	TagMatch           string // TagMatchAny (default) or TagMatchAll for TagIDs
 	TagNames           []string
 	ExcludeTagNames    []string
 	TagMode            string      // OR or AND
@@ -510,6 +576,7 @@ type Chat struct {
 	LastOutboundAt                 *time.Time `json:"last_outbound_at,omitempty"`
 	CustomerServiceWindowExpiresAt *time.Time `json:"customer_service_window_expires_at,omitempty"`
 	LastMessageProvider            *string    `json:"last_message_provider,omitempty"`
+	AssignedTo                     *uuid.UUID `json:"assigned_to,omitempty"`
 	CreatedAt                      time.Time  `json:"created_at"`
 	UpdatedAt                      time.Time  `json:"updated_at"`
 
@@ -532,16 +599,52 @@ type Chat struct {
 	Messages []*Message `json:"messages,omitempty"`
 }
 
+// WhatsAppGroup is the persisted metadata of a WhatsApp group the account's
+// devices belong to. It is refreshed from whatsmeow on connect and whenever
+// the group changes.
+type WhatsAppGroup struct {
+	ID               uuid.UUID                  `json:"id"`
+	AccountID        uuid.UUID                  `json:"account_id"`
+	DeviceID         *uuid.UUID                 `json:"device_id,omitempty"`
+	JID              string                     `json:"jid"`
+	Subject          string                     `json:"subject"`
+	Topic            string                     `json:"topic,omitempty"`
+	OwnerJID         string                     `json:"owner_jid,omitempty"`
+	IsAnnounce       bool                       `json:"is_announce"`
+	IsLocked         bool                       `json:"is_locked"`
+	Participants     []WhatsAppGroupParticipant `json:"participants"`
+	ParticipantCount int                        `json:"participant_count"`
+	ChatID           *uuid.UUID                 `json:"chat_id,omitempty"`
+	CreatedAt        time.Time                  `json:"created_at"`
+	UpdatedAt        time.Time                  `json:"updated_at"`
+}
+
+// WhatsAppGroupParticipant is one member of a WhatsAppGroup.
+type WhatsAppGroupParticipant struct {
+	JID          string `json:"jid"`
+	Phone        string `json:"phone,omitempty"`
+	Name         string `json:"name,omitempty"`
+	IsAdmin      bool   `json:"is_admin"`
+	IsSuperAdmin bool   `json:"is_super_admin"`
+
+	// Matching contact of the account, resolved on read
+	ContactID   *uuid.UUID `json:"contact_id,omitempty"`
+	ContactName string     `json:"contact_name,omitempty"`
+}
+
 // ChatFilter defines filter options for listing chats
 type ChatFilter struct {
-	DeviceIDs  []uuid.UUID
-	Provider   string
-	TagIDs     []uuid.UUID
-	UnreadOnly bool
-	Archived   bool
-	Search     string
-	Limit      int
-	Offset     int
+	DeviceIDs     []uuid.UUID
+	Provider      string
+	TagIDs        []uuid.UUID
+	TagMatch      string // TagMatchAny (default) or TagMatchAll for TagIDs
+	UnreadOnly    bool
+	Archived      bool
+	IncludeGroups bool // group chats (@g.us) are hidden unless set
+	Search        string
+	Limit         int
+	Offset        int
+	Visibility    *AssignmentScope // nil = every chat of the account
 
 	// Reaction-based filtering
 	HasReaction    bool       // when true, only chats with at least one reaction matching the criteria below
@@ -551,6 +654,35 @@ type ChatFilter struct {
 	ReactionUntil  *time.Time // optional upper bound on reaction timestamp
 }
 
+// ChatUnreadSummary aggregates unread counters for the chat list badge.
+type ChatUnreadSummary struct {
+	TotalUnread int                  `json:"total_unread"`
+	UnreadChats int                  `json:"unread_chats"`
+	Devices     []*DeviceUnreadCount `json:"devices"`
+}
+
+// InboxChat is one row of the aggregated multi-account inbox.
+type InboxChat struct {
+	AccountID     uuid.UUID  `json:"account_id"`
+	AccountName   string     `json:"account_name"`
+	ChatID        uuid.UUID  `json:"chat_id"`
+	DeviceID      *uuid.UUID `json:"device_id,omitempty"`
+	ContactID     *uuid.UUID `json:"contact_id,omitempty"`
+	JID           string     `json:"jid"`
+	Name          string     `json:"name"`
+	LastMessage   *string    `json:"last_message,omitempty"`
+	LastMessageAt *time.Time `json:"last_message_at,omitempty"`
+	UnreadCount   int        `json:"unread_count"`
+}
+
+// DeviceUnreadCount is the per-device slice of a ChatUnreadSummary.
+type DeviceUnreadCount struct {
+	DeviceID    *uuid.UUID `json:"device_id"`
+	DeviceName  *string    `json:"device_name,omitempty"`
+	TotalUnread int        `json:"total_unread"`
+	UnreadChats int        `json:"unread_chats"`
+}
+
 // ChatDetails contains full chat information with related data
 type ChatDetails struct {
 	Chat                *Chat                 `json:"chat"`
@@ -598,7 +730,10 @@ type Message struct {
 	IsRead        bool       `json:"is_read"`
 	IsRevoked     bool       `json:"is_revoked"`
 	IsEdited      bool       `json:"is_edited"`
+	EditedAt      *time.Time `json:"edited_at,omitempty"`
 	IsViewOnce    bool       `json:"is_view_once"`
+	IsForwarded   bool       `json:"is_forwarded"`
+	Starred       bool       `json:"starred"`
 	Status        *string    `json:"status,omitempty"` // sent, delivered, read, failed
 	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
 	ReadAt        *time.Time `json:"read_at,omitempty"`
@@ -607,6 +742,10 @@ type Message struct {
 	Timestamp     time.Time  `json:"timestamp"`
 	CreatedAt     time.Time  `json:"created_at"`
 
+	// ForwardingScore is WhatsApp's count of how many times the message was
+	// forwarded; forwarding it again sends the next score.
+	ForwardingScore int `json:"forwarding_score,omitempty"`
+
 	// Quoted/reply fields
 	QuotedMessageID *string `json:"quoted_message_id,omitempty"`
 	QuotedBody      *string `json:"quoted_body,omitempty"`
@@ -632,6 +771,18 @@ type Message struct {
 	PollMaxSelections int           `json:"poll_max_selections,omitempty"`
 }
 
+// MarshalJSON adds epoch-millisecond copies of Timestamp (the WhatsApp send
+// time) and CreatedAt (when the server stored it). A created_at far after the
+// timestamp marks history that arrived out of order (history sync, redelivery).
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	return json.Marshal(struct {
+		message
+		TimestampMs int64 `json:"timestamp_ms"`
+		CreatedAtMs int64 `json:"created_at_ms"`
+	}{message(m), m.Timestamp.UnixMilli(), m.CreatedAt.UnixMilli()})
+}
+
 type MediaAsset struct {
 	ID          uuid.UUID  `json:"id"`
 	AccountID   uuid.UUID  `json:"account_id"`
@@ -654,6 +805,7 @@ const (
 	MessageTypeVideo    = "video"
 	MessageTypeGIF      = "gif"
 	MessageTypeAudio    = "audio"
+	MessageTypePTT      = "ptt" // outgoing voice note: sent as "audio/ogg; codecs=opus" with the PTT flag, stored as audio
 	MessageTypeDocument = "document"
 	MessageTypeSticker  = "sticker"
 	MessageTypeLocation = "location"
@@ -871,6 +1023,7 @@ type Lead struct {
 	DeletedAt      *time.Time             `json:"deleted_at,omitempty"`
 	DeletedBy      *uuid.UUID             `json:"deleted_by,omitempty"`
 	DeleteReason   string                 `json:"delete_reason,omitempty"`
+	Score          int                    `json:"score"`
 	CreatedAt      time.Time              `json:"created_at"`
 	UpdatedAt      time.Time              `json:"updated_at"`
 
@@ -945,11 +1098,38 @@ type PipelineTemplateStage struct {
 // LeadFilter defines filter options for listing leads
 type LeadFilter struct {
 	Search     string
+	Status     string
 	PipelineID *uuid.UUID
 	StageID    *uuid.UUID
+	AssignedTo *uuid.UUID
+	DeviceIDs  []uuid.UUID // leads whose JID has a chat on one of these devices
 	TagIDs     []uuid.UUID
+	TagMatch   string // TagMatchAny (default) or TagMatchAll for TagIDs
+	SortBy     string // created_at (default) or score
 	Limit      int
 	Offset     int
+	AfterID    *uuid.UUID       // keyset cursor: leads with a greater id, in id order; skips SortBy, Offset and the total
+	Visibility *AssignmentScope // nil = every lead of the account
+}
+
+// AssignmentScope limits a lead or chat list to the records assigned to
+// UserID, for agents of accounts with restrict_to_assigned. A chat counts as
+// assigned through chats.assigned_to or, when unset, its lead's assignee.
+type AssignmentScope struct {
+	UserID            uuid.UUID
+	IncludeUnassigned bool
+}
+
+// Allows reports whether a record with the given assignee is visible under
+// the scope. A nil scope allows everything.
+func (s *AssignmentScope) Allows(assignee *uuid.UUID) bool {
+	if s == nil {
+		return true
+	}
+	if assignee == nil {
+		return s.IncludeUnassigned
+	}
+	return *assignee == s.UserID
 }
 
 // Person represents a unified search result from contacts and leads
@@ -962,6 +1142,20 @@ type Person struct {
 	Tags       []*Tag    `json:"tags,omitempty"`
 }
 
+// TagUsage counts the records that reference a tag. Leads share their
+// contact's tags, so Leads counts the leads whose contact has the tag and
+// Total counts each contact once together with its leads.
+type TagUsage struct {
+	TagID        uuid.UUID `json:"tag_id"`
+	Name         string    `json:"name"`
+	Color        string    `json:"color"`
+	Contacts     int       `json:"contacts"`
+	Leads        int       `json:"leads"`
+	Chats        int       `json:"chats"`
+	Participants int       `json:"participants"`
+	Total        int       `json:"total"`
+}
+
 // Tag represents a global label with color
 type Tag struct {
 	ID        uuid.UUID `json:"id"`
@@ -974,6 +1168,28 @@ type Tag struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Auto-tag rule match modes.
+const (
+	AutoTagMatchContains = "contains" // case-insensitive substring
+	AutoTagMatchRegex    = "regex"
+)
+
+// AutoTagRule assigns TagID to the contact behind a chat when an inbound
+// message matches Pattern.
+type AutoTagRule struct {
+	ID        uuid.UUID `json:"id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Name      string    `json:"name"`
+	Pattern   string    `json:"pattern"`
+	MatchMode string    `json:"match_mode"`
+	TagID     uuid.UUID `json:"tag_id"`
+	TagName   string    `json:"tag_name,omitempty"`
+	TagColor  string    `json:"tag_color,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // Campaign represents a mass messaging campaign
 type Campaign struct {
 	ID              uuid.UUID              `json:"id"`
@@ -1026,11 +1242,107 @@ type CampaignRecipient struct {
 	JID          string                 `json:"jid"`
 	Name         *string                `json:"name,omitempty"`
 	Phone        *string                `json:"phone,omitempty"`
-	Status       string                 `json:"status"` // pending, sent, delivered, failed, skipped
+	Status       string                 `json:"status"` // pending, sent, delivered, failed, skipped, skipped_test, invalid
 	SentAt       *time.Time             `json:"sent_at,omitempty"`
 	ErrorMessage *string                `json:"error_message,omitempty"`
 	WaitTimeMs   *int                   `json:"wait_time_ms,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Channel      string                 `json:"channel"` // whatsapp, sms
+	RetryCount   int                    `json:"retry_count"`
+	NextRetryAt  *time.Time             `json:"next_retry_at,omitempty"`
+}
+
+// Campaign delivery channels recorded on each recipient.
+const (
+	CampaignChannelWhatsApp = "whatsapp"
+	CampaignChannelSMS      = "sms"
+)
+
+const SMSProviderTwilio = "twilio"
+
+// Outbound webhook events an account can subscribe to.
+const (
+	WebhookEventMessageReceived  = "message.received"
+	WebhookEventMessageSent      = "message.sent"
+	WebhookEventLeadCreated      = "lead.created"
+	WebhookEventLeadStageChanged = "lead.stage_changed"
+	// WebhookEventTest is only sent by the test endpoint.
+	WebhookEventTest = "webhook.test"
+)
+
+// WebhookEvents lists the events accepted in an account webhook subscription.
+var WebhookEvents = []string{
+	WebhookEventMessageReceived,
+	WebhookEventMessageSent,
+	WebhookEventLeadCreated,
+	WebhookEventLeadStageChanged,
+}
+
+// AccountWebhook is the endpoint an account receives signed event
+// notifications on.
+type AccountWebhook struct {
+	AccountID uuid.UUID `json:"account_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribed reports whether the webhook is active and wants event.
+func (w *AccountWebhook) Subscribed(event string) bool {
+	if w == nil || !w.Enabled || w.URL == "" {
+		return false
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery logs one event sent to an account webhook.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	AccountID      uuid.UUID       `json:"account_id"`
+	Event          string          `json:"event"`
+	URL            string          `json:"url"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	Error          *string         `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	// NextAttemptAt is when a pending delivery is next tried.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// AccountSMSSettings configures the optional SMS fallback used for campaign
+// recipients whose number is not on WhatsApp.
+type AccountSMSSettings struct {
+	AccountID        uuid.UUID `json:"account_id"`
+	Provider         string    `json:"provider"`
+	Enabled          bool      `json:"enabled"`
+	TwilioAccountSID string    `json:"twilio_account_sid"`
+	TwilioAuthToken  string    `json:"-"`
+	FromNumber       string    `json:"from_number"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Ready reports whether the fallback is enabled and has credentials to send.
+func (s *AccountSMSSettings) Ready() bool {
+	return s != nil && s.Enabled && s.TwilioAccountSID != "" && s.TwilioAuthToken != "" && s.FromNumber != ""
 }
 
 // Campaign status constants
@@ -1085,9 +1397,15 @@ type Event struct {
 	TagFormulaType     string     `json:"tag_formula_type"` // simple, advanced
 	RuleRevision       int64      `json:"rule_revision"`
 	HasMembershipRules bool       `json:"has_membership_rules"`
-	CreatedBy          *uuid.UUID `json:"created_by,omitempty"`
-	CreatedAt          time.Time  `json:"created_at"`
-	UpdatedAt          time.Time  `json:"updated_at"`
+	// RecurrenceRule repeats the event, e.g. "FREQ=WEEKLY;INTERVAL=1". Once an
+	// occurrence's date passes, the next one is created as a new event.
+	RecurrenceRule             string     `json:"recurrence_rule,omitempty"`
+	RecurrenceCopyParticipants bool       `json:"recurrence_copy_participants,omitempty"`
+	RecurrenceParentID         *uuid.UUID `json:"recurrence_parent_id,omitempty"`
+	RecurrenceNextID           *uuid.UUID `json:"recurrence_next_id,omitempty"`
+	CreatedBy                  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt                  time.Time  `json:"created_at"`
+	UpdatedAt                  time.Time  `json:"updated_at"`
 
 	// Populated on demand
 	ParticipantCounts map[string]int `json:"participant_counts,omitempty"`
@@ -1145,6 +1463,22 @@ func CanTransitionEventStatus(from, to string) bool {
 }
 
 // EventParticipant represents a contact participating in an event
+// ParticipantActionReminder is a participant next action that has come due
+// and was claimed for a reminder. AssignedTo is the lead's assignee, falling
+// back to the event creator; nil notifies the whole account.
+type ParticipantActionReminder struct {
+	ParticipantID  uuid.UUID
+	EventID        uuid.UUID
+	AccountID      uuid.UUID
+	EventName      string
+	ContactID      *uuid.UUID
+	LeadID         *uuid.UUID
+	Name           string
+	NextAction     *string
+	NextActionDate time.Time
+	AssignedTo     *uuid.UUID
+}
+
 type EventParticipant struct {
 	ID                  uuid.UUID  `json:"id"`
 	EventID             uuid.UUID  `json:"event_id"`
@@ -1245,6 +1579,44 @@ const (
 	ParticipantStatusNoShow    = "no_show"
 )
 
+// EventStats summarizes an event's active participants for its dashboard.
+// Rates are percentages: ConfirmationRate over all participants, counting
+// attendees as confirmed, and AttendanceRate over the participants who
+// confirmed (confirmed, attended or no-show). PendingActions counts
+// participants with a next action date who haven't attended, declined or
+// missed the event.
+type EventStats struct {
+	EventID          uuid.UUID        `json:"event_id"`
+	Total            int              `json:"total"`
+	Invited          int              `json:"invited"`
+	Contacted        int              `json:"contacted"`
+	Confirmed        int              `json:"confirmed"`
+	Declined         int              `json:"declined"`
+	Attended         int              `json:"attended"`
+	NoShow           int              `json:"no_show"`
+	ConfirmationRate float64          `json:"confirmation_rate"`
+	AttendanceRate   float64          `json:"attendance_rate"`
+	PendingActions   int              `json:"pending_actions"`
+	Tags             []*EventTagCount `json:"tags"`
+}
+
+// EventTagCount is the number of an event's active participants whose
+// contact carries a tag.
+type EventTagCount struct {
+	TagID uuid.UUID `json:"tag_id"`
+	Name  string    `json:"name"`
+	Color string    `json:"color"`
+	Count int       `json:"count"`
+}
+
+// EventCheckInResult reports a bulk check-in. Unknown lists the phones, as
+// received, that matched no active participant of the event.
+type EventCheckInResult struct {
+	CheckedIn        []uuid.UUID `json:"checked_in"`
+	AlreadyCheckedIn []uuid.UUID `json:"already_checked_in"`
+	Unknown          []string    `json:"unknown"`
+}
+
 // Interaction represents a communication log entry with a contact
 type Interaction struct {
 	ID                   uuid.UUID  `json:"id"`
@@ -1272,6 +1644,68 @@ type Interaction struct {
 	EventName     *string `json:"event_name,omitempty"`
 }
 
+// LeadStageChange is one pipeline stage move of a lead. Stage names are
+// copied so the history survives renamed or deleted stages.
+type LeadStageChange struct {
+	ID            uuid.UUID  `json:"id"`
+	AccountID     uuid.UUID  `json:"account_id"`
+	LeadID        uuid.UUID  `json:"lead_id"`
+	FromStageID   *uuid.UUID `json:"from_stage_id,omitempty"`
+	ToStageID     *uuid.UUID `json:"to_stage_id,omitempty"`
+	FromStageName string     `json:"from_stage_name"`
+	ToStageName   string     `json:"to_stage_name"`
+	ChangedBy     *uuid.UUID `json:"changed_by,omitempty"`
+	ChangedByName *string    `json:"changed_by_name,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// PipelineFunnelStage summarises how leads moved through one pipeline stage.
+// EnteredPipeline counts first assignments (from no stage); the median only
+// covers stays that already ended.
+type PipelineFunnelStage struct {
+	StageID              uuid.UUID `json:"stage_id"`
+	Name                 string    `json:"name"`
+	Color                string    `json:"color"`
+	Position             int       `json:"position"`
+	StageType            string    `json:"stage_type"`
+	Entered              int       `json:"entered"`
+	EnteredPipeline      int       `json:"entered_pipeline"`
+	Exited               int       `json:"exited"`
+	MedianSecondsInStage *float64  `json:"median_seconds_in_stage"`
+}
+
+// PipelineBoardColumn is one Kanban column: a stage with the first page of
+// its leads and counts over all of them.
+type PipelineBoardColumn struct {
+	StageID    uuid.UUID `json:"stage_id"`
+	Name       string    `json:"name"`
+	Color      string    `json:"color"`
+	Position   int       `json:"position"`
+	StageType  string    `json:"stage_type"`
+	Total      int       `json:"total"`
+	Unassigned int       `json:"unassigned"`
+	Leads      []*Lead   `json:"leads"`
+	HasMore    bool      `json:"has_more"`
+}
+
+// Lead timeline item types.
+const (
+	LeadTimelineInteraction = "interaction"
+	LeadTimelineMessage     = "message"
+	LeadTimelineStageChange = "stage_change"
+)
+
+// LeadTimelineItem is one entry of a lead's activity feed. Type tells which
+// of the payload fields is set.
+type LeadTimelineItem struct {
+	Type        string           `json:"type"`
+	ID          uuid.UUID        `json:"id"`
+	At          time.Time        `json:"at"`
+	Interaction *Interaction     `json:"interaction,omitempty"`
+	Message     *Message         `json:"message,omitempty"`
+	StageChange *LeadStageChange `json:"stage_change,omitempty"`
+}
+
 // Interaction type constants
 const (
 	InteractionTypeCall       = "call"
@@ -1282,6 +1716,25 @@ const (
 	InteractionTypeAttendance = "attendance"
 )
 
+// Lead scoring rule types.
+const (
+	LeadScoreRuleHasEmail              = "has_email"
+	LeadScoreRuleHasPhone              = "has_phone"
+	LeadScoreRuleHasTag                = "has_tag"
+	LeadScoreRuleStatus                = "status"
+	LeadScoreRuleRepliedWithinDays     = "replied_within_days"
+	LeadScoreRuleInteractionWithinDays = "interaction_within_days"
+)
+
+// LeadScoringRule adds Points to a lead's score when it matches. Value holds
+// the tag name or status; Days the window for the *_within_days rules.
+type LeadScoringRule struct {
+	Type   string `json:"type"`
+	Value  string `json:"value,omitempty"`
+	Days   int    `json:"days,omitempty"`
+	Points int    `json:"points"`
+}
+
 // Interaction outcome constants
 const (
 	InteractionOutcomeAnswered    = "answered"
@@ -1423,6 +1876,7 @@ type InteractionFilter struct {
 type QuickReply struct {
 	ID            uuid.UUID              `json:"id"`
 	AccountID     uuid.UUID              `json:"account_id"`
+	OwnerID       *uuid.UUID             `json:"owner_id,omitempty"` // nil = shared with the account
 	Shortcut      string                 `json:"shortcut"`
 	Title         string                 `json:"title"`
 	Body          string                 `json:"body"`
@@ -1430,6 +1884,8 @@ type QuickReply struct {
 	MediaType     string                 `json:"media_type"`
 	MediaFilename string                 `json:"media_filename"`
 	Attachments   []QuickReplyAttachment `json:"attachments"`
+	UseCount      int                    `json:"use_count"`
+	LastUsedAt    *time.Time             `json:"last_used_at,omitempty"`
 	CreatedAt     time.Time              `json:"created_at"`
 	UpdatedAt     time.Time              `json:"updated_at"`
 }
@@ -1962,16 +2418,53 @@ const (
 
 // APIKey represents an API key for MCP / external integrations
 type APIKey struct {
+	ID          uuid.UUID `json:"id"`
+	AccountID   uuid.UUID `json:"account_id"`
+	Name        string    `json:"name"`
+	KeyHash     string    `json:"-"`
+	KeyPrefix   string    `json:"key_prefix"`
+	Permissions string    `json:"permissions"`
+	// Role is admin (full account access) or agent; agent keys only get the
+	// module permissions of RoleID.
+	Role       string     `json:"role"`
+	RoleID     *uuid.UUID `json:"role_id,omitempty"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	IsActive   bool       `json:"is_active"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ChatReplyToken is a short-lived credential that lets an external bot reply
+// to exactly one chat. Only the SHA-256 hash of the token is stored.
+type ChatReplyToken struct {
 	ID          uuid.UUID  `json:"id"`
 	AccountID   uuid.UUID  `json:"account_id"`
-	Name        string     `json:"name"`
-	KeyHash     string     `json:"-"`
-	KeyPrefix   string     `json:"key_prefix"`
-	Permissions string     `json:"permissions"`
-	IsActive    bool       `json:"is_active"`
+	ChatID      uuid.UUID  `json:"chat_id"`
+	Label       string     `json:"label"`
+	TokenHash   string     `json:"-"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
 	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	UseCount    int        `json:"use_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// LeadWebhookToken lets an external form or landing page create leads in one
+// account. Only the SHA-256 hash of the token is stored.
+type LeadWebhookToken struct {
+	ID          uuid.UUID  `json:"id"`
+	AccountID   uuid.UUID  `json:"account_id"`
+	Label       string     `json:"label"`
+	TokenHash   string     `json:"-"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	UseCount    int        `json:"use_count"`
 	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 // MCPClient represents a named, global MCP connection.
@@ -2077,6 +2570,30 @@ const (
 	MCPScopeSelectedAccounts = "selected_accounts"
 )
 
+// AuditLogEntry records one super-admin mutation. TargetID is the :id of the
+// route (account, user, role...) and Status the HTTP status returned.
+type AuditLogEntry struct {
+	ID            uuid.UUID  `json:"id"`
+	ActorUserID   *uuid.UUID `json:"actor_user_id,omitempty"`
+	ActorUsername string     `json:"actor_username"`
+	Action        string     `json:"action"`
+	TargetType    string     `json:"target_type,omitempty"`
+	TargetID      string     `json:"target_id,omitempty"`
+	Method        string     `json:"method"`
+	Path          string     `json:"path"`
+	Status        int        `json:"status"`
+	IPHash        string     `json:"ip_hash,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// AuditLogFilter selects audit entries, newest first.
+type AuditLogFilter struct {
+	ActorUserID *uuid.UUID
+	Action      string
+	Limit       int
+	Offset      int
+}
+
 // ErosConversation represents a persistent chat conversation with Eros AI
 type ErosConversation struct {
 	ID            uuid.UUID `json:"id"`
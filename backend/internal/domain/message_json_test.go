@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMessageJSONIncludesBothTimestamps(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	created := ts.Add(90 * time.Minute)
+	raw, err := json.Marshal(&Message{ID: uuid.New(), Timestamp: ts, CreatedAt: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["timestamp"] != ts.Format(time.RFC3339) || decoded["created_at"] != created.Format(time.RFC3339) {
+		t.Fatalf("RFC3339 fields missing: %s", raw)
+	}
+	if int64(decoded["timestamp_ms"].(float64)) != ts.UnixMilli() || int64(decoded["created_at_ms"].(float64)) != created.UnixMilli() {
+		t.Fatalf("millisecond fields wrong: %s", raw)
+	}
+}
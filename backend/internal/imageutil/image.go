@@ -0,0 +1,167 @@
+// Package imageutil holds the small image helpers shared by avatar
+// normalization and outbound media preparation.
+package imageutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+)
+
+// ErrCannotFit is returned when an image cannot be brought under the requested
+// size without shrinking it below MinDownscaleSide.
+var ErrCannotFit = errors.New("image cannot be downscaled under the size limit")
+
+// MinDownscaleSide is the smallest longest-side DownscaleToFit will produce.
+const MinDownscaleSide = 320
+
+// DownscaleToFit re-encodes an image as JPEG, shrinking its dimensions until
+// the encoded result is at most maxBytes. Input already under the limit is
+// returned unchanged.
+func DownscaleToFit(input []byte, maxBytes int64) ([]byte, error) {
+	if int64(len(input)) <= maxBytes {
+		return input, nil
+	}
+	source, _, err := image.Decode(bytes.NewReader(input))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return nil, ErrCannotFit
+	}
+	// Start from the area ratio; each miss shrinks another 20%.
+	scale := math.Sqrt(float64(maxBytes) / float64(len(input)))
+	if scale > 1 {
+		scale = 1
+	}
+	for attempt := 0; attempt < 8; attempt++ {
+		w := int(float64(width) * scale)
+		h := int(float64(height) * scale)
+		if w < 1 {
+			w = 1
+		}
+		if h < 1 {
+			h = 1
+		}
+		if w < MinDownscaleSide && h < MinDownscaleSide {
+			break
+		}
+		destination := image.NewRGBA(image.Rect(0, 0, w, h))
+		ResizeBilinear(destination, source, bounds)
+		var output bytes.Buffer
+		if err := jpeg.Encode(&output, destination, &jpeg.Options{Quality: 82}); err != nil {
+			return nil, fmt.Errorf("encode image: %w", err)
+		}
+		if int64(output.Len()) <= maxBytes {
+			return output.Bytes(), nil
+		}
+		scale *= 0.8
+	}
+	return nil, ErrCannotFit
+}
+
+// ErrTooManyPixels is returned when an image declares more pixels than
+// MaxThumbnailPixels; decoding it could take gigabytes of memory.
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// MaxThumbnailPixels bounds the declared size of images Thumbnail decodes.
+const MaxThumbnailPixels = 50_000_000
+
+// Thumbnail re-encodes an image as a JPEG whose longest side is at most
+// maxSide. Smaller images are re-encoded at their own size. The header is
+// checked first, so an image declaring more than MaxThumbnailPixels is
+// rejected before its pixels are allocated.
+func Thumbnail(input []byte, maxSide int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(input))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if int64(config.Width)*int64(config.Height) > MaxThumbnailPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooManyPixels, config.Width, config.Height)
+	}
+	source, _, err := image.Decode(bytes.NewReader(input))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("decode image: empty bounds")
+	}
+	if longest := max(width, height); longest > maxSide {
+		width = max(1, width*maxSide/longest)
+		height = max(1, height*maxSide/longest)
+	}
+	destination := image.NewRGBA(image.Rect(0, 0, width, height))
+	ResizeBilinear(destination, source, bounds)
+	var output bytes.Buffer
+	if err := jpeg.Encode(&output, destination, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+	return output.Bytes(), nil
+}
+
+// ResizeBilinear scales the crop rectangle of src into dst using bilinear
+// interpolation.
+func ResizeBilinear(dst *image.RGBA, src image.Image, crop image.Rectangle) {
+	if crop.Dx() <= 0 || crop.Dy() <= 0 {
+		return
+	}
+	scaleX := float64(crop.Dx()) / float64(dst.Bounds().Dx())
+	scaleY := float64(crop.Dy()) / float64(dst.Bounds().Dy())
+	for y := 0; y < dst.Bounds().Dy(); y++ {
+		sy := float64(crop.Min.Y) + (float64(y)+0.5)*scaleY - 0.5
+		y0 := int(math.Floor(sy))
+		y1 := y0 + 1
+		fy := sy - float64(y0)
+		if y0 < crop.Min.Y {
+			y0, y1, fy = crop.Min.Y, crop.Min.Y, 0
+		}
+		if y1 >= crop.Max.Y {
+			y1 = crop.Max.Y - 1
+		}
+		for x := 0; x < dst.Bounds().Dx(); x++ {
+			sx := float64(crop.Min.X) + (float64(x)+0.5)*scaleX - 0.5
+			x0 := int(math.Floor(sx))
+			x1 := x0 + 1
+			fx := sx - float64(x0)
+			if x0 < crop.Min.X {
+				x0, x1, fx = crop.Min.X, crop.Min.X, 0
+			}
+			if x1 >= crop.Max.X {
+				x1 = crop.Max.X - 1
+			}
+			c00 := color.RGBAModel.Convert(src.At(x0, y0)).(color.RGBA)
+			c10 := color.RGBAModel.Convert(src.At(x1, y0)).(color.RGBA)
+			c01 := color.RGBAModel.Convert(src.At(x0, y1)).(color.RGBA)
+			c11 := color.RGBAModel.Convert(src.At(x1, y1)).(color.RGBA)
+			dst.SetRGBA(x, y, color.RGBA{
+				R: interpolate(c00.R, c10.R, c01.R, c11.R, fx, fy),
+				G: interpolate(c00.G, c10.G, c01.G, c11.G, fx, fy),
+				B: interpolate(c00.B, c10.B, c01.B, c11.B, fx, fy),
+				A: interpolate(c00.A, c10.A, c01.A, c11.A, fx, fy),
+			})
+		}
+	}
+}
+
+func interpolate(c00, c10, c01, c11 uint8, fx, fy float64) uint8 {
+	top := float64(c00)*(1-fx) + float64(c10)*fx
+	bottom := float64(c01)*(1-fx) + float64(c11)*fx
+	value := top*(1-fy) + bottom*fy
+	if value < 0 {
+		return 0
+	}
+	if value > 255 {
+		return 255
+	}
+	return uint8(math.Round(value))
+}
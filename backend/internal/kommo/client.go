@@ -20,6 +20,11 @@ type Client struct {
 	httpClient *http.Client
 	mu         sync.Mutex
 	lastReq    time.Time
+	// OAuth refresh state, guarded by tokenMu. oauth is nil for static tokens.
+	tokenMu         sync.Mutex
+	oauth           *OAuthConfig
+	refreshFailures int
+	refreshRetryAt  time.Time
 }
 
 // NewClient creates a new Kommo API client.
@@ -62,69 +67,96 @@ func (c *Client) rateLimit() {
 	c.lastReq = time.Now()
 }
 
-func (c *Client) get(path string) ([]byte, error) {
-	c.rateLimit()
+// APIError is a non-2xx response from the Kommo API. Body holds Kommo's
+// problem+json payload (title, detail, hint...).
+type APIError struct {
+	Method     string // empty for plain GETs
+	Path       string
+	StatusCode int
+	Body       string
+}
 
-	req, err := http.NewRequest("GET", c.baseURL+path, nil)
-	if err != nil {
-		return nil, err
+func (e *APIError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("kommo API %s returned %d: %s", e.Path, e.StatusCode, e.Body)
 	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
+	return fmt.Sprintf("kommo API %s %s returned %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
 
-	resp, err := c.httpClient.Do(req)
+func (c *Client) get(path string) ([]byte, error) {
+	status, body, err := c.send("GET", path, nil)
 	if err != nil {
 		return nil, fmt.Errorf("kommo request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	if status != http.StatusOK {
+		return nil, &APIError{Path: path, StatusCode: status, Body: string(body)}
+	}
+	return body, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (c *Client) doRequest(method, path string, payload interface{}) ([]byte, error) {
+	var data []byte
+	if payload != nil {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("kommo marshal body: %w", err)
+		}
+	}
+	status, body, err := c.send(method, path, data)
 	if err != nil {
-		return nil, fmt.Errorf("kommo read body: %w", err)
+		return nil, fmt.Errorf("kommo %s request failed: %w", method, err)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("kommo API %s returned %d: %s", path, resp.StatusCode, string(body))
+	if status < 200 || status >= 300 {
+		return nil, &APIError{Method: method, Path: path, StatusCode: status, Body: string(body)}
 	}
-
 	return body, nil
 }
 
-func (c *Client) doRequest(method, path string, payload interface{}) ([]byte, error) {
+// send performs one API call. With OAuth refresh configured, the access token
+// is refreshed shortly before it expires, and a 401 triggers one
+// refresh-and-retry in case the token was revoked or rotated early.
+func (c *Client) send(method, path string, payload []byte) (int, []byte, error) {
+	if err := c.refreshIfExpiring(); err != nil {
+		log.Printf("[KOMMO] Token refresh before %s %s failed: %v", method, path, err)
+	}
+	token := c.accessToken()
+	status, body, err := c.sendOnce(method, path, payload, token)
+	if err == nil && status == http.StatusUnauthorized && c.canRefresh() {
+		if refreshErr := c.refreshAfterUnauthorized(token); refreshErr != nil {
+			log.Printf("[KOMMO] Token refresh after 401 on %s %s failed: %v", method, path, refreshErr)
+			return status, body, nil
+		}
+		return c.sendOnce(method, path, payload, c.accessToken())
+	}
+	return status, body, err
+}
+
+func (c *Client) sendOnce(method, path string, payload []byte, token string) (int, []byte, error) {
 	c.rateLimit()
 
 	var bodyReader io.Reader
 	if payload != nil {
-		data, err := json.Marshal(payload)
-		if err != nil {
-			return nil, fmt.Errorf("kommo marshal body: %w", err)
-		}
-		bodyReader = bytes.NewReader(data)
+		bodyReader = bytes.NewReader(payload)
 	}
-
 	req, err := http.NewRequest(method, c.baseURL+path, bodyReader)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("kommo %s request failed: %w", method, err)
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("kommo read body: %w", err)
+		return 0, nil, fmt.Errorf("kommo read body: %w", err)
 	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("kommo API %s %s returned %d: %s", method, path, resp.StatusCode, string(body))
-	}
-
-	return body, nil
+	return resp.StatusCode, body, nil
 }
 
 // --- Kommo API types ---
@@ -0,0 +1,80 @@
+package kommo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConflictPolicy decides which side wins when a lead changed both in Clarin
+// and in Kommo since the last pull. It is stored per account in
+// accounts.kommo_conflict_policy.
+//
+// Fields subject to conflict resolution (leads table): name, pipeline_id,
+// stage_id and the open/won/lost status with closed_at/close_reason, including
+// Kommo won/lost transitions and moves to a pipeline not synced in Clarin, plus
+// the custom_fields keys mapped in kommo_field_mappings.
+// Tags are not: they already use a three-way merge against
+// leads.kommo_synced_tags. Contact data follows its own push/pull rules.
+//
+// A Clarin edit is detected by leads.clarin_edited_at moving past
+// leads.kommo_pulled_at, the last time Kommo values were applied. A database
+// trigger stamps clarin_edited_at only when one of the fields above changes
+// outside a Kommo pull, so archive, block, merge or JID writes that bump
+// updated_at do not count as edits.
+type ConflictPolicy string
+
+const (
+	// ConflictKommoWins always applies Kommo values (the historical behavior).
+	ConflictKommoWins ConflictPolicy = "kommo_wins"
+	// ConflictClarinWins keeps Clarin values whenever the lead was edited
+	// locally since the last pull.
+	ConflictClarinWins ConflictPolicy = "clarin_wins"
+	// ConflictNewestWins compares the local edit time with Kommo's updated_at.
+	ConflictNewestWins ConflictPolicy = "newest_wins"
+)
+
+// Valid reports whether p is a known policy.
+func (p ConflictPolicy) Valid() bool {
+	switch p {
+	case ConflictKommoWins, ConflictClarinWins, ConflictNewestWins:
+		return true
+	}
+	return false
+}
+
+// keepClarinValues reports whether a Kommo update must be discarded in favor
+// of the local values. editedAt/pulledAt come from the lead row and
+// kommoUpdatedAt is the lead's updated_at in Kommo (Unix seconds). Leads never
+// pulled since the policy existed (nil pulledAt) or never edited locally (nil
+// editedAt) take Kommo values.
+func keepClarinValues(policy ConflictPolicy, editedAt, pulledAt *time.Time, kommoUpdatedAt int64) bool {
+	localEdited := editedAt != nil && pulledAt != nil && editedAt.After(*pulledAt)
+	if !localEdited {
+		return false
+	}
+	switch policy {
+	case ConflictClarinWins:
+		return true
+	case ConflictNewestWins:
+		return editedAt.Unix() > kommoUpdatedAt
+	}
+	return false
+}
+
+// keepClarinLead loads the account policy and the lead timestamps and reports
+// whether the incoming Kommo values for the lead should be discarded.
+func (s *SyncService) keepClarinLead(ctx context.Context, leadID uuid.UUID, kommoUpdatedAt int64) bool {
+	var policy string
+	var editedAt, pulledAt *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(a.kommo_conflict_policy, 'kommo_wins'), l.clarin_edited_at, l.kommo_pulled_at
+		FROM leads l JOIN accounts a ON a.id = l.account_id
+		WHERE l.id = $1
+	`, leadID).Scan(&policy, &editedAt, &pulledAt)
+	if err != nil {
+		return false
+	}
+	return keepClarinValues(ConflictPolicy(policy), editedAt, pulledAt, kommoUpdatedAt)
+}
@@ -0,0 +1,36 @@
+package kommo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepClarinValues(t *testing.T) {
+	pulled := time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)
+	edited := pulled.Add(time.Hour)
+	before := edited.Add(-time.Minute).Unix()
+	after := edited.Add(time.Minute).Unix()
+
+	cases := []struct {
+		name     string
+		policy   ConflictPolicy
+		edited   *time.Time
+		pulledAt *time.Time
+		kommoAt  int64
+		want     bool
+	}{
+		{"kommo wins over local edit", ConflictKommoWins, &edited, &pulled, before, false},
+		{"clarin wins local edit", ConflictClarinWins, &edited, &pulled, after, true},
+		{"clarin wins without local edit", ConflictClarinWins, &pulled, &pulled, after, false},
+		{"never edited takes kommo", ConflictClarinWins, nil, &pulled, after, false},
+		{"never pulled takes kommo", ConflictClarinWins, &edited, nil, before, false},
+		{"newest wins local newer", ConflictNewestWins, &edited, &pulled, before, true},
+		{"newest wins kommo newer", ConflictNewestWins, &edited, &pulled, after, false},
+		{"unknown policy takes kommo", ConflictPolicy("other"), &edited, &pulled, before, false},
+	}
+	for _, tc := range cases {
+		if got := keepClarinValues(tc.policy, tc.edited, tc.pulledAt, tc.kommoAt); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package kommo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Diagnostic check names, in the order they run.
+const (
+	CheckToken     = "token"
+	CheckAccount   = "account"
+	CheckSubdomain = "subdomain"
+	CheckPipelines = "pipelines"
+)
+
+// DiagnosticCheck is the outcome of one step of a connection test. Code is a
+// stable identifier (KOMMO_401, NETWORK_ERROR...) the UI can map to help.
+type DiagnosticCheck struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Message    string `json:"message"`
+	Code       string `json:"code,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// TokenInfo is what can be read from a Kommo long-lived token (a JWT)
+// without calling the API. Fields missing from the token stay empty.
+type TokenInfo struct {
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Expired    bool       `json:"expired"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	BaseDomain string     `json:"base_domain,omitempty"`
+	AccountID  int        `json:"account_id,omitempty"`
+}
+
+// DiagnosticPipeline is a pipeline visible to the token.
+type DiagnosticPipeline struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	IsMain bool   `json:"is_main"`
+	Stages int    `json:"stages"`
+}
+
+// ConnectionDiagnostic is the structured result of Client.Diagnose.
+type ConnectionDiagnostic struct {
+	OK         bool                 `json:"ok"`
+	Subdomain  string               `json:"subdomain"`
+	Token      *TokenInfo           `json:"token,omitempty"`
+	Account    *KommoAccount        `json:"account,omitempty"`
+	Pipelines  []DiagnosticPipeline `json:"pipelines"`
+	Checks     []DiagnosticCheck    `json:"checks"`
+	DurationMS int64                `json:"duration_ms"`
+}
+
+// Subdomain returns the Kommo subdomain the client was configured with.
+func (c *Client) Subdomain() string {
+	parsed, err := url.Parse(c.baseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(parsed.Hostname(), ".kommo.com")
+}
+
+// Diagnose checks the token, the account it belongs to, the configured
+// subdomain and the pipelines it can read. Every check runs even when an
+// earlier one fails, unless the API is unreachable.
+func (c *Client) Diagnose() *ConnectionDiagnostic {
+	start := time.Now()
+	diag := &ConnectionDiagnostic{Subdomain: c.Subdomain(), Pipelines: []DiagnosticPipeline{}}
+	add := func(check DiagnosticCheck) {
+		diag.Checks = append(diag.Checks, check)
+	}
+
+	token := c.accessToken()
+	info, err := parseTokenInfo(token, start)
+	switch {
+	case strings.TrimSpace(token) == "":
+		add(DiagnosticCheck{Name: CheckToken, Code: "TOKEN_MISSING", Message: "No hay token de acceso configurado"})
+	case err != nil:
+		// Not a JWT: the API calls below still tell whether it works.
+		add(DiagnosticCheck{Name: CheckToken, OK: true, Code: "TOKEN_OPAQUE", Message: "El token no permite leer alcances ni vencimiento"})
+	case info.Expired:
+		diag.Token = info
+		add(DiagnosticCheck{Name: CheckToken, Code: "TOKEN_EXPIRED", Message: fmt.Sprintf("El token venció el %s", info.ExpiresAt.Format(time.RFC3339))})
+	default:
+		diag.Token = info
+		add(DiagnosticCheck{Name: CheckToken, OK: true, Message: "Token con formato válido"})
+	}
+
+	account, err := c.GetAccount()
+	if err != nil {
+		add(apiErrorCheck(CheckAccount, err))
+	} else {
+		diag.Account = account
+		add(DiagnosticCheck{Name: CheckAccount, OK: true, Message: fmt.Sprintf("Conectado a la cuenta %q (%d)", account.Name, account.ID)})
+	}
+	if isNetworkError(err) {
+		diag.DurationMS = time.Since(start).Milliseconds()
+		return diag
+	}
+
+	switch {
+	case diag.Account == nil:
+		add(DiagnosticCheck{Name: CheckSubdomain, Code: "NOT_VERIFIED", Message: "No se pudo verificar el subdominio sin acceso a la cuenta"})
+	case !strings.EqualFold(diag.Account.Subdomain, diag.Subdomain):
+		add(DiagnosticCheck{Name: CheckSubdomain, Code: "SUBDOMAIN_MISMATCH", Message: fmt.Sprintf("El subdominio configurado %q no coincide con el de la cuenta (%q)", diag.Subdomain, diag.Account.Subdomain)})
+	default:
+		add(DiagnosticCheck{Name: CheckSubdomain, OK: true, Message: fmt.Sprintf("Subdominio %q verificado", diag.Subdomain)})
+	}
+
+	pipelines, err := c.GetPipelines()
+	if err != nil {
+		add(apiErrorCheck(CheckPipelines, err))
+	} else {
+		for _, p := range pipelines {
+			diag.Pipelines = append(diag.Pipelines, DiagnosticPipeline{ID: p.ID, Name: p.Name, IsMain: p.IsMain, Stages: len(p.Statuses)})
+		}
+		if len(pipelines) == 0 {
+			add(DiagnosticCheck{Name: CheckPipelines, Code: "NO_PIPELINES", Message: "El token no tiene acceso a ningún embudo"})
+		} else {
+			add(DiagnosticCheck{Name: CheckPipelines, OK: true, Message: fmt.Sprintf("%d embudo(s) accesibles", len(pipelines))})
+		}
+	}
+
+	diag.OK = true
+	for _, check := range diag.Checks {
+		diag.OK = diag.OK && check.OK
+	}
+	diag.DurationMS = time.Since(start).Milliseconds()
+	return diag
+}
+
+// parseTokenInfo decodes the claims of a JWT access token without verifying
+// its signature.
+func parseTokenInfo(token string, now time.Time) (*TokenInfo, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode token payload: %w", err)
+	}
+	var claims struct {
+		Exp        int64           `json:"exp"`
+		Scopes     json.RawMessage `json:"scopes"`
+		BaseDomain string          `json:"base_domain"`
+		AccountID  int             `json:"account_id"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("parse token payload: %w", err)
+	}
+	info := &TokenInfo{BaseDomain: claims.BaseDomain, AccountID: claims.AccountID}
+	if claims.Exp > 0 {
+		expiresAt := time.Unix(claims.Exp, 0).UTC()
+		info.ExpiresAt = &expiresAt
+		info.Expired = !now.Before(expiresAt)
+	}
+	// scopes is an array in current tokens and a space separated string in older ones.
+	if err := json.Unmarshal(claims.Scopes, &info.Scopes); err != nil {
+		var scopes string
+		if json.Unmarshal(claims.Scopes, &scopes) == nil {
+			info.Scopes = strings.Fields(scopes)
+		}
+	}
+	return info, nil
+}
+
+// apiErrorCheck turns a failed API call into a check carrying Kommo's status
+// code and problem title/detail.
+func apiErrorCheck(name string, err error) DiagnosticCheck {
+	check := DiagnosticCheck{Name: name, Message: err.Error()}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		if isNetworkError(err) {
+			check.Code = "NETWORK_ERROR"
+			check.Message = "No se pudo contactar a Kommo: " + err.Error()
+		} else {
+			check.Code = "UNEXPECTED_RESPONSE"
+		}
+		return check
+	}
+	check.HTTPStatus = apiErr.StatusCode
+	check.Code = fmt.Sprintf("KOMMO_%d", apiErr.StatusCode)
+	var problem struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+		Hint   string `json:"hint"`
+	}
+	if json.Unmarshal([]byte(apiErr.Body), &problem) == nil {
+		check.Detail = strings.TrimSpace(strings.Join(nonEmpty(problem.Title, problem.Detail, problem.Hint), " — "))
+	}
+	switch apiErr.StatusCode {
+	case 401:
+		check.Message = "Kommo rechazó el token (401): inválido, revocado o vencido"
+	case 402:
+		check.Message = "La cuenta de Kommo no tiene una suscripción activa (402)"
+	case 403:
+		check.Message = "El token no tiene permiso para este recurso o la cuenta está bloqueada (403)"
+	case 404:
+		check.Message = "Kommo no encontró el recurso (404): revisa el subdominio"
+	case 429:
+		check.Message = "Kommo limitó las solicitudes (429): intenta de nuevo en unos segundos"
+	default:
+		check.Message = fmt.Sprintf("Kommo respondió %d", apiErr.StatusCode)
+	}
+	return check
+}
+
+// isNetworkError reports whether the request never got an HTTP response.
+func isNetworkError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+func nonEmpty(values ...string) []string {
+	out := values[:0:0]
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
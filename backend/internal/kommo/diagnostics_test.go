@@ -0,0 +1,56 @@
+package kommo
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testJWT(payload string) string {
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".sig"
+}
+
+func TestParseTokenInfo(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	info, err := parseTokenInfo(testJWT(`{"exp":1900000000,"scopes":["crm","notifications"],"base_domain":"kommo.com","account_id":42}`), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Expired || info.ExpiresAt == nil || info.ExpiresAt.Unix() != 1_900_000_000 {
+		t.Fatalf("unexpected expiry: %+v", info)
+	}
+	if len(info.Scopes) != 2 || info.AccountID != 42 || info.BaseDomain != "kommo.com" {
+		t.Fatalf("unexpected claims: %+v", info)
+	}
+
+	info, err = parseTokenInfo(testJWT(`{"exp":1700000000,"scopes":"crm files"}`), now)
+	if err != nil || !info.Expired || len(info.Scopes) != 2 {
+		t.Fatalf("expired token with string scopes: %+v, %v", info, err)
+	}
+
+	if _, err := parseTokenInfo("opaque-token", now); err == nil {
+		t.Fatal("expected an error for a non-JWT token")
+	}
+}
+
+func TestAPIErrorCheck(t *testing.T) {
+	err := fmt.Errorf("wrap: %w", &APIError{Path: "/account", StatusCode: 401, Body: `{"title":"Unauthorized","detail":"Token revoked"}`})
+	check := apiErrorCheck(CheckAccount, err)
+	if check.OK || check.Code != "KOMMO_401" || check.HTTPStatus != 401 || check.Detail != "Unauthorized — Token revoked" {
+		t.Fatalf("unexpected check: %+v", check)
+	}
+	if got := (&APIError{Path: "/account", StatusCode: 404, Body: "x"}).Error(); got != "kommo API /account returned 404: x" {
+		t.Fatalf("error message changed: %q", got)
+	}
+	if check := apiErrorCheck(CheckPipelines, errors.New("boom")); check.Code != "UNEXPECTED_RESPONSE" {
+		t.Fatalf("unexpected code: %+v", check)
+	}
+}
+
+func TestClientSubdomain(t *testing.T) {
+	if got := NewClient("acme", "t").Subdomain(); got != "acme" {
+		t.Fatalf("Subdomain() = %q", got)
+	}
+}
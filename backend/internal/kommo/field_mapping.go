@@ -0,0 +1,273 @@
+package kommo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// FieldMapping links a key of leads.custom_fields to a Kommo lead custom
+// field. FieldType is the Kommo field type the value is coerced to.
+type FieldMapping struct {
+	ClarinKey    string `json:"clarin_key"`
+	KommoFieldID int    `json:"kommo_field_id"`
+	FieldType    string `json:"field_type"`
+}
+
+// MappableFieldTypes lists the Kommo field types that can be mapped. Other
+// types (dates, addresses, linked entities...) are skipped with a warning.
+var MappableFieldTypes = []string{"text", "textarea", "url", "numeric", "select", "radiobutton", "multiselect", "checkbox"}
+
+// IsMappableFieldType reports whether values of the Kommo field type can be
+// converted to and from leads.custom_fields.
+func IsMappableFieldType(fieldType string) bool {
+	for _, t := range MappableFieldTypes {
+		if t == fieldType {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFieldMappings returns the custom field mappings of an account.
+func LoadFieldMappings(ctx context.Context, db *pgxpool.Pool, accountID uuid.UUID) ([]FieldMapping, error) {
+	rows, err := db.Query(ctx, `
+		SELECT clarin_key, kommo_field_id, field_type
+		FROM kommo_field_mappings WHERE account_id = $1
+		ORDER BY clarin_key
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	mappings := []FieldMapping{}
+	for rows.Next() {
+		var m FieldMapping
+		if err := rows.Scan(&m.ClarinKey, &m.KommoFieldID, &m.FieldType); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
+
+// ReplaceFieldMappings replaces every custom field mapping of an account.
+func ReplaceFieldMappings(ctx context.Context, db *pgxpool.Pool, accountID uuid.UUID, mappings []FieldMapping) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM kommo_field_mappings WHERE account_id = $1`, accountID); err != nil {
+		return err
+	}
+	for _, m := range mappings {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO kommo_field_mappings (account_id, clarin_key, kommo_field_id, field_type)
+			VALUES ($1, $2, $3, $4)
+		`, accountID, m.ClarinKey, m.KommoFieldID, m.FieldType); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// kommoFieldToClarin converts a Kommo custom field value to the value stored
+// in leads.custom_fields. A field without values maps to nil (key removed).
+// ok is false when the value cannot be coerced to the field type.
+func kommoFieldToClarin(fieldType string, f KommoCustomField) (interface{}, bool) {
+	if len(f.Values) == 0 {
+		return nil, true
+	}
+	first := f.Values[0].Value
+	switch fieldType {
+	case "text", "textarea", "url", "select", "radiobutton":
+		if first == nil {
+			return nil, true
+		}
+		return fmt.Sprint(first), true
+	case "numeric":
+		return coerceNumber(first)
+	case "multiselect":
+		values := make([]interface{}, 0, len(f.Values))
+		for _, v := range f.Values {
+			if v.Value != nil {
+				values = append(values, fmt.Sprint(v.Value))
+			}
+		}
+		return values, true
+	case "checkbox":
+		return coerceBool(first)
+	}
+	return nil, false
+}
+
+// clarinToKommoField builds the write payload for a leads.custom_fields value.
+// A nil or empty value clears the field in Kommo. ok is false when the value
+// cannot be coerced to the mapped field type.
+func clarinToKommoField(m FieldMapping, value interface{}) (KommoCustomFieldWrite, bool) {
+	write := KommoCustomFieldWrite{FieldID: m.KommoFieldID}
+	if value == nil || value == "" {
+		return write, true
+	}
+	switch m.FieldType {
+	case "text", "textarea", "url", "select", "radiobutton":
+		if number, ok := value.(float64); ok {
+			value = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		write.Values = []KommoCustomFieldWriteVal{{Value: fmt.Sprint(value)}}
+	case "numeric":
+		number, ok := coerceNumber(value)
+		if !ok {
+			return write, false
+		}
+		write.Values = []KommoCustomFieldWriteVal{{Value: number}}
+	case "multiselect":
+		list, isList := value.([]interface{})
+		if !isList {
+			list = []interface{}{value}
+		}
+		for _, item := range list {
+			if item != nil && item != "" {
+				write.Values = append(write.Values, KommoCustomFieldWriteVal{Value: fmt.Sprint(item)})
+			}
+		}
+	case "checkbox":
+		flag, ok := coerceBool(value)
+		if !ok {
+			return write, false
+		}
+		write.Values = []KommoCustomFieldWriteVal{{Value: flag}}
+	default:
+		return write, false
+	}
+	return write, true
+}
+
+func coerceNumber(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f, true
+		}
+	case string:
+		raw := strings.ReplaceAll(strings.TrimSpace(v), ",", ".")
+		if raw == "" {
+			return nil, true
+		}
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f, true
+		}
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return nil, false
+}
+
+func coerceBool(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case float64:
+		return v != 0, true
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// pullMappedCustomFields copies the mapped Kommo custom fields into
+// leads.custom_fields. Unmapped keys are left untouched. Returns true if the
+// lead changed.
+func (s *SyncService) pullMappedCustomFields(ctx context.Context, accountID, leadID uuid.UUID, fields []KommoCustomField) bool {
+	mappings, err := LoadFieldMappings(ctx, s.db, accountID)
+	if err != nil || len(mappings) == 0 {
+		return false
+	}
+	byID := make(map[int]KommoCustomField, len(fields))
+	for _, f := range fields {
+		byID[f.FieldID] = f
+	}
+
+	var current map[string]interface{}
+	if err := s.db.QueryRow(ctx, `SELECT COALESCE(custom_fields, '{}'::jsonb) FROM leads WHERE id = $1`, leadID).Scan(&current); err != nil {
+		return false
+	}
+	if current == nil {
+		current = map[string]interface{}{}
+	}
+	merged := make(map[string]interface{}, len(current))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for _, m := range mappings {
+		f := byID[m.KommoFieldID]
+		fieldType := m.FieldType
+		if f.FieldType != "" {
+			fieldType = f.FieldType
+		}
+		if !IsMappableFieldType(fieldType) {
+			log.Printf("[Kommo Sync] WARNING: field %d (%s) has unsupported type %q, skipped", m.KommoFieldID, m.ClarinKey, fieldType)
+			continue
+		}
+		value, ok := kommoFieldToClarin(fieldType, f)
+		if !ok {
+			log.Printf("[Kommo Sync] WARNING: lead %s field %d value cannot be read as %s, skipped", leadID, m.KommoFieldID, fieldType)
+			continue
+		}
+		if value == nil {
+			delete(merged, m.ClarinKey)
+		} else {
+			merged[m.ClarinKey] = value
+		}
+	}
+	if reflect.DeepEqual(current, merged) {
+		return false
+	}
+	if _, err := s.db.Exec(ctx, `UPDATE leads SET custom_fields = $1, updated_at = NOW(), kommo_pulled_at = NOW() WHERE id = $2`, merged, leadID); err != nil {
+		log.Printf("[Kommo Sync] lead %s custom fields update failed: %v", leadID, err)
+		return false
+	}
+	return true
+}
+
+// mappedFieldWrites builds the Kommo writes for the given changed keys of
+// leads.custom_fields. Only keys with a mapping are sent; a changed key that
+// is now absent clears the Kommo field. Values that cannot be coerced to the
+// Kommo type are skipped.
+func mappedFieldWrites(leadID uuid.UUID, mappings []FieldMapping, values map[string]interface{}, keys []string) []KommoCustomFieldWrite {
+	changed := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		changed[k] = true
+	}
+	var fields []KommoCustomFieldWrite
+	for _, m := range mappings {
+		if !changed[m.ClarinKey] {
+			continue
+		}
+		if !IsMappableFieldType(m.FieldType) {
+			log.Printf("[PUSH] WARNING: field %d (%s) has unsupported type %q, skipped", m.KommoFieldID, m.ClarinKey, m.FieldType)
+			continue
+		}
+		write, ok := clarinToKommoField(m, values[m.ClarinKey])
+		if !ok {
+			log.Printf("[PUSH] WARNING: lead %s value of %s cannot be sent as %s, skipped", leadID, m.ClarinKey, m.FieldType)
+			continue
+		}
+		fields = append(fields, write)
+	}
+	return fields
+}
@@ -0,0 +1,86 @@
+package kommo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func kommoField(fieldType string, values ...interface{}) KommoCustomField {
+	f := KommoCustomField{FieldType: fieldType}
+	for _, v := range values {
+		f.Values = append(f.Values, struct {
+			Value    interface{} `json:"value"`
+			EnumID   int         `json:"enum_id,omitempty"`
+			EnumCode string      `json:"enum_code,omitempty"`
+		}{Value: v})
+	}
+	return f
+}
+
+func TestKommoFieldToClarin(t *testing.T) {
+	cases := []struct {
+		name   string
+		field  KommoCustomField
+		want   interface{}
+		wantOK bool
+	}{
+		{"text", kommoField("text", "Lima"), "Lima", true},
+		{"select", kommoField("select", "Ingeniería"), "Ingeniería", true},
+		{"numeric string", kommoField("numeric", "1500,50"), 1500.5, true},
+		{"numeric invalid", kommoField("numeric", "abc"), nil, false},
+		{"multiselect", kommoField("multiselect", "A", "B"), []interface{}{"A", "B"}, true},
+		{"checkbox", kommoField("checkbox", true), true, true},
+		{"empty clears", kommoField("text"), nil, true},
+		{"unsupported", kommoField("date", float64(1700000000)), nil, false},
+	}
+	for _, tc := range cases {
+		got, ok := kommoFieldToClarin(tc.field.FieldType, tc.field)
+		if ok != tc.wantOK || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", tc.name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestClarinToKommoField(t *testing.T) {
+	write, ok := clarinToKommoField(FieldMapping{KommoFieldID: 7, FieldType: "numeric"}, "42")
+	if !ok || len(write.Values) != 1 || write.Values[0].Value != float64(42) {
+		t.Fatalf("numeric: got %+v, %v", write, ok)
+	}
+	if _, ok := clarinToKommoField(FieldMapping{KommoFieldID: 7, FieldType: "numeric"}, "n/a"); ok {
+		t.Fatal("numeric: expected non-numeric text to be skipped")
+	}
+	write, ok = clarinToKommoField(FieldMapping{KommoFieldID: 8, FieldType: "text"}, float64(12))
+	if !ok || write.Values[0].Value != "12" {
+		t.Fatalf("text: got %+v, %v", write, ok)
+	}
+	write, ok = clarinToKommoField(FieldMapping{KommoFieldID: 9, FieldType: "multiselect"}, []interface{}{"A", "", "B"})
+	if !ok || len(write.Values) != 2 {
+		t.Fatalf("multiselect: got %+v, %v", write, ok)
+	}
+	write, ok = clarinToKommoField(FieldMapping{KommoFieldID: 10, FieldType: "select"}, nil)
+	if !ok || write.Values != nil || write.FieldID != 10 {
+		t.Fatalf("clear: got %+v, %v", write, ok)
+	}
+}
+
+func TestMappedFieldWritesSendsOnlyChangedKeys(t *testing.T) {
+	mappings := []FieldMapping{
+		{ClarinKey: "plan", KommoFieldID: 1, FieldType: "text"},
+		{ClarinKey: "budget", KommoFieldID: 2, FieldType: "numeric"},
+		{ClarinKey: "city", KommoFieldID: 3, FieldType: "text"},
+	}
+	values := map[string]interface{}{"plan": "Gold", "budget": float64(10)}
+
+	fields := mappedFieldWrites(uuid.New(), mappings, values, []string{"plan", "city", "unmapped"})
+	if len(fields) != 2 {
+		t.Fatalf("expected the changed mapped keys only, got %+v", fields)
+	}
+	if fields[0].FieldID != 1 || fields[0].Values[0].Value != "Gold" {
+		t.Fatalf("plan: got %+v", fields[0])
+	}
+	if fields[1].FieldID != 3 || fields[1].Values != nil {
+		t.Fatalf("removed city should clear the Kommo field, got %+v", fields[1])
+	}
+}
@@ -58,7 +58,8 @@ func (m *Manager) Reload(ctx context.Context) error {
 	m.primary = nil
 
 	rows, err := m.db.Query(ctx, `
-		SELECT id, name, subdomain, access_token, webhook_secret
+		SELECT id, name, subdomain, access_token, webhook_secret,
+			client_id, client_secret, refresh_token, redirect_uri, token_expires_at
 		FROM integration_instances
 		WHERE provider = $1 AND is_active = TRUE AND status = $2
 		ORDER BY created_at ASC
@@ -71,7 +72,9 @@ func (m *Manager) Reload(ctx context.Context) error {
 	for rows.Next() {
 		var id uuid.UUID
 		var name, subdomain, accessToken, webhookSecret string
-		if err := rows.Scan(&id, &name, &subdomain, &accessToken, &webhookSecret); err != nil {
+		var clientID, clientSecret, refreshToken, redirectURI string
+		var tokenExpiresAt *time.Time
+		if err := rows.Scan(&id, &name, &subdomain, &accessToken, &webhookSecret, &clientID, &clientSecret, &refreshToken, &redirectURI, &tokenExpiresAt); err != nil {
 			return err
 		}
 		if subdomain == "" || accessToken == "" {
@@ -81,6 +84,17 @@ func (m *Manager) Reload(ctx context.Context) error {
 
 		client := NewClientWithProxy(subdomain, accessToken, m.cfg.ProxyURL)
 		instanceID := id
+		oauth := OAuthConfig{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURI:  redirectURI,
+			RefreshToken: refreshToken,
+			Rotate:       m.rotateToken(instanceID, name),
+		}
+		if tokenExpiresAt != nil {
+			oauth.ExpiresAt = *tokenExpiresAt
+		}
+		client.EnableTokenRefresh(oauth)
 		svc := NewSyncServiceForInstance(client, m.db, m.hub, &instanceID, name)
 		svc.WebhookSecret = webhookSecret
 		svc.PublicURL = m.cfg.PublicURL
@@ -122,6 +136,63 @@ func (m *Manager) Reload(ctx context.Context) error {
 	return rows.Err()
 }
 
+// rotateToken refreshes an instance's token pair under a row lock on
+// integration_instances, so replicas never spend the same single-use refresh
+// token twice. A replica that waited on the lock finds the pair already
+// rotated and adopts it without calling Kommo.
+func (m *Manager) rotateToken(instanceID uuid.UUID, name string) TokenRotator {
+	return func(current OAuthToken, exchange func(string) (OAuthToken, error)) (OAuthToken, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		tx, err := m.db.Begin(ctx)
+		if err != nil {
+			return OAuthToken{}, err
+		}
+		defer tx.Rollback(ctx)
+
+		var stored OAuthToken
+		var expiresAt *time.Time
+		if err := tx.QueryRow(ctx, `
+			SELECT access_token, refresh_token, token_expires_at
+			FROM integration_instances WHERE id = $1
+			FOR UPDATE
+		`, instanceID).Scan(&stored.AccessToken, &stored.RefreshToken, &expiresAt); err != nil {
+			return OAuthToken{}, err
+		}
+		if expiresAt != nil {
+			stored.ExpiresAt = *expiresAt
+		}
+		if stored.RefreshToken != current.RefreshToken {
+			if err := tx.Commit(ctx); err != nil {
+				return OAuthToken{}, err
+			}
+			log.Printf("[KOMMO_MANAGER] Instance %s access token was already refreshed by another replica", name)
+			return stored, nil
+		}
+
+		refreshed, err := exchange(stored.RefreshToken)
+		if err != nil {
+			return OAuthToken{}, err
+		}
+		var newExpiresAt *time.Time
+		if !refreshed.ExpiresAt.IsZero() {
+			newExpiresAt = &refreshed.ExpiresAt
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE integration_instances
+			SET access_token = $2, refresh_token = $3, token_expires_at = $4, updated_at = NOW()
+			WHERE id = $1
+		`, instanceID, refreshed.AccessToken, refreshed.RefreshToken, newExpiresAt); err != nil {
+			return refreshed, fmt.Errorf("persist refreshed kommo token: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return refreshed, fmt.Errorf("persist refreshed kommo token: %w", err)
+		}
+		log.Printf("[KOMMO_MANAGER] Instance %s access token refreshed", name)
+		return refreshed, nil
+	}
+}
+
 func (m *Manager) Stop() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -0,0 +1,215 @@
+package kommo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tokenRefreshMargin refreshes the access token this long before it expires,
+// so a request never starts with a token about to die mid-sync.
+const tokenRefreshMargin = 5 * time.Minute
+
+// After a failed refresh the client waits before trying again, doubling the
+// wait from tokenRefreshBackoffMin up to tokenRefreshBackoffMax.
+const (
+	tokenRefreshBackoffMin = 30 * time.Second
+	tokenRefreshBackoffMax = 15 * time.Minute
+)
+
+var errTokenRefreshBackoff = errors.New("kommo token refresh is backing off after a failure")
+
+// OAuthConfig enables refreshing the access token of an OAuth integration.
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	RefreshToken string
+	// ExpiresAt is when the current access token expires. When zero it is
+	// read from the token itself (Kommo tokens are JWTs).
+	ExpiresAt time.Time
+	// Rotate runs exchange for the current pair and persists the result.
+	// Kommo refresh tokens are single use, so with several replicas Rotate
+	// must hold a lock shared by all of them and, when the stored pair no
+	// longer matches current, return it instead of calling exchange. When
+	// nil the exchange runs locally and nothing is persisted.
+	Rotate TokenRotator
+}
+
+// TokenRotator coordinates one token refresh; see OAuthConfig.Rotate. When
+// Kommo issued a new pair that could not be persisted, it returns that pair
+// along with the error so the client keeps working until the next restart.
+type TokenRotator func(current OAuthToken, exchange func(refreshToken string) (OAuthToken, error)) (OAuthToken, error)
+
+// OAuthToken is the result of a token refresh.
+type OAuthToken struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// EnableTokenRefresh makes the client refresh its access token before it
+// expires and once after a 401. Without a client ID, secret and refresh
+// token it does nothing.
+func (c *Client) EnableTokenRefresh(cfg OAuthConfig) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" || cfg.RefreshToken == "" {
+		return
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if cfg.ExpiresAt.IsZero() {
+		if info, err := parseTokenInfo(c.token, time.Now()); err == nil && info.ExpiresAt != nil {
+			cfg.ExpiresAt = *info.ExpiresAt
+		}
+	}
+	c.oauth = &cfg
+}
+
+// accessToken returns the current access token.
+func (c *Client) accessToken() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.token
+}
+
+func (c *Client) canRefresh() bool {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.oauth != nil
+}
+
+// refreshIfExpiring refreshes the token when it expires within
+// tokenRefreshMargin. Unknown expiry never triggers a refresh.
+func (c *Client) refreshIfExpiring() error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.oauth == nil || c.oauth.ExpiresAt.IsZero() || time.Until(c.oauth.ExpiresAt) > tokenRefreshMargin {
+		return nil
+	}
+	if time.Now().Before(c.refreshRetryAt) {
+		return errTokenRefreshBackoff
+	}
+	return c.refreshLocked()
+}
+
+// refreshAfterUnauthorized refreshes the token after a 401, unless another
+// request already replaced the token that was rejected.
+func (c *Client) refreshAfterUnauthorized(rejected string) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.oauth == nil {
+		return errors.New("token refresh not configured")
+	}
+	if c.token != rejected {
+		return nil
+	}
+	if time.Now().Before(c.refreshRetryAt) {
+		return errTokenRefreshBackoff
+	}
+	return c.refreshLocked()
+}
+
+// refreshLocked replaces the token pair through Rotate, or a local
+// exchange, and schedules the next attempt when no new pair was issued. tokenMu must be
+// held, so concurrent requests wait for a single refresh.
+func (c *Client) refreshLocked() error {
+	current := OAuthToken{AccessToken: c.token, RefreshToken: c.oauth.RefreshToken, ExpiresAt: c.oauth.ExpiresAt}
+	var refreshed OAuthToken
+	var err error
+	if c.oauth.Rotate != nil {
+		refreshed, err = c.oauth.Rotate(current, c.exchangeRefreshToken)
+	} else {
+		refreshed, err = c.exchangeRefreshToken(current.RefreshToken)
+	}
+	if refreshed.AccessToken != "" {
+		c.token = refreshed.AccessToken
+		c.oauth.RefreshToken = refreshed.RefreshToken
+		c.oauth.ExpiresAt = refreshed.ExpiresAt
+		if err != nil {
+			// Kommo already issued the pair, so the refresh worked; only
+			// saving it failed and another restart would need a new login.
+			log.Printf("[KOMMO] ALERT: refreshed token could not be saved, it is kept in memory only: %v", err)
+			err = nil
+		}
+	}
+	if err != nil {
+		c.refreshFailures++
+		c.refreshRetryAt = time.Now().Add(tokenRefreshBackoff(c.refreshFailures))
+		return err
+	}
+	c.refreshFailures = 0
+	c.refreshRetryAt = time.Time{}
+	return nil
+}
+
+// tokenRefreshBackoff is the wait after the given number of consecutive
+// refresh failures.
+func tokenRefreshBackoff(failures int) time.Duration {
+	wait := tokenRefreshBackoffMin
+	for i := 1; i < failures && wait < tokenRefreshBackoffMax; i++ {
+		wait *= 2
+	}
+	if wait > tokenRefreshBackoffMax {
+		wait = tokenRefreshBackoffMax
+	}
+	return wait
+}
+
+// exchangeRefreshToken trades refreshToken for a new token pair. tokenMu
+// must be held.
+func (c *Client) exchangeRefreshToken(refreshToken string) (OAuthToken, error) {
+	payload, err := json.Marshal(map[string]string{
+		"client_id":     c.oauth.ClientID,
+		"client_secret": c.oauth.ClientSecret,
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+		"redirect_uri":  c.oauth.RedirectURI,
+	})
+	if err != nil {
+		return OAuthToken{}, err
+	}
+	tokenURL := strings.TrimSuffix(c.baseURL, "/api/v4") + "/oauth2/access_token"
+	req, err := http.NewRequest("POST", tokenURL, bytes.NewReader(payload))
+	if err != nil {
+		return OAuthToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("kommo token refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OAuthToken{}, fmt.Errorf("kommo read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return OAuthToken{}, &APIError{Method: "POST", Path: "/oauth2/access_token", StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return OAuthToken{}, fmt.Errorf("kommo parse token response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return OAuthToken{}, errors.New("kommo token response has no access_token")
+	}
+
+	refreshed := OAuthToken{AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = refreshToken
+	}
+	if tokens.ExpiresIn > 0 {
+		refreshed.ExpiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+	return refreshed, nil
+}
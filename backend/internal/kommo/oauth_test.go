@@ -0,0 +1,208 @@
+package kommo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRefreshesTokenAfterUnauthorized(t *testing.T) {
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/access_token":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["grant_type"] != "refresh_token" || body["refresh_token"] != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			refreshes.Add(1)
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"refresh-2","expires_in":86400}`))
+		case "/api/v4/account":
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":1,"name":"Acme","subdomain":"acme"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("acme", "stale")
+	client.baseURL = server.URL + "/api/v4"
+	var persisted OAuthToken
+	client.EnableTokenRefresh(OAuthConfig{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		RefreshToken: "refresh-1",
+		Rotate: func(_ OAuthToken, exchange func(string) (OAuthToken, error)) (OAuthToken, error) {
+			token, err := exchange("refresh-1")
+			persisted = token
+			return token, err
+		},
+	})
+
+	account, err := client.GetAccount()
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if account.Name != "Acme" || refreshes.Load() != 1 {
+		t.Fatalf("account=%+v refreshes=%d", account, refreshes.Load())
+	}
+	if persisted.AccessToken != "fresh" || persisted.RefreshToken != "refresh-2" || time.Until(persisted.ExpiresAt) < time.Hour {
+		t.Fatalf("unexpected persisted token: %+v", persisted)
+	}
+}
+
+func TestClientRefreshesExpiringToken(t *testing.T) {
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			refreshes.Add(1)
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"refresh-2","expires_in":86400}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("acme", "old")
+	client.baseURL = server.URL + "/api/v4"
+	client.EnableTokenRefresh(OAuthConfig{ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh-1", ExpiresAt: time.Now().Add(time.Minute)})
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetAccount(); err != nil {
+			t.Fatalf("GetAccount: %v", err)
+		}
+	}
+	if refreshes.Load() != 1 || client.accessToken() != "fresh" {
+		t.Fatalf("refreshes=%d token=%q", refreshes.Load(), client.accessToken())
+	}
+}
+
+func TestClientWithoutRefreshKeepsUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient("acme", "static")
+	client.baseURL = server.URL + "/api/v4"
+	if _, err := client.GetAccount(); err == nil {
+		t.Fatal("expected a 401 error")
+	}
+}
+
+func TestClientAdoptsTokenRotatedByAnotherReplica(t *testing.T) {
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			refreshes.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer rotated" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("acme", "stale")
+	client.baseURL = server.URL + "/api/v4"
+	client.EnableTokenRefresh(OAuthConfig{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		RefreshToken: "refresh-1",
+		// The stored pair no longer matches: another replica spent refresh-1.
+		Rotate: func(current OAuthToken, _ func(string) (OAuthToken, error)) (OAuthToken, error) {
+			if current.RefreshToken != "refresh-1" {
+				t.Fatalf("current refresh token = %q", current.RefreshToken)
+			}
+			return OAuthToken{AccessToken: "rotated", RefreshToken: "refresh-2", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	})
+
+	if _, err := client.GetAccount(); err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if refreshes.Load() != 0 || client.accessToken() != "rotated" || client.oauth.RefreshToken != "refresh-2" {
+		t.Fatalf("refreshes=%d token=%q refresh=%q", refreshes.Load(), client.accessToken(), client.oauth.RefreshToken)
+	}
+}
+
+func TestClientBacksOffAfterFailedRefresh(t *testing.T) {
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			refreshes.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("acme", "old")
+	client.baseURL = server.URL + "/api/v4"
+	client.EnableTokenRefresh(OAuthConfig{ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh-1", ExpiresAt: time.Now().Add(time.Minute)})
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetAccount(); err != nil {
+			t.Fatalf("GetAccount: %v", err)
+		}
+	}
+	if refreshes.Load() != 1 {
+		t.Fatalf("refreshes = %d, want 1 while backing off", refreshes.Load())
+	}
+	if wait := time.Until(client.refreshRetryAt); wait <= 0 || wait > tokenRefreshBackoffMin {
+		t.Fatalf("retry in %v", wait)
+	}
+}
+
+func TestClientKeepsRefreshedTokenWhenSaveFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			// The rotator does the exchange.
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("acme", "old")
+	client.baseURL = server.URL + "/api/v4"
+	client.EnableTokenRefresh(OAuthConfig{
+		ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh-1", ExpiresAt: time.Now().Add(time.Minute),
+		Rotate: func(current OAuthToken, exchange func(string) (OAuthToken, error)) (OAuthToken, error) {
+			return OAuthToken{AccessToken: "fresh", RefreshToken: "refresh-2", ExpiresAt: time.Now().Add(time.Hour)}, errors.New("persist refreshed kommo token: connection reset")
+		},
+	})
+	if _, err := client.GetAccount(); err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if client.accessToken() != "fresh" || client.oauth.RefreshToken != "refresh-2" {
+		t.Fatalf("token=%q refresh=%q", client.accessToken(), client.oauth.RefreshToken)
+	}
+	if client.refreshFailures != 0 || !client.refreshRetryAt.IsZero() {
+		t.Fatalf("failures=%d retryAt=%v, want no backoff", client.refreshFailures, client.refreshRetryAt)
+	}
+}
+
+func TestTokenRefreshBackoffDoublesUpToMax(t *testing.T) {
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 15 * time.Minute, 15 * time.Minute}
+	for i, w := range want {
+		if got := tokenRefreshBackoff(i + 1); got != w {
+			t.Fatalf("tokenRefreshBackoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
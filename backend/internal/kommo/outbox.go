@@ -22,6 +22,7 @@ const (
 	OpLeadStageForced  = "lead_stage_forced" // payload carries status_id + pipeline_id (used when local lead is deleted)
 	OpLeadTags         = "lead_tags"
 	OpLeadCustomFields = "lead_custom_fields"
+	OpLeadMappedFields = "lead_mapped_fields" // payload carries the changed custom_fields keys; enqueued by LeadRepository.Update
 	OpContactName      = "contact_name"
 	OpContactTags      = "contact_tags"
 )
@@ -147,7 +148,7 @@ func (o *Outbox) flushAll() {
 	// Order matters: names before tags before stage (so a rename + retag lands
 	// both on the same Kommo lead without conflicting updated_at races, and
 	// stage changes stabilize last).
-	for _, op := range []string{OpLeadName, OpContactName, OpLeadTags, OpContactTags, OpLeadCustomFields, OpLeadStage, OpLeadStageForced} {
+	for _, op := range []string{OpLeadName, OpContactName, OpLeadTags, OpContactTags, OpLeadCustomFields, OpLeadMappedFields, OpLeadStage, OpLeadStageForced} {
 		for {
 			claimed, err := o.flushOnce(op)
 			if err != nil {
@@ -288,6 +289,8 @@ func (o *Outbox) flushOnce(op string) (int, error) {
 		o.flushContactTags(ctx, claimed)
 	case OpLeadCustomFields:
 		o.flushLeadCustomFields(ctx, claimed)
+	case OpLeadMappedFields:
+		o.flushLeadMappedFields(ctx, claimed)
 	default:
 		// Unknown operation — mark failed to avoid infinite retries.
 		for _, r := range claimed {
@@ -1029,6 +1032,73 @@ func (o *Outbox) flushLeadCustomFields(ctx context.Context, claimed []claimedRow
 	o.logBatch("lead_observations", "lead", fmt.Sprintf("Batch observations → Kommo (%d leads)", len(items)), "pushed", claimed, 1, startedAt, nil)
 }
 
+// flushLeadMappedFields pushes the changed, mapped leads.custom_fields keys
+// listed in each row's payload. Keys the lead no longer has clear the Kommo
+// field; unchanged keys are never sent, so Kommo-side values survive.
+func (o *Outbox) flushLeadMappedFields(ctx context.Context, claimed []claimedRow) {
+	startedAt := time.Now()
+	mappingsByAccount := make(map[uuid.UUID][]FieldMapping)
+	var entries []claimedRow
+	var items []map[string]interface{}
+	for _, r := range claimed {
+		var payload struct {
+			Keys []string `json:"keys"`
+		}
+		if err := json.Unmarshal(r.Payload, &payload); err != nil {
+			o.failRow(ctx, r.ID, "invalid payload: "+err.Error())
+			continue
+		}
+		mappings, ok := mappingsByAccount[r.AccountID]
+		if !ok {
+			var err error
+			if mappings, err = LoadFieldMappings(ctx, o.db, r.AccountID); err != nil {
+				o.failRow(ctx, r.ID, err.Error())
+				continue
+			}
+			mappingsByAccount[r.AccountID] = mappings
+		}
+		var values map[string]interface{}
+		if err := o.db.QueryRow(ctx, `SELECT COALESCE(custom_fields, '{}'::jsonb) FROM leads WHERE id = $1`, r.EntityID).Scan(&values); err != nil {
+			// Lead vanished — nothing to push.
+			o.completeRows(ctx, []uuid.UUID{r.ID})
+			continue
+		}
+		fields := mappedFieldWrites(r.EntityID, mappings, values, payload.Keys)
+		if len(fields) == 0 {
+			o.completeRows(ctx, []uuid.UUID{r.ID})
+			continue
+		}
+		entries = append(entries, r)
+		items = append(items, map[string]interface{}{
+			"id":                   r.KommoEntityID,
+			"custom_fields_values": fields,
+		})
+	}
+	if len(items) == 0 {
+		return
+	}
+	result, err := o.client.BatchUpdateLeads(items)
+	if err != nil {
+		log.Printf("[OUTBOX] BatchUpdateLeads (mapped fields, %d items) failed: %v", len(items), err)
+		o.failRowsBulk(ctx, entries, err.Error())
+		return
+	}
+	tsByKommoID := make(map[int64]int64, len(result))
+	for _, r := range result {
+		tsByKommoID[int64(r.ID)] = r.UpdatedAt
+	}
+	completed := make([]uuid.UUID, 0, len(entries))
+	for _, r := range entries {
+		if ts := tsByKommoID[r.KommoEntityID]; ts > 0 {
+			_, _ = o.db.Exec(ctx, `UPDATE leads SET kommo_last_pushed_at = $1 WHERE id = $2`, ts, r.EntityID)
+		}
+		completed = append(completed, r.ID)
+	}
+	o.completeRows(ctx, completed)
+	log.Printf("[OUTBOX] flushLeadMappedFields pushed %d leads in 1 PATCH", len(items))
+	o.logBatch(OpLeadMappedFields, "lead", fmt.Sprintf("Batch campos personalizados → Kommo (%d leads)", len(items)), "pushed", claimed, 1, startedAt, nil)
+}
+
 // interactionData mirrors the local struct used by PushLeadObservations.
 type interactionData struct {
 	id        uuid.UUID
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/naperu/clarin/internal/domain"
 	"github.com/naperu/clarin/internal/ws"
@@ -320,14 +321,32 @@ type WorkerStatus struct {
 
 // FullSyncStatus tracks the progress of a background full sync.
 type FullSyncStatus struct {
-	Running   bool        `json:"running"`
-	Progress  string      `json:"progress"`
+	Running  bool   `json:"running"`
+	Progress string `json:"progress"`
+	// Phase is one of the SyncPhase* labels. Done/Total count the steps of
+	// the phase (pipelines); Contacts/Leads are running totals.
+	Phase     string      `json:"phase,omitempty"`
+	Done      int         `json:"done"`
+	Total     int         `json:"total"`
+	Contacts  int         `json:"contacts"`
+	Leads     int         `json:"leads"`
 	Result    *SyncResult `json:"result,omitempty"`
 	Error     string      `json:"error,omitempty"`
 	StartedAt time.Time   `json:"started_at"`
 	DoneAt    *time.Time  `json:"done_at,omitempty"`
 }
 
+// Full sync phases, reported in FullSyncStatus and kommo_sync_progress events.
+const (
+	SyncPhasePipelines = "pipelines"
+	SyncPhaseTags      = "tags"
+	SyncPhaseContacts  = "contacts"
+	SyncPhaseLeads     = "leads"
+	SyncPhaseReconcile = "reconcile"
+	SyncPhaseDone      = "done"
+	SyncPhaseFailed    = "failed"
+)
+
 // SyncService handles one-way sync from Kommo → Clarin.
 type SyncService struct {
 	InstanceID   *uuid.UUID
@@ -507,50 +526,68 @@ func (s *SyncService) StartFullSyncAsync(accountID uuid.UUID) bool {
 	go func() {
 		ctx := context.Background()
 
-		// Update progress helper
-		setProgress := func(msg string) {
-			s.fullSyncMu.Lock()
-			if st, ok := s.fullSync[accountID]; ok {
-				st.Progress = msg
-			}
-			s.fullSyncMu.Unlock()
-		}
-
-		setProgress("Sincronizando pipelines y etapas...")
+		s.reportFullSync(accountID, func(st *FullSyncStatus) {
+			st.Phase, st.Progress = SyncPhasePipelines, "Sincronizando pipelines y etapas..."
+		})
+		startedAt := time.Now()
 		result, err := s.SyncAll(ctx, accountID)
 
 		if err != nil {
 			now := time.Now()
-			s.fullSyncMu.Lock()
-			if st, ok := s.fullSync[accountID]; ok {
+			s.reportFullSync(accountID, func(st *FullSyncStatus) {
 				st.Running = false
 				st.DoneAt = &now
 				st.Error = err.Error()
-			}
-			s.fullSyncMu.Unlock()
+				st.Phase = SyncPhaseFailed
+			})
 			log.Printf("[Kommo Sync] Background full sync failed for %s: %v", accountID, err)
 			return
 		}
 
 		// Run full reconciliation (stale + reverse) without batch limits
-		setProgress("Reconciliando leads (stale + faltantes)...")
+		s.reportFullSync(accountID, func(st *FullSyncStatus) {
+			st.Phase, st.Done, st.Total = SyncPhaseReconcile, 0, 0
+			st.Progress = "Reconciliando leads (stale + faltantes)..."
+		})
 		log.Printf("[Kommo Sync] Running full reconciliation (unlimited) for %s", accountID)
 		s.reconcileAccount(ctx, accountID, true)
 
+		// The full import covers every lead, so routine polls can resume from here.
+		if err := s.markSynced(ctx, accountID, startedAt, true); err != nil {
+			log.Printf("[Kommo Sync] Failed to save sync cursor for %s: %v", accountID, err)
+		}
+
 		now := time.Now()
-		s.fullSyncMu.Lock()
-		if st, ok := s.fullSync[accountID]; ok {
+		s.reportFullSync(accountID, func(st *FullSyncStatus) {
 			st.Running = false
 			st.DoneAt = &now
 			st.Result = result
+			st.Phase = SyncPhaseDone
 			st.Progress = "Completado"
-		}
-		s.fullSyncMu.Unlock()
+		})
 		log.Printf("[Kommo Sync] Background full sync completed for %s in %s", accountID, result.Duration)
 	}()
 	return true
 }
 
+// reportFullSync applies update to the account's full sync status and pushes
+// the new status to that account's websocket clients only. It is a no-op when
+// no full sync was started for the account.
+func (s *SyncService) reportFullSync(accountID uuid.UUID, update func(st *FullSyncStatus)) {
+	s.fullSyncMu.Lock()
+	st, ok := s.fullSync[accountID]
+	if !ok {
+		s.fullSyncMu.Unlock()
+		return
+	}
+	update(st)
+	snapshot := *st
+	s.fullSyncMu.Unlock()
+	if s.hub != nil {
+		s.hub.BroadcastToAccount(accountID, ws.EventKommoSyncProgress, snapshot)
+	}
+}
+
 // GetFullSyncStatus returns the current status of a full sync for the given account.
 func (s *SyncService) GetFullSyncStatus(accountID uuid.UUID) *FullSyncStatus {
 	s.fullSyncMu.RLock()
@@ -947,7 +984,7 @@ func (s *SyncService) GetStatus() WorkerStatus {
 
 // spawnAccountSync spawns a goroutine to sync an account if not already running.
 // Returns true if spawned, false if a sync for this account is already in progress (skip-if-busy).
-func (s *SyncService) spawnAccountSync(accountID uuid.UUID, updatedSince int64) bool {
+func (s *SyncService) spawnAccountSync(accountID uuid.UUID) bool {
 	s.busyMu.Lock()
 	if s.busyAccounts[accountID] {
 		s.busyMu.Unlock()
@@ -968,7 +1005,15 @@ func (s *SyncService) spawnAccountSync(accountID uuid.UUID, updatedSince int64)
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		count, err := s.syncGlobalLeads(ctx, accountID, updatedSince)
+		since, err := s.LastSyncedAt(ctx, accountID)
+		if err != nil {
+			log.Printf("[Kommo Sync] Account %s cursor error: %v", accountID, err)
+			return
+		}
+		if !since.IsZero() {
+			since = since.Add(-incrementalSyncBuffer)
+		}
+		count, err := s.SyncModifiedSince(ctx, accountID, since)
 		if err != nil {
 			if !strings.Contains(err.Error(), "204") && !strings.Contains(err.Error(), "No content") {
 				log.Printf("[Kommo Sync] Account %s sync error: %v", accountID, err)
@@ -977,10 +1022,6 @@ func (s *SyncService) spawnAccountSync(accountID uuid.UUID, updatedSince int64)
 			log.Printf("[Kommo Sync] Account %s: synced %d leads", accountID, count)
 		}
 
-		_, _ = s.db.Exec(context.Background(),
-			`UPDATE kommo_connected_pipelines SET last_synced_at = NOW() WHERE account_id = $1 AND enabled = TRUE AND integration_instance_id IS NOT DISTINCT FROM $2`,
-			accountID, s.instanceArg())
-
 		// Only trigger event reconciliation if leads were actually synced
 		// (avoids running expensive reconciliation every 5s when nothing changed)
 		if s.OnLeadTagsChanged != nil && count > 0 {
@@ -1024,12 +1065,10 @@ func (s *SyncService) pollConnectedPipelines() {
 	}
 	defer rows.Close()
 
-	// 2. Collect all account IDs first, then compute a GLOBAL cursor.
-	// Since all accounts share the same Kommo API/token, they all see the same leads.
-	// Using a per-account cursor based on local MAX(updated_at) is wrong because
-	// an account with newer local leads would skip leads that are new TO IT but
-	// older in Kommo. The global cursor = MIN across all accounts ensures every
-	// account sees every lead that changed since the slowest account's last sync.
+	// 2. Collect all account IDs first. Each account resumes from its own last
+	// successful sync (kommo_sync_state), not from local MAX(updated_at): an
+	// account with newer local leads would otherwise skip leads that are new TO
+	// IT but older in Kommo. The cursor only advances when a sync succeeds.
 	var accountIDs []uuid.UUID
 	for rows.Next() {
 		var accountID uuid.UUID
@@ -1044,26 +1083,9 @@ func (s *SyncService) pollConnectedPipelines() {
 		return
 	}
 
-	// Global cursor: the MINIMUM last_synced_at across all enabled pipelines.
-	// This guarantees every account gets every lead updated since the slowest sync.
-	var globalSince int64
-	_ = s.db.QueryRow(ctx, `
-		SELECT COALESCE(EXTRACT(EPOCH FROM MIN(last_synced_at))::bigint, 0)
-		FROM kommo_connected_pipelines
-		WHERE enabled = TRUE AND last_synced_at IS NOT NULL AND integration_instance_id IS NOT DISTINCT FROM $1
-	`, s.instanceArg()).Scan(&globalSince)
-
-	if globalSince > 0 {
-		// Subtract 5 minutes buffer for safety (API timestamp drift, slow processing)
-		globalSince = globalSince - 300
-		if globalSince < 0 {
-			globalSince = 0
-		}
-	}
-
 	count := 0
 	for _, accountID := range accountIDs {
-		s.spawnAccountSync(accountID, globalSince)
+		s.spawnAccountSync(accountID)
 		count++
 	}
 
@@ -1074,6 +1096,70 @@ func (s *SyncService) pollConnectedPipelines() {
 	s.mu.Unlock()
 }
 
+// incrementalSyncBuffer is subtracted from the stored cursor before asking
+// Kommo for modified leads (API timestamp drift, slow processing).
+const incrementalSyncBuffer = 5 * time.Minute
+
+// LastSyncedAt returns when leads were last synced successfully for the
+// account in this instance, or the zero time if they never were.
+func (s *SyncService) LastSyncedAt(ctx context.Context, accountID uuid.UUID) (time.Time, error) {
+	var last *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT last_synced_at FROM kommo_sync_state
+		WHERE account_id = $1 AND integration_instance_id IS NOT DISTINCT FROM $2
+	`, accountID, s.instanceArg()).Scan(&last)
+	if err == pgx.ErrNoRows || (err == nil && last == nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *last, nil
+}
+
+// markSynced stores syncedAt as the account's incremental cursor. It also
+// stamps the enabled connected pipelines so the pipeline list shows it.
+func (s *SyncService) markSynced(ctx context.Context, accountID uuid.UUID, syncedAt time.Time, full bool) error {
+	var fullAt *time.Time
+	if full {
+		fullAt = &syncedAt
+	}
+	if _, err := s.db.Exec(ctx, `
+		INSERT INTO kommo_sync_state (integration_instance_id, account_id, last_synced_at, last_full_sync_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (COALESCE(integration_instance_id, '00000000-0000-0000-0000-000000000000'::uuid), account_id) DO UPDATE SET
+			last_synced_at = GREATEST(kommo_sync_state.last_synced_at, EXCLUDED.last_synced_at),
+			last_full_sync_at = COALESCE(EXCLUDED.last_full_sync_at, kommo_sync_state.last_full_sync_at),
+			updated_at = NOW()
+	`, s.instanceArg(), accountID, syncedAt, fullAt); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(ctx,
+		`UPDATE kommo_connected_pipelines SET last_synced_at = $3 WHERE account_id = $1 AND enabled = TRUE AND integration_instance_id IS NOT DISTINCT FROM $2`,
+		accountID, s.instanceArg(), syncedAt)
+	return err
+}
+
+// SyncModifiedSince pulls only the Kommo leads modified since the given time
+// (all leads when since is zero) and, on success, advances the account's
+// cursor to the moment the sync started, so changes made while it ran are
+// picked up next time. A failed sync leaves the cursor untouched.
+func (s *SyncService) SyncModifiedSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error) {
+	startedAt := time.Now()
+	var updatedSince int64
+	if !since.IsZero() {
+		updatedSince = since.Unix()
+	}
+	count, err := s.syncGlobalLeads(ctx, accountID, updatedSince)
+	if err != nil {
+		return count, err
+	}
+	if err := s.markSynced(ctx, accountID, startedAt, false); err != nil {
+		return count, fmt.Errorf("save sync cursor: %w", err)
+	}
+	return count, nil
+}
+
 // --- Connected Pipeline Management ---
 
 // GetConnectedPipelines returns all connected pipelines for an account.
@@ -1141,7 +1227,7 @@ func (s *SyncService) ConnectPipeline(ctx context.Context, accountID uuid.UUID,
 	go func() {
 		bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 		defer cancel()
-		count, err := s.syncPipelineLeads(bgCtx, accountID, kommoPipelineID, 0)
+		count, err := s.syncPipelineLeads(bgCtx, accountID, kommoPipelineID, 0, nil)
 		if err != nil {
 			log.Printf("[Kommo Sync] Initial sync error for pipeline %d: %v", kommoPipelineID, err)
 		} else {
@@ -1175,13 +1261,11 @@ func (s *SyncService) SyncAll(ctx context.Context, accountID uuid.UUID) (*SyncRe
 	start := time.Now()
 	result := &SyncResult{}
 
-	// Progress update helper
-	setProgress := func(msg string) {
-		s.fullSyncMu.Lock()
-		if st, ok := s.fullSync[accountID]; ok {
-			st.Progress = msg
-		}
-		s.fullSyncMu.Unlock()
+	// Progress update helper (pushed live over the websocket)
+	setProgress := func(phase, msg string, done, total int) {
+		s.reportFullSync(accountID, func(st *FullSyncStatus) {
+			st.Phase, st.Progress, st.Done, st.Total = phase, msg, done, total
+		})
 	}
 
 	// Get connected pipelines - only sync those
@@ -1199,7 +1283,7 @@ func (s *SyncService) SyncAll(ctx context.Context, accountID uuid.UUID) (*SyncRe
 	}
 
 	// Sync pipeline metadata + stages with a SINGLE API call to Kommo
-	setProgress(fmt.Sprintf("Sincronizando %d pipeline(s)...", len(activePipelines)))
+	setProgress(SyncPhasePipelines, fmt.Sprintf("Sincronizando %d pipeline(s)...", len(activePipelines)), 0, len(activePipelines))
 	log.Printf("[SYNC] Account %s: syncing %d pipeline metadata...", accountID, len(activePipelines))
 	kommoPipelines, err := s.client.GetPipelines()
 	if err != nil {
@@ -1215,6 +1299,7 @@ func (s *SyncService) SyncAll(ctx context.Context, accountID uuid.UUID) (*SyncRe
 			if kp, ok := kpMap[int(cp.KommoPipelineID)]; ok {
 				_, _ = s.ensurePipelineForAccount(ctx, accountID, kp)
 				pCount++
+				setProgress(SyncPhasePipelines, fmt.Sprintf("Sincronizados %d de %d pipeline(s)", pCount, len(activePipelines)), pCount, len(activePipelines))
 			}
 		}
 		result.Pipelines = pCount
@@ -1222,7 +1307,7 @@ func (s *SyncService) SyncAll(ctx context.Context, accountID uuid.UUID) (*SyncRe
 
 	// Only sync tags if the account has at least one active pipeline.
 	if len(activePipelines) > 0 {
-		setProgress("Sincronizando etiquetas...")
+		setProgress(SyncPhaseTags, "Sincronizando etiquetas...", 0, 0)
 		tCount, err := s.syncTags(ctx, accountID)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("tags: %v", err))
@@ -1230,7 +1315,7 @@ func (s *SyncService) SyncAll(ctx context.Context, accountID uuid.UUID) (*SyncRe
 		result.Tags = tCount
 
 		// Push Clarin-only tags to Kommo (bidirectional sync)
-		setProgress("Sincronizando etiquetas locales a Kommo...")
+		setProgress(SyncPhaseTags, "Sincronizando etiquetas locales a Kommo...", 0, 0)
 		pushedTags, pushErr := s.pushMissingTagsToKommo(ctx, accountID)
 		if pushErr != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("push tags: %v", pushErr))
@@ -1252,10 +1337,26 @@ func (s *SyncService) SyncAll(ctx context.Context, accountID uuid.UUID) (*SyncRe
 			pName = pn
 		}
 		progress := fmt.Sprintf("Sincronizando leads: %s (%d/%d)...", pName, i+1, len(activePipelines))
-		setProgress(progress)
+		setProgress(SyncPhaseLeads, progress, i, len(activePipelines))
 		log.Printf("[SYNC] Account %s: %s", accountID, progress)
 
-		count, err := s.syncPipelineLeads(ctx, accountID, int(cp.KommoPipelineID), 0)
+		// Each page first downloads its contacts, then upserts its leads.
+		done := i
+		onPage := func(contacts, leads int) {
+			s.reportFullSync(accountID, func(st *FullSyncStatus) {
+				st.Done, st.Total = done, len(activePipelines)
+				if leads == 0 {
+					st.Contacts += contacts
+					st.Phase = SyncPhaseContacts
+					st.Progress = fmt.Sprintf("Descargando contactos: %s (%d/%d) — %d contactos", pName, done+1, len(activePipelines), st.Contacts)
+					return
+				}
+				st.Leads += leads
+				st.Phase = SyncPhaseLeads
+				st.Progress = fmt.Sprintf("Sincronizando leads: %s (%d/%d) — %d leads", pName, done+1, len(activePipelines), st.Leads)
+			})
+		}
+		count, err := s.syncPipelineLeads(ctx, accountID, int(cp.KommoPipelineID), 0, onPage)
 		if err != nil {
 			log.Printf("[SYNC] Account %s: pipeline %s error: %v", accountID, pName, err)
 			result.Errors = append(result.Errors, fmt.Sprintf("leads %s: %v", pName, err))
@@ -1267,6 +1368,7 @@ func (s *SyncService) SyncAll(ctx context.Context, accountID uuid.UUID) (*SyncRe
 		}
 	}
 	result.Leads = lCount
+	setProgress(SyncPhaseLeads, fmt.Sprintf("Sincronizados %d de %d pipeline(s)", len(activePipelines), len(activePipelines)), len(activePipelines), len(activePipelines))
 
 	// Count contacts that were synced (those with kommo_id linked to this account)
 	var contactCount int
@@ -1491,7 +1593,9 @@ func (s *SyncService) syncUnsortedLeads(ctx context.Context, accountID uuid.UUID
 
 // syncPipelineLeads syncs leads for a specific pipeline.
 // NOTE: Now mainly used when user manually triggers a pipeline sync or on initial connection.
-func (s *SyncService) syncPipelineLeads(ctx context.Context, accountID uuid.UUID, kommoPipelineID int, updatedSince int64) (int, error) {
+// onPage, when set, is called after each page with the contacts downloaded
+// (leads == 0) and then with the leads upserted.
+func (s *SyncService) syncPipelineLeads(ctx context.Context, accountID uuid.UUID, kommoPipelineID int, updatedSince int64, onPage func(contacts, leads int)) (int, error) {
 	// If kommoPipelineID is 0, treat as Global Sync
 	if kommoPipelineID == 0 {
 		return s.syncGlobalLeads(ctx, accountID, updatedSince)
@@ -1511,7 +1615,11 @@ func (s *SyncService) syncPipelineLeads(ctx context.Context, accountID uuid.UUID
 
 		// Batch-fetch contacts for this page of leads
 		contactMap := s.batchFetchContacts(leads)
+		if onPage != nil {
+			onPage(len(contactMap), 0)
+		}
 
+		pageCount := 0
 		for _, kl := range leads {
 			var prefetched *KommoContact
 			if kl.Embedded != nil && len(kl.Embedded.Contacts) > 0 {
@@ -1523,7 +1631,11 @@ func (s *SyncService) syncPipelineLeads(ctx context.Context, accountID uuid.UUID
 				log.Printf("[Kommo Sync] lead %d error: %v", kl.ID, err)
 				continue
 			}
-			count++
+			pageCount++
+		}
+		count += pageCount
+		if onPage != nil && pageCount > 0 {
+			onPage(0, pageCount)
 		}
 
 		if !hasMore || len(leads) == 0 {
@@ -1606,11 +1718,11 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 	// Contact globally (DNC is an orthogonal, explicit contactability decision).
 	if statusKommoID == 142 || statusKommoID == 143 {
 		var existingLeadID uuid.UUID
-		var existingPipelineID *uuid.UUID
+		var existingPipelineID, existingStageID *uuid.UUID
 		var currentStatus string
 		err := s.db.QueryRow(ctx,
-			`SELECT id, pipeline_id, status FROM leads WHERE account_id = $1 AND kommo_id = $2`,
-			accountID, kommoID).Scan(&existingLeadID, &existingPipelineID, &currentStatus)
+			`SELECT id, pipeline_id, stage_id, status FROM leads WHERE account_id = $1 AND kommo_id = $2`,
+			accountID, kommoID).Scan(&existingLeadID, &existingPipelineID, &existingStageID, &currentStatus)
 		if err != nil {
 			// Lead doesn't exist in Clarin → skip, don't import won/lost
 			return false, nil
@@ -1626,6 +1738,10 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 		if currentStatus == targetStatus {
 			return false, nil
 		}
+		if s.keepClarinLead(ctx, existingLeadID, kl.UpdatedAt) {
+			log.Printf("[Kommo Sync] Lead %s (Kommo %d) %s in Kommo ignored: Clarin edit wins the conflict", existingLeadID, kommoID, statusLabel)
+			return false, nil
+		}
 		targetPipelineID := pipelineID
 		if targetPipelineID == nil {
 			targetPipelineID = existingPipelineID
@@ -1639,12 +1755,14 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 				terminalStageID = &sid
 			}
 		}
-		_, _ = s.db.Exec(ctx, `
+		if _, updErr := s.db.Exec(ctx, `
 			UPDATE leads SET status=$2, pipeline_id=COALESCE($3,pipeline_id), stage_id=$4,
 				closed_at=COALESCE(closed_at,NOW()), close_reason=COALESCE(NULLIF(close_reason,''),$5),
-				is_blocked=FALSE, blocked_at=NULL, block_reason='', kommo_deleted_at=NULL, updated_at=NOW()
+				is_blocked=FALSE, blocked_at=NULL, block_reason='', kommo_deleted_at=NULL, updated_at=NOW(), kommo_pulled_at=NOW()
 			WHERE id = $1
-		`, existingLeadID, targetStatus, targetPipelineID, terminalStageID, closeReason)
+		`, existingLeadID, targetStatus, targetPipelineID, terminalStageID, closeReason); updErr == nil {
+			s.recordStageChange(ctx, accountID, existingLeadID, existingStageID, terminalStageID)
+		}
 		// Create observation explaining what happened
 		obsNotes := fmt.Sprintf("Oportunidad marcada como %s en Kommo. Se registró como cierre comercial en Clarin; la preferencia de contacto no fue modificada.", statusLabel)
 		_, _ = s.db.Exec(ctx, `
@@ -1732,6 +1850,8 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 			ON CONFLICT (account_id, jid) DO UPDATE SET
 				phone = COALESCE(NULLIF(EXCLUDED.phone, ''), contacts.phone),
 				name = COALESCE(NULLIF(EXCLUDED.name, ''), contacts.name),
+				deleted_at = NULL,
+				deleted_by = NULL,
 				updated_at = NOW()
 			RETURNING id
 		`, accountID, jid, normalizePhone(phone), cleanQuotes(kl.Name)).Scan(&cid); err == nil {
@@ -1800,18 +1920,20 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 		}
 		_, err = s.db.Exec(ctx, `
 			INSERT INTO leads (id, account_id, contact_id, title, jid, name, status, source,
-					pipeline_id, stage_id, tags, kommo_synced_tags, kommo_id, closed_at, close_reason, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, 'kommo', $8, $9, $10, $10, $11, $12, $13, NOW(), NOW())
+					pipeline_id, stage_id, tags, kommo_synced_tags, kommo_id, closed_at, close_reason, created_at, updated_at, kommo_pulled_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, 'kommo', $8, $9, $10, $10, $11, $12, $13, NOW(), NOW(), NOW())
 		`, leadID, accountID, contactID, title, jid,
 			nilIfEmpty(cleanQuotes(kl.Name)), status, pipelineID, stageID, tagNames, kommoID, closedAt, closeReason)
 		if err != nil {
 			return false, err
 		}
-		// New lead inserted — sync tags and calls
+		s.recordStageChange(ctx, accountID, leadID, nil, stageID)
+		// New lead inserted — sync tags, calls and mapped custom fields
 		if len(tagNames) > 0 {
 			s.syncLeadTags(ctx, accountID, leadID, tagNames)
 		}
 		s.syncCallsFromKommo(ctx, accountID, leadID, contactID, kl.CustomFields)
+		s.pullMappedCustomFields(ctx, accountID, leadID, kl.CustomFields)
 		return true, nil
 	}
 
@@ -1832,10 +1954,14 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 		var curPipelineID *uuid.UUID
 		_ = s.db.QueryRow(ctx, `SELECT pipeline_id FROM leads WHERE id = $1`, leadID).Scan(&curPipelineID)
 		if curPipelineID != nil {
+			if s.keepClarinLead(ctx, leadID, kl.UpdatedAt) {
+				log.Printf("[Kommo Sync] Lead %s (Kommo %d) move to non-synced pipeline %d ignored: Clarin edit wins the conflict", leadID, kommoID, pipelineKommoID)
+				return false, nil
+			}
 			_, _ = s.db.Exec(ctx, `
 				UPDATE leads
 				SET pipeline_id = NULL, stage_id = NULL, status = 'open',
-					closed_at = NULL, close_reason = '', updated_at = NOW()
+					closed_at = NULL, close_reason = '', updated_at = NOW(), kommo_pulled_at = NOW()
 				WHERE id = $1
 			`, leadID)
 			log.Printf("[Kommo Sync] Lead %s (Kommo %d) moved to non-synced pipeline %d in Kommo → removed from Clarin pipeline", leadID, kommoID, pipelineKommoID)
@@ -1899,6 +2025,17 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 	leadName := nilIfEmpty(cleanQuotes(kl.Name))
 	leadNameSame := leadName == nil || (curLeadName != nil && *curLeadName == *leadName)
 
+	// Conflict resolution: when the account policy keeps Clarin's edit, the
+	// conflict fields stay as they are and only the rest is synced.
+	applyKommo := true
+	if foundByKommoID && !(pipelineSame && stageSame && leadNameSame && curStatus == status) && s.keepClarinLead(ctx, leadID, kl.UpdatedAt) {
+		log.Printf("[Kommo Sync] Lead %s (Kommo %d): Clarin edit wins the conflict, keeping local name/pipeline/stage/status", leadID, kommoID)
+		applyKommo = false
+		leadName, pipelineID, stageID = nil, curPipelineID, curStageID
+		status, closedAt, closeReason = curStatus, curClosedAt, curCloseReason
+		pipelineSame, stageSame, leadNameSame = true, true, true
+	}
+
 	// Ensure tagNames is never nil (nil would be stored as SQL NULL)
 	if tagNames == nil {
 		tagNames = []string{}
@@ -1907,6 +2044,9 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 	if foundByKommoID && pipelineSame && stageSame && tagsSame && leadNameSame && curStatus == status && !contactChanged {
 		// Lead fields unchanged — still reconcile contact_tags junction (may be out of sync)
 		s.syncLeadTags(ctx, accountID, leadID, tagNames)
+		if applyKommo {
+			return s.pullMappedCustomFields(ctx, accountID, leadID, kl.CustomFields), nil
+		}
 		return false, nil
 	}
 
@@ -1923,9 +2063,10 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 				close_reason = $7,
 				tags = $8,
 				kommo_deleted_at = NULL,
-				updated_at = NOW()
+				updated_at = NOW(),
+				kommo_pulled_at = CASE WHEN $10 THEN NOW() ELSE kommo_pulled_at END
 			WHERE id = $9
-		`, leadName, contactID, pipelineID, stageID, status, closedAt, closeReason, tagNames, leadID)
+		`, leadName, contactID, pipelineID, stageID, status, closedAt, closeReason, tagNames, leadID, applyKommo)
 	} else {
 		// First-time linking (found by JID) — Clarin keeps name/phone/email,
 		// only link kommo_id and sync CRM fields (pipeline, stage, tags)
@@ -1941,7 +2082,8 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 				close_reason = $8,
 				tags = $9,
 				kommo_deleted_at = NULL,
-				updated_at = NOW()
+				updated_at = NOW(),
+				kommo_pulled_at = NOW()
 			WHERE id = $10
 		`, kommoID, leadName, contactID, pipelineID, stageID, status, closedAt, closeReason, tagNames, leadID)
 		log.Printf("[Kommo Sync] Linked existing Clarin lead %s to Kommo ID %d (preserved Clarin name/phone/email)", leadID, kommoID)
@@ -1949,16 +2091,37 @@ func (s *SyncService) upsertLead(ctx context.Context, accountID uuid.UUID, kl Ko
 	if err != nil {
 		return false, err
 	}
+	s.recordStageChange(ctx, accountID, leadID, curStageID, stageID)
 
 	// Sync contact_tags junction table (always call — even with empty tagNames to clean up removed tags)
 	s.syncLeadTags(ctx, accountID, leadID, tagNames)
 
 	// Sync call observations from Kommo custom fields → Clarin interactions
 	s.syncCallsFromKommo(ctx, accountID, leadID, contactID, kl.CustomFields)
+	if applyKommo {
+		s.pullMappedCustomFields(ctx, accountID, leadID, kl.CustomFields)
+	}
 
 	return true, nil
 }
 
+// recordStageChange adds a stage move pulled from Kommo to the lead's
+// stage-change history, with no author. Unchanged or cleared stages are
+// not recorded.
+func (s *SyncService) recordStageChange(ctx context.Context, accountID, leadID uuid.UUID, fromStageID, toStageID *uuid.UUID) {
+	if toStageID == nil || (fromStageID != nil && *fromStageID == *toStageID) {
+		return
+	}
+	if _, err := s.db.Exec(ctx, `
+		INSERT INTO lead_stage_changes (account_id, lead_id, from_stage_id, to_stage_id, from_stage_name, to_stage_name)
+		VALUES ($1, $2, $3, $4,
+		        COALESCE((SELECT name FROM pipeline_stages WHERE id = $3), ''),
+		        COALESCE((SELECT name FROM pipeline_stages WHERE id = $4), ''))
+	`, accountID, leadID, fromStageID, *toStageID); err != nil {
+		log.Printf("[Kommo Sync] Failed to record stage change for lead %s: %v", leadID, err)
+	}
+}
+
 // syncCallsFromKommo reads the 10 call slots from Kommo custom fields and upserts
 // them as type=call interactions in Clarin. Uses kommo_call_slot for dedup.
 func (s *SyncService) syncCallsFromKommo(ctx context.Context, accountID, leadID uuid.UUID, contactID *uuid.UUID, fields []KommoCustomField) {
@@ -3257,6 +3420,56 @@ func (s *SyncService) EnqueuePushLeadTags(accountID, leadID uuid.UUID) {
 	}
 }
 
+// EnqueuePushLeadsTags is the bulk form of EnqueuePushLeadTags: the Kommo ids
+// of all leads are read in one query and each linked lead is queued, so the
+// outbox flushes them together.
+func (s *SyncService) EnqueuePushLeadsTags(accountID uuid.UUID, leadIDs []uuid.UUID) {
+	if len(leadIDs) == 0 {
+		return
+	}
+	if s.Outbox == nil {
+		go func() {
+			for _, leadID := range leadIDs {
+				s.PushLeadTagsChange(accountID, leadID)
+			}
+		}()
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if !s.isKommoEnabled(ctx, accountID) {
+		return
+	}
+	rows, err := s.db.Query(ctx, `
+		SELECT id, kommo_id FROM leads
+		WHERE id = ANY($1::uuid[]) AND account_id = $2 AND kommo_id IS NOT NULL AND kommo_id <> 0
+	`, leadIDs, accountID)
+	if err != nil {
+		log.Printf("[OUTBOX] EnqueuePushLeadsTags account=%s: %v", accountID, err)
+		return
+	}
+	type linkedLead struct {
+		id      uuid.UUID
+		kommoID int64
+	}
+	var linked []linkedLead
+	for rows.Next() {
+		var l linkedLead
+		if err := rows.Scan(&l.id, &l.kommoID); err != nil {
+			rows.Close()
+			log.Printf("[OUTBOX] EnqueuePushLeadsTags account=%s: %v", accountID, err)
+			return
+		}
+		linked = append(linked, l)
+	}
+	rows.Close()
+	for _, l := range linked {
+		if err := s.Outbox.Enqueue(ctx, accountID, l.id, l.kommoID, OpLeadTags, nil); err != nil {
+			log.Printf("[OUTBOX] EnqueuePushLeadsTags lead=%s: %v", l.id, err)
+		}
+	}
+}
+
 // EnqueuePushLeadObservations coalesces the observations (custom-fields calls) push.
 func (s *SyncService) EnqueuePushLeadObservations(accountID, leadID uuid.UUID) {
 	if s.Outbox == nil {
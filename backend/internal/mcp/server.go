@@ -562,14 +562,7 @@ func (s *MCPServer) authenticatedMCPHandler(next http.Handler, transport string)
 			return
 		}
 
-		keyHash := hashKey(token)
-		client, err := s.repos.MCP.GetClientByOAuthTokenHash(r.Context(), keyHash, mcpResourceURI)
-		if err != nil {
-			log.Printf("[MCP] oauth token lookup error: %v", err)
-		}
-		if client == nil {
-			client, err = s.repos.MCP.GetClientByTokenHash(r.Context(), keyHash)
-		}
+		client, err := ClientForToken(r.Context(), s.repos, token)
 		if err != nil || client == nil {
 			setMCPAuthChallenge(w, "invalid_token")
 			s.recordAuthFailure(r, "invalid_token", nil)
@@ -937,6 +930,21 @@ func (s *MCPServer) allowMCPRequest(remote string) bool {
 	return true
 }
 
+// ClientForToken resolves the MCP client behind a bearer token, either an
+// OAuth access token or the client's static token. It returns nil when the
+// token matches neither; the caller still has to check the client status.
+func ClientForToken(ctx context.Context, repos *repository.Repositories, token string) (*domain.MCPClient, error) {
+	keyHash := hashKey(token)
+	client, err := repos.MCP.GetClientByOAuthTokenHash(ctx, keyHash, mcpResourceURI)
+	if err != nil {
+		log.Printf("[MCP] oauth token lookup error: %v", err)
+	}
+	if client == nil {
+		client, err = repos.MCP.GetClientByTokenHash(ctx, keyHash)
+	}
+	return client, err
+}
+
 func hashKey(key string) string {
 	h := sha256.Sum256([]byte(key))
 	return hex.EncodeToString(h[:])
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// AccountAnonymizeOptions controls what Anonymize rewrites besides the
+// always-scrubbed contact/lead identity fields.
+type AccountAnonymizeOptions struct {
+	ScrubMessages bool
+}
+
+// AccountAnonymizeResult reports how many rows each step rewrote.
+type AccountAnonymizeResult struct {
+	Contacts         int64 `json:"contacts"`
+	Leads            int64 `json:"leads"`
+	Chats            int64 `json:"chats"`
+	Participants     int64 `json:"participants"`
+	Recipients       int64 `json:"recipients"`
+	Messages         int64 `json:"messages"`
+	AliasesDeleted   int64 `json:"aliases_deleted"`
+	MessagesScrubbed bool  `json:"messages_scrubbed"`
+}
+
+type anonymizeStep struct {
+	name  string
+	query string
+}
+
+// anonymizedPhoneExpr is the SQL expression for a contact's fake phone. The
+// 51999 prefix keeps fake numbers in one recognizable, sequential block.
+const anonymizedPhoneExpr = `'51999' || lpad(m.n::text, 7, '0')`
+
+// accountAnonymizeSteps returns the ordered statements for Anonymize. Every
+// statement is scoped by $1 = account_id. Fake values are derived from a
+// per-contact sequence so joins (contact ↔ chat ↔ lead ↔ recipient) survive.
+func accountAnonymizeSteps(opts AccountAnonymizeOptions) []anonymizeStep {
+	steps := []anonymizeStep{
+		{"", `INSERT INTO anon_contacts (id, old_jid, n)
+			SELECT id, jid, row_number() OVER (ORDER BY created_at, id)
+			FROM contacts WHERE account_id = $1 AND COALESCE(is_group, FALSE) = FALSE`},
+		{"contacts", `UPDATE contacts c SET
+			name = 'Contacto ' || m.n, last_name = 'Demo', short_name = 'Contacto ' || m.n,
+			custom_name = NULL, push_name = NULL,
+			phone = ` + anonymizedPhoneExpr + `,
+			jid = ` + anonymizedPhoneExpr + ` || '@s.whatsapp.net',
+			email = CASE WHEN COALESCE(c.email, '') = '' THEN c.email ELSE 'contacto' || m.n || '@demo.invalid' END,
+			company = CASE WHEN COALESCE(c.company, '') = '' THEN c.company ELSE 'Empresa ' || m.n END,
+			dni = NULL, address = NULL, birth_date = NULL, notes = NULL,
+			avatar_url = NULL, kommo_id = NULL, google_sync = FALSE, google_resource_name = NULL,
+			updated_at = NOW()
+			FROM anon_contacts m WHERE c.id = m.id AND c.account_id = $1`},
+		{"aliases_deleted", `DELETE FROM contact_aliases WHERE account_id = $1`},
+		{"leads", `UPDATE leads l SET
+			name = c.name, last_name = c.last_name, short_name = c.short_name,
+			phone = c.phone, email = c.email, company = c.company,
+			jid = CASE WHEN l.jid = m.old_jid THEN c.jid ELSE l.jid END,
+			dni = NULL, address = NULL, birth_date = NULL, notes = NULL, kommo_id = NULL,
+			updated_at = NOW()
+			FROM contacts c JOIN anon_contacts m ON m.id = c.id
+			WHERE l.account_id = $1 AND l.contact_id = c.id`},
+		{"leads", `UPDATE leads SET
+			name = 'Lead demo', last_name = NULL, short_name = NULL, phone = NULL, email = NULL,
+			dni = NULL, address = NULL, birth_date = NULL, notes = NULL, kommo_id = NULL,
+			updated_at = NOW()
+			WHERE account_id = $1 AND contact_id IS NULL`},
+		{"chats", `UPDATE chats ch SET
+			jid = CASE WHEN ch.jid = m.old_jid THEN c.jid ELSE ch.jid END,
+			name = c.name, updated_at = NOW()
+			FROM contacts c JOIN anon_contacts m ON m.id = c.id
+			WHERE ch.account_id = $1 AND ch.contact_id = c.id`},
+		{"participants", `UPDATE event_participants ep SET
+			name = c.name, last_name = c.last_name, short_name = c.short_name,
+			phone = c.phone, email = c.email, company = c.company,
+			dni = NULL, address = NULL, birth_date = NULL, notes = NULL
+			FROM contacts c
+			WHERE ep.contact_id = c.id AND c.account_id = $1`},
+		{"recipients", `UPDATE campaign_recipients cr SET
+			name = c.name, phone = c.phone, jid = c.jid, metadata = '{}'::jsonb
+			FROM contacts c
+			WHERE cr.contact_id = c.id AND c.account_id = $1`},
+		{"messages", `UPDATE messages msg SET
+			from_jid = ` + anonymizedPhoneExpr + ` || '@s.whatsapp.net',
+			from_name = NULL
+			FROM anon_contacts m
+			WHERE msg.account_id = $1 AND msg.from_jid = m.old_jid`},
+		{"", `UPDATE messages SET contact_name = 'Contacto compartido', contact_phone = NULL, contact_vcard = NULL
+			WHERE account_id = $1 AND (contact_phone IS NOT NULL OR contact_vcard IS NOT NULL)`},
+	}
+	if opts.ScrubMessages {
+		steps = append(steps,
+			anonymizeStep{"", `UPDATE messages SET
+				body = CASE WHEN COALESCE(body, '') = '' THEN body ELSE '[mensaje anonimizado]' END,
+				quoted_body = CASE WHEN COALESCE(quoted_body, '') = '' THEN quoted_body ELSE '[mensaje anonimizado]' END,
+				poll_question = CASE WHEN COALESCE(poll_question, '') = '' THEN poll_question ELSE '[encuesta anonimizada]' END,
+				media_url = NULL, media_filename = NULL
+				WHERE account_id = $1`},
+			anonymizeStep{"", `UPDATE chats SET last_message = '[mensaje anonimizado]'
+				WHERE account_id = $1 AND COALESCE(last_message, '') <> ''`},
+		)
+	}
+	return steps
+}
+
+// Anonymize irreversibly replaces personal data in an account with generated
+// fakes, keeping row counts, pipeline stages and tag links intact. All steps
+// run in a single transaction.
+func (r *AccountRepository) Anonymize(ctx context.Context, accountID uuid.UUID, opts AccountAnonymizeOptions) (*AccountAnonymizeResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Utility statements cannot take bind parameters, so the mapping table is
+	// created on its own and filled by the first step.
+	if _, err := tx.Exec(ctx, `CREATE TEMP TABLE anon_contacts (id UUID PRIMARY KEY, old_jid TEXT NOT NULL, n BIGINT NOT NULL) ON COMMIT DROP`); err != nil {
+		return nil, err
+	}
+
+	result := &AccountAnonymizeResult{MessagesScrubbed: opts.ScrubMessages}
+	counters := map[string]*int64{
+		"contacts":        &result.Contacts,
+		"leads":           &result.Leads,
+		"chats":           &result.Chats,
+		"participants":    &result.Participants,
+		"recipients":      &result.Recipients,
+		"messages":        &result.Messages,
+		"aliases_deleted": &result.AliasesDeleted,
+	}
+	for i, step := range accountAnonymizeSteps(opts) {
+		tag, err := tx.Exec(ctx, step.query, accountID)
+		if err != nil {
+			return nil, fmt.Errorf("anonymize step %d (%s): %w", i, step.name, err)
+		}
+		if counter := counters[step.name]; counter != nil {
+			*counter += tag.RowsAffected()
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
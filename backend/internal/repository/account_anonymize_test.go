@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAccountAnonymizeStepsAreAccountScoped(t *testing.T) {
+	for _, step := range accountAnonymizeSteps(AccountAnonymizeOptions{ScrubMessages: true}) {
+		if !strings.Contains(step.query, "$1") {
+			t.Fatalf("step %q is not scoped by account_id:\n%s", step.name, step.query)
+		}
+	}
+}
+
+func TestAccountAnonymizeScrubsBodiesOnlyWhenRequested(t *testing.T) {
+	scrubs := func(opts AccountAnonymizeOptions) bool {
+		for _, step := range accountAnonymizeSteps(opts) {
+			if strings.Contains(step.query, "[mensaje anonimizado]") {
+				return true
+			}
+		}
+		return false
+	}
+	if scrubs(AccountAnonymizeOptions{}) {
+		t.Fatal("message bodies scrubbed without ScrubMessages")
+	}
+	if !scrubs(AccountAnonymizeOptions{ScrubMessages: true}) {
+		t.Fatal("message bodies not scrubbed with ScrubMessages")
+	}
+}
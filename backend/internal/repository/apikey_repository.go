@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/naperu/clarin/internal/domain"
 )
@@ -15,15 +16,15 @@ type APIKeyRepository struct {
 
 func (r *APIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
 	_, err := r.db.Exec(ctx, `
-		INSERT INTO api_keys (id, account_id, name, key_hash, key_prefix, permissions, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, key.ID, key.AccountID, key.Name, key.KeyHash, key.KeyPrefix, key.Permissions, key.IsActive, key.CreatedAt, key.UpdatedAt)
+		INSERT INTO api_keys (id, account_id, name, key_hash, key_prefix, permissions, role, role_id, created_by, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`, key.ID, key.AccountID, key.Name, key.KeyHash, key.KeyPrefix, key.Permissions, key.Role, key.RoleID, key.CreatedBy, key.IsActive, key.CreatedAt, key.UpdatedAt)
 	return err
 }
 
 func (r *APIKeyRepository) ListByAccountID(ctx context.Context, accountID uuid.UUID) ([]*domain.APIKey, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, account_id, name, key_prefix, permissions, is_active, last_used_at, created_at, updated_at
+		SELECT id, account_id, name, key_prefix, permissions, role, role_id, created_by, is_active, last_used_at, created_at, updated_at
 		FROM api_keys WHERE account_id = $1
 		ORDER BY created_at DESC
 	`, accountID)
@@ -35,7 +36,7 @@ func (r *APIKeyRepository) ListByAccountID(ctx context.Context, accountID uuid.U
 	var keys []*domain.APIKey
 	for rows.Next() {
 		k := &domain.APIKey{}
-		if err := rows.Scan(&k.ID, &k.AccountID, &k.Name, &k.KeyPrefix, &k.Permissions, &k.IsActive, &k.LastUsedAt, &k.CreatedAt, &k.UpdatedAt); err != nil {
+		if err := rows.Scan(&k.ID, &k.AccountID, &k.Name, &k.KeyPrefix, &k.Permissions, &k.Role, &k.RoleID, &k.CreatedBy, &k.IsActive, &k.LastUsedAt, &k.CreatedAt, &k.UpdatedAt); err != nil {
 			return nil, err
 		}
 		keys = append(keys, k)
@@ -46,9 +47,9 @@ func (r *APIKeyRepository) ListByAccountID(ctx context.Context, accountID uuid.U
 func (r *APIKeyRepository) GetByKeyHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
 	k := &domain.APIKey{}
 	err := r.db.QueryRow(ctx, `
-		SELECT id, account_id, name, key_hash, key_prefix, permissions, is_active, last_used_at, created_at, updated_at
+		SELECT id, account_id, name, key_hash, key_prefix, permissions, role, role_id, created_by, is_active, last_used_at, created_at, updated_at
 		FROM api_keys WHERE key_hash = $1 AND is_active = true
-	`, keyHash).Scan(&k.ID, &k.AccountID, &k.Name, &k.KeyHash, &k.KeyPrefix, &k.Permissions, &k.IsActive, &k.LastUsedAt, &k.CreatedAt, &k.UpdatedAt)
+	`, keyHash).Scan(&k.ID, &k.AccountID, &k.Name, &k.KeyHash, &k.KeyPrefix, &k.Permissions, &k.Role, &k.RoleID, &k.CreatedBy, &k.IsActive, &k.LastUsedAt, &k.CreatedAt, &k.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -64,6 +65,83 @@ func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID) {
 	_, _ = r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, time.Now(), id)
 }
 
+// TouchLastUsed records a use of the key, writing at most once a minute so a
+// busy integration doesn't turn every request into an UPDATE.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID) {
+	_, _ = r.db.Exec(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+}
+
+// Authenticate resolves an active key by hash together with the module
+// permissions of its role. Keys of a disabled account, or whose creator was
+// deactivated or removed from the account, are treated as not found. The key
+// never outranks its creator: see capAPIKeyToCreator.
+func (r *APIKeyRepository) Authenticate(ctx context.Context, keyHash string) (*domain.APIKey, []string, error) {
+	k := &domain.APIKey{}
+	var permissions, creatorPermissions []string
+	var creatorIsAdmin bool
+	err := r.db.QueryRow(ctx, `
+		SELECT k.id, k.account_id, k.name, k.key_prefix, k.role, k.role_id, k.created_by, COALESCE(ro.permissions, '{}'),
+		       COALESCE(u.is_admin, false) OR COALESCE(u.is_super_admin, false) OR ua.role IN ('admin', 'super_admin'),
+		       COALESCE(cro.permissions, '{}')
+		FROM api_keys k
+		JOIN users u ON u.id = k.created_by AND u.is_active = true
+		JOIN accounts a ON a.id = k.account_id AND COALESCE(a.is_active, true)
+		JOIN user_accounts ua ON ua.user_id = k.created_by AND ua.account_id = k.account_id
+		LEFT JOIN roles ro ON ro.id = k.role_id
+		LEFT JOIN roles cro ON cro.id = ua.role_id
+		WHERE k.key_hash = $1 AND k.is_active = true
+	`, keyHash).Scan(&k.ID, &k.AccountID, &k.Name, &k.KeyPrefix, &k.Role, &k.RoleID, &k.CreatedBy, &permissions,
+		&creatorIsAdmin, &creatorPermissions)
+	if err == pgx.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, capAPIKeyToCreator(k, permissions, creatorIsAdmin, creatorPermissions), nil
+}
+
+// capAPIKeyToCreator limits a key to what its creator may currently do in
+// the key's account, judged like a login (users.is_admin, is_super_admin or
+// the user_accounts role). When the creator is not an admin there, an admin
+// key is downgraded to agent with the creator's permissions, and an agent
+// key keeps only the permissions both roles grant. Returns the effective
+// permissions; key.Role is updated in place.
+func capAPIKeyToCreator(key *domain.APIKey, permissions []string, creatorIsAdmin bool, creatorPermissions []string) []string {
+	if creatorIsAdmin {
+		return permissions
+	}
+	if key.Role != domain.RoleAgent {
+		key.Role = domain.RoleAgent
+		return creatorPermissions
+	}
+	creatorHasAll := false
+	granted := make(map[string]bool, len(creatorPermissions))
+	for _, p := range creatorPermissions {
+		granted[p] = true
+		creatorHasAll = creatorHasAll || p == domain.PermAll
+	}
+	if creatorHasAll {
+		return permissions
+	}
+	effective := []string{}
+	keyHasAll := false
+	for _, p := range permissions {
+		if p == domain.PermAll {
+			keyHasAll = true
+		} else if granted[p] {
+			effective = append(effective, p)
+		}
+	}
+	if keyHasAll {
+		return creatorPermissions
+	}
+	return effective
+}
+
 // ValidateKeyHash looks up an active API key by its SHA-256 hash.
 // Returns the key record or nil if not found / inactive.
 func (r *APIKeyRepository) ValidateKeyHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestCapAPIKeyToCreator(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		permissions    []string
+		creatorIsAdmin bool
+		creatorPerms   []string
+		wantRole       string
+		wantPerms      string
+	}{
+		{name: "admin creator keeps admin key", role: domain.RoleAdmin, creatorIsAdmin: true, wantRole: domain.RoleAdmin},
+		{name: "admin creator keeps agent permissions", role: domain.RoleAgent, permissions: []string{"leads"}, creatorIsAdmin: true, wantRole: domain.RoleAgent, wantPerms: "leads"},
+		{name: "demoted creator downgrades admin key", role: domain.RoleAdmin, creatorPerms: []string{"chats"}, wantRole: domain.RoleAgent, wantPerms: "chats"},
+		{name: "agent key keeps shared permissions", role: domain.RoleAgent, permissions: []string{"leads", "chats"}, creatorPerms: []string{"chats", "tags"}, wantRole: domain.RoleAgent, wantPerms: "chats"},
+		{name: "agent creator without role grants nothing", role: domain.RoleAgent, permissions: []string{"leads"}, wantRole: domain.RoleAgent},
+	}
+	for _, tt := range tests {
+		key := &domain.APIKey{Role: tt.role}
+		got := capAPIKeyToCreator(key, tt.permissions, tt.creatorIsAdmin, tt.creatorPerms)
+		if key.Role != tt.wantRole || strings.Join(got, ",") != tt.wantPerms {
+			t.Errorf("%s: got role %q permissions %v, want %q [%s]", tt.name, key.Role, got, tt.wantRole, tt.wantPerms)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestAssignmentScopeSQL(t *testing.T) {
+	userID := uuid.New()
+	if got, want := assignmentScopeSQL("l.assigned_to", 4, &domain.AssignmentScope{UserID: userID}), " AND l.assigned_to = $4"; got != want {
+		t.Fatalf("assignmentScopeSQL() = %q, want %q", got, want)
+	}
+	got := assignmentScopeSQL("COALESCE(c.assigned_to, l.assigned_to)", 2, &domain.AssignmentScope{UserID: userID, IncludeUnassigned: true})
+	if want := " AND (COALESCE(c.assigned_to, l.assigned_to) = $2 OR COALESCE(c.assigned_to, l.assigned_to) IS NULL)"; got != want {
+		t.Fatalf("assignmentScopeSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestUnreadScopesSQLLimitsOnlyRestrictedAccounts(t *testing.T) {
+	if clause, args := unreadScopesSQL(map[uuid.UUID]*domain.AssignmentScope{uuid.New(): nil}, 4); clause != "" || args != nil {
+		t.Fatalf("unrestricted accounts were scoped: %q %v", clause, args)
+	}
+	userID, strict, lenient := uuid.New(), uuid.New(), uuid.New()
+	clause, args := unreadScopesSQL(map[uuid.UUID]*domain.AssignmentScope{
+		strict:  {UserID: userID},
+		lenient: {UserID: userID, IncludeUnassigned: true},
+	}, 4)
+	if !strings.Contains(clause, "c.account_id <> ALL($4::uuid[])") || !strings.Contains(clause, "= $5") || !strings.Contains(clause, "ANY($6::uuid[])") {
+		t.Fatalf("unexpected clause %q", clause)
+	}
+	if len(args) != 3 || args[1] != userID {
+		t.Fatalf("unexpected args %v", args)
+	}
+	if restricted := args[0].([]uuid.UUID); len(restricted) != 2 {
+		t.Fatalf("restricted accounts = %v", restricted)
+	}
+	if withUnassigned := args[2].([]uuid.UUID); len(withUnassigned) != 1 || withUnassigned[0] != lenient {
+		t.Fatalf("accounts showing unassigned chats = %v", withUnassigned)
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+type AuditLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func (r *AuditLogRepository) Create(ctx context.Context, e *domain.AuditLogEntry) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO audit_log (actor_user_id, actor_username, action, target_type, target_id, method, path, status, ip_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, e.ActorUserID, e.ActorUsername, e.Action, e.TargetType, e.TargetID, e.Method, e.Path, e.Status, e.IPHash).Scan(&e.ID, &e.CreatedAt)
+}
+
+// List returns one page of audit entries matching the filter and the total.
+func (r *AuditLogRepository) List(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLogEntry, int, error) {
+	where := " WHERE TRUE"
+	args := []interface{}{}
+	if filter.ActorUserID != nil {
+		args = append(args, *filter.ActorUserID)
+		where += fmt.Sprintf(" AND actor_user_id = $%d", len(args))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		where += fmt.Sprintf(" AND action = $%d", len(args))
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := r.db.Query(ctx, fmt.Sprintf(`
+		SELECT id, actor_user_id, actor_username, action, target_type, target_id, method, path, status, ip_hash, created_at
+		FROM audit_log%s
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	entries := []*domain.AuditLogEntry{}
+	for rows.Next() {
+		e := &domain.AuditLogEntry{}
+		if err := rows.Scan(&e.ID, &e.ActorUserID, &e.ActorUsername, &e.Action, &e.TargetType, &e.TargetID,
+			&e.Method, &e.Path, &e.Status, &e.IPHash, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+type AutoTagRuleRepository struct {
+	db *pgxpool.Pool
+}
+
+const autoTagRuleSelect = `
+	SELECT r.id, r.account_id, r.name, r.pattern, r.match_mode, r.tag_id, t.name, t.color,
+		r.is_active, r.created_at, r.updated_at
+	FROM auto_tag_rules r
+	JOIN tags t ON t.id = r.tag_id AND t.account_id = r.account_id`
+
+func scanAutoTagRule(row pgx.Row) (*domain.AutoTagRule, error) {
+	rule := &domain.AutoTagRule{}
+	if err := row.Scan(&rule.ID, &rule.AccountID, &rule.Name, &rule.Pattern, &rule.MatchMode, &rule.TagID, &rule.TagName, &rule.TagColor,
+		&rule.IsActive, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *AutoTagRuleRepository) list(ctx context.Context, query string, args ...any) ([]*domain.AutoTagRule, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rules := []*domain.AutoTagRule{}
+	for rows.Next() {
+		rule, err := scanAutoTagRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *AutoTagRuleRepository) ListByAccount(ctx context.Context, accountID uuid.UUID) ([]*domain.AutoTagRule, error) {
+	return r.list(ctx, autoTagRuleSelect+` WHERE r.account_id = $1 ORDER BY r.created_at, r.id`, accountID)
+}
+
+// ListActive returns the rules the inbound pipeline evaluates, oldest first so
+// evaluation order is stable.
+func (r *AutoTagRuleRepository) ListActive(ctx context.Context, accountID uuid.UUID) ([]*domain.AutoTagRule, error) {
+	return r.list(ctx, autoTagRuleSelect+` WHERE r.account_id = $1 AND r.is_active ORDER BY r.created_at, r.id`, accountID)
+}
+
+// GetByID returns nil when the rule does not exist in the account.
+func (r *AutoTagRuleRepository) GetByID(ctx context.Context, accountID, id uuid.UUID) (*domain.AutoTagRule, error) {
+	rule, err := scanAutoTagRule(r.db.QueryRow(ctx, autoTagRuleSelect+` WHERE r.account_id = $1 AND r.id = $2`, accountID, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+func (r *AutoTagRuleRepository) Create(ctx context.Context, rule *domain.AutoTagRule) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO auto_tag_rules (account_id, name, pattern, match_mode, tag_id, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, rule.AccountID, rule.Name, rule.Pattern, rule.MatchMode, rule.TagID, rule.IsActive).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+func (r *AutoTagRuleRepository) Update(ctx context.Context, rule *domain.AutoTagRule) error {
+	return r.db.QueryRow(ctx, `
+		UPDATE auto_tag_rules
+		SET name = $3, pattern = $4, match_mode = $5, tag_id = $6, is_active = $7, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2
+		RETURNING updated_at
+	`, rule.AccountID, rule.ID, rule.Name, rule.Pattern, rule.MatchMode, rule.TagID, rule.IsActive).Scan(&rule.UpdatedAt)
+}
+
+func (r *AutoTagRuleRepository) Delete(ctx context.Context, accountID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM auto_tag_rules WHERE account_id = $1 AND id = $2`, accountID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// campaignAnalyticsMaxBuckets bounds the timeline; longer campaigns get wider
+// buckets (whole minutes) instead of an unbounded series.
+const campaignAnalyticsMaxBuckets = 1440
+
+// CampaignRateBucket is one slice of the send timeline. Pending is what was
+// still queued at the end of the bucket.
+type CampaignRateBucket struct {
+	Start   time.Time `json:"start"`
+	Sent    int       `json:"sent"`
+	Failed  int       `json:"failed"`
+	Skipped int       `json:"skipped"`
+	Pending int       `json:"pending"`
+}
+
+// CampaignFailureReason groups failed recipients by error message.
+type CampaignFailureReason struct {
+	ErrorMessage string `json:"error_message"`
+	Count        int    `json:"count"`
+}
+
+// CampaignAnalytics is the send-rate report for one campaign.
+type CampaignAnalytics struct {
+	Total          int                     `json:"total"`
+	Sent           int                     `json:"sent"`
+	Failed         int                     `json:"failed"`
+	Skipped        int                     `json:"skipped"`
+	Pending        int                     `json:"pending"`
+	AvgWaitTimeMs  *float64                `json:"avg_wait_time_ms"`
+	BucketSeconds  int                     `json:"bucket_seconds"`
+	Buckets        []CampaignRateBucket    `json:"buckets"`
+	FailureReasons []CampaignFailureReason `json:"failure_reasons"`
+}
+
+// campaignRateEvent is the number of recipients that reached status during
+// the minute starting at Minute.
+type campaignRateEvent struct {
+	Minute time.Time
+	Status string
+	Count  int
+}
+
+// GetAnalytics aggregates campaign_recipients into per-minute send/fail
+// counts, the average wait between sends and grouped failure reasons.
+func (r *CampaignRepository) GetAnalytics(ctx context.Context, campaignID uuid.UUID) (*CampaignAnalytics, error) {
+	counts, err := r.CountRecipientsByStatus(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	result := &CampaignAnalytics{FailureReasons: []CampaignFailureReason{}}
+	for status, n := range counts {
+		result.Total += n
+		switch status {
+		case "sent", "delivered":
+			result.Sent += n
+		case "failed":
+			result.Failed += n
+		case "pending":
+			result.Pending += n
+		default:
+			result.Skipped += n
+		}
+	}
+
+	if err := r.db.QueryRow(ctx, `
+		SELECT AVG(wait_time_ms)::float8 FROM campaign_recipients
+		WHERE campaign_id = $1 AND status IN ('sent', 'delivered') AND wait_time_ms IS NOT NULL
+	`, campaignID).Scan(&result.AvgWaitTimeMs); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT date_trunc('minute', COALESCE(sent_at, processed_at)) AS minute, status, COUNT(*)
+		FROM campaign_recipients
+		WHERE campaign_id = $1 AND status <> 'pending' AND COALESCE(sent_at, processed_at) IS NOT NULL
+		GROUP BY 1, 2
+		ORDER BY 1
+	`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	var events []campaignRateEvent
+	for rows.Next() {
+		var ev campaignRateEvent
+		if err := rows.Scan(&ev.Minute, &ev.Status, &ev.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.BucketSeconds, result.Buckets = buildCampaignRateBuckets(events, result.Total, campaignAnalyticsMaxBuckets)
+
+	reasonRows, err := r.db.Query(ctx, `
+		SELECT COALESCE(NULLIF(error_message, ''), 'Sin detalle'), COUNT(*)
+		FROM campaign_recipients
+		WHERE campaign_id = $1 AND status = 'failed'
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+	`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer reasonRows.Close()
+	for reasonRows.Next() {
+		var reason CampaignFailureReason
+		if err := reasonRows.Scan(&reason.ErrorMessage, &reason.Count); err != nil {
+			return nil, err
+		}
+		result.FailureReasons = append(result.FailureReasons, reason)
+	}
+	return result, reasonRows.Err()
+}
+
+// buildCampaignRateBuckets turns per-minute events into a continuous timeline
+// from the first to the last activity, zero-filling idle minutes. When the
+// span exceeds maxBuckets minutes, each bucket covers several minutes.
+func buildCampaignRateBuckets(events []campaignRateEvent, total, maxBuckets int) (int, []CampaignRateBucket) {
+	buckets := []CampaignRateBucket{}
+	if len(events) == 0 {
+		return 60, buckets
+	}
+	first, last := events[0].Minute, events[0].Minute
+	for _, ev := range events {
+		if ev.Minute.Before(first) {
+			first = ev.Minute
+		}
+		if ev.Minute.After(last) {
+			last = ev.Minute
+		}
+	}
+	span := int(last.Sub(first)/time.Minute) + 1
+	width := 1
+	if maxBuckets > 0 && span > maxBuckets {
+		width = (span + maxBuckets - 1) / maxBuckets
+	}
+	buckets = make([]CampaignRateBucket, (span+width-1)/width)
+	for i := range buckets {
+		buckets[i].Start = first.Add(time.Duration(i*width) * time.Minute)
+	}
+	for _, ev := range events {
+		b := &buckets[int(ev.Minute.Sub(first)/time.Minute)/width]
+		switch ev.Status {
+		case "sent", "delivered":
+			b.Sent += ev.Count
+		case "failed":
+			b.Failed += ev.Count
+		default:
+			b.Skipped += ev.Count
+		}
+	}
+	remaining := total
+	for i := range buckets {
+		remaining -= buckets[i].Sent + buckets[i].Failed + buckets[i].Skipped
+		buckets[i].Pending = remaining
+	}
+	return width * 60, buckets
+}
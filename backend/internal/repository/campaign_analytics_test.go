@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildCampaignRateBucketsZeroFillsGaps(t *testing.T) {
+	t0 := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	events := []campaignRateEvent{
+		{Minute: t0, Status: "sent", Count: 3},
+		{Minute: t0, Status: "failed", Count: 1},
+		{Minute: t0.Add(3 * time.Minute), Status: "sent", Count: 2},
+		{Minute: t0.Add(3 * time.Minute), Status: "skipped", Count: 1},
+	}
+	width, buckets := buildCampaignRateBuckets(events, 10, 1440)
+	if width != 60 || len(buckets) != 4 {
+		t.Fatalf("got width %d and %d buckets, want 60 and 4", width, len(buckets))
+	}
+	if b := buckets[0]; b.Sent != 3 || b.Failed != 1 || b.Pending != 6 {
+		t.Fatalf("first bucket = %+v", b)
+	}
+	for _, b := range buckets[1:3] {
+		if b.Sent != 0 || b.Failed != 0 || b.Pending != 6 {
+			t.Fatalf("idle bucket not zero-filled: %+v", b)
+		}
+	}
+	if b := buckets[3]; !b.Start.Equal(t0.Add(3*time.Minute)) || b.Sent != 2 || b.Skipped != 1 || b.Pending != 3 {
+		t.Fatalf("last bucket = %+v", b)
+	}
+}
+
+func TestBuildCampaignRateBucketsWidensLongCampaigns(t *testing.T) {
+	t0 := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	events := []campaignRateEvent{
+		{Minute: t0, Status: "sent", Count: 1},
+		{Minute: t0.Add(9 * time.Minute), Status: "sent", Count: 1},
+	}
+	width, buckets := buildCampaignRateBuckets(events, 2, 4)
+	if width != 180 || len(buckets) != 4 {
+		t.Fatalf("got width %d and %d buckets, want 180 and 4", width, len(buckets))
+	}
+	if buckets[3].Sent != 1 || buckets[3].Pending != 0 {
+		t.Fatalf("last bucket = %+v", buckets[3])
+	}
+}
+
+func TestBuildCampaignRateBucketsEmpty(t *testing.T) {
+	width, buckets := buildCampaignRateBuckets(nil, 5, 1440)
+	if width != 60 || buckets == nil || len(buckets) != 0 {
+		t.Fatalf("got width %d, buckets %v", width, buckets)
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestDuplicatePhoneKeyPeruvianVariants(t *testing.T) {
+	variants := []struct {
+		name  string
+		phone *string
+		jid   string
+	}{
+		{name: "international with plus", phone: strPtr("+51999888777")},
+		{name: "international without plus", phone: strPtr("51999888777")},
+		{name: "local nine digits", phone: strPtr("999888777")},
+		{name: "spaced", phone: strPtr("+51 999 888 777")},
+		{name: "dashed local", phone: strPtr("999-888-777")},
+		{name: "parenthesised code", phone: strPtr("(51) 999888777")},
+		{name: "double zero prefix", phone: strPtr("0051999888777")},
+		{name: "jid fallback", jid: "51999888777@s.whatsapp.net"},
+		{name: "blank phone uses jid", phone: strPtr("  "), jid: "51999888777@s.whatsapp.net"},
+	}
+	for _, v := range variants {
+		t.Run(v.name, func(t *testing.T) {
+			if got := duplicatePhoneKey(v.phone, v.jid); got != "51999888777" {
+				t.Fatalf("duplicatePhoneKey() = %q, want 51999888777", got)
+			}
+		})
+	}
+
+	if got := duplicatePhoneKey(nil, "123456789012345@lid"); got != "" {
+		t.Fatalf("lid JID should not produce a phone key, got %q", got)
+	}
+	if got := duplicatePhoneKey(strPtr("123"), ""); got != "" {
+		t.Fatalf("short numbers should be ignored, got %q", got)
+	}
+}
+
+func TestDuplicateContactIndexGroups(t *testing.T) {
+	a, b, c, d, e := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	contacts := []duplicateContactKeys{
+		{ID: a, Phone: strPtr("+51 999 888 777"), Email: strPtr("ana@example.com")},
+		{ID: b, Phone: strPtr("999888777"), Email: strPtr("ANA@example.com ")},
+		{ID: c, Phone: strPtr("51911222333"), Email: strPtr("luis@example.com")},
+		{ID: d, Phone: strPtr("51944555666"), Email: strPtr("Luis@Example.com")},
+		{ID: e, Phone: strPtr("51977000111")},
+	}
+
+	group := func(matchEmail bool) []duplicateKeyGroup {
+		index := newDuplicateContactIndex(matchEmail)
+		for _, c := range contacts {
+			index.add(c)
+		}
+		return index.groups()
+	}
+
+	phoneOnly := group(false)
+	if len(phoneOnly) != 1 || phoneOnly[0].Key != "phone:51999888777" || len(phoneOnly[0].IDs) != 2 {
+		t.Fatalf("phone groups = %+v", phoneOnly)
+	}
+	if phoneOnly[0].IDs[0] != a || phoneOnly[0].IDs[1] != b {
+		t.Fatalf("group members should keep input order, got %v", phoneOnly[0].IDs)
+	}
+
+	withEmail := group(true)
+	if len(withEmail) != 2 {
+		t.Fatalf("expected phone group plus one email group, got %+v", withEmail)
+	}
+	emailGroup := withEmail[1]
+	if emailGroup.Key != "email:luis@example.com" || emailGroup.Phone != "" || len(emailGroup.IDs) != 2 {
+		t.Fatalf("email group = %+v", emailGroup)
+	}
+}
@@ -0,0 +1,134 @@
+package repository_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/repository"
+	"github.com/naperu/clarin/pkg/database"
+)
+
+func TestContactPhoneChangeMovesChatAndMergesExisting(t *testing.T) {
+	if os.Getenv("CLARIN_RUN_MIGRATION_INTEGRATION") != "1" {
+		t.Skip("set CLARIN_RUN_MIGRATION_INTEGRATION=1 in an isolated PostgreSQL environment")
+	}
+	rawURL := os.Getenv("DATABASE_URL")
+	if rawURL == "" {
+		t.Fatal("DATABASE_URL is required")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse DATABASE_URL: %v", err)
+	}
+	const databaseName = "clarin_contact_phone_rekey_test"
+	adminURL := *parsed
+	adminURL.Path = "/postgres"
+	testURL := *parsed
+	testURL.Path = "/" + databaseName
+
+	ctx := context.Background()
+	admin, err := pgxpool.New(ctx, adminURL.String())
+	if err != nil {
+		t.Fatalf("connect admin database: %v", err)
+	}
+	defer admin.Close()
+	_, _ = admin.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname=$1 AND pid<>pg_backend_pid()`, databaseName)
+	_, _ = admin.Exec(ctx, `DROP DATABASE IF EXISTS `+databaseName)
+	if _, err := admin.Exec(ctx, `CREATE DATABASE `+databaseName); err != nil {
+		t.Fatalf("create disposable database: %v", err)
+	}
+	defer func() {
+		_, _ = admin.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname=$1 AND pid<>pg_backend_pid()`, databaseName)
+		_, _ = admin.Exec(ctx, `DROP DATABASE IF EXISTS `+databaseName)
+	}()
+
+	db, err := pgxpool.New(ctx, testURL.String())
+	if err != nil {
+		t.Fatalf("connect disposable database: %v", err)
+	}
+	defer db.Close()
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	accountID, deviceID, contactID := uuid.New(), uuid.New(), uuid.New()
+	chatID, existingChatID, leadID := uuid.New(), uuid.New(), uuid.New()
+	oldJID := "51999000111@s.whatsapp.net"
+	if _, err := db.Exec(ctx, `INSERT INTO accounts(id,name) VALUES ($1,'Rekey account')`, accountID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO devices(id,account_id,name) VALUES ($1,$2,'Rekey device')`, deviceID, accountID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO contacts(id,account_id,device_id,jid,phone,name) VALUES ($1,$2,$3,$4,'51999000111','Rosa')`, contactID, accountID, deviceID, oldJID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO chats(id,account_id,device_id,contact_id,jid,last_message,last_message_at,unread_count) VALUES ($1,$2,$3,$4,$5,'hola',$6,1)`, chatID, accountID, deviceID, contactID, oldJID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO leads(id,account_id,contact_id,jid) VALUES ($1,$2,$3,$4)`, leadID, accountID, contactID, oldJID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO messages(account_id,device_id,chat_id,message_id,body,timestamp) VALUES ($1,$2,$3,'old-1','hola',NOW())`, accountID, deviceID, chatID); err != nil {
+		t.Fatal(err)
+	}
+
+	repos := repository.NewRepositories(db)
+	setPhone := func(phone string) {
+		t.Helper()
+		if _, err := repos.ContactProfile.Update(ctx, accountID, contactID, repository.ContactProfilePatch{PhoneSet: true, Phone: &phone}); err != nil {
+			t.Fatalf("update phone %s: %v", phone, err)
+		}
+	}
+
+	// A plain number change moves the chat and lead with the contact.
+	setPhone("999000222")
+	movedJID := "51999000222@s.whatsapp.net"
+	var contactJID, chatJID, leadJID string
+	if err := db.QueryRow(ctx, `SELECT jid FROM contacts WHERE id=$1`, contactID).Scan(&contactJID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(ctx, `SELECT jid FROM chats WHERE id=$1`, chatID).Scan(&chatJID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(ctx, `SELECT jid FROM leads WHERE id=$1`, leadID).Scan(&leadJID); err != nil {
+		t.Fatal(err)
+	}
+	if contactJID != movedJID || chatJID != movedJID || leadJID != movedJID {
+		t.Fatalf("jids after change: contact=%s chat=%s lead=%s, want %s", contactJID, chatJID, leadJID, movedJID)
+	}
+
+	// Changing to a number that already has its own chat merges into it.
+	mergedJID := "51999000333@s.whatsapp.net"
+	if _, err := db.Exec(ctx, `INSERT INTO chats(id,account_id,device_id,jid,last_message,last_message_at,unread_count) VALUES ($1,$2,$3,$4,'nuevo',NOW(),2)`, existingChatID, accountID, deviceID, mergedJID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO messages(account_id,device_id,chat_id,message_id,body,timestamp) VALUES ($1,$2,$3,'new-1','nuevo',NOW())`, accountID, deviceID, existingChatID); err != nil {
+		t.Fatal(err)
+	}
+	setPhone("51999000333")
+
+	var remaining int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM chats WHERE id=$1`, chatID).Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Fatal("old chat should be merged away, not orphaned")
+	}
+	var linkedContact uuid.UUID
+	var unread, messages int
+	if err := db.QueryRow(ctx, `
+		SELECT contact_id,unread_count,(SELECT COUNT(*) FROM messages WHERE chat_id=chats.id)
+		FROM chats WHERE id=$1
+	`, existingChatID).Scan(&linkedContact, &unread, &messages); err != nil {
+		t.Fatal(err)
+	}
+	if linkedContact != contactID || unread != 3 || messages != 2 {
+		t.Fatalf("merged chat contact=%s unread=%d messages=%d, want %s 3 2", linkedContact, unread, messages, contactID)
+	}
+}
@@ -0,0 +1,26 @@
+package repository
+
+import "testing"
+
+func TestContactJIDForPhone(t *testing.T) {
+	phone := func(v string) *string { return &v }
+	cases := []struct {
+		name    string
+		jid     string
+		phone   *string
+		wantJID string
+		changed bool
+	}{
+		{"local number gains country code", "51999000111@s.whatsapp.net", phone("999 000 222"), "51999000222@s.whatsapp.net", true},
+		{"same number reformatted", "51999000111@s.whatsapp.net", phone("+51 999-000-111"), "51999000111@s.whatsapp.net", false},
+		{"cleared phone keeps jid", "51999000111@s.whatsapp.net", nil, "51999000111@s.whatsapp.net", false},
+		{"lid is not phone addressed", "123456789@lid", phone("51999000222"), "123456789@lid", false},
+		{"group is not phone addressed", "120363000000@g.us", phone("51999000222"), "120363000000@g.us", false},
+	}
+	for _, tc := range cases {
+		got, changed := contactJIDForPhone(tc.jid, tc.phone)
+		if got != tc.wantJID || changed != tc.changed {
+			t.Errorf("%s: got (%s, %v), want (%s, %v)", tc.name, got, changed, tc.wantJID, tc.changed)
+		}
+	}
+}
@@ -663,6 +663,135 @@ func ensureContactProfilePhoneOwnershipTx(ctx context.Context, tx pgx.Tx, accoun
 	return nil
 }
 
+// contactJIDForPhone derives the WhatsApp user JID for an edited primary
+// phone. Only phone-addressed JIDs follow the phone: lid, group and other
+// server JIDs are not derived from the number and are left alone, as is a
+// contact whose phone was cleared.
+func contactJIDForPhone(currentJID string, phone *string) (string, bool) {
+	if phone == nil || !strings.HasSuffix(currentJID, "@s.whatsapp.net") {
+		return currentJID, false
+	}
+	digits := normalizeAliasValue("phone", *phone)
+	if digits == "" || digits == normalizeAliasValue("phone", currentJID) {
+		return currentJID, false
+	}
+	return digits + "@s.whatsapp.net", true
+}
+
+// rekeyContactJIDTx moves a Contact, its chats and its leads from oldJID to
+// newJID. A chat that already exists for newJID on the same channel absorbs
+// the old one instead of leaving two chats for the same person.
+func rekeyContactJIDTx(ctx context.Context, tx pgx.Tx, accountID, contactID uuid.UUID, oldJID, newJID string) error {
+	var taken bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM contacts WHERE account_id=$1 AND jid=$2 AND id<>$3)
+	`, accountID, newJID, contactID).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrContactIdentityConflict
+	}
+	if _, err := tx.Exec(ctx, `UPDATE contacts SET jid=$3,updated_at=NOW() WHERE account_id=$1 AND id=$2`, accountID, contactID, newJID); err != nil {
+		return err
+	}
+
+	type chatRef struct {
+		id         uuid.UUID
+		channelKey string
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id,channel_key FROM chats
+		WHERE account_id=$1 AND jid=$2
+		ORDER BY id
+		FOR UPDATE
+	`, accountID, oldJID)
+	if err != nil {
+		return err
+	}
+	sources := make([]chatRef, 0, 1)
+	for rows.Next() {
+		var ref chatRef
+		if err := rows.Scan(&ref.id, &ref.channelKey); err != nil {
+			rows.Close()
+			return err
+		}
+		sources = append(sources, ref)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		var targetID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			SELECT id FROM chats WHERE account_id=$1 AND channel_key=$2 AND jid=$3 FOR UPDATE
+		`, accountID, source.channelKey, newJID).Scan(&targetID)
+		if err == pgx.ErrNoRows {
+			if _, err := tx.Exec(ctx, `
+				UPDATE chats SET jid=$3,contact_id=$4,updated_at=NOW() WHERE account_id=$1 AND id=$2
+			`, accountID, source.id, newJID, contactID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := mergeChatIntoTx(ctx, tx, accountID, source.id, targetID, contactID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE leads SET jid=$3,updated_at=NOW()
+		WHERE account_id=$1 AND (contact_id=$2 OR jid=$4)
+	`, accountID, contactID, newJID, oldJID)
+	return err
+}
+
+// mergeChatIntoTx folds sourceID into targetID and deletes the source chat.
+// Messages and reactions already present in the target win over the copies
+// the source holds for the same WhatsApp IDs.
+func mergeChatIntoTx(ctx context.Context, tx pgx.Tx, accountID, sourceID, targetID, contactID uuid.UUID) error {
+	moves := []string{
+		`DELETE FROM messages src USING messages dst
+		 WHERE src.account_id=$1 AND src.chat_id=$2 AND dst.chat_id=$3 AND dst.message_id=src.message_id`,
+		`UPDATE messages SET chat_id=$3 WHERE account_id=$1 AND chat_id=$2`,
+		`DELETE FROM message_reactions src USING message_reactions dst
+		 WHERE src.account_id=$1 AND src.chat_id=$2 AND dst.account_id=$1 AND dst.chat_id=$3
+		   AND dst.target_message_id=src.target_message_id AND dst.sender_jid=src.sender_jid`,
+		`UPDATE message_reactions SET chat_id=$3 WHERE account_id=$1 AND chat_id=$2`,
+		`INSERT INTO chat_tags (chat_id,tag_id)
+		 SELECT $3,ct.tag_id FROM chat_tags ct JOIN chats ch ON ch.id=ct.chat_id AND ch.account_id=$1
+		 WHERE ct.chat_id=$2
+		 ON CONFLICT DO NOTHING`,
+		`UPDATE bot_sessions SET chat_id=$3 WHERE account_id=$1 AND chat_id=$2`,
+		`UPDATE chat_reply_tokens SET chat_id=$3 WHERE account_id=$1 AND chat_id=$2`,
+	}
+	for _, move := range moves {
+		if _, err := tx.Exec(ctx, move, accountID, sourceID, targetID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE chats dst SET
+			contact_id=$4,
+			unread_count=COALESCE(dst.unread_count,0)+COALESCE(src.unread_count,0),
+			last_message=CASE WHEN src.last_message_at > COALESCE(dst.last_message_at,'-infinity') THEN src.last_message ELSE dst.last_message END,
+			last_message_at=GREATEST(dst.last_message_at,src.last_message_at),
+			is_pinned=COALESCE(dst.is_pinned,FALSE) OR COALESCE(src.is_pinned,FALSE),
+			updated_at=NOW()
+		FROM chats src
+		WHERE dst.account_id=$1 AND dst.id=$3 AND src.account_id=$1 AND src.id=$2
+	`, accountID, sourceID, targetID, contactID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `DELETE FROM chats WHERE account_id=$1 AND id=$2`, accountID, sourceID)
+	return err
+}
+
 // Update applies the canonical scalar profile and every compatibility snapshot
 // in one account-scoped transaction. Legacy Lead personal columns are cleared
 // so an explicitly removed Contact value cannot reappear through a fallback.
@@ -726,6 +855,12 @@ func (r *ContactProfileRepository) Update(ctx context.Context, accountID, contac
 	if err := ensureContactProfilePhoneOwnershipTx(ctx, tx, accountID, contactID, phoneAliases); err != nil {
 		return nil, err
 	}
+	if newJID, changed := contactJIDForPhone(contact.JID, contact.Phone); patch.PhoneSet && changed {
+		if err := rekeyContactJIDTx(ctx, tx, accountID, contactID, contact.JID, newJID); err != nil {
+			return nil, err
+		}
+		contact.JID = newJID
+	}
 
 	if _, err := tx.Exec(ctx, `
 		UPDATE contacts SET
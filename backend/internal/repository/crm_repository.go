@@ -142,7 +142,7 @@ func (r *PipelineRepository) GetByIDForAccount(ctx context.Context, accountID, i
 	}
 	rows, err := r.db.Query(ctx, `
 		SELECT ps.id, ps.pipeline_id, ps.name, ps.color, ps.position, ps.stage_type, ps.kommo_id, ps.created_at,
-		       COUNT(l.id) FILTER (WHERE l.deleted_at IS NULL)
+		       COUNT(l.id) FILTER (WHERE `+NotDeleted("l")+`)
 		FROM pipeline_stages ps
 		LEFT JOIN leads l ON l.stage_id = ps.id AND l.account_id = $2
 		WHERE ps.pipeline_id = $1
@@ -348,7 +348,7 @@ func (r *PipelineRepository) SaveStageLayout(ctx context.Context, accountID, pip
 			}
 		}
 		var leadCount, visibleLeadCount int
-		if err := tx.QueryRow(ctx, `SELECT COUNT(*), COUNT(*) FILTER (WHERE deleted_at IS NULL) FROM leads WHERE account_id=$1 AND stage_id=$2`, accountID, deletion.ID).Scan(&leadCount, &visibleLeadCount); err != nil {
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*), COUNT(*) FILTER (WHERE `+NotDeleted("")+`) FROM leads WHERE account_id=$1 AND stage_id=$2`, accountID, deletion.ID).Scan(&leadCount, &visibleLeadCount); err != nil {
 			return nil, err
 		}
 		if leadCount > 0 {
@@ -472,7 +472,7 @@ func (r *LeadRepository) HasOpenDuplicate(ctx context.Context, accountID, contac
 	if normalized == "" {
 		return nil, nil
 	}
-	query := `SELECT id FROM leads WHERE account_id=$1 AND contact_id=$2 AND status='open' AND deleted_at IS NULL AND LOWER(REGEXP_REPLACE(BTRIM(title), '\s+', ' ', 'g'))=$3`
+	query := `SELECT id FROM leads WHERE account_id=$1 AND contact_id=$2 AND status='open' AND ` + NotDeleted("") + ` AND LOWER(REGEXP_REPLACE(BTRIM(title), '\s+', ' ', 'g'))=$3`
 	args := []interface{}{accountID, contactID, normalized}
 	if excludeID != nil {
 		query += ` AND id<>$4`
@@ -507,6 +507,87 @@ func (r *LeadRepository) MoveToStage(ctx context.Context, accountID, leadID, sta
 		return err
 	}
 	defer tx.Rollback(ctx)
+	pipelineID, stageType, err := stageDestinationTx(ctx, tx, accountID, stageID)
+	if err != nil {
+		return err
+	}
+	var fromStageID *uuid.UUID
+	if err := tx.QueryRow(ctx, `SELECT stage_id FROM leads WHERE id=$1 AND account_id=$2 AND `+NotDeleted("")+` FOR UPDATE`, leadID, accountID).Scan(&fromStageID); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrCRMNotFound
+		}
+		return err
+	}
+	if err := setLeadsStageTx(ctx, tx, accountID, []uuid.UUID{leadID}, pipelineID, stageID, stageType, closeReason, closedBy); err != nil {
+		return err
+	}
+	if err := insertLeadStageChange(ctx, tx, accountID, leadID, fromStageID, stageID, closedBy); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// BulkStageMoveResult reports which leads a bulk stage move changed.
+type BulkStageMoveResult struct {
+	Moved   []uuid.UUID
+	Skipped int
+}
+
+// MoveLeadsToStage moves several leads to one stage in a single transaction.
+// Leads that are missing, trashed, already in the stage or assigned to a
+// different pipeline than the stage are skipped.
+func (r *LeadRepository) MoveLeadsToStage(ctx context.Context, accountID uuid.UUID, leadIDs []uuid.UUID, stageID uuid.UUID, closeReason string, closedBy *uuid.UUID) (*BulkStageMoveResult, error) {
+	result := &BulkStageMoveResult{Moved: []uuid.UUID{}}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	pipelineID, stageType, err := stageDestinationTx(ctx, tx, accountID, stageID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, `SELECT id, pipeline_id, stage_id FROM leads WHERE account_id=$1 AND id=ANY($2) AND `+NotDeleted("")+` ORDER BY id FOR UPDATE`, accountID, leadIDs)
+	if err != nil {
+		return nil, err
+	}
+	fromStages := make(map[uuid.UUID]*uuid.UUID)
+	for rows.Next() {
+		var id uuid.UUID
+		var leadPipelineID, fromStageID *uuid.UUID
+		if err := rows.Scan(&id, &leadPipelineID, &fromStageID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if (leadPipelineID != nil && *leadPipelineID != pipelineID) || (fromStageID != nil && *fromStageID == stageID) {
+			continue
+		}
+		result.Moved = append(result.Moved, id)
+		fromStages[id] = fromStageID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.Skipped = len(leadIDs) - len(result.Moved)
+	if len(result.Moved) == 0 {
+		return result, nil
+	}
+	if err := setLeadsStageTx(ctx, tx, accountID, result.Moved, pipelineID, stageID, stageType, closeReason, closedBy); err != nil {
+		return nil, err
+	}
+	for _, id := range result.Moved {
+		if err := insertLeadStageChange(ctx, tx, accountID, id, fromStages[id], stageID, closedBy); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func stageDestinationTx(ctx context.Context, tx pgx.Tx, accountID, stageID uuid.UUID) (uuid.UUID, string, error) {
 	var pipelineID uuid.UUID
 	var stageType string
 	if err := tx.QueryRow(ctx, `
@@ -515,39 +596,45 @@ func (r *LeadRepository) MoveToStage(ctx context.Context, accountID, leadID, sta
 		WHERE ps.id=$1 AND p.account_id=$2
 	`, stageID, accountID).Scan(&pipelineID, &stageType); err != nil {
 		if err == pgx.ErrNoRows {
-			return ErrCRMNotFound
+			return uuid.Nil, "", ErrCRMNotFound
 		}
-		return err
-	}
-	var leadExists bool
-	if err := tx.QueryRow(ctx, `SELECT TRUE FROM leads WHERE id=$1 AND account_id=$2 AND deleted_at IS NULL FOR UPDATE`, leadID, accountID).Scan(&leadExists); err != nil {
-		if err == pgx.ErrNoRows {
-			return ErrCRMNotFound
-		}
-		return err
+		return uuid.Nil, "", err
 	}
+	return pipelineID, stageType, nil
+}
+
+// setLeadsStageTx moves leads to a stage and derives their open/won/lost
+// status from the stage type.
+func setLeadsStageTx(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, leadIDs []uuid.UUID, pipelineID, stageID uuid.UUID, stageType, closeReason string, closedBy *uuid.UUID) error {
 	closeReason = strings.TrimSpace(closeReason)
+	var err error
 	switch stageType {
 	case domain.PipelineStageTypeActive:
-		_, err = tx.Exec(ctx, `UPDATE leads SET pipeline_id=$1, stage_id=$2, status='open', closed_at=NULL, closed_by=NULL, close_reason='', updated_at=NOW() WHERE id=$3 AND account_id=$4`, pipelineID, stageID, leadID, accountID)
+		_, err = tx.Exec(ctx, `UPDATE leads SET pipeline_id=$1, stage_id=$2, status='open', closed_at=NULL, closed_by=NULL, close_reason='', updated_at=NOW() WHERE id=ANY($3) AND account_id=$4`, pipelineID, stageID, leadIDs, accountID)
 	case domain.PipelineStageTypeWon:
-		_, err = tx.Exec(ctx, `UPDATE leads SET pipeline_id=$1, stage_id=$2, status='won', closed_at=NOW(), closed_by=$3, close_reason=$4, updated_at=NOW() WHERE id=$5 AND account_id=$6`, pipelineID, stageID, closedBy, closeReason, leadID, accountID)
+		_, err = tx.Exec(ctx, `UPDATE leads SET pipeline_id=$1, stage_id=$2, status='won', closed_at=NOW(), closed_by=$3, close_reason=$4, updated_at=NOW() WHERE id=ANY($5) AND account_id=$6`, pipelineID, stageID, closedBy, closeReason, leadIDs, accountID)
 	case domain.PipelineStageTypeLost:
 		if closeReason == "" {
 			return ErrLostReasonRequired
 		}
-		_, err = tx.Exec(ctx, `UPDATE leads SET pipeline_id=$1, stage_id=$2, status='lost', closed_at=NOW(), closed_by=$3, close_reason=$4, updated_at=NOW() WHERE id=$5 AND account_id=$6`, pipelineID, stageID, closedBy, closeReason, leadID, accountID)
+		_, err = tx.Exec(ctx, `UPDATE leads SET pipeline_id=$1, stage_id=$2, status='lost', closed_at=NOW(), closed_by=$3, close_reason=$4, updated_at=NOW() WHERE id=ANY($5) AND account_id=$6`, pipelineID, stageID, closedBy, closeReason, leadIDs, accountID)
 	default:
 		return fmt.Errorf("tipo de etapa inválido")
 	}
-	if err != nil {
-		return err
+	return err
+}
+
+// NotDeleted is the soft-delete filter shared by lead and contact queries.
+// alias is the table alias, or "" when the query uses the bare table.
+func NotDeleted(alias string) string {
+	if alias == "" {
+		return "deleted_at IS NULL"
 	}
-	return tx.Commit(ctx)
+	return alias + ".deleted_at IS NULL"
 }
 
 func (r *LeadRepository) SoftDelete(ctx context.Context, accountID, id uuid.UUID, deletedBy *uuid.UUID, reason string) error {
-	tag, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at=NOW(), deleted_by=$3, delete_reason=$4, updated_at=NOW() WHERE account_id=$1 AND id=$2 AND deleted_at IS NULL`, accountID, id, deletedBy, strings.TrimSpace(reason))
+	tag, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at=NOW(), deleted_by=$3, delete_reason=$4, updated_at=NOW() WHERE account_id=$1 AND id=$2 AND `+NotDeleted(""), accountID, id, deletedBy, strings.TrimSpace(reason))
 	if err != nil {
 		return err
 	}
@@ -558,7 +645,7 @@ func (r *LeadRepository) SoftDelete(ctx context.Context, accountID, id uuid.UUID
 }
 
 func (r *LeadRepository) SoftDeleteBatch(ctx context.Context, accountID uuid.UUID, ids []uuid.UUID, deletedBy *uuid.UUID, reason string) (int64, error) {
-	tag, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at=NOW(), deleted_by=$3, delete_reason=$4, updated_at=NOW() WHERE account_id=$1 AND id=ANY($2) AND deleted_at IS NULL`, accountID, ids, deletedBy, strings.TrimSpace(reason))
+	tag, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at=NOW(), deleted_by=$3, delete_reason=$4, updated_at=NOW() WHERE account_id=$1 AND id=ANY($2) AND `+NotDeleted(""), accountID, ids, deletedBy, strings.TrimSpace(reason))
 	if err != nil {
 		return 0, err
 	}
@@ -566,7 +653,7 @@ func (r *LeadRepository) SoftDeleteBatch(ctx context.Context, accountID uuid.UUI
 }
 
 func (r *LeadRepository) SoftDeleteAll(ctx context.Context, accountID uuid.UUID, deletedBy *uuid.UUID, reason string) (int64, error) {
-	tag, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at=NOW(), deleted_by=$2, delete_reason=$3, updated_at=NOW() WHERE account_id=$1 AND deleted_at IS NULL`, accountID, deletedBy, strings.TrimSpace(reason))
+	tag, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at=NOW(), deleted_by=$2, delete_reason=$3, updated_at=NOW() WHERE account_id=$1 AND `+NotDeleted(""), accountID, deletedBy, strings.TrimSpace(reason))
 	if err != nil {
 		return 0, err
 	}
@@ -869,6 +956,196 @@ func (r *TagRepository) SetEntityTagForAccount(ctx context.Context, accountID uu
 	return contactID, nil
 }
 
+// SetEntitiesTagForAccount is the bulk form of SetEntityTagForAccount: every
+// entity of entityType is resolved to its same-account contact and tagID is
+// added to or removed from all of them in one statement. Entities that do not
+// resolve are skipped; the entity ids that did are returned along with their
+// distinct contacts and the number of contact_tags rows changed.
+func (r *TagRepository) SetEntitiesTagForAccount(ctx context.Context, accountID uuid.UUID, entityType string, entityIDs []uuid.UUID, tagID uuid.UUID, assign bool) ([]uuid.UUID, []uuid.UUID, int64, error) {
+	var resolveQuery string
+	switch entityType {
+	case "contact":
+		resolveQuery = `SELECT id, id FROM contacts WHERE id = ANY($1::uuid[]) AND account_id=$2`
+	case "lead":
+		resolveQuery = `SELECT id, contact_id FROM leads WHERE id = ANY($1::uuid[]) AND account_id=$2 AND contact_id IS NOT NULL`
+	case "chat":
+		resolveQuery = `SELECT id, contact_id FROM chats WHERE id = ANY($1::uuid[]) AND account_id=$2 AND contact_id IS NOT NULL`
+	case "participant":
+		resolveQuery = `SELECT ep.id, ep.contact_id FROM event_participants ep JOIN events e ON e.id=ep.event_id WHERE ep.id = ANY($1::uuid[]) AND e.account_id=$2 AND ep.contact_id IS NOT NULL`
+	default:
+		return nil, nil, 0, fmt.Errorf("tipo de entidad inválido")
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1::text))`, accountID); err != nil {
+		return nil, nil, 0, err
+	}
+	var tagExists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM tags WHERE id=$1 AND account_id=$2)`, tagID, accountID).Scan(&tagExists); err != nil {
+		return nil, nil, 0, err
+	}
+	if !tagExists {
+		return nil, nil, 0, ErrCRMNotFound
+	}
+
+	rows, err := tx.Query(ctx, resolveQuery, entityIDs, accountID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	var resolved, contactIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var entityID, contactID uuid.UUID
+		if err := rows.Scan(&entityID, &contactID); err != nil {
+			rows.Close()
+			return nil, nil, 0, err
+		}
+		resolved = append(resolved, entityID)
+		if !seen[contactID] {
+			seen[contactID] = true
+			contactIDs = append(contactIDs, contactID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+	if len(contactIDs) == 0 {
+		return resolved, contactIDs, 0, nil
+	}
+
+	var tag pgconn.CommandTag
+	if assign {
+		tag, err = tx.Exec(ctx, `
+			INSERT INTO contact_tags(contact_id,tag_id)
+			SELECT unnest($1::uuid[]), $2
+			ON CONFLICT DO NOTHING
+		`, contactIDs, tagID)
+	} else {
+		tag, err = tx.Exec(ctx, `DELETE FROM contact_tags WHERE contact_id = ANY($1::uuid[]) AND tag_id=$2`, contactIDs, tagID)
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, 0, err
+	}
+	return resolved, contactIDs, tag.RowsAffected(), nil
+}
+
+// MergeTagsForAccount moves every use of sourceID onto targetID and deletes
+// sourceID, in one transaction. Junction rows that already exist for the
+// target are dropped instead of duplicated. It returns the number of rows
+// moved per table and the leads whose contacts gained the target tag, which
+// need their tags pushed to Kommo.
+func (r *TagRepository) MergeTagsForAccount(ctx context.Context, accountID, sourceID, targetID uuid.UUID) (map[string]int64, []uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1::text))`, accountID); err != nil {
+		return nil, nil, err
+	}
+	var found int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM tags WHERE id = ANY($1::uuid[]) AND account_id=$2`, []uuid.UUID{sourceID, targetID}, accountID).Scan(&found); err != nil {
+		return nil, nil, err
+	}
+	if found != 2 {
+		return nil, nil, ErrCRMNotFound
+	}
+
+	var leadIDs []uuid.UUID
+	rows, err := tx.Query(ctx, `
+		SELECT l.id FROM leads l JOIN contact_tags ct ON ct.contact_id=l.contact_id
+		WHERE ct.tag_id=$1 AND l.account_id=$2
+	`, sourceID, accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		leadIDs = append(leadIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	moved := make(map[string]int64)
+	junctions := []struct{ table, column string }{
+		{"contact_tags", "contact_id"},
+		{"chat_tags", "chat_id"},
+		{"participant_tags", "participant_id"},
+		{"event_tags", "event_id"},
+	}
+	for _, j := range junctions {
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`
+			INSERT INTO %[1]s(%[2]s, tag_id)
+			SELECT %[2]s, $2 FROM %[1]s WHERE tag_id=$1
+			ON CONFLICT DO NOTHING
+		`, j.table, j.column), sourceID, targetID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("merge %s: %w", j.table, err)
+		}
+		moved[j.table] = tag.RowsAffected()
+	}
+	for _, table := range []string{"auto_tag_rules", "crm_audit_events"} {
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE %s SET tag_id=$2 WHERE tag_id=$1 AND account_id=$3`, table), sourceID, targetID, accountID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("merge %s: %w", table, err)
+		}
+		moved[table] = tag.RowsAffected()
+	}
+	// The source's own junction rows go with it through ON DELETE CASCADE.
+	if _, err := tx.Exec(ctx, `DELETE FROM tags WHERE id=$1 AND account_id=$2`, sourceID, accountID); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return moved, leadIDs, nil
+}
+
+// AssignToChatContactForAccount adds tagIDs to the same-account contact behind
+// chatID. It returns that contact and only the tags that were newly added, so
+// callers can skip side effects for tags the contact already had.
+func (r *TagRepository) AssignToChatContactForAccount(ctx context.Context, accountID, chatID uuid.UUID, tagIDs []uuid.UUID) (*uuid.UUID, []uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `
+		INSERT INTO contact_tags(contact_id,tag_id)
+		SELECT ch.contact_id,t.id
+		FROM chats ch
+		JOIN contacts c ON c.id=ch.contact_id AND c.account_id=ch.account_id
+		JOIN tags t ON t.account_id=ch.account_id AND t.id=ANY($3::uuid[])
+		WHERE ch.account_id=$1 AND ch.id=$2
+		ON CONFLICT DO NOTHING
+		RETURNING contact_id,tag_id
+	`, accountID, chatID, tagIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var contactID *uuid.UUID
+	added := make([]uuid.UUID, 0, len(tagIDs))
+	for rows.Next() {
+		var cid, tagID uuid.UUID
+		if err := rows.Scan(&cid, &tagID); err != nil {
+			return nil, nil, err
+		}
+		contactID = &cid
+		added = append(added, tagID)
+	}
+	return contactID, added, rows.Err()
+}
+
 func (r *TagRepository) GetByEntityForAccount(ctx context.Context, accountID uuid.UUID, entityType string, entityID uuid.UUID) ([]*domain.Tag, error) {
 	var contactID uuid.UUID
 	var err error
@@ -78,3 +78,12 @@ func TestNormalizeStageDraftsRejectsInvalidLayouts(t *testing.T) {
 		})
 	}
 }
+
+func TestNotDeletedPredicate(t *testing.T) {
+	if got := NotDeleted(""); got != "deleted_at IS NULL" {
+		t.Fatalf("bare predicate = %q", got)
+	}
+	if got := NotDeleted("l"); got != "l.deleted_at IS NULL" {
+		t.Fatalf("aliased predicate = %q", got)
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// RecordEvent appends an entry to the device's connection history.
+func (r *DeviceRepository) RecordEvent(ctx context.Context, accountID, deviceID uuid.UUID, eventType, detail string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO device_events (account_id, device_id, event_type, detail)
+		VALUES ($1, $2, $3, $4)
+	`, accountID, deviceID, eventType, detail)
+	return err
+}
+
+// ListEvents returns the device's most recent connection events, newest first.
+func (r *DeviceRepository) ListEvents(ctx context.Context, accountID, deviceID uuid.UUID, limit int) ([]*domain.DeviceEvent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, account_id, device_id, event_type, detail, created_at
+		FROM device_events
+		WHERE account_id = $1 AND device_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, accountID, deviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.DeviceEvent{}
+	for rows.Next() {
+		evt := &domain.DeviceEvent{}
+		if err := rows.Scan(&evt.ID, &evt.AccountID, &evt.DeviceID, &evt.Type, &evt.Detail, &evt.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, evt)
+	}
+	return events, rows.Err()
+}
+
+// PruneEvents deletes connection events older than retention.
+func (r *DeviceRepository) PruneEvents(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM device_events WHERE created_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -50,6 +50,7 @@ type EventParticipantCandidateCounts struct {
 type EventParticipantCandidateFilter struct {
 	Search   string
 	TagIDs   []uuid.UUID
+	TagMatch string
 	HasPhone *bool
 	Limit    int
 	Offset   int
@@ -394,11 +395,19 @@ func (r *EventRepository) ListParticipantCandidates(ctx context.Context, account
 	}
 	if len(filter.TagIDs) > 0 {
 		tagArg := len(args) + 1
-		where += fmt.Sprintf(` AND EXISTS (
-			SELECT 1 FROM contact_tags ct JOIN tags t ON t.id=ct.tag_id AND t.account_id=$1
-			WHERE ct.contact_id=c.id AND ct.tag_id=ANY($%d::uuid[])
-		)`, tagArg)
-		args = append(args, filter.TagIDs)
+		if filter.TagMatch == domain.TagMatchAll {
+			where += fmt.Sprintf(` AND (
+				SELECT COUNT(DISTINCT ct.tag_id) FROM contact_tags ct JOIN tags t ON t.id=ct.tag_id AND t.account_id=$1
+				WHERE ct.contact_id=c.id AND ct.tag_id=ANY($%d::uuid[])
+			) = cardinality($%d::uuid[])`, tagArg, tagArg)
+			args = append(args, dedupeUUIDs(filter.TagIDs))
+		} else {
+			where += fmt.Sprintf(` AND EXISTS (
+				SELECT 1 FROM contact_tags ct JOIN tags t ON t.id=ct.tag_id AND t.account_id=$1
+				WHERE ct.contact_id=c.id AND ct.tag_id=ANY($%d::uuid[])
+			)`, tagArg)
+			args = append(args, filter.TagIDs)
+		}
 	}
 	if filter.HasPhone != nil {
 		if *filter.HasPhone {
@@ -1044,7 +1053,7 @@ func (r *ParticipantRepository) EnrichRelatedLeads(ctx context.Context, accountI
 		JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
 		LEFT JOIN pipelines p ON p.id=l.pipeline_id AND p.account_id=l.account_id
 		LEFT JOIN pipeline_stages ps ON ps.id=l.stage_id AND ps.pipeline_id=l.pipeline_id
-		WHERE l.account_id=$1 AND l.contact_id=ANY($2::uuid[]) AND l.deleted_at IS NULL
+		WHERE l.account_id=$1 AND l.contact_id=ANY($2::uuid[]) AND `+NotDeleted("l")+`
 		ORDER BY l.contact_id,
 		         CASE WHEN COALESCE(l.status,'open')='open' AND l.is_archived=FALSE THEN 0 ELSE 1 END,
 		         l.updated_at DESC,l.id DESC
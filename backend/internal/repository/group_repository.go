@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// GroupRepository persists WhatsApp group metadata.
+type GroupRepository struct {
+	db *pgxpool.Pool
+}
+
+// Upsert stores the group's current metadata, replacing the participant
+// list. A non-empty subject is also copied to the group's chat so the inbox
+// shows the group name rather than the last sender.
+func (r *GroupRepository) Upsert(ctx context.Context, g *domain.WhatsAppGroup) error {
+	if g.Participants == nil {
+		g.Participants = []domain.WhatsAppGroupParticipant{}
+	}
+	participantsJSON, _ := json.Marshal(g.Participants)
+	if err := r.db.QueryRow(ctx, `
+		INSERT INTO whatsapp_groups (account_id, device_id, jid, subject, topic, owner_jid, is_announce, is_locked, participants, participant_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (account_id, jid) DO UPDATE SET
+			device_id = COALESCE(EXCLUDED.device_id, whatsapp_groups.device_id),
+			subject = EXCLUDED.subject,
+			topic = EXCLUDED.topic,
+			owner_jid = EXCLUDED.owner_jid,
+			is_announce = EXCLUDED.is_announce,
+			is_locked = EXCLUDED.is_locked,
+			participants = EXCLUDED.participants,
+			participant_count = EXCLUDED.participant_count,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, g.AccountID, g.DeviceID, g.JID, g.Subject, g.Topic, g.OwnerJID, g.IsAnnounce, g.IsLocked,
+		participantsJSON, g.ParticipantCount).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return err
+	}
+	if strings.TrimSpace(g.Subject) == "" {
+		return nil
+	}
+	_, err := r.db.Exec(ctx, `
+		UPDATE chats SET name = $3, updated_at = NOW()
+		WHERE account_id = $1 AND jid = $2 AND name IS DISTINCT FROM $3
+	`, g.AccountID, g.JID, g.Subject)
+	return err
+}
+
+// GetByJID returns the stored metadata of one group, or nil when unknown.
+func (r *GroupRepository) GetByJID(ctx context.Context, accountID uuid.UUID, jid string) (*domain.WhatsAppGroup, error) {
+	g, err := scanWhatsAppGroup(r.db.QueryRow(ctx, groupSelectSQL+`
+		WHERE g.account_id = $1 AND g.jid = $2
+	`, accountID, jid))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return g, err
+}
+
+// ListByAccount returns the account's groups ordered by subject, optionally
+// limited to the groups seen by one device.
+func (r *GroupRepository) ListByAccount(ctx context.Context, accountID uuid.UUID, deviceID *uuid.UUID) ([]*domain.WhatsAppGroup, error) {
+	rows, err := r.db.Query(ctx, groupSelectSQL+`
+		WHERE g.account_id = $1 AND ($2::uuid IS NULL OR g.device_id = $2)
+		ORDER BY LOWER(g.subject), g.jid
+	`, accountID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*domain.WhatsAppGroup{}
+	for rows.Next() {
+		g, err := scanWhatsAppGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+const groupSelectSQL = `
+	SELECT g.id, g.account_id, g.device_id, g.jid, g.subject, g.topic, g.owner_jid,
+	       g.is_announce, g.is_locked, g.participants, g.participant_count,
+	       (SELECT c.id FROM chats c WHERE c.account_id = g.account_id AND c.jid = g.jid AND c.channel_key = 'whatsapp_web'),
+	       g.created_at, g.updated_at
+	FROM whatsapp_groups g
+`
+
+func scanWhatsAppGroup(row pgx.Row) (*domain.WhatsAppGroup, error) {
+	g := &domain.WhatsAppGroup{}
+	var participantsJSON []byte
+	if err := row.Scan(
+		&g.ID, &g.AccountID, &g.DeviceID, &g.JID, &g.Subject, &g.Topic, &g.OwnerJID,
+		&g.IsAnnounce, &g.IsLocked, &participantsJSON, &g.ParticipantCount,
+		&g.ChatID, &g.CreatedAt, &g.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal(participantsJSON, &g.Participants)
+	if g.Participants == nil {
+		g.Participants = []domain.WhatsAppGroupParticipant{}
+	}
+	return g, nil
+}
+
+// ResolveParticipantContacts fills ContactID and ContactName of each
+// participant that matches one of the account's live (not trashed) contacts
+// by JID or phone.
+func (r *GroupRepository) ResolveParticipantContacts(ctx context.Context, accountID uuid.UUID, participants []domain.WhatsAppGroupParticipant) error {
+	jids := make([]string, 0, len(participants))
+	phones := make([]string, 0, len(participants))
+	for _, p := range participants {
+		if jid := strings.ToLower(strings.TrimSpace(p.JID)); jid != "" {
+			jids = append(jids, jid)
+		}
+		if phone := phoneFromJID(p.Phone); phone != "" {
+			phones = append(phones, phone)
+		}
+	}
+	if len(jids) == 0 && len(phones) == 0 {
+		return nil
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT c.id, LOWER(BTRIM(COALESCE(c.jid, ''))), REGEXP_REPLACE(COALESCE(c.phone, ''), '[^0-9]', '', 'g'),
+		       COALESCE(NULLIF(c.custom_name, ''), NULLIF(c.name, ''), NULLIF(c.push_name, ''), '')
+		FROM contacts c
+		WHERE c.account_id = $1 AND c.is_group = FALSE AND c.deleted_at IS NULL
+		  AND (LOWER(BTRIM(c.jid)) = ANY($2) OR REGEXP_REPLACE(COALESCE(c.phone, ''), '[^0-9]', '', 'g') = ANY($3))
+	`, accountID, jids, phones)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type match struct {
+		id   uuid.UUID
+		name string
+	}
+	byJID := map[string]match{}
+	byPhone := map[string]match{}
+	for rows.Next() {
+		var m match
+		var jid, phone string
+		if err := rows.Scan(&m.id, &jid, &phone, &m.name); err != nil {
+			return err
+		}
+		if jid != "" {
+			byJID[jid] = m
+		}
+		if phone != "" {
+			byPhone[phone] = m
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range participants {
+		m, ok := byJID[strings.ToLower(strings.TrimSpace(participants[i].JID))]
+		if !ok {
+			m, ok = byPhone[phoneFromJID(participants[i].Phone)]
+		}
+		if !ok {
+			continue
+		}
+		id := m.id
+		participants[i].ContactID = &id
+		participants[i].ContactName = m.name
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ type EnvKommoInstance struct {
 	ClientID      string
 	ClientSecret  string
 	AccessToken   string
+	RefreshToken  string
 	RedirectURI   string
 	WebhookSecret string
 }
@@ -173,6 +174,7 @@ func (r *IntegrationRepository) Update(ctx context.Context, instance *domain.Int
 		    client_secret = CASE WHEN $8 = '' THEN client_secret ELSE $8 END,
 		    access_token = CASE WHEN $9 = '' THEN access_token ELSE $9 END,
 		    refresh_token = CASE WHEN $10 = '' THEN refresh_token ELSE $10 END,
+		    token_expires_at = CASE WHEN $9 = '' THEN token_expires_at ELSE NULL END,
 		    redirect_uri = $11,
 		    webhook_secret = CASE WHEN $12 = '' THEN webhook_secret ELSE $12 END,
 		    config = $13::jsonb,
@@ -250,11 +252,11 @@ func (r *IntegrationRepository) EnsureDefaultKommoInstance(ctx context.Context,
 	var instanceID uuid.UUID
 	err := r.db.QueryRow(ctx, `
 		INSERT INTO integration_instances
-			(provider, scope, name, status, is_active, subdomain, client_id, client_secret, access_token, redirect_uri, webhook_secret, config)
-		VALUES ($1, $2, $3, $4, TRUE, $5, $6, $7, $8, $9, $10, '{}'::jsonb)
+			(provider, scope, name, status, is_active, subdomain, client_id, client_secret, access_token, refresh_token, redirect_uri, webhook_secret, config)
+		VALUES ($1, $2, $3, $4, TRUE, $5, $6, $7, $8, $9, $10, $11, '{}'::jsonb)
 		ON CONFLICT (provider, name) DO NOTHING
 		RETURNING id
-	`, domain.IntegrationProviderKommo, domain.IntegrationScopeMultiAccount, name, domain.IntegrationStatusActive, env.Subdomain, env.ClientID, env.ClientSecret, env.AccessToken, env.RedirectURI, env.WebhookSecret).Scan(&instanceID)
+	`, domain.IntegrationProviderKommo, domain.IntegrationScopeMultiAccount, name, domain.IntegrationStatusActive, env.Subdomain, env.ClientID, env.ClientSecret, env.AccessToken, env.RefreshToken, env.RedirectURI, env.WebhookSecret).Scan(&instanceID)
 	if err != nil {
 		return nil, err
 	}
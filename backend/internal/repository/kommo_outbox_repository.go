@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// kommoOpLeadMappedFields is kommo.OpLeadMappedFields; the Kommo outbox
+// worker pushes these rows.
+const kommoOpLeadMappedFields = "lead_mapped_fields"
+
+// changedCustomFieldKeys lists, sorted, the custom_fields keys whose value
+// differs between before and after, removed keys included.
+func changedCustomFieldKeys(before, after map[string]interface{}) []string {
+	var keys []string
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// enqueueKommoMappedFieldsTx queues a Kommo push of the changed custom_fields
+// keys that are mapped to Kommo fields, for leads linked to Kommo in
+// accounts with an enabled integration. A pending row for the lead absorbs
+// the keys so successive edits accumulate until the outbox flushes.
+func enqueueKommoMappedFieldsTx(ctx context.Context, tx pgx.Tx, accountID, leadID uuid.UUID, keys []string) error {
+	_, err := tx.Exec(ctx, `
+		WITH target AS (
+			SELECT l.kommo_id, ia.integration_instance_id,
+			       (SELECT jsonb_agg(m.clarin_key ORDER BY m.clarin_key)
+			        FROM kommo_field_mappings m
+			        WHERE m.account_id = l.account_id AND m.clarin_key = ANY($3::text[])) AS keys
+			FROM leads l
+			JOIN accounts a ON a.id = l.account_id AND COALESCE(a.kommo_enabled, false)
+			JOIN integration_instance_accounts ia ON ia.account_id = l.account_id AND ia.enabled
+			WHERE l.id = $1 AND l.account_id = $2 AND l.kommo_id > 0
+			ORDER BY ia.created_at
+			LIMIT 1
+		), merged AS (
+			UPDATE kommo_push_outbox o
+			SET payload = jsonb_build_object('keys', (
+			        SELECT jsonb_agg(DISTINCT k ORDER BY k)
+			        FROM jsonb_array_elements_text(COALESCE(o.payload->'keys', '[]'::jsonb) || t.keys) k)),
+			    kommo_entity_id = t.kommo_id,
+			    enqueued_at = NOW(),
+			    attempts = 0,
+			    last_error = NULL
+			FROM target t
+			WHERE t.keys IS NOT NULL
+			  AND o.entity_id = $1 AND o.operation = $4
+			  AND o.processing_started_at IS NULL
+			  AND o.integration_instance_id IS NOT DISTINCT FROM t.integration_instance_id
+			RETURNING o.id
+		)
+		INSERT INTO kommo_push_outbox (id, integration_instance_id, account_id, operation, entity_id, kommo_entity_id, payload)
+		SELECT gen_random_uuid(), t.integration_instance_id, $2, $4, $1, t.kommo_id, jsonb_build_object('keys', t.keys)
+		FROM target t
+		WHERE t.keys IS NOT NULL AND NOT EXISTS (SELECT 1 FROM merged)
+	`, leadID, accountID, keys, kommoOpLeadMappedFields)
+	return err
+}
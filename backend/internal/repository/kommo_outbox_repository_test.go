@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedCustomFieldKeys(t *testing.T) {
+	before := map[string]interface{}{"same": "a", "edited": float64(1), "removed": "x", "list": []interface{}{"a"}}
+	after := map[string]interface{}{"same": "a", "edited": float64(2), "added": true, "list": []interface{}{"a"}}
+
+	got := changedCustomFieldKeys(before, after)
+	want := []string{"added", "edited", "removed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changedCustomFieldKeys = %v, want %v", got, want)
+	}
+	if keys := changedCustomFieldKeys(after, after); len(keys) != 0 {
+		t.Fatalf("unchanged fields reported as changed: %v", keys)
+	}
+}
@@ -0,0 +1,196 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
+	"github.com/naperu/clarin/pkg/config"
+	"github.com/naperu/clarin/pkg/database"
+)
+
+func TestMoveLeadsToStageSkipsIneligibleLeadsAndRecordsHistory(t *testing.T) {
+	if os.Getenv("CLARIN_RUN_MIGRATION_INTEGRATION") != "1" {
+		t.Skip("set CLARIN_RUN_MIGRATION_INTEGRATION=1 in an isolated PostgreSQL environment")
+	}
+	rawURL := os.Getenv("DATABASE_URL")
+	if rawURL == "" {
+		t.Fatal("DATABASE_URL is required")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse DATABASE_URL: %v", err)
+	}
+	const databaseName = "clarin_bulk_stage_test"
+	adminURL := *parsed
+	adminURL.Path = "/postgres"
+	testURL := *parsed
+	testURL.Path = "/" + databaseName
+
+	ctx := context.Background()
+	admin, err := pgxpool.New(ctx, adminURL.String())
+	if err != nil {
+		t.Fatalf("connect admin database: %v", err)
+	}
+	defer admin.Close()
+	_, _ = admin.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname=$1 AND pid<>pg_backend_pid()`, databaseName)
+	_, _ = admin.Exec(ctx, `DROP DATABASE IF EXISTS `+databaseName)
+	if _, err := admin.Exec(ctx, `CREATE DATABASE `+databaseName); err != nil {
+		t.Fatalf("create disposable database: %v", err)
+	}
+	defer func() {
+		_, _ = admin.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname=$1 AND pid<>pg_backend_pid()`, databaseName)
+		_, _ = admin.Exec(ctx, `DROP DATABASE IF EXISTS `+databaseName)
+	}()
+
+	db, err := pgxpool.New(ctx, testURL.String())
+	if err != nil {
+		t.Fatalf("connect disposable database: %v", err)
+	}
+	defer db.Close()
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// lead_stage_changes is created alongside the admin seed.
+	if err := database.SeedAdmin(db, &config.Config{AdminUser: "admin", AdminEmail: "admin@example.com", AdminPassword: "admin-password"}); err != nil {
+		t.Fatalf("seed admin: %v", err)
+	}
+
+	accountID, otherAccountID := uuid.New(), uuid.New()
+	pipelineID, otherPipelineID, foreignPipelineID := uuid.New(), uuid.New(), uuid.New()
+	newStage, contactedStage, lostStage := uuid.New(), uuid.New(), uuid.New()
+	otherStage, foreignStage := uuid.New(), uuid.New()
+	if _, err := db.Exec(ctx, `INSERT INTO accounts(id,name) VALUES ($1,'Bulk account'),($2,'Other bulk account')`, accountID, otherAccountID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `
+		INSERT INTO pipelines(id,account_id,name) VALUES ($1,$4,'Ventas'),($2,$4,'Postventa'),($3,$5,'Ajeno')
+	`, pipelineID, otherPipelineID, foreignPipelineID, accountID, otherAccountID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `
+		INSERT INTO pipeline_stages(id,pipeline_id,name,position,stage_type) VALUES
+			($1,$6,'Nuevo',0,'active'),($2,$6,'Contactado',1,'active'),($3,$6,'Perdido',2,'lost'),
+			($4,$7,'Seguimiento',0,'active'),($5,$8,'Ajena',0,'active')
+	`, newStage, contactedStage, lostStage, otherStage, foreignStage, pipelineID, otherPipelineID, foreignPipelineID); err != nil {
+		t.Fatal(err)
+	}
+
+	inNew, alreadyThere, otherPipeline, unassigned, trashed, missing := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	if _, err := db.Exec(ctx, `
+		INSERT INTO leads(id,account_id,jid,pipeline_id,stage_id,deleted_at) VALUES
+			($1,$6,'51999000401@s.whatsapp.net',$7,$8,NULL),
+			($2,$6,'51999000402@s.whatsapp.net',$7,$9,NULL),
+			($3,$6,'51999000403@s.whatsapp.net',$10,$11,NULL),
+			($4,$6,'51999000404@s.whatsapp.net',NULL,NULL,NULL),
+			($5,$6,'51999000405@s.whatsapp.net',$7,$8,NOW())
+	`, inNew, alreadyThere, otherPipeline, unassigned, trashed, accountID, pipelineID, newStage, contactedStage, otherPipelineID, otherStage); err != nil {
+		t.Fatal(err)
+	}
+
+	repos := repository.NewRepositories(db)
+	leadIDs := []uuid.UUID{inNew, alreadyThere, otherPipeline, unassigned, trashed, missing}
+
+	if _, err := repos.Lead.MoveLeadsToStage(ctx, accountID, leadIDs, foreignStage, "", nil); !errors.Is(err, repository.ErrCRMNotFound) {
+		t.Fatalf("stage of another account: err = %v, want ErrCRMNotFound", err)
+	}
+	if _, err := repos.Lead.MoveLeadsToStage(ctx, accountID, leadIDs, lostStage, " ", nil); !errors.Is(err, repository.ErrLostReasonRequired) {
+		t.Fatalf("lost stage without reason: err = %v, want ErrLostReasonRequired", err)
+	}
+	var stageAfterRejected uuid.UUID
+	if err := db.QueryRow(ctx, `SELECT stage_id FROM leads WHERE id=$1`, inNew).Scan(&stageAfterRejected); err != nil {
+		t.Fatal(err)
+	}
+	if stageAfterRejected != newStage {
+		t.Fatalf("rejected move changed the lead's stage to %s", stageAfterRejected)
+	}
+
+	result, err := repos.Lead.MoveLeadsToStage(ctx, accountID, leadIDs, contactedStage, "", nil)
+	if err != nil {
+		t.Fatalf("MoveLeadsToStage: %v", err)
+	}
+	want := []uuid.UUID{inNew, unassigned}
+	sort.Slice(want, func(i, j int) bool { return want[i].String() < want[j].String() })
+	if len(result.Moved) != len(want) || result.Moved[0] != want[0] || result.Moved[1] != want[1] {
+		t.Fatalf("moved = %v, want %v", result.Moved, want)
+	}
+	if result.Skipped != 4 {
+		t.Fatalf("skipped = %d, want 4", result.Skipped)
+	}
+
+	for _, id := range want {
+		var gotPipeline, gotStage uuid.UUID
+		var status string
+		if err := db.QueryRow(ctx, `SELECT pipeline_id, stage_id, status FROM leads WHERE id=$1`, id).Scan(&gotPipeline, &gotStage, &status); err != nil {
+			t.Fatal(err)
+		}
+		if gotPipeline != pipelineID || gotStage != contactedStage || status != "open" {
+			t.Fatalf("lead %s: pipeline=%s stage=%s status=%s", id, gotPipeline, gotStage, status)
+		}
+	}
+	var trashedStage uuid.UUID
+	if err := db.QueryRow(ctx, `SELECT stage_id FROM leads WHERE id=$1`, trashed).Scan(&trashedStage); err != nil {
+		t.Fatal(err)
+	}
+	if trashedStage != newStage {
+		t.Fatalf("trashed lead was moved to %s", trashedStage)
+	}
+
+	rows, err := db.Query(ctx, `SELECT lead_id, from_stage_name, to_stage_name FROM lead_stage_changes WHERE account_id=$1`, accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	history := map[uuid.UUID]string{}
+	for rows.Next() {
+		var leadID uuid.UUID
+		var fromName, toName string
+		if err := rows.Scan(&leadID, &fromName, &toName); err != nil {
+			t.Fatal(err)
+		}
+		history[leadID] = fromName + "->" + toName
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[inNew] != "Nuevo->Contactado" || history[unassigned] != "->Contactado" {
+		t.Fatalf("stage history = %v", history)
+	}
+
+	agentID := uuid.New()
+	if _, err := db.Exec(ctx, `INSERT INTO users(id,account_id,username,email,password_hash) VALUES ($1,$2,'bulk-agent','bulk-agent@example.test','test')`, agentID, accountID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `UPDATE leads SET assigned_to=$1 WHERE id=$2`, agentID, inNew); err != nil {
+		t.Fatal(err)
+	}
+	scopeIDs := []uuid.UUID{unassigned, missing, inNew, otherPipeline}
+	own, err := repos.Lead.FilterVisible(ctx, accountID, scopeIDs, &domain.AssignmentScope{UserID: agentID})
+	if err != nil {
+		t.Fatalf("FilterVisible: %v", err)
+	}
+	if len(own) != 1 || own[0] != inNew {
+		t.Fatalf("visible to agent = %v, want [%s]", own, inNew)
+	}
+	withUnassigned, err := repos.Lead.FilterVisible(ctx, accountID, scopeIDs, &domain.AssignmentScope{UserID: agentID, IncludeUnassigned: true})
+	if err != nil {
+		t.Fatalf("FilterVisible: %v", err)
+	}
+	if len(withUnassigned) != 3 || withUnassigned[0] != unassigned || withUnassigned[1] != inNew || withUnassigned[2] != otherPipeline {
+		t.Fatalf("visible with unassigned = %v", withUnassigned)
+	}
+	foreign, err := repos.Lead.FilterVisible(ctx, otherAccountID, scopeIDs, nil)
+	if err != nil {
+		t.Fatalf("FilterVisible: %v", err)
+	}
+	if len(foreign) != 0 {
+		t.Fatalf("leads visible from another account = %v", foreign)
+	}
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// GetScoringRules returns the account's lead scoring rules, or nil when the
+// account never configured any.
+func (r *LeadRepository) GetScoringRules(ctx context.Context, accountID uuid.UUID) ([]domain.LeadScoringRule, error) {
+	var raw []byte
+	err := r.db.QueryRow(ctx, `SELECT rules FROM lead_scoring_rules WHERE account_id = $1`, accountID).Scan(&raw)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules := []domain.LeadScoringRule{}
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *LeadRepository) UpsertScoringRules(ctx context.Context, accountID uuid.UUID, rules []domain.LeadScoringRule) error {
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO lead_scoring_rules (account_id, rules) VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE SET rules = EXCLUDED.rules, updated_at = NOW()
+	`, accountID, raw)
+	return err
+}
+
+// SetScore stores a recalculated score without touching updated_at, so
+// scoring never reorders "recently updated" lists.
+func (r *LeadRepository) SetScore(ctx context.Context, leadID uuid.UUID, score int) error {
+	_, err := r.db.Exec(ctx, `UPDATE leads SET score = $2 WHERE id = $1 AND score <> $2`, leadID, score)
+	return err
+}
+
+// LastInboundMessageAt returns when the lead's contact last wrote on
+// WhatsApp, or nil when it never did.
+func (r *LeadRepository) LastInboundMessageAt(ctx context.Context, accountID, leadID uuid.UUID) (*time.Time, error) {
+	var at *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT MAX(m.timestamp)
+		FROM leads l
+		JOIN chats ch ON ch.account_id = l.account_id
+		             AND ((l.contact_id IS NOT NULL AND ch.contact_id = l.contact_id) OR (NULLIF(l.jid, '') IS NOT NULL AND ch.jid = l.jid))
+		JOIN messages m ON m.chat_id = ch.id AND m.is_from_me = FALSE
+		WHERE l.account_id = $1 AND l.id = $2
+	`, accountID, leadID).Scan(&at)
+	return at, err
+}
+
+// ListLiveIDs returns the ids of the account's leads that are not in the trash.
+func (r *LeadRepository) ListLiveIDs(ctx context.Context, accountID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT id FROM leads WHERE account_id = $1 AND `+NotDeleted(""), accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListAccountsWithLiveLeads returns the accounts that have at least one lead
+// outside the trash.
+func (r *LeadRepository) ListAccountsWithLiveLeads(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT DISTINCT account_id FROM leads WHERE `+NotDeleted(""))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
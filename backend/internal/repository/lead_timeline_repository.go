@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// insertLeadStageChange records a stage move for the lead timeline. Nothing
+// is written when the lead stays in the same stage.
+func insertLeadStageChange(ctx context.Context, tx pgx.Tx, accountID, leadID uuid.UUID, fromStageID *uuid.UUID, toStageID uuid.UUID, changedBy *uuid.UUID) error {
+	if fromStageID != nil && *fromStageID == toStageID {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO lead_stage_changes (account_id, lead_id, from_stage_id, to_stage_id, from_stage_name, to_stage_name, changed_by)
+		VALUES ($1, $2, $3, $4,
+		        COALESCE((SELECT name FROM pipeline_stages WHERE id = $3), ''),
+		        COALESCE((SELECT name FROM pipeline_stages WHERE id = $4), ''),
+		        $5)
+	`, accountID, leadID, fromStageID, toStageID, changedBy)
+	return err
+}
+
+// ListStageChanges returns the lead's stage moves that sort before the
+// (before, beforeID) cursor, newest first.
+func (r *LeadRepository) ListStageChanges(ctx context.Context, accountID, leadID uuid.UUID, before time.Time, beforeID uuid.UUID, limit int) ([]*domain.LeadStageChange, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT sc.id, sc.account_id, sc.lead_id, sc.from_stage_id, sc.to_stage_id, sc.from_stage_name, sc.to_stage_name,
+		       sc.changed_by, u.display_name, sc.created_at
+		FROM lead_stage_changes sc
+		LEFT JOIN users u ON u.id = sc.changed_by
+		WHERE sc.account_id = $1 AND sc.lead_id = $2 AND (sc.created_at, sc.id) < ($3, $4)
+		ORDER BY sc.created_at DESC, sc.id DESC
+		LIMIT $5
+	`, accountID, leadID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	changes := []*domain.LeadStageChange{}
+	for rows.Next() {
+		sc := &domain.LeadStageChange{}
+		if err := rows.Scan(&sc.ID, &sc.AccountID, &sc.LeadID, &sc.FromStageID, &sc.ToStageID, &sc.FromStageName, &sc.ToStageName,
+			&sc.ChangedBy, &sc.ChangedByName, &sc.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, sc)
+	}
+	return changes, rows.Err()
+}
+
+// ListByLeadBefore returns the lead's interactions that sort before the
+// (before, beforeID) cursor, newest first.
+func (r *InteractionRepository) ListByLeadBefore(ctx context.Context, accountID, leadID uuid.UUID, before time.Time, beforeID uuid.UUID, limit int) ([]*domain.Interaction, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT i.id, i.account_id, i.contact_id, i.lead_id, i.event_id, i.participant_id, i.type, i.direction, i.outcome, i.notes, i.next_action, i.next_action_date, i.created_by, i.created_at,
+		       u.display_name as created_by_name
+		FROM interactions i
+		LEFT JOIN users u ON u.id = i.created_by
+		WHERE i.account_id = $1 AND i.lead_id = $2 AND (i.created_at, i.id) < ($3, $4)
+		ORDER BY i.created_at DESC, i.id DESC
+		LIMIT $5
+	`, accountID, leadID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	interactions := []*domain.Interaction{}
+	for rows.Next() {
+		it := &domain.Interaction{}
+		if err := rows.Scan(&it.ID, &it.AccountID, &it.ContactID, &it.LeadID, &it.EventID, &it.ParticipantID, &it.Type, &it.Direction, &it.Outcome, &it.Notes, &it.NextAction, &it.NextActionDate, &it.CreatedBy, &it.CreatedAt, &it.CreatedByName); err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, it)
+	}
+	return interactions, rows.Err()
+}
+
+// ListForLeadBefore returns WhatsApp messages from the chats of the lead's
+// contact or JID that sort before the (before, beforeID) cursor, newest first.
+func (r *MessageRepository) ListForLeadBefore(ctx context.Context, accountID, leadID uuid.UUID, before time.Time, beforeID uuid.UUID, limit int) ([]*domain.Message, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT m.id, m.account_id, m.device_id, m.chat_id, m.message_id, m.from_jid, m.from_name, m.body,
+		       m.message_type, m.media_url, m.media_mimetype, m.media_filename, m.media_size,
+		       m.is_from_me, m.status, COALESCE(m.is_revoked, false), m.timestamp, m.created_at
+		FROM leads l
+		JOIN chats ch ON ch.account_id = l.account_id
+		             AND ((l.contact_id IS NOT NULL AND ch.contact_id = l.contact_id) OR (NULLIF(l.jid, '') IS NOT NULL AND ch.jid = l.jid))
+		JOIN messages m ON m.chat_id = ch.id
+		WHERE l.account_id = $1 AND l.id = $2 AND (m.timestamp, m.id) < ($3, $4)
+		ORDER BY m.timestamp DESC, m.id DESC
+		LIMIT $5
+	`, accountID, leadID, before, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := []*domain.Message{}
+	for rows.Next() {
+		msg := &domain.Message{}
+		if err := rows.Scan(
+			&msg.ID, &msg.AccountID, &msg.DeviceID, &msg.ChatID, &msg.MessageID, &msg.FromJID, &msg.FromName, &msg.Body,
+			&msg.MessageType, &msg.MediaURL, &msg.MediaMimetype, &msg.MediaFilename, &msg.MediaSize,
+			&msg.IsFromMe, &msg.Status, &msg.IsRevoked, &msg.Timestamp, &msg.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+type LeadWebhookTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+const leadWebhookTokenColumns = `id, account_id, label, token_hash, token_prefix, created_by,
+	revoked_at, last_used_at, use_count, created_at`
+
+func scanLeadWebhookToken(row pgx.Row) (*domain.LeadWebhookToken, error) {
+	t := &domain.LeadWebhookToken{}
+	if err := row.Scan(&t.ID, &t.AccountID, &t.Label, &t.TokenHash, &t.TokenPrefix, &t.CreatedBy,
+		&t.RevokedAt, &t.LastUsedAt, &t.UseCount, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *LeadWebhookTokenRepository) Create(ctx context.Context, t *domain.LeadWebhookToken) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO lead_webhook_tokens (account_id, label, token_hash, token_prefix, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, t.AccountID, t.Label, t.TokenHash, t.TokenPrefix, t.CreatedBy).Scan(&t.ID, &t.CreatedAt)
+}
+
+func (r *LeadWebhookTokenRepository) ListByAccount(ctx context.Context, accountID uuid.UUID) ([]*domain.LeadWebhookToken, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+leadWebhookTokenColumns+` FROM lead_webhook_tokens
+		WHERE account_id = $1 ORDER BY created_at DESC LIMIT 100`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tokens := []*domain.LeadWebhookToken{}
+	for rows.Next() {
+		t, err := scanLeadWebhookToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// GetByHash returns the token even when revoked so callers can audit why it
+// was rejected. Returns nil when unknown.
+func (r *LeadWebhookTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.LeadWebhookToken, error) {
+	t, err := scanLeadWebhookToken(r.db.QueryRow(ctx, `SELECT `+leadWebhookTokenColumns+` FROM lead_webhook_tokens WHERE token_hash = $1`, tokenHash))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (r *LeadWebhookTokenRepository) Revoke(ctx context.Context, accountID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE lead_webhook_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND account_id = $2 AND revoked_at IS NULL
+	`, id, accountID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *LeadWebhookTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID) {
+	_, _ = r.db.Exec(ctx, `UPDATE lead_webhook_tokens SET last_used_at = NOW(), use_count = use_count + 1 WHERE id = $1`, id)
+}
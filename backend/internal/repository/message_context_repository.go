@@ -16,8 +16,8 @@ func (r *MessageRepository) GetByReference(ctx context.Context, accountID, chatI
 		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
 		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited,false), provider, template_name, timestamp, created_at,
 		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
-		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(media_deleted,false),
-		       latitude, longitude, contact_name, contact_phone, contact_vcard
+		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(is_forwarded,false), COALESCE(forwarding_score,0), COALESCE(media_deleted,false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, COALESCE(starred,false), edited_at
 		FROM messages
 		WHERE account_id=$1 AND chat_id=$2 AND (message_id=$3 OR id::text=$3)
 		ORDER BY CASE WHEN message_id=$3 THEN 0 ELSE 1 END
@@ -35,13 +35,13 @@ func (r *MessageRepository) GetWindowByChatID(ctx context.Context, accountID, ch
 		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
 		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited,false), provider, template_name, timestamp, created_at,
 		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
-		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(media_deleted,false),
-		       latitude, longitude, contact_name, contact_phone, contact_vcard
+		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(is_forwarded,false), COALESCE(forwarding_score,0), COALESCE(media_deleted,false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, COALESCE(starred,false), edited_at
 		FROM (
 			SELECT * FROM messages WHERE account_id=$1 AND chat_id=$2
-			ORDER BY timestamp DESC, id DESC LIMIT $3 OFFSET $4
+			ORDER BY timestamp DESC, COALESCE(created_at, timestamp) DESC, id DESC LIMIT $3 OFFSET $4
 		) page
-		ORDER BY timestamp ASC, id ASC
+		ORDER BY timestamp ASC, COALESCE(created_at, timestamp) ASC, id ASC
 	`, accountID, chatID, limit, offset)
 	if err != nil {
 		return nil, err
@@ -71,9 +71,9 @@ func scanContextMessage(scanner messageScanner) (*domain.Message, error) {
 		&message.IsFromMe, &message.IsRead, &message.Status, &message.DeliveredAt, &message.ReadAt, &message.IsEdited,
 		&message.Provider, &message.TemplateName,
 		&message.Timestamp, &message.CreatedAt, &message.QuotedMessageID, &message.QuotedBody,
-		&message.QuotedSender, &message.QuotedIsFromMe, &message.IsRevoked, &message.IsViewOnce, &message.MediaDeleted,
+		&message.QuotedSender, &message.QuotedIsFromMe, &message.IsRevoked, &message.IsViewOnce, &message.IsForwarded, &message.ForwardingScore, &message.MediaDeleted,
 		&message.Latitude, &message.Longitude, &message.ContactName, &message.ContactPhone,
-		&message.ContactVCard,
+		&message.ContactVCard, &message.Starred, &message.EditedAt,
 	); err != nil {
 		return nil, err
 	}
@@ -81,3 +81,77 @@ func scanContextMessage(scanner messageScanner) (*domain.Message, error) {
 }
 
 var _ messageScanner = pgx.Row(nil)
+
+// SetStarred flags or unflags a message inside the account and returns it,
+// or nil when the account has no such message.
+func (r *MessageRepository) SetStarred(ctx context.Context, accountID, id uuid.UUID, starred bool) (*domain.Message, error) {
+	row := r.db.QueryRow(ctx, `
+		UPDATE messages SET starred=$3
+		WHERE account_id=$1 AND id=$2
+		RETURNING id, account_id, device_id, chat_id, message_id, from_jid, from_name, body,
+		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
+		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited,false), provider, template_name, timestamp, created_at,
+		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
+		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(is_forwarded,false), COALESCE(forwarding_score,0), COALESCE(media_deleted,false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, starred, edited_at
+	`, accountID, id, starred)
+	message, err := scanContextMessage(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return message, err
+}
+
+// GetStarredByChatID lists the starred messages of a chat, newest first.
+func (r *MessageRepository) GetStarredByChatID(ctx context.Context, accountID, chatID uuid.UUID, limit, offset int) ([]*domain.Message, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM messages WHERE account_id=$1 AND chat_id=$2 AND starred = true
+	`, accountID, chatID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT id, account_id, device_id, chat_id, message_id, from_jid, from_name, body,
+		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
+		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited,false), provider, template_name, timestamp, created_at,
+		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
+		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(is_forwarded,false), COALESCE(forwarding_score,0), COALESCE(media_deleted,false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, starred, edited_at
+		FROM messages
+		WHERE account_id=$1 AND chat_id=$2 AND starred = true
+		ORDER BY timestamp DESC, id DESC LIMIT $3 OFFSET $4
+	`, accountID, chatID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	messages := make([]*domain.Message, 0)
+	for rows.Next() {
+		message, scanErr := scanContextMessage(rows)
+		if scanErr != nil {
+			return nil, 0, scanErr
+		}
+		messages = append(messages, message)
+	}
+	return messages, total, rows.Err()
+}
+
+// GetByIDForAccount loads a message by its local ID, or nil when the
+// account has no such message.
+func (r *MessageRepository) GetByIDForAccount(ctx context.Context, accountID, id uuid.UUID) (*domain.Message, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT id, account_id, device_id, chat_id, message_id, from_jid, from_name, body,
+		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
+		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited,false), provider, template_name, timestamp, created_at,
+		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
+		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(is_forwarded,false), COALESCE(forwarding_score,0), COALESCE(media_deleted,false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, COALESCE(starred,false), edited_at
+		FROM messages
+		WHERE account_id=$1 AND id=$2
+	`, accountID, id)
+	message, err := scanContextMessage(row)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return message, err
+}
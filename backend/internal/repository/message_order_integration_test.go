@@ -0,0 +1,110 @@
+package repository_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/repository"
+	"github.com/naperu/clarin/pkg/database"
+)
+
+func TestMessageHistoryOrderIsStableForEqualTimestamps(t *testing.T) {
+	if os.Getenv("CLARIN_RUN_MIGRATION_INTEGRATION") != "1" {
+		t.Skip("set CLARIN_RUN_MIGRATION_INTEGRATION=1 in an isolated PostgreSQL environment")
+	}
+	rawURL := os.Getenv("DATABASE_URL")
+	if rawURL == "" {
+		t.Fatal("DATABASE_URL is required")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse DATABASE_URL: %v", err)
+	}
+	const databaseName = "clarin_message_order_test"
+	adminURL := *parsed
+	adminURL.Path = "/postgres"
+	testURL := *parsed
+	testURL.Path = "/" + databaseName
+
+	ctx := context.Background()
+	admin, err := pgxpool.New(ctx, adminURL.String())
+	if err != nil {
+		t.Fatalf("connect admin database: %v", err)
+	}
+	defer admin.Close()
+	_, _ = admin.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname=$1 AND pid<>pg_backend_pid()`, databaseName)
+	_, _ = admin.Exec(ctx, `DROP DATABASE IF EXISTS `+databaseName)
+	if _, err := admin.Exec(ctx, `CREATE DATABASE `+databaseName); err != nil {
+		t.Fatalf("create disposable database: %v", err)
+	}
+	defer func() {
+		_, _ = admin.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname=$1 AND pid<>pg_backend_pid()`, databaseName)
+		_, _ = admin.Exec(ctx, `DROP DATABASE IF EXISTS `+databaseName)
+	}()
+
+	db, err := pgxpool.New(ctx, testURL.String())
+	if err != nil {
+		t.Fatalf("connect disposable database: %v", err)
+	}
+	defer db.Close()
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	accountID, chatID := uuid.New(), uuid.New()
+	if _, err := db.Exec(ctx, `INSERT INTO accounts(id,name) VALUES ($1,'Order account')`, accountID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO chats(id,account_id,jid) VALUES ($1,$2,'51999000333@s.whatsapp.net')`, chatID, accountID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Five messages share one WhatsApp timestamp but arrived one second apart,
+	// as happens when history sync replays a burst. Arrival order must win.
+	sentAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var want []string
+	for i := 0; i < 5; i++ {
+		messageID := "burst-" + string(rune('a'+i))
+		want = append(want, messageID)
+		if _, err := db.Exec(ctx, `
+			INSERT INTO messages(id,account_id,chat_id,message_id,body,timestamp,created_at)
+			VALUES ($1,$2,$3,$4,$4,$5,$6)
+		`, uuid.New(), accountID, chatID, messageID, sentAt, sentAt.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repos := repository.NewRepositories(db)
+	for attempt := 0; attempt < 3; attempt++ {
+		messages, err := repos.Message.GetByChatID(ctx, chatID, 10, 0)
+		if err != nil {
+			t.Fatalf("get messages: %v", err)
+		}
+		if len(messages) != len(want) {
+			t.Fatalf("got %d messages, want %d", len(messages), len(want))
+		}
+		for i, msg := range messages {
+			if msg.MessageID != want[i] {
+				t.Fatalf("position %d = %s, want %s", i, msg.MessageID, want[i])
+			}
+		}
+	}
+
+	// Paging from the newest end must split the burst without overlap.
+	newest, err := repos.Message.GetByChatID(ctx, chatID, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	older, err := repos.Message.GetByChatID(ctx, chatID, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newest[0].MessageID != "burst-d" || newest[1].MessageID != "burst-e" || older[2].MessageID != "burst-c" {
+		t.Fatalf("unexpected pages: newest=[%s %s] older tail=%s", newest[0].MessageID, newest[1].MessageID, older[2].MessageID)
+	}
+}
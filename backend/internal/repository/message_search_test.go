@@ -0,0 +1,17 @@
+package repository
+
+import "testing"
+
+func TestMessageSearchEscaperKeepsWildcardsLiteral(t *testing.T) {
+	cases := map[string]string{
+		"hola":       "hola",
+		"50%":        `50\%`,
+		"mi_archivo": `mi\_archivo`,
+		`c:\temp`:    `c:\\temp`,
+	}
+	for in, want := range cases {
+		if got := messageSearchEscaper.Replace(in); got != want {
+			t.Errorf("escape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
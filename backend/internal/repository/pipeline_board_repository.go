@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// Board returns the pipeline's stages in order, each with up to perStage of
+// its live, non-archived leads (most recently updated first, skipping the
+// first offset) and counts over the whole column. assignedTo narrows the
+// board to one agent; a non-nil scope hides the leads it cannot see.
+func (r *LeadRepository) Board(ctx context.Context, accountID, pipelineID uuid.UUID, assignedTo *uuid.UUID, scope *domain.AssignmentScope, perStage, offset int) ([]*domain.PipelineBoardColumn, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM pipelines WHERE id=$1 AND account_id=$2)`, pipelineID, accountID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCRMNotFound
+	}
+
+	boardLeads := `
+		SELECT l.id, l.stage_id, l.assigned_to, l.updated_at FROM leads l
+		WHERE l.account_id = $1 AND l.pipeline_id = $2 AND ` + NotDeleted("l") + ` AND l.is_archived = FALSE
+		  AND ($3::uuid IS NULL OR l.assigned_to = $3)
+		  AND ($4::uuid IS NULL OR l.assigned_to = $4 OR ($5::bool AND l.assigned_to IS NULL))
+	`
+	var scopeUser *uuid.UUID
+	scopeUnassigned := false
+	if scope != nil {
+		scopeUser = &scope.UserID
+		scopeUnassigned = scope.IncludeUnassigned
+	}
+	rows, err := r.db.Query(ctx, `
+		WITH board AS (`+boardLeads+`)
+		SELECT ps.id, ps.name, ps.color, ps.position, ps.stage_type,
+		       COUNT(b.id), COUNT(b.id) FILTER (WHERE b.assigned_to IS NULL)
+		FROM pipeline_stages ps
+		LEFT JOIN board b ON b.stage_id = ps.id
+		WHERE ps.pipeline_id = $2
+		GROUP BY ps.id, ps.name, ps.color, ps.position, ps.stage_type
+		ORDER BY ps.position, ps.id
+	`, accountID, pipelineID, assignedTo, scopeUser, scopeUnassigned)
+	if err != nil {
+		return nil, err
+	}
+	columns := []*domain.PipelineBoardColumn{}
+	byStage := make(map[uuid.UUID]*domain.PipelineBoardColumn)
+	for rows.Next() {
+		col := &domain.PipelineBoardColumn{Leads: []*domain.Lead{}}
+		if err := rows.Scan(&col.StageID, &col.Name, &col.Color, &col.Position, &col.StageType, &col.Total, &col.Unassigned); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		col.HasMore = offset+perStage < col.Total
+		columns = append(columns, col)
+		byStage[col.StageID] = col
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	leads, err := r.queryLeads(ctx, `
+		WITH ranked AS (
+			SELECT b.id, ROW_NUMBER() OVER (PARTITION BY b.stage_id ORDER BY b.updated_at DESC, b.id) AS rn
+			FROM (`+boardLeads+`) b
+			WHERE b.stage_id IS NOT NULL
+		)
+	`+leadListQuery+`
+		JOIN ranked rk ON rk.id = l.id
+		WHERE rk.rn > $6 AND rk.rn <= $6 + $7
+		ORDER BY l.stage_id, rk.rn
+	`, accountID, pipelineID, assignedTo, scopeUser, scopeUnassigned, offset, perStage)
+	if err != nil {
+		return nil, err
+	}
+	for _, lead := range leads {
+		if lead.StageID == nil {
+			continue
+		}
+		if col := byStage[*lead.StageID]; col != nil {
+			col.Leads = append(col.Leads, lead)
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// Funnel reports, per stage of the pipeline, how many leads entered it and
+// the median time they stayed, derived from lead_stage_changes. A stay ends
+// with the lead's next recorded move.
+func (r *PipelineRepository) Funnel(ctx context.Context, accountID, pipelineID uuid.UUID) ([]*domain.PipelineFunnelStage, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM pipelines WHERE id=$1 AND account_id=$2)`, pipelineID, accountID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCRMNotFound
+	}
+	rows, err := r.db.Query(ctx, `
+		WITH stays AS (
+			SELECT sc.lead_id, sc.from_stage_id, sc.to_stage_id, sc.created_at,
+			       LEAD(sc.created_at) OVER (PARTITION BY sc.lead_id ORDER BY sc.created_at, sc.id) AS left_at
+			FROM lead_stage_changes sc
+			WHERE sc.account_id = $1
+		)
+		SELECT ps.id, ps.name, ps.color, ps.position, ps.stage_type,
+		       COUNT(DISTINCT st.lead_id),
+		       COUNT(*) FILTER (WHERE st.lead_id IS NOT NULL AND st.from_stage_id IS NULL),
+		       COUNT(st.left_at),
+		       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM st.left_at - st.created_at)) FILTER (WHERE st.left_at IS NOT NULL)
+		FROM pipeline_stages ps
+		LEFT JOIN stays st ON st.to_stage_id = ps.id
+		WHERE ps.pipeline_id = $2
+		GROUP BY ps.id, ps.name, ps.color, ps.position, ps.stage_type
+		ORDER BY ps.position, ps.id
+	`, accountID, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stages := []*domain.PipelineFunnelStage{}
+	for rows.Next() {
+		st := &domain.PipelineFunnelStage{}
+		if err := rows.Scan(&st.StageID, &st.Name, &st.Color, &st.Position, &st.StageType,
+			&st.Entered, &st.EnteredPipeline, &st.Exited, &st.MedianSecondsInStage); err != nil {
+			return nil, err
+		}
+		stages = append(stages, st)
+	}
+	return stages, rows.Err()
+}
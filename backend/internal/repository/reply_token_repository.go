@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+type ReplyTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+const replyTokenColumns = `id, account_id, chat_id, label, token_hash, token_prefix, created_by,
+	expires_at, revoked_at, last_used_at, use_count, created_at`
+
+func scanReplyToken(row pgx.Row) (*domain.ChatReplyToken, error) {
+	t := &domain.ChatReplyToken{}
+	if err := row.Scan(&t.ID, &t.AccountID, &t.ChatID, &t.Label, &t.TokenHash, &t.TokenPrefix, &t.CreatedBy,
+		&t.ExpiresAt, &t.RevokedAt, &t.LastUsedAt, &t.UseCount, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *ReplyTokenRepository) Create(ctx context.Context, t *domain.ChatReplyToken) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO chat_reply_tokens (account_id, chat_id, label, token_hash, token_prefix, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, t.AccountID, t.ChatID, t.Label, t.TokenHash, t.TokenPrefix, t.CreatedBy, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+}
+
+func (r *ReplyTokenRepository) ListByChat(ctx context.Context, accountID, chatID uuid.UUID) ([]*domain.ChatReplyToken, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+replyTokenColumns+` FROM chat_reply_tokens
+		WHERE account_id = $1 AND chat_id = $2 ORDER BY created_at DESC LIMIT 100`, accountID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tokens := []*domain.ChatReplyToken{}
+	for rows.Next() {
+		t, err := scanReplyToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// GetByHash returns the token regardless of expiry/revocation so callers can
+// audit why a presented token was rejected. Returns nil when unknown.
+func (r *ReplyTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.ChatReplyToken, error) {
+	t, err := scanReplyToken(r.db.QueryRow(ctx, `SELECT `+replyTokenColumns+` FROM chat_reply_tokens WHERE token_hash = $1`, tokenHash))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (r *ReplyTokenRepository) Revoke(ctx context.Context, accountID, chatID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE chat_reply_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND account_id = $2 AND chat_id = $3 AND revoked_at IS NULL
+	`, id, accountID, chatID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *ReplyTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID) {
+	_, _ = r.db.Exec(ctx, `UPDATE chat_reply_tokens SET last_used_at = NOW(), use_count = use_count + 1 WHERE id = $1`, id)
+}
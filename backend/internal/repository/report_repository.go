@@ -143,7 +143,7 @@ func (r *ReportRepository) GetWhatsAppReportContacts(ctx context.Context, accoun
 				WHERE ua.account_id = l.account_id AND ua.user_id = l.assigned_to
 				LIMIT 1
 			) assignee ON TRUE
-			WHERE l.account_id = $1 AND l.contact_id = ANY($2::uuid[]) AND l.deleted_at IS NULL
+			WHERE l.account_id = $1 AND l.contact_id = ANY($2::uuid[]) AND `+NotDeleted("l")+`
 		), lead_agg AS (
 			SELECT contact_id,
 			       COALESCE(JSONB_AGG(JSONB_BUILD_OBJECT(
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -49,7 +50,13 @@ type Repositories struct {
 	Role               *RoleRepository
 	Logbook            *LogbookRepository
 	APIKey             *APIKeyRepository
+	ReplyToken         *ReplyTokenRepository
+	LeadWebhookToken   *LeadWebhookTokenRepository
+	AutoTagRule        *AutoTagRuleRepository
+	SMSSettings        *SMSSettingsRepository
+	Webhook            *WebhookRepository
 	MCP                *MCPRepository
+	AuditLog           *AuditLogRepository
 	ErosSettings       *ErosSettingsRepository
 	ErosConversation   *ErosConversationRepository
 	ErosRun            *ErosRunRepository
@@ -70,6 +77,7 @@ type Repositories struct {
 	Report             *ReportRepository
 	LeadIntelligence   *LeadIntelligenceReportRepository
 	WhatsAppStatus     *WhatsAppStatusRepository
+	Group              *GroupRepository
 }
 
 func NewRepositories(db *pgxpool.Pool) *Repositories {
@@ -105,7 +113,13 @@ func NewRepositories(db *pgxpool.Pool) *Repositories {
 		Role:               &RoleRepository{db: db},
 		Logbook:            &LogbookRepository{db: db},
 		APIKey:             &APIKeyRepository{db: db},
+		ReplyToken:         &ReplyTokenRepository{db: db},
+		LeadWebhookToken:   &LeadWebhookTokenRepository{db: db},
+		AutoTagRule:        &AutoTagRuleRepository{db: db},
+		SMSSettings:        &SMSSettingsRepository{db: db},
+		Webhook:            &WebhookRepository{db: db},
 		MCP:                &MCPRepository{db: db},
+		AuditLog:           &AuditLogRepository{db: db},
 		ErosSettings:       &ErosSettingsRepository{db: db},
 		ErosConversation:   &ErosConversationRepository{db: db},
 		ErosRun:            &ErosRunRepository{db: db},
@@ -126,9 +140,36 @@ func NewRepositories(db *pgxpool.Pool) *Repositories {
 		Report:             &ReportRepository{db: db},
 		LeadIntelligence:   &LeadIntelligenceReportRepository{db: db},
 		WhatsAppStatus:     &WhatsAppStatusRepository{db: db},
+		Group:              &GroupRepository{db: db},
 	}
 }
 
+// tagIDsFilterSQL returns an " AND <idExpr> IN (...)" clause for a tag_ids
+// filter over a tag junction table. With TagMatchAll the entity must carry
+// every requested tag; otherwise any one of them matches. The tag id array
+// is bound once at argNum and must be deduplicated by the caller.
+func tagIDsFilterSQL(idExpr, junctionTable, junctionColumn string, argNum int, match string) string {
+	if match == domain.TagMatchAll {
+		return fmt.Sprintf(" AND %s IN (SELECT %s FROM %s WHERE tag_id = ANY($%d::uuid[]) GROUP BY %s HAVING COUNT(DISTINCT tag_id) = cardinality($%d::uuid[]))",
+			idExpr, junctionColumn, junctionTable, argNum, junctionColumn, argNum)
+	}
+	return fmt.Sprintf(" AND %s IN (SELECT %s FROM %s WHERE tag_id = ANY($%d::uuid[]))", idExpr, junctionColumn, junctionTable, argNum)
+}
+
+// dedupeUUIDs returns ids without repeats, preserving order.
+func dedupeUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
+
 // DB returns the underlying database pool.
 func (r *Repositories) DB() *pgxpool.Pool {
 	return r.db
@@ -512,6 +553,7 @@ func (r *AccountRepository) GetAll(ctx context.Context) ([]*domain.Account, erro
 				a.max_users_override,
 				COALESCE(a.max_users_override, NULLIF(regexp_replace(pe.value_json #>> '{}', '[^0-9-]', '', 'g'), '')::int, 0) AS max_users_effective,
 				COALESCE(a.storage_limit_bytes, 0), COALESCE(a.is_active, true), COALESCE(a.kommo_enabled, false), a.created_at, a.updated_at,
+				a.campaign_min_delay_seconds, a.campaign_max_batch_size, a.campaign_min_batch_pause_minutes,
 			COALESCE(s.status, 'active'), s.trial_ends_at, s.current_period_end, s.grace_ends_at,
 			(SELECT COUNT(*) FROM user_accounts WHERE account_id = a.id) as user_count,
 			(SELECT COUNT(*) FROM devices WHERE account_id = a.id) as device_count,
@@ -530,6 +572,7 @@ func (r *AccountRepository) GetAll(ctx context.Context) ([]*domain.Account, erro
 	for rows.Next() {
 		a := &domain.Account{}
 		if err := rows.Scan(&a.ID, &a.Name, &a.Slug, &a.Plan, &a.MaxDevices, &a.MaxUsersOverride, &a.MaxUsersEffective, &a.StorageLimitBytes, &a.IsActive, &a.KommoEnabled, &a.CreatedAt, &a.UpdatedAt,
+			&a.CampaignRateLimits.MinDelaySeconds, &a.CampaignRateLimits.MaxBatchSize, &a.CampaignRateLimits.MinBatchPauseMinutes,
 			&a.SubscriptionStatus, &a.TrialEndsAt, &a.CurrentPeriodEnd, &a.GraceEndsAt,
 			&a.UserCount, &a.DeviceCount, &a.ChatCount); err != nil {
 			return nil, err
@@ -545,7 +588,10 @@ func (r *AccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 			SELECT a.id, a.name, COALESCE(a.slug, ''), COALESCE(s.plan_code, a.plan), a.max_devices,
 				a.max_users_override,
 				COALESCE(a.max_users_override, NULLIF(regexp_replace(pe.value_json #>> '{}', '[^0-9-]', '', 'g'), '')::int, 0) AS max_users_effective,
-				COALESCE(a.storage_limit_bytes, 0), COALESCE(a.is_active, true), COALESCE(a.kommo_enabled, false), a.default_incoming_stage_id, a.created_at, a.updated_at,
+				COALESCE(a.storage_limit_bytes, 0), COALESCE(a.is_active, true), COALESCE(a.kommo_enabled, false), a.default_incoming_stage_id, COALESCE(a.campaign_confirm_threshold, 500), a.created_at, a.updated_at,
+				a.campaign_min_delay_seconds, a.campaign_max_batch_size, a.campaign_min_batch_pause_minutes,
+				a.quiet_hours_start, a.quiet_hours_end, COALESCE(a.timezone, 'America/Lima'),
+				COALESCE(a.restrict_to_assigned, false), COALESCE(a.agents_see_unassigned, true),
 			COALESCE(s.status, 'active'), s.trial_ends_at, s.current_period_end, s.grace_ends_at,
 			(SELECT COUNT(*) FROM user_accounts WHERE account_id = a.id) as user_count,
 			(SELECT COUNT(*) FROM devices WHERE account_id = a.id) as device_count,
@@ -555,7 +601,10 @@ func (r *AccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 		LEFT JOIN subscriptions s ON s.account_id = a.id
 		LEFT JOIN plan_entitlements pe ON pe.plan_code = COALESCE(s.plan_code, a.plan) AND pe.key = 'max_users'
 		WHERE a.id = $1
-		`, id).Scan(&a.ID, &a.Name, &a.Slug, &a.Plan, &a.MaxDevices, &a.MaxUsersOverride, &a.MaxUsersEffective, &a.StorageLimitBytes, &a.IsActive, &a.KommoEnabled, &a.DefaultIncomingStageID, &a.CreatedAt, &a.UpdatedAt,
+		`, id).Scan(&a.ID, &a.Name, &a.Slug, &a.Plan, &a.MaxDevices, &a.MaxUsersOverride, &a.MaxUsersEffective, &a.StorageLimitBytes, &a.IsActive, &a.KommoEnabled, &a.DefaultIncomingStageID, &a.CampaignConfirmThreshold, &a.CreatedAt, &a.UpdatedAt,
+		&a.CampaignRateLimits.MinDelaySeconds, &a.CampaignRateLimits.MaxBatchSize, &a.CampaignRateLimits.MinBatchPauseMinutes,
+		&a.QuietHoursStart, &a.QuietHoursEnd, &a.Timezone,
+		&a.RestrictToAssigned, &a.AgentsSeeUnassigned,
 		&a.SubscriptionStatus, &a.TrialEndsAt, &a.CurrentPeriodEnd, &a.GraceEndsAt,
 		&a.UserCount, &a.DeviceCount, &a.ChatCount,
 		&a.GoogleEmail, &a.GoogleContactGroupID, &a.GoogleConnectedAt, &a.GoogleSyncLimit)
@@ -581,6 +630,53 @@ func (r *AccountRepository) Update(ctx context.Context, a *domain.Account) error
 	return err
 }
 
+// SetCampaignConfirmThreshold stores the recipient count above which campaign
+// starts must be confirmed explicitly.
+func (r *AccountRepository) SetCampaignConfirmThreshold(ctx context.Context, id uuid.UUID, threshold int) error {
+	_, err := r.db.Exec(ctx, `UPDATE accounts SET campaign_confirm_threshold = $2, updated_at = NOW() WHERE id = $1`, id, threshold)
+	return err
+}
+
+// GetAssignmentVisibility returns the account's restrict_to_assigned and
+// agents_see_unassigned settings.
+func (r *AccountRepository) GetAssignmentVisibility(ctx context.Context, id uuid.UUID) (restrict, seeUnassigned bool, err error) {
+	err = r.db.QueryRow(ctx, `
+		SELECT COALESCE(restrict_to_assigned, false), COALESCE(agents_see_unassigned, true)
+		FROM accounts WHERE id = $1
+	`, id).Scan(&restrict, &seeUnassigned)
+	if err == pgx.ErrNoRows {
+		return false, true, nil
+	}
+	return restrict, seeUnassigned, err
+}
+
+// SetAssignmentVisibility stores whether agents only see assigned leads and
+// chats, and whether unassigned ones stay visible to them.
+func (r *AccountRepository) SetAssignmentVisibility(ctx context.Context, id uuid.UUID, restrict, seeUnassigned bool) error {
+	_, err := r.db.Exec(ctx, `UPDATE accounts SET restrict_to_assigned = $2, agents_see_unassigned = $3, updated_at = NOW() WHERE id = $1`, id, restrict, seeUnassigned)
+	return err
+}
+
+// SetCampaignRateLimits stores the account-wide campaign pacing ceilings.
+func (r *AccountRepository) SetCampaignRateLimits(ctx context.Context, id uuid.UUID, limits domain.CampaignRateLimits) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE accounts SET campaign_min_delay_seconds = $2, campaign_max_batch_size = $3,
+			campaign_min_batch_pause_minutes = $4, updated_at = NOW()
+		WHERE id = $1
+	`, id, limits.MinDelaySeconds, limits.MaxBatchSize, limits.MinBatchPauseMinutes)
+	return err
+}
+
+// SetQuietHours stores the campaign quiet window and the account timezone.
+// Nil start/end turn quiet hours off.
+func (r *AccountRepository) SetQuietHours(ctx context.Context, id uuid.UUID, start, end *string, timezone string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE accounts SET quiet_hours_start = $2, quiet_hours_end = $3, timezone = $4, updated_at = NOW()
+		WHERE id = $1
+	`, id, start, end, timezone)
+	return err
+}
+
 func (r *AccountRepository) ToggleActive(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.Exec(ctx, `UPDATE accounts SET is_active = NOT COALESCE(is_active, true), updated_at = NOW() WHERE id = $1`, id)
 	return err
@@ -842,6 +938,8 @@ func (r *ChatRepository) GetOrCreate(ctx context.Context, accountID, deviceID uu
 				    name = COALESCE(NULLIF($3, ''), name),
 				    push_name = COALESCE(NULLIF($3, ''), push_name),
 				    phone = COALESCE(NULLIF(phone, ''), NULLIF($4, '')),
+				    deleted_at = NULL,
+				    deleted_by = NULL,
 				    updated_at = NOW()
 				WHERE account_id = $1 AND id = $5
 			`, accountID, deviceID, name, phone, *aliasContactID); err != nil {
@@ -881,6 +979,8 @@ func (r *ChatRepository) GetOrCreate(ctx context.Context, accountID, deviceID uu
 				name = COALESCE(NULLIF(EXCLUDED.name, ''), contacts.name),
 				push_name = COALESCE(NULLIF(EXCLUDED.push_name, ''), contacts.push_name),
 				phone = COALESCE(NULLIF(EXCLUDED.phone, ''), contacts.phone),
+				deleted_at = NULL,
+				deleted_by = NULL,
 				updated_at = NOW()
 			RETURNING id, (xmax = 0) AS created
 		), upserted_chat AS (
@@ -1233,6 +1333,9 @@ func (r *ChatRepository) CreateAndLinkContact(ctx context.Context, accountID, ch
 	var contactID uuid.UUID
 	if len(matchingIDs) == 1 {
 		contactID = matchingIDs[0]
+		if _, err := tx.Exec(ctx, `UPDATE contacts SET deleted_at=NULL, deleted_by=NULL, updated_at=NOW() WHERE id=$1 AND deleted_at IS NOT NULL`, contactID); err != nil {
+			return uuid.Nil, err
+		}
 	} else {
 		if err := tx.QueryRow(ctx, `
 			INSERT INTO contacts (account_id,device_id,jid,phone,name,push_name,is_group)
@@ -1242,6 +1345,8 @@ func (r *ChatRepository) CreateAndLinkContact(ctx context.Context, accountID, ch
 				phone=COALESCE(NULLIF(contacts.phone,''),EXCLUDED.phone),
 				name=COALESCE(NULLIF(contacts.name,''),EXCLUDED.name),
 				push_name=COALESCE(NULLIF(contacts.push_name,''),EXCLUDED.push_name),
+				deleted_at=NULL,
+				deleted_by=NULL,
 				updated_at=NOW()
 			RETURNING id
 		`, accountID, deviceID, jid, phone, strings.TrimSpace(name)).Scan(&contactID); err != nil {
@@ -1400,7 +1505,9 @@ func (r *ChatRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Cha
 	return chat, err
 }
 
-func (r *ChatRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID) ([]*domain.Chat, error) {
+// GetByAccountID lists the account's chats. Group chats (@g.us) are only
+// included with includeGroups, as in GetByAccountIDWithFilters.
+func (r *ChatRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID, includeGroups bool) ([]*domain.Chat, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT c.id, c.account_id, c.device_id, c.contact_id, c.jid, c.name, c.last_message, c.last_message_at,
 		       c.unread_count, c.is_archived, c.is_pinned, c.created_at, c.updated_at,
@@ -1409,9 +1516,9 @@ func (r *ChatRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID
 		FROM chats c
 		LEFT JOIN devices d ON c.device_id = d.id
 		LEFT JOIN contacts ctc ON ctc.id = c.contact_id AND ctc.account_id = c.account_id
-		WHERE c.account_id = $1 AND c.jid NOT LIKE '%@g.us' AND c.jid NOT LIKE '%@newsletter' AND c.jid NOT LIKE '%@broadcast' AND c.jid NOT LIKE '%@lid'
+		WHERE c.account_id = $1 AND ($2 OR c.jid NOT LIKE '%@g.us') AND c.jid NOT LIKE '%@newsletter' AND c.jid NOT LIKE '%@broadcast' AND c.jid NOT LIKE '%@lid'
 		ORDER BY c.is_pinned DESC, c.last_message_at DESC NULLS LAST
-	`, accountID)
+	`, accountID, includeGroups)
 	if err != nil {
 		return nil, err
 	}
@@ -1441,8 +1548,11 @@ func (r *ChatRepository) GetByAccountIDWithFilters(ctx context.Context, accountI
 		LEFT JOIN devices d ON c.device_id = d.id
 		LEFT JOIN contacts ctc ON ctc.id = c.contact_id AND ctc.account_id = c.account_id
 		LEFT JOIN leads l ON l.account_id = c.account_id AND l.jid = c.jid
-		WHERE c.account_id = $1 AND c.jid NOT LIKE '%@g.us' AND c.jid NOT LIKE '%@newsletter' AND c.jid NOT LIKE '%@broadcast' AND c.jid NOT LIKE '%@lid'
+		WHERE c.account_id = $1 AND c.jid NOT LIKE '%@newsletter' AND c.jid NOT LIKE '%@broadcast' AND c.jid NOT LIKE '%@lid'
 	`
+	if !filter.IncludeGroups {
+		baseQuery += " AND c.jid NOT LIKE '%@g.us'"
+	}
 	args := []interface{}{accountID}
 	argNum := 2
 
@@ -1465,8 +1575,15 @@ func (r *ChatRepository) GetByAccountIDWithFilters(ctx context.Context, accountI
 
 	// Tag filter (filter by contact tags)
 	if len(filter.TagIDs) > 0 {
-		baseQuery += fmt.Sprintf(" AND ctc.id IN (SELECT contact_id FROM contact_tags WHERE tag_id = ANY($%d))", argNum)
-		args = append(args, filter.TagIDs)
+		baseQuery += tagIDsFilterSQL("ctc.id", "contact_tags", "contact_id", argNum, filter.TagMatch)
+		args = append(args, dedupeUUIDs(filter.TagIDs))
+		argNum++
+	}
+
+	// Assignment scope (agents of accounts with restrict_to_assigned)
+	if filter.Visibility != nil {
+		baseQuery += assignmentScopeSQL("COALESCE(c.assigned_to, l.assigned_to)", argNum, filter.Visibility)
+		args = append(args, filter.Visibility.UserID)
 		argNum++
 	}
 
@@ -1527,7 +1644,7 @@ func (r *ChatRepository) GetByAccountIDWithFilters(ctx context.Context, accountI
 		       c.id, c.account_id, c.device_id, c.contact_id, c.jid, c.name, c.last_message, c.last_message_at,
 		       c.unread_count, c.is_archived, c.is_pinned,
 		       c.last_inbound_at, c.last_outbound_at, c.customer_service_window_expires_at, c.last_message_provider,
-		       c.created_at, c.updated_at,
+		       c.assigned_to, c.created_at, c.updated_at,
 		       d.name, d.phone,
 		       ctc.phone, ctc.avatar_url, ctc.custom_name, ctc.name,
 		       COALESCE(l.is_blocked, false)
@@ -1555,7 +1672,7 @@ func (r *ChatRepository) GetByAccountIDWithFilters(ctx context.Context, accountI
 			&chat.LastMessage, &chat.LastMessageAt, &chat.UnreadCount, &chat.IsArchived,
 			&chat.IsPinned, &chat.LastInboundAt, &chat.LastOutboundAt,
 			&chat.CustomerServiceWindowExpiresAt, &chat.LastMessageProvider,
-			&chat.CreatedAt, &chat.UpdatedAt,
+			&chat.AssignedTo, &chat.CreatedAt, &chat.UpdatedAt,
 			&chat.DeviceName, &chat.DevicePhone,
 			&chat.ContactPhone, &chat.ContactAvatarURL, &chat.ContactCustomName, &chat.ContactName,
 			&chat.LeadIsBlocked,
@@ -1568,6 +1685,55 @@ func (r *ChatRepository) GetByAccountIDWithFilters(ctx context.Context, accountI
 	return chats, total, nil
 }
 
+// assignmentScopeSQL restricts assigneeExpr to the scope's user, bound as
+// $argNum, or to no assignee when unassigned records are included.
+func assignmentScopeSQL(assigneeExpr string, argNum int, scope *domain.AssignmentScope) string {
+	if scope.IncludeUnassigned {
+		return fmt.Sprintf(" AND (%s = $%d OR %s IS NULL)", assigneeExpr, argNum, assigneeExpr)
+	}
+	return fmt.Sprintf(" AND %s = $%d", assigneeExpr, argNum)
+}
+
+// SetAssignedTo assigns a chat to a user of its account, or unassigns it,
+// and returns the previous assignee.
+func (r *ChatRepository) SetAssignedTo(ctx context.Context, accountID, chatID uuid.UUID, userID *uuid.UUID) (*uuid.UUID, error) {
+	var previous *uuid.UUID
+	err := r.db.QueryRow(ctx, `
+		UPDATE chats c SET assigned_to = $3, updated_at = NOW()
+		FROM (SELECT id, assigned_to FROM chats WHERE id = $1 AND account_id = $2 FOR UPDATE) old
+		WHERE c.id = old.id
+		RETURNING old.assigned_to
+	`, chatID, accountID, userID).Scan(&previous)
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// chatAssigneeSQL is the effective assignee of chat c for queries that do
+// not join its lead: chats.assigned_to, or the lead's assignee when unset.
+const chatAssigneeSQL = `COALESCE(c.assigned_to, (SELECT l.assigned_to FROM leads l WHERE l.account_id = c.account_id AND l.jid = c.jid ORDER BY l.updated_at DESC LIMIT 1))`
+
+// GetAssignee returns the effective assignee of a chat, nil when nobody is
+// assigned, or pgx.ErrNoRows when the chat is not in the account.
+func (r *ChatRepository) GetAssignee(ctx context.Context, accountID, chatID uuid.UUID) (*uuid.UUID, error) {
+	var assignee *uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT `+chatAssigneeSQL+` FROM chats c WHERE c.id = $1 AND c.account_id = $2`, chatID, accountID).Scan(&assignee)
+	return assignee, err
+}
+
+// GetAssigneeByJID is GetAssignee for events that only carry the chat JID.
+// Every chat of the account with that JID shares the lead's assignee, so the
+// first one with an explicit assignee wins.
+func (r *ChatRepository) GetAssigneeByJID(ctx context.Context, accountID uuid.UUID, jid string) (*uuid.UUID, error) {
+	var assignee *uuid.UUID
+	err := r.db.QueryRow(ctx, `
+		SELECT `+chatAssigneeSQL+` FROM chats c WHERE c.account_id = $1 AND c.jid = $2
+		ORDER BY c.assigned_to IS NULL, c.updated_at DESC LIMIT 1
+	`, accountID, jid).Scan(&assignee)
+	return assignee, err
+}
+
 func (r *ChatRepository) UpdateLastMessage(ctx context.Context, chatID uuid.UUID, message string, timestamp time.Time, incrementUnread bool) error {
 	query := `
 		UPDATE chats SET last_message = $1, last_message_at = $2, updated_at = NOW()
@@ -1580,6 +1746,187 @@ func (r *ChatRepository) UpdateLastMessage(ctx context.Context, chatID uuid.UUID
 	return err
 }
 
+// GetUnreadSummary returns unread totals for the chats visible in the default
+// chat list (non-archived, 1:1 conversations), grouped by device. A non-nil
+// scope counts only the chats that scope can see.
+func (r *ChatRepository) GetUnreadSummary(ctx context.Context, accountID uuid.UUID, scope *domain.AssignmentScope) (*domain.ChatUnreadSummary, error) {
+	query := `
+		SELECT c.device_id, MAX(d.name), COALESCE(SUM(c.unread_count), 0)::int, COUNT(*)::int
+		FROM chats c
+		LEFT JOIN devices d ON d.id = c.device_id
+		WHERE c.account_id = $1 AND c.unread_count > 0 AND c.is_archived = FALSE
+		  AND c.jid NOT LIKE '%@g.us' AND c.jid NOT LIKE '%@newsletter' AND c.jid NOT LIKE '%@broadcast' AND c.jid NOT LIKE '%@lid'
+	`
+	args := []interface{}{accountID}
+	if scope != nil {
+		query += assignmentScopeSQL(chatAssigneeSQL, 2, scope)
+		args = append(args, scope.UserID)
+	}
+	query += `
+		GROUP BY c.device_id
+		ORDER BY MAX(d.name) NULLS LAST, c.device_id
+	`
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &domain.ChatUnreadSummary{Devices: []*domain.DeviceUnreadCount{}}
+	for rows.Next() {
+		d := &domain.DeviceUnreadCount{}
+		if err := rows.Scan(&d.DeviceID, &d.DeviceName, &d.TotalUnread, &d.UnreadChats); err != nil {
+			return nil, err
+		}
+		summary.TotalUnread += d.TotalUnread
+		summary.UnreadChats += d.UnreadChats
+		summary.Devices = append(summary.Devices, d)
+	}
+	return summary, rows.Err()
+}
+
+// ListUnreadAcrossAccounts returns unread one-to-one chats from every account
+// in accountIDs, newest activity first, using the same exclusions as
+// GetUnreadSummary. The total counts all matching chats before paging.
+// scopes holds the assignment scope of the accounts where the caller only
+// sees assigned chats; accounts missing from it are unrestricted.
+func (r *ChatRepository) ListUnreadAcrossAccounts(ctx context.Context, accountIDs []uuid.UUID, scopes map[uuid.UUID]*domain.AssignmentScope, limit, offset int) ([]*domain.InboxChat, int, error) {
+	if len(accountIDs) == 0 {
+		return []*domain.InboxChat{}, 0, nil
+	}
+	visibility, visibilityArgs := unreadScopesSQL(scopes, 4)
+	rows, err := r.db.Query(ctx, `
+		SELECT c.account_id, a.name, c.id, c.device_id, c.contact_id, c.jid,
+		       COALESCE(NULLIF(ct.custom_name, ''), NULLIF(ct.name, ''), NULLIF(c.name, ''), NULLIF(ct.push_name, ''), c.jid),
+		       c.last_message, c.last_message_at, c.unread_count,
+		       COUNT(*) OVER ()::int
+		FROM chats c
+		JOIN accounts a ON a.id = c.account_id
+		LEFT JOIN contacts ct ON ct.id = c.contact_id AND ct.account_id = c.account_id
+		WHERE c.account_id = ANY($1::uuid[]) AND c.unread_count > 0 AND c.is_archived = FALSE
+		  AND c.jid NOT LIKE '%@g.us' AND c.jid NOT LIKE '%@newsletter' AND c.jid NOT LIKE '%@broadcast' AND c.jid NOT LIKE '%@lid'
+		`+visibility+`
+		ORDER BY c.last_message_at DESC NULLS LAST, c.id
+		LIMIT $2 OFFSET $3
+	`, append([]interface{}{accountIDs, limit, offset}, visibilityArgs...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	chats := []*domain.InboxChat{}
+	total := 0
+	for rows.Next() {
+		ic := &domain.InboxChat{}
+		if err := rows.Scan(&ic.AccountID, &ic.AccountName, &ic.ChatID, &ic.DeviceID, &ic.ContactID, &ic.JID,
+			&ic.Name, &ic.LastMessage, &ic.LastMessageAt, &ic.UnreadCount, &total); err != nil {
+			return nil, 0, err
+		}
+		chats = append(chats, ic)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(chats) == 0 && offset > 0 {
+		// COUNT(*) OVER () has no row to ride on past the last page.
+		visibility, visibilityArgs := unreadScopesSQL(scopes, 2)
+		if err := r.db.QueryRow(ctx, `
+			SELECT COUNT(*) FROM chats c
+			WHERE c.account_id = ANY($1::uuid[]) AND c.unread_count > 0 AND c.is_archived = FALSE
+			  AND c.jid NOT LIKE '%@g.us' AND c.jid NOT LIKE '%@newsletter' AND c.jid NOT LIKE '%@broadcast' AND c.jid NOT LIKE '%@lid'
+			`+visibility+`
+		`, append([]interface{}{accountIDs}, visibilityArgs...)...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+	return chats, total, nil
+}
+
+// unreadScopesSQL limits chats of the scoped accounts to their visible ones,
+// binding its arguments from $argNum on.
+func unreadScopesSQL(scopes map[uuid.UUID]*domain.AssignmentScope, argNum int) (string, []interface{}) {
+	var restricted, withUnassigned []uuid.UUID
+	var userID uuid.UUID
+	for accountID, scope := range scopes {
+		if scope == nil {
+			continue
+		}
+		userID = scope.UserID
+		restricted = append(restricted, accountID)
+		if scope.IncludeUnassigned {
+			withUnassigned = append(withUnassigned, accountID)
+		}
+	}
+	if len(restricted) == 0 {
+		return "", nil
+	}
+	if withUnassigned == nil {
+		withUnassigned = []uuid.UUID{}
+	}
+	clause := fmt.Sprintf(` AND (c.account_id <> ALL($%[1]d::uuid[]) OR %[4]s = $%[2]d OR (%[4]s IS NULL AND c.account_id = ANY($%[3]d::uuid[])))`,
+		argNum, argNum+1, argNum+2, chatAssigneeSQL)
+	return clause, []interface{}{restricted, userID, withUnassigned}
+}
+
+// SetArchived archives or unarchives a chat. Archiving also unpins it, as
+// WhatsApp does.
+func (r *ChatRepository) SetArchived(ctx context.Context, accountID, chatID uuid.UUID, archived bool) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE chats SET is_archived = $3, is_pinned = is_pinned AND NOT $3, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2
+	`, accountID, chatID, archived)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetPinned pins or unpins a chat.
+func (r *ChatRepository) SetPinned(ctx context.Context, accountID, chatID uuid.UUID, pinned bool) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE chats SET is_pinned = $3, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2
+	`, accountID, chatID, pinned)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// MarkReadUpToByJID marks the account's WhatsApp Web chat with jid as read
+// up to upTo, e.g. after it was read on the phone: only inbound messages newer
+// than upTo stay unread. changed is false when the chat does not exist or the
+// counter did not drop.
+func (r *ChatRepository) MarkReadUpToByJID(ctx context.Context, accountID uuid.UUID, jid string, upTo time.Time) (chatID uuid.UUID, unread int, changed bool, err error) {
+	err = r.db.QueryRow(ctx, `
+		WITH remaining AS (
+			SELECT c.id, LEAST(c.unread_count, (
+				SELECT COUNT(*) FROM messages m
+				WHERE m.chat_id = c.id AND COALESCE(m.is_from_me, false) = false AND m.timestamp > $3
+			))::int AS unread
+			FROM chats c
+			WHERE c.account_id = $1 AND c.jid = $2 AND c.channel_key = 'whatsapp_web' AND c.unread_count > 0
+		)
+		UPDATE chats SET unread_count = remaining.unread, updated_at = NOW()
+		FROM remaining
+		WHERE chats.id = remaining.id AND remaining.unread < chats.unread_count
+		RETURNING chats.id, chats.unread_count
+	`, accountID, jid, upTo).Scan(&chatID, &unread)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, 0, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, 0, false, err
+	}
+	return chatID, unread, true, nil
+}
+
 func (r *ChatRepository) MarkAsRead(ctx context.Context, chatID uuid.UUID) error {
 	_, err := r.db.Exec(ctx, `UPDATE chats SET unread_count = 0, updated_at = NOW() WHERE id = $1`, chatID)
 	return err
@@ -1713,9 +2060,9 @@ func (r *MessageRepository) Create(ctx context.Context, msg *domain.Message) err
 		                      quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
 		                      poll_question, poll_max_selections,
 		                      is_revoked, is_view_once, latitude, longitude,
-		                      contact_name, contact_phone, contact_vcard, provider, template_name)
+		                      contact_name, contact_phone, contact_vcard, provider, template_name, is_forwarded, forwarding_score)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21,
-		        $22, $23, $24, $25, $26, $27, $28, $29, $30, COALESCE(NULLIF($31::text, ''), 'whatsapp_web'), $32)
+		        $22, $23, $24, $25, $26, $27, $28, $29, $30, COALESCE(NULLIF($31::text, ''), 'whatsapp_web'), $32, $33, $34)
 		ON CONFLICT (chat_id, message_id) DO NOTHING
 		RETURNING id, created_at
 	`, msg.AccountID, msg.DeviceID, msg.ChatID, msg.MessageID, msg.FromJID, msg.FromName, msg.Body,
@@ -1724,7 +2071,7 @@ func (r *MessageRepository) Create(ctx context.Context, msg *domain.Message) err
 			msg.QuotedMessageID, msg.QuotedBody, msg.QuotedSender, msg.QuotedIsFromMe,
 			msg.PollQuestion, msg.PollMaxSelections,
 			msg.IsRevoked, msg.IsViewOnce, msg.Latitude, msg.Longitude,
-			msg.ContactName, msg.ContactPhone, msg.ContactVCard, msg.Provider, msg.TemplateName,
+			msg.ContactName, msg.ContactPhone, msg.ContactVCard, msg.Provider, msg.TemplateName, msg.IsForwarded, msg.ForwardingScore,
 		).Scan(&msg.ID, &msg.CreatedAt)
 	}
 	if msg.MediaAssetID == nil {
@@ -1752,19 +2099,22 @@ func (r *MessageRepository) Create(ctx context.Context, msg *domain.Message) err
 	return tx.Commit(ctx)
 }
 
+// GetByChatID returns a chronological page of a chat. Messages sharing a
+// WhatsApp timestamp are ordered by arrival (created_at, or the timestamp for
+// rows without one) and then id, so pages never shuffle between requests.
 func (r *MessageRepository) GetByChatID(ctx context.Context, chatID uuid.UUID, limit, offset int) ([]*domain.Message, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT id, account_id, device_id, chat_id, message_id, from_jid, from_name, body,
 		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
 		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited, false), provider, template_name, timestamp, created_at,
 		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
-		       COALESCE(is_revoked, false), COALESCE(is_view_once, false), COALESCE(media_deleted, false),
-		       latitude, longitude, contact_name, contact_phone, contact_vcard
+		       COALESCE(is_revoked, false), COALESCE(is_view_once, false), COALESCE(is_forwarded, false), COALESCE(forwarding_score, 0), COALESCE(media_deleted, false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, COALESCE(starred, false), edited_at
 		FROM (
 			SELECT * FROM messages WHERE chat_id = $1
-			ORDER BY timestamp DESC, id DESC
+			ORDER BY timestamp DESC, COALESCE(created_at, timestamp) DESC, id DESC
 			LIMIT $2 OFFSET $3
-		) sub ORDER BY timestamp ASC, id ASC
+		) sub ORDER BY timestamp ASC, COALESCE(created_at, timestamp) ASC, id ASC
 	`, chatID, limit, offset)
 	if err != nil {
 		return nil, err
@@ -1781,8 +2131,8 @@ func (r *MessageRepository) GetByChatID(ctx context.Context, chatID uuid.UUID, l
 			&msg.DeliveredAt, &msg.ReadAt, &msg.IsEdited,
 			&msg.Provider, &msg.TemplateName, &msg.Timestamp, &msg.CreatedAt,
 			&msg.QuotedMessageID, &msg.QuotedBody, &msg.QuotedSender, &msg.QuotedIsFromMe,
-			&msg.IsRevoked, &msg.IsViewOnce, &msg.MediaDeleted,
-			&msg.Latitude, &msg.Longitude, &msg.ContactName, &msg.ContactPhone, &msg.ContactVCard,
+			&msg.IsRevoked, &msg.IsViewOnce, &msg.IsForwarded, &msg.ForwardingScore, &msg.MediaDeleted,
+			&msg.Latitude, &msg.Longitude, &msg.ContactName, &msg.ContactPhone, &msg.ContactVCard, &msg.Starred, &msg.EditedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1798,18 +2148,26 @@ func (r *MessageRepository) GetHistoryOffset(ctx context.Context, accountID, cha
 		FROM messages newer
 		JOIN messages target ON target.id=$3 AND target.account_id=$1 AND target.chat_id=$2
 		WHERE newer.account_id=$1 AND newer.chat_id=$2
-		  AND (newer.timestamp>target.timestamp OR (newer.timestamp=target.timestamp AND newer.id>target.id))
+		  AND (newer.timestamp, COALESCE(newer.created_at, newer.timestamp), newer.id)
+		      > (target.timestamp, COALESCE(target.created_at, target.timestamp), target.id)
 	`, accountID, chatID, messageID).Scan(&offset)
 	return offset, err
 }
 
+// messageSearchEscaper escapes LIKE wildcards so a search for "50%" matches
+// the literal text.
+var messageSearchEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchByChat finds messages in a chat whose text or media filename
+// contains query, case-insensitively, newest first.
 func (r *MessageRepository) SearchByChat(ctx context.Context, accountID, chatID uuid.UUID, query string, limit, offset int) ([]*domain.Message, int, error) {
-	pattern := "%" + strings.ToLower(strings.TrimSpace(query)) + "%"
+	pattern := "%" + messageSearchEscaper.Replace(strings.TrimSpace(query)) + "%"
 	var total int
 	if err := r.db.QueryRow(ctx, `
 		SELECT COUNT(*) FROM messages
 		WHERE account_id=$1 AND chat_id=$2 AND COALESCE(is_revoked,false)=false
-		  AND (LOWER(COALESCE(body,'')) LIKE $3 OR LOWER(COALESCE(media_filename,'')) LIKE $3)
+		  AND ((body IS NOT NULL AND body <> '' AND body ILIKE $3)
+		    OR (media_filename IS NOT NULL AND media_filename <> '' AND media_filename ILIKE $3))
 	`, accountID, chatID, pattern).Scan(&total); err != nil {
 		return nil, 0, err
 	}
@@ -1818,11 +2176,12 @@ func (r *MessageRepository) SearchByChat(ctx context.Context, accountID, chatID
 		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
 		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited, false), provider, template_name, timestamp, created_at,
 		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
-		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(media_deleted,false),
-		       latitude, longitude, contact_name, contact_phone, contact_vcard
+		       COALESCE(is_revoked,false), COALESCE(is_view_once,false), COALESCE(is_forwarded,false), COALESCE(forwarding_score,0), COALESCE(media_deleted,false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, COALESCE(starred, false), edited_at
 		FROM messages
 		WHERE account_id=$1 AND chat_id=$2 AND COALESCE(is_revoked,false)=false
-		  AND (LOWER(COALESCE(body,'')) LIKE $3 OR LOWER(COALESCE(media_filename,'')) LIKE $3)
+		  AND ((body IS NOT NULL AND body <> '' AND body ILIKE $3)
+		    OR (media_filename IS NOT NULL AND media_filename <> '' AND media_filename ILIKE $3))
 		ORDER BY timestamp DESC, id DESC LIMIT $4 OFFSET $5
 	`, accountID, chatID, pattern, limit, offset)
 	if err != nil {
@@ -1839,8 +2198,8 @@ func (r *MessageRepository) SearchByChat(ctx context.Context, accountID, chatID
 			&msg.DeliveredAt, &msg.ReadAt, &msg.IsEdited,
 			&msg.Provider, &msg.TemplateName, &msg.Timestamp, &msg.CreatedAt,
 			&msg.QuotedMessageID, &msg.QuotedBody, &msg.QuotedSender, &msg.QuotedIsFromMe,
-			&msg.IsRevoked, &msg.IsViewOnce, &msg.MediaDeleted,
-			&msg.Latitude, &msg.Longitude, &msg.ContactName, &msg.ContactPhone, &msg.ContactVCard,
+			&msg.IsRevoked, &msg.IsViewOnce, &msg.IsForwarded, &msg.ForwardingScore, &msg.MediaDeleted,
+			&msg.Latitude, &msg.Longitude, &msg.ContactName, &msg.ContactPhone, &msg.ContactVCard, &msg.Starred, &msg.EditedAt,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -1857,8 +2216,8 @@ func (r *MessageRepository) GetByMessageID(ctx context.Context, chatID uuid.UUID
 		       message_type, media_url, media_mimetype, media_filename, media_size, media_asset_id,
 		       is_from_me, is_read, status, delivered_at, read_at, COALESCE(is_edited, false), provider, template_name, timestamp, created_at,
 		       quoted_message_id, quoted_body, quoted_sender, quoted_is_from_me,
-		       COALESCE(is_revoked, false), COALESCE(is_view_once, false), COALESCE(media_deleted, false),
-		       latitude, longitude, contact_name, contact_phone, contact_vcard
+		       COALESCE(is_revoked, false), COALESCE(is_view_once, false), COALESCE(is_forwarded, false), COALESCE(forwarding_score, 0), COALESCE(media_deleted, false),
+		       latitude, longitude, contact_name, contact_phone, contact_vcard, COALESCE(starred, false), edited_at
 		FROM messages WHERE chat_id = $1 AND message_id = $2
 		LIMIT 1
 	`, chatID, messageID).Scan(
@@ -1868,8 +2227,8 @@ func (r *MessageRepository) GetByMessageID(ctx context.Context, chatID uuid.UUID
 		&msg.DeliveredAt, &msg.ReadAt, &msg.IsEdited,
 		&msg.Provider, &msg.TemplateName, &msg.Timestamp, &msg.CreatedAt,
 		&msg.QuotedMessageID, &msg.QuotedBody, &msg.QuotedSender, &msg.QuotedIsFromMe,
-		&msg.IsRevoked, &msg.IsViewOnce, &msg.MediaDeleted,
-		&msg.Latitude, &msg.Longitude, &msg.ContactName, &msg.ContactPhone, &msg.ContactVCard,
+		&msg.IsRevoked, &msg.IsViewOnce, &msg.IsForwarded, &msg.ForwardingScore, &msg.MediaDeleted,
+		&msg.Latitude, &msg.Longitude, &msg.ContactName, &msg.ContactPhone, &msg.ContactVCard, &msg.Starred, &msg.EditedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -1912,9 +2271,12 @@ func (r *MessageRepository) UpdateStatus(ctx context.Context, accountID uuid.UUI
 
 // UpdateStatusUpgrade persists receipt timestamps while keeping status monotonic.
 // A delayed delivered receipt may still fill delivered_at after read_at was seen,
-// but it can never downgrade the visible state from read to delivered.
-func (r *MessageRepository) UpdateStatusUpgrade(ctx context.Context, accountID uuid.UUID, chatJID string, messageID string, status string, receiptAt time.Time) error {
-	_, err := r.db.Exec(ctx, `
+// but it can never downgrade the visible state from read to delivered. It
+// returns the status stored after the update, or "" when no outbound message
+// matched.
+func (r *MessageRepository) UpdateStatusUpgrade(ctx context.Context, accountID uuid.UUID, chatJID string, messageID string, status string, receiptAt time.Time) (string, error) {
+	var stored string
+	err := r.db.QueryRow(ctx, `
 		UPDATE messages
 		SET status = CASE
 				WHEN $1::text = 'read' THEN 'read'
@@ -1933,14 +2295,19 @@ func (r *MessageRepository) UpdateStatusUpgrade(ctx context.Context, accountID u
 		WHERE account_id = $2 AND message_id = $3 AND is_from_me = true
 		AND chat_id IN (SELECT id FROM chats WHERE account_id = $2 AND jid = $4)
 		AND $1::text IN ('sent', 'delivered', 'read')
-	`, status, accountID, messageID, chatJID, receiptAt)
-	return err
+		RETURNING COALESCE(status, '')
+	`, status, accountID, messageID, chatJID, receiptAt).Scan(&stored)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return stored, err
 }
 
-// MarkAsRevoked marks a message as revoked (deleted for everyone)
+// MarkAsRevoked keeps a deleted-for-everyone message for audit but drops its
+// text and media link.
 func (r *MessageRepository) MarkAsRevoked(ctx context.Context, accountID uuid.UUID, chatJID string, messageID string) error {
 	_, err := r.db.Exec(ctx, `
-		UPDATE messages SET is_revoked = true, body = NULL
+		UPDATE messages SET is_revoked = true, body = NULL, media_url = NULL
 		WHERE account_id = $1 AND message_id = $2
 		AND chat_id IN (SELECT id FROM chats WHERE account_id = $1 AND jid = $3)
 	`, accountID, messageID, chatJID)
@@ -1950,7 +2317,7 @@ func (r *MessageRepository) MarkAsRevoked(ctx context.Context, accountID uuid.UU
 // UpdateBody updates the body text of an edited message
 func (r *MessageRepository) UpdateBody(ctx context.Context, accountID uuid.UUID, chatJID string, messageID string, newBody string) error {
 	_, err := r.db.Exec(ctx, `
-		UPDATE messages SET body = $4, is_edited = true
+		UPDATE messages SET body = $4, is_edited = true, edited_at = NOW()
 		WHERE account_id = $1 AND message_id = $2
 		AND chat_id IN (SELECT id FROM chats WHERE account_id = $1 AND jid = $3)
 	`, accountID, messageID, chatJID, newBody)
@@ -1981,6 +2348,9 @@ func (r *MessageRepository) GetRecentStickers(ctx context.Context, accountID uui
 	return urls, nil
 }
 
+// GetOrCreate resolves the contact for a WhatsApp identity. New activity on a
+// trashed contact takes it out of the trash so it is not purged with the
+// chats and leads now attached to it; its trashed leads stay in the trash.
 func (r *ContactRepository) GetOrCreate(ctx context.Context, accountID uuid.UUID, deviceID *uuid.UUID, jid, phone, name, pushName string, isGroup bool) (*domain.Contact, error) {
 	if !isGroup {
 		if aliasContactID, err := findContactAliasID(ctx, r.db, accountID, jid, phone); err != nil {
@@ -1999,6 +2369,8 @@ func (r *ContactRepository) GetOrCreate(ctx context.Context, accountID uuid.UUID
 				    name = COALESCE(NULLIF($3, ''), name),
 				    push_name = COALESCE(NULLIF($4, ''), push_name),
 				    phone = COALESCE(NULLIF(phone, ''), NULLIF($5, '')),
+				    deleted_at = NULL,
+				    deleted_by = NULL,
 				    updated_at = NOW()
 				WHERE account_id = $1 AND id = $6
 			`, accountID, deviceID, name, pushName, phone, *aliasContactID); err != nil {
@@ -2018,6 +2390,8 @@ func (r *ContactRepository) GetOrCreate(ctx context.Context, accountID uuid.UUID
 			name = COALESCE(NULLIF(EXCLUDED.name, ''), contacts.name),
 			push_name = COALESCE(NULLIF(EXCLUDED.push_name, ''), contacts.push_name),
 			phone = COALESCE(NULLIF(EXCLUDED.phone, ''), contacts.phone),
+			deleted_at = NULL,
+			deleted_by = NULL,
 			updated_at = NOW()
 		RETURNING id, account_id, device_id, jid, phone, name, last_name, short_name, custom_name, push_name, avatar_url, avatar_checked_at,
 		          email, company, age, dni, birth_date, address, distrito, ocupacion, tags, notes, source, is_group, created_at, updated_at,
@@ -2078,7 +2452,7 @@ func (r *ContactRepository) GetByAccountID(ctx context.Context, accountID uuid.U
 		       avatar_media_asset_id,avatar_source,avatar_updated_at,COALESCE(avatar_revision,0),
 		       email, company, age, dni, birth_date, address, distrito, ocupacion, tags, notes, source, is_group, created_at, updated_at,
 		       do_not_contact, do_not_contact_at, do_not_contact_by, do_not_contact_reason
-		FROM contacts WHERE account_id = $1 ORDER BY COALESCE(custom_name, name, push_name, phone) ASC
+		FROM contacts WHERE account_id = $1 AND `+NotDeleted("")+` ORDER BY COALESCE(custom_name, name, push_name, phone) ASC
 	`, accountID)
 	if err != nil {
 		return nil, err
@@ -2106,7 +2480,7 @@ func (r *ContactRepository) GetByAccountID(ctx context.Context, accountID uuid.U
 func (r *ContactRepository) GetByAccountIDWithFilters(ctx context.Context, accountID uuid.UUID, filter domain.ContactFilter) ([]*domain.Contact, int, error) {
 	baseQuery := `
 		FROM contacts
-		WHERE account_id = $1 AND is_group = $2
+		WHERE account_id = $1 AND is_group = $2 AND ` + NotDeleted("") + `
 	`
 	args := []interface{}{accountID, filter.IsGroup}
 	argNum := 3
@@ -2137,8 +2511,8 @@ func (r *ContactRepository) GetByAccountIDWithFilters(ctx context.Context, accou
 	}
 
 	if len(filter.TagIDs) > 0 {
-		baseQuery += fmt.Sprintf(" AND id IN (SELECT contact_id FROM contact_tags WHERE tag_id = ANY($%d))", argNum)
-		args = append(args, filter.TagIDs)
+		baseQuery += tagIDsFilterSQL("id", "contact_tags", "contact_id", argNum, filter.TagMatch)
+		args = append(args, dedupeUUIDs(filter.TagIDs))
 		argNum++
 	}
 
@@ -2188,7 +2562,7 @@ func (r *ContactRepository) GetByAccountIDWithFilters(ctx context.Context, accou
 			  AND l.contact_id = contacts.id
 			  AND l.is_archived = false
 			  AND l.status = 'open'
-			  AND l.deleted_at IS NULL
+			  AND ` + NotDeleted("l") + `
 		)`
 	}
 
@@ -2229,10 +2603,10 @@ func (r *ContactRepository) GetByAccountIDWithFilters(ctx context.Context, accou
 		LEFT JOIN (
 			SELECT contact_id, COUNT(*) AS cnt
 			FROM leads
-			WHERE account_id = $1 AND contact_id IS NOT NULL AND is_archived = false AND status = 'open' AND deleted_at IS NULL
+			WHERE account_id = $1 AND contact_id IS NOT NULL AND is_archived = false AND status = 'open' AND ` + NotDeleted("") + `
 			GROUP BY contact_id
 		) lc ON lc.contact_id = c.id
-		WHERE c.account_id = $1 AND c.is_group = $2
+		WHERE c.account_id = $1 AND c.is_group = $2 AND ` + NotDeleted("c") + `
 	`
 
 	// Re-apply filters with c. prefix
@@ -2261,8 +2635,8 @@ func (r *ContactRepository) GetByAccountIDWithFilters(ctx context.Context, accou
 		selectArgNum++
 	}
 	if len(filter.TagIDs) > 0 {
-		selectQuery += fmt.Sprintf(" AND c.id IN (SELECT contact_id FROM contact_tags WHERE tag_id = ANY($%d))", selectArgNum)
-		selectArgs = append(selectArgs, filter.TagIDs)
+		selectQuery += tagIDsFilterSQL("c.id", "contact_tags", "contact_id", selectArgNum, filter.TagMatch)
+		selectArgs = append(selectArgs, dedupeUUIDs(filter.TagIDs))
 		selectArgNum++
 	}
 
@@ -2312,7 +2686,7 @@ func (r *ContactRepository) GetByAccountIDWithFilters(ctx context.Context, accou
 			  AND l.contact_id = c.id
 			  AND l.is_archived = false
 			  AND l.status = 'open'
-			  AND l.deleted_at IS NULL
+			  AND ` + NotDeleted("l") + `
 		)`
 	}
 
@@ -2414,7 +2788,7 @@ func (r *ContactRepository) GetByJID(ctx context.Context, accountID uuid.UUID, j
 		       avatar_media_asset_id,avatar_source,avatar_updated_at,COALESCE(avatar_revision,0),
 		       email, company, age, dni, birth_date, address, distrito, ocupacion, tags, notes, source, is_group, created_at, updated_at,
 		       do_not_contact, do_not_contact_at, do_not_contact_by, do_not_contact_reason
-		FROM contacts WHERE account_id = $1 AND jid = $2
+		FROM contacts WHERE account_id = $1 AND jid = $2 AND `+NotDeleted("")+`
 	`, accountID, jid).Scan(
 		&contact.ID, &contact.AccountID, &contact.DeviceID, &contact.JID, &contact.Phone,
 		&contact.Name, &contact.LastName, &contact.ShortName, &contact.CustomName, &contact.PushName, &contact.AvatarURL,
@@ -2435,7 +2809,7 @@ func (r *ContactRepository) GetByPhone(ctx context.Context, accountID uuid.UUID,
 		SELECT id, account_id, device_id, jid, phone, name, last_name, short_name, custom_name, push_name, avatar_url,
 		       email, company, age, dni, birth_date, address, distrito, ocupacion, tags, notes, source, is_group, created_at, updated_at,
 		       do_not_contact, do_not_contact_at, do_not_contact_by, do_not_contact_reason
-		FROM contacts WHERE account_id = $1 AND phone = $2
+		FROM contacts WHERE account_id = $1 AND phone = $2 AND `+NotDeleted("")+`
 		LIMIT 1
 	`, accountID, phone).Scan(
 		&contact.ID, &contact.AccountID, &contact.DeviceID, &contact.JID, &contact.Phone,
@@ -2457,7 +2831,7 @@ func (r *ContactRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 		       email, company, age, dni, birth_date, address, distrito, ocupacion, tags, notes, source, is_group, created_at, updated_at,
 		       google_sync, google_resource_name, google_synced_at, google_sync_error,
 		       do_not_contact, do_not_contact_at, do_not_contact_by, do_not_contact_reason
-		FROM contacts WHERE id = $1
+		FROM contacts WHERE id = $1 AND `+NotDeleted("")+`
 	`, id).Scan(
 		&contact.ID, &contact.AccountID, &contact.DeviceID, &contact.JID, &contact.Phone,
 		&contact.Name, &contact.LastName, &contact.ShortName, &contact.CustomName, &contact.PushName, &contact.AvatarURL,
@@ -2479,7 +2853,7 @@ func (r *ContactRepository) GetByIDForAccount(ctx context.Context, accountID, id
 		       email, company, age, dni, birth_date, address, distrito, ocupacion, tags, notes, source, is_group, created_at, updated_at,
 		       google_sync, google_resource_name, google_synced_at, google_sync_error,
 		       do_not_contact, do_not_contact_at, do_not_contact_by, do_not_contact_reason
-		FROM contacts WHERE account_id = $1 AND id = $2
+		FROM contacts WHERE account_id = $1 AND id = $2 AND `+NotDeleted("")+`
 	`, accountID, id).Scan(
 		&contact.ID, &contact.AccountID, &contact.DeviceID, &contact.JID, &contact.Phone,
 		&contact.Name, &contact.LastName, &contact.ShortName, &contact.CustomName, &contact.PushName, &contact.AvatarURL,
@@ -2619,19 +2993,21 @@ func (r *ContactRepository) UpdateAvatarURL(ctx context.Context, accountID uuid.
 	return changed, err
 }
 
+// Delete moves a contact and its live leads to the trash. Chats and
+// messages are kept until the contact is purged.
 func (r *ContactRepository) Delete(ctx context.Context, accountID, id uuid.UUID) error {
-	return r.deleteTree(ctx, accountID, []uuid.UUID{id}, false)
+	return r.trash(ctx, accountID, []uuid.UUID{id}, false)
 }
 
 func (r *ContactRepository) DeleteBatch(ctx context.Context, accountID uuid.UUID, ids []uuid.UUID) error {
-	return r.deleteTree(ctx, accountID, ids, false)
+	return r.trash(ctx, accountID, ids, false)
 }
 
 func (r *ContactRepository) DeleteAll(ctx context.Context, accountID uuid.UUID) error {
-	return r.deleteTree(ctx, accountID, nil, true)
+	return r.trash(ctx, accountID, nil, true)
 }
 
-func (r *ContactRepository) deleteTree(ctx context.Context, accountID uuid.UUID, ids []uuid.UUID, deleteAll bool) error {
+func (r *ContactRepository) trash(ctx context.Context, accountID uuid.UUID, ids []uuid.UUID, deleteAll bool) error {
 	if !deleteAll && len(ids) == 0 {
 		return nil
 	}
@@ -2641,18 +3017,164 @@ func (r *ContactRepository) deleteTree(ctx context.Context, accountID uuid.UUID,
 	}
 	defer tx.Rollback(ctx)
 
-	where := `account_id = $1`
+	where := `account_id = $1 AND ` + NotDeleted("")
 	args := []interface{}{accountID}
 	if !deleteAll {
 		where += ` AND id = ANY($2)`
 		args = append(args, ids)
 	}
+	rows, err := tx.Query(ctx, `UPDATE contacts SET deleted_at = NOW(), updated_at = NOW() WHERE `+where+` RETURNING id`, args...)
+	if err != nil {
+		return err
+	}
+	contactIDs := make([]uuid.UUID, 0, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		contactIDs = append(contactIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(contactIDs) == 0 {
+		if !deleteAll && len(ids) == 1 {
+			return pgx.ErrNoRows
+		}
+		return tx.Commit(ctx)
+	}
+	// NOW() is fixed for the transaction, so Restore can bring back exactly
+	// the leads that went to the trash together with their contact.
+	if _, err := tx.Exec(ctx, `
+		UPDATE leads SET deleted_at = NOW(), delete_reason = 'contact_deleted', updated_at = NOW()
+		WHERE account_id = $1 AND contact_id = ANY($2) AND `+NotDeleted(""), accountID, contactIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
 
-	contactSQL := fmt.Sprintf(`SELECT id, jid FROM contacts WHERE %s`, where)
-	rows, err := tx.Query(ctx, contactSQL, args...)
+// Restore takes a contact out of the trash along with the leads that were
+// trashed with it.
+func (r *ContactRepository) Restore(ctx context.Context, accountID, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
+	var deletedAt time.Time
+	if err := tx.QueryRow(ctx, `SELECT deleted_at FROM contacts WHERE account_id = $1 AND id = $2 AND deleted_at IS NOT NULL FOR UPDATE`, accountID, id).Scan(&deletedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE contacts SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE leads SET deleted_at = NULL, deleted_by = NULL, delete_reason = '', updated_at = NOW()
+		WHERE account_id = $1 AND contact_id = $2 AND deleted_at = $3 AND delete_reason = 'contact_deleted'
+	`, accountID, id, deletedAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetTrash lists the account's soft-deleted contacts, most recently deleted first.
+func (r *ContactRepository) GetTrash(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*domain.Contact, int, error) {
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM contacts WHERE account_id = $1 AND deleted_at IS NOT NULL`, accountID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT id, account_id, jid, phone, name, last_name, custom_name, push_name, email, company, is_group,
+		       created_at, updated_at, deleted_at, deleted_by
+		FROM contacts WHERE account_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id LIMIT $2 OFFSET $3
+	`, accountID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	contacts := []*domain.Contact{}
+	for rows.Next() {
+		contact := &domain.Contact{}
+		if err := rows.Scan(
+			&contact.ID, &contact.AccountID, &contact.JID, &contact.Phone, &contact.Name, &contact.LastName,
+			&contact.CustomName, &contact.PushName, &contact.Email, &contact.Company, &contact.IsGroup,
+			&contact.CreatedAt, &contact.UpdatedAt, &contact.DeletedAt, &contact.DeletedBy,
+		); err != nil {
+			return nil, 0, err
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, total, rows.Err()
+}
+
+// PurgeExpired permanently deletes contacts that have been in the trash for
+// longer than retention, together with their chats, messages and leads.
+func (r *ContactRepository) PurgeExpired(ctx context.Context, retention time.Duration) (int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT account_id, id FROM contacts WHERE deleted_at IS NOT NULL AND deleted_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	byAccount := make(map[uuid.UUID][]uuid.UUID)
+	for rows.Next() {
+		var accountID, id uuid.UUID
+		if err := rows.Scan(&accountID, &id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		byAccount[accountID] = append(byAccount[accountID], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	var purged int64
+	for accountID, ids := range byAccount {
+		n, err := r.purgeTree(ctx, accountID, ids)
+		if err != nil {
+			return purged, err
+		}
+		purged += int64(n)
+	}
+	return purged, nil
+}
+
+// purgeTree permanently deletes trashed contacts with their chats, messages
+// and leads. Contacts restored in the meantime, or that picked up a live lead,
+// a chat or a message after they were trashed, are skipped.
+func (r *ContactRepository) purgeTree(ctx context.Context, accountID uuid.UUID, ids []uuid.UUID) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT c.id, c.jid FROM contacts c
+		WHERE c.account_id = $1 AND c.id = ANY($2) AND c.deleted_at IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM leads l
+			WHERE l.account_id = $1 AND l.contact_id = c.id
+			  AND (`+NotDeleted("l")+` OR l.created_at > c.deleted_at)
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM chats ch
+			WHERE ch.account_id = $1 AND (ch.contact_id = c.id OR ch.jid = c.jid)
+			  AND (ch.created_at > c.deleted_at OR EXISTS (
+				SELECT 1 FROM messages m WHERE m.chat_id = ch.id AND m.created_at > c.deleted_at
+			  ))
+		  )
+		FOR UPDATE OF c
+	`, accountID, ids)
+	if err != nil {
+		return 0, err
+	}
 	contactIDs := make([]uuid.UUID, 0, len(ids))
 	jids := make([]string, 0, len(ids))
 	for rows.Next() {
@@ -2660,7 +3182,7 @@ func (r *ContactRepository) deleteTree(ctx context.Context, accountID uuid.UUID,
 		var jid string
 		if err := rows.Scan(&id, &jid); err != nil {
 			rows.Close()
-			return err
+			return 0, err
 		}
 		contactIDs = append(contactIDs, id)
 		if strings.TrimSpace(jid) != "" {
@@ -2669,14 +3191,11 @@ func (r *ContactRepository) deleteTree(ctx context.Context, accountID uuid.UUID,
 	}
 	if err := rows.Err(); err != nil {
 		rows.Close()
-		return err
+		return 0, err
 	}
 	rows.Close()
 	if len(contactIDs) == 0 {
-		if !deleteAll && len(ids) == 1 {
-			return pgx.ErrNoRows
-		}
-		return tx.Commit(ctx)
+		return 0, tx.Commit(ctx)
 	}
 
 	// Freeze every current identity of DNC contacts before deleting aliases,
@@ -2697,7 +3216,7 @@ func (r *ContactRepository) deleteTree(ctx context.Context, accountID uuid.UUID,
 		SELECT $1, contact_id, identity_type, normalized_value, reason, do_not_contact_by FROM identities WHERE NULLIF(BTRIM(normalized_value),'') IS NOT NULL
 		ON CONFLICT (account_id, identity_type, normalized_value) DO UPDATE SET contact_id=EXCLUDED.contact_id, reason=EXCLUDED.reason, created_by=EXCLUDED.created_by, active=TRUE, updated_at=NOW(), released_at=NULL, released_by=NULL
 	`, accountID, contactIDs); err != nil {
-		return err
+		return 0, err
 	}
 
 	chatRows, err := tx.Query(ctx, `
@@ -2707,42 +3226,42 @@ func (r *ContactRepository) deleteTree(ctx context.Context, accountID uuid.UUID,
 		  AND (contact_id = ANY($2) OR jid = ANY($3))
 	`, accountID, contactIDs, jids)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	chatIDs := []uuid.UUID{}
 	for chatRows.Next() {
 		var id uuid.UUID
 		if err := chatRows.Scan(&id); err != nil {
 			chatRows.Close()
-			return err
+			return 0, err
 		}
 		chatIDs = append(chatIDs, id)
 	}
 	if err := chatRows.Err(); err != nil {
 		chatRows.Close()
-		return err
+		return 0, err
 	}
 	chatRows.Close()
 
 	if len(chatIDs) > 0 {
 		if _, err := tx.Exec(ctx, `DELETE FROM messages WHERE account_id = $1 AND chat_id = ANY($2)`, accountID, chatIDs); err != nil {
-			return err
+			return 0, err
 		}
 		if _, err := tx.Exec(ctx, `DELETE FROM chats WHERE account_id = $1 AND id = ANY($2)`, accountID, chatIDs); err != nil {
-			return err
+			return 0, err
 		}
 	}
-	if _, err := tx.Exec(ctx, `DELETE FROM leads WHERE account_id = $1 AND contact_id = ANY($2)`, accountID, contactIDs); err != nil {
-		return err
+	if _, err := tx.Exec(ctx, `DELETE FROM leads WHERE account_id = $1 AND contact_id = ANY($2) AND deleted_at IS NOT NULL`, accountID, contactIDs); err != nil {
+		return 0, err
 	}
-	if _, err := tx.Exec(ctx, `DELETE FROM contacts WHERE account_id = $1 AND id = ANY($2)`, accountID, contactIDs); err != nil {
-		return err
+	if _, err := tx.Exec(ctx, `DELETE FROM contacts WHERE account_id = $1 AND id = ANY($2) AND deleted_at IS NOT NULL`, accountID, contactIDs); err != nil {
+		return 0, err
 	}
-	return tx.Commit(ctx)
+	return len(contactIDs), tx.Commit(ctx)
 }
 
 func (r *ContactRepository) FindDuplicates(ctx context.Context, accountID uuid.UUID) ([][]*domain.Contact, error) {
-	groups, err := r.FindDuplicateGroups(ctx, accountID)
+	groups, err := r.FindDuplicateGroups(ctx, accountID, false)
 	if err != nil {
 		return nil, err
 	}
@@ -2757,74 +3276,247 @@ func (r *ContactRepository) FindDuplicates(ctx context.Context, accountID uuid.U
 	return result, nil
 }
 
-func (r *ContactRepository) FindDuplicateGroups(ctx context.Context, accountID uuid.UUID) ([]*domain.ContactDuplicateGroup, error) {
-	rows, err := r.db.Query(ctx, `
-		WITH normalized AS (
-			SELECT c.id,
-			       regexp_replace(COALESCE(NULLIF(c.phone, ''), split_part(c.jid, '@', 1)), '[^0-9]', '', 'g') AS normalized_phone
+// duplicateScanPageSize is how many contacts FindDuplicateGroups reads per
+// query while indexing an account.
+const duplicateScanPageSize = 2000
+
+// FindDuplicateGroups groups the account's live contacts that share a
+// canonical phone number and, when matchEmail is set, an email address.
+// Contacts are indexed page by page; only the duplicates are loaded in full.
+func (r *ContactRepository) FindDuplicateGroups(ctx context.Context, accountID uuid.UUID, matchEmail bool) ([]*domain.ContactDuplicateGroup, error) {
+	index := newDuplicateContactIndex(matchEmail)
+	after := uuid.Nil
+	for {
+		rows, err := r.db.Query(ctx, `
+			SELECT c.id, c.phone, c.jid, c.email
 			FROM contacts c
-			WHERE c.account_id = $1 AND c.is_group = FALSE
-		), duplicate_keys AS (
-			SELECT normalized_phone
-			FROM normalized
-			WHERE normalized_phone <> ''
-			GROUP BY normalized_phone
-			HAVING COUNT(*) > 1
-		)
+			WHERE c.account_id = $1 AND c.is_group = FALSE AND `+NotDeleted("c")+` AND c.id > $2
+			ORDER BY c.id
+			LIMIT $3
+		`, accountID, after, duplicateScanPageSize)
+		if err != nil {
+			return nil, err
+		}
+		n := 0
+		for rows.Next() {
+			var k duplicateContactKeys
+			if err := rows.Scan(&k.ID, &k.Phone, &k.JID, &k.Email); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			index.add(k)
+			after = k.ID
+			n++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if n < duplicateScanPageSize {
+			break
+		}
+	}
+
+	keyGroups := index.groups()
+	if len(keyGroups) == 0 {
+		return []*domain.ContactDuplicateGroup{}, nil
+	}
+	idSet := map[uuid.UUID]bool{}
+	var ids []uuid.UUID
+	for _, g := range keyGroups {
+		for _, id := range g.IDs {
+			if !idSet[id] {
+				idSet[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	contactRows, err := r.db.Query(ctx, `
 		SELECT c.id, c.account_id, c.device_id, c.jid, c.phone, c.name, c.last_name, c.short_name, c.custom_name, c.push_name,
 		       c.avatar_url, c.email, c.company, c.age, c.dni, c.birth_date, c.address, c.distrito, c.ocupacion, c.tags, c.notes,
-		       c.source, c.is_group, c.created_at, c.updated_at, c.google_sync, c.google_resource_name, c.google_synced_at, c.google_sync_error,
-		       n.normalized_phone
+		       c.source, c.is_group, c.created_at, c.updated_at, c.google_sync, c.google_resource_name, c.google_synced_at, c.google_sync_error
 		FROM contacts c
-		JOIN normalized n ON n.id = c.id
-		JOIN duplicate_keys dk ON dk.normalized_phone = n.normalized_phone
-		WHERE c.account_id = $1 AND c.is_group = FALSE
-		ORDER BY n.normalized_phone, c.updated_at DESC
-	`, accountID)
+		WHERE c.account_id = $1 AND c.id = ANY($2)
+	`, accountID, ids)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	grouped := map[string][]*domain.ContactDuplicateCandidate{}
-	order := []string{}
-	for rows.Next() {
+	contacts := make(map[uuid.UUID]*domain.Contact, len(ids))
+	for contactRows.Next() {
 		contact := &domain.Contact{}
-		normalizedPhone := ""
-		if err := rows.Scan(
+		if err := contactRows.Scan(
 			&contact.ID, &contact.AccountID, &contact.DeviceID, &contact.JID, &contact.Phone,
 			&contact.Name, &contact.LastName, &contact.ShortName, &contact.CustomName, &contact.PushName, &contact.AvatarURL,
 			&contact.Email, &contact.Company, &contact.Age, &contact.DNI, &contact.BirthDate, &contact.Address, &contact.Distrito, &contact.Ocupacion, &contact.Tags, &contact.Notes,
 			&contact.Source, &contact.IsGroup, &contact.CreatedAt, &contact.UpdatedAt, &contact.GoogleSync, &contact.GoogleResourceName, &contact.GoogleSyncedAt, &contact.GoogleSyncError,
-			&normalizedPhone,
 		); err != nil {
+			contactRows.Close()
 			return nil, err
 		}
-		counts, _ := r.countContactRelations(ctx, accountID, []uuid.UUID{contact.ID})
-		if _, ok := grouped[normalizedPhone]; !ok {
-			order = append(order, normalizedPhone)
-		}
-		grouped[normalizedPhone] = append(grouped[normalizedPhone], &domain.ContactDuplicateCandidate{Contact: contact, Counts: counts})
+		contacts[contact.ID] = contact
 	}
-	if err := rows.Err(); err != nil {
+	contactRows.Close()
+	if err := contactRows.Err(); err != nil {
 		return nil, err
 	}
 
-	groups := make([]*domain.ContactDuplicateGroup, 0, len(order))
-	for _, phone := range order {
-		candidates := grouped[phone]
-		groups = append(groups, &domain.ContactDuplicateGroup{
-			GroupKey:          "phone:" + phone,
-			NormalizedPhone:   phone,
+	counts := make(map[uuid.UUID]domain.ContactRelationCounts, len(ids))
+	for _, id := range ids {
+		counts[id], _ = r.countContactRelations(ctx, accountID, []uuid.UUID{id})
+	}
+
+	groups := make([]*domain.ContactDuplicateGroup, 0, len(keyGroups))
+	for _, g := range keyGroups {
+		candidates := make([]*domain.ContactDuplicateCandidate, 0, len(g.IDs))
+		for _, id := range g.IDs {
+			if contact := contacts[id]; contact != nil {
+				candidates = append(candidates, &domain.ContactDuplicateCandidate{Contact: contact, Counts: counts[id]})
+			}
+		}
+		if len(candidates) < 2 {
+			continue
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Contact.UpdatedAt.After(candidates[j].Contact.UpdatedAt)
+		})
+		group := &domain.ContactDuplicateGroup{
+			GroupKey:          g.Key,
 			Confidence:        "high",
 			Reason:            "same_normalized_phone",
 			RecommendedKeepID: recommendedContactToKeep(candidates),
 			Contacts:          candidates,
-		})
+		}
+		if g.Phone != "" {
+			group.NormalizedPhone = g.Phone
+		} else {
+			group.Confidence = "medium"
+			group.Reason = "same_email"
+		}
+		groups = append(groups, group)
 	}
 	return groups, nil
 }
 
+type duplicateContactKeys struct {
+	ID    uuid.UUID
+	Phone *string
+	JID   string
+	Email *string
+}
+
+type duplicateKeyGroup struct {
+	Key   string
+	Phone string // canonical phone; empty for email groups
+	IDs   []uuid.UUID
+}
+
+// duplicatePhoneKey returns the canonical phone used to detect duplicates,
+// so "+51 999 888 777", "51999888777" and "999888777" collapse together. The
+// JID user part is used when the contact has no phone; @lid JIDs are not
+// phone numbers and are ignored.
+func duplicatePhoneKey(phone *string, jid string) string {
+	raw := ""
+	if phone != nil {
+		raw = strings.TrimSpace(*phone)
+	}
+	if raw == "" {
+		if strings.HasSuffix(strings.ToLower(jid), "@lid") {
+			return ""
+		}
+		raw = jid
+	}
+	digits := strings.TrimPrefix(phoneFromJID(raw), "00")
+	// Peruvian mobiles are often stored without the country code.
+	if len(digits) == 9 && strings.HasPrefix(digits, "9") {
+		digits = "51" + digits
+	}
+	if len(digits) < 7 {
+		return ""
+	}
+	return digits
+}
+
+func duplicateEmailKey(email *string) string {
+	if email == nil {
+		return ""
+	}
+	key := strings.ToLower(strings.TrimSpace(*email))
+	if !strings.Contains(key, "@") {
+		return ""
+	}
+	return key
+}
+
+// duplicateContactIndex buckets contacts by canonical phone and, optionally,
+// email as they are read. Members keep the order they were added in.
+type duplicateContactIndex struct {
+	matchEmail bool
+	byPhone    map[string][]uuid.UUID
+	byEmail    map[string][]uuid.UUID
+	phoneOf    map[uuid.UUID]string // only for contacts with an email key
+}
+
+func newDuplicateContactIndex(matchEmail bool) *duplicateContactIndex {
+	return &duplicateContactIndex{
+		matchEmail: matchEmail,
+		byPhone:    map[string][]uuid.UUID{},
+		byEmail:    map[string][]uuid.UUID{},
+		phoneOf:    map[uuid.UUID]string{},
+	}
+}
+
+func (x *duplicateContactIndex) add(c duplicateContactKeys) {
+	phone := duplicatePhoneKey(c.Phone, c.JID)
+	if phone != "" {
+		x.byPhone[phone] = append(x.byPhone[phone], c.ID)
+	}
+	if !x.matchEmail {
+		return
+	}
+	if email := duplicateEmailKey(c.Email); email != "" {
+		x.byEmail[email] = append(x.byEmail[email], c.ID)
+		if phone != "" {
+			x.phoneOf[c.ID] = phone
+		}
+	}
+}
+
+// groups returns the phone groups, then the email groups. Email groups whose
+// members all share one phone group are dropped as redundant.
+func (x *duplicateContactIndex) groups() []duplicateKeyGroup {
+	var groups []duplicateKeyGroup
+	for _, phone := range sortedDuplicateKeys(x.byPhone) {
+		groups = append(groups, duplicateKeyGroup{Key: "phone:" + phone, Phone: phone, IDs: x.byPhone[phone]})
+	}
+	for _, email := range sortedDuplicateKeys(x.byEmail) {
+		ids := x.byEmail[email]
+		phone := x.phoneOf[ids[0]]
+		redundant := phone != "" && len(x.byPhone[phone]) > 1
+		for _, id := range ids[1:] {
+			if x.phoneOf[id] != phone {
+				redundant = false
+				break
+			}
+		}
+		if !redundant {
+			groups = append(groups, duplicateKeyGroup{Key: "email:" + email, IDs: ids})
+		}
+	}
+	return groups
+}
+
+func sortedDuplicateKeys(buckets map[string][]uuid.UUID) []string {
+	keys := make([]string, 0, len(buckets))
+	for key, ids := range buckets {
+		if len(ids) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (r *ContactRepository) PreviewMergeContacts(ctx context.Context, accountID, keepID uuid.UUID, mergeIDs []uuid.UUID) (*domain.ContactMergePreview, error) {
 	mergeIDs = uniqueUUIDsExcluding(mergeIDs, keepID)
 	if len(mergeIDs) == 0 {
@@ -2865,7 +3557,7 @@ func (r *ContactRepository) GetContactsWithDuplicateLeads(ctx context.Context, a
 		SELECT COUNT(DISTINCT contact_id) FROM (
 			SELECT contact_id, LOWER(REGEXP_REPLACE(BTRIM(title), '\s+', ' ', 'g')) AS normalized_title
 			FROM leads
-			WHERE account_id = $1 AND contact_id IS NOT NULL AND status='open' AND deleted_at IS NULL
+			WHERE account_id = $1 AND contact_id IS NOT NULL AND status='open' AND `+NotDeleted("")+`
 			GROUP BY contact_id, LOWER(REGEXP_REPLACE(BTRIM(title), '\s+', ' ', 'g'))
 			HAVING COUNT(*) > 1
 		) dup
@@ -3980,19 +4672,28 @@ func (r *LeadRepository) Create(ctx context.Context, lead *domain.Lead) error {
 			return fmt.Errorf("lead assignee does not belong to account")
 		}
 	}
+	// The initial stage is recorded as an "entered pipeline" move (from no
+	// stage) so funnels count leads created directly in a stage.
 	return r.db.QueryRow(ctx, `
-		INSERT INTO leads (account_id, contact_id, title, jid, name, phone, email, notes, dni, birth_date, status, source, pipeline_id, stage_id, tags, custom_fields, assigned_to, kommo_id, kommo_synced_tags, closed_at, closed_by, close_reason)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
-		        CASE WHEN $18::bigint IS NOT NULL THEN COALESCE($15::text[], '{}'::text[]) ELSE '{}'::text[] END,
-		        $19, $20, $21)
-		RETURNING id, created_at, updated_at
+		WITH created AS (
+			INSERT INTO leads (account_id, contact_id, title, jid, name, phone, email, notes, dni, birth_date, status, source, pipeline_id, stage_id, tags, custom_fields, assigned_to, kommo_id, kommo_synced_tags, closed_at, closed_by, close_reason)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+			        CASE WHEN $18::bigint IS NOT NULL THEN COALESCE($15::text[], '{}'::text[]) ELSE '{}'::text[] END,
+			        $19, $20, $21)
+			RETURNING id, account_id, stage_id, created_at, updated_at
+		), entered AS (
+			INSERT INTO lead_stage_changes (account_id, lead_id, to_stage_id, to_stage_name, created_at)
+			SELECT created.account_id, created.id, created.stage_id,
+			       COALESCE((SELECT name FROM pipeline_stages WHERE id = created.stage_id), ''), created.created_at
+			FROM created WHERE created.stage_id IS NOT NULL
+		)
+		SELECT id, created_at, updated_at FROM created
 	`, lead.AccountID, lead.ContactID, lead.Title, lead.JID, nil, nil, nil, lead.Notes, nil, nil, lead.Status, lead.Source, lead.PipelineID, lead.StageID, lead.Tags, lead.CustomFields, lead.AssignedTo,
 		lead.KommoID, lead.ClosedAt, lead.ClosedBy, lead.CloseReason,
 	).Scan(&lead.ID, &lead.CreatedAt, &lead.UpdatedAt)
 }
 
-func (r *LeadRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID) ([]*domain.Lead, error) {
-	rows, err := r.db.Query(ctx, `
+const leadListQuery = `
 		SELECT l.id, l.account_id, l.contact_id, l.jid,
 		       CASE WHEN l.contact_id IS NULL THEN COALESCE(l.name,'') ELSE COALESCE(c.custom_name,c.name,c.push_name,c.phone,c.jid,'') END,
 		       CASE WHEN l.contact_id IS NULL THEN l.last_name ELSE c.last_name END,
@@ -4013,12 +4714,126 @@ func (r *LeadRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID
 		       CASE WHEN l.contact_id IS NULL THEN COALESCE(l.is_blocked,FALSE) ELSE COALESCE(c.do_not_contact,FALSE) END,
 		       CASE WHEN l.contact_id IS NULL THEN l.blocked_at ELSE c.do_not_contact_at END,
 		       CASE WHEN l.contact_id IS NULL THEN l.block_reason ELSE c.do_not_contact_reason END,l.kommo_deleted_at,
-		       l.title, l.closed_at, l.closed_by, l.close_reason, l.deleted_at, l.deleted_by, l.delete_reason
+		       l.title, l.closed_at, l.closed_by, l.close_reason, l.deleted_at, l.deleted_by, l.delete_reason, l.score
 		FROM leads l
 		LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
 		LEFT JOIN pipeline_stages ps ON ps.id = l.stage_id
-		WHERE l.account_id = $1 AND l.deleted_at IS NULL ORDER BY l.created_at DESC
+`
+
+func (r *LeadRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID) ([]*domain.Lead, error) {
+	return r.queryLeads(ctx, leadListQuery+`
+		WHERE l.account_id = $1 AND `+NotDeleted("l")+` ORDER BY l.created_at DESC
 	`, accountID)
+}
+
+// GetByAccountIDWithFilters returns one page of the account's live leads and
+// the total matching the filter. Search matches the lead's own fields and
+// those of its linked contact.
+func (r *LeadRepository) GetByAccountIDWithFilters(ctx context.Context, accountID uuid.UUID, filter domain.LeadFilter) ([]*domain.Lead, int, error) {
+	where := " WHERE l.account_id = $1 AND " + NotDeleted("l")
+	args := []interface{}{accountID}
+	argNum := 2
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		where += fmt.Sprintf(` AND (
+			l.name ILIKE $%d OR l.last_name ILIKE $%d OR l.title ILIKE $%d OR l.phone ILIKE $%d OR l.email ILIKE $%d OR l.company ILIKE $%d OR
+			c.name ILIKE $%d OR c.custom_name ILIKE $%d OR c.push_name ILIKE $%d OR c.last_name ILIKE $%d OR c.phone ILIKE $%d OR c.email ILIKE $%d OR c.company ILIKE $%d
+		)`, argNum, argNum, argNum, argNum, argNum, argNum, argNum, argNum, argNum, argNum, argNum, argNum, argNum)
+		args = append(args, "%"+search+"%")
+		argNum++
+	}
+	if filter.Status != "" {
+		where += fmt.Sprintf(" AND l.status = $%d", argNum)
+		args = append(args, filter.Status)
+		argNum++
+	}
+	if filter.PipelineID != nil {
+		where += fmt.Sprintf(" AND l.pipeline_id = $%d", argNum)
+		args = append(args, *filter.PipelineID)
+		argNum++
+	}
+	if filter.StageID != nil {
+		where += fmt.Sprintf(" AND l.stage_id = $%d", argNum)
+		args = append(args, *filter.StageID)
+		argNum++
+	}
+	if filter.AssignedTo != nil {
+		where += fmt.Sprintf(" AND l.assigned_to = $%d", argNum)
+		args = append(args, *filter.AssignedTo)
+		argNum++
+	}
+	if filter.Visibility != nil {
+		where += assignmentScopeSQL("l.assigned_to", argNum, filter.Visibility)
+		args = append(args, filter.Visibility.UserID)
+		argNum++
+	}
+	if len(filter.DeviceIDs) > 0 {
+		where += fmt.Sprintf(" AND l.jid IN (SELECT DISTINCT jid FROM chats WHERE device_id = ANY($%d))", argNum)
+		args = append(args, filter.DeviceIDs)
+		argNum++
+	}
+	if len(filter.TagIDs) > 0 {
+		where += tagIDsFilterSQL("l.contact_id", "contact_tags", "contact_id", argNum, filter.TagMatch)
+		args = append(args, dedupeUUIDs(filter.TagIDs))
+		argNum++
+	}
+
+	if filter.AfterID != nil {
+		where += fmt.Sprintf(" AND l.id > $%d", argNum)
+		args = append(args, *filter.AfterID)
+		argNum++
+		query := leadListQuery + where + " ORDER BY l.id"
+		if filter.Limit > 0 {
+			query += fmt.Sprintf(" LIMIT $%d", argNum)
+			args = append(args, filter.Limit)
+		}
+		leads, err := r.queryLeads(ctx, query, args...)
+		return leads, 0, err
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM leads l
+		LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	orderBy := " ORDER BY l.created_at DESC, l.id"
+	if filter.SortBy == "score" {
+		orderBy = " ORDER BY l.score DESC, l.created_at DESC, l.id"
+	}
+	query := leadListQuery + where + orderBy
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argNum, argNum+1)
+		args = append(args, filter.Limit, filter.Offset)
+	}
+	leads, err := r.queryLeads(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return leads, total, nil
+}
+
+// GetTrash lists the account's soft-deleted leads, most recently deleted first.
+// A non-nil scope limits the trash to the leads visible to that agent.
+func (r *LeadRepository) GetTrash(ctx context.Context, accountID uuid.UUID, limit, offset int, scope *domain.AssignmentScope) ([]*domain.Lead, int, error) {
+	where := " WHERE l.account_id = $1 AND l.deleted_at IS NOT NULL"
+	args := []interface{}{accountID}
+	if scope != nil {
+		where += assignmentScopeSQL("l.assigned_to", 2, scope)
+		args = append(args, scope.UserID)
+	}
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM leads l`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	leads, err := r.queryLeads(ctx, leadListQuery+where+fmt.Sprintf(` ORDER BY l.deleted_at DESC, l.id LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2),
+		append(args, limit, offset)...)
+	return leads, total, err
+}
+
+func (r *LeadRepository) queryLeads(ctx context.Context, query string, args ...interface{}) ([]*domain.Lead, error) {
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -4033,7 +4848,7 @@ func (r *LeadRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID
 			&lead.CustomFields, &lead.AssignedTo, &lead.PipelineID, &lead.StageID, &lead.CreatedAt, &lead.UpdatedAt,
 			&lead.StageName, &lead.StageColor, &lead.StagePosition, &lead.KommoID,
 			&lead.IsArchived, &lead.ArchivedAt, &lead.IsBlocked, &lead.BlockedAt, &lead.BlockReason, &lead.KommoDeletedAt,
-			&lead.Title, &lead.ClosedAt, &lead.ClosedBy, &lead.CloseReason, &lead.DeletedAt, &lead.DeletedBy, &lead.DeleteReason,
+			&lead.Title, &lead.ClosedAt, &lead.ClosedBy, &lead.CloseReason, &lead.DeletedAt, &lead.DeletedBy, &lead.DeleteReason, &lead.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -4070,7 +4885,7 @@ func (r *LeadRepository) GetByJID(ctx context.Context, accountID uuid.UUID, jid
 		LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
 		LEFT JOIN pipeline_stages ps ON ps.id = l.stage_id
 		WHERE l.account_id = $1 AND l.jid = $2
-		ORDER BY (l.deleted_at IS NULL) DESC, (l.status = 'open') DESC, l.updated_at DESC, l.id LIMIT 1
+		ORDER BY (`+NotDeleted("l")+`) DESC, (l.status = 'open') DESC, l.updated_at DESC, l.id LIMIT 1
 	`, accountID, jid).Scan(
 		&lead.ID, &lead.AccountID, &lead.ContactID, &lead.JID, &lead.Name, &lead.LastName, &lead.ShortName, &lead.Phone,
 		&lead.Email, &lead.Company, &lead.Age, &lead.DNI, &lead.BirthDate, &lead.Address, &lead.Distrito, &lead.Ocupacion, &lead.Status, &lead.Source, &lead.Notes, &lead.Tags,
@@ -4085,11 +4900,85 @@ func (r *LeadRepository) GetByJID(ctx context.Context, accountID uuid.UUID, jid
 	return lead, err
 }
 
+// GetNamesByJIDs resolves, in one query, the name fields GetByJID would
+// return for each JID. Only Name, LastName and ShortName are populated; JIDs
+// without a lead are absent from the map.
+func (r *LeadRepository) GetNamesByJIDs(ctx context.Context, accountID uuid.UUID, jids []string) (map[string]*domain.Lead, error) {
+	leads := make(map[string]*domain.Lead, len(jids))
+	if len(jids) == 0 {
+		return leads, nil
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT ON (l.jid) l.jid,
+		       CASE WHEN l.contact_id IS NULL THEN COALESCE(l.name,'') ELSE COALESCE(c.custom_name,c.name,c.push_name,c.phone,c.jid,'') END,
+		       CASE WHEN l.contact_id IS NULL THEN l.last_name ELSE c.last_name END,
+		       CASE WHEN l.contact_id IS NULL THEN l.short_name ELSE c.short_name END
+		FROM leads l
+		LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
+		WHERE l.account_id = $1 AND l.jid = ANY($2)
+		ORDER BY l.jid, (`+NotDeleted("l")+`) DESC, (l.status = 'open') DESC, l.updated_at DESC, l.id
+	`, accountID, jids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		lead := &domain.Lead{AccountID: accountID}
+		if err := rows.Scan(&lead.JID, &lead.Name, &lead.LastName, &lead.ShortName); err != nil {
+			return nil, err
+		}
+		leads[lead.JID] = lead
+	}
+	return leads, rows.Err()
+}
+
 func (r *LeadRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
 	_, err := r.db.Exec(ctx, `UPDATE leads SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
 	return err
 }
 
+// GetAssignee returns a lead's assignee, trashed leads included, or
+// pgx.ErrNoRows when the lead is not in the account.
+func (r *LeadRepository) GetAssignee(ctx context.Context, accountID, leadID uuid.UUID) (*uuid.UUID, error) {
+	var assignee *uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT assigned_to FROM leads WHERE id = $1 AND account_id = $2`, leadID, accountID).Scan(&assignee)
+	return assignee, err
+}
+
+// FilterVisible keeps the IDs of leads of the account that the scope
+// allows, in their original order. Unknown IDs are dropped.
+func (r *LeadRepository) FilterVisible(ctx context.Context, accountID uuid.UUID, ids []uuid.UUID, scope *domain.AssignmentScope) ([]uuid.UUID, error) {
+	query := `SELECT id FROM leads l WHERE l.account_id = $1 AND l.id = ANY($2)`
+	args := []interface{}{accountID, ids}
+	if scope != nil {
+		query += assignmentScopeSQL("l.assigned_to", 3, scope)
+		args = append(args, scope.UserID)
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	visible := make(map[uuid.UUID]struct{}, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		visible[id] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	kept := make([]uuid.UUID, 0, len(visible))
+	for _, id := range ids {
+		if _, ok := visible[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
 func (r *LeadRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Lead, error) {
 	lead := &domain.Lead{}
 	err := r.db.QueryRow(ctx, `
@@ -4113,7 +5002,7 @@ func (r *LeadRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Lea
 		       CASE WHEN l.contact_id IS NULL THEN COALESCE(l.is_blocked,FALSE) ELSE COALESCE(c.do_not_contact,FALSE) END,
 		       CASE WHEN l.contact_id IS NULL THEN l.blocked_at ELSE c.do_not_contact_at END,
 		       CASE WHEN l.contact_id IS NULL THEN l.block_reason ELSE c.do_not_contact_reason END,l.kommo_deleted_at,
-		       l.title, l.closed_at, l.closed_by, l.close_reason, l.deleted_at, l.deleted_by, l.delete_reason
+		       l.title, l.closed_at, l.closed_by, l.close_reason, l.deleted_at, l.deleted_by, l.delete_reason, l.score
 		FROM leads l
 		LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
 		LEFT JOIN pipeline_stages ps ON ps.id = l.stage_id
@@ -4124,7 +5013,7 @@ func (r *LeadRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Lea
 		&lead.CustomFields, &lead.AssignedTo, &lead.PipelineID, &lead.StageID, &lead.CreatedAt, &lead.UpdatedAt,
 		&lead.StageName, &lead.StageColor, &lead.StagePosition, &lead.KommoID,
 		&lead.IsArchived, &lead.ArchivedAt, &lead.IsBlocked, &lead.BlockedAt, &lead.BlockReason, &lead.KommoDeletedAt,
-		&lead.Title, &lead.ClosedAt, &lead.ClosedBy, &lead.CloseReason, &lead.DeletedAt, &lead.DeletedBy, &lead.DeleteReason,
+		&lead.Title, &lead.ClosedAt, &lead.ClosedBy, &lead.CloseReason, &lead.DeletedAt, &lead.DeletedBy, &lead.DeleteReason, &lead.Score,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -4143,9 +5032,10 @@ func (r *LeadRepository) Update(ctx context.Context, lead *domain.Lead) error {
 	}
 	defer tx.Rollback(ctx)
 	var contactID *uuid.UUID
+	var previousCustomFields map[string]interface{}
 	if err := tx.QueryRow(ctx, `
-		SELECT contact_id FROM leads WHERE id=$1 AND account_id=$2 FOR UPDATE
-	`, lead.ID, lead.AccountID).Scan(&contactID); err != nil {
+		SELECT contact_id, COALESCE(custom_fields, '{}'::jsonb) FROM leads WHERE id=$1 AND account_id=$2 FOR UPDATE
+	`, lead.ID, lead.AccountID).Scan(&contactID, &previousCustomFields); err != nil {
 		return err
 	}
 	if lead.AssignedTo != nil {
@@ -4164,6 +5054,11 @@ func (r *LeadRepository) Update(ctx context.Context, lead *domain.Lead) error {
 	`, lead.Notes, lead.Source, lead.Tags, lead.CustomFields, lead.AssignedTo, lead.Title, lead.ID, lead.AccountID); err != nil {
 		return err
 	}
+	if keys := changedCustomFieldKeys(previousCustomFields, lead.CustomFields); len(keys) > 0 {
+		if err := enqueueKommoMappedFieldsTx(ctx, tx, lead.AccountID, lead.ID, keys); err != nil {
+			return err
+		}
+	}
 	if contactID != nil && len(lead.PersonalFieldChanges) > 0 {
 		if lead.PersonalFieldChanges["phone"] && lead.Phone != nil {
 			normalized, err := normalizeContactProfilePhone(*lead.Phone)
@@ -4233,10 +5128,29 @@ func (r *LeadRepository) Update(ctx context.Context, lead *domain.Lead) error {
 	return tx.Commit(ctx)
 }
 
-// UpdateStage moves a lead to a different pipeline stage
-func (r *LeadRepository) UpdateStage(ctx context.Context, id uuid.UUID, stageID uuid.UUID) error {
-	_, err := r.db.Exec(ctx, `UPDATE leads SET stage_id = $1, updated_at = NOW() WHERE id = $2`, stageID, id)
-	return err
+// UpdateStage moves a lead to another stage and records the move in the
+// lead's stage-change history.
+func (r *LeadRepository) UpdateStage(ctx context.Context, id uuid.UUID, stageID uuid.UUID, changedBy *uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var accountID uuid.UUID
+	var fromStageID *uuid.UUID
+	if err := tx.QueryRow(ctx, `SELECT account_id, stage_id FROM leads WHERE id = $1 FOR UPDATE`, id).Scan(&accountID, &fromStageID); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE leads SET stage_id = $1, updated_at = NOW() WHERE id = $2`, stageID, id); err != nil {
+		return err
+	}
+	if err := insertLeadStageChange(ctx, tx, accountID, id, fromStageID, stageID, changedBy); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // SyncToContact remains for compatibility with older service callers. Contact
@@ -4275,7 +5189,7 @@ func (r *LeadRepository) GetByContactID(ctx context.Context, contactID uuid.UUID
 		LEFT JOIN contacts c ON c.id=l.contact_id AND c.account_id=l.account_id
 		LEFT JOIN pipeline_stages ps ON ps.id = l.stage_id
 		WHERE l.contact_id = $1
-		ORDER BY (l.deleted_at IS NULL) DESC, (l.status = 'open') DESC, l.updated_at DESC, l.id
+		ORDER BY (`+NotDeleted("l")+`) DESC, (l.status = 'open') DESC, l.updated_at DESC, l.id
 		LIMIT 1
 	`, contactID).Scan(
 		&lead.ID, &lead.AccountID, &lead.ContactID, &lead.JID, &lead.Name, &lead.LastName, &lead.ShortName, &lead.Phone,
@@ -4301,7 +5215,7 @@ func (r *LeadRepository) GetSummariesByContactID(ctx context.Context, accountID,
 		LEFT JOIN pipelines pp ON pp.id=l.pipeline_id AND pp.account_id=l.account_id
 		LEFT JOIN pipeline_stages ps ON ps.id=l.stage_id AND ps.pipeline_id=pp.id
 		WHERE l.account_id=$1 AND l.contact_id=$2
-		ORDER BY (l.deleted_at IS NULL) DESC, (l.status='open') DESC,
+		ORDER BY (`+NotDeleted("l")+`) DESC, (l.status='open') DESC,
 		         COALESCE(l.is_archived,false), l.updated_at DESC, l.id
 	`, accountID, contactID)
 	if err != nil {
@@ -4322,8 +5236,9 @@ func (r *LeadRepository) GetSummariesByContactID(ctx context.Context, accountID,
 	return items, rows.Err()
 }
 
+// Delete moves a lead to the trash; PurgeExpired removes it for good later.
 func (r *LeadRepository) Delete(ctx context.Context, accountID, id uuid.UUID) error {
-	cmd, err := r.db.Exec(ctx, `DELETE FROM leads WHERE account_id = $1 AND id = $2`, accountID, id)
+	cmd, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at = NOW(), updated_at = NOW() WHERE account_id = $1 AND id = $2 AND `+NotDeleted(""), accountID, id)
 	if err != nil {
 		return err
 	}
@@ -4334,7 +5249,7 @@ func (r *LeadRepository) Delete(ctx context.Context, accountID, id uuid.UUID) er
 }
 
 func (r *LeadRepository) DeleteBatch(ctx context.Context, accountID uuid.UUID, ids []uuid.UUID) error {
-	_, err := r.db.Exec(ctx, `DELETE FROM leads WHERE account_id = $1 AND id = ANY($2)`, accountID, ids)
+	_, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at = NOW(), updated_at = NOW() WHERE account_id = $1 AND id = ANY($2) AND `+NotDeleted(""), accountID, ids)
 	return err
 }
 
@@ -4349,7 +5264,7 @@ func (r *LeadRepository) GetContactIDForLead(ctx context.Context, leadID uuid.UU
 }
 
 func (r *LeadRepository) DeleteAll(ctx context.Context, accountID uuid.UUID) error {
-	_, err := r.db.Exec(ctx, `DELETE FROM leads WHERE account_id = $1`, accountID)
+	_, err := r.db.Exec(ctx, `UPDATE leads SET deleted_at = NOW(), updated_at = NOW() WHERE account_id = $1 AND `+NotDeleted(""), accountID)
 	return err
 }
 
@@ -4442,7 +5357,7 @@ func (r *PipelineRepository) GetByAccountID(ctx context.Context, accountID uuid.
 		}
 		stageRows, err := r.db.Query(ctx, `
 			SELECT ps.id, ps.pipeline_id, ps.name, ps.color, ps.position, ps.stage_type, ps.created_at,
-			       (SELECT COUNT(*) FROM leads WHERE stage_id = ps.id AND deleted_at IS NULL) as lead_count
+			       (SELECT COUNT(*) FROM leads WHERE stage_id = ps.id AND `+NotDeleted("")+`) as lead_count
 			FROM pipeline_stages ps WHERE ps.pipeline_id = ANY($1) ORDER BY ps.pipeline_id, ps.position
 		`, pipelineIDs)
 		if err != nil {
@@ -4469,7 +5384,7 @@ func (r *PipelineRepository) GetByAccountID(ctx context.Context, accountID uuid.
 func (r *PipelineRepository) GetStages(ctx context.Context, pipelineID uuid.UUID) ([]*domain.PipelineStage, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT ps.id, ps.pipeline_id, ps.name, ps.color, ps.position, ps.stage_type, ps.created_at,
-		       (SELECT COUNT(*) FROM leads WHERE stage_id = ps.id AND deleted_at IS NULL) as lead_count
+		       (SELECT COUNT(*) FROM leads WHERE stage_id = ps.id AND `+NotDeleted("")+`) as lead_count
 		FROM pipeline_stages ps WHERE ps.pipeline_id = $1 ORDER BY ps.position
 	`, pipelineID)
 	if err != nil {
@@ -4797,6 +5712,55 @@ func (r *TagRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID)
 	return tags, nil
 }
 
+// GetUsage returns every tag of the account with the number of live contacts,
+// leads, chats and event participants carrying it; unused tags count zero.
+func (r *TagRepository) GetUsage(ctx context.Context, accountID uuid.UUID) ([]*domain.TagUsage, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH account_tags AS (SELECT id FROM tags WHERE account_id = $1)
+		SELECT t.id, t.name, COALESCE(t.color, ''),
+			COALESCE(ct.n, 0), COALESCE(lt.n, 0), COALESCE(cht.n, 0), COALESCE(pt.n, 0)
+		FROM tags t
+		LEFT JOIN (
+			SELECT ctg.tag_id, COUNT(*) AS n FROM contact_tags ctg JOIN contacts c ON c.id = ctg.contact_id
+			WHERE ctg.tag_id IN (SELECT id FROM account_tags) AND c.deleted_at IS NULL GROUP BY ctg.tag_id
+		) ct ON ct.tag_id = t.id
+		LEFT JOIN (
+			SELECT ctg.tag_id, COUNT(*) AS n FROM contact_tags ctg
+			JOIN contacts c ON c.id = ctg.contact_id
+			JOIN leads l ON l.contact_id = ctg.contact_id
+			WHERE ctg.tag_id IN (SELECT id FROM account_tags) AND c.deleted_at IS NULL AND l.deleted_at IS NULL
+			GROUP BY ctg.tag_id
+		) lt ON lt.tag_id = t.id
+		LEFT JOIN (
+			SELECT tag_id, COUNT(*) AS n FROM chat_tags
+			WHERE tag_id IN (SELECT id FROM account_tags) GROUP BY tag_id
+		) cht ON cht.tag_id = t.id
+		LEFT JOIN (
+			SELECT tag_id, COUNT(*) AS n FROM participant_tags
+			WHERE tag_id IN (SELECT id FROM account_tags) GROUP BY tag_id
+		) pt ON pt.tag_id = t.id
+		WHERE t.account_id = $1
+		ORDER BY t.name
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []*domain.TagUsage
+	for rows.Next() {
+		u := &domain.TagUsage{}
+		if err := rows.Scan(&u.TagID, &u.Name, &u.Color, &u.Contacts, &u.Leads, &u.Chats, &u.Participants); err != nil {
+			return nil, err
+		}
+		// Leads carry their contact's tags, so they are already counted
+		// through the contact.
+		u.Total = u.Contacts + u.Chats + u.Participants
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
 func (r *TagRepository) ListPaginated(ctx context.Context, accountID uuid.UUID, search string, limit, offset int) ([]*domain.Tag, int, error) {
 	args := []interface{}{accountID}
 	where := "WHERE account_id = $1"
@@ -5416,22 +6380,25 @@ func (r *CampaignRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-func (r *CampaignRepository) AddRecipients(ctx context.Context, recipients []*domain.CampaignRecipient) error {
+// AddRecipients inserts recipients and returns how many rows were actually
+// added; a contact already in the campaign is skipped by the unique index.
+func (r *CampaignRepository) AddRecipients(ctx context.Context, recipients []*domain.CampaignRecipient) (int, error) {
 	if len(recipients) == 0 {
-		return nil
+		return 0, nil
 	}
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback(ctx)
 	var accountID uuid.UUID
 	if err := tx.QueryRow(ctx, `SELECT account_id FROM campaigns WHERE id=$1 FOR UPDATE`, recipients[0].CampaignID).Scan(&accountID); err != nil {
-		return err
+		return 0, err
 	}
+	added := 0
 	for _, rec := range recipients {
 		if rec.CampaignID != recipients[0].CampaignID || rec.ContactID == nil {
-			return fmt.Errorf("cada destinatario debe pertenecer a la campaña y tener un contacto")
+			return 0, fmt.Errorf("cada destinatario debe pertenecer a la campaña y tener un contacto")
 		}
 		var allowed bool
 		if err := tx.QueryRow(ctx, `
@@ -5445,10 +6412,10 @@ func (r *CampaignRepository) AddRecipients(ctx context.Context, recipients []*do
 				  )
 			)
 		`, *rec.ContactID, accountID).Scan(&allowed); err != nil {
-			return err
+			return 0, err
 		}
 		if !allowed {
-			return fmt.Errorf("el destinatario no es un contacto vigente y habilitado de la cuenta")
+			return 0, fmt.Errorf("el destinatario no es un contacto vigente y habilitado de la cuenta")
 		}
 		rec.ID = uuid.New()
 		if rec.Status == "" {
@@ -5460,14 +6427,15 @@ func (r *CampaignRepository) AddRecipients(ctx context.Context, recipients []*do
 				metaJSON = b
 			}
 		}
-		_, err := tx.Exec(ctx, `
+		tag, err := tx.Exec(ctx, `
 			INSERT INTO campaign_recipients (id, campaign_id, contact_id, jid, name, phone, status, metadata)
 			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
 			ON CONFLICT (campaign_id, contact_id) WHERE contact_id IS NOT NULL DO NOTHING
 		`, rec.ID, rec.CampaignID, rec.ContactID, rec.JID, rec.Name, rec.Phone, rec.Status, metaJSON)
 		if err != nil {
-			return err
+			return 0, err
 		}
+		added += int(tag.RowsAffected())
 	}
 	// Update total count
 	_, err = tx.Exec(ctx, `
@@ -5475,9 +6443,28 @@ func (r *CampaignRepository) AddRecipients(ctx context.Context, recipients []*do
 		WHERE id = $1
 	`, recipients[0].CampaignID)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return tx.Commit(ctx)
+	return added, tx.Commit(ctx)
+}
+
+// GetRecipientAddresses returns the jid and phone of every recipient already
+// in the campaign, for duplicate detection before adding more.
+func (r *CampaignRepository) GetRecipientAddresses(ctx context.Context, campaignID uuid.UUID) ([]domain.CampaignRecipient, error) {
+	rows, err := r.db.Query(ctx, `SELECT jid, phone FROM campaign_recipients WHERE campaign_id = $1`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var recipients []domain.CampaignRecipient
+	for rows.Next() {
+		var rec domain.CampaignRecipient
+		if err := rows.Scan(&rec.JID, &rec.Phone); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, rec)
+	}
+	return recipients, rows.Err()
 }
 
 // AddRecipientsFromContactIDs resolves canonical Contact data and inserts all
@@ -5607,7 +6594,7 @@ func (r *CampaignRepository) AddRecipientsFromContactIDs(ctx context.Context, ca
 
 func (r *CampaignRepository) GetRecipients(ctx context.Context, campaignID uuid.UUID) ([]*domain.CampaignRecipient, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, COALESCE(metadata, '{}')
+		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, COALESCE(metadata, '{}'), COALESCE(channel, 'whatsapp'), retry_count, next_retry_at
 		FROM campaign_recipients WHERE campaign_id = $1 ORDER BY sent_at ASC NULLS LAST, id
 	`, campaignID)
 	if err != nil {
@@ -5619,7 +6606,7 @@ func (r *CampaignRepository) GetRecipients(ctx context.Context, campaignID uuid.
 	for rows.Next() {
 		rec := &domain.CampaignRecipient{}
 		var metaJSON []byte
-		if err := rows.Scan(&rec.ID, &rec.CampaignID, &rec.ContactID, &rec.JID, &rec.Name, &rec.Phone, &rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &metaJSON); err != nil {
+		if err := rows.Scan(&rec.ID, &rec.CampaignID, &rec.ContactID, &rec.JID, &rec.Name, &rec.Phone, &rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &metaJSON, &rec.Channel, &rec.RetryCount, &rec.NextRetryAt); err != nil {
 			return nil, err
 		}
 		if len(metaJSON) > 2 {
@@ -5634,9 +6621,9 @@ func (r *CampaignRepository) GetRecipientByID(ctx context.Context, recipientID u
 	rec := &domain.CampaignRecipient{}
 	var metaJSON []byte
 	err := r.db.QueryRow(ctx, `
-		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, COALESCE(metadata, '{}')
+		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, COALESCE(metadata, '{}'), COALESCE(channel, 'whatsapp'), retry_count, next_retry_at
 		FROM campaign_recipients WHERE id = $1
-	`, recipientID).Scan(&rec.ID, &rec.CampaignID, &rec.ContactID, &rec.JID, &rec.Name, &rec.Phone, &rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &metaJSON)
+	`, recipientID).Scan(&rec.ID, &rec.CampaignID, &rec.ContactID, &rec.JID, &rec.Name, &rec.Phone, &rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &metaJSON, &rec.Channel, &rec.RetryCount, &rec.NextRetryAt)
 	if err != nil {
 		return nil, err
 	}
@@ -5646,14 +6633,23 @@ func (r *CampaignRepository) GetRecipientByID(ctx context.Context, recipientID u
 	return rec, nil
 }
 
+// CountPendingRecipients returns how many recipients a start or resume
+// would still message.
+func (r *CampaignRepository) CountPendingRecipients(ctx context.Context, campaignID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM campaign_recipients WHERE campaign_id = $1 AND status = 'pending'`, campaignID).Scan(&count)
+	return count, err
+}
+
 func (r *CampaignRepository) GetNextPendingRecipient(ctx context.Context, campaignID uuid.UUID) (*domain.CampaignRecipient, error) {
 	rec := &domain.CampaignRecipient{}
 	var metaJSON []byte
 	err := r.db.QueryRow(ctx, `
-		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, COALESCE(metadata, '{}')
+		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, COALESCE(metadata, '{}'), COALESCE(channel, 'whatsapp'), retry_count, next_retry_at
 		FROM campaign_recipients WHERE campaign_id = $1 AND status = 'pending'
+			AND (next_retry_at IS NULL OR next_retry_at <= NOW())
 		ORDER BY id LIMIT 1
-	`, campaignID).Scan(&rec.ID, &rec.CampaignID, &rec.ContactID, &rec.JID, &rec.Name, &rec.Phone, &rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &metaJSON)
+	`, campaignID).Scan(&rec.ID, &rec.CampaignID, &rec.ContactID, &rec.JID, &rec.Name, &rec.Phone, &rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &metaJSON, &rec.Channel, &rec.RetryCount, &rec.NextRetryAt)
 	if err != nil {
 		return nil, err
 	}
@@ -5663,20 +6659,76 @@ func (r *CampaignRepository) GetNextPendingRecipient(ctx context.Context, campai
 	return rec, nil
 }
 
+// MarkRecipientsInvalid flags pending recipients whose number is not on
+// WhatsApp so the campaign never attempts them.
+func (r *CampaignRepository) MarkRecipientsInvalid(ctx context.Context, campaignID uuid.UUID, ids []uuid.UUID, errMsg string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	tag, err := r.db.Exec(ctx, `
+		UPDATE campaign_recipients SET status = 'invalid', error_message = $1, processed_at = NOW()
+		WHERE campaign_id = $2 AND id = ANY($3) AND status = 'pending'
+	`, errMsg, campaignID, ids)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// RequeueRecipient puts a recipient whose send failed transiently back in
+// the queue, bumping retry_count and holding it until nextRetryAt.
+func (r *CampaignRepository) RequeueRecipient(ctx context.Context, id uuid.UUID, errMsg string, nextRetryAt time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE campaign_recipients SET status = 'pending', error_message = $1, retry_count = retry_count + 1,
+			next_retry_at = $2, processed_at = NULL
+		WHERE id = $3
+	`, errMsg, nextRetryAt, id)
+	return err
+}
+
 func (r *CampaignRepository) UpdateRecipientStatus(ctx context.Context, id uuid.UUID, status string, errMsg *string, waitTimeMs *int) error {
 	if status == "sent" {
 		now := time.Now()
 		_, err := r.db.Exec(ctx, `
-			UPDATE campaign_recipients SET status = $1, sent_at = $2, error_message = $3, wait_time_ms = $4 WHERE id = $5
+			UPDATE campaign_recipients SET status = $1, sent_at = $2, error_message = $3, wait_time_ms = $4, processed_at = $2 WHERE id = $5
 		`, status, now, errMsg, waitTimeMs, id)
 		return err
 	}
 	_, err := r.db.Exec(ctx, `
-		UPDATE campaign_recipients SET status = $1, error_message = $2, wait_time_ms = $3 WHERE id = $4
+		UPDATE campaign_recipients SET status = $1, error_message = $2, wait_time_ms = $3,
+			processed_at = CASE WHEN $1 = 'pending' THEN NULL ELSE NOW() END
+		WHERE id = $4
 	`, status, errMsg, waitTimeMs, id)
 	return err
 }
 
+// CountRecipientsByStatus returns how many recipients the campaign has in
+// each status.
+func (r *CampaignRepository) CountRecipientsByStatus(ctx context.Context, campaignID uuid.UUID) (map[string]int, error) {
+	rows, err := r.db.Query(ctx, `SELECT status, COUNT(*) FROM campaign_recipients WHERE campaign_id = $1 GROUP BY status`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// UpdateRecipientChannel records which channel delivered (or attempted) the
+// recipient's message.
+func (r *CampaignRepository) UpdateRecipientChannel(ctx context.Context, id uuid.UUID, channel string) error {
+	_, err := r.db.Exec(ctx, `UPDATE campaign_recipients SET channel = $1 WHERE id = $2`, channel, id)
+	return err
+}
+
 func (r *CampaignRepository) IncrementSentCount(ctx context.Context, campaignID uuid.UUID) error {
 	_, err := r.db.Exec(ctx, `UPDATE campaigns SET sent_count = sent_count + 1, updated_at = NOW() WHERE id = $1`, campaignID)
 	return err
@@ -5720,11 +6772,11 @@ func (r *CampaignRepository) UpdateRecipientData(ctx context.Context, campaignID
 	}
 	rec := &domain.CampaignRecipient{}
 	err = r.db.QueryRow(ctx, `
-		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, metadata
+		SELECT id, campaign_id, contact_id, jid, name, phone, status, sent_at, error_message, wait_time_ms, metadata, COALESCE(channel, 'whatsapp'), retry_count, next_retry_at
 		FROM campaign_recipients WHERE id = $1
 	`, recipientID).Scan(
 		&rec.ID, &rec.CampaignID, &rec.ContactID, &rec.JID, &rec.Name, &rec.Phone,
-		&rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &rec.Metadata,
+		&rec.Status, &rec.SentAt, &rec.ErrorMessage, &rec.WaitTimeMs, &rec.Metadata, &rec.Channel, &rec.RetryCount, &rec.NextRetryAt,
 	)
 	if err != nil {
 		return nil, err
@@ -5793,12 +6845,104 @@ func (r *EventRepository) Create(ctx context.Context, e *domain.Event) error {
 		e.TagFormulaType = "simple"
 	}
 	_, err := r.db.Exec(ctx, `
-		INSERT INTO events (id, account_id, folder_id, pipeline_id, name, description, event_date, event_end, location, status, color, tag_formula_mode, tag_formula, tag_formula_type, created_by, created_at, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)
-	`, e.ID, e.AccountID, e.FolderID, e.PipelineID, e.Name, e.Description, e.EventDate, e.EventEnd, e.Location, e.Status, e.Color, e.TagFormulaMode, e.TagFormula, e.TagFormulaType, e.CreatedBy, e.CreatedAt, e.UpdatedAt)
+		INSERT INTO events (id, account_id, folder_id, pipeline_id, name, description, event_date, event_end, location, status, color, tag_formula_mode, tag_formula, tag_formula_type, recurrence_rule, recurrence_copy_participants, recurrence_parent_id, created_by, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)
+	`, e.ID, e.AccountID, e.FolderID, e.PipelineID, e.Name, e.Description, e.EventDate, e.EventEnd, e.Location, e.Status, e.Color, e.TagFormulaMode, e.TagFormula, e.TagFormulaType, e.RecurrenceRule, e.RecurrenceCopyParticipants, e.RecurrenceParentID, e.CreatedBy, e.CreatedAt, e.UpdatedAt)
+	return err
+}
+
+// SetRecurrence changes how an event repeats. Clearing the rule on an
+// occurrence stops the series after it.
+func (r *EventRepository) SetRecurrence(ctx context.Context, eventID, accountID uuid.UUID, rule string, copyParticipants bool) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE events SET recurrence_rule=$1, recurrence_copy_participants=$2, updated_at=NOW()
+		WHERE id=$3 AND account_id=$4
+	`, rule, copyParticipants, eventID, accountID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimDueRecurrences marks up to limit recurring events whose date has
+// passed as materialized and returns them, so each occurrence produces its
+// successor exactly once even with several workers.
+func (r *EventRepository) ClaimDueRecurrences(ctx context.Context, limit int) ([]*domain.Event, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM events
+			WHERE recurrence_rule <> '' AND recurrence_materialized_at IS NULL
+			  AND event_date IS NOT NULL AND event_date < NOW() AND status <> 'cancelled'
+			ORDER BY event_date
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE events e SET recurrence_materialized_at=NOW()
+		FROM due WHERE e.id=due.id
+		RETURNING e.id, e.account_id, e.folder_id, e.pipeline_id, e.name, e.description, e.event_date, e.event_end, e.location, e.status, e.color,
+		          COALESCE(e.tag_formula_mode, 'OR'), COALESCE(e.tag_formula, ''), COALESCE(e.tag_formula_type, 'simple'),
+		          e.recurrence_rule, e.recurrence_copy_participants, e.created_by
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*domain.Event
+	for rows.Next() {
+		ev := &domain.Event{}
+		if err := rows.Scan(&ev.ID, &ev.AccountID, &ev.FolderID, &ev.PipelineID, &ev.Name, &ev.Description, &ev.EventDate, &ev.EventEnd, &ev.Location, &ev.Status, &ev.Color,
+			&ev.TagFormulaMode, &ev.TagFormula, &ev.TagFormulaType,
+			&ev.RecurrenceRule, &ev.RecurrenceCopyParticipants, &ev.CreatedBy); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// ReleaseRecurrenceClaim undoes ClaimDueRecurrences for an event whose next
+// occurrence could not be created, so a later run retries it.
+func (r *EventRepository) ReleaseRecurrenceClaim(ctx context.Context, eventID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE events SET recurrence_materialized_at=NULL WHERE id=$1 AND recurrence_next_id IS NULL`, eventID)
+	return err
+}
+
+// LinkRecurrence records nextID as the occurrence that follows eventID.
+func (r *EventRepository) LinkRecurrence(ctx context.Context, eventID, nextID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE events SET recurrence_next_id=$2 WHERE id=$1`, eventID, nextID)
+	return err
+}
+
+// CopyEventTags gives toEventID the tag conditions of fromEventID.
+func (r *EventRepository) CopyEventTags(ctx context.Context, fromEventID, toEventID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO event_tags (event_id, tag_id, negate)
+		SELECT $2, tag_id, negate FROM event_tags WHERE event_id = $1
+		ON CONFLICT DO NOTHING
+	`, fromEventID, toEventID)
 	return err
 }
 
+// CopyRecurringParticipants invites the active participants of one occurrence
+// to the next, with fresh status and no stage, next action or notes.
+func (r *EventRepository) CopyRecurringParticipants(ctx context.Context, fromEventID, toEventID uuid.UUID) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO event_participants (id,event_id,contact_id,lead_id,name,last_name,short_name,phone,email,age,company,dni,birth_date,address,distrito,ocupacion,status,membership_state,membership_reason,membership_source,membership_changed_at,invited_at,created_at,updated_at)
+		SELECT gen_random_uuid(),$2,contact_id,lead_id,name,last_name,short_name,phone,email,age,company,dni,birth_date,address,distrito,ocupacion,'invited','active','','manual',NOW(),NOW(),NOW(),NOW()
+		FROM event_participants
+		WHERE event_id=$1 AND membership_state='active'
+		ON CONFLICT DO NOTHING
+	`, fromEventID, toEventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 func (r *EventRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID, filter domain.EventFilter) ([]*domain.Event, int, error) {
 	baseQuery := ` FROM events WHERE account_id = $1`
 	args := []interface{}{accountID}
@@ -5842,7 +6986,7 @@ func (r *EventRepository) GetByAccountID(ctx context.Context, accountID uuid.UUI
 		return nil, 0, err
 	}
 
-	selectQuery := `SELECT id, account_id, folder_id, pipeline_id, name, description, event_date, event_end, location, status, color, tag_formula_mode, tag_formula, tag_formula_type, rule_revision, created_by, created_at, updated_at` + baseQuery + ` ORDER BY COALESCE(event_date, created_at) DESC`
+	selectQuery := `SELECT id, account_id, folder_id, pipeline_id, name, description, event_date, event_end, location, status, color, tag_formula_mode, tag_formula, tag_formula_type, rule_revision, recurrence_rule, recurrence_copy_participants, recurrence_parent_id, recurrence_next_id, created_by, created_at, updated_at` + baseQuery + ` ORDER BY COALESCE(event_date, created_at) DESC`
 	if filter.Limit > 0 {
 		selectQuery += fmt.Sprintf(" LIMIT %d", filter.Limit)
 		if filter.Offset > 0 {
@@ -5859,7 +7003,7 @@ func (r *EventRepository) GetByAccountID(ctx context.Context, accountID uuid.UUI
 	var events []*domain.Event
 	for rows.Next() {
 		ev := &domain.Event{}
-		if err := rows.Scan(&ev.ID, &ev.AccountID, &ev.FolderID, &ev.PipelineID, &ev.Name, &ev.Description, &ev.EventDate, &ev.EventEnd, &ev.Location, &ev.Status, &ev.Color, &ev.TagFormulaMode, &ev.TagFormula, &ev.TagFormulaType, &ev.RuleRevision, &ev.CreatedBy, &ev.CreatedAt, &ev.UpdatedAt); err != nil {
+		if err := rows.Scan(&ev.ID, &ev.AccountID, &ev.FolderID, &ev.PipelineID, &ev.Name, &ev.Description, &ev.EventDate, &ev.EventEnd, &ev.Location, &ev.Status, &ev.Color, &ev.TagFormulaMode, &ev.TagFormula, &ev.TagFormulaType, &ev.RuleRevision, &ev.RecurrenceRule, &ev.RecurrenceCopyParticipants, &ev.RecurrenceParentID, &ev.RecurrenceNextID, &ev.CreatedBy, &ev.CreatedAt, &ev.UpdatedAt); err != nil {
 			return nil, 0, err
 		}
 		events = append(events, ev)
@@ -5879,9 +7023,9 @@ func (r *EventRepository) GetByAccountID(ctx context.Context, accountID uuid.UUI
 func (r *EventRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Event, error) {
 	ev := &domain.Event{}
 	err := r.db.QueryRow(ctx, `
-		SELECT id, account_id, folder_id, pipeline_id, name, description, event_date, event_end, location, status, color, tag_formula_mode, tag_formula, tag_formula_type, rule_revision, created_by, created_at, updated_at
+		SELECT id, account_id, folder_id, pipeline_id, name, description, event_date, event_end, location, status, color, tag_formula_mode, tag_formula, tag_formula_type, rule_revision, recurrence_rule, recurrence_copy_participants, recurrence_parent_id, recurrence_next_id, created_by, created_at, updated_at
 		FROM events WHERE id = $1
-	`, id).Scan(&ev.ID, &ev.AccountID, &ev.FolderID, &ev.PipelineID, &ev.Name, &ev.Description, &ev.EventDate, &ev.EventEnd, &ev.Location, &ev.Status, &ev.Color, &ev.TagFormulaMode, &ev.TagFormula, &ev.TagFormulaType, &ev.RuleRevision, &ev.CreatedBy, &ev.CreatedAt, &ev.UpdatedAt)
+	`, id).Scan(&ev.ID, &ev.AccountID, &ev.FolderID, &ev.PipelineID, &ev.Name, &ev.Description, &ev.EventDate, &ev.EventEnd, &ev.Location, &ev.Status, &ev.Color, &ev.TagFormulaMode, &ev.TagFormula, &ev.TagFormulaType, &ev.RuleRevision, &ev.RecurrenceRule, &ev.RecurrenceCopyParticipants, &ev.RecurrenceParentID, &ev.RecurrenceNextID, &ev.CreatedBy, &ev.CreatedAt, &ev.UpdatedAt)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -6112,6 +7256,46 @@ func (r *EventRepository) GetParticipantCounts(ctx context.Context, eventID uuid
 	return counts, total, nil
 }
 
+// GetParticipantTagCounts counts the event's active participants per tag of
+// their contact, most used first.
+func (r *EventRepository) GetParticipantTagCounts(ctx context.Context, eventID uuid.UUID) ([]*domain.EventTagCount, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT t.id, t.name, t.color, COUNT(DISTINCT ep.id)
+		FROM event_participants ep
+		LEFT JOIN leads l ON l.id = ep.lead_id
+		JOIN contact_tags ct ON ct.contact_id = COALESCE(ep.contact_id, l.contact_id)
+		JOIN tags t ON t.id = ct.tag_id
+		WHERE ep.event_id = $1 AND ep.membership_state='active'
+		GROUP BY t.id, t.name, t.color
+		ORDER BY COUNT(DISTINCT ep.id) DESC, t.name
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := make([]*domain.EventTagCount, 0)
+	for rows.Next() {
+		tc := &domain.EventTagCount{}
+		if err := rows.Scan(&tc.TagID, &tc.Name, &tc.Color, &tc.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tc)
+	}
+	return tags, rows.Err()
+}
+
+// GetPendingActionCount counts active participants with a next action date
+// who haven't attended, declined or missed the event.
+func (r *EventRepository) GetPendingActionCount(ctx context.Context, eventID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM event_participants
+		WHERE event_id = $1 AND membership_state='active' AND next_action_date IS NOT NULL
+		  AND status NOT IN ('attended','no_show','declined')
+	`, eventID).Scan(&count)
+	return count, err
+}
+
 func (r *EventRepository) GetStageNameCounts(ctx context.Context, eventID uuid.UUID) (map[string]int, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT eps.name, COUNT(*) FROM event_participants ep
@@ -7585,6 +8769,51 @@ func (r *ParticipantRepository) BulkUpdateStatus(ctx context.Context, accountID,
 	return finishEventParticipantMutation(ctx, tx, result.RowsAffected(), int64(len(ids)))
 }
 
+// FindActiveByPhones maps each normalized phone to the active event
+// participant it belongs to. The participant's own phone is used unless it is
+// linked to a contact, matching what the participant list shows. Phones must
+// already be normalized (digits only, Peruvian mobiles prefixed with 51);
+// when two participants share a phone the oldest one wins.
+func (r *ParticipantRepository) FindActiveByPhones(ctx context.Context, eventID uuid.UUID, phones []string) (map[string]*domain.EventParticipant, error) {
+	found := make(map[string]*domain.EventParticipant)
+	if len(phones) == 0 {
+		return found, nil
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT ON (m.normalized) m.normalized, m.id, m.contact_id, m.lead_id, m.status, m.attended_at
+		FROM (
+			SELECT p.id, p.contact_id, p.lead_id, p.status, p.attended_at, p.created_at,
+				CASE
+					WHEN LENGTH(x.digits)=9 AND x.digits LIKE '9%' THEN '51'||x.digits
+					ELSE x.digits
+				END AS normalized
+			FROM event_participants p
+			LEFT JOIN leads l ON l.id=p.lead_id
+			LEFT JOIN contacts c ON c.id=COALESCE(p.contact_id,l.contact_id)
+			CROSS JOIN LATERAL (
+				SELECT REGEXP_REPLACE(COALESCE(CASE WHEN COALESCE(p.contact_id,l.contact_id) IS NULL THEN p.phone ELSE c.phone END,''),'[^0-9]','','g') AS digits
+			) x
+			WHERE p.event_id=$1 AND p.membership_state='active'
+		) m
+		WHERE m.normalized=ANY($2::text[])
+		ORDER BY m.normalized, m.created_at
+	`, eventID, phones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var phone string
+		p := &domain.EventParticipant{EventID: eventID, MembershipState: "active"}
+		if err := rows.Scan(&phone, &p.ID, &p.ContactID, &p.LeadID, &p.Status, &p.AttendedAt); err != nil {
+			return nil, err
+		}
+		p.Phone = &phone
+		found[phone] = p
+	}
+	return found, rows.Err()
+}
+
 // UpdateStage updates a participant's stage after revalidating the stage
 // against the event's current pipeline inside the same transaction.
 func (r *ParticipantRepository) UpdateStage(ctx context.Context, accountID, eventID, id, stageID uuid.UUID) (int64, error) {
@@ -7822,6 +9051,54 @@ func (r *ParticipantRepository) GetUpcomingActions(ctx context.Context, accountI
 	return participants, nil
 }
 
+// ClaimDueActionReminders marks up to limit participant next actions due
+// within lookahead as reminded and returns them. Actions more than lookback
+// overdue are left alone, and SKIP LOCKED keeps concurrent workers from
+// claiming the same row.
+func (r *ParticipantRepository) ClaimDueActionReminders(ctx context.Context, lookahead, lookback time.Duration, limit int) ([]*domain.ParticipantActionReminder, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH due AS (
+			SELECT ep.id
+			FROM event_participants ep
+			WHERE ep.membership_state='active' AND ep.next_action_date IS NOT NULL
+			  AND ep.status NOT IN ('attended','no_show','declined')
+			  AND ep.next_action_date <= NOW() + $1::interval
+			  AND ep.next_action_date >= NOW() - $2::interval
+			  AND ep.next_action_reminded_for IS DISTINCT FROM ep.next_action_date
+			ORDER BY ep.next_action_date
+			LIMIT $3
+			FOR UPDATE OF ep SKIP LOCKED
+		), claimed AS (
+			UPDATE event_participants ep
+			SET next_action_reminded_at=NOW(), next_action_reminded_for=ep.next_action_date
+			FROM due WHERE ep.id=due.id
+			RETURNING ep.id, ep.event_id, ep.contact_id, ep.lead_id, ep.name, ep.next_action, ep.next_action_date
+		)
+		SELECT cl.id, cl.event_id, e.account_id, e.name, cl.contact_id, cl.lead_id,
+		       COALESCE(NULLIF(BTRIM(c.custom_name),''),NULLIF(BTRIM(c.name),''),NULLIF(BTRIM(c.push_name),''),cl.name,''),
+		       cl.next_action, cl.next_action_date, COALESCE(l.assigned_to, e.created_by)
+		FROM claimed cl
+		JOIN events e ON e.id=cl.event_id
+		LEFT JOIN leads l ON l.id=cl.lead_id AND l.account_id=e.account_id
+		LEFT JOIN contacts c ON c.id=COALESCE(cl.contact_id,l.contact_id) AND c.account_id=e.account_id
+		ORDER BY cl.next_action_date
+	`, lookahead.String(), lookback.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*domain.ParticipantActionReminder
+	for rows.Next() {
+		rem := &domain.ParticipantActionReminder{}
+		if err := rows.Scan(&rem.ParticipantID, &rem.EventID, &rem.AccountID, &rem.EventName, &rem.ContactID, &rem.LeadID, &rem.Name, &rem.NextAction, &rem.NextActionDate, &rem.AssignedTo); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, rem)
+	}
+	return reminders, rows.Err()
+}
+
 // ============================================================
 // InteractionRepository handles interaction data access
 // ============================================================
@@ -8301,11 +9578,14 @@ func (r *QuickReplyRepository) loadAttachments(ctx context.Context, qrIDs []uuid
 	return m, nil
 }
 
-func (r *QuickReplyRepository) GetByAccountID(ctx context.Context, accountID uuid.UUID) ([]*domain.QuickReply, error) {
+// GetByAccountID returns the account's shared quick replies plus the personal
+// ones owned by userID.
+func (r *QuickReplyRepository) GetByAccountID(ctx context.Context, accountID, userID uuid.UUID) ([]*domain.QuickReply, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, account_id, shortcut, title, body, COALESCE(media_url,''), COALESCE(media_type,''), COALESCE(media_filename,''), created_at, updated_at
-		FROM quick_replies WHERE account_id = $1 ORDER BY shortcut
-	`, accountID)
+		SELECT id, account_id, owner_id, shortcut, title, body, COALESCE(media_url,''), COALESCE(media_type,''), COALESCE(media_filename,''), use_count, last_used_at, created_at, updated_at
+		FROM quick_replies WHERE account_id = $1 AND (owner_id IS NULL OR owner_id = $2)
+		ORDER BY use_count DESC, last_used_at DESC NULLS LAST, shortcut
+	`, accountID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -8315,7 +9595,7 @@ func (r *QuickReplyRepository) GetByAccountID(ctx context.Context, accountID uui
 	var ids []uuid.UUID
 	for rows.Next() {
 		qr := &domain.QuickReply{}
-		if err := rows.Scan(&qr.ID, &qr.AccountID, &qr.Shortcut, &qr.Title, &qr.Body, &qr.MediaURL, &qr.MediaType, &qr.MediaFilename, &qr.CreatedAt, &qr.UpdatedAt); err != nil {
+		if err := rows.Scan(&qr.ID, &qr.AccountID, &qr.OwnerID, &qr.Shortcut, &qr.Title, &qr.Body, &qr.MediaURL, &qr.MediaType, &qr.MediaFilename, &qr.UseCount, &qr.LastUsedAt, &qr.CreatedAt, &qr.UpdatedAt); err != nil {
 			return nil, err
 		}
 		replies = append(replies, qr)
@@ -8339,9 +9619,9 @@ func (r *QuickReplyRepository) GetByAccountID(ctx context.Context, accountID uui
 func (r *QuickReplyRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.QuickReply, error) {
 	qr := &domain.QuickReply{}
 	err := r.db.QueryRow(ctx, `
-		SELECT id, account_id, shortcut, title, body, COALESCE(media_url,''), COALESCE(media_type,''), COALESCE(media_filename,''), created_at, updated_at
+		SELECT id, account_id, owner_id, shortcut, title, body, COALESCE(media_url,''), COALESCE(media_type,''), COALESCE(media_filename,''), use_count, last_used_at, created_at, updated_at
 		FROM quick_replies WHERE id = $1
-	`, id).Scan(&qr.ID, &qr.AccountID, &qr.Shortcut, &qr.Title, &qr.Body, &qr.MediaURL, &qr.MediaType, &qr.MediaFilename, &qr.CreatedAt, &qr.UpdatedAt)
+	`, id).Scan(&qr.ID, &qr.AccountID, &qr.OwnerID, &qr.Shortcut, &qr.Title, &qr.Body, &qr.MediaURL, &qr.MediaType, &qr.MediaFilename, &qr.UseCount, &qr.LastUsedAt, &qr.CreatedAt, &qr.UpdatedAt)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -8365,9 +9645,9 @@ func (r *QuickReplyRepository) Create(ctx context.Context, qr *domain.QuickReply
 	qr.CreatedAt = now
 	qr.UpdatedAt = now
 	_, err := r.db.Exec(ctx, `
-		INSERT INTO quick_replies (id, account_id, shortcut, title, body, media_url, media_type, media_filename, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`, qr.ID, qr.AccountID, qr.Shortcut, qr.Title, qr.Body, qr.MediaURL, qr.MediaType, qr.MediaFilename, qr.CreatedAt, qr.UpdatedAt)
+		INSERT INTO quick_replies (id, account_id, owner_id, shortcut, title, body, media_url, media_type, media_filename, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, qr.ID, qr.AccountID, qr.OwnerID, qr.Shortcut, qr.Title, qr.Body, qr.MediaURL, qr.MediaType, qr.MediaFilename, qr.CreatedAt, qr.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -8411,6 +9691,20 @@ func (r *QuickReplyRepository) ReplaceAttachments(ctx context.Context, quickRepl
 	return nil
 }
 
+// MarkUsed records one use of a quick reply. The increment happens in the
+// UPDATE itself so concurrent agents never lose a count. It returns
+// pgx.ErrNoRows when the reply is not visible to userID in the account.
+func (r *QuickReplyRepository) MarkUsed(ctx context.Context, accountID, userID, id uuid.UUID) (int, time.Time, error) {
+	var useCount int
+	var lastUsedAt time.Time
+	err := r.db.QueryRow(ctx, `
+		UPDATE quick_replies SET use_count = use_count + 1, last_used_at = NOW()
+		WHERE id = $1 AND account_id = $2 AND (owner_id IS NULL OR owner_id = $3)
+		RETURNING use_count, last_used_at
+	`, id, accountID, userID).Scan(&useCount, &lastUsedAt)
+	return useCount, lastUsedAt, err
+}
+
 func (r *QuickReplyRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.Exec(ctx, `DELETE FROM quick_replies WHERE id = $1`, id)
 	return err
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+type SMSSettingsRepository struct {
+	db *pgxpool.Pool
+}
+
+// Get returns nil when the account has never configured SMS.
+func (r *SMSSettingsRepository) Get(ctx context.Context, accountID uuid.UUID) (*domain.AccountSMSSettings, error) {
+	settings := &domain.AccountSMSSettings{}
+	err := r.db.QueryRow(ctx, `
+		SELECT account_id, provider, enabled, twilio_account_sid, twilio_auth_token, from_number, created_at, updated_at
+		FROM account_sms_settings WHERE account_id = $1
+	`, accountID).Scan(&settings.AccountID, &settings.Provider, &settings.Enabled, &settings.TwilioAccountSID,
+		&settings.TwilioAuthToken, &settings.FromNumber, &settings.CreatedAt, &settings.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *SMSSettingsRepository) Upsert(ctx context.Context, settings *domain.AccountSMSSettings) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO account_sms_settings (account_id, provider, enabled, twilio_account_sid, twilio_auth_token, from_number)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (account_id) DO UPDATE SET
+			provider = EXCLUDED.provider,
+			enabled = EXCLUDED.enabled,
+			twilio_account_sid = EXCLUDED.twilio_account_sid,
+			twilio_auth_token = EXCLUDED.twilio_auth_token,
+			from_number = EXCLUDED.from_number,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, settings.AccountID, settings.Provider, settings.Enabled, settings.TwilioAccountSID, settings.TwilioAuthToken, settings.FromNumber).
+		Scan(&settings.CreatedAt, &settings.UpdatedAt)
+}
+
+func (r *SMSSettingsRepository) Delete(ctx context.Context, accountID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM account_sms_settings WHERE account_id = $1`, accountID)
+	return err
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestTagIDsFilterSQLMatchAllGroupsByEntity(t *testing.T) {
+	got := tagIDsFilterSQL("c.id", "contact_tags", "contact_id", 3, domain.TagMatchAll)
+	for _, want := range []string{"GROUP BY contact_id", "HAVING COUNT(DISTINCT tag_id) = cardinality($3::uuid[])", "tag_id = ANY($3::uuid[])"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("tagIDsFilterSQL(all) = %q, missing %q", got, want)
+		}
+	}
+	any := tagIDsFilterSQL("c.id", "contact_tags", "contact_id", 3, domain.TagMatchAny)
+	if strings.Contains(any, "HAVING") {
+		t.Fatalf("tagIDsFilterSQL(any) = %q, want plain IN subquery", any)
+	}
+}
+
+func TestDedupeUUIDsKeepsOrder(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	got := dedupeUUIDs([]uuid.UUID{a, b, a})
+	if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Fatalf("dedupeUUIDs() = %v", got)
+	}
+}
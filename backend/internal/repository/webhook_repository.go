@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+// Get returns nil when the account has never configured a webhook.
+func (r *WebhookRepository) Get(ctx context.Context, accountID uuid.UUID) (*domain.AccountWebhook, error) {
+	hook := &domain.AccountWebhook{}
+	err := r.db.QueryRow(ctx, `
+		SELECT account_id, url, secret, events, enabled, created_at, updated_at
+		FROM account_webhooks WHERE account_id = $1
+	`, accountID).Scan(&hook.AccountID, &hook.URL, &hook.Secret, &hook.Events, &hook.Enabled, &hook.CreatedAt, &hook.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepository) Upsert(ctx context.Context, hook *domain.AccountWebhook) error {
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	return r.db.QueryRow(ctx, `
+		INSERT INTO account_webhooks (account_id, url, secret, events, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id) DO UPDATE SET
+			url = EXCLUDED.url,
+			secret = EXCLUDED.secret,
+			events = EXCLUDED.events,
+			enabled = EXCLUDED.enabled,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, hook.AccountID, hook.URL, hook.Secret, hook.Events, hook.Enabled).Scan(&hook.CreatedAt, &hook.UpdatedAt)
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, accountID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM account_webhooks WHERE account_id = $1`, accountID)
+	return err
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (account_id, event, url, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, delivery.AccountID, delivery.Event, delivery.URL, delivery.Payload, delivery.Status, delivery.NextAttemptAt).Scan(&delivery.ID, &delivery.CreatedAt)
+}
+
+// UpdateDelivery records the outcome of the latest attempt.
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, error = $5, delivered_at = $6, next_attempt_at = $7
+		WHERE id = $1
+	`, delivery.ID, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.Error, delivery.DeliveredAt, delivery.NextAttemptAt)
+	return err
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next
+// attempt is due and pushes that attempt lease into the future, so another
+// replica polling at the same time does not send them too.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE d.id = due.id
+		RETURNING d.id, d.account_id, d.event, d.url, d.payload, d.status, d.attempts, d.response_status, d.error, d.created_at, d.delivered_at, d.next_attempt_at
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookDeliveries(rows)
+}
+
+// ListDeliveries returns the account's most recent deliveries, newest first.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, accountID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, account_id, event, url, payload, status, attempts, response_status, error, created_at, delivered_at, next_attempt_at
+		FROM webhook_deliveries
+		WHERE account_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, accountID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookDeliveries(rows)
+}
+
+func scanWebhookDeliveries(rows pgx.Rows) ([]*domain.WebhookDelivery, error) {
+	defer rows.Close()
+	deliveries := make([]*domain.WebhookDelivery, 0)
+	for rows.Next() {
+		d := &domain.WebhookDelivery{}
+		if err := rows.Scan(&d.ID, &d.AccountID, &d.Event, &d.URL, &d.Payload, &d.Status, &d.Attempts,
+			&d.ResponseStatus, &d.Error, &d.CreatedAt, &d.DeliveredAt, &d.NextAttemptAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/pkg/cache"
+)
+
+func newRedisAuthService(t *testing.T) *AuthService {
+	t.Helper()
+	server := miniredis.RunT(t)
+	c, err := cache.New("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := &AuthService{}
+	auth.SetCache(c)
+	return auth
+}
+
+func createTestSessions(t *testing.T, auth *AuthService, userID uuid.UUID, n int) []string {
+	t.Helper()
+	sessions := make([]string, n)
+	for i := range sessions {
+		id, _, err := auth.createSession(context.Background(), userID, uuid.New(), "ana")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[i] = id
+	}
+	return sessions
+}
+
+func markRefreshTokenRotated(t *testing.T, auth *AuthService, token string, userID uuid.UUID, sessionID string, rotatedAt time.Time) {
+	t.Helper()
+	raw, _ := json.Marshal(usedRefreshTokenData{UserID: userID.String(), SessionID: sessionID, RotatedAt: rotatedAt.Unix()})
+	if err := auth.cache.Set(context.Background(), usedRefreshKeyPrefix+token, raw, refreshTokenTTL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sessionAlive(auth *AuthService, sessionID string) bool {
+	_, err := auth.TouchSession(context.Background(), sessionID)
+	return err == nil
+}
+
+func TestReusedRefreshTokenRevokesEverySession(t *testing.T) {
+	ctx := context.Background()
+	auth := newRedisAuthService(t)
+	userID, otherUserID := uuid.New(), uuid.New()
+	sessions := createTestSessions(t, auth, userID, 3)
+	otherSessions := createTestSessions(t, auth, otherUserID, 1)
+	markRefreshTokenRotated(t, auth, "stolen", userID, sessions[0], time.Now().Add(-time.Minute))
+
+	if !auth.revokeReusedRefreshToken(ctx, "stolen") {
+		t.Fatal("reuse after the grace window was not detected")
+	}
+	for _, id := range sessions {
+		if sessionAlive(auth, id) {
+			t.Fatalf("session %s survived the reuse", id)
+		}
+	}
+	if members, _ := auth.cache.SMembers(ctx, userSessionsKeyPrefix+userID.String()); len(members) != 0 {
+		t.Fatalf("session index still lists %v", members)
+	}
+	if !sessionAlive(auth, otherSessions[0]) {
+		t.Fatal("another user's session was revoked")
+	}
+}
+
+func TestRefreshTokenRotationDoesNotRevoke(t *testing.T) {
+	ctx := context.Background()
+	auth := newRedisAuthService(t)
+	userID := uuid.New()
+	sessions := createTestSessions(t, auth, userID, 2)
+
+	// A token never rotated is just invalid.
+	if auth.revokeReusedRefreshToken(ctx, "unknown") {
+		t.Fatal("an unknown token was treated as reused")
+	}
+	// A tab refreshing concurrently replays the token within the grace window.
+	markRefreshTokenRotated(t, auth, "rotated", userID, sessions[0], time.Now())
+	if auth.revokeReusedRefreshToken(ctx, "rotated") {
+		t.Fatal("a replay within the grace window was treated as reuse")
+	}
+	for _, id := range sessions {
+		if !sessionAlive(auth, id) {
+			t.Fatalf("session %s was revoked by a normal rotation", id)
+		}
+	}
+}
+
+func TestRevokeOtherSessionsKeepsCurrent(t *testing.T) {
+	ctx := context.Background()
+	auth := newRedisAuthService(t)
+	userID := uuid.New()
+	sessions := createTestSessions(t, auth, userID, 3)
+	current := sessions[1]
+
+	if revoked := auth.RevokeOtherSessions(ctx, userID, current); revoked != 2 {
+		t.Fatalf("revoked = %d, want 2", revoked)
+	}
+	if !sessionAlive(auth, current) {
+		t.Fatal("the current session was revoked")
+	}
+	for _, id := range []string{sessions[0], sessions[2]} {
+		if sessionAlive(auth, id) {
+			t.Fatalf("session %s survived", id)
+		}
+	}
+	members, err := auth.cache.SMembers(ctx, userSessionsKeyPrefix+userID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != current {
+		t.Fatalf("session index = %v, want only %s", members, current)
+	}
+	if revoked := auth.RevokeOtherSessions(ctx, userID, current); revoked != 0 {
+		t.Fatalf("second revoke removed %d sessions", revoked)
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
+)
+
+// autoTagRulesetTTL bounds how stale a cached ruleset can get on replicas
+// that did not see the edit. Local edits invalidate immediately.
+const autoTagRulesetTTL = 2 * time.Minute
+
+const maxAutoTagPatternLength = 500
+
+// AutoTagService evaluates keyword auto-tag rules against inbound messages.
+// Compiled rulesets are cached in memory per account because the inbound
+// path runs for every message and regexes should not be recompiled each time.
+type AutoTagService struct {
+	repos *repository.Repositories
+
+	mu       sync.RWMutex
+	rulesets map[uuid.UUID]*autoTagRuleset
+}
+
+type autoTagRuleset struct {
+	rules    []compiledAutoTagRule
+	loadedAt time.Time
+}
+
+type compiledAutoTagRule struct {
+	tagID  uuid.UUID
+	needle string
+	re     *regexp.Regexp
+}
+
+func NewAutoTagService(repos *repository.Repositories) *AutoTagService {
+	return &AutoTagService{repos: repos, rulesets: make(map[uuid.UUID]*autoTagRuleset)}
+}
+
+// ValidateAutoTagPattern checks a rule before it is stored so a bad regex is
+// reported to the user instead of being skipped silently at match time.
+func ValidateAutoTagPattern(matchMode, pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if len(pattern) > maxAutoTagPatternLength {
+		return fmt.Errorf("pattern must be at most %d characters", maxAutoTagPatternLength)
+	}
+	switch matchMode {
+	case domain.AutoTagMatchContains:
+		return nil
+	case domain.AutoTagMatchRegex:
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("match_mode must be %q or %q", domain.AutoTagMatchContains, domain.AutoTagMatchRegex)
+	}
+}
+
+func compileAutoTagRules(rules []*domain.AutoTagRule) []compiledAutoTagRule {
+	compiled := make([]compiledAutoTagRule, 0, len(rules))
+	for _, rule := range rules {
+		entry := compiledAutoTagRule{tagID: rule.TagID}
+		switch rule.MatchMode {
+		case domain.AutoTagMatchRegex:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				continue
+			}
+			entry.re = re
+		default:
+			entry.needle = strings.ToLower(strings.TrimSpace(rule.Pattern))
+			if entry.needle == "" {
+				continue
+			}
+		}
+		compiled = append(compiled, entry)
+	}
+	return compiled
+}
+
+// matchAutoTagRules returns the distinct tags whose rules match text, in rule
+// order.
+func matchAutoTagRules(rules []compiledAutoTagRule, text string) []uuid.UUID {
+	lower := strings.ToLower(text)
+	seen := make(map[uuid.UUID]struct{}, len(rules))
+	matched := make([]uuid.UUID, 0)
+	for _, rule := range rules {
+		if _, done := seen[rule.tagID]; done {
+			continue
+		}
+		var hit bool
+		if rule.re != nil {
+			hit = rule.re.MatchString(text)
+		} else {
+			hit = strings.Contains(lower, rule.needle)
+		}
+		if hit {
+			seen[rule.tagID] = struct{}{}
+			matched = append(matched, rule.tagID)
+		}
+	}
+	return matched
+}
+
+func (s *AutoTagService) ruleset(ctx context.Context, accountID uuid.UUID) ([]compiledAutoTagRule, error) {
+	s.mu.RLock()
+	cached := s.rulesets[accountID]
+	s.mu.RUnlock()
+	if cached != nil && time.Since(cached.loadedAt) < autoTagRulesetTTL {
+		return cached.rules, nil
+	}
+	rules, err := s.repos.AutoTagRule.ListActive(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	compiled := compileAutoTagRules(rules)
+	s.mu.Lock()
+	s.rulesets[accountID] = &autoTagRuleset{rules: compiled, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return compiled, nil
+}
+
+// Invalidate drops the cached ruleset after a rule or tag change.
+func (s *AutoTagService) Invalidate(accountID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.rulesets, accountID)
+	s.mu.Unlock()
+}
+
+// Apply tags the contact behind chatID with every rule matching text. It
+// returns the contact and the tags that were newly added; both are empty when
+// nothing matched or the contact already carried every matched tag.
+func (s *AutoTagService) Apply(ctx context.Context, accountID, chatID uuid.UUID, text string) (*uuid.UUID, []uuid.UUID, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil, nil
+	}
+	rules, err := s.ruleset(ctx, accountID)
+	if err != nil || len(rules) == 0 {
+		return nil, nil, err
+	}
+	matched := matchAutoTagRules(rules, text)
+	if len(matched) == 0 {
+		return nil, nil, nil
+	}
+	return s.repos.Tag.AssignToChatContactForAccount(ctx, accountID, chatID, matched)
+}
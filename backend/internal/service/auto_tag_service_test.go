@@ -0,0 +1,49 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestMatchAutoTagRules(t *testing.T) {
+	complaint, pricing, urgent := uuid.New(), uuid.New(), uuid.New()
+	rules := compileAutoTagRules([]*domain.AutoTagRule{
+		{TagID: complaint, MatchMode: domain.AutoTagMatchContains, Pattern: "Reembolso"},
+		{TagID: complaint, MatchMode: domain.AutoTagMatchContains, Pattern: "refund"},
+		{TagID: pricing, MatchMode: domain.AutoTagMatchRegex, Pattern: `(?i)\bprecios?\b`},
+		{TagID: urgent, MatchMode: domain.AutoTagMatchRegex, Pattern: `[`}, // invalid, skipped
+		{TagID: urgent, MatchMode: domain.AutoTagMatchContains, Pattern: "   "},
+	})
+	if len(rules) != 3 {
+		t.Fatalf("compiled %d rules, want 3", len(rules))
+	}
+
+	got := matchAutoTagRules(rules, "Quiero un REEMBOLSO y saber el Precio")
+	if want := []uuid.UUID{complaint, pricing}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("matched %v, want %v", got, want)
+	}
+	if got := matchAutoTagRules(rules, "refund please, reembolso"); len(got) != 1 || got[0] != complaint {
+		t.Fatalf("duplicate tag should be reported once, got %v", got)
+	}
+	if got := matchAutoTagRules(rules, "preciosa foto"); len(got) != 0 {
+		t.Fatalf("word-bounded regex matched inside a word: %v", got)
+	}
+}
+
+func TestValidateAutoTagPattern(t *testing.T) {
+	if err := ValidateAutoTagPattern(domain.AutoTagMatchContains, "reembolso"); err != nil {
+		t.Fatalf("contains rule rejected: %v", err)
+	}
+	if err := ValidateAutoTagPattern(domain.AutoTagMatchRegex, `(`); err == nil {
+		t.Fatal("invalid regex accepted")
+	}
+	if err := ValidateAutoTagPattern("fuzzy", "x"); err == nil {
+		t.Fatal("unknown match mode accepted")
+	}
+	if err := ValidateAutoTagPattern(domain.AutoTagMatchContains, " "); err == nil {
+		t.Fatal("blank pattern accepted")
+	}
+}
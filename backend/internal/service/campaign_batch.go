@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// campaignBatchProgressKey is the campaign settings key holding the batch
+// position saved by Pause.
+const campaignBatchProgressKey = "batch_progress"
+
+// CampaignBatchProgress is the worker's position in the current batch.
+// PauseUntil is set while the worker sleeps between batches.
+type CampaignBatchProgress struct {
+	SentInBatch int
+	LastSendAt  time.Time
+	PauseUntil  time.Time
+}
+
+// CampaignResumeState is what a resumed worker must honor before sending
+// again: the remaining inter-batch sleep, or the partially used batch.
+type CampaignResumeState struct {
+	SentInBatch    int
+	LastSendAt     time.Time
+	PauseRemaining time.Duration
+}
+
+// RecordBatchProgress is called by the campaign worker after every send and
+// when it starts or finishes an inter-batch sleep.
+func (s *CampaignService) RecordBatchProgress(campaignID uuid.UUID, progress CampaignBatchProgress) {
+	s.batchProgress.Store(campaignID, progress)
+}
+
+// ForgetBatchProgress drops the in-memory position once a worker exits.
+func (s *CampaignService) ForgetBatchProgress(campaignID uuid.UUID) {
+	s.batchProgress.Delete(campaignID)
+}
+
+// batchProgressSettings converts the live worker position into the value
+// stored in campaign settings. Nil means there is nothing worth resuming.
+func batchProgressSettings(progress CampaignBatchProgress, now time.Time) map[string]interface{} {
+	value := map[string]interface{}{}
+	if remaining := progress.PauseUntil.Sub(now); !progress.PauseUntil.IsZero() && remaining > 0 {
+		value["pause_remaining_ms"] = float64(remaining.Milliseconds())
+	} else if progress.SentInBatch > 0 {
+		value["sent_in_batch"] = float64(progress.SentInBatch)
+	} else {
+		return nil
+	}
+	if !progress.LastSendAt.IsZero() {
+		value["last_send_at"] = progress.LastSendAt.UTC().Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+// parseCampaignResumeState reads the value written by batchProgressSettings.
+// Settings round-trip through JSONB, so numbers arrive as float64.
+func parseCampaignResumeState(raw interface{}) *CampaignResumeState {
+	value, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	state := &CampaignResumeState{}
+	if v, ok := value["sent_in_batch"].(float64); ok && v > 0 {
+		state.SentInBatch = int(v)
+	}
+	if v, ok := value["pause_remaining_ms"].(float64); ok && v > 0 {
+		state.PauseRemaining = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := value["last_send_at"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			state.LastSendAt = t
+		}
+	}
+	if state.SentInBatch == 0 && state.PauseRemaining == 0 {
+		return nil
+	}
+	return state
+}
+
+// TakeResumeState returns the batch position saved when the campaign was
+// paused and removes it from settings so it is applied only once.
+func (s *CampaignService) TakeResumeState(ctx context.Context, campaign *domain.Campaign) (*CampaignResumeState, error) {
+	raw, ok := campaign.Settings[campaignBatchProgressKey]
+	if !ok {
+		return nil, nil
+	}
+	fresh, err := s.repos.Campaign.GetByID(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	delete(fresh.Settings, campaignBatchProgressKey)
+	if err := s.repos.Campaign.Update(ctx, fresh); err != nil {
+		return nil, err
+	}
+	delete(campaign.Settings, campaignBatchProgressKey)
+	return parseCampaignResumeState(raw), nil
+}
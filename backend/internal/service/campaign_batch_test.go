@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// roundTripSettings mimics storing campaign settings in JSONB and reading
+// them back.
+func roundTripSettings(t *testing.T, value map[string]interface{}) interface{} {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{campaignBatchProgressKey: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		t.Fatal(err)
+	}
+	return settings[campaignBatchProgressKey]
+}
+
+func TestBatchProgressMidBatchRoundTrip(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	lastSend := now.Add(-5 * time.Second)
+	value := batchProgressSettings(CampaignBatchProgress{SentInBatch: 7, LastSendAt: lastSend}, now)
+
+	state := parseCampaignResumeState(roundTripSettings(t, value))
+	if state == nil || state.SentInBatch != 7 || state.PauseRemaining != 0 || !state.LastSendAt.Equal(lastSend) {
+		t.Fatalf("unexpected resume state: %+v", state)
+	}
+}
+
+func TestBatchProgressDuringInterBatchSleep(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	value := batchProgressSettings(CampaignBatchProgress{SentInBatch: 25, LastSendAt: now.Add(-time.Minute), PauseUntil: now.Add(90 * time.Second)}, now)
+
+	state := parseCampaignResumeState(roundTripSettings(t, value))
+	if state == nil || state.PauseRemaining != 90*time.Second || state.SentInBatch != 0 {
+		t.Fatalf("expected to resume into the remaining sleep, got %+v", state)
+	}
+}
+
+func TestBatchProgressNothingToResume(t *testing.T) {
+	now := time.Now()
+	if value := batchProgressSettings(CampaignBatchProgress{}, now); value != nil {
+		t.Fatalf("fresh batch should not be persisted, got %v", value)
+	}
+	if value := batchProgressSettings(CampaignBatchProgress{PauseUntil: now.Add(-time.Second)}, now); value != nil {
+		t.Fatalf("elapsed sleep should not be persisted, got %v", value)
+	}
+	if state := parseCampaignResumeState("garbage"); state != nil {
+		t.Fatalf("unexpected state from malformed settings: %+v", state)
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/kommo"
+)
+
+// CampaignRecipientAddResult reports how a batch of recipients was applied to
+// a campaign.
+type CampaignRecipientAddResult struct {
+	Added             int `json:"added"`
+	DuplicatesSkipped int `json:"duplicates_skipped"`
+}
+
+// campaignRecipientKey is the normalized phone a recipient will be messaged
+// at: the user part of its JID, or its phone when the JID is empty.
+func campaignRecipientKey(jid string, phone *string) string {
+	raw := jid
+	if at := strings.IndexByte(raw, '@'); at >= 0 {
+		raw = raw[:at]
+	}
+	if colon := strings.IndexByte(raw, ':'); colon >= 0 {
+		raw = raw[:colon]
+	}
+	if raw == "" && phone != nil {
+		raw = *phone
+	}
+	return kommo.NormalizePhone(raw)
+}
+
+// dedupeCampaignRecipients drops recipients whose normalized phone is already
+// in existing or appears earlier in the same batch. It returns the recipients
+// to insert and how many were skipped.
+func dedupeCampaignRecipients(recipients []*domain.CampaignRecipient, existing map[string]bool) ([]*domain.CampaignRecipient, int) {
+	seen := make(map[string]bool, len(existing)+len(recipients))
+	for key := range existing {
+		seen[key] = true
+	}
+	unique := make([]*domain.CampaignRecipient, 0, len(recipients))
+	skipped := 0
+	for _, rec := range recipients {
+		key := campaignRecipientKey(rec.JID, rec.Phone)
+		if key != "" {
+			if seen[key] {
+				skipped++
+				continue
+			}
+			seen[key] = true
+		}
+		unique = append(unique, rec)
+	}
+	return unique, skipped
+}
+
+// AddRecipients adds recipients to a campaign, collapsing numbers repeated in
+// the batch and skipping numbers the campaign already has so no contact gets
+// the same blast twice.
+func (s *CampaignService) AddRecipients(ctx context.Context, recipients []*domain.CampaignRecipient) (CampaignRecipientAddResult, error) {
+	var result CampaignRecipientAddResult
+	if len(recipients) == 0 {
+		return result, nil
+	}
+	current, err := s.repos.Campaign.GetRecipientAddresses(ctx, recipients[0].CampaignID)
+	if err != nil {
+		return result, err
+	}
+	existing := make(map[string]bool, len(current))
+	for _, rec := range current {
+		if key := campaignRecipientKey(rec.JID, rec.Phone); key != "" {
+			existing[key] = true
+		}
+	}
+	unique, skipped := dedupeCampaignRecipients(recipients, existing)
+	added, err := s.repos.Campaign.AddRecipients(ctx, unique)
+	if err != nil {
+		return result, err
+	}
+	result.Added = added
+	// Rows the insert ignored were already present under the same contact.
+	result.DuplicatesSkipped = skipped + len(unique) - added
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestCampaignRecipientKey(t *testing.T) {
+	phone := "+51 999-888-777"
+	cases := []struct {
+		jid   string
+		phone *string
+		want  string
+	}{
+		{"51999888777@s.whatsapp.net", nil, "51999888777"},
+		{"51999888777:12@s.whatsapp.net", nil, "51999888777"},
+		{"999888777@s.whatsapp.net", nil, "51999888777"},
+		{"", &phone, "51999888777"},
+		{"", nil, ""},
+	}
+	for _, tc := range cases {
+		if got := campaignRecipientKey(tc.jid, tc.phone); got != tc.want {
+			t.Errorf("campaignRecipientKey(%q) = %q, want %q", tc.jid, got, tc.want)
+		}
+	}
+}
+
+func TestDedupeCampaignRecipients(t *testing.T) {
+	recipients := []*domain.CampaignRecipient{
+		{JID: "51999888777@s.whatsapp.net"},
+		{JID: "999888777@s.whatsapp.net"},
+		{JID: "51911111111@s.whatsapp.net"},
+		{JID: "51922222222@s.whatsapp.net"},
+		{JID: ""},
+		{JID: ""},
+	}
+	existing := map[string]bool{"51911111111": true}
+
+	unique, skipped := dedupeCampaignRecipients(recipients, existing)
+	if skipped != 2 {
+		t.Fatalf("expected 2 duplicates skipped, got %d", skipped)
+	}
+	if len(unique) != 4 || unique[0] != recipients[0] || unique[1] != recipients[3] {
+		t.Fatalf("unexpected recipients kept: %+v", unique)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// GroupByDevice buckets campaigns by the device that sends them, oldest
+// campaign first within each device so turn order is deterministic.
+func (s *CampaignService) GroupByDevice(campaigns []*domain.Campaign) map[uuid.UUID][]*domain.Campaign {
+	return groupCampaignsByDevice(campaigns)
+}
+
+func groupCampaignsByDevice(campaigns []*domain.Campaign) map[uuid.UUID][]*domain.Campaign {
+	groups := make(map[uuid.UUID][]*domain.Campaign)
+	for _, c := range campaigns {
+		groups[c.DeviceID] = append(groups[c.DeviceID], c)
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			if !group[i].CreatedAt.Equal(group[j].CreatedAt) {
+				return group[i].CreatedAt.Before(group[j].CreatedAt)
+			}
+			return group[i].ID.String() < group[j].ID.String()
+		})
+	}
+	return groups
+}
+
+// campaignDeviceTurns serializes campaign batches per device. Each device has
+// at most one campaign sending at a time; waiters are served in FIFO order, so
+// a campaign that finishes a batch and asks again goes behind the others.
+type campaignDeviceTurns struct {
+	mu      sync.Mutex
+	holders map[uuid.UUID]uuid.UUID
+	queues  map[uuid.UUID][]*campaignDeviceWaiter
+}
+
+type campaignDeviceWaiter struct {
+	campaignID uuid.UUID
+	ready      chan struct{}
+}
+
+func (t *campaignDeviceTurns) acquire(ctx context.Context, deviceID, campaignID uuid.UUID) error {
+	t.mu.Lock()
+	if t.holders == nil {
+		t.holders = make(map[uuid.UUID]uuid.UUID)
+		t.queues = make(map[uuid.UUID][]*campaignDeviceWaiter)
+	}
+	if holder, busy := t.holders[deviceID]; !busy || holder == campaignID {
+		t.holders[deviceID] = campaignID
+		t.mu.Unlock()
+		return nil
+	}
+	w := &campaignDeviceWaiter{campaignID: campaignID, ready: make(chan struct{})}
+	t.queues[deviceID] = append(t.queues[deviceID], w)
+	t.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		queue := t.queues[deviceID]
+		for i, q := range queue {
+			if q == w {
+				t.queues[deviceID] = append(queue[:i], queue[i+1:]...)
+				t.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		t.mu.Unlock()
+		// The turn was handed over while cancelling; pass it on.
+		t.release(deviceID, campaignID)
+		return ctx.Err()
+	}
+}
+
+func (t *campaignDeviceTurns) release(deviceID, campaignID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.holders[deviceID] != campaignID {
+		return
+	}
+	queue := t.queues[deviceID]
+	if len(queue) == 0 {
+		delete(t.holders, deviceID)
+		delete(t.queues, deviceID)
+		return
+	}
+	next := queue[0]
+	t.queues[deviceID] = queue[1:]
+	t.holders[deviceID] = next.campaignID
+	close(next.ready)
+}
+
+// AcquireDeviceTurn blocks until the campaign may send a batch on deviceID.
+// Campaigns on other devices are unaffected.
+func (s *CampaignService) AcquireDeviceTurn(ctx context.Context, deviceID, campaignID uuid.UUID) error {
+	return s.deviceTurns.acquire(ctx, deviceID, campaignID)
+}
+
+// ReleaseDeviceTurn hands the device to the next waiting campaign.
+func (s *CampaignService) ReleaseDeviceTurn(deviceID, campaignID uuid.UUID) {
+	s.deviceTurns.release(deviceID, campaignID)
+}
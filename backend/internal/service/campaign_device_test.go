@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestGroupCampaignsByDeviceOrdersOldestFirst(t *testing.T) {
+	deviceA, deviceB := uuid.New(), uuid.New()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := &domain.Campaign{ID: uuid.New(), DeviceID: deviceA, CreatedAt: t0.Add(time.Hour)}
+	older := &domain.Campaign{ID: uuid.New(), DeviceID: deviceA, CreatedAt: t0}
+	other := &domain.Campaign{ID: uuid.New(), DeviceID: deviceB, CreatedAt: t0}
+
+	groups := groupCampaignsByDevice([]*domain.Campaign{newer, other, older})
+	if len(groups) != 2 || len(groups[deviceB]) != 1 {
+		t.Fatalf("unexpected grouping: %v", groups)
+	}
+	if a := groups[deviceA]; len(a) != 2 || a[0] != older || a[1] != newer {
+		t.Fatalf("device A campaigns not ordered oldest first")
+	}
+}
+
+func TestDeviceTurnsSerializeAndRotate(t *testing.T) {
+	var turns campaignDeviceTurns
+	ctx := context.Background()
+	device, otherDevice := uuid.New(), uuid.New()
+	first, second := uuid.New(), uuid.New()
+
+	if err := turns.acquire(ctx, device, first); err != nil {
+		t.Fatal(err)
+	}
+	// A different device is never blocked.
+	if err := turns.acquire(ctx, otherDevice, second); err != nil {
+		t.Fatal(err)
+	}
+	turns.release(otherDevice, second)
+
+	granted := make(chan struct{})
+	go func() {
+		_ = turns.acquire(ctx, device, second)
+		close(granted)
+	}()
+	select {
+	case <-granted:
+		t.Fatal("second campaign sent while the device was busy")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	turns.release(device, first)
+	select {
+	case <-granted:
+	case <-time.After(time.Second):
+		t.Fatal("turn was not handed to the waiting campaign")
+	}
+
+	// The first campaign asking again must queue behind the current holder.
+	again := make(chan struct{})
+	go func() {
+		_ = turns.acquire(ctx, device, first)
+		close(again)
+	}()
+	select {
+	case <-again:
+		t.Fatal("campaign jumped the queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	turns.release(device, second)
+	select {
+	case <-again:
+	case <-time.After(time.Second):
+		t.Fatal("rotation did not return to the first campaign")
+	}
+}
+
+func TestDeviceTurnsCancelledWaiterLeavesQueue(t *testing.T) {
+	var turns campaignDeviceTurns
+	device, holder, waiter := uuid.New(), uuid.New(), uuid.New()
+	if err := turns.acquire(context.Background(), device, holder); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- turns.acquire(ctx, device, waiter) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatal("cancelled acquire returned nil")
+	}
+	turns.release(device, holder)
+	if err := turns.acquire(context.Background(), device, uuid.New()); err != nil {
+		t.Fatal("device stayed reserved for a cancelled waiter")
+	}
+}
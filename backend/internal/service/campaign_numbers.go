@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+const campaignInvalidNumberMessage = "Número no registrado en WhatsApp"
+
+// CampaignNumberValidation summarises a pre-start check of recipient numbers.
+type CampaignNumberValidation struct {
+	Checked    int `json:"checked"`
+	Registered int `json:"registered"`
+	Invalid    int `json:"invalid"`
+}
+
+// NumberChecker reports which JIDs have a WhatsApp account on a device.
+type NumberChecker func(ctx context.Context, deviceID uuid.UUID, jids []string) (map[string]bool, error)
+
+// ValidateRecipientNumbers checks every pending recipient against WhatsApp
+// and marks the unregistered ones invalid so they never use a send slot.
+func (s *CampaignService) ValidateRecipientNumbers(ctx context.Context, campaign *domain.Campaign, check NumberChecker) (CampaignNumberValidation, error) {
+	var summary CampaignNumberValidation
+	recipients, err := s.repos.Campaign.GetRecipients(ctx, campaign.ID)
+	if err != nil {
+		return summary, err
+	}
+	var jids []string
+	for _, rec := range recipients {
+		if rec.Status == "pending" && rec.JID != "" {
+			jids = append(jids, rec.JID)
+		}
+	}
+	if len(jids) == 0 {
+		return summary, nil
+	}
+	registered, err := check(ctx, campaign.DeviceID, jids)
+	if err != nil {
+		return summary, err
+	}
+	var invalid []uuid.UUID
+	for _, rec := range recipients {
+		if rec.Status != "pending" || rec.JID == "" {
+			continue
+		}
+		summary.Checked++
+		if registered[rec.JID] {
+			summary.Registered++
+		} else {
+			invalid = append(invalid, rec.ID)
+		}
+	}
+	summary.Invalid, err = s.repos.Campaign.MarkRecipientsInvalid(ctx, campaign.ID, invalid, campaignInvalidNumberMessage)
+	return summary, err
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// CampaignPacing is how fast the worker sends one campaign.
+type CampaignPacing struct {
+	MinDelaySeconds   int
+	MaxDelaySeconds   int
+	BatchSize         int
+	BatchPauseMinutes int
+	// QuietHours is the account's no-send window, nil when off.
+	QuietHours *CampaignQuietHours
+}
+
+// defaultCampaignPacing applies when the campaign settings leave a value out.
+var defaultCampaignPacing = CampaignPacing{MinDelaySeconds: 8, MaxDelaySeconds: 15, BatchSize: 25, BatchPauseMinutes: 2}
+
+// ResolveCampaignPacing reads the pacing from the campaign settings and then
+// clamps it to the account limits, so a campaign can be slower than its
+// account allows but never faster.
+func ResolveCampaignPacing(settings map[string]interface{}, limits domain.CampaignRateLimits) CampaignPacing {
+	readInt := func(keys []string, def int) int {
+		for _, key := range keys {
+			if v, ok := settings[key]; ok {
+				if f, ok := v.(float64); ok {
+					return int(f)
+				}
+			}
+		}
+		return def
+	}
+
+	pacing := CampaignPacing{
+		MinDelaySeconds:   readInt([]string{"min_delay_seconds", "min_delay"}, defaultCampaignPacing.MinDelaySeconds),
+		MaxDelaySeconds:   readInt([]string{"max_delay_seconds", "max_delay"}, defaultCampaignPacing.MaxDelaySeconds),
+		BatchSize:         readInt([]string{"batch_size"}, defaultCampaignPacing.BatchSize),
+		BatchPauseMinutes: readInt([]string{"batch_pause_minutes", "batch_pause"}, defaultCampaignPacing.BatchPauseMinutes),
+	}
+	if pacing.MinDelaySeconds > pacing.MaxDelaySeconds {
+		pacing.MinDelaySeconds = pacing.MaxDelaySeconds
+	}
+
+	if limits.MinDelaySeconds != nil && pacing.MinDelaySeconds < *limits.MinDelaySeconds {
+		pacing.MinDelaySeconds = *limits.MinDelaySeconds
+		if pacing.MaxDelaySeconds < pacing.MinDelaySeconds {
+			pacing.MaxDelaySeconds = pacing.MinDelaySeconds
+		}
+	}
+	if limits.MaxBatchSize != nil && pacing.BatchSize > *limits.MaxBatchSize {
+		pacing.BatchSize = *limits.MaxBatchSize
+	}
+	if limits.MinBatchPauseMinutes != nil && pacing.BatchPauseMinutes < *limits.MinBatchPauseMinutes {
+		pacing.BatchPauseMinutes = *limits.MinBatchPauseMinutes
+	}
+	return pacing
+}
+
+// NormalizeCampaignRateLimits rejects negative limits and turns 0 into "no
+// limit".
+func NormalizeCampaignRateLimits(limits domain.CampaignRateLimits) (domain.CampaignRateLimits, error) {
+	normalize := func(name string, value *int) (*int, error) {
+		if value == nil || *value == 0 {
+			return nil, nil
+		}
+		if *value < 0 {
+			return nil, fmt.Errorf("%s must be 0 or greater", name)
+		}
+		v := *value
+		return &v, nil
+	}
+	var err error
+	var out domain.CampaignRateLimits
+	if out.MinDelaySeconds, err = normalize("min_delay_seconds", limits.MinDelaySeconds); err != nil {
+		return out, err
+	}
+	if out.MaxBatchSize, err = normalize("max_batch_size", limits.MaxBatchSize); err != nil {
+		return out, err
+	}
+	if out.MinBatchPauseMinutes, err = normalize("min_batch_pause_minutes", limits.MinBatchPauseMinutes); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// BatchPacing resolves the pacing of the campaign's next batch. The account's
+// rate limits and quiet hours are read on every call, so an edit applies from
+// the next batch. An error means the limits are unknown and the batch must not
+// be sent.
+func (s *CampaignService) BatchPacing(ctx context.Context, campaign *domain.Campaign) (CampaignPacing, error) {
+	account, err := s.repos.Account.GetByID(ctx, campaign.AccountID)
+	if err != nil {
+		return CampaignPacing{}, fmt.Errorf("load account rate limits: %w", err)
+	}
+	return accountCampaignPacing(campaign.Settings, account), nil
+}
+
+// accountCampaignPacing resolves the campaign settings under the account's
+// limits and quiet hours.
+func accountCampaignPacing(settings map[string]interface{}, account *domain.Account) CampaignPacing {
+	pacing := ResolveCampaignPacing(settings, account.CampaignRateLimits)
+	pacing.QuietHours = accountQuietHours(account)
+	return pacing
+}
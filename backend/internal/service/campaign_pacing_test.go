@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestResolveCampaignPacingDefaults(t *testing.T) {
+	got := ResolveCampaignPacing(nil, domain.CampaignRateLimits{})
+	if got != defaultCampaignPacing {
+		t.Fatalf("expected defaults, got %+v", got)
+	}
+}
+
+func TestResolveCampaignPacingClampsToAccountLimits(t *testing.T) {
+	minDelay, maxBatch, minPause := 20, 10, 5
+	limits := domain.CampaignRateLimits{MinDelaySeconds: &minDelay, MaxBatchSize: &maxBatch, MinBatchPauseMinutes: &minPause}
+	settings := map[string]interface{}{"min_delay_seconds": float64(3), "max_delay_seconds": float64(6), "batch_size": float64(100), "batch_pause": float64(0)}
+
+	got := ResolveCampaignPacing(settings, limits)
+	want := CampaignPacing{MinDelaySeconds: 20, MaxDelaySeconds: 20, BatchSize: 10, BatchPauseMinutes: 5}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	// Campaigns already slower than the account limits are left alone.
+	settings = map[string]interface{}{"min_delay": float64(30), "max_delay": float64(60), "batch_size": float64(5), "batch_pause_minutes": float64(10)}
+	got = ResolveCampaignPacing(settings, limits)
+	want = CampaignPacing{MinDelaySeconds: 30, MaxDelaySeconds: 60, BatchSize: 5, BatchPauseMinutes: 10}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestNormalizeCampaignRateLimits(t *testing.T) {
+	zero, negative, ten := 0, -1, 10
+	got, err := NormalizeCampaignRateLimits(domain.CampaignRateLimits{MinDelaySeconds: &zero, MaxBatchSize: &ten})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MinDelaySeconds != nil || got.MaxBatchSize == nil || *got.MaxBatchSize != 10 || got.MinBatchPauseMinutes != nil {
+		t.Fatalf("unexpected limits %+v", got)
+	}
+	if _, err := NormalizeCampaignRateLimits(domain.CampaignRateLimits{MinBatchPauseMinutes: &negative}); err == nil {
+		t.Fatal("expected negative limit to be rejected")
+	}
+}
+
+func TestAccountCampaignPacingUsesCurrentLimits(t *testing.T) {
+	settings := map[string]interface{}{"min_delay_seconds": float64(3), "max_delay_seconds": float64(6), "batch_size": float64(100)}
+	account := &domain.Account{}
+	if got := accountCampaignPacing(settings, account); got.BatchSize != 100 || got.MinDelaySeconds != 3 {
+		t.Fatalf("unlimited account: %+v", got)
+	}
+
+	// Limits set while the campaign runs apply to the next batch.
+	minDelay, maxBatch := 20, 10
+	account.CampaignRateLimits = domain.CampaignRateLimits{MinDelaySeconds: &minDelay, MaxBatchSize: &maxBatch}
+	if got := accountCampaignPacing(settings, account); got.BatchSize != 10 || got.MinDelaySeconds != 20 {
+		t.Fatalf("limited account: %+v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// DefaultAccountTimezone is used when an account has no valid timezone.
+const DefaultAccountTimezone = "America/Lima"
+
+// CampaignQuietHours is a daily window, in the account's timezone, during
+// which campaigns do not send. The window may wrap past midnight.
+type CampaignQuietHours struct {
+	start    int // minutes after midnight
+	end      int
+	location *time.Location
+}
+
+// parseClock reads "HH:MM" into minutes after midnight.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("hora inválida %q, usa HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseCampaignQuietHours builds the quiet window from account settings. It
+// returns nil when quiet hours are off.
+func ParseCampaignQuietHours(start, end *string, timezone string) (*CampaignQuietHours, error) {
+	hasStart := start != nil && strings.TrimSpace(*start) != ""
+	hasEnd := end != nil && strings.TrimSpace(*end) != ""
+	if !hasStart && !hasEnd {
+		return nil, nil
+	}
+	if !hasStart || !hasEnd {
+		return nil, fmt.Errorf("quiet_hours_start y quiet_hours_end deben indicarse juntos")
+	}
+	from, err := parseClock(*start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseClock(*end)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, fmt.Errorf("el horario de silencio no puede empezar y terminar a la misma hora")
+	}
+	location, err := loadAccountLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	return &CampaignQuietHours{start: from, end: to, location: location}, nil
+}
+
+func loadAccountLocation(timezone string) (*time.Location, error) {
+	if strings.TrimSpace(timezone) == "" {
+		timezone = DefaultAccountTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("zona horaria inválida: %s", timezone)
+	}
+	return location, nil
+}
+
+// ResumeAt reports whether now falls inside the quiet window and, if so,
+// when sending may start again.
+func (q *CampaignQuietHours) ResumeAt(now time.Time) (time.Time, bool) {
+	if q == nil {
+		return time.Time{}, false
+	}
+	local := now.In(q.location)
+	minute := local.Hour()*60 + local.Minute()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, q.location)
+	atEnd := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), q.end/60, q.end%60, 0, 0, q.location)
+	}
+	if q.start < q.end {
+		if minute >= q.start && minute < q.end {
+			return atEnd(midnight), true
+		}
+		return time.Time{}, false
+	}
+	// Wraps past midnight, e.g. 21:00–08:00.
+	if minute >= q.start {
+		return atEnd(midnight.AddDate(0, 0, 1)), true
+	}
+	if minute < q.end {
+		return atEnd(midnight), true
+	}
+	return time.Time{}, false
+}
+
+// ValidateAccountQuietHours checks quiet hours and timezone settings before
+// they are stored.
+func ValidateAccountQuietHours(start, end *string, timezone string) error {
+	if _, err := loadAccountLocation(timezone); err != nil {
+		return err
+	}
+	_, err := ParseCampaignQuietHours(start, end, timezone)
+	return err
+}
+
+// accountQuietHours returns the account's quiet window, or nil when it is
+// off or misconfigured.
+func accountQuietHours(account *domain.Account) *CampaignQuietHours {
+	if account == nil {
+		return nil
+	}
+	quiet, err := ParseCampaignQuietHours(account.QuietHoursStart, account.QuietHoursEnd, account.Timezone)
+	if err != nil {
+		return nil
+	}
+	return quiet
+}
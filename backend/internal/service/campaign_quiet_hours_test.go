@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestCampaignQuietHoursWrapsMidnight(t *testing.T) {
+	start, end := "21:00", "08:00"
+	quiet, err := ParseCampaignQuietHours(&start, &end, "America/Lima")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lima, _ := time.LoadLocation("America/Lima")
+
+	// 03:00 in Lima is 08:00 UTC; the server clock must not matter.
+	now := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	resumeAt, ok := quiet.ResumeAt(now)
+	if !ok {
+		t.Fatal("03:00 local should be inside quiet hours")
+	}
+	if want := time.Date(2026, 3, 10, 8, 0, 0, 0, lima); !resumeAt.Equal(want) {
+		t.Fatalf("expected resume at %v, got %v", want, resumeAt)
+	}
+
+	resumeAt, ok = quiet.ResumeAt(time.Date(2026, 3, 10, 22, 30, 0, 0, lima))
+	if !ok || !resumeAt.Equal(time.Date(2026, 3, 11, 8, 0, 0, 0, lima)) {
+		t.Fatalf("22:30 should resume next morning, got %v %v", resumeAt, ok)
+	}
+	if _, ok := quiet.ResumeAt(time.Date(2026, 3, 10, 12, 0, 0, 0, lima)); ok {
+		t.Fatal("noon should be outside quiet hours")
+	}
+}
+
+func TestCampaignQuietHoursSameDayWindow(t *testing.T) {
+	start, end := "13:00", "14:30"
+	quiet, err := ParseCampaignQuietHours(&start, &end, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumeAt, ok := quiet.ResumeAt(time.Date(2026, 3, 10, 13, 59, 0, 0, time.UTC))
+	if !ok || !resumeAt.Equal(time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected %v %v", resumeAt, ok)
+	}
+	if _, ok := quiet.ResumeAt(time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)); ok {
+		t.Fatal("the end minute should already allow sending")
+	}
+}
+
+func TestParseCampaignQuietHoursValidation(t *testing.T) {
+	start, end, bad := "22:00", "07:00", "25:00"
+	if quiet, err := ParseCampaignQuietHours(nil, nil, ""); err != nil || quiet != nil {
+		t.Fatalf("unset quiet hours should be off, got %v %v", quiet, err)
+	}
+	if _, err := ParseCampaignQuietHours(&start, nil, ""); err == nil {
+		t.Fatal("expected error for start without end")
+	}
+	if _, err := ParseCampaignQuietHours(&start, &bad, ""); err == nil {
+		t.Fatal("expected error for invalid clock")
+	}
+	if err := ValidateAccountQuietHours(&start, &end, "Mars/Olympus"); err == nil {
+		t.Fatal("expected error for unknown timezone")
+	}
+	var off *CampaignQuietHours
+	if _, ok := off.ResumeAt(time.Now()); ok {
+		t.Fatal("nil quiet hours should never be quiet")
+	}
+}
+
+func TestAccountCampaignPacingFollowsQuietHourEdits(t *testing.T) {
+	account := &domain.Account{Timezone: "America/Lima"}
+	// 22:00 in Lima.
+	now := time.Date(2026, 3, 11, 3, 0, 0, 0, time.UTC)
+	if _, quiet := accountCampaignPacing(nil, account).QuietHours.ResumeAt(now); quiet {
+		t.Fatal("no quiet hours configured yet")
+	}
+
+	// Quiet hours set while the campaign runs apply to the next batch.
+	start, end := "21:00", "08:00"
+	account.QuietHoursStart, account.QuietHoursEnd = &start, &end
+	if _, quiet := accountCampaignPacing(nil, account).QuietHours.ResumeAt(now); !quiet {
+		t.Fatal("22:00 in Lima should be inside the new quiet hours")
+	}
+
+	// So does a timezone change: it is 12:00 in Tokyo.
+	account.Timezone = "Asia/Tokyo"
+	if _, quiet := accountCampaignPacing(nil, account).QuietHours.ResumeAt(now); quiet {
+		t.Fatal("noon in Tokyo should be outside quiet hours")
+	}
+}
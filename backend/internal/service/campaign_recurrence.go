@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+const (
+	CampaignRecurrenceNone   = "none"
+	CampaignRecurrenceDaily  = "daily"
+	CampaignRecurrenceWeekly = "weekly"
+
+	// campaignRecurrenceClonedKey marks a completed run whose next occurrence
+	// has already been created, so it is never cloned twice.
+	campaignRecurrenceClonedKey = "recurrence_next_campaign_id"
+)
+
+var campaignWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "domingo": time.Sunday,
+	"monday": time.Monday, "lunes": time.Monday,
+	"tuesday": time.Tuesday, "martes": time.Tuesday,
+	"wednesday": time.Wednesday, "miercoles": time.Wednesday, "miércoles": time.Wednesday,
+	"thursday": time.Thursday, "jueves": time.Thursday,
+	"friday": time.Friday, "viernes": time.Friday,
+	"saturday": time.Saturday, "sabado": time.Saturday, "sábado": time.Saturday,
+}
+
+// CampaignRecurrence is the parsed recurrence / recurrence_weekday settings.
+// Weekday is only meaningful for weekly recurrence; nil keeps the weekday of
+// the previous run.
+type CampaignRecurrence struct {
+	Frequency string
+	Weekday   *time.Weekday
+}
+
+// ParseCampaignRecurrence reads the recurrence settings. A missing value is
+// "none". recurrence_weekday accepts 0-6 (Sunday = 0) or an English or
+// Spanish day name.
+func ParseCampaignRecurrence(settings map[string]interface{}) (CampaignRecurrence, error) {
+	rec := CampaignRecurrence{Frequency: CampaignRecurrenceNone}
+	if raw, ok := settings["recurrence"]; ok && raw != nil {
+		value, isString := raw.(string)
+		if !isString {
+			return rec, fmt.Errorf("recurrence debe ser none, daily o weekly")
+		}
+		switch value = strings.ToLower(strings.TrimSpace(value)); value {
+		case "", CampaignRecurrenceNone:
+		case CampaignRecurrenceDaily, CampaignRecurrenceWeekly:
+			rec.Frequency = value
+		default:
+			return rec, fmt.Errorf("recurrence debe ser none, daily o weekly")
+		}
+	}
+	raw, ok := settings["recurrence_weekday"]
+	if !ok || raw == nil || raw == "" {
+		return rec, nil
+	}
+	var day time.Weekday
+	switch v := raw.(type) {
+	case float64:
+		if v < 0 || v > 6 || v != float64(int(v)) {
+			return rec, fmt.Errorf("recurrence_weekday debe estar entre 0 (domingo) y 6 (sábado)")
+		}
+		day = time.Weekday(int(v))
+	case string:
+		d, known := campaignWeekdays[strings.ToLower(strings.TrimSpace(v))]
+		if !known {
+			return rec, fmt.Errorf("recurrence_weekday no reconocido: %s", v)
+		}
+		day = d
+	default:
+		return rec, fmt.Errorf("recurrence_weekday no reconocido")
+	}
+	if rec.Frequency == CampaignRecurrenceWeekly {
+		rec.Weekday = &day
+	}
+	return rec, nil
+}
+
+// NextRun returns the next occurrence after base that is strictly later than
+// now, keeping base's time of day. It reports false for non-recurring
+// campaigns. Stepping from base (not from now) keeps the wall-clock slot
+// stable even when a run finished late.
+func (r CampaignRecurrence) NextRun(base, now time.Time) (time.Time, bool) {
+	var step time.Duration
+	switch r.Frequency {
+	case CampaignRecurrenceDaily:
+		step = 24 * time.Hour
+	case CampaignRecurrenceWeekly:
+		step = 7 * 24 * time.Hour
+	default:
+		return time.Time{}, false
+	}
+	next := base
+	if r.Frequency == CampaignRecurrenceWeekly && r.Weekday != nil {
+		next = base.AddDate(0, 0, (int(*r.Weekday)-int(base.Weekday())+7)%7)
+		if !next.After(base) {
+			next = next.AddDate(0, 0, 7)
+		}
+	} else {
+		next = base.Add(step)
+	}
+	for !next.After(now) {
+		next = next.Add(step)
+	}
+	return next, true
+}
+
+// scheduleNextRecurrence clones a completed recurring campaign as a new
+// scheduled run with every recipient back to pending. It is a no-op for
+// one-off campaigns and for runs that were already cloned.
+func (s *CampaignService) scheduleNextRecurrence(ctx context.Context, campaign *domain.Campaign) (*domain.Campaign, error) {
+	recurrence, err := ParseCampaignRecurrence(campaign.Settings)
+	if err != nil || recurrence.Frequency == CampaignRecurrenceNone {
+		return nil, err
+	}
+	if _, cloned := campaign.Settings[campaignRecurrenceClonedKey]; cloned {
+		return nil, nil
+	}
+	base := time.Now()
+	if campaign.ScheduledAt != nil {
+		base = *campaign.ScheduledAt
+	} else if campaign.StartedAt != nil {
+		base = *campaign.StartedAt
+	}
+	nextAt, ok := recurrence.NextRun(base, time.Now())
+	if !ok {
+		return nil, nil
+	}
+
+	next, err := s.duplicate(ctx, campaign, &domain.Campaign{
+		Name:        campaign.Name,
+		Status:      domain.CampaignStatusScheduled,
+		ScheduledAt: &nextAt,
+		Settings:    campaign.Settings,
+		CreatedBy:   campaign.CreatedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The next run is sent unattended, so it goes through the same start
+	// guard as any scheduled campaign, against the count confirmed for the
+	// series.
+	confirmed := CampaignConfirmedCount(campaign.Settings)
+	if confirmed != nil {
+		next.Settings[CampaignConfirmedCountKey] = *confirmed
+	}
+	var confirmErr *CampaignStartConfirmationError
+	if err := s.checkStartConfirmation(ctx, next, confirmed); errors.As(err, &confirmErr) {
+		if err := s.holdForConfirmation(ctx, next, confirmErr.RecipientCount); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	} else if confirmed != nil {
+		if err := s.repos.Campaign.Update(ctx, next); err != nil {
+			return nil, err
+		}
+	}
+
+	campaign.Settings[campaignRecurrenceClonedKey] = next.ID.String()
+	if err := s.repos.Campaign.Update(ctx, campaign); err != nil {
+		log.Printf("[Campaign %s] Failed to mark recurrence as scheduled: %v", campaign.ID, err)
+	}
+	log.Printf("[Campaign %s] 🔁 Next %s run scheduled as %s at %s", campaign.ID, recurrence.Frequency, next.ID, nextAt.Format(time.RFC3339))
+	return next, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCampaignRecurrence(t *testing.T) {
+	rec, err := ParseCampaignRecurrence(map[string]interface{}{})
+	if err != nil || rec.Frequency != CampaignRecurrenceNone {
+		t.Fatalf("missing recurrence = %+v, %v", rec, err)
+	}
+	rec, err = ParseCampaignRecurrence(map[string]interface{}{"recurrence": "Weekly", "recurrence_weekday": "lunes"})
+	if err != nil || rec.Frequency != CampaignRecurrenceWeekly || rec.Weekday == nil || *rec.Weekday != time.Monday {
+		t.Fatalf("weekly recurrence = %+v, %v", rec, err)
+	}
+	rec, err = ParseCampaignRecurrence(map[string]interface{}{"recurrence": "weekly", "recurrence_weekday": float64(5)})
+	if err != nil || *rec.Weekday != time.Friday {
+		t.Fatalf("numeric weekday = %+v, %v", rec, err)
+	}
+	for _, bad := range []map[string]interface{}{
+		{"recurrence": "monthly"},
+		{"recurrence": true},
+		{"recurrence": "weekly", "recurrence_weekday": float64(7)},
+		{"recurrence": "weekly", "recurrence_weekday": "someday"},
+	} {
+		if _, err := ParseCampaignRecurrence(bad); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestCampaignRecurrenceNextRun(t *testing.T) {
+	// Monday 2026-03-02 09:00 UTC
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	daily := CampaignRecurrence{Frequency: CampaignRecurrenceDaily}
+	if next, ok := daily.NextRun(base, base.Add(time.Hour)); !ok || !next.Equal(base.AddDate(0, 0, 1)) {
+		t.Fatalf("daily next = %v", next)
+	}
+	// A run that finished days late still lands on the next future slot.
+	if next, _ := daily.NextRun(base, base.AddDate(0, 0, 3).Add(time.Minute)); !next.Equal(base.AddDate(0, 0, 4)) {
+		t.Fatalf("late daily next = %v", next)
+	}
+
+	weekly := CampaignRecurrence{Frequency: CampaignRecurrenceWeekly}
+	if next, _ := weekly.NextRun(base, base.Add(time.Hour)); !next.Equal(base.AddDate(0, 0, 7)) {
+		t.Fatalf("weekly next = %v", next)
+	}
+	thursday := time.Thursday
+	weekly.Weekday = &thursday
+	if next, _ := weekly.NextRun(base, base.Add(time.Hour)); !next.Equal(time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("weekly thursday next = %v", next)
+	}
+	monday := time.Monday
+	weekly.Weekday = &monday
+	if next, _ := weekly.NextRun(base, base); !next.After(base) || next.Weekday() != time.Monday {
+		t.Fatalf("same-weekday next must be strictly later, got %v", next)
+	}
+
+	if _, ok := (CampaignRecurrence{Frequency: CampaignRecurrenceNone}).NextRun(base, base); ok {
+		t.Fatal("non-recurring campaign produced a next run")
+	}
+}
+
+func TestCopyCampaignSettingsDropsRunState(t *testing.T) {
+	settings := map[string]interface{}{
+		"recurrence":                "daily",
+		campaignBatchProgressKey:    map[string]interface{}{"sent_in_batch": float64(3)},
+		campaignRecurrenceClonedKey: "x",
+	}
+	copied := copyCampaignSettings(settings)
+	if copied["recurrence"] != "daily" || len(copied) != 1 {
+		t.Fatalf("copied settings = %v", copied)
+	}
+	if len(settings) != 3 {
+		t.Fatal("original settings were modified")
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/naperu/clarin/internal/whatsapp"
+)
+
+const (
+	// DefaultCampaignMaxRetries is how many times a transient send failure is
+	// requeued when the campaign settings do not say otherwise.
+	DefaultCampaignMaxRetries = 2
+	campaignMaxRetriesLimit   = 10
+
+	campaignRetryBaseBackoff = time.Minute
+	campaignRetryMaxBackoff  = 30 * time.Minute
+)
+
+// ErrCampaignRetryPending means a running campaign has no recipient ready to
+// send, only ones waiting out the backoff of a transient failure.
+var ErrCampaignRetryPending = errors.New("destinatarios en espera de reintento")
+
+// campaignTransientErrorMarkers are fragments of send errors that clear up on
+// their own: the device dropping its connection or the request timing out.
+var campaignTransientErrorMarkers = []string{
+	"not connected",
+	"websocket not connected",
+	"timed out",
+	"timeout",
+	"connection reset",
+	"broken pipe",
+}
+
+// campaignTransientStatuses are the WhatsApp status codes of a throttled or
+// temporarily unavailable send (see whatsapp.SendErrorStatus).
+var campaignTransientStatuses = map[int]bool{
+	429: true, // rate-overlimit
+	475: true, // anti-spam throttling
+	503: true, // service-unavailable
+}
+
+// CampaignMaxRetries reads max_retries from the campaign settings, falling
+// back to DefaultCampaignMaxRetries.
+func CampaignMaxRetries(settings map[string]interface{}) int {
+	n, err := parseCampaignMaxRetries(settings)
+	if err != nil {
+		return DefaultCampaignMaxRetries
+	}
+	return n
+}
+
+// ValidateCampaignMaxRetries rejects a max_retries that is not a whole number
+// between 0 and campaignMaxRetriesLimit.
+func ValidateCampaignMaxRetries(settings map[string]interface{}) error {
+	_, err := parseCampaignMaxRetries(settings)
+	return err
+}
+
+func parseCampaignMaxRetries(settings map[string]interface{}) (int, error) {
+	raw, ok := settings["max_retries"]
+	if !ok || raw == nil {
+		return DefaultCampaignMaxRetries, nil
+	}
+	v, isNumber := raw.(float64)
+	if !isNumber || v < 0 || v > campaignMaxRetriesLimit || v != float64(int(v)) {
+		return DefaultCampaignMaxRetries, fmt.Errorf("max_retries debe ser un entero entre 0 y %d", campaignMaxRetriesLimit)
+	}
+	return int(v), nil
+}
+
+// isTransientSendError reports whether a failed send is worth retrying.
+// Anything unrecognised is treated as permanent so a bad number is never
+// hammered.
+func isTransientSendError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || campaignTransientStatuses[whatsapp.SendErrorStatus(err)] {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range campaignTransientErrorMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// campaignRetryBackoff is the wait before retry number retryCount+1:
+// 1m, 2m, 4m... capped at campaignRetryMaxBackoff.
+func campaignRetryBackoff(retryCount int) time.Duration {
+	if retryCount < 0 {
+		retryCount = 0
+	}
+	if retryCount > 5 {
+		return campaignRetryMaxBackoff
+	}
+	backoff := campaignRetryBaseBackoff << retryCount
+	if backoff > campaignRetryMaxBackoff {
+		return campaignRetryMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+func TestCampaignMaxRetries(t *testing.T) {
+	cases := []struct {
+		settings map[string]interface{}
+		want     int
+		invalid  bool
+	}{
+		{nil, DefaultCampaignMaxRetries, false},
+		{map[string]interface{}{"max_retries": float64(0)}, 0, false},
+		{map[string]interface{}{"max_retries": float64(5)}, 5, false},
+		{map[string]interface{}{"max_retries": float64(-1)}, DefaultCampaignMaxRetries, true},
+		{map[string]interface{}{"max_retries": float64(1.5)}, DefaultCampaignMaxRetries, true},
+		{map[string]interface{}{"max_retries": float64(campaignMaxRetriesLimit + 1)}, DefaultCampaignMaxRetries, true},
+		{map[string]interface{}{"max_retries": "3"}, DefaultCampaignMaxRetries, true},
+	}
+	for _, tc := range cases {
+		if got := CampaignMaxRetries(tc.settings); got != tc.want {
+			t.Errorf("CampaignMaxRetries(%v) = %d, want %d", tc.settings, got, tc.want)
+		}
+		if err := ValidateCampaignMaxRetries(tc.settings); (err != nil) != tc.invalid {
+			t.Errorf("ValidateCampaignMaxRetries(%v) error = %v, invalid %v", tc.settings, err, tc.invalid)
+		}
+	}
+}
+
+func TestIsTransientSendError(t *testing.T) {
+	transient := []error{
+		fmt.Errorf("device not connected: %s", "abc"),
+		fmt.Errorf("failed to send message: %w", fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 475)),
+		fmt.Errorf("failed to upload: %w", whatsmeow.ErrIQServiceUnavailable),
+		errors.New("info query timed out"),
+		fmt.Errorf("upload: %w", context.DeadlineExceeded),
+	}
+	for _, err := range transient {
+		if !isTransientSendError(err) {
+			t.Errorf("expected %q to be transient", err)
+		}
+	}
+	permanent := []error{
+		nil,
+		errors.New("Número no disponible en WhatsApp"),
+		errors.New("invalid JID"),
+		errors.New("invalid JID: 51947503429@s.whatsapp.net"),
+		fmt.Errorf("failed to send message: %w", fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 463)),
+	}
+	for _, err := range permanent {
+		if isTransientSendError(err) {
+			t.Errorf("expected %v to be permanent", err)
+		}
+	}
+}
+
+func TestCampaignRetryBackoff(t *testing.T) {
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute, 30 * time.Minute, 30 * time.Minute}
+	for retry, expected := range want {
+		if got := campaignRetryBackoff(retry); got != expected {
+			t.Errorf("campaignRetryBackoff(%d) = %v, want %v", retry, got, expected)
+		}
+	}
+	if got := campaignRetryBackoff(100); got != campaignRetryMaxBackoff {
+		t.Errorf("campaignRetryBackoff(100) = %v", got)
+	}
+}
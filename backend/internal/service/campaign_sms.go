@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/sms"
+)
+
+// SMSSender delivers a plain-text SMS. Campaigns use it as an optional
+// fallback for recipients whose number is not on WhatsApp.
+type SMSSender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// newSMSSender builds the sender for an account's settings. Only Twilio is
+// supported today.
+func newSMSSender(settings *domain.AccountSMSSettings) SMSSender {
+	return sms.NewTwilioSender(settings.TwilioAccountSID, settings.TwilioAuthToken, settings.FromNumber)
+}
+
+// smsSenderForAccount returns nil when the account has no enabled, complete
+// SMS configuration, which keeps the fallback a no-op.
+func (s *CampaignService) smsSenderForAccount(ctx context.Context, accountID uuid.UUID) SMSSender {
+	if s.repos == nil || s.repos.SMSSettings == nil {
+		return nil
+	}
+	settings, err := s.repos.SMSSettings.Get(ctx, accountID)
+	if err != nil {
+		log.Printf("[Campaign] SMS settings lookup failed for account %s: %v", accountID, err)
+		return nil
+	}
+	if !settings.Ready() {
+		return nil
+	}
+	return newSMSSender(settings)
+}
+
+// smsRecipientNumber returns the E.164 number for a recipient, preferring the
+// number WhatsApp was checked against.
+func smsRecipientNumber(rec *domain.CampaignRecipient) string {
+	raw := strings.Split(rec.JID, "@")[0]
+	if raw == "" {
+		raw = stringValue(rec.Phone)
+	}
+	digits := digitsOnly(raw)
+	if digits == "" {
+		return ""
+	}
+	return "+" + digits
+}
+
+// sendCampaignSMS delivers the campaign text to a recipient that is not on
+// WhatsApp. Privacy rules apply exactly as for WhatsApp deliveries; media is
+// not sent over SMS.
+func (s *CampaignService) sendCampaignSMS(ctx context.Context, campaign *domain.Campaign, rec *domain.CampaignRecipient, sender SMSSender, waitTimeMs *int) {
+	contact, privacyErr := s.validateRecipientPrivacy(ctx, campaign, rec)
+	if privacyErr != nil {
+		errMsg := privacyErr.Error()
+		log.Printf("[Campaign %s] SKIPPED %s: %s", campaign.ID, rec.JID, errMsg)
+		s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "skipped", &errMsg, waitTimeMs)
+		return
+	}
+	var lead *domain.Lead
+	if rec.JID != "" {
+		lead, _ = s.repos.Lead.GetByJID(ctx, campaign.AccountID, rec.JID)
+	}
+
+	s.repos.Campaign.UpdateRecipientChannel(ctx, rec.ID, domain.CampaignChannelSMS)
+	body := strings.TrimSpace(personalizeText(campaign.MessageTemplate, rec, contact, lead))
+	to := smsRecipientNumber(rec)
+	var errMsg string
+	switch {
+	case body == "":
+		errMsg = "Número no disponible en WhatsApp y la campaña no tiene texto para SMS"
+	case to == "":
+		errMsg = "Número no disponible en WhatsApp ni válido para SMS"
+	default:
+		if err := sender.Send(ctx, to, body); err != nil {
+			errMsg = "SMS: " + err.Error()
+		}
+	}
+	if errMsg != "" {
+		log.Printf("[Campaign %s] FAILED %s via SMS: %s", campaign.ID, rec.JID, errMsg)
+		s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "failed", &errMsg, waitTimeMs)
+		s.repos.Campaign.IncrementFailedCount(ctx, campaign.ID)
+		return
+	}
+	log.Printf("[Campaign %s] SENT to %s via SMS", campaign.ID, to)
+	s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "sent", nil, waitTimeMs)
+	s.repos.Campaign.IncrementSentCount(ctx, campaign.ID)
+}
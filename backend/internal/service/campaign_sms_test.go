@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestSMSRecipientNumber(t *testing.T) {
+	phone := "+51 999-888-777"
+	cases := []struct {
+		name string
+		rec  *domain.CampaignRecipient
+		want string
+	}{
+		{"jid", &domain.CampaignRecipient{JID: "51999999999@s.whatsapp.net", Phone: &phone}, "+51999999999"},
+		{"phone fallback", &domain.CampaignRecipient{Phone: &phone}, "+51999888777"},
+		{"nothing usable", &domain.CampaignRecipient{}, ""},
+	}
+	for _, tc := range cases {
+		if got := smsRecipientNumber(tc.rec); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSMSSettingsReady(t *testing.T) {
+	var unset *domain.AccountSMSSettings
+	if unset.Ready() {
+		t.Fatal("missing settings reported ready")
+	}
+	settings := &domain.AccountSMSSettings{Enabled: true, TwilioAccountSID: "AC1", TwilioAuthToken: "tok", FromNumber: "+15550000000"}
+	if !settings.Ready() {
+		t.Fatal("complete settings reported not ready")
+	}
+	settings.Enabled = false
+	if settings.Ready() {
+		t.Fatal("disabled settings reported ready")
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import "testing"
+
+func TestCampaignStartNeedsConfirmation(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	cases := []struct {
+		name      string
+		count     int
+		threshold int
+		confirm   *int
+		want      bool
+	}{
+		{"below threshold", 120, 500, nil, false},
+		{"at threshold", 500, 500, nil, false},
+		{"above threshold without confirmation", 501, 500, nil, true},
+		{"above threshold with stale count", 2400, 500, intPtr(2300), true},
+		{"above threshold with matching count", 2400, 500, intPtr(2400), false},
+		{"guard disabled", 10000, 0, nil, false},
+	}
+	for _, tc := range cases {
+		if got := campaignStartNeedsConfirmation(tc.count, tc.threshold, tc.confirm); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCampaignConfirmedCount(t *testing.T) {
+	if got := CampaignConfirmedCount(nil); got != nil {
+		t.Fatalf("no settings: got %v, want nil", *got)
+	}
+	// Settings round-trip through JSON, so stored counts come back as float64.
+	got := CampaignConfirmedCount(map[string]interface{}{CampaignConfirmedCountKey: float64(2400)})
+	if got == nil || *got != 2400 {
+		t.Fatalf("confirmed count = %v, want 2400", got)
+	}
+	if got := CampaignConfirmedCount(map[string]interface{}{CampaignConfirmedCountKey: "2400"}); got != nil {
+		t.Fatalf("string count: got %v, want nil", *got)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestCampaignTemplateVariablesDistinctInOrder(t *testing.T) {
+	got := campaignTemplateVariables("Hola {{nombre}}, {{empresa}} te espera. {{nombre}} {{ bad }}")
+	want := []string{"nombre", "empresa"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("variables = %v, want %v", got, want)
+	}
+}
+
+func TestPersonalizeTextResolvesAndBlanksMissing(t *testing.T) {
+	name := "Ana"
+	shortName := "Anita"
+	rec := &domain.CampaignRecipient{
+		Name:     &name,
+		Metadata: map[string]interface{}{"empresa": "Clarin", "name": "ignored"},
+	}
+	contact := &domain.Contact{ShortName: &shortName}
+	got := personalizeText("Hola {{name}} ({{nombre_corto}}) de {{empresa}}{{ciudad}}", rec, contact, nil)
+	if want := "Hola Ana (Anita) de Clarin"; got != want {
+		t.Fatalf("personalizeText = %q, want %q", got, want)
+	}
+}
+
+func TestCampaignTemplateValuesOmitsEmptyData(t *testing.T) {
+	empty := ""
+	rec := &domain.CampaignRecipient{Phone: &empty, Metadata: map[string]interface{}{"empresa": ""}}
+	values := campaignTemplateValues(rec, nil, nil)
+	for _, key := range []string{"phone", "empresa", "nombre_completo"} {
+		if _, ok := values[key]; ok {
+			t.Fatalf("expected %q to be missing, got %q", key, values[key])
+		}
+	}
+}
+
+func TestRenderTemplateUsesRecipientFields(t *testing.T) {
+	name := "Ana María Torres"
+	phone := "+51999999999"
+	rec := &domain.CampaignRecipient{
+		Name:     &name,
+		Phone:    &phone,
+		Metadata: map[string]interface{}{"empresa": "Clarin"},
+	}
+	svc := &CampaignService{}
+	got := svc.RenderTemplate("Hola {{first_name}} ({{name}}, {{phone}}) de {{company}}{{unknown}}", rec)
+	if want := "Hola Ana (Ana María Torres, +51999999999) de Clarin"; got != want {
+		t.Fatalf("RenderTemplate = %q, want %q", got, want)
+	}
+}
+
+func TestRenderTemplateLeavesMalformedPlaceholders(t *testing.T) {
+	name := "Ana"
+	rec := &domain.CampaignRecipient{Name: &name}
+	svc := &CampaignService{}
+	for template, want := range map[string]string{
+		"Hola {{name":           "Hola {{name",
+		"Precio {{ 10 }} {{":    "Precio {{ 10 }} {{",
+		"{{{{name}}}}":          "{{Ana}}",
+		"Hola {{name}} {{x-y}}": "Hola Ana {{x-y}}",
+	} {
+		if got := svc.RenderTemplate(template, rec); got != want {
+			t.Errorf("RenderTemplate(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// campaignTestNumberMinDigits is the shortest whitelist entry matched as a
+// suffix, so numbers saved without country code still match.
+const campaignTestNumberMinDigits = 8
+
+func digitsOnly(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// CampaignTestNumbers returns the normalized whitelist when the campaign has
+// test_mode enabled, and ok=false otherwise.
+func CampaignTestNumbers(settings map[string]interface{}) (numbers []string, ok bool) {
+	if enabled, _ := settings["test_mode"].(bool); !enabled {
+		return nil, false
+	}
+	switch raw := settings["test_numbers"].(type) {
+	case []interface{}:
+		for _, v := range raw {
+			if s, isString := v.(string); isString {
+				if d := digitsOnly(s); d != "" {
+					numbers = append(numbers, d)
+				}
+			}
+		}
+	case []string:
+		for _, s := range raw {
+			if d := digitsOnly(s); d != "" {
+				numbers = append(numbers, d)
+			}
+		}
+	}
+	return numbers, true
+}
+
+// campaignTestNumberAllowed reports whether a recipient is on the test
+// whitelist. Entries match the full number or, when long enough, its tail.
+func campaignTestNumberAllowed(rec *domain.CampaignRecipient, whitelist []string) bool {
+	candidates := []string{digitsOnly(stringValue(rec.Phone)), digitsOnly(strings.Split(rec.JID, "@")[0])}
+	for _, number := range whitelist {
+		for _, candidate := range candidates {
+			if candidate == "" {
+				continue
+			}
+			if candidate == number || (len(number) >= campaignTestNumberMinDigits && strings.HasSuffix(candidate, number)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestCampaignTestNumbers(t *testing.T) {
+	if _, ok := CampaignTestNumbers(map[string]interface{}{"test_numbers": []interface{}{"+51 999"}}); ok {
+		t.Fatal("test mode reported without test_mode=true")
+	}
+	numbers, ok := CampaignTestNumbers(map[string]interface{}{
+		"test_mode":    true,
+		"test_numbers": []interface{}{"+51 999-888-777", "", 42, "999 111 222"},
+	})
+	if !ok || !reflect.DeepEqual(numbers, []string{"51999888777", "999111222"}) {
+		t.Fatalf("unexpected whitelist %v (ok=%v)", numbers, ok)
+	}
+}
+
+func TestCampaignTestNumberAllowed(t *testing.T) {
+	whitelist := []string{"51999888777", "999111222", "123"}
+	phone := "+51 999 111 222"
+	cases := []struct {
+		name string
+		rec  *domain.CampaignRecipient
+		want bool
+	}{
+		{"exact jid", &domain.CampaignRecipient{JID: "51999888777@s.whatsapp.net"}, true},
+		{"suffix without country code", &domain.CampaignRecipient{Phone: &phone}, true},
+		{"short entries need exact match", &domain.CampaignRecipient{JID: "51000000123@s.whatsapp.net"}, false},
+		{"not listed", &domain.CampaignRecipient{JID: "51900000000@s.whatsapp.net"}, false},
+	}
+	for _, tc := range cases {
+		if got := campaignTestNumberAllowed(tc.rec, whitelist); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
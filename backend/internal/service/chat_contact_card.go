@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+var (
+	ErrContactCardNotFound = errors.New("contacto no encontrado")
+	ErrContactCardInvalid  = errors.New("el mensaje no contiene una tarjeta de contacto con teléfono")
+)
+
+// SendContactCard shares a contact card in a chat. When contactID is set the
+// card is built from that saved contact; otherwise name and phone are used.
+func (s *ChatService) SendContactCard(ctx context.Context, accountID, deviceID uuid.UUID, to string, contactID *uuid.UUID, name, phone string) (*domain.Message, error) {
+	if contactID != nil {
+		contact, err := s.repos.Contact.GetByIDForAccount(ctx, accountID, *contactID)
+		if err != nil {
+			return nil, err
+		}
+		if contact == nil || contact.IsGroup {
+			return nil, ErrContactCardNotFound
+		}
+		name, phone = contactCardIdentity(contact)
+	}
+	name = strings.TrimSpace(name)
+	phone = digitsOnly(phone)
+	if name == "" {
+		name = phone
+	}
+	if phone == "" {
+		return nil, ErrContactCardInvalid
+	}
+	return s.SendContactMessage(ctx, deviceID, to, name, phone)
+}
+
+// AddContactFromCard saves the person shared in a received contact card to
+// the account's contacts, reusing the existing contact for that number.
+func (s *ChatService) AddContactFromCard(ctx context.Context, accountID, messageID uuid.UUID) (*domain.Contact, error) {
+	message, err := s.repos.Message.GetByIDForAccount(ctx, accountID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, nil
+	}
+	if message.MessageType == nil || *message.MessageType != domain.MessageTypeContact || message.ContactPhone == nil {
+		return nil, ErrContactCardInvalid
+	}
+	phone := digitsOnly(*message.ContactPhone)
+	if phone == "" {
+		return nil, ErrContactCardInvalid
+	}
+	name := ""
+	if message.ContactName != nil {
+		name = strings.TrimSpace(*message.ContactName)
+	}
+	return s.repos.Contact.GetOrCreate(ctx, accountID, message.DeviceID, phone+"@s.whatsapp.net", phone, name, "", false)
+}
+
+// contactCardIdentity picks the name and number to put on a saved contact's card.
+func contactCardIdentity(contact *domain.Contact) (name, phone string) {
+	if contact.Phone != nil {
+		phone = *contact.Phone
+	}
+	if digitsOnly(phone) == "" && strings.HasSuffix(contact.JID, "@s.whatsapp.net") {
+		phone = strings.TrimSuffix(contact.JID, "@s.whatsapp.net")
+	}
+	return contact.DisplayName(), phone
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+const (
+	// numberCheckBatchSize bounds each IsOnWhatsApp query; WhatsApp throttles
+	// devices that look up many numbers at once.
+	numberCheckBatchSize  = 50
+	numberCheckBatchPause = time.Second
+	numberCheckCacheTTL   = 10 * time.Minute
+)
+
+type numberCheckEntry struct {
+	registered bool
+	checkedAt  time.Time
+}
+
+// numberCheckCache remembers recent IsOnWhatsApp answers per device so that
+// validating the same list twice does not query WhatsApp again.
+type numberCheckCache struct {
+	mu      sync.Mutex
+	devices map[uuid.UUID]map[string]numberCheckEntry
+}
+
+func (c *numberCheckCache) get(deviceID uuid.UUID, phone string, now time.Time) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.devices[deviceID][phone]
+	if !ok || now.Sub(entry.checkedAt) > numberCheckCacheTTL {
+		return false, false
+	}
+	return entry.registered, true
+}
+
+func (c *numberCheckCache) put(deviceID uuid.UUID, phone string, registered bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.devices == nil {
+		c.devices = make(map[uuid.UUID]map[string]numberCheckEntry)
+	}
+	entries := c.devices[deviceID]
+	if entries == nil {
+		entries = make(map[string]numberCheckEntry)
+		c.devices[deviceID] = entries
+	}
+	for key, entry := range entries {
+		if now.Sub(entry.checkedAt) > numberCheckCacheTTL {
+			delete(entries, key)
+		}
+	}
+	entries[phone] = numberCheckEntry{registered: registered, checkedAt: now}
+}
+
+type numberLookupFunc func(ctx context.Context, phones []string) ([]domain.WhatsAppCheckResult, error)
+
+// CheckNumbersRegistered reports, for each JID, whether its number has a
+// WhatsApp account. Lookups go through the device in batches and answers are
+// cached per device for a few minutes.
+func (s *ChatService) CheckNumbersRegistered(ctx context.Context, deviceID uuid.UUID, jids []string) (map[string]bool, error) {
+	return s.numberChecks.check(ctx, deviceID, jids, func(ctx context.Context, phones []string) ([]domain.WhatsAppCheckResult, error) {
+		return s.pool.IsOnWhatsApp(ctx, deviceID, phones)
+	})
+}
+
+func (c *numberCheckCache) check(ctx context.Context, deviceID uuid.UUID, jids []string, lookup numberLookupFunc) (map[string]bool, error) {
+	result := make(map[string]bool, len(jids))
+	byPhone := make(map[string][]string)
+	var missing []string
+	now := time.Now()
+	for _, jid := range jids {
+		phone := numberCheckPhone(jid)
+		if phone == "" {
+			result[jid] = false
+			continue
+		}
+		if registered, ok := c.get(deviceID, phone, now); ok {
+			result[jid] = registered
+			continue
+		}
+		if _, queued := byPhone[phone]; !queued {
+			missing = append(missing, phone)
+		}
+		byPhone[phone] = append(byPhone[phone], jid)
+	}
+
+	for start := 0; start < len(missing); start += numberCheckBatchSize {
+		if start > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(numberCheckBatchPause):
+			}
+		}
+		end := start + numberCheckBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := missing[start:end]
+		queries := make([]string, len(batch))
+		for i, phone := range batch {
+			queries[i] = "+" + phone
+		}
+		answers, err := lookup(ctx, queries)
+		if err != nil {
+			return nil, err
+		}
+		registered := make(map[string]bool, len(answers))
+		for _, answer := range answers {
+			if answer.IsOnWhatsApp {
+				registered[strings.TrimPrefix(answer.Phone, "+")] = true
+			}
+		}
+		checkedAt := time.Now()
+		for _, phone := range batch {
+			c.put(deviceID, phone, registered[phone], checkedAt)
+			for _, jid := range byPhone[phone] {
+				result[jid] = registered[phone]
+			}
+		}
+	}
+	return result, nil
+}
+
+// numberCheckPhone extracts the digits of the user part of a JID.
+func numberCheckPhone(jid string) string {
+	user := jid
+	if at := strings.IndexByte(user, '@'); at >= 0 {
+		user = user[:at]
+	}
+	if colon := strings.IndexByte(user, ':'); colon >= 0 {
+		user = user[:colon]
+	}
+	return digitsOnly(user)
+}
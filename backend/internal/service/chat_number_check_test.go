@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestNumberCheckCacheBatchesAndCaches(t *testing.T) {
+	var cache numberCheckCache
+	device := uuid.New()
+	var calls [][]string
+	lookup := func(_ context.Context, phones []string) ([]domain.WhatsAppCheckResult, error) {
+		calls = append(calls, phones)
+		results := make([]domain.WhatsAppCheckResult, len(phones))
+		for i, phone := range phones {
+			results[i] = domain.WhatsAppCheckResult{Phone: phone, IsOnWhatsApp: phone != "+51900000001"}
+		}
+		return results, nil
+	}
+
+	jids := []string{"51900000001@s.whatsapp.net", "51900000002@s.whatsapp.net", "51900000002:3@s.whatsapp.net", ""}
+	got, err := cache.check(context.Background(), device, jids, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[jids[0]] || !got[jids[1]] || !got[jids[2]] || got[""] {
+		t.Fatalf("unexpected results %v", got)
+	}
+	if len(calls) != 1 || len(calls[0]) != 2 {
+		t.Fatalf("expected one lookup of two distinct numbers, got %v", calls)
+	}
+
+	if _, err := cache.check(context.Background(), device, jids[:2], lookup); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("cached numbers were looked up again: %v", calls)
+	}
+	if _, err := cache.check(context.Background(), uuid.New(), jids[:1], lookup); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("cache leaked across devices: %v", calls)
+	}
+}
+
+func TestNumberCheckCacheSplitsBatches(t *testing.T) {
+	var cache numberCheckCache
+	var sizes []int
+	lookup := func(_ context.Context, phones []string) ([]domain.WhatsAppCheckResult, error) {
+		sizes = append(sizes, len(phones))
+		return nil, nil
+	}
+	jids := make([]string, numberCheckBatchSize+1)
+	for i := range jids {
+		jids[i] = fmt.Sprintf("5199%07d@s.whatsapp.net", i)
+	}
+	got, err := cache.check(context.Background(), uuid.New(), jids, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 2 || sizes[0] != numberCheckBatchSize || sizes[1] != 1 {
+		t.Fatalf("unexpected batch sizes %v", sizes)
+	}
+	for _, jid := range jids {
+		if got[jid] {
+			t.Fatalf("%s reported registered without an answer", jid)
+		}
+	}
+}
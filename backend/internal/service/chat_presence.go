@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+const (
+	ChatPresenceComposing   = "composing"
+	ChatPresencePaused      = "paused"
+	ChatPresenceAvailable   = "available"
+	ChatPresenceUnavailable = "unavailable"
+
+	// composingDebounce drops repeated "composing" events for the same chat;
+	// WhatsApp keeps the indicator up for several seconds on its own.
+	composingDebounce = 2 * time.Second
+)
+
+// presenceDebouncer remembers when "composing" was last forwarded per
+// device and chat.
+type presenceDebouncer struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// allow reports whether a composing event may be forwarded now. Any other
+// state resets the chat so the next composing goes through immediately.
+func (d *presenceDebouncer) allow(deviceID uuid.UUID, jid, state string, now time.Time) bool {
+	key := deviceID.String() + "|" + jid
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if state != ChatPresenceComposing {
+		delete(d.last, key)
+		return true
+	}
+	if at, ok := d.last[key]; ok && now.Sub(at) < composingDebounce {
+		return false
+	}
+	if d.last == nil {
+		d.last = make(map[string]time.Time)
+	}
+	for k, at := range d.last {
+		if now.Sub(at) >= composingDebounce {
+			delete(d.last, k)
+		}
+	}
+	d.last[key] = now
+	return true
+}
+
+// ValidChatPresence reports whether state can be sent to a chat.
+func ValidChatPresence(state string) bool {
+	switch state {
+	case ChatPresenceComposing, ChatPresencePaused, ChatPresenceAvailable:
+		return true
+	}
+	return false
+}
+
+// SendPresence forwards an agent's composing/paused state to a chat, or marks
+// the device available. Repeated composing events are debounced; sent
+// reports whether anything reached WhatsApp.
+func (s *ChatService) SendPresence(ctx context.Context, deviceID uuid.UUID, jid, state string) (sent bool, err error) {
+	if !ValidChatPresence(state) {
+		return false, fmt.Errorf("estado de presencia no válido: %s", state)
+	}
+	if err := s.ensureWhatsAppWebOutbound(ctx, deviceID); err != nil {
+		return false, err
+	}
+	if state == ChatPresenceAvailable {
+		return true, s.pool.SendPresence(ctx, deviceID, true)
+	}
+	if !s.presence.allow(deviceID, jid, state, time.Now()) {
+		return false, nil
+	}
+	return true, s.pool.SendChatPresence(ctx, deviceID, jid, state == ChatPresenceComposing, "")
+}
+
+// SetAccountPresence marks every connected WhatsApp Web device of the
+// account online while an agent has the app open, and offline afterwards so
+// the phone keeps receiving notifications.
+func (s *ChatService) SetAccountPresence(ctx context.Context, accountID uuid.UUID, available bool) {
+	devices, err := s.repos.Device.GetByAccountID(ctx, accountID)
+	if err != nil {
+		log.Printf("[Presence] failed to list devices for account %s: %v", accountID, err)
+		return
+	}
+	for _, device := range devices {
+		if device.Provider != nil && *device.Provider == domain.DeviceProviderWhatsAppCloudAPI {
+			continue
+		}
+		if !s.pool.IsDeviceConnected(device.ID) {
+			continue
+		}
+		if err := s.pool.SendPresence(ctx, device.ID, available); err != nil {
+			log.Printf("[Presence] failed to set device %s available=%v: %v", device.ID, available, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPresenceDebouncerDropsRepeatedComposing(t *testing.T) {
+	var d presenceDebouncer
+	device := uuid.New()
+	jid := "51999888777@s.whatsapp.net"
+	now := time.Now()
+
+	if !d.allow(device, jid, ChatPresenceComposing, now) {
+		t.Fatal("first composing was dropped")
+	}
+	if d.allow(device, jid, ChatPresenceComposing, now.Add(time.Second)) {
+		t.Fatal("composing within the debounce window was forwarded")
+	}
+	if !d.allow(device, "51911111111@s.whatsapp.net", ChatPresenceComposing, now.Add(time.Second)) {
+		t.Fatal("composing in another chat was dropped")
+	}
+	if !d.allow(device, jid, ChatPresenceComposing, now.Add(composingDebounce)) {
+		t.Fatal("composing after the window was dropped")
+	}
+	if !d.allow(device, jid, ChatPresencePaused, now.Add(composingDebounce+time.Millisecond)) {
+		t.Fatal("paused must always be forwarded")
+	}
+	if !d.allow(device, jid, ChatPresenceComposing, now.Add(composingDebounce+2*time.Millisecond)) {
+		t.Fatal("composing right after paused was dropped")
+	}
+}
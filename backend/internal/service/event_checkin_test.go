@@ -0,0 +1,21 @@
+package service
+
+import "testing"
+
+func TestCheckInPhone(t *testing.T) {
+	cases := map[string]string{
+		"999 888 777":         "51999888777",
+		"+51 999-888-777":     "51999888777",
+		"(51) 999.888.777":    "51999888777",
+		"0051999888777":       "51999888777",
+		"14155550123":         "14155550123",
+		"123":                 "",
+		"sin teléfono":        "",
+		"9998887776665554443": "",
+	}
+	for in, want := range cases {
+		if got := checkInPhone(in); got != want {
+			t.Errorf("checkInPhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// ErrRecurrenceRuleInvalid is returned for recurrence rules outside the
+// supported FREQ/INTERVAL/UNTIL subset.
+var ErrRecurrenceRuleInvalid = errors.New("regla de recurrencia inválida; usa FREQ=DAILY|WEEKLY|MONTHLY;INTERVAL=n;UNTIL=AAAA-MM-DD")
+
+const (
+	maxRecurrenceInterval = 365
+	// maxRecurrenceSteps bounds the catch-up loop when a series lapsed for a
+	// long time; past it the series is treated as ended.
+	maxRecurrenceSteps = 5000
+	recurrenceBatch    = 50
+)
+
+// eventRecurrence is a parsed recurrence rule.
+type eventRecurrence struct {
+	freq     string // DAILY, WEEKLY, MONTHLY
+	interval int
+	until    *time.Time // last day (inclusive) an occurrence may fall on
+}
+
+var recurrenceShorthands = map[string]string{
+	"daily":    "FREQ=DAILY",
+	"weekly":   "FREQ=WEEKLY",
+	"biweekly": "FREQ=WEEKLY;INTERVAL=2",
+	"monthly":  "FREQ=MONTHLY",
+}
+
+// parseEventRecurrence parses an RRULE-style rule such as
+// "FREQ=WEEKLY;INTERVAL=2;UNTIL=2026-12-31", or one of the shorthands daily,
+// weekly, biweekly and monthly.
+func parseEventRecurrence(rule string) (eventRecurrence, error) {
+	rule = strings.TrimSpace(rule)
+	if expanded, ok := recurrenceShorthands[strings.ToLower(rule)]; ok {
+		rule = expanded
+	}
+	rule = strings.TrimPrefix(strings.ToUpper(rule), "RRULE:")
+	rec := eventRecurrence{interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return eventRecurrence{}, ErrRecurrenceRuleInvalid
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY":
+				rec.freq = value
+			default:
+				return eventRecurrence{}, ErrRecurrenceRuleInvalid
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxRecurrenceInterval {
+				return eventRecurrence{}, ErrRecurrenceRuleInvalid
+			}
+			rec.interval = n
+		case "UNTIL":
+			until, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				if until, err = time.Parse("20060102", value); err != nil {
+					return eventRecurrence{}, ErrRecurrenceRuleInvalid
+				}
+			}
+			rec.until = &until
+		default:
+			return eventRecurrence{}, ErrRecurrenceRuleInvalid
+		}
+	}
+	if rec.freq == "" {
+		return eventRecurrence{}, ErrRecurrenceRuleInvalid
+	}
+	return rec, nil
+}
+
+// String returns the canonical form of the rule, as stored.
+func (r eventRecurrence) String() string {
+	rule := "FREQ=" + r.freq + ";INTERVAL=" + strconv.Itoa(r.interval)
+	if r.until != nil {
+		rule += ";UNTIL=" + r.until.Format("2006-01-02")
+	}
+	return rule
+}
+
+func (r eventRecurrence) step(t time.Time) time.Time {
+	switch r.freq {
+	case "DAILY":
+		return t.AddDate(0, 0, r.interval)
+	case "WEEKLY":
+		return t.AddDate(0, 0, 7*r.interval)
+	default:
+		return t.AddDate(0, r.interval, 0)
+	}
+}
+
+// next returns the first occurrence after now that follows prev in the
+// series, skipping occurrences that already passed. It reports false when
+// the series has ended.
+func (r eventRecurrence) next(prev, now time.Time) (time.Time, bool) {
+	next := r.step(prev)
+	for i := 0; !next.After(now); i++ {
+		if i >= maxRecurrenceSteps {
+			return time.Time{}, false
+		}
+		next = r.step(next)
+	}
+	if r.until != nil && next.After(r.until.AddDate(0, 0, 1)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// NormalizeRecurrenceRule validates rule and returns its canonical form; an
+// empty rule means no recurrence.
+func NormalizeRecurrenceRule(rule string) (string, error) {
+	if strings.TrimSpace(rule) == "" {
+		return "", nil
+	}
+	rec, err := parseEventRecurrence(rule)
+	if err != nil {
+		return "", err
+	}
+	return rec.String(), nil
+}
+
+// SetRecurrence stores a validated recurrence rule on an event.
+func (s *EventService) SetRecurrence(ctx context.Context, eventID, accountID uuid.UUID, rule string, copyParticipants bool) (string, error) {
+	normalized, err := NormalizeRecurrenceRule(rule)
+	if err != nil {
+		return "", err
+	}
+	return normalized, s.repos.Event.SetRecurrence(ctx, eventID, accountID, normalized, copyParticipants)
+}
+
+// MaterializeRecurrences creates the next occurrence of every recurring event
+// whose date has passed. Only the single next occurrence is created; it
+// carries the rule forward and produces its own successor once it passes. It
+// returns the accounts that got new events.
+func (s *EventService) MaterializeRecurrences(ctx context.Context) []uuid.UUID {
+	var accounts []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for {
+		due, err := s.repos.Event.ClaimDueRecurrences(ctx, recurrenceBatch)
+		if err != nil {
+			log.Printf("[EVENT] Error claiming recurring events: %v", err)
+			return accounts
+		}
+		succeeded := 0
+		for _, prev := range due {
+			next, err := s.materializeNextOccurrence(ctx, prev, time.Now())
+			if err != nil {
+				log.Printf("[EVENT] Recurring event %s: %v", prev.ID, err)
+				if releaseErr := s.repos.Event.ReleaseRecurrenceClaim(ctx, prev.ID); releaseErr != nil {
+					log.Printf("[EVENT] Error releasing recurring event %s: %v", prev.ID, releaseErr)
+				}
+				continue
+			}
+			succeeded++
+			if next != nil && !seen[next.AccountID] {
+				seen[next.AccountID] = true
+				accounts = append(accounts, next.AccountID)
+			}
+		}
+		// A batch that failed entirely was released and would be claimed
+		// again right away; leave it for the next run.
+		if len(due) < recurrenceBatch || succeeded == 0 {
+			return accounts
+		}
+	}
+}
+
+// materializeNextOccurrence creates the occurrence after prev, or returns nil
+// when the series has ended or its rule no longer parses.
+func (s *EventService) materializeNextOccurrence(ctx context.Context, prev *domain.Event, now time.Time) (*domain.Event, error) {
+	rec, err := parseEventRecurrence(prev.RecurrenceRule)
+	if err != nil {
+		log.Printf("[EVENT] Recurring event %s has an invalid rule %q; series stopped", prev.ID, prev.RecurrenceRule)
+		return nil, nil
+	}
+	nextDate, ok := rec.next(*prev.EventDate, now)
+	if !ok {
+		return nil, nil
+	}
+	parentID := prev.ID
+	next := &domain.Event{
+		AccountID:                  prev.AccountID,
+		FolderID:                   prev.FolderID,
+		PipelineID:                 prev.PipelineID,
+		Name:                       prev.Name,
+		Description:                prev.Description,
+		EventDate:                  &nextDate,
+		Location:                   prev.Location,
+		Status:                     domain.EventStatusActive,
+		Color:                      prev.Color,
+		TagFormulaMode:             prev.TagFormulaMode,
+		TagFormula:                 prev.TagFormula,
+		TagFormulaType:             prev.TagFormulaType,
+		RecurrenceRule:             prev.RecurrenceRule,
+		RecurrenceCopyParticipants: prev.RecurrenceCopyParticipants,
+		RecurrenceParentID:         &parentID,
+		CreatedBy:                  prev.CreatedBy,
+	}
+	if prev.EventEnd != nil {
+		end := nextDate.Add(prev.EventEnd.Sub(*prev.EventDate))
+		next.EventEnd = &end
+	}
+	if err := s.Create(ctx, next); err != nil {
+		return nil, fmt.Errorf("create next occurrence: %w", err)
+	}
+	if err := s.repos.Event.CopyEventTags(ctx, prev.ID, next.ID); err != nil {
+		log.Printf("[EVENT] Error copying tags from %s to %s: %v", prev.ID, next.ID, err)
+	}
+	if err := s.repos.Event.LinkRecurrence(ctx, prev.ID, next.ID); err != nil {
+		log.Printf("[EVENT] Error linking occurrence %s to %s: %v", prev.ID, next.ID, err)
+	}
+	if prev.RecurrenceCopyParticipants {
+		if _, err := s.repos.Event.CopyRecurringParticipants(ctx, prev.ID, next.ID); err != nil {
+			log.Printf("[EVENT] Error copying participants from %s to %s: %v", prev.ID, next.ID, err)
+		}
+	}
+	return next, nil
+}
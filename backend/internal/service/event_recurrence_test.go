@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeRecurrenceRule(t *testing.T) {
+	cases := map[string]string{
+		"":                              "",
+		"weekly":                        "FREQ=WEEKLY;INTERVAL=1",
+		"Biweekly":                      "FREQ=WEEKLY;INTERVAL=2",
+		"RRULE:FREQ=MONTHLY;INTERVAL=3": "FREQ=MONTHLY;INTERVAL=3",
+		"freq=daily;until=20261231":     "FREQ=DAILY;INTERVAL=1;UNTIL=2026-12-31",
+		"FREQ=WEEKLY;UNTIL=2026-01-31":  "FREQ=WEEKLY;INTERVAL=1;UNTIL=2026-01-31",
+	}
+	for in, want := range cases {
+		got, err := NormalizeRecurrenceRule(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeRecurrenceRule(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"FREQ=YEARLY", "INTERVAL=2", "FREQ=WEEKLY;INTERVAL=0", "FREQ=WEEKLY;BYDAY=MO", "sometimes"} {
+		if _, err := NormalizeRecurrenceRule(bad); err == nil {
+			t.Errorf("NormalizeRecurrenceRule(%q) accepted an invalid rule", bad)
+		}
+	}
+}
+
+func TestRecurrenceNextSkipsPassedOccurrences(t *testing.T) {
+	rec, err := parseEventRecurrence("weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := time.Date(2026, 3, 2, 19, 0, 0, 0, time.UTC)
+
+	next, ok := rec.next(prev, prev.Add(time.Hour))
+	if !ok || !next.Equal(prev.AddDate(0, 0, 7)) {
+		t.Fatalf("next = %v, %v; want one week later", next, ok)
+	}
+
+	// A series that lapsed for five weeks resumes at the first future date
+	// instead of creating the missed occurrences.
+	now := prev.AddDate(0, 0, 33)
+	next, ok = rec.next(prev, now)
+	if !ok || !next.Equal(prev.AddDate(0, 0, 35)) {
+		t.Fatalf("next = %v, %v; want five weeks later", next, ok)
+	}
+}
+
+func TestRecurrenceNextStopsAfterUntil(t *testing.T) {
+	rec, err := parseEventRecurrence("FREQ=WEEKLY;UNTIL=2026-03-09")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := time.Date(2026, 3, 2, 19, 0, 0, 0, time.UTC)
+	if next, ok := rec.next(prev, prev); !ok || next.Day() != 9 {
+		t.Fatalf("occurrence on the until day should be created, got %v, %v", next, ok)
+	}
+	if _, ok := rec.next(prev.AddDate(0, 0, 7), prev.AddDate(0, 0, 7)); ok {
+		t.Fatal("occurrence after until was created")
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewEventStatsRates(t *testing.T) {
+	counts := map[string]int{"invited": 4, "confirmed": 3, "attended": 2, "no_show": 1}
+	stats := newEventStats(uuid.New(), counts, 10)
+	if stats.ConfirmationRate != 50 {
+		t.Errorf("ConfirmationRate = %v, want 50", stats.ConfirmationRate)
+	}
+	if stats.AttendanceRate != float64(2)/6*100 {
+		t.Errorf("AttendanceRate = %v, want %v", stats.AttendanceRate, float64(2)/6*100)
+	}
+	if stats.Invited != 4 || stats.NoShow != 1 || stats.Declined != 0 {
+		t.Errorf("status counts = %+v", stats)
+	}
+
+	empty := newEventStats(uuid.New(), map[string]int{}, 0)
+	if empty.ConfirmationRate != 0 || empty.AttendanceRate != 0 || empty.Tags == nil {
+		t.Errorf("empty stats = %+v", empty)
+	}
+}
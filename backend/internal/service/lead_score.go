@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
+)
+
+const (
+	maxLeadScoringRules   = 50
+	maxLeadScoringPoints  = 1000
+	leadScoreInteractions = 200
+)
+
+// DefaultLeadScoringRules is used for accounts that never saved their own rules.
+func DefaultLeadScoringRules() []domain.LeadScoringRule {
+	return []domain.LeadScoringRule{
+		{Type: domain.LeadScoreRuleHasEmail, Points: 10},
+		{Type: domain.LeadScoreRuleRepliedWithinDays, Days: 7, Points: 20},
+		{Type: domain.LeadScoreRuleHasTag, Value: "hot", Points: 30},
+	}
+}
+
+// ValidateLeadScoringRules rejects unknown rule types and out-of-range values.
+func ValidateLeadScoringRules(rules []domain.LeadScoringRule) error {
+	if len(rules) > maxLeadScoringRules {
+		return fmt.Errorf("at most %d scoring rules are allowed", maxLeadScoringRules)
+	}
+	for i, r := range rules {
+		if r.Points < -maxLeadScoringPoints || r.Points > maxLeadScoringPoints {
+			return fmt.Errorf("rule %d: points must be between -%d and %d", i+1, maxLeadScoringPoints, maxLeadScoringPoints)
+		}
+		switch r.Type {
+		case domain.LeadScoreRuleHasEmail, domain.LeadScoreRuleHasPhone:
+		case domain.LeadScoreRuleHasTag, domain.LeadScoreRuleStatus:
+			if strings.TrimSpace(r.Value) == "" {
+				return fmt.Errorf("rule %d: value is required for %s", i+1, r.Type)
+			}
+		case domain.LeadScoreRuleRepliedWithinDays, domain.LeadScoreRuleInteractionWithinDays:
+			if r.Days <= 0 {
+				return fmt.Errorf("rule %d: days must be positive for %s", i+1, r.Type)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown type %q", i+1, r.Type)
+		}
+	}
+	return nil
+}
+
+// ScoreLead sums the points of every rule the lead matches. Interactions
+// with direction "inbound" count as replies from the lead.
+func ScoreLead(rules []domain.LeadScoringRule, lead *domain.Lead, interactions []*domain.Interaction, now time.Time) int {
+	if lead == nil {
+		return 0
+	}
+	tags := make(map[string]bool, len(lead.Tags)+len(lead.StructuredTags))
+	for _, t := range lead.Tags {
+		tags[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+	for _, t := range lead.StructuredTags {
+		if t != nil {
+			tags[strings.ToLower(strings.TrimSpace(t.Name))] = true
+		}
+	}
+
+	score := 0
+	for _, r := range rules {
+		if leadMatchesScoringRule(r, lead, tags, interactions, now) {
+			score += r.Points
+		}
+	}
+	return score
+}
+
+func leadMatchesScoringRule(r domain.LeadScoringRule, lead *domain.Lead, tags map[string]bool, interactions []*domain.Interaction, now time.Time) bool {
+	switch r.Type {
+	case domain.LeadScoreRuleHasEmail:
+		return lead.Email != nil && strings.TrimSpace(*lead.Email) != ""
+	case domain.LeadScoreRuleHasPhone:
+		return lead.Phone != nil && strings.TrimSpace(*lead.Phone) != ""
+	case domain.LeadScoreRuleHasTag:
+		return tags[strings.ToLower(strings.TrimSpace(r.Value))]
+	case domain.LeadScoreRuleStatus:
+		return lead.Status != nil && strings.EqualFold(*lead.Status, r.Value)
+	case domain.LeadScoreRuleRepliedWithinDays, domain.LeadScoreRuleInteractionWithinDays:
+		since := now.AddDate(0, 0, -r.Days)
+		for _, it := range interactions {
+			if it == nil || it.CreatedAt.Before(since) {
+				continue
+			}
+			if r.Type == domain.LeadScoreRuleInteractionWithinDays {
+				return true
+			}
+			if it.Direction != nil && *it.Direction == "inbound" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ScoringRules returns the account's rules, falling back to the defaults.
+func (s *LeadService) ScoringRules(ctx context.Context, accountID uuid.UUID) ([]domain.LeadScoringRule, error) {
+	rules, err := s.repos.Lead.GetScoringRules(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		return DefaultLeadScoringRules(), nil
+	}
+	return rules, nil
+}
+
+// SetScoringRules saves the account's rules and rescores its leads in the
+// background.
+func (s *LeadService) SetScoringRules(ctx context.Context, accountID uuid.UUID, rules []domain.LeadScoringRule) error {
+	if err := ValidateLeadScoringRules(rules); err != nil {
+		return err
+	}
+	if err := s.repos.Lead.UpsertScoringRules(ctx, accountID, rules); err != nil {
+		return err
+	}
+	go func() {
+		bg, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		s.rescoreAccount(bg, accountID, rules)
+	}()
+	return nil
+}
+
+// rescoreAccount recalculates every live lead of the account with rules.
+func (s *LeadService) rescoreAccount(ctx context.Context, accountID uuid.UUID, rules []domain.LeadScoringRule) {
+	ids, err := s.repos.Lead.ListLiveIDs(ctx, accountID)
+	if err != nil {
+		log.Printf("[LeadScore] list leads for account %s: %v", accountID, err)
+		return
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := recalculateLeadScore(ctx, s.repos, id, rules); err != nil {
+			log.Printf("[LeadScore] recalculate lead %s: %v", id, err)
+		}
+	}
+}
+
+// hasTimeWindowRule reports whether any rule depends on how recent the
+// lead's activity is, so its points change with time alone.
+func hasTimeWindowRule(rules []domain.LeadScoringRule) bool {
+	for _, r := range rules {
+		if r.Type == domain.LeadScoreRuleRepliedWithinDays || r.Type == domain.LeadScoreRuleInteractionWithinDays {
+			return true
+		}
+	}
+	return false
+}
+
+// RecomputeTimeWindowScores rescores the leads of every account whose rules
+// count recent activity. Edits, interactions and messages already rescore a
+// lead, but nothing does when a reply simply ages out of its window.
+func (s *LeadService) RecomputeTimeWindowScores(ctx context.Context) {
+	accountIDs, err := s.repos.Lead.ListAccountsWithLiveLeads(ctx)
+	if err != nil {
+		log.Printf("[LeadScore] list accounts: %v", err)
+		return
+	}
+	for _, accountID := range accountIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		rules, err := s.ScoringRules(ctx, accountID)
+		if err != nil {
+			log.Printf("[LeadScore] load rules for account %s: %v", accountID, err)
+			continue
+		}
+		if hasTimeWindowRule(rules) {
+			s.rescoreAccount(ctx, accountID, rules)
+		}
+	}
+}
+
+// RecalculateScore re-evaluates the account's rules for one lead and stores
+// the result.
+func (s *LeadService) RecalculateScore(ctx context.Context, leadID uuid.UUID) (int, error) {
+	return recalculateLeadScore(ctx, s.repos, leadID, nil)
+}
+
+// recalculateLeadScore loads the lead's tags, interactions and last inbound
+// WhatsApp message and stores the new score. A nil rules slice loads the
+// account's rules.
+func recalculateLeadScore(ctx context.Context, repos *repository.Repositories, leadID uuid.UUID, rules []domain.LeadScoringRule) (int, error) {
+	lead, err := repos.Lead.GetByID(ctx, leadID)
+	if err != nil {
+		return 0, err
+	}
+	if lead == nil || lead.DeletedAt != nil {
+		return 0, nil
+	}
+	if rules == nil {
+		if rules, err = repos.Lead.GetScoringRules(ctx, lead.AccountID); err != nil {
+			return 0, err
+		}
+		if rules == nil {
+			rules = DefaultLeadScoringRules()
+		}
+	}
+	if tags, err := repos.Tag.GetByLead(ctx, leadID); err == nil {
+		lead.StructuredTags = tags
+	}
+	interactions, err := repos.Interaction.GetByLeadID(ctx, leadID, leadScoreInteractions, 0)
+	if err != nil {
+		return 0, err
+	}
+	// WhatsApp replies live in messages, not interactions; fold the most
+	// recent one in as an inbound interaction.
+	if at, err := repos.Lead.LastInboundMessageAt(ctx, lead.AccountID, leadID); err == nil && at != nil {
+		inbound := "inbound"
+		interactions = append(interactions, &domain.Interaction{Type: domain.InteractionTypeWhatsApp, Direction: &inbound, CreatedAt: *at})
+	}
+
+	score := ScoreLead(rules, lead, interactions, time.Now())
+	if err := repos.Lead.SetScore(ctx, leadID, score); err != nil {
+		return 0, err
+	}
+	return score, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestScoreLeadDefaultRules(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	email := "ana@example.com"
+	inbound := "inbound"
+	outbound := "outbound"
+
+	tests := []struct {
+		name         string
+		lead         *domain.Lead
+		interactions []*domain.Interaction
+		want         int
+	}{
+		{name: "empty lead", lead: &domain.Lead{}, want: 0},
+		{name: "email only", lead: &domain.Lead{Email: &email}, want: 10},
+		{
+			name: "hot tag matches case-insensitively on structured tags",
+			lead: &domain.Lead{StructuredTags: []*domain.Tag{{Name: "HOT"}}},
+			want: 30,
+		},
+		{
+			name:         "recent inbound reply",
+			lead:         &domain.Lead{},
+			interactions: []*domain.Interaction{{Direction: &inbound, CreatedAt: now.AddDate(0, 0, -3)}},
+			want:         20,
+		},
+		{
+			name: "old or outbound interactions do not count as replies",
+			lead: &domain.Lead{},
+			interactions: []*domain.Interaction{
+				{Direction: &inbound, CreatedAt: now.AddDate(0, 0, -8)},
+				{Direction: &outbound, CreatedAt: now.AddDate(0, 0, -1)},
+			},
+			want: 0,
+		},
+		{
+			name:         "all rules",
+			lead:         &domain.Lead{Email: &email, Tags: []string{"hot"}},
+			interactions: []*domain.Interaction{{Direction: &inbound, CreatedAt: now}},
+			want:         60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScoreLead(DefaultLeadScoringRules(), tt.lead, tt.interactions, now); got != tt.want {
+				t.Fatalf("ScoreLead() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreLeadCustomRules(t *testing.T) {
+	now := time.Now()
+	phone := "51999888777"
+	won := "won"
+	rules := []domain.LeadScoringRule{
+		{Type: domain.LeadScoreRuleHasPhone, Points: 5},
+		{Type: domain.LeadScoreRuleStatus, Value: "WON", Points: 50},
+		{Type: domain.LeadScoreRuleInteractionWithinDays, Days: 2, Points: 15},
+		{Type: domain.LeadScoreRuleHasTag, Value: "cold", Points: -40},
+	}
+	lead := &domain.Lead{Phone: &phone, Status: &won, Tags: []string{"cold"}}
+	interactions := []*domain.Interaction{{Type: domain.InteractionTypeCall, CreatedAt: now.Add(-time.Hour)}}
+
+	if got := ScoreLead(rules, lead, interactions, now); got != 30 {
+		t.Fatalf("ScoreLead() = %d, want 30", got)
+	}
+}
+
+func TestValidateLeadScoringRules(t *testing.T) {
+	if err := ValidateLeadScoringRules(DefaultLeadScoringRules()); err != nil {
+		t.Fatalf("default rules rejected: %v", err)
+	}
+	invalid := [][]domain.LeadScoringRule{
+		{{Type: "unknown", Points: 1}},
+		{{Type: domain.LeadScoreRuleHasTag, Points: 1}},
+		{{Type: domain.LeadScoreRuleRepliedWithinDays, Points: 1}},
+		{{Type: domain.LeadScoreRuleHasEmail, Points: 5000}},
+		make([]domain.LeadScoringRule, maxLeadScoringRules+1),
+	}
+	for i, rules := range invalid {
+		if err := ValidateLeadScoringRules(rules); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestHasTimeWindowRule(t *testing.T) {
+	if !hasTimeWindowRule(DefaultLeadScoringRules()) {
+		t.Fatal("default rules count recent replies")
+	}
+	static := []domain.LeadScoringRule{{Type: domain.LeadScoreRuleHasEmail, Points: 10}, {Type: domain.LeadScoreRuleHasTag, Value: "vip", Points: 5}}
+	if hasTimeWindowRule(static) {
+		t.Fatal("rules without a day window never decay")
+	}
+}
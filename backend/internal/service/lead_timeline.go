@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+const (
+	leadTimelineDefaultLimit = 50
+	leadTimelineMaxLimit     = 200
+)
+
+// LeadTimelinePage is one page of a lead's activity feed. NextBefore and
+// NextBeforeID are the cursor for the following page, nil when there is
+// nothing older.
+type LeadTimelinePage struct {
+	Items        []*domain.LeadTimelineItem `json:"items"`
+	NextBefore   *time.Time                 `json:"next_before,omitempty"`
+	NextBeforeID *uuid.UUID                 `json:"next_before_id,omitempty"`
+}
+
+// Timeline merges the lead's interactions, WhatsApp messages and stage
+// changes into one feed ordered by (time, id), newest first, starting after
+// the (before, beforeID) cursor. The id breaks ties, so items sharing a
+// timestamp are neither repeated nor skipped across pages. A zero before
+// starts at the newest item.
+func (s *LeadService) Timeline(ctx context.Context, accountID, leadID uuid.UUID, before time.Time, beforeID uuid.UUID, limit int) (*LeadTimelinePage, error) {
+	if limit <= 0 {
+		limit = leadTimelineDefaultLimit
+	}
+	if limit > leadTimelineMaxLimit {
+		limit = leadTimelineMaxLimit
+	}
+	if before.IsZero() {
+		before, beforeID = time.Now().Add(time.Minute), uuid.Max
+	}
+	// Each source is asked for one extra row so we know whether an older
+	// page exists once the three are merged.
+	interactions, err := s.repos.Interaction.ListByLeadBefore(ctx, accountID, leadID, before, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.repos.Message.ListForLeadBefore(ctx, accountID, leadID, before, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.repos.Lead.ListStageChanges(ctx, accountID, leadID, before, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return mergeLeadTimeline(interactions, messages, changes, limit), nil
+}
+
+func mergeLeadTimeline(interactions []*domain.Interaction, messages []*domain.Message, changes []*domain.LeadStageChange, limit int) *LeadTimelinePage {
+	items := make([]*domain.LeadTimelineItem, 0, len(interactions)+len(messages)+len(changes))
+	for _, it := range interactions {
+		items = append(items, &domain.LeadTimelineItem{Type: domain.LeadTimelineInteraction, ID: it.ID, At: it.CreatedAt, Interaction: it})
+	}
+	for _, msg := range messages {
+		items = append(items, &domain.LeadTimelineItem{Type: domain.LeadTimelineMessage, ID: msg.ID, At: msg.Timestamp, Message: msg})
+	}
+	for _, sc := range changes {
+		items = append(items, &domain.LeadTimelineItem{Type: domain.LeadTimelineStageChange, ID: sc.ID, At: sc.CreatedAt, StageChange: sc})
+	}
+	// Same order as the queries: uuid comparison in Postgres is bytewise.
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].At.Equal(items[j].At) {
+			return items[i].At.After(items[j].At)
+		}
+		return bytes.Compare(items[i].ID[:], items[j].ID[:]) > 0
+	})
+
+	page := &LeadTimelinePage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		next, nextID := last.At, last.ID
+		page.NextBefore, page.NextBeforeID = &next, &nextID
+	}
+	return page
+}
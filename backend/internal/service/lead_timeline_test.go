@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestMergeLeadTimelineSortsAndPages(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	interactions := []*domain.Interaction{{CreatedAt: base.Add(3 * time.Minute)}, {CreatedAt: base}}
+	messages := []*domain.Message{{Timestamp: base.Add(4 * time.Minute)}, {Timestamp: base.Add(time.Minute)}}
+	changes := []*domain.LeadStageChange{{ID: uuid.New(), CreatedAt: base.Add(2 * time.Minute)}}
+
+	page := mergeLeadTimeline(interactions, messages, changes, 3)
+	want := []string{domain.LeadTimelineMessage, domain.LeadTimelineInteraction, domain.LeadTimelineStageChange}
+	if len(page.Items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(page.Items), len(want))
+	}
+	for i, item := range page.Items {
+		if item.Type != want[i] {
+			t.Fatalf("item %d type = %s, want %s", i, item.Type, want[i])
+		}
+	}
+	if page.NextBefore == nil || !page.NextBefore.Equal(base.Add(2*time.Minute)) || page.NextBeforeID == nil || *page.NextBeforeID != changes[0].ID {
+		t.Fatalf("next cursor = %v %v", page.NextBefore, page.NextBeforeID)
+	}
+
+	last := mergeLeadTimeline(interactions, messages, changes, 10)
+	if len(last.Items) != 5 || last.NextBefore != nil {
+		t.Fatalf("expected a single final page, got %d items cursor %v", len(last.Items), last.NextBefore)
+	}
+}
+
+func TestMergeLeadTimelineBreaksTimestampTiesByID(t *testing.T) {
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	low := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	high := uuid.MustParse("ffffffff-0000-0000-0000-000000000000")
+	interactions := []*domain.Interaction{{ID: low, CreatedAt: at}}
+	messages := []*domain.Message{{ID: high, Timestamp: at}}
+
+	page := mergeLeadTimeline(interactions, messages, nil, 1)
+	if len(page.Items) != 1 || page.Items[0].ID != high {
+		t.Fatalf("first item = %+v, want the higher id", page.Items)
+	}
+	if page.NextBefore == nil || !page.NextBefore.Equal(at) || page.NextBeforeID == nil || *page.NextBeforeID != high {
+		t.Fatalf("next cursor = %v %v, want the shared timestamp and the higher id", page.NextBefore, page.NextBeforeID)
+	}
+}
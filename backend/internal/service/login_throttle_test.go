@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLoginLockoutFallsBackToMemory(t *testing.T) {
+	ctx := context.Background()
+	auth := &AuthService{}
+	for i := 0; i < maxLoginAttempts-1; i++ {
+		auth.recordLoginFailure(ctx, "Ana")
+	}
+	if auth.loginLocked(ctx, "ana") {
+		t.Fatal("locked before reaching the attempt limit")
+	}
+	auth.recordLoginFailure(ctx, " ANA ")
+	if !auth.loginLocked(ctx, "ana") {
+		t.Fatal("expected lockout after max failed attempts, case-insensitive")
+	}
+	if auth.loginLocked(ctx, "bob") {
+		t.Fatal("lockout must be per username")
+	}
+	auth.clearLoginFailures(ctx, "ana")
+	if auth.loginLocked(ctx, "ana") {
+		t.Fatal("successful login must reset the counter")
+	}
+}
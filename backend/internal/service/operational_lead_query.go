@@ -411,7 +411,7 @@ func buildOperationalLeadBaseSQL(filters OperationalLeadFilters, cursor *operati
 		if *filters.Deleted {
 			b.where = append(b.where, "l.deleted_at IS NOT NULL")
 		} else {
-			b.where = append(b.where, "l.deleted_at IS NULL")
+			b.where = append(b.where, repository.NotDeleted("l"))
 		}
 	}
 	if filters.Contactable != nil {
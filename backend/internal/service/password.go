@@ -2,12 +2,54 @@ package service
 
 import (
 	"fmt"
+	"strings"
 	"unicode"
 )
 
+// PasswordPolicy is the strength policy applied to every password set through
+// the API: login passwords, admin resets, user creation and registration.
+type PasswordPolicy struct {
+	MinLength int
+	// RequireClasses asks for an uppercase and a lowercase letter, a digit
+	// and a symbol.
+	RequireClasses bool
+	// RejectCommon refuses well-known passwords, including their variants
+	// with leetspeak or trailing digits/symbols ("P@ssw0rd2024!").
+	RejectCommon bool
+}
+
+// DefaultPasswordPolicy is used until SetPasswordPolicy is called.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 10, RequireClasses: true, RejectCommon: true}
+
+var passwordPolicy = DefaultPasswordPolicy
+
+// SetPasswordPolicy replaces the policy checked by ValidateStrongPassword. It
+// is meant to be called once at startup; a MinLength below 8 is raised to 8.
+func SetPasswordPolicy(p PasswordPolicy) {
+	if p.MinLength < 8 {
+		p.MinLength = 8
+	}
+	passwordPolicy = p
+}
+
+// ValidateStrongPassword checks password against the configured policy.
 func ValidateStrongPassword(password string) error {
-	if len(password) < 10 {
-		return fmt.Errorf("la contraseña debe tener al menos 10 caracteres")
+	return passwordPolicy.Validate(password)
+}
+
+// Validate returns an error naming the first rule password breaks.
+func (p PasswordPolicy) Validate(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("la contraseña debe tener al menos %d caracteres", p.MinLength)
+	}
+	if strings.TrimFunc(password, unicode.IsDigit) == "" {
+		return fmt.Errorf("la contraseña no puede contener solo números")
+	}
+	if p.RejectCommon && isCommonPassword(password) {
+		return fmt.Errorf("la contraseña es demasiado común o fácil de adivinar")
+	}
+	if !p.RequireClasses {
+		return nil
 	}
 	var hasUpper, hasLower, hasDigit, hasSymbol bool
 	for _, r := range password {
@@ -22,8 +64,63 @@ func ValidateStrongPassword(password string) error {
 			hasSymbol = true
 		}
 	}
-	if !hasUpper || !hasLower || !hasDigit || !hasSymbol {
-		return fmt.Errorf("usa una contraseña fuerte con mayúscula, minúscula, número y símbolo")
+	var missing []string
+	if !hasUpper {
+		missing = append(missing, "una mayúscula")
+	}
+	if !hasLower {
+		missing = append(missing, "una minúscula")
+	}
+	if !hasDigit {
+		missing = append(missing, "un número")
+	}
+	if !hasSymbol {
+		missing = append(missing, "un símbolo")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("la contraseña debe incluir %s", strings.Join(missing, ", "))
 	}
 	return nil
 }
+
+// commonPasswords holds the normalized base of frequently used passwords.
+var commonPasswords = map[string]bool{
+	"password": true, "passw": true, "pass": true, "contrasena": true, "contraseña": true,
+	"clave": true, "secreto": true, "admin": true, "administrador": true, "administrator": true,
+	"root": true, "qwerty": true, "qwertyuiop": true, "asdfgh": true, "asdfghjkl": true,
+	"zxcvbn": true, "abc": true, "abcdef": true, "abcdefgh": true, "letmein": true,
+	"welcome": true, "bienvenido": true, "iloveyou": true, "teamo": true, "monkey": true,
+	"dragon": true, "master": true, "sunshine": true, "princess": true, "football": true,
+	"futbol": true, "superman": true, "batman": true, "trustno": true, "changeme": true,
+	"default": true, "usuario": true, "user": true, "test": true, "prueba": true,
+	"hola": true, "holamundo": true, "clarin": true, "peru": true, "lima": true,
+}
+
+var leetReplacer = strings.NewReplacer("@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t")
+
+// isCommonPassword reports whether password is a common password, a single
+// repeated character or a digit/keyboard sequence, once lowercased, stripped
+// of leading/trailing digits and symbols and de-leeted.
+func isCommonPassword(password string) bool {
+	lower := strings.ToLower(strings.TrimSpace(password))
+	if isRepeatedOrSequential(lower) {
+		return true
+	}
+	base := strings.TrimFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	base = leetReplacer.Replace(base)
+	return base == "" || commonPasswords[base]
+}
+
+func isRepeatedOrSequential(s string) bool {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return true
+	}
+	repeated, ascending, descending := true, true, true
+	for i := 1; i < len(runes); i++ {
+		repeated = repeated && runes[i] == runes[0]
+		ascending = ascending && runes[i] == runes[i-1]+1
+		descending = descending && runes[i] == runes[i-1]-1
+	}
+	return repeated || ascending || descending
+}
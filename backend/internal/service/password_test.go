@@ -0,0 +1,40 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyNamesTheFailedRule(t *testing.T) {
+	cases := []struct {
+		password string
+		wantErr  string
+	}{
+		{"Ab1!", "al menos 10 caracteres"},
+		{"12345678901", "solo números"},
+		{"P@ssw0rd2024!", "demasiado común"},
+		{"Clarin2026!!", "demasiado común"},
+		{"aaaaaaaaaaaa", "demasiado común"},
+		{"correct horse battery", "una mayúscula, un número"},
+		{"Tranquilo-Rio-47", ""},
+	}
+	for _, tc := range cases {
+		err := DefaultPasswordPolicy.Validate(tc.password)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Fatalf("Validate(%q) = %v, want nil", tc.password, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Fatalf("Validate(%q) = %v, want error containing %q", tc.password, err, tc.wantErr)
+		}
+	}
+}
+
+func TestPasswordPolicyRulesAreConfigurable(t *testing.T) {
+	relaxed := PasswordPolicy{MinLength: 8}
+	if err := relaxed.Validate("correct horse"); err != nil {
+		t.Fatalf("relaxed policy rejected a long passphrase: %v", err)
+	}
+	if err := relaxed.Validate("password"); err != nil {
+		t.Fatalf("common passwords are allowed when RejectCommon is off: %v", err)
+	}
+}
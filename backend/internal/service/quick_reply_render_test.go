@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestQuickReplyRenderFillsContactVariables(t *testing.T) {
+	s := &QuickReplyService{}
+	reply := &domain.QuickReply{
+		Body: "Hola {{primer_nombre}} de {{empresa}}, {{desconocida}}listo",
+		Attachments: []domain.QuickReplyAttachment{
+			{MediaURL: "/api/media/file/x.jpg", Caption: "Para {{nombre}}"},
+		},
+	}
+	name, lastName, company, pushName := "Ana", "Ruiz", "Naperu", "ana.r"
+	contact := &domain.Contact{Name: &name, LastName: &lastName, Company: &company, PushName: &pushName}
+
+	got := s.Render(reply, contact)
+	if got.Body != "Hola Ana de Naperu, listo" {
+		t.Fatalf("body = %q", got.Body)
+	}
+	if got.Attachments[0].Caption != "Para Ana Ruiz" {
+		t.Fatalf("caption = %q", got.Attachments[0].Caption)
+	}
+	if reply.Body != "Hola {{primer_nombre}} de {{empresa}}, {{desconocida}}listo" || reply.Attachments[0].Caption != "Para {{nombre}}" {
+		t.Fatal("Render modified the stored quick reply")
+	}
+}
+
+func TestQuickReplyRenderWithoutContact(t *testing.T) {
+	got := (&QuickReplyService{}).Render(&domain.QuickReply{Body: "Hola {{nombre}}!"}, nil)
+	if got.Body != "Hola !" {
+		t.Fatalf("body = %q", got.Body)
+	}
+}
+
+func TestQuickReplyOwnershipRules(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	shared := &domain.QuickReply{}
+	personal := &domain.QuickReply{OwnerID: &owner}
+
+	if !QuickReplyVisible(shared, other) || !QuickReplyVisible(personal, owner) {
+		t.Fatal("shared replies and own personal replies must be visible")
+	}
+	if QuickReplyVisible(personal, other) {
+		t.Fatal("personal reply visible to another user")
+	}
+	if QuickReplyEditable(shared, other, false) {
+		t.Fatal("non-admin can edit a shared reply")
+	}
+	if !QuickReplyEditable(shared, other, true) {
+		t.Fatal("admin cannot edit a shared reply")
+	}
+	if !QuickReplyEditable(personal, owner, false) {
+		t.Fatal("owner cannot edit their personal reply")
+	}
+	if QuickReplyEditable(personal, other, true) {
+		t.Fatal("admin can edit another user's personal reply")
+	}
+}
@@ -46,6 +46,8 @@ type Services struct {
 	Task             *TaskService
 	DocumentTemplate *DocumentTemplateService
 	Report           *ReportService
+	AutoTag          *AutoTagService
+	Webhook          *WebhookService
 }
 
 func NewServices(repos *repository.Repositories, pool *whatsapp.DevicePool, hub *ws.Hub) *Services {
@@ -72,13 +74,16 @@ func NewServices(repos *repository.Repositories, pool *whatsapp.DevicePool, hub
 		Task:             NewTaskService(repos, hub),
 		DocumentTemplate: NewDocumentTemplateService(repos),
 		Report:           NewReportService(repos, pool),
+		AutoTag:          NewAutoTagService(repos),
+		Webhook:          NewWebhookService(repos),
 	}
 }
 
 // AuthService handles authentication
 type AuthService struct {
-	repos *repository.Repositories
-	cache *cache.Cache
+	repos         *repository.Repositories
+	loginFailures loginThrottle
+	cache         *cache.Cache
 }
 
 // SetCache injects the Redis cache into AuthService (for refresh tokens, blacklist, rate limiting)
@@ -97,8 +102,16 @@ const (
 	loginFailuresKeyPrefix = "loginfail:"       // Redis key prefix for login failures
 	userInvalidatedPrefix  = "userinv:"         // Redis key prefix for invalidated users
 	sessionKeyPrefix       = "session:"         // Redis key prefix for active login sessions
+	userSessionsKeyPrefix  = "usersessions:"    // Redis key prefix for the set of a user's session IDs
+	usedRefreshKeyPrefix   = "refreshused:"     // Redis key prefix for rotated (already used) refresh tokens
+	refreshReuseGrace      = 30 * time.Second   // Concurrent tabs may replay a token this soon after rotation
 )
 
+// ErrRefreshTokenReused is returned when a refresh token that was already
+// rotated is presented again. Every session of its user is revoked, since
+// only a copied token can be replayed after its rotation.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
 type JWTClaims struct {
 	UserID       uuid.UUID `json:"user_id"`
 	AccountID    uuid.UUID `json:"account_id"`
@@ -108,6 +121,9 @@ type JWTClaims struct {
 	IsSuperAdmin bool      `json:"is_super_admin"`
 	Role         string    `json:"role"`
 	Permissions  []string  `json:"permissions"`
+	// APIKeyID is set on claims built from an X-API-Key header; they carry
+	// exactly the key's role and never the creator's.
+	APIKeyID string `json:"api_key_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -119,6 +135,13 @@ type authSessionData struct {
 	LastSeen  int64  `json:"last_seen"`
 }
 
+// usedRefreshTokenData is kept for a rotated refresh token to detect replays.
+type usedRefreshTokenData struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	RotatedAt int64  `json:"rotated_at"`
+}
+
 type refreshTokenData struct {
 	UserID    string `json:"user_id"`
 	AccountID string `json:"account_id"`
@@ -132,24 +155,15 @@ func (s *AuthService) Login(ctx context.Context, username, password, jwtSecret s
 		return "", "", nil, nil, fmt.Errorf("session service unavailable")
 	}
 
-	// Check login rate limiting
-	if s.cache != nil {
-		failKey := loginFailuresKeyPrefix + strings.ToLower(username)
-		data, _ := s.cache.Get(ctx, failKey)
-		if data != nil {
-			var failures int
-			if err := json.Unmarshal(data, &failures); err == nil && failures >= maxLoginAttempts {
-				return "", "", nil, nil, fmt.Errorf("cuenta bloqueada temporalmente, intente en 15 minutos")
-			}
-		}
+	// Check login rate limiting. Unknown usernames are counted and locked the
+	// same way, so the lockout does not reveal which usernames exist.
+	if s.loginLocked(ctx, username) {
+		return "", "", nil, nil, fmt.Errorf("cuenta bloqueada temporalmente, intente en 15 minutos")
 	}
 
 	user, err := s.repos.User.GetByUsername(ctx, username)
-	if err != nil {
-		s.recordLoginFailure(ctx, username)
-		return "", "", nil, nil, fmt.Errorf("invalid credentials")
-	}
-	if user == nil {
+	if err != nil || user == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
 		s.recordLoginFailure(ctx, username)
 		return "", "", nil, nil, fmt.Errorf("invalid credentials")
 	}
@@ -160,10 +174,7 @@ func (s *AuthService) Login(ctx context.Context, username, password, jwtSecret s
 	}
 
 	// Clear login failures on success
-	if s.cache != nil {
-		failKey := loginFailuresKeyPrefix + strings.ToLower(username)
-		_ = s.cache.Del(ctx, failKey)
-	}
+	s.clearLoginFailures(ctx, username)
 
 	// Get user's account assignments
 	if err := s.repos.UserAccount.NormalizeForUser(ctx, user.ID); err != nil {
@@ -398,6 +409,7 @@ func (s *AuthService) Logout(ctx context.Context, claims *JWTClaims, refreshToke
 	}
 	if claims != nil && claims.SessionID != "" {
 		_ = s.cache.Del(ctx, sessionKeyPrefix+claims.SessionID)
+		_ = s.cache.SRem(ctx, userSessionsKeyPrefix+claims.UserID.String(), claims.SessionID)
 	}
 }
 
@@ -464,6 +476,9 @@ func (s *AuthService) RefreshToken(ctx context.Context, oldRefreshToken, jwtSecr
 	// Look up refresh token in Redis
 	data, err := s.cache.Get(ctx, refreshTokenKeyPrefix+oldRefreshToken)
 	if err != nil || data == nil {
+		if s.revokeReusedRefreshToken(ctx, oldRefreshToken) {
+			return "", "", ErrRefreshTokenReused
+		}
 		return "", "", fmt.Errorf("invalid or expired refresh token")
 	}
 
@@ -551,8 +566,10 @@ func (s *AuthService) RefreshToken(ctx context.Context, oldRefreshToken, jwtSecr
 		return "", "", fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	// Rotate refresh token: delete old, create new
+	// Rotate refresh token: delete old, remember it as used, create new
 	_ = s.cache.Del(ctx, refreshTokenKeyPrefix+oldRefreshToken)
+	usedJSON, _ := json.Marshal(usedRefreshTokenData{UserID: user.ID.String(), SessionID: rtData.SessionID, RotatedAt: time.Now().Unix()})
+	_ = s.cache.Set(ctx, usedRefreshKeyPrefix+oldRefreshToken, usedJSON, refreshTokenTTL)
 	newRefreshToken := uuid.New().String()
 	newRTData := refreshTokenData{
 		UserID:    user.ID.String(),
@@ -567,6 +584,31 @@ func (s *AuthService) RefreshToken(ctx context.Context, oldRefreshToken, jwtSecr
 	return tokenString, newRefreshToken, nil
 }
 
+// revokeReusedRefreshToken reports whether refreshToken was already rotated
+// and, past the grace window, revokes every session of its user: whoever
+// holds a copy may also have stolen the others.
+func (s *AuthService) revokeReusedRefreshToken(ctx context.Context, refreshToken string) bool {
+	data, _ := s.cache.Get(ctx, usedRefreshKeyPrefix+refreshToken)
+	if data == nil {
+		return false
+	}
+	var used usedRefreshTokenData
+	if err := json.Unmarshal(data, &used); err != nil || used.SessionID == "" {
+		return false
+	}
+	if time.Since(time.Unix(used.RotatedAt, 0)) < refreshReuseGrace {
+		return false
+	}
+	_ = s.cache.Del(ctx, sessionKeyPrefix+used.SessionID, usedRefreshKeyPrefix+refreshToken)
+	_ = s.cache.SRem(ctx, userSessionsKeyPrefix+used.UserID, used.SessionID)
+	revoked := 1
+	if userID, err := uuid.Parse(used.UserID); err == nil {
+		revoked += s.RevokeOtherSessions(ctx, userID, "")
+	}
+	log.Printf("[AUTH] refresh token reuse for user %s (session %s), %d sessions revoked", used.UserID, used.SessionID, revoked)
+	return true
+}
+
 func (s *AuthService) createSession(ctx context.Context, userID, accountID uuid.UUID, username string) (string, int64, error) {
 	if s.cache == nil {
 		return "", 0, fmt.Errorf("session service unavailable")
@@ -584,9 +626,45 @@ func (s *AuthService) createSession(ctx context.Context, userID, accountID uuid.
 	if err := s.cache.Set(ctx, sessionKeyPrefix+sessionID, raw, sessionIdleTTL); err != nil {
 		return "", 0, fmt.Errorf("failed to create session: %w", err)
 	}
+	// Index the session under its user so a password change can revoke it.
+	_ = s.cache.SAdd(ctx, userSessionsKeyPrefix+userID.String(), refreshTokenTTL, sessionID)
 	return sessionID, now, nil
 }
 
+// RevokeOtherSessions deletes every tracked session of a user except keepSessionID.
+// Access tokens and refresh tokens bound to a deleted session stop validating
+// immediately because both paths go through TouchSession. Returns how many
+// sessions were revoked.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, keepSessionID string) int {
+	if s.cache == nil {
+		return 0
+	}
+	setKey := userSessionsKeyPrefix + userID.String()
+	sessionIDs, err := s.cache.SMembers(ctx, setKey)
+	if err != nil {
+		log.Printf("[AUTH] failed to list sessions for user %s: %v", userID, err)
+		return 0
+	}
+	revoked := make([]string, 0, len(sessionIDs))
+	keys := make([]string, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		if sessionID == "" || sessionID == keepSessionID {
+			continue
+		}
+		revoked = append(revoked, sessionID)
+		keys = append(keys, sessionKeyPrefix+sessionID)
+	}
+	if len(keys) == 0 {
+		return 0
+	}
+	if err := s.cache.Del(ctx, keys...); err != nil {
+		log.Printf("[AUTH] failed to revoke sessions for user %s: %v", userID, err)
+		return 0
+	}
+	_ = s.cache.SRem(ctx, setKey, revoked...)
+	return len(revoked)
+}
+
 func (s *AuthService) TouchSession(ctx context.Context, sessionID string) (*authSessionData, error) {
 	if s.cache == nil {
 		return nil, fmt.Errorf("session service unavailable")
@@ -636,21 +714,6 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, curr
 }
 
 // recordLoginFailure increments the failed login counter for a username
-func (s *AuthService) recordLoginFailure(ctx context.Context, username string) {
-	if s.cache == nil {
-		return
-	}
-	failKey := loginFailuresKeyPrefix + strings.ToLower(username)
-	data, _ := s.cache.Get(ctx, failKey)
-	failures := 0
-	if data != nil {
-		_ = json.Unmarshal(data, &failures)
-	}
-	failures++
-	countJSON, _ := json.Marshal(failures)
-	_ = s.cache.Set(ctx, failKey, countJSON, loginLockoutTTL)
-}
-
 // AccountService handles account management (super admin)
 type AccountService struct {
 	repos *repository.Repositories
@@ -783,6 +846,11 @@ func (s *DeviceService) Reset(ctx context.Context, deviceID uuid.UUID) error {
 	return s.pool.ResetDevice(ctx, deviceID)
 }
 
+// Logout invalidates the WhatsApp session; reconnecting requires a new QR scan.
+func (s *DeviceService) Logout(ctx context.Context, deviceID uuid.UUID) error {
+	return s.pool.LogoutDevice(ctx, deviceID)
+}
+
 func (s *DeviceService) Delete(ctx context.Context, deviceID uuid.UUID) error {
 	return s.pool.DeleteDevice(ctx, deviceID)
 }
@@ -850,8 +918,10 @@ func (s *DeviceService) GetByID(ctx context.Context, deviceID uuid.UUID) (*domai
 
 // ChatService handles chat operations
 type ChatService struct {
-	repos *repository.Repositories
-	pool  *whatsapp.DevicePool
+	repos        *repository.Repositories
+	pool         *whatsapp.DevicePool
+	numberChecks numberCheckCache
+	presence     presenceDebouncer
 }
 
 func (s *ChatService) ensureWhatsAppWebOutbound(ctx context.Context, deviceID uuid.UUID) error {
@@ -868,8 +938,8 @@ func (s *ChatService) ensureWhatsAppWebOutbound(ctx context.Context, deviceID uu
 	return nil
 }
 
-func (s *ChatService) GetByAccountID(ctx context.Context, accountID uuid.UUID) ([]*domain.Chat, error) {
-	return s.repos.Chat.GetByAccountID(ctx, accountID)
+func (s *ChatService) GetByAccountID(ctx context.Context, accountID uuid.UUID, includeGroups bool) ([]*domain.Chat, error) {
+	return s.repos.Chat.GetByAccountID(ctx, accountID, includeGroups)
 }
 
 func (s *ChatService) GetByAccountIDWithFilters(ctx context.Context, accountID uuid.UUID, filter domain.ChatFilter) ([]*domain.Chat, int, error) {
@@ -958,6 +1028,36 @@ func (s *ChatService) FindByJID(ctx context.Context, accountID uuid.UUID, jid st
 	return s.repos.Chat.FindByJID(ctx, accountID, jid)
 }
 
+// groupParticipantsMaxAge is how long stored group members are served before
+// GetGroupParticipants reloads them from WhatsApp.
+const groupParticipantsMaxAge = time.Hour
+
+// GetGroupParticipants returns the group with its members, each mapped to a
+// contact of the account where one matches. Stored metadata is used while
+// fresh; otherwise, or when refresh is set, it is reloaded through the device.
+// A failed reload falls back to the stored copy when there is one.
+func (s *ChatService) GetGroupParticipants(ctx context.Context, accountID, deviceID uuid.UUID, jid string, refresh bool) (*domain.WhatsAppGroup, error) {
+	group, err := s.repos.Group.GetByJID(ctx, accountID, jid)
+	if err != nil {
+		return nil, err
+	}
+	if refresh || group == nil || time.Since(group.UpdatedAt) > groupParticipantsMaxAge {
+		fresh, refreshErr := s.pool.RefreshGroup(ctx, accountID, deviceID, jid)
+		switch {
+		case refreshErr == nil:
+			group = fresh
+		case group == nil:
+			return nil, refreshErr
+		default:
+			log.Printf("[Groups] Serving stored participants of %s: %v", jid, refreshErr)
+		}
+	}
+	if err := s.repos.Group.ResolveParticipantContacts(ctx, accountID, group.Participants); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
 func (s *ChatService) CreateNewChat(ctx context.Context, accountID, deviceID uuid.UUID, phone string) (*domain.Chat, error) {
 	// Normalize phone number to JID
 	jid := phone
@@ -1004,6 +1104,22 @@ func (s *ChatService) SearchMessages(ctx context.Context, accountID, chatID uuid
 	return s.repos.Message.SearchByChat(ctx, accountID, chatID, query, limit, offset)
 }
 
+func (s *ChatService) SetMessageStarred(ctx context.Context, accountID, messageID uuid.UUID, starred bool) (*domain.Message, error) {
+	return s.repos.Message.SetStarred(ctx, accountID, messageID, starred)
+}
+
+func (s *ChatService) GetStarredMessages(ctx context.Context, accountID, chatID uuid.UUID, limit, offset int) ([]*domain.Message, int, error) {
+	if limit <= 0 {
+		limit = 50
+	} else if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repos.Message.GetStarredByChatID(ctx, accountID, chatID, limit, offset)
+}
+
 func (s *ChatService) GetMessageHistoryOffset(ctx context.Context, accountID, chatID, messageID uuid.UUID) (int, error) {
 	return s.repos.Message.GetHistoryOffset(ctx, accountID, chatID, messageID)
 }
@@ -1044,9 +1160,11 @@ func (s *ChatService) SendReplyMessage(ctx context.Context, deviceID uuid.UUID,
 	return s.pool.SendReplyMessage(ctx, deviceID, to, body, quotedID, quotedBody, quotedSender, quotedIsFromMe)
 }
 
-func (s *ChatService) ForwardMessage(ctx context.Context, deviceID uuid.UUID, to string, originalMsg *domain.Message) (*domain.Message, error) {
+// ForwardMessage reports mediaMissing when the original media was gone and
+// only its caption could be forwarded.
+func (s *ChatService) ForwardMessage(ctx context.Context, deviceID uuid.UUID, to string, originalMsg *domain.Message) (message *domain.Message, mediaMissing bool, err error) {
 	if err := s.ensureWhatsAppWebOutbound(ctx, deviceID); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	return s.pool.ForwardMessage(ctx, deviceID, to, originalMsg)
 }
@@ -1072,6 +1190,13 @@ func (s *ChatService) SendContactMessage(ctx context.Context, deviceID uuid.UUID
 	return s.pool.SendContactMessage(ctx, deviceID, to, contactName, contactPhone)
 }
 
+func (s *ChatService) SendLocation(ctx context.Context, deviceID uuid.UUID, to string, latitude, longitude float64, name, address string) (*domain.Message, error) {
+	if err := s.ensureWhatsAppWebOutbound(ctx, deviceID); err != nil {
+		return nil, err
+	}
+	return s.pool.SendLocationMessage(ctx, deviceID, to, latitude, longitude, name, address)
+}
+
 func (s *ChatService) GetReactions(ctx context.Context, chatID uuid.UUID) ([]*domain.MessageReaction, error) {
 	return s.repos.Reaction.GetByChatID(ctx, chatID)
 }
@@ -1092,6 +1217,53 @@ func (s *ChatService) GetMessageByID(ctx context.Context, chatID uuid.UUID, mess
 	return s.repos.Message.GetByMessageID(ctx, chatID, messageID)
 }
 
+func (s *ChatService) GetUnreadSummary(ctx context.Context, accountID uuid.UUID, scope *domain.AssignmentScope) (*domain.ChatUnreadSummary, error) {
+	return s.repos.Chat.GetUnreadSummary(ctx, accountID, scope)
+}
+
+// SetArchived archives or unarchives a chat and mirrors the change to the
+// phone through the chat's WhatsApp Web device. synced reports whether the
+// phone was updated; a failed mirror does not undo the local change.
+func (s *ChatService) SetArchived(ctx context.Context, chat *domain.Chat, archived bool) (synced bool, err error) {
+	if err := s.repos.Chat.SetArchived(ctx, chat.AccountID, chat.ID, archived); err != nil {
+		return false, err
+	}
+	return s.mirrorChatState(ctx, chat, func(deviceID uuid.UUID) error {
+		return s.pool.SetChatArchived(ctx, deviceID, chat.JID, archived, chatLastMessageAt(chat))
+	}), nil
+}
+
+// SetPinned pins or unpins a chat, mirrored like SetArchived.
+func (s *ChatService) SetPinned(ctx context.Context, chat *domain.Chat, pinned bool) (synced bool, err error) {
+	if err := s.repos.Chat.SetPinned(ctx, chat.AccountID, chat.ID, pinned); err != nil {
+		return false, err
+	}
+	return s.mirrorChatState(ctx, chat, func(deviceID uuid.UUID) error {
+		return s.pool.SetChatPinned(ctx, deviceID, chat.JID, pinned)
+	}), nil
+}
+
+func (s *ChatService) mirrorChatState(ctx context.Context, chat *domain.Chat, send func(deviceID uuid.UUID) error) bool {
+	if chat.DeviceID == nil || s.pool == nil {
+		return false
+	}
+	if err := s.ensureWhatsAppWebOutbound(ctx, *chat.DeviceID); err != nil {
+		return false
+	}
+	if err := send(*chat.DeviceID); err != nil {
+		log.Printf("[Chat] Failed to mirror state of chat %s to WhatsApp: %v", chat.ID, err)
+		return false
+	}
+	return true
+}
+
+func chatLastMessageAt(chat *domain.Chat) time.Time {
+	if chat.LastMessageAt == nil {
+		return time.Time{}
+	}
+	return *chat.LastMessageAt
+}
+
 func (s *ChatService) MarkAsRead(ctx context.Context, chatID uuid.UUID) error {
 	return s.repos.Chat.MarkAsRead(ctx, chatID)
 }
@@ -1217,6 +1389,14 @@ func (s *ContactService) DeleteAll(ctx context.Context, accountID uuid.UUID) err
 	return s.repos.Contact.DeleteAll(ctx, accountID)
 }
 
+func (s *ContactService) Restore(ctx context.Context, accountID, id uuid.UUID) error {
+	return s.repos.Contact.Restore(ctx, accountID, id)
+}
+
+func (s *ContactService) GetTrash(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*domain.Contact, int, error) {
+	return s.repos.Contact.GetTrash(ctx, accountID, limit, offset)
+}
+
 func (s *ContactService) FindDuplicates(ctx context.Context, accountID uuid.UUID) ([][]*domain.Contact, error) {
 	return s.repos.Contact.FindDuplicates(ctx, accountID)
 }
@@ -1225,8 +1405,8 @@ func (s *ContactService) GetDuplicateLeadsCount(ctx context.Context, accountID u
 	return s.repos.Contact.GetContactsWithDuplicateLeads(ctx, accountID)
 }
 
-func (s *ContactService) FindDuplicateGroups(ctx context.Context, accountID uuid.UUID) ([]*domain.ContactDuplicateGroup, error) {
-	return s.repos.Contact.FindDuplicateGroups(ctx, accountID)
+func (s *ContactService) FindDuplicateGroups(ctx context.Context, accountID uuid.UUID, matchEmail bool) ([]*domain.ContactDuplicateGroup, error) {
+	return s.repos.Contact.FindDuplicateGroups(ctx, accountID, matchEmail)
 }
 
 func (s *ContactService) PreviewMergeContacts(ctx context.Context, accountID, keepID uuid.UUID, mergeIDs []uuid.UUID) (*domain.ContactMergePreview, error) {
@@ -1275,6 +1455,10 @@ func (s *LeadService) GetByAccountID(ctx context.Context, accountID uuid.UUID) (
 	return s.repos.Lead.GetByAccountID(ctx, accountID)
 }
 
+func (s *LeadService) GetByAccountIDWithFilters(ctx context.Context, accountID uuid.UUID, filter domain.LeadFilter) ([]*domain.Lead, int, error) {
+	return s.repos.Lead.GetByAccountIDWithFilters(ctx, accountID, filter)
+}
+
 func (s *LeadService) GetByID(ctx context.Context, leadID uuid.UUID) (*domain.Lead, error) {
 	return s.repos.Lead.GetByID(ctx, leadID)
 }
@@ -1295,8 +1479,14 @@ func (s *LeadService) UpdateStatus(ctx context.Context, leadID uuid.UUID, status
 	return s.repos.Lead.UpdateStatus(ctx, leadID, status)
 }
 
-func (s *LeadService) UpdateStage(ctx context.Context, leadID uuid.UUID, stageID uuid.UUID) error {
-	return s.repos.Lead.UpdateStage(ctx, leadID, stageID)
+func (s *LeadService) UpdateStage(ctx context.Context, leadID uuid.UUID, stageID uuid.UUID, changedBy *uuid.UUID) error {
+	if err := s.repos.Lead.UpdateStage(ctx, leadID, stageID, changedBy); err != nil {
+		return err
+	}
+	if _, err := s.RecalculateScore(ctx, leadID); err != nil {
+		log.Printf("[LeadScore] recalculate lead %s: %v", leadID, err)
+	}
+	return nil
 }
 
 func (s *LeadService) GetByJID(ctx context.Context, accountID uuid.UUID, jid string) (*domain.Lead, error) {
@@ -1399,6 +1589,10 @@ func (s *TagService) ListPaginated(ctx context.Context, accountID uuid.UUID, sea
 	return s.repos.Tag.ListPaginated(ctx, accountID, search, limit, offset)
 }
 
+func (s *TagService) GetUsage(ctx context.Context, accountID uuid.UUID) ([]*domain.TagUsage, error) {
+	return s.repos.Tag.GetUsage(ctx, accountID)
+}
+
 func (s *TagService) Create(ctx context.Context, tag *domain.Tag) error {
 	return s.repos.Tag.Create(ctx, tag)
 }
@@ -1466,6 +1660,10 @@ type CampaignService struct {
 	pool       *whatsapp.DevicePool
 	hub        *ws.Hub
 	mediaCache sync.Map // map[string]*whatsapp.PreUploadedMedia — keyed by mediaURL
+	// batchProgress holds each running worker's CampaignBatchProgress so Pause
+	// can persist it.
+	batchProgress sync.Map // map[uuid.UUID]CampaignBatchProgress
+	deviceTurns   campaignDeviceTurns
 }
 
 func (s *CampaignService) validateRecipientPrivacy(ctx context.Context, campaign *domain.Campaign, rec *domain.CampaignRecipient) (*domain.Contact, error) {
@@ -1522,10 +1720,6 @@ func (s *CampaignService) Delete(ctx context.Context, id uuid.UUID) error {
 	return s.repos.Campaign.Delete(ctx, id)
 }
 
-func (s *CampaignService) AddRecipients(ctx context.Context, recipients []*domain.CampaignRecipient) error {
-	return s.repos.Campaign.AddRecipients(ctx, recipients)
-}
-
 func (s *CampaignService) GetRecipients(ctx context.Context, campaignID uuid.UUID) ([]*domain.CampaignRecipient, error) {
 	return s.repos.Campaign.GetRecipients(ctx, campaignID)
 }
@@ -1552,7 +1746,63 @@ func (s *CampaignService) UpdateRecipientData(ctx context.Context, campaignID, r
 	return s.repos.Campaign.UpdateRecipientData(ctx, campaignID, recipientID, name, phone, metadata)
 }
 
-func (s *CampaignService) Start(ctx context.Context, campaignID uuid.UUID, startedBy *uuid.UUID) error {
+const (
+	// CampaignConfirmedCountKey is the campaign settings key holding the
+	// recipient count confirmed when the campaign was scheduled.
+	CampaignConfirmedCountKey = "confirmed_recipient_count"
+	// campaignConfirmationHeldKey marks a scheduled or recurring run that was
+	// put back to draft because its recipients needed a new confirmation.
+	campaignConfirmationHeldKey = "confirmation_required_count"
+)
+
+// CampaignStartConfirmationError is returned by Start when the campaign would
+// message more recipients than the account threshold allows without an
+// explicit confirm_count matching RecipientCount.
+type CampaignStartConfirmationError struct {
+	RecipientCount int
+	Threshold      int
+}
+
+func (e *CampaignStartConfirmationError) Error() string {
+	return fmt.Sprintf("campaign has %d pending recipients; confirm_count must match to start", e.RecipientCount)
+}
+
+// campaignStartNeedsConfirmation reports whether a start must be rejected.
+// A threshold of 0 disables the guard.
+func campaignStartNeedsConfirmation(recipientCount, threshold int, confirmCount *int) bool {
+	if threshold <= 0 || recipientCount <= threshold {
+		return false
+	}
+	return confirmCount == nil || *confirmCount != recipientCount
+}
+
+// Start moves a campaign to running. confirmCount is the recipient total the
+// caller acknowledged; it is required once pending recipients exceed the
+// account's campaign_confirm_threshold.
+func (s *CampaignService) Start(ctx context.Context, campaignID uuid.UUID, startedBy *uuid.UUID, confirmCount *int) error {
+	return s.start(ctx, campaignID, startedBy, confirmCount)
+}
+
+// StartScheduled is used by the worker when a scheduled campaign comes due.
+// Nobody is present to confirm at that point, so the guard uses the count
+// confirmed when the campaign was scheduled. A campaign whose recipients no
+// longer match it goes back to draft for someone to start by hand.
+func (s *CampaignService) StartScheduled(ctx context.Context, campaignID uuid.UUID) error {
+	campaign, err := s.repos.Campaign.GetByID(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	err = s.start(ctx, campaignID, nil, CampaignConfirmedCount(campaign.Settings))
+	var confirmErr *CampaignStartConfirmationError
+	if errors.As(err, &confirmErr) {
+		if holdErr := s.holdForConfirmation(ctx, campaign, confirmErr.RecipientCount); holdErr != nil {
+			log.Printf("[Campaign %s] Failed to hold scheduled campaign for confirmation: %v", campaign.ID, holdErr)
+		}
+	}
+	return err
+}
+
+func (s *CampaignService) start(ctx context.Context, campaignID uuid.UUID, startedBy *uuid.UUID, confirmCount *int) error {
 	campaign, err := s.repos.Campaign.GetByID(ctx, campaignID)
 	if err != nil {
 		return err
@@ -1560,6 +1810,15 @@ func (s *CampaignService) Start(ctx context.Context, campaignID uuid.UUID, start
 	if campaign.Status != domain.CampaignStatusDraft && campaign.Status != domain.CampaignStatusPaused && campaign.Status != domain.CampaignStatusScheduled {
 		return fmt.Errorf("campaign cannot be started from status: %s", campaign.Status)
 	}
+	if err := s.checkStartConfirmation(ctx, campaign, confirmCount); err != nil {
+		return err
+	}
+	delete(campaign.Settings, campaignConfirmationHeldKey)
+	// Only a resume continues a saved batch position; a fresh start must not
+	// inherit one left over from an earlier run.
+	if campaign.Status != domain.CampaignStatusPaused {
+		delete(campaign.Settings, campaignBatchProgressKey)
+	}
 	now := time.Now()
 	campaign.Status = domain.CampaignStatusRunning
 	campaign.StartedAt = &now
@@ -1567,6 +1826,70 @@ func (s *CampaignService) Start(ctx context.Context, campaignID uuid.UUID, start
 	return s.repos.Campaign.Update(ctx, campaign)
 }
 
+// ConfirmSchedule applies the start guard when a campaign is scheduled, while
+// someone is still there to confirm, and records the confirmed count for
+// StartScheduled.
+func (s *CampaignService) ConfirmSchedule(ctx context.Context, campaign *domain.Campaign, confirmCount *int) error {
+	if err := s.checkStartConfirmation(ctx, campaign, confirmCount); err != nil {
+		return err
+	}
+	if campaign.Settings == nil {
+		campaign.Settings = map[string]interface{}{}
+	}
+	delete(campaign.Settings, campaignConfirmationHeldKey)
+	if confirmCount != nil {
+		campaign.Settings[CampaignConfirmedCountKey] = *confirmCount
+	} else {
+		delete(campaign.Settings, CampaignConfirmedCountKey)
+	}
+	return nil
+}
+
+// CampaignConfirmedCount returns the recipient count confirmed when the
+// campaign was scheduled, or nil when none was given.
+func CampaignConfirmedCount(settings map[string]interface{}) *int {
+	var count int
+	switch v := settings[CampaignConfirmedCountKey].(type) {
+	case int:
+		count = v
+	case float64:
+		count = int(v)
+	default:
+		return nil
+	}
+	return &count
+}
+
+// holdForConfirmation moves a campaign that failed the start guard without a
+// user present back to draft, noting the count that needs confirming.
+func (s *CampaignService) holdForConfirmation(ctx context.Context, campaign *domain.Campaign, recipientCount int) error {
+	if campaign.Settings == nil {
+		campaign.Settings = map[string]interface{}{}
+	}
+	campaign.Settings[campaignConfirmationHeldKey] = recipientCount
+	delete(campaign.Settings, CampaignConfirmedCountKey)
+	campaign.Status = domain.CampaignStatusDraft
+	log.Printf("[Campaign %s] Held as draft: %d recipients need a confirmed start", campaign.ID, recipientCount)
+	return s.repos.Campaign.Update(ctx, campaign)
+}
+
+func (s *CampaignService) checkStartConfirmation(ctx context.Context, campaign *domain.Campaign, confirmCount *int) error {
+	account, err := s.repos.Account.GetByID(ctx, campaign.AccountID)
+	if err != nil {
+		return err
+	}
+	if account != nil && account.CampaignConfirmThreshold > 0 {
+		pending, err := s.repos.Campaign.CountPendingRecipients(ctx, campaign.ID)
+		if err != nil {
+			return err
+		}
+		if campaignStartNeedsConfirmation(pending, account.CampaignConfirmThreshold, confirmCount) {
+			return &CampaignStartConfirmationError{RecipientCount: pending, Threshold: account.CampaignConfirmThreshold}
+		}
+	}
+	return nil
+}
+
 func (s *CampaignService) Pause(ctx context.Context, campaignID uuid.UUID) error {
 	campaign, err := s.repos.Campaign.GetByID(ctx, campaignID)
 	if err != nil {
@@ -1576,6 +1899,18 @@ func (s *CampaignService) Pause(ctx context.Context, campaignID uuid.UUID) error
 		return fmt.Errorf("campaign is not running")
 	}
 	campaign.Status = domain.CampaignStatusPaused
+	// Persist the worker's batch position so a resume continues the same
+	// batch (or inter-batch sleep) instead of starting a fresh one.
+	if value, ok := s.batchProgress.Load(campaignID); ok {
+		if progress := batchProgressSettings(value.(CampaignBatchProgress), time.Now()); progress != nil {
+			if campaign.Settings == nil {
+				campaign.Settings = map[string]interface{}{}
+			}
+			campaign.Settings[campaignBatchProgressKey] = progress
+		} else {
+			delete(campaign.Settings, campaignBatchProgressKey)
+		}
+	}
 	return s.repos.Campaign.Update(ctx, campaign)
 }
 
@@ -1595,6 +1930,10 @@ func (s *CampaignService) Cancel(ctx context.Context, campaignID uuid.UUID) erro
 	}
 }
 
+func (s *CampaignService) GetAnalytics(ctx context.Context, campaignID uuid.UUID) (*repository.CampaignAnalytics, error) {
+	return s.repos.Campaign.GetAnalytics(ctx, campaignID)
+}
+
 func (s *CampaignService) GetRunningCampaigns(ctx context.Context) ([]*domain.Campaign, error) {
 	return s.repos.Campaign.GetRunningCampaigns(ctx)
 }
@@ -1683,6 +2022,9 @@ func (s *CampaignService) RetryRecipient(ctx context.Context, campaignID uuid.UU
 
 	// Mark as sent and update counters
 	s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "sent", nil, nil)
+	if rec.Channel != domain.CampaignChannelWhatsApp {
+		s.repos.Campaign.UpdateRecipientChannel(ctx, rec.ID, domain.CampaignChannelWhatsApp)
+	}
 	s.repos.Campaign.IncrementSentCount(ctx, campaignID)
 	// Decrement failed count
 	s.repos.Campaign.DecrementFailedCount(ctx, campaignID)
@@ -1696,19 +2038,55 @@ func (s *CampaignService) Duplicate(ctx context.Context, campaignID uuid.UUID, n
 		return nil, fmt.Errorf("campaign not found: %w", err)
 	}
 
+	template := &domain.Campaign{
+		Name:     original.Name + " (copia)",
+		Settings: original.Settings,
+	}
+	if newMessage != nil && *newMessage != "" {
+		template.MessageTemplate = *newMessage
+	}
+	return s.duplicate(ctx, original, template)
+}
+
+// copyCampaignSettings copies settings without the per-run state the worker
+// keeps there (saved batch position, recurrence bookkeeping).
+func copyCampaignSettings(settings map[string]interface{}) map[string]interface{} {
+	if settings == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		copied[k] = v
+	}
+	delete(copied, campaignBatchProgressKey)
+	delete(copied, campaignRecurrenceClonedKey)
+	delete(copied, CampaignConfirmedCountKey)
+	delete(copied, campaignConfirmationHeldKey)
+	return copied
+}
+
+// duplicate copies original's content, recipients (reset to pending) and
+// attachments into a new campaign. Name, Status, ScheduledAt, Settings and
+// CreatedBy come from overrides; a non-empty MessageTemplate replaces the
+// original text.
+func (s *CampaignService) duplicate(ctx context.Context, original *domain.Campaign, overrides *domain.Campaign) (*domain.Campaign, error) {
+	campaignID := original.ID
 	newCampaign := &domain.Campaign{
 		AccountID:       original.AccountID,
 		DeviceID:        original.DeviceID,
-		Name:            original.Name + " (copia)",
+		Name:            overrides.Name,
 		MessageTemplate: original.MessageTemplate,
 		MediaURL:        original.MediaURL,
 		MediaType:       original.MediaType,
-		Settings:        original.Settings,
+		Status:          overrides.Status,
+		ScheduledAt:     overrides.ScheduledAt,
+		Settings:        copyCampaignSettings(overrides.Settings),
 		EventID:         original.EventID,
 		Source:          original.Source,
+		CreatedBy:       overrides.CreatedBy,
 	}
-	if newMessage != nil && *newMessage != "" {
-		newCampaign.MessageTemplate = *newMessage
+	if overrides.MessageTemplate != "" {
+		newCampaign.MessageTemplate = overrides.MessageTemplate
 	}
 
 	if err := s.repos.Campaign.Create(ctx, newCampaign); err != nil {
@@ -1759,30 +2137,61 @@ func (s *CampaignService) Duplicate(ctx context.Context, campaignID uuid.UUID, n
 	return newCampaign, nil
 }
 
-func personalizeText(text string, rec *domain.CampaignRecipient, contact *domain.Contact, lead *domain.Lead) string {
-	if text == "" {
-		return text
+// campaignPlaceholderPattern matches the {{variable}} placeholders supported in
+// campaign messages and attachment captions.
+var campaignPlaceholderPattern = regexp.MustCompile(`\{\{([a-zA-Z0-9_]+)\}\}`)
+
+// campaignTemplateVariables returns the distinct placeholder names referenced by
+// text, in order of first appearance.
+func campaignTemplateVariables(text string) []string {
+	seen := map[string]bool{}
+	var vars []string
+	for _, m := range campaignPlaceholderPattern.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// campaignTemplateValues resolves every placeholder value available for a
+// recipient. Variables without data are absent from the map.
+func campaignTemplateValues(rec *domain.CampaignRecipient, contact *domain.Contact, lead *domain.Lead) map[string]string {
+	values := map[string]string{}
+
+	// Custom metadata variables (e.g. {{empresa}}, {{ciudad}}) go first so the
+	// built-in names below take precedence over same-named metadata keys.
+	for key, val := range rec.Metadata {
+		if str, ok := val.(string); ok && str != "" {
+			values[key] = str
+		}
 	}
+
+	// {{company}} and {{empresa}} are the same field under either key.
+	if values["company"] == "" && values["empresa"] != "" {
+		values["company"] = values["empresa"]
+	} else if values["empresa"] == "" && values["company"] != "" {
+		values["empresa"] = values["company"]
+	}
+
 	if rec.Name != nil && *rec.Name != "" {
-		text = strings.Replace(text, "{{nombre}}", *rec.Name, -1)
-		text = strings.Replace(text, "{{name}}", *rec.Name, -1)
+		values["nombre"] = *rec.Name
+		values["name"] = *rec.Name
+		if first := strings.Fields(*rec.Name); len(first) > 0 {
+			values["first_name"] = first[0]
+			values["primer_nombre"] = first[0]
+		}
 	}
-	if rec.Phone != nil {
-		text = strings.Replace(text, "{{telefono}}", *rec.Phone, -1)
-		text = strings.Replace(text, "{{phone}}", *rec.Phone, -1)
-		text = strings.Replace(text, "{{celular}}", *rec.Phone, -1)
+	if rec.Phone != nil && *rec.Phone != "" {
+		values["telefono"] = *rec.Phone
+		values["phone"] = *rec.Phone
+		values["celular"] = *rec.Phone
 	}
 
 	// Resolve nombre_corto: check recipient metadata first (event participant override),
 	// then contact, then lead
-	shortName := ""
-	if rec.Metadata != nil {
-		if v, ok := rec.Metadata["nombre_corto"]; ok {
-			if s, ok := v.(string); ok && s != "" {
-				shortName = s
-			}
-		}
-	}
+	shortName := values["nombre_corto"]
 	if shortName == "" && contact != nil && contact.ShortName != nil && *contact.ShortName != "" {
 		shortName = *contact.ShortName
 	}
@@ -1790,7 +2199,7 @@ func personalizeText(text string, rec *domain.CampaignRecipient, contact *domain
 		shortName = *lead.ShortName
 	}
 	if shortName != "" {
-		text = strings.Replace(text, "{{nombre_corto}}", shortName, -1)
+		values["nombre_corto"] = shortName
 	}
 
 	// Resolve nombre_completo: try contact first, then lead
@@ -1799,48 +2208,208 @@ func personalizeText(text string, rec *domain.CampaignRecipient, contact *domain
 		if contact.CustomName != nil && *contact.CustomName != "" {
 			fullName = *contact.CustomName
 		} else {
-			parts := []string{}
-			if contact.Name != nil && *contact.Name != "" {
-				parts = append(parts, *contact.Name)
-			}
-			if contact.LastName != nil && *contact.LastName != "" {
-				parts = append(parts, *contact.LastName)
-			}
-			if len(parts) > 0 {
-				fullName = strings.Join(parts, " ")
-			}
+			fullName = joinNameParts(contact.Name, contact.LastName)
 		}
 	}
 	if fullName == "" && lead != nil {
-		parts := []string{}
-		if lead.Name != nil && *lead.Name != "" {
-			parts = append(parts, *lead.Name)
+		fullName = joinNameParts(lead.Name, lead.LastName)
+	}
+	if fullName != "" {
+		values["nombre_completo"] = fullName
+	}
+	return values
+}
+
+func joinNameParts(name, lastName *string) string {
+	parts := []string{}
+	if name != nil && *name != "" {
+		parts = append(parts, *name)
+	}
+	if lastName != nil && *lastName != "" {
+		parts = append(parts, *lastName)
+	}
+	return strings.Join(parts, " ")
+}
+
+func personalizeText(text string, rec *domain.CampaignRecipient, contact *domain.Contact, lead *domain.Lead) string {
+	if text == "" {
+		return text
+	}
+	values := campaignTemplateValues(rec, contact, lead)
+	// Unresolved placeholders (e.g. {{nombre_corto}} when no data exists) render empty.
+	return campaignPlaceholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return values[match[2:len(match)-2]]
+	})
+}
+
+// RenderTemplate fills template placeholders from the recipient's own Name,
+// Phone and Metadata. Unknown placeholders render empty; anything that is not
+// a well-formed {{variable}} (e.g. a stray "{{") is left as written.
+func (s *CampaignService) RenderTemplate(template string, rec *domain.CampaignRecipient) string {
+	return personalizeText(template, rec, nil, nil)
+}
+
+// CampaignPreview is the message a campaign would send to one recipient.
+type CampaignPreview struct {
+	Recipient *domain.CampaignRecipient `json:"recipient"`
+	Message   string                    `json:"message"`
+	Captions  []string                  `json:"captions"`
+}
+
+// Preview renders the campaign for its first recipient in send order, using
+// the same contact and lead data the worker resolves. It returns nil when the
+// campaign has no recipients.
+func (s *CampaignService) Preview(ctx context.Context, campaign *domain.Campaign) (*CampaignPreview, error) {
+	recipients, err := s.repos.Campaign.GetRecipients(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+	// Pending recipients share the worker's id order; fall back to the first
+	// recipient once everyone has been processed.
+	rec := recipients[0]
+	for _, r := range recipients {
+		if r.Status == "pending" {
+			rec = r
+			break
 		}
-		if lead.LastName != nil && *lead.LastName != "" {
-			parts = append(parts, *lead.LastName)
+	}
+
+	var contact *domain.Contact
+	if rec.ContactID != nil {
+		if ct, err := s.repos.Contact.GetByID(ctx, *rec.ContactID); err == nil && ct != nil && ct.AccountID == campaign.AccountID {
+			contact = ct
 		}
-		if len(parts) > 0 {
-			fullName = strings.Join(parts, " ")
+	}
+	var lead *domain.Lead
+	if rec.JID != "" {
+		lead, _ = s.repos.Lead.GetByJID(ctx, campaign.AccountID, rec.JID)
+	}
+
+	preview := &CampaignPreview{
+		Recipient: rec,
+		Message:   personalizeText(campaign.MessageTemplate, rec, contact, lead),
+		Captions:  []string{},
+	}
+	attachments, _ := s.repos.CampaignAttachment.GetByCampaignID(ctx, campaign.ID)
+	for _, att := range attachments {
+		preview.Captions = append(preview.Captions, personalizeText(att.Caption, rec, contact, lead))
+	}
+	return preview, nil
+}
+
+// CampaignVariableIssue summarizes recipients that would render a template
+// variable as an empty string.
+type CampaignVariableIssue struct {
+	Variable     string                        `json:"variable"`
+	MissingCount int                           `json:"missing_count"`
+	Samples      []CampaignVariableIssueSample `json:"samples"`
+}
+
+type CampaignVariableIssueSample struct {
+	RecipientID uuid.UUID `json:"recipient_id"`
+	Name        *string   `json:"name,omitempty"`
+	Phone       *string   `json:"phone,omitempty"`
+}
+
+// CampaignTemplateValidation is the result of ValidateTemplateVariables.
+type CampaignTemplateValidation struct {
+	Variables     []string                 `json:"variables"`
+	CheckedCount  int                      `json:"checked_count"`
+	AffectedCount int                      `json:"affected_count"`
+	Issues        []*CampaignVariableIssue `json:"issues"`
+}
+
+// ValidateTemplateVariables scans the pending recipients of a campaign and
+// reports, per referenced variable, how many of them have no value for it.
+// Missing values are warnings: the campaign can still be started.
+func (s *CampaignService) ValidateTemplateVariables(ctx context.Context, campaign *domain.Campaign, sampleSize int) (*CampaignTemplateValidation, error) {
+	texts := []string{campaign.MessageTemplate}
+	attachments, err := s.repos.CampaignAttachment.GetByCampaignID(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, att := range attachments {
+		texts = append(texts, att.Caption)
+	}
+	variables := campaignTemplateVariables(strings.Join(texts, "\n"))
+
+	result := &CampaignTemplateValidation{Variables: variables, Issues: []*CampaignVariableIssue{}}
+	if len(variables) == 0 {
+		return result, nil
+	}
+	recipients, err := s.repos.Campaign.GetRecipients(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Contacts and leads are loaded in one query each rather than per recipient.
+	pending := make([]*domain.CampaignRecipient, 0, len(recipients))
+	var contactIDs []uuid.UUID
+	var jids []string
+	for _, rec := range recipients {
+		if rec.Status != "pending" {
+			continue
+		}
+		pending = append(pending, rec)
+		if rec.ContactID != nil {
+			contactIDs = append(contactIDs, *rec.ContactID)
+		}
+		if rec.JID != "" {
+			jids = append(jids, rec.JID)
 		}
 	}
-	if fullName != "" {
-		text = strings.Replace(text, "{{nombre_completo}}", fullName, -1)
+	contacts := make(map[uuid.UUID]*domain.Contact, len(contactIDs))
+	if len(contactIDs) > 0 {
+		found, err := s.repos.Contact.GetContactsByIDs(ctx, campaign.AccountID, contactIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, ct := range found {
+			contacts[ct.ID] = ct
+		}
+	}
+	leads, err := s.repos.Lead.GetNamesByJIDs(ctx, campaign.AccountID, jids)
+	if err != nil {
+		return nil, err
 	}
 
-	// Resolve custom metadata variables (e.g. {{empresa}}, {{ciudad}})
-	if rec.Metadata != nil {
-		for key, val := range rec.Metadata {
-			if str, ok := val.(string); ok && str != "" {
-				text = strings.Replace(text, "{{"+key+"}}", str, -1)
+	issues := make(map[string]*CampaignVariableIssue, len(variables))
+	for _, rec := range pending {
+		result.CheckedCount++
+		var contact *domain.Contact
+		if rec.ContactID != nil {
+			contact = contacts[*rec.ContactID]
+		}
+		values := campaignTemplateValues(rec, contact, leads[rec.JID])
+		affected := false
+		for _, variable := range variables {
+			if values[variable] != "" {
+				continue
+			}
+			affected = true
+			issue := issues[variable]
+			if issue == nil {
+				issue = &CampaignVariableIssue{Variable: variable, Samples: []CampaignVariableIssueSample{}}
+				issues[variable] = issue
+			}
+			issue.MissingCount++
+			if len(issue.Samples) < sampleSize {
+				issue.Samples = append(issue.Samples, CampaignVariableIssueSample{RecipientID: rec.ID, Name: rec.Name, Phone: rec.Phone})
 			}
 		}
+		if affected {
+			result.AffectedCount++
+		}
 	}
-
-	// Clean up any remaining unresolved placeholders (e.g. {{nombre_corto}} when no data exists)
-	re := regexp.MustCompile(`\{\{[a-zA-Z0-9_]+\}\}`)
-	text = re.ReplaceAllString(text, "")
-
-	return text
+	for _, variable := range variables {
+		if issue := issues[variable]; issue != nil {
+			result.Issues = append(result.Issues, issue)
+		}
+	}
+	return result, nil
 }
 
 // getOrUploadMedia returns cached pre-uploaded media or uploads it once.
@@ -1867,7 +2436,7 @@ func sendWithRetry(campaignID uuid.UUID, recipientJID string, sendFunc func() er
 			return nil
 		}
 		// Only retry on error 475 (WhatsApp anti-spam rate limit)
-		if !strings.Contains(err.Error(), "475") {
+		if whatsapp.SendErrorStatus(err) != 475 {
 			return err
 		}
 		if attempt < 3 {
@@ -1889,12 +2458,30 @@ func (s *CampaignService) ProcessNextRecipient(ctx context.Context, campaignID u
 	}
 
 	rec, err := s.repos.Campaign.GetNextPendingRecipient(ctx, campaignID)
+	// Test mode only delivers to whitelisted numbers; everyone else is marked
+	// skipped_test without consuming a send slot in the batch.
+	if whitelist, testMode := CampaignTestNumbers(campaign.Settings); testMode {
+		for err == nil && !campaignTestNumberAllowed(rec, whitelist) {
+			errMsg := "Omitido: modo de prueba, número fuera de la lista"
+			if updateErr := s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "skipped_test", &errMsg, nil); updateErr != nil {
+				return false, updateErr
+			}
+			rec, err = s.repos.Campaign.GetNextPendingRecipient(ctx, campaignID)
+		}
+	}
 	if err != nil {
+		// Recipients backing off after a transient failure keep the campaign open.
+		if waiting, countErr := s.repos.Campaign.CountPendingRecipients(ctx, campaignID); countErr == nil && waiting > 0 {
+			return false, ErrCampaignRetryPending
+		}
 		// No more recipients
 		now := time.Now()
 		campaign.Status = domain.CampaignStatusCompleted
 		campaign.CompletedAt = &now
 		s.repos.Campaign.Update(ctx, campaign)
+		if _, recurErr := s.scheduleNextRecurrence(ctx, campaign); recurErr != nil {
+			log.Printf("[Campaign %s] ⚠️ Failed to schedule next recurrence: %v", campaignID, recurErr)
+		}
 		return false, nil
 	}
 
@@ -1906,6 +2493,10 @@ func (s *CampaignService) ProcessNextRecipient(ctx context.Context, campaignID u
 		if verifyErr != nil {
 			log.Printf("[Campaign %s] WA verify error for %s: %v (proceeding with send)", campaignID, rec.JID, verifyErr)
 		} else if len(results) > 0 && !results[0].IsOnWhatsApp {
+			if sender := s.smsSenderForAccount(ctx, campaign.AccountID); sender != nil {
+				s.sendCampaignSMS(ctx, campaign, rec, sender, waitTimeMs)
+				return true, nil
+			}
 			errMsg := "Número no disponible en WhatsApp"
 			log.Printf("[Campaign %s] SKIPPED %s: %s", campaignID, rec.JID, errMsg)
 			s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "failed", &errMsg, waitTimeMs)
@@ -2024,6 +2615,13 @@ func (s *CampaignService) ProcessNextRecipient(ctx context.Context, campaignID u
 			s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "skipped", &errMsg, waitTimeMs)
 			return true, nil
 		}
+		if isTransientSendError(sendErr) && rec.RetryCount < CampaignMaxRetries(campaign.Settings) {
+			backoff := campaignRetryBackoff(rec.RetryCount)
+			log.Printf("[Campaign %s] RETRY %s in %v (attempt %d): %s", campaignID, rec.JID, backoff, rec.RetryCount+1, errMsg)
+			if requeueErr := s.repos.Campaign.RequeueRecipient(ctx, rec.ID, errMsg, time.Now().Add(backoff)); requeueErr == nil {
+				return true, sendErr
+			}
+		}
 		log.Printf("[Campaign %s] FAILED %s: %s", campaignID, rec.JID, errMsg)
 		s.repos.Campaign.UpdateRecipientStatus(ctx, rec.ID, "failed", &errMsg, waitTimeMs)
 		s.repos.Campaign.IncrementFailedCount(ctx, campaignID)
@@ -2140,10 +2738,205 @@ func (s *EventService) BulkUpdateParticipantStatus(ctx context.Context, accountI
 	return s.repos.Participant.BulkUpdateStatus(ctx, accountID, eventID, ids, status)
 }
 
+// checkInPhone normalizes a phone from a sign-in sheet the way participant
+// phones are compared: digits only, with Peruvian mobiles prefixed with 51.
+// It returns "" for values that can't be a phone number.
+func checkInPhone(raw string) string {
+	var digits strings.Builder
+	for _, r := range raw {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	phone := strings.TrimPrefix(digits.String(), "00")
+	if len(phone) == 9 && strings.HasPrefix(phone, "9") {
+		phone = "51" + phone
+	}
+	if len(phone) < 7 || len(phone) > 15 {
+		return ""
+	}
+	return phone
+}
+
+// CheckInParticipant marks an active participant as attended and logs the
+// check-in as an interaction. A participant already marked attended is left
+// untouched so repeated scans don't log it twice; the result reports whether
+// the participant was checked in by this call.
+func (s *EventService) CheckInParticipant(ctx context.Context, accountID, eventID uuid.UUID, p *domain.EventParticipant, userID *uuid.UUID) (bool, error) {
+	if p.Status == domain.ParticipantStatusAttended {
+		return false, nil
+	}
+	if _, err := s.UpdateParticipantStatus(ctx, accountID, eventID, p.ID, domain.ParticipantStatusAttended); err != nil {
+		return false, err
+	}
+	s.logCheckIn(ctx, accountID, eventID, p, userID)
+	s.broadcastCheckIn(accountID, eventID, []uuid.UUID{p.ID})
+	return true, nil
+}
+
+// CheckInByPhones checks in the active participants matching a list of
+// phones, e.g. from a scanned sign-in sheet. Phones that match nobody are
+// reported back in Unknown rather than dropped.
+func (s *EventService) CheckInByPhones(ctx context.Context, accountID, eventID uuid.UUID, phones []string, userID *uuid.UUID) (*domain.EventCheckInResult, error) {
+	result := &domain.EventCheckInResult{
+		CheckedIn:        []uuid.UUID{},
+		AlreadyCheckedIn: []uuid.UUID{},
+		Unknown:          []string{},
+	}
+	normalized := make([]string, 0, len(phones))
+	raw := make(map[string]string, len(phones))
+	for _, phone := range phones {
+		if strings.TrimSpace(phone) == "" {
+			continue
+		}
+		n := checkInPhone(phone)
+		if n == "" {
+			result.Unknown = append(result.Unknown, phone)
+			continue
+		}
+		if _, dup := raw[n]; dup {
+			continue
+		}
+		raw[n] = phone
+		normalized = append(normalized, n)
+	}
+	found, err := s.repos.Participant.FindActiveByPhones(ctx, eventID, normalized)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[uuid.UUID]bool)
+	var pending []*domain.EventParticipant
+	for _, phone := range normalized {
+		p := found[phone]
+		if p == nil {
+			result.Unknown = append(result.Unknown, raw[phone])
+			continue
+		}
+		if seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		if p.Status == domain.ParticipantStatusAttended {
+			result.AlreadyCheckedIn = append(result.AlreadyCheckedIn, p.ID)
+			continue
+		}
+		pending = append(pending, p)
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+	ids := make([]uuid.UUID, len(pending))
+	for i, p := range pending {
+		ids[i] = p.ID
+	}
+	if _, err := s.BulkUpdateParticipantStatus(ctx, accountID, eventID, ids, domain.ParticipantStatusAttended); err != nil {
+		return nil, err
+	}
+	for _, p := range pending {
+		s.logCheckIn(ctx, accountID, eventID, p, userID)
+	}
+	result.CheckedIn = ids
+	s.broadcastCheckIn(accountID, eventID, ids)
+	return result, nil
+}
+
+func (s *EventService) logCheckIn(ctx context.Context, accountID, eventID uuid.UUID, p *domain.EventParticipant, userID *uuid.UUID) {
+	notes := "Asistencia registrada"
+	participantID := p.ID
+	interaction := &domain.Interaction{
+		AccountID:     accountID,
+		ContactID:     p.ContactID,
+		LeadID:        p.LeadID,
+		EventID:       &eventID,
+		ParticipantID: &participantID,
+		Type:          "note",
+		Notes:         &notes,
+		SourceLabel:   "checkin",
+		CreatedBy:     userID,
+	}
+	if err := s.repos.Interaction.Create(ctx, interaction); err != nil {
+		log.Printf("[EVENT] Error logging check-in interaction for participant %s: %v", p.ID, err)
+	}
+}
+
+func (s *EventService) broadcastCheckIn(accountID, eventID uuid.UUID, ids []uuid.UUID) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.BroadcastToAccount(accountID, "event_participant_update", map[string]interface{}{
+		"event_id":        eventID,
+		"action":          "checkin",
+		"participant_ids": ids,
+	})
+}
+
 func (s *EventService) DeleteParticipant(ctx context.Context, id uuid.UUID) error {
 	return s.repos.Participant.Delete(ctx, id)
 }
 
+const (
+	// participantReminderLookback bounds how overdue an action may be and
+	// still be reminded, so enabling reminders does not replay old actions.
+	participantReminderLookback = time.Hour
+	participantReminderBatch    = 200
+)
+
+// ProcessActionReminders notifies the assignee of every participant next
+// action due within lookahead that was not reminded yet. With
+// createInteraction, a note interaction is also logged on the participant.
+func (s *EventService) ProcessActionReminders(ctx context.Context, lookahead time.Duration, createInteraction bool) {
+	for {
+		reminders, err := s.repos.Participant.ClaimDueActionReminders(ctx, lookahead, participantReminderLookback, participantReminderBatch)
+		if err != nil {
+			log.Printf("[EVENT] Error claiming participant reminders: %v", err)
+			return
+		}
+		for _, rem := range reminders {
+			data := map[string]interface{}{
+				"participant_id":   rem.ParticipantID.String(),
+				"event_id":         rem.EventID.String(),
+				"event_name":       rem.EventName,
+				"name":             rem.Name,
+				"next_action":      rem.NextAction,
+				"next_action_date": rem.NextActionDate,
+			}
+			if s.hub != nil {
+				if rem.AssignedTo == nil {
+					s.hub.BroadcastToAccount(rem.AccountID, ws.EventParticipantReminder, data)
+				} else {
+					data["assigned_to"] = rem.AssignedTo.String()
+					s.hub.SendToUser(rem.AccountID, *rem.AssignedTo, ws.EventParticipantReminder, data)
+				}
+			}
+			if createInteraction {
+				notes := "Recordatorio de próxima acción"
+				if rem.NextAction != nil && strings.TrimSpace(*rem.NextAction) != "" {
+					notes += ": " + strings.TrimSpace(*rem.NextAction)
+				}
+				due := rem.NextActionDate
+				interaction := &domain.Interaction{
+					AccountID:      rem.AccountID,
+					ContactID:      rem.ContactID,
+					LeadID:         rem.LeadID,
+					EventID:        &rem.EventID,
+					ParticipantID:  &rem.ParticipantID,
+					Type:           "note",
+					Notes:          &notes,
+					NextAction:     rem.NextAction,
+					NextActionDate: &due,
+					SourceLabel:    "reminder",
+				}
+				if err := s.repos.Interaction.Create(ctx, interaction); err != nil {
+					log.Printf("[EVENT] Error logging reminder interaction for participant %s: %v", rem.ParticipantID, err)
+				}
+			}
+		}
+		if len(reminders) < participantReminderBatch {
+			return
+		}
+	}
+}
+
 func (s *EventService) GetUpcomingActions(ctx context.Context, accountID uuid.UUID, limit int) ([]*domain.EventParticipant, error) {
 	return s.repos.Participant.GetUpcomingActions(ctx, accountID, limit)
 }
@@ -2225,6 +3018,45 @@ func (s *EventService) GetPipelineStages(ctx context.Context, pipelineID uuid.UU
 	return s.repos.EventPipeline.GetStagesByPipelineID(ctx, pipelineID)
 }
 
+// GetStats returns the dashboard summary of an event's participants.
+func (s *EventService) GetStats(ctx context.Context, eventID uuid.UUID) (*domain.EventStats, error) {
+	counts, total, err := s.repos.Event.GetParticipantCounts(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	stats := newEventStats(eventID, counts, total)
+	if stats.PendingActions, err = s.repos.Event.GetPendingActionCount(ctx, eventID); err != nil {
+		return nil, err
+	}
+	if stats.Tags, err = s.repos.Event.GetParticipantTagCounts(ctx, eventID); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// newEventStats fills the status counts and rates from participant counts
+// by status.
+func newEventStats(eventID uuid.UUID, counts map[string]int, total int) *domain.EventStats {
+	stats := &domain.EventStats{
+		EventID:   eventID,
+		Total:     total,
+		Invited:   counts[domain.ParticipantStatusInvited],
+		Contacted: counts[domain.ParticipantStatusContacted],
+		Confirmed: counts[domain.ParticipantStatusConfirmed],
+		Declined:  counts[domain.ParticipantStatusDeclined],
+		Attended:  counts[domain.ParticipantStatusAttended],
+		NoShow:    counts[domain.ParticipantStatusNoShow],
+		Tags:      []*domain.EventTagCount{},
+	}
+	if total > 0 {
+		stats.ConfirmationRate = float64(stats.Confirmed+stats.Attended) / float64(total) * 100
+	}
+	if committed := stats.Confirmed + stats.Attended + stats.NoShow; committed > 0 {
+		stats.AttendanceRate = float64(stats.Attended) / float64(committed) * 100
+	}
+	return stats
+}
+
 func (s *EventService) GetParticipantCountsByStage(ctx context.Context, eventID uuid.UUID) (map[uuid.UUID]int, int, error) {
 	return s.repos.EventPipeline.GetParticipantCountsByStage(ctx, eventID)
 }
@@ -2421,6 +3253,11 @@ func (s *InteractionService) LogInteraction(ctx context.Context, interaction *do
 	if err := s.repos.Interaction.Create(ctx, interaction); err != nil {
 		return err
 	}
+	if interaction.LeadID != nil {
+		if _, err := recalculateLeadScore(ctx, s.repos, *interaction.LeadID, nil); err != nil {
+			log.Printf("[LeadScore] recalculate lead %s: %v", *interaction.LeadID, err)
+		}
+	}
 
 	var participant *domain.EventParticipant
 	if interaction.ParticipantID != nil {
@@ -2477,8 +3314,15 @@ type QuickReplyService struct {
 	repos *repository.Repositories
 }
 
-func (s *QuickReplyService) GetByAccountID(ctx context.Context, accountID uuid.UUID) ([]*domain.QuickReply, error) {
-	return s.repos.QuickReply.GetByAccountID(ctx, accountID)
+var (
+	ErrQuickReplyNotFound  = errors.New("respuesta rápida no encontrada")
+	ErrQuickReplyForbidden = errors.New("no puedes modificar esta respuesta rápida")
+)
+
+// GetByAccountID lists the account's shared quick replies plus userID's
+// personal ones.
+func (s *QuickReplyService) GetByAccountID(ctx context.Context, accountID, userID uuid.UUID) ([]*domain.QuickReply, error) {
+	return s.repos.QuickReply.GetByAccountID(ctx, accountID, userID)
 }
 
 func (s *QuickReplyService) GetByID(ctx context.Context, id uuid.UUID) (*domain.QuickReply, error) {
@@ -2489,14 +3333,107 @@ func (s *QuickReplyService) Create(ctx context.Context, qr *domain.QuickReply) e
 	return s.repos.QuickReply.Create(ctx, qr)
 }
 
-func (s *QuickReplyService) Update(ctx context.Context, qr *domain.QuickReply) error {
+// QuickReplyVisible reports whether userID may see and use reply: shared
+// replies are visible to everyone in the account, personal ones only to
+// their owner.
+func QuickReplyVisible(reply *domain.QuickReply, userID uuid.UUID) bool {
+	return reply.OwnerID == nil || *reply.OwnerID == userID
+}
+
+// QuickReplyEditable reports whether userID may change or delete reply.
+// Personal replies belong to their owner alone; shared ones need an admin.
+func QuickReplyEditable(reply *domain.QuickReply, userID uuid.UUID, isAdmin bool) bool {
+	if reply.OwnerID != nil {
+		return *reply.OwnerID == userID
+	}
+	return isAdmin
+}
+
+// editable loads quick reply id and checks that userID may change it.
+func (s *QuickReplyService) editable(ctx context.Context, accountID, userID uuid.UUID, isAdmin bool, id uuid.UUID) (*domain.QuickReply, error) {
+	existing, err := s.repos.QuickReply.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.AccountID != accountID || !QuickReplyVisible(existing, userID) {
+		return nil, ErrQuickReplyNotFound
+	}
+	if !QuickReplyEditable(existing, userID, isAdmin) {
+		return nil, ErrQuickReplyForbidden
+	}
+	return existing, nil
+}
+
+// Update saves qr if userID may edit it. The shared/personal scope of a reply
+// is fixed at creation.
+func (s *QuickReplyService) Update(ctx context.Context, accountID, userID uuid.UUID, isAdmin bool, qr *domain.QuickReply) error {
+	existing, err := s.editable(ctx, accountID, userID, isAdmin, qr.ID)
+	if err != nil {
+		return err
+	}
+	qr.AccountID = existing.AccountID
+	qr.OwnerID = existing.OwnerID
 	return s.repos.QuickReply.Update(ctx, qr)
 }
 
-func (s *QuickReplyService) Delete(ctx context.Context, id uuid.UUID) error {
+// MarkUsed counts one send of the quick reply, returning the new use count
+// and timestamp.
+func (s *QuickReplyService) MarkUsed(ctx context.Context, accountID, userID, id uuid.UUID) (int, time.Time, error) {
+	return s.repos.QuickReply.MarkUsed(ctx, accountID, userID, id)
+}
+
+// Delete removes quick reply id if userID may edit it.
+func (s *QuickReplyService) Delete(ctx context.Context, accountID, userID uuid.UUID, isAdmin bool, id uuid.UUID) error {
+	if _, err := s.editable(ctx, accountID, userID, isAdmin, id); err != nil {
+		return err
+	}
 	return s.repos.QuickReply.Delete(ctx, id)
 }
 
+// Render returns a copy of reply with {{variables}} in its body and attachment
+// captions filled from contact. Variable names are the campaign ones
+// ({{nombre}}, {{primer_nombre}}, {{empresa}}, ...); unknown variables and a
+// nil contact render empty.
+func (s *QuickReplyService) Render(reply *domain.QuickReply, contact *domain.Contact) *domain.QuickReply {
+	rendered := *reply
+	rec := quickReplyRecipient(contact)
+	rendered.Body = personalizeText(reply.Body, rec, contact, nil)
+	rendered.Attachments = make([]domain.QuickReplyAttachment, len(reply.Attachments))
+	for i, att := range reply.Attachments {
+		att.Caption = personalizeText(att.Caption, rec, contact, nil)
+		rendered.Attachments[i] = att
+	}
+	return &rendered
+}
+
+// quickReplyRecipient maps a contact onto the recipient fields the campaign
+// variables read from.
+func quickReplyRecipient(contact *domain.Contact) *domain.CampaignRecipient {
+	rec := &domain.CampaignRecipient{Metadata: map[string]interface{}{}}
+	if contact == nil {
+		return rec
+	}
+	name := ""
+	if contact.CustomName != nil && *contact.CustomName != "" {
+		name = *contact.CustomName
+	} else if full := joinNameParts(contact.Name, contact.LastName); full != "" {
+		name = full
+	} else if contact.PushName != nil {
+		name = *contact.PushName
+	}
+	if name != "" {
+		rec.Name = &name
+	}
+	rec.Phone = contact.Phone
+	if contact.Company != nil && *contact.Company != "" {
+		rec.Metadata["empresa"] = *contact.Company
+	}
+	if contact.Email != nil && *contact.Email != "" {
+		rec.Metadata["email"] = *contact.Email
+	}
+	return rec
+}
+
 // RoleService handles RBAC role management
 type RoleService struct {
 	repos *repository.Repositories
@@ -137,14 +137,12 @@ func (s *TaskService) ProcessOverdueTasks(ctx context.Context) {
 		return
 	}
 	for _, t := range tasks {
-		if s.hub != nil {
-			s.hub.BroadcastToAccount(t.AccountID, ws.EventTaskOverdue, map[string]interface{}{
-				"task_id":     t.ID.String(),
-				"title":       t.Title,
-				"type":        t.Type,
-				"assigned_to": t.AssignedTo.String(),
-			})
-		}
+		s.notifyAssignee(t.AccountID, t.AssignedTo, ws.EventTaskOverdue, map[string]interface{}{
+			"task_id":     t.ID.String(),
+			"title":       t.Title,
+			"type":        t.Type,
+			"assigned_to": t.AssignedTo.String(),
+		})
 	}
 	if len(tasks) > 0 {
 		log.Printf("[TASK] Marked %d tasks as overdue", len(tasks))
@@ -165,19 +163,30 @@ func (s *TaskService) ProcessReminders(ctx context.Context) {
 			continue
 		}
 
-		if s.hub != nil {
-			s.hub.BroadcastToAccount(rem.AccountID, ws.EventTaskReminder, map[string]interface{}{
-				"task_id":     rem.TaskID.String(),
-				"title":       title,
-				"type":        taskType,
-				"due_at":      dueAt,
-				"assigned_to": rem.AssignedTo.String(),
-				"reminder_at": rem.ReminderAt,
-			})
-		}
+		s.notifyAssignee(rem.AccountID, rem.AssignedTo, ws.EventTaskReminder, map[string]interface{}{
+			"task_id":     rem.TaskID.String(),
+			"title":       title,
+			"type":        taskType,
+			"due_at":      dueAt,
+			"assigned_to": rem.AssignedTo.String(),
+			"reminder_at": rem.ReminderAt,
+		})
 
 		if err := s.repos.Task.MarkReminderDelivered(ctx, rem.ID); err != nil {
 			log.Printf("[TASK] Error marking reminder %s as delivered: %v", rem.ID, err)
 		}
 	}
 }
+
+// notifyAssignee sends a task notification to the assigned user only, or to
+// the whole account when the task has no assignee.
+func (s *TaskService) notifyAssignee(accountID, assignedTo uuid.UUID, event string, data map[string]interface{}) {
+	if s.hub == nil {
+		return
+	}
+	if assignedTo == uuid.Nil {
+		s.hub.BroadcastToAccount(accountID, event, data)
+		return
+	}
+	s.hub.SendToUser(accountID, assignedTo, event, data)
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/repository"
+	"github.com/naperu/clarin/pkg/webhook"
+)
+
+const (
+	webhookRequestTimeout = 10 * time.Second
+	webhookMaxErrorLength = 500
+	// HeaderWebhookEvent and HeaderWebhookDelivery accompany the signature
+	// headers so receivers can route and deduplicate deliveries.
+	HeaderWebhookEvent    = "X-Clarin-Event"
+	HeaderWebhookDelivery = "X-Clarin-Delivery"
+)
+
+// webhookRetryBackoff is the wait before each retry of a failed delivery.
+var webhookRetryBackoff = []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute}
+
+const (
+	// webhookAttemptLease keeps a delivery being attempted away from the
+	// retry worker; a delivery whose sender died is retried once it lapses.
+	webhookAttemptLease = time.Minute
+	// webhookRetryBatch bounds the deliveries retried per worker pass.
+	webhookRetryBatch = 50
+	// webhookConfigTTL is how long an account's webhook settings are reused
+	// before being read again. Saving the settings on this replica forgets
+	// them immediately.
+	webhookConfigTTL = time.Minute
+)
+
+// WebhookPayload is the JSON body of every delivery.
+type WebhookPayload struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     string      `json:"event"`
+	AccountID uuid.UUID   `json:"account_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookService posts signed event notifications to account webhooks and
+// keeps a log of each delivery.
+type WebhookService struct {
+	repos   *repository.Repositories
+	client  *http.Client
+	backoff []time.Duration
+
+	// hooks caches each account's settings, nil included, so inbound
+	// messages do not each cost a query.
+	hooksMu sync.Mutex
+	hooks   map[uuid.UUID]cachedWebhook
+
+	// signers keeps one Signer per account webhook for the life of the
+	// process, so its nonces keep increasing across deliveries.
+	signersMu sync.Mutex
+	signers   map[uuid.UUID]cachedSigner
+}
+
+type cachedSigner struct {
+	secret string
+	signer *webhook.Signer
+}
+
+type cachedWebhook struct {
+	hook     *domain.AccountWebhook
+	loadedAt time.Time
+}
+
+func NewWebhookService(repos *repository.Repositories) *WebhookService {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+			if err != nil {
+				return nil, err
+			}
+			for _, addr := range addrs {
+				if !isPublicWebhookAddr(addr) {
+					return nil, errors.New("webhook host resolves to a non-public address")
+				}
+			}
+			if len(addrs) == 0 {
+				return nil, errors.New("webhook host resolved to no addresses")
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].String(), port))
+		},
+	}
+	return &WebhookService{
+		repos: repos,
+		client: &http.Client{
+			Timeout:   webhookRequestTimeout,
+			Transport: transport,
+			// A redirect could point at an internal address; treat it as the answer.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		backoff: webhookRetryBackoff,
+		hooks:   make(map[uuid.UUID]cachedWebhook),
+		signers: make(map[uuid.UUID]cachedSigner),
+	}
+}
+
+// webhook returns the account's webhook settings, from the cache while they
+// are fresh.
+func (s *WebhookService) webhook(ctx context.Context, accountID uuid.UUID) (*domain.AccountWebhook, error) {
+	s.hooksMu.Lock()
+	cached, ok := s.hooks[accountID]
+	s.hooksMu.Unlock()
+	if ok && time.Since(cached.loadedAt) < webhookConfigTTL {
+		return cached.hook, nil
+	}
+	hook, err := s.repos.Webhook.Get(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	s.hooksMu.Lock()
+	if s.hooks == nil {
+		s.hooks = make(map[uuid.UUID]cachedWebhook)
+	}
+	s.hooks[accountID] = cachedWebhook{hook: hook, loadedAt: time.Now()}
+	s.hooksMu.Unlock()
+	return hook, nil
+}
+
+// Forget drops the cached settings of accountID after they change.
+func (s *WebhookService) Forget(accountID uuid.UUID) {
+	if s == nil {
+		return
+	}
+	s.hooksMu.Lock()
+	delete(s.hooks, accountID)
+	s.hooksMu.Unlock()
+}
+
+// GenerateWebhookSecret returns a new random signing secret.
+func GenerateWebhookSecret() string {
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	return "whsec_" + hex.EncodeToString(buf)
+}
+
+// ValidateWebhookURL accepts only absolute http(s) URLs on public hosts.
+func ValidateWebhookURL(raw string) error {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return errors.New("la URL del webhook debe ser una dirección http o https absoluta")
+	}
+	if parsed.User != nil {
+		return errors.New("la URL del webhook no debe incluir credenciales")
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return errors.New("la URL del webhook debe apuntar a un servidor público")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublicWebhookAddr(addr) {
+		return errors.New("la URL del webhook debe apuntar a un servidor público")
+	}
+	return nil
+}
+
+// ValidateWebhookEvents rejects unknown event names.
+func ValidateWebhookEvents(events []string) error {
+	for _, event := range events {
+		known := false
+		for _, allowed := range domain.WebhookEvents {
+			if event == allowed {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("evento de webhook desconocido: %s", event)
+		}
+	}
+	return nil
+}
+
+func isPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// Dispatch notifies the account webhook of event when it is subscribed.
+// The first attempt runs in the background; failed deliveries stay pending
+// in the delivery log and RetryDue picks them up, so callers never wait and
+// retries survive a restart.
+func (s *WebhookService) Dispatch(accountID uuid.UUID, event string, data interface{}) {
+	if s == nil || s.repos == nil || s.repos.Webhook == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		hook, err := s.webhook(ctx, accountID)
+		cancel()
+		if err != nil {
+			log.Printf("[Webhook] account=%s event=%s: failed to load webhook: %v", accountID, event, err)
+			return
+		}
+		if !hook.Subscribed(event) {
+			return
+		}
+		delivery, err := s.createDelivery(context.Background(), hook, event, data)
+		if err != nil {
+			log.Printf("[Webhook] account=%s event=%s: failed to log delivery: %v", accountID, event, err)
+			return
+		}
+		s.attempt(context.Background(), hook, delivery, true)
+	}()
+}
+
+// SendTest posts a sample payload to the account webhook once and returns
+// the logged delivery. It does not require the webhook to be enabled and is
+// never retried.
+func (s *WebhookService) SendTest(ctx context.Context, accountID uuid.UUID) (*domain.WebhookDelivery, error) {
+	hook, err := s.repos.Webhook.Get(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil || hook.URL == "" {
+		return nil, errors.New("configura primero la URL del webhook")
+	}
+	delivery, err := s.createDelivery(ctx, hook, domain.WebhookEventTest, map[string]interface{}{
+		"message": "Webhook de prueba de Clarin",
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.attempt(ctx, hook, delivery, false)
+	return delivery, nil
+}
+
+// RetryDue retries the pending deliveries whose next attempt is due. A
+// delivery whose webhook has since been disabled or removed is failed.
+func (s *WebhookService) RetryDue(ctx context.Context) {
+	deliveries, err := s.repos.Webhook.ClaimDueDeliveries(ctx, webhookRetryBatch, webhookAttemptLease)
+	if err != nil {
+		log.Printf("[Webhook] failed to load due deliveries: %v", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		hook, err := s.webhook(ctx, delivery.AccountID)
+		if err != nil {
+			log.Printf("[Webhook] delivery=%s: failed to load webhook: %v", delivery.ID, err)
+			continue
+		}
+		if !hook.Subscribed(delivery.Event) {
+			msg := "el webhook se desactivó antes de entregar el evento"
+			delivery.Status = domain.WebhookDeliveryFailed
+			delivery.Error = &msg
+			delivery.NextAttemptAt = nil
+			if err := s.repos.Webhook.UpdateDelivery(ctx, delivery); err != nil {
+				log.Printf("[Webhook] delivery=%s: failed to record attempt: %v", delivery.ID, err)
+			}
+			continue
+		}
+		s.attempt(ctx, hook, delivery, true)
+	}
+}
+
+// ListDeliveries returns the account's recent deliveries.
+func (s *WebhookService) ListDeliveries(ctx context.Context, accountID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repos.Webhook.ListDeliveries(ctx, accountID, limit)
+}
+
+func (s *WebhookService) createDelivery(ctx context.Context, hook *domain.AccountWebhook, event string, data interface{}) (*domain.WebhookDelivery, error) {
+	payload := WebhookPayload{ID: uuid.New(), Event: event, AccountID: hook.AccountID, CreatedAt: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	// The first attempt holds a lease so the retry worker leaves it alone
+	// unless this process dies before recording the outcome.
+	leaseUntil := time.Now().Add(webhookAttemptLease)
+	delivery := &domain.WebhookDelivery{
+		AccountID:     hook.AccountID,
+		Event:         event,
+		URL:           hook.URL,
+		Payload:       body,
+		Status:        domain.WebhookDeliveryPending,
+		NextAttemptAt: &leaseUntil,
+	}
+	if err := s.repos.Webhook.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// signer returns the account's long-lived Signer, replacing it when the
+// webhook secret changed.
+func (s *WebhookService) signer(hook *domain.AccountWebhook) *webhook.Signer {
+	s.signersMu.Lock()
+	defer s.signersMu.Unlock()
+	if cached, ok := s.signers[hook.AccountID]; ok && cached.secret == hook.Secret {
+		return cached.signer
+	}
+	if s.signers == nil {
+		s.signers = make(map[uuid.UUID]cachedSigner)
+	}
+	signer := webhook.NewSigner(hook.Secret)
+	s.signers[hook.AccountID] = cachedSigner{secret: hook.Secret, signer: signer}
+	return signer
+}
+
+// attempt posts the delivery payload once and records the outcome. A failed
+// attempt is scheduled again after s.backoff while retry is set and retries
+// remain; otherwise the delivery is failed.
+func (s *WebhookService) attempt(ctx context.Context, hook *domain.AccountWebhook, delivery *domain.WebhookDelivery, retry bool) {
+	status, err := s.post(ctx, delivery.URL, s.signer(hook), delivery, delivery.Payload)
+	recordWebhookAttempt(delivery, status, err, s.backoff, retry, time.Now())
+	if updateErr := s.repos.Webhook.UpdateDelivery(context.Background(), delivery); updateErr != nil {
+		log.Printf("[Webhook] delivery=%s: failed to record attempt: %v", delivery.ID, updateErr)
+	}
+	if err != nil {
+		log.Printf("[Webhook] delivery=%s event=%s attempt %d/%d failed: %v", delivery.ID, delivery.Event, delivery.Attempts, len(s.backoff)+1, err)
+	}
+}
+
+// recordWebhookAttempt applies the outcome of one attempt to delivery.
+func recordWebhookAttempt(delivery *domain.WebhookDelivery, status int, err error, backoff []time.Duration, retry bool, now time.Time) {
+	delivery.Attempts++
+	delivery.ResponseStatus = nil
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+	delivery.NextAttemptAt = nil
+	if err == nil {
+		delivery.Status = domain.WebhookDeliveryDelivered
+		delivery.Error = nil
+		delivery.DeliveredAt = &now
+		return
+	}
+	msg := err.Error()
+	if len(msg) > webhookMaxErrorLength {
+		msg = msg[:webhookMaxErrorLength]
+	}
+	delivery.Error = &msg
+	if !retry || delivery.Attempts > len(backoff) {
+		delivery.Status = domain.WebhookDeliveryFailed
+		return
+	}
+	next := now.Add(backoff[delivery.Attempts-1])
+	delivery.Status = domain.WebhookDeliveryPending
+	delivery.NextAttemptAt = &next
+}
+
+func (s *WebhookService) post(ctx context.Context, target string, signer *webhook.Signer, delivery *domain.WebhookDelivery, body []byte) (int, error) {
+	if err := ValidateWebhookURL(target); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Clarin-Webhooks/1.0")
+	req.Header.Set(HeaderWebhookEvent, delivery.Event)
+	req.Header.Set(HeaderWebhookDelivery, delivery.ID.String())
+	for name, value := range signer.Sign(body).Map() {
+		req.Header.Set(name, value)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("el webhook respondió HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
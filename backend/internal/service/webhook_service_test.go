@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/pkg/webhook"
+)
+
+type webhookRoundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f webhookRoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestWebhookPostSignsPayload(t *testing.T) {
+	secret := GenerateWebhookSecret()
+	body := []byte(`{"event":"message.received"}`)
+	delivery := &domain.WebhookDelivery{ID: uuid.New(), Event: domain.WebhookEventMessageReceived}
+
+	var got *http.Request
+	var gotBody []byte
+	svc := &WebhookService{client: &http.Client{Transport: webhookRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		gotBody, _ = io.ReadAll(req.Body)
+		return &http.Response{StatusCode: 204, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})}}
+
+	status, err := svc.post(context.Background(), "https://hooks.example.com/clarin", webhook.NewSigner(secret), delivery, body)
+	if err != nil || status != 204 {
+		t.Fatalf("unexpected result %d %v", status, err)
+	}
+	if got.Header.Get(HeaderWebhookEvent) != domain.WebhookEventMessageReceived || got.Header.Get(HeaderWebhookDelivery) != delivery.ID.String() {
+		t.Fatalf("missing event headers: %v", got.Header)
+	}
+	headers := webhook.Headers{
+		Timestamp: got.Header.Get(webhook.HeaderTimestamp),
+		Nonce:     got.Header.Get(webhook.HeaderNonce),
+		Signature: got.Header.Get(webhook.HeaderSignature),
+	}
+	if err := webhook.NewVerifier(0, nil).Verify(secret, headers, gotBody); err != nil {
+		t.Fatalf("receiver could not verify delivery: %v", err)
+	}
+}
+
+func TestWebhookSignerIsReusedPerAccount(t *testing.T) {
+	svc := &WebhookService{}
+	hook := &domain.AccountWebhook{AccountID: uuid.New(), Secret: "first"}
+	first := svc.signer(hook)
+	if svc.signer(hook) != first {
+		t.Fatal("each delivery got a new signer")
+	}
+	a, b := first.Sign([]byte(`{}`)), svc.signer(hook).Sign([]byte(`{}`))
+	if a.Nonce == b.Nonce {
+		t.Fatalf("nonce %q was repeated", a.Nonce)
+	}
+
+	if svc.signer(&domain.AccountWebhook{AccountID: uuid.New(), Secret: "first"}) == first {
+		t.Fatal("accounts share a signer")
+	}
+	hook.Secret = "rotated"
+	rotated := svc.signer(hook)
+	if rotated == first {
+		t.Fatal("signer kept the old secret")
+	}
+	if err := webhook.NewVerifier(time.Minute, webhook.NewMemoryNonceStore()).Verify("rotated", rotated.Sign([]byte(`{}`)), []byte(`{}`)); err != nil {
+		t.Fatalf("rotated signer: %v", err)
+	}
+}
+
+func TestWebhookPostReportsNon2xx(t *testing.T) {
+	svc := &WebhookService{client: &http.Client{Transport: webhookRoundTripFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 500, Body: io.NopCloser(strings.NewReader("boom"))}, nil
+	})}}
+	delivery := &domain.WebhookDelivery{ID: uuid.New(), Event: domain.WebhookEventTest}
+	status, err := svc.post(context.Background(), "https://hooks.example.com/clarin", webhook.NewSigner("s"), delivery, []byte(`{}`))
+	if err == nil || status != 500 {
+		t.Fatalf("expected HTTP 500 failure, got %d %v", status, err)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, raw := range []string{"https://hooks.example.com/in", "http://203.0.113.7:8080/hook"} {
+		if err := ValidateWebhookURL(raw); err != nil {
+			t.Fatalf("%s rejected: %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"ftp://example.com", "https://localhost/hook", "http://127.0.0.1/hook", "http://10.0.0.5/hook", "http://169.254.169.254/latest", "https://user:pw@example.com", "/relative"} {
+		if err := ValidateWebhookURL(raw); err == nil {
+			t.Fatalf("%s should be rejected", raw)
+		}
+	}
+}
+
+func TestAccountWebhookSubscribed(t *testing.T) {
+	hook := &domain.AccountWebhook{URL: "https://hooks.example.com", Enabled: true, Events: []string{domain.WebhookEventMessageReceived}}
+	if !hook.Subscribed(domain.WebhookEventMessageReceived) || hook.Subscribed(domain.WebhookEventLeadCreated) {
+		t.Fatal("unexpected subscription result")
+	}
+	hook.Enabled = false
+	if hook.Subscribed(domain.WebhookEventMessageReceived) {
+		t.Fatal("disabled webhook must not be subscribed")
+	}
+	if err := ValidateWebhookEvents([]string{"message.deleted"}); err == nil {
+		t.Fatal("expected unknown event to be rejected")
+	}
+}
+
+func TestRecordWebhookAttemptSchedulesPersistedRetries(t *testing.T) {
+	backoff := []time.Duration{10 * time.Second, time.Minute}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	delivery := &domain.WebhookDelivery{Status: domain.WebhookDeliveryPending}
+
+	recordWebhookAttempt(delivery, 500, errors.New("boom"), backoff, true, now)
+	if delivery.Status != domain.WebhookDeliveryPending || delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("first failure not scheduled for retry: %+v", delivery)
+	}
+	recordWebhookAttempt(delivery, 0, errors.New("timeout"), backoff, true, now)
+	if delivery.ResponseStatus != nil || delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("second failure not scheduled with the next backoff: %+v", delivery)
+	}
+	recordWebhookAttempt(delivery, 502, errors.New("bad gateway"), backoff, true, now)
+	if delivery.Status != domain.WebhookDeliveryFailed || delivery.NextAttemptAt != nil || delivery.Attempts != 3 {
+		t.Fatalf("delivery not failed after its last retry: %+v", delivery)
+	}
+
+	ok := &domain.WebhookDelivery{Status: domain.WebhookDeliveryPending}
+	recordWebhookAttempt(ok, 200, nil, backoff, true, now)
+	if ok.Status != domain.WebhookDeliveryDelivered || ok.DeliveredAt == nil || ok.NextAttemptAt != nil {
+		t.Fatalf("successful delivery not recorded: %+v", ok)
+	}
+
+	test := &domain.WebhookDelivery{Status: domain.WebhookDeliveryPending}
+	recordWebhookAttempt(test, 500, errors.New("boom"), backoff, false, now)
+	if test.Status != domain.WebhookDeliveryFailed || test.NextAttemptAt != nil {
+		t.Fatalf("non-retried delivery left pending: %+v", test)
+	}
+}
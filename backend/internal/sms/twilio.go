@@ -0,0 +1,71 @@
+// Package sms sends plain-text SMS through external providers. It is used as
+// an optional fallback channel for campaign recipients without WhatsApp.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const twilioAPIBase = "https://api.twilio.com/2010-04-01"
+
+// TwilioSender sends messages through the Twilio Programmable Messaging API.
+type TwilioSender struct {
+	httpClient *http.Client
+	baseURL    string
+	accountSID string
+	authToken  string
+	from       string
+}
+
+// NewTwilioSender creates a sender for one Twilio account and sender number.
+func NewTwilioSender(accountSID, authToken, from string) *TwilioSender {
+	return &TwilioSender{
+		httpClient: &http.Client{Timeout: 20 * time.Second},
+		baseURL:    twilioAPIBase,
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+	}
+}
+
+// Send delivers body to the E.164 number to.
+func (t *TwilioSender) Send(ctx context.Context, to, body string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", t.from)
+	form.Set("Body", body)
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", t.baseURL, url.PathEscape(t.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apiErr struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &apiErr) == nil && apiErr.Message != "" {
+		return fmt.Errorf("twilio error %d: %s", apiErr.Code, apiErr.Message)
+	}
+	return fmt.Errorf("twilio returned status %d", resp.StatusCode)
+}
@@ -0,0 +1,50 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTwilioSenderPostsMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Accounts/AC123/Messages.json" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "AC123" || pass != "secret" {
+			t.Errorf("missing basic auth")
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.PostForm.Get("To") != "+51999999999" || r.PostForm.Get("From") != "+15550000000" || r.PostForm.Get("Body") != "Hola" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM1"}`))
+	}))
+	defer server.Close()
+
+	sender := NewTwilioSender("AC123", "secret", "+15550000000")
+	sender.baseURL = server.URL
+	if err := sender.Send(context.Background(), "+51999999999", "Hola"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
+
+func TestTwilioSenderSurfacesAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":21211,"message":"Invalid 'To' Phone Number"}`))
+	}))
+	defer server.Close()
+
+	sender := NewTwilioSender("AC123", "secret", "+15550000000")
+	sender.baseURL = server.URL
+	err := sender.Send(context.Background(), "+1", "Hola")
+	if err == nil || !strings.Contains(err.Error(), "21211") {
+		t.Fatalf("expected Twilio error code, got %v", err)
+	}
+}
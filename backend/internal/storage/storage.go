@@ -3,8 +3,10 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
@@ -150,6 +152,21 @@ func IsAccountStatusObjectKey(accountID uuid.UUID, objectKey string) bool {
 	return IsAccountPrivateStatusObjectKey(accountID, objectKey) || IsAccountLegacyStatusObjectKey(accountID, objectKey)
 }
 
+// IsAccountDownloadableObjectKey reports whether objectKey is a normalized
+// key under accountID's prefix that may be handed out as a direct download.
+// Every private-bucket key (avatars and statuses alike) and the legacy status
+// namespace are excluded; those are only served through authorized proxies.
+func IsAccountDownloadableObjectKey(accountID uuid.UUID, objectKey string) bool {
+	raw := strings.TrimSpace(objectKey)
+	if raw == "" || strings.HasPrefix(raw, "/") || path.Clean(raw) != raw {
+		return false
+	}
+	if IsPrivateObjectKey(raw) || IsLegacyStatusObjectKey(raw) {
+		return false
+	}
+	return strings.HasPrefix(raw, accountID.String()+"/")
+}
+
 func (s *Storage) bucketForObjectKey(objectKey string) string {
 	if IsPrivateObjectKey(objectKey) {
 		return s.privateBucket
@@ -298,6 +315,31 @@ func (s *Storage) GetPresignedUploadURL(ctx context.Context, accountID uuid.UUID
 	return urlStr, nil
 }
 
+// GetPresignedDownloadURL generates a short-lived URL that downloads
+// objectKey directly from storage as an attachment named filename. Private
+// and legacy status keys are refused, so only public-bucket objects are ever
+// presigned.
+func (s *Storage) GetPresignedDownloadURL(ctx context.Context, objectKey, filename string, expiry time.Duration) (string, error) {
+	if IsPrivateObjectKey(objectKey) || IsLegacyStatusObjectKey(objectKey) {
+		return "", fmt.Errorf("object key is not downloadable")
+	}
+	params := url.Values{}
+	if filename != "" {
+		params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	presignedURL, err := s.client.PresignedGetObject(ctx, s.bucket, objectKey, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	urlStr := presignedURL.String()
+	if s.publicURL != "" && s.internalURL != "" {
+		urlStr = strings.Replace(urlStr, s.internalURL, s.publicURL, 1)
+	}
+
+	return urlStr, nil
+}
+
 // GetFile retrieves a file from storage
 func (s *Storage) GetFile(ctx context.Context, objectKey string) ([]byte, error) {
 	object, err := s.client.GetObject(ctx, s.bucketForObjectKey(objectKey), objectKey, minio.GetObjectOptions{})
@@ -314,11 +356,46 @@ func (s *Storage) GetFile(ctx context.Context, objectKey string) ([]byte, error)
 	return data, nil
 }
 
+// OpenFileRange returns a reader over length bytes of an object starting at
+// offset; a non-positive length reads to the end. The object is fetched as
+// the reader is consumed, so callers can stream large media without holding
+// it in memory. The request is issued before returning, so a missing object
+// or a failed read surfaces here rather than after response headers are
+// written. Callers must close the reader.
+func (s *Storage) OpenFileRange(ctx context.Context, objectKey string, offset, length int64) (io.ReadCloser, error) {
+	opts := minio.GetObjectOptions{}
+	if length > 0 {
+		if err := opts.SetRange(offset, offset+length-1); err != nil {
+			return nil, fmt.Errorf("invalid file range: %w", err)
+		}
+	} else if offset > 0 {
+		if err := opts.SetRange(offset, 0); err != nil {
+			return nil, fmt.Errorf("invalid file range: %w", err)
+		}
+	}
+	object, err := s.client.GetObject(ctx, s.bucketForObjectKey(objectKey), objectKey, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	// GetObject is lazy; Stat performs the request.
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	return object, nil
+}
+
 // GetFileInfo retrieves file metadata (size, content-type) from storage
 func (s *Storage) GetFileInfo(ctx context.Context, objectKey string) (minio.ObjectInfo, error) {
 	return s.client.StatObject(ctx, s.bucketForObjectKey(objectKey), objectKey, minio.StatObjectOptions{})
 }
 
+// IsNotFound reports whether err means the object doesn't exist in storage.
+func IsNotFound(err error) bool {
+	var resp minio.ErrorResponse
+	return errors.As(err, &resp) && (resp.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound)
+}
+
 // GetFileRange retrieves a byte range of a file from storage
 func (s *Storage) GetFileRange(ctx context.Context, objectKey string, offset, length int64) ([]byte, error) {
 	opts := minio.GetObjectOptions{}
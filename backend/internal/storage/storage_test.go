@@ -2,11 +2,14 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
 )
 
 func TestPrivateObjectKey(t *testing.T) {
@@ -63,6 +66,39 @@ func TestAccountScopedObjectKey(t *testing.T) {
 	}
 }
 
+func TestIsAccountDownloadableObjectKey(t *testing.T) {
+	accountID := uuid.New()
+	if !IsAccountDownloadableObjectKey(accountID, accountID.String()+"/media/mp4/video.mp4") {
+		t.Fatal("account media key was rejected")
+	}
+	for _, key := range []string{
+		"",
+		uuid.NewString() + "/media/mp4/video.mp4",
+		"/" + accountID.String() + "/media/mp4/video.mp4",
+		accountID.String() + "/media/../../" + uuid.NewString() + "/video.mp4",
+		accountID.String() + "/_private/statuses/photo.webp",
+		accountID.String() + "/_private/avatars/contact.jpg",
+		accountID.String() + "/statuses/photo.webp",
+		accountID.String(),
+	} {
+		if IsAccountDownloadableObjectKey(accountID, key) {
+			t.Fatalf("unsafe download key accepted: %q", key)
+		}
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if !IsNotFound(minio.ErrorResponse{Code: "NoSuchKey", StatusCode: http.StatusNotFound}) {
+		t.Fatal("NoSuchKey must be reported as not found")
+	}
+	if !IsNotFound(fmt.Errorf("stat: %w", minio.ErrorResponse{StatusCode: http.StatusNotFound})) {
+		t.Fatal("a wrapped 404 must be reported as not found")
+	}
+	if IsNotFound(minio.ErrorResponse{Code: "AccessDenied", StatusCode: http.StatusForbidden}) || IsNotFound(errors.New("connection refused")) || IsNotFound(nil) {
+		t.Fatal("other errors must not be reported as not found")
+	}
+}
+
 func TestPublicAndPrivateBucketsIntegration(t *testing.T) {
 	if os.Getenv("CLARIN_RUN_STORAGE_INTEGRATION") != "1" {
 		t.Skip("set CLARIN_RUN_STORAGE_INTEGRATION=1 with a disposable MinIO endpoint")
@@ -0,0 +1,41 @@
+package whatsapp
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/naperu/clarin/internal/ws"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// handleChatReadElsewhere lowers the unread counter of a chat that was read
+// up to readAt on the phone or another linked device and tells the web
+// clients. Messages that arrived after readAt stay unread.
+func (p *DevicePool) handleChatReadElsewhere(ctx context.Context, instance *DeviceInstance, chat types.JID, readAt time.Time) {
+	chatJID := chat.ToNonAD().String()
+	if chat.Server == types.HiddenUserServer && p.store != nil && p.store.LIDMap != nil {
+		if pnJID, err := p.store.LIDMap.GetPNForLID(ctx, chat.ToNonAD()); err == nil && !pnJID.IsEmpty() {
+			chatJID = pnJID.User + "@s.whatsapp.net"
+		}
+	}
+
+	if readAt.IsZero() {
+		readAt = time.Now()
+	}
+	chatID, unread, changed, err := p.repos.Chat.MarkReadUpToByJID(ctx, instance.AccountID, chatJID, readAt)
+	if err != nil {
+		log.Printf("[Read] Failed to clear unread count of %s: %v", chatJID, err)
+		return
+	}
+	if !changed {
+		return
+	}
+	p.invalidateChatCaches(instance.AccountID, chatID)
+	p.BroadcastUnreadSummary(instance.AccountID)
+	p.hub.BroadcastToAccount(instance.AccountID, ws.EventChatUpdate, map[string]interface{}{
+		"chat_id":      chatID.String(),
+		"chat_jid":     chatJID,
+		"unread_count": unread,
+	})
+}
@@ -0,0 +1,53 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// SetChatArchived archives or unarchives a chat on the device's phone.
+// lastMessageAt may be zero when unknown.
+func (p *DevicePool) SetChatArchived(ctx context.Context, deviceID uuid.UUID, chatJID string, archived bool, lastMessageAt time.Time) error {
+	jid, err := parseChatJID(chatJID)
+	if err != nil {
+		return err
+	}
+	return p.sendChatAppState(ctx, deviceID, appstate.BuildArchive(jid, archived, lastMessageAt, nil))
+}
+
+// SetChatPinned pins or unpins a chat on the device's phone.
+func (p *DevicePool) SetChatPinned(ctx context.Context, deviceID uuid.UUID, chatJID string, pinned bool) error {
+	jid, err := parseChatJID(chatJID)
+	if err != nil {
+		return err
+	}
+	return p.sendChatAppState(ctx, deviceID, appstate.BuildPin(jid, pinned))
+}
+
+func (p *DevicePool) sendChatAppState(ctx context.Context, deviceID uuid.UUID, patch appstate.PatchInfo) error {
+	p.mu.RLock()
+	instance, exists := p.devices[deviceID]
+	p.mu.RUnlock()
+
+	if !exists || instance.Client == nil || !instance.Client.IsLoggedIn() {
+		return fmt.Errorf("device not connected: %s", deviceID)
+	}
+	return instance.Client.SendAppState(ctx, patch)
+}
+
+func parseChatJID(chatJID string) (types.JID, error) {
+	if !strings.Contains(chatJID, "@") {
+		return types.NewJID(chatJID, types.DefaultUserServer), nil
+	}
+	jid, err := types.ParseJID(chatJID)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid chat JID: %s", chatJID)
+	}
+	return jid.ToNonAD(), nil
+}
@@ -0,0 +1,17 @@
+package whatsapp
+
+import "testing"
+
+func TestParseChatJID(t *testing.T) {
+	cases := map[string]string{
+		"51999000111":                  "51999000111@s.whatsapp.net",
+		"51999000111:3@s.whatsapp.net": "51999000111@s.whatsapp.net",
+		"120363000000000001@g.us":      "120363000000000001@g.us",
+	}
+	for in, want := range cases {
+		jid, err := parseChatJID(in)
+		if err != nil || jid.String() != want {
+			t.Fatalf("parseChatJID(%q) = %q, %v; want %q", in, jid.String(), err, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package whatsapp
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	// DeviceHealthCheckInterval is how often StartHealthMonitor checks the sockets.
+	DeviceHealthCheckInterval = 30 * time.Second
+	// keepAliveFailureLimit is how many consecutive keepalive timeouts force a
+	// reconnect instead of waiting for the TCP connection to notice.
+	keepAliveFailureLimit = 3
+	// deviceEventRetention bounds the device_events history.
+	deviceEventRetention     = 30 * 24 * time.Hour
+	deviceEventPruneInterval = 6 * time.Hour
+)
+
+// Socket states reported by the device health endpoint.
+const (
+	DeviceSocketOpen            = "open"
+	DeviceSocketUnauthenticated = "unauthenticated"
+	DeviceSocketReconnecting    = "reconnecting"
+	DeviceSocketClosed          = "closed"
+)
+
+// StartHealthMonitor periodically checks every device that should be online.
+// A device whose socket dropped without a Disconnected event is marked as
+// disconnected, which broadcasts the transition and starts the reconnect
+// supervisor. The same loop prunes old device events. It stops on Shutdown.
+func (p *DevicePool) StartHealthMonitor(interval time.Duration) {
+	p.mu.Lock()
+	if p.stopHealth != nil {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.stopHealth = stop
+	p.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		pruneTicker := time.NewTicker(deviceEventPruneInterval)
+		defer ticker.Stop()
+		defer pruneTicker.Stop()
+		p.pruneDeviceEvents()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.checkDevicesHealth()
+			case <-pruneTicker.C:
+				p.pruneDeviceEvents()
+			}
+		}
+	}()
+}
+
+func (p *DevicePool) checkDevicesHealth() {
+	p.mu.RLock()
+	instances := make([]*DeviceInstance, 0, len(p.devices))
+	for _, instance := range p.devices {
+		instances = append(instances, instance)
+	}
+	p.mu.RUnlock()
+
+	for _, instance := range instances {
+		p.checkDeviceHealth(instance)
+	}
+}
+
+func (p *DevicePool) checkDeviceHealth(instance *DeviceInstance) {
+	instance.mu.Lock()
+	if instance.Client == nil || instance.Status != domain.DeviceStatusConnected {
+		instance.mu.Unlock()
+		return
+	}
+	alive := instance.Client.IsConnected() && instance.Client.IsLoggedIn()
+	if alive {
+		instance.Metrics.LastSeen = time.Now()
+	}
+	instance.mu.Unlock()
+
+	if !alive {
+		log.Printf("[Health %s] Socket is down while marked connected", instance.ID)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		p.handleDisconnected(ctx, instance, "socket_down")
+	}
+}
+
+// handleKeepAliveTimeout forces a reconnect once keepalives have failed
+// keepAliveFailureLimit times in a row.
+func (p *DevicePool) handleKeepAliveTimeout(ctx context.Context, instance *DeviceInstance, evt *events.KeepAliveTimeout) {
+	instance.mu.Lock()
+	instance.Metrics.KeepAliveFailures = evt.ErrorCount
+	client := instance.Client
+	connected := instance.Status == domain.DeviceStatusConnected
+	instance.mu.Unlock()
+
+	log.Printf("[Health %s] Keepalive timeout (%d in a row, last success %s)", instance.ID, evt.ErrorCount, evt.LastSuccess.Format(time.RFC3339))
+	if evt.ErrorCount < keepAliveFailureLimit || !connected || client == nil {
+		return
+	}
+	client.Disconnect()
+	p.handleDisconnected(ctx, instance, "keepalive_timeout")
+}
+
+func (p *DevicePool) handleKeepAliveRestored(instance *DeviceInstance) {
+	instance.mu.Lock()
+	instance.Metrics.KeepAliveFailures = 0
+	instance.Metrics.LastSeen = time.Now()
+	instance.mu.Unlock()
+}
+
+// GetDeviceHealth returns the health summary of one device in the pool.
+func (p *DevicePool) GetDeviceHealth(deviceID uuid.UUID) (DeviceHealthSummary, bool) {
+	p.mu.RLock()
+	instance, ok := p.devices[deviceID]
+	p.mu.RUnlock()
+	if !ok {
+		return DeviceHealthSummary{}, false
+	}
+	return instance.healthSummary(), true
+}
+
+func (instance *DeviceInstance) healthSummary() DeviceHealthSummary {
+	instance.mu.RLock()
+	defer instance.mu.RUnlock()
+	connected := instance.Client != nil && instance.Client.IsConnected()
+	loggedIn := connected && instance.Client.IsLoggedIn()
+	return DeviceHealthSummary{
+		ID:           instance.ID,
+		JID:          instance.JID,
+		Status:       instance.Status,
+		Connected:    connected,
+		Socket:       deviceSocketState(connected, loggedIn, instance.reconnecting),
+		Reconnecting: instance.reconnecting,
+		Metrics:      instance.Metrics,
+	}
+}
+
+func deviceSocketState(connected, loggedIn, reconnecting bool) string {
+	switch {
+	case connected && loggedIn:
+		return DeviceSocketOpen
+	case connected:
+		return DeviceSocketUnauthenticated
+	case reconnecting:
+		return DeviceSocketReconnecting
+	default:
+		return DeviceSocketClosed
+	}
+}
+
+// recordDeviceEvent stores a connection history entry; a failed write is
+// only logged.
+func (p *DevicePool) recordDeviceEvent(instance *DeviceInstance, eventType, detail string) {
+	if p.repos == nil || p.repos.Device == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.repos.Device.RecordEvent(ctx, instance.AccountID, instance.ID, eventType, detail); err != nil {
+		log.Printf("[Device %s] Failed to record %s event: %v", instance.ID, eventType, err)
+	}
+}
+
+func (p *DevicePool) pruneDeviceEvents() {
+	if p.repos == nil || p.repos.Device == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	n, err := p.repos.Device.PruneEvents(ctx, deviceEventRetention)
+	if err != nil {
+		log.Printf("[Health] Failed to prune device events: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Health] Pruned %d device events older than %s", n, deviceEventRetention)
+	}
+}
@@ -0,0 +1,45 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestDeviceSocketState(t *testing.T) {
+	cases := []struct {
+		connected, loggedIn, reconnecting bool
+		want                              string
+	}{
+		{true, true, false, DeviceSocketOpen},
+		{true, false, false, DeviceSocketUnauthenticated},
+		{false, false, true, DeviceSocketReconnecting},
+		{false, false, false, DeviceSocketClosed},
+	}
+	for _, tc := range cases {
+		if got := deviceSocketState(tc.connected, tc.loggedIn, tc.reconnecting); got != tc.want {
+			t.Fatalf("deviceSocketState(%v, %v, %v) = %q, want %q", tc.connected, tc.loggedIn, tc.reconnecting, got, tc.want)
+		}
+	}
+}
+
+func TestGetDeviceHealthWithoutClient(t *testing.T) {
+	id := uuid.New()
+	instance := &DeviceInstance{ID: id, Status: domain.DeviceStatusDisconnected, reconnecting: true}
+	instance.Metrics.ReconnectAttempts = 4
+	pool := &DevicePool{devices: map[uuid.UUID]*DeviceInstance{id: instance}}
+
+	health, ok := pool.GetDeviceHealth(id)
+	if !ok {
+		t.Fatal("device not found")
+	}
+	if health.Connected || health.Socket != DeviceSocketReconnecting || health.Metrics.ReconnectAttempts != 4 {
+		t.Fatalf("health = %+v", health)
+	}
+	if _, ok := pool.GetDeviceHealth(uuid.New()); ok {
+		t.Fatal("unknown device reported as present")
+	}
+	// checkDeviceHealth ignores devices that are not marked connected.
+	pool.checkDeviceHealth(instance)
+}
@@ -112,15 +112,22 @@ type DeviceHealthMetrics struct {
 	LastSendError    time.Time `json:"last_send_error,omitempty"`
 	LastSendSuccess  time.Time `json:"last_send_success,omitempty"`
 	UptimeStart      time.Time `json:"uptime_start"`
+	// LastSeen is the last time the health monitor found the socket alive.
+	LastSeen time.Time `json:"last_seen"`
+	// ReconnectAttempts counts attempts in the current outage; it resets on connect.
+	ReconnectAttempts int `json:"reconnect_attempts"`
+	KeepAliveFailures int `json:"keepalive_failures"`
 }
 
 // DeviceHealthSummary is returned by the health endpoint
 type DeviceHealthSummary struct {
-	ID        uuid.UUID           `json:"id"`
-	JID       string              `json:"jid"`
-	Status    string              `json:"status"`
-	Connected bool                `json:"connected"`
-	Metrics   DeviceHealthMetrics `json:"metrics"`
+	ID           uuid.UUID           `json:"id"`
+	JID          string              `json:"jid"`
+	Status       string              `json:"status"`
+	Connected    bool                `json:"connected"`
+	Socket       string              `json:"socket"`
+	Reconnecting bool                `json:"reconnecting"`
+	Metrics      DeviceHealthMetrics `json:"metrics"`
 }
 
 // DeviceInstance represents a single WhatsApp connection
@@ -137,6 +144,9 @@ type DeviceInstance struct {
 	// reconnect control
 	reconnecting  bool
 	stopReconnect chan struct{}
+	// pairing: raw QR code and its live subscribers
+	qrRaw  string
+	qrSubs map[chan QRUpdate]struct{}
 }
 
 // DevicePool manages multiple WhatsApp connections
@@ -164,6 +174,8 @@ type DevicePool struct {
 	mu                  sync.RWMutex
 	startTime           time.Time
 	onDemandSyncTargets map[uuid.UUID]*onDemandSyncTarget // one active request per device
+	inboundHook         func(chat *domain.Chat, msg *domain.Message)
+	stopHealth          chan struct{}
 }
 
 // NewDevicePool creates a new device pool
@@ -205,6 +217,13 @@ func (p *DevicePool) SetCache(c *cache.Cache) {
 	p.cache = c
 }
 
+// SetInboundMessageHook registers a callback run after every stored incoming
+// message. It lets the API layer react to messages (auto-tagging, webhooks)
+// without the pool depending on services.
+func (p *DevicePool) SetInboundMessageHook(fn func(chat *domain.Chat, msg *domain.Message)) {
+	p.inboundHook = fn
+}
+
 func (p *DevicePool) invalidateChatCaches(accountID, chatID uuid.UUID) {
 	if p.cache == nil {
 		return
@@ -213,6 +232,33 @@ func (p *DevicePool) invalidateChatCaches(accountID, chatID uuid.UUID) {
 	_ = p.cache.DelPattern(context.Background(), "messages:"+accountID.String()+":"+chatID.String()+":*")
 }
 
+// BroadcastUnreadSummary pushes fresh unread totals to the account's clients
+// after a chat's unread counter changed. It is safe to call on a nil pool.
+func (p *DevicePool) BroadcastUnreadSummary(accountID uuid.UUID) {
+	if p == nil || p.hub == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		summary, err := p.repos.Chat.GetUnreadSummary(ctx, accountID, nil)
+		if err != nil {
+			log.Printf("[Chats] unread summary failed for account %s: %v", accountID, err)
+			return
+		}
+		p.hub.BroadcastAccountAggregate(accountID, ws.EventUnreadSummary, summary)
+		// Agents limited to their assigned chats get totals over those only.
+		for _, scope := range p.hub.RestrictedScopes(accountID) {
+			scoped, err := p.repos.Chat.GetUnreadSummary(ctx, accountID, scope)
+			if err != nil {
+				log.Printf("[Chats] unread summary failed for user %s in account %s: %v", scope.UserID, accountID, err)
+				continue
+			}
+			p.hub.SendToUser(accountID, scope.UserID, ws.EventUnreadSummary, scoped)
+		}
+	}()
+}
+
 func (p *DevicePool) invalidateAccountMessageCaches(accountID uuid.UUID) {
 	if p.cache == nil {
 		return
@@ -392,6 +438,7 @@ func (p *DevicePool) ConnectDevice(ctx context.Context, deviceID uuid.UUID) erro
 
 // handleQRChannel handles QR code events
 func (p *DevicePool) handleQRChannel(ctx context.Context, instance *DeviceInstance, qrChan <-chan whatsmeow.QRChannelItem) {
+	qrRecorded := false
 	for evt := range qrChan {
 		switch evt.Event {
 		case whatsmeow.QRChannelEventCode:
@@ -413,6 +460,12 @@ func (p *DevicePool) handleQRChannel(ctx context.Context, instance *DeviceInstan
 
 			// Broadcast to frontend
 			p.hub.BroadcastQRCode(instance.AccountID, instance.ID, qrBase64)
+			instance.setQRCode(evt.Code, qrBase64, domain.DeviceStatusConnecting)
+			// The code rotates every few seconds; one history entry per pairing is enough.
+			if !qrRecorded {
+				p.recordDeviceEvent(instance, domain.DeviceEventQR, "")
+				qrRecorded = true
+			}
 			log.Printf("[QR] New QR code generated for device %s", instance.ID)
 
 		case "success":
@@ -428,6 +481,7 @@ func (p *DevicePool) handleQRChannel(ctx context.Context, instance *DeviceInstan
 				log.Printf("[QR] Failed to persist timeout status for device %s: %v", instance.ID, err)
 			}
 			p.hub.BroadcastDeviceStatus(instance.AccountID, instance.ID, domain.DeviceStatusDisconnected, "")
+			instance.setQRCode("", "", domain.DeviceStatusDisconnected)
 
 		case whatsmeow.QRChannelEventPasskeyRequest:
 			p.failUnsupportedPairing(ctx, instance, "WhatsApp requested passkey authentication")
@@ -471,6 +525,7 @@ func (p *DevicePool) failUnsupportedPairing(ctx context.Context, instance *Devic
 		log.Printf("[QR] Failed to persist pairing failure for device %s: %v", instance.ID, err)
 	}
 	p.hub.BroadcastDeviceStatus(instance.AccountID, instance.ID, domain.DeviceStatusDisconnected, "")
+	instance.setQRCode("", "", domain.DeviceStatusDisconnected)
 	if instance.Client != nil {
 		instance.Client.Disconnect()
 	}
@@ -486,7 +541,13 @@ func (p *DevicePool) handleEvent(ctx context.Context, instance *DeviceInstance,
 		p.handleLoggedOut(ctx, instance, evt)
 
 	case *events.Disconnected:
-		p.handleDisconnected(ctx, instance)
+		p.handleDisconnected(ctx, instance, "")
+
+	case *events.KeepAliveTimeout:
+		p.handleKeepAliveTimeout(ctx, instance, evt)
+
+	case *events.KeepAliveRestored:
+		p.handleKeepAliveRestored(instance)
 
 	case *events.Message:
 		p.handleMessage(ctx, instance, evt)
@@ -494,6 +555,15 @@ func (p *DevicePool) handleEvent(ctx context.Context, instance *DeviceInstance,
 	case *events.Receipt:
 		p.handleReceipt(ctx, instance, evt)
 
+	case *events.MarkChatAsRead:
+		if evt.Action.GetRead() {
+			readAt := evt.Timestamp
+			if last := evt.Action.GetMessageRange().GetLastMessageTimestamp(); last > 0 {
+				readAt = time.Unix(last, 0)
+			}
+			p.handleChatReadElsewhere(ctx, instance, evt.JID, readAt)
+		}
+
 	case *events.ChatPresence:
 		p.handleChatPresence(ctx, instance, evt)
 
@@ -506,6 +576,12 @@ func (p *DevicePool) handleEvent(ctx context.Context, instance *DeviceInstance,
 	case *events.Contact:
 		p.handleContactEvent(ctx, instance, evt)
 
+	case *events.GroupInfo:
+		p.handleGroupInfo(ctx, instance, evt)
+
+	case *events.JoinedGroup:
+		p.handleJoinedGroup(ctx, instance, evt)
+
 	case *events.HistorySync:
 		log.Printf("[HistorySync] EVENT RECEIVED: type=%v, conversations=%d, device=%s",
 			evt.Data.GetSyncType(), len(evt.Data.Conversations), instance.ID)
@@ -536,6 +612,9 @@ func (p *DevicePool) handleConnected(ctx context.Context, instance *DeviceInstan
 	instance.QRCode = ""
 	instance.Metrics.LastConnected = time.Now()
 	instance.Metrics.UptimeStart = time.Now()
+	instance.Metrics.LastSeen = time.Now()
+	instance.Metrics.ReconnectAttempts = 0
+	instance.Metrics.KeepAliveFailures = 0
 	// Stop any active reconnect supervisor since we're connected now
 	if instance.reconnecting {
 		instance.reconnecting = false
@@ -551,11 +630,14 @@ func (p *DevicePool) handleConnected(ctx context.Context, instance *DeviceInstan
 
 	// Broadcast status
 	p.hub.BroadcastDeviceStatus(instance.AccountID, instance.ID, domain.DeviceStatusConnected, "")
+	instance.setQRCode("", "", domain.DeviceStatusConnected)
+	p.recordDeviceEvent(instance, domain.DeviceEventConnected, jid)
 
 	log.Printf("[Device %s] Connected as %s", instance.ID, jid)
 
-	// Sync contacts in background after connection
+	// Sync contacts and group metadata in background after connection
 	go p.syncContacts(context.Background(), instance)
+	go p.syncGroupsAfterConnect(instance)
 }
 
 // handleLoggedOut processes logout events
@@ -575,12 +657,15 @@ func (p *DevicePool) handleLoggedOut(ctx context.Context, instance *DeviceInstan
 
 	_ = p.repos.Device.UpdateStatus(ctx, instance.ID, domain.DeviceStatusLoggedOut)
 	p.hub.BroadcastDeviceStatus(instance.AccountID, instance.ID, domain.DeviceStatusLoggedOut, "")
+	instance.setQRCode("", "", domain.DeviceStatusLoggedOut)
+	p.recordDeviceEvent(instance, domain.DeviceEventLoggedOut, evt.Reason.String())
 
 	log.Printf("[Device %s] Logged out: %s", instance.ID, evt.Reason)
 }
 
-// handleDisconnected processes disconnection events
-func (p *DevicePool) handleDisconnected(ctx context.Context, instance *DeviceInstance) {
+// handleDisconnected processes disconnection events. reason is recorded in the
+// device history; it is empty for disconnects reported by whatsmeow.
+func (p *DevicePool) handleDisconnected(ctx context.Context, instance *DeviceInstance, reason string) {
 	instance.mu.Lock()
 	instance.Status = domain.DeviceStatusDisconnected
 	instance.Metrics.DisconnectCount++
@@ -590,6 +675,7 @@ func (p *DevicePool) handleDisconnected(ctx context.Context, instance *DeviceIns
 
 	_ = p.repos.Device.UpdateStatus(ctx, instance.ID, domain.DeviceStatusDisconnected)
 	p.hub.BroadcastDeviceStatus(instance.AccountID, instance.ID, domain.DeviceStatusDisconnected, "")
+	p.recordDeviceEvent(instance, domain.DeviceEventDisconnected, reason)
 
 	log.Printf("[Device %s] Disconnected (total disconnects: %d)", instance.ID, instance.Metrics.DisconnectCount)
 
@@ -660,6 +746,7 @@ func (p *DevicePool) reconnectSupervisor(instance *DeviceInstance) {
 
 		instance.mu.Lock()
 		instance.Metrics.ReconnectCount++
+		instance.Metrics.ReconnectAttempts = attempt
 		instance.mu.Unlock()
 
 		err := p.ConnectDevice(context.Background(), instance.ID)
@@ -737,6 +824,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 				r.MediaURL = &stored.URL
 				r.MediaAssetID = stored.AssetID
 				r.MediaSize = &stored.SizeBytes
+				r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 			}
 		}
 	} else if vidMsg := waMsg.GetVideoMessage(); vidMsg != nil {
@@ -752,6 +840,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 				r.MediaURL = &stored.URL
 				r.MediaAssetID = stored.AssetID
 				r.MediaSize = &stored.SizeBytes
+				r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 			}
 		}
 	} else if audMsg := waMsg.GetAudioMessage(); audMsg != nil {
@@ -764,6 +853,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 				r.MediaURL = &stored.URL
 				r.MediaAssetID = stored.AssetID
 				r.MediaSize = &stored.SizeBytes
+				r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 			}
 		}
 	} else if docMsg := waMsg.GetDocumentMessage(); docMsg != nil {
@@ -785,6 +875,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 				r.MediaURL = &stored.URL
 				r.MediaAssetID = stored.AssetID
 				r.MediaSize = &stored.SizeBytes
+				r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 			}
 		}
 	} else if stickerMsg := waMsg.GetStickerMessage(); stickerMsg != nil {
@@ -796,22 +887,20 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 				r.MediaURL = &stored.URL
 				r.MediaAssetID = stored.AssetID
 				r.MediaSize = &stored.SizeBytes
+				r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 			}
 		}
 	} else if locMsg := waMsg.GetLocationMessage(); locMsg != nil {
 		r.MessageType = domain.MessageTypeLocation
-		r.Body = locMsg.GetName()
-		if r.Body == "" {
-			r.Body = locMsg.GetAddress()
-		}
+		r.Body = locationBody(locMsg.GetName(), locMsg.GetAddress())
 		lat := locMsg.GetDegreesLatitude()
 		lng := locMsg.GetDegreesLongitude()
 		r.Latitude = &lat
 		r.Longitude = &lng
 	} else if contactMsg := waMsg.GetContactMessage(); contactMsg != nil {
 		r.MessageType = domain.MessageTypeContact
-		r.Body = contactMsg.GetDisplayName()
-		r.ContactName = strPtr(contactMsg.GetDisplayName())
+		r.Body = contactCardName(contactMsg)
+		r.ContactName = strPtr(r.Body)
 		r.ContactVCard = strPtr(contactMsg.GetVcard())
 		if phone := extractPhoneFromVCard(contactMsg.GetVcard()); phone != "" {
 			r.ContactPhone = strPtr(phone)
@@ -832,6 +921,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 						r.MediaURL = &stored.URL
 						r.MediaAssetID = stored.AssetID
 						r.MediaSize = &stored.SizeBytes
+						r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 					}
 				}
 			} else if vidMsg := inner.GetVideoMessage(); vidMsg != nil {
@@ -847,6 +937,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 						r.MediaURL = &stored.URL
 						r.MediaAssetID = stored.AssetID
 						r.MediaSize = &stored.SizeBytes
+						r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 					}
 				}
 			}
@@ -865,6 +956,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 						r.MediaURL = &stored.URL
 						r.MediaAssetID = stored.AssetID
 						r.MediaSize = &stored.SizeBytes
+						r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 					}
 				}
 			} else if vidMsg := inner.GetVideoMessage(); vidMsg != nil {
@@ -880,6 +972,7 @@ func (p *DevicePool) extractMessageContent(ctx context.Context, instance *Device
 						r.MediaURL = &stored.URL
 						r.MediaAssetID = stored.AssetID
 						r.MediaSize = &stored.SizeBytes
+						r.MediaMimetype = storedMediaMimetype(r.MediaMimetype, stored)
 					}
 				}
 			}
@@ -908,10 +1001,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 		return
 	}
 
-	// Skip group messages - only process 1-to-1 chats
-	if evt.Info.Chat.Server == "g.us" {
-		return
-	}
+	isGroup := evt.Info.Chat.Server == types.GroupServer
 
 	// Skip newsletter/channel messages
 	if evt.Info.Chat.Server == "newsletter" {
@@ -1005,6 +1095,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 				"message_id": editedMsgID,
 				"new_body":   newBody,
 				"is_from_me": evt.Info.IsFromMe,
+				"edited_at":  time.Now(),
 			})
 
 			log.Printf("[Edit] Message %s edited in chat %s", editedMsgID, chatJID)
@@ -1037,6 +1128,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 				mediaURL = &stored.URL
 				mediaAssetID = stored.AssetID
 				mediaSize = &stored.SizeBytes
+				mediaMimetype = storedMediaMimetype(mediaMimetype, stored)
 			}
 		}
 	} else if vidMsg := evt.Message.GetVideoMessage(); vidMsg != nil {
@@ -1052,6 +1144,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 				mediaURL = &stored.URL
 				mediaAssetID = stored.AssetID
 				mediaSize = &stored.SizeBytes
+				mediaMimetype = storedMediaMimetype(mediaMimetype, stored)
 			}
 		}
 	} else if audMsg := evt.Message.GetAudioMessage(); audMsg != nil {
@@ -1067,6 +1160,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 				mediaURL = &stored.URL
 				mediaAssetID = stored.AssetID
 				mediaSize = &stored.SizeBytes
+				mediaMimetype = storedMediaMimetype(mediaMimetype, stored)
 			}
 		}
 	} else if docMsg := evt.Message.GetDocumentMessage(); docMsg != nil {
@@ -1088,6 +1182,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 				mediaURL = &stored.URL
 				mediaAssetID = stored.AssetID
 				mediaSize = &stored.SizeBytes
+				mediaMimetype = storedMediaMimetype(mediaMimetype, stored)
 			}
 		}
 	} else if stickerMsg := evt.Message.GetStickerMessage(); stickerMsg != nil {
@@ -1099,17 +1194,15 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 				mediaURL = &stored.URL
 				mediaAssetID = stored.AssetID
 				mediaSize = &stored.SizeBytes
+				mediaMimetype = storedMediaMimetype(mediaMimetype, stored)
 			}
 		}
 	} else if locMsg := evt.Message.GetLocationMessage(); locMsg != nil {
 		msgType = domain.MessageTypeLocation
-		body = locMsg.GetName()
-		if body == "" {
-			body = locMsg.GetAddress()
-		}
+		body = locationBody(locMsg.GetName(), locMsg.GetAddress())
 	} else if contactMsg := evt.Message.GetContactMessage(); contactMsg != nil {
 		msgType = domain.MessageTypeContact
-		body = contactMsg.GetDisplayName()
+		body = contactCardName(contactMsg)
 	}
 
 	// Get sender info - normalize JIDs to remove device suffix for consistent chat matching
@@ -1137,9 +1230,12 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 		}
 	}
 
-	// Get or create chat - only use sender name for incoming messages (not our own)
+	// Get or create chat - only use sender name for incoming messages (not our own).
+	// Group chats are named after the group subject instead.
 	chatName := ""
-	if !isFromMe {
+	if isGroup {
+		chatName = p.groupChatName(ctx, instance, evt.Info.Chat)
+	} else if !isFromMe {
 		chatName = senderName
 	}
 	chat, err := p.repos.Chat.GetOrCreate(ctx, instance.AccountID, instance.ID, chatJID, chatName)
@@ -1254,6 +1350,8 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 		QuotedBody:      quotedBody,
 		QuotedSender:    quotedSender,
 		QuotedIsFromMe:  quotedIsFromMe,
+		IsForwarded:     contextInfo.GetIsForwarded(),
+		ForwardingScore: int(contextInfo.GetForwardingScore()),
 	}
 
 	// Populate location data
@@ -1266,7 +1364,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 
 	// Populate contact card data
 	if contactMsg := evt.Message.GetContactMessage(); contactMsg != nil {
-		msg.ContactName = strPtr(contactMsg.GetDisplayName())
+		msg.ContactName = strPtr(contactCardName(contactMsg))
 		msg.ContactVCard = strPtr(contactMsg.GetVcard())
 		// Extract phone from vCard
 		vcard := contactMsg.GetVcard()
@@ -1291,6 +1389,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 						msg.MediaURL = &stored.URL
 						msg.MediaAssetID = stored.AssetID
 						msg.MediaSize = &stored.SizeBytes
+						msg.MediaMimetype = storedMediaMimetype(msg.MediaMimetype, stored)
 					}
 				}
 			} else if vidMsg := inner.GetVideoMessage(); vidMsg != nil {
@@ -1308,6 +1407,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 						msg.MediaURL = &stored.URL
 						msg.MediaAssetID = stored.AssetID
 						msg.MediaSize = &stored.SizeBytes
+						msg.MediaMimetype = storedMediaMimetype(msg.MediaMimetype, stored)
 					}
 				}
 			}
@@ -1327,6 +1427,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 						msg.MediaURL = &stored.URL
 						msg.MediaAssetID = stored.AssetID
 						msg.MediaSize = &stored.SizeBytes
+						msg.MediaMimetype = storedMediaMimetype(msg.MediaMimetype, stored)
 					}
 				}
 			} else if vidMsg := inner.GetVideoMessage(); vidMsg != nil {
@@ -1344,6 +1445,7 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 						msg.MediaURL = &stored.URL
 						msg.MediaAssetID = stored.AssetID
 						msg.MediaSize = &stored.SizeBytes
+						msg.MediaMimetype = storedMediaMimetype(msg.MediaMimetype, stored)
 					}
 				}
 			}
@@ -1359,6 +1461,27 @@ func (p *DevicePool) handleMessage(ctx context.Context, instance *DeviceInstance
 	_ = p.repos.Chat.UpdateLastMessage(ctx, chat.ID, body, evt.Info.Timestamp, !isFromMe)
 
 	p.invalidateChatCaches(instance.AccountID, chat.ID)
+	if !isFromMe {
+		p.BroadcastUnreadSummary(instance.AccountID)
+		if p.inboundHook != nil {
+			p.inboundHook(chat, msg)
+		}
+	}
+
+	// Group chats are read-only context: the group contact is not a person, so
+	// no lead, contact sync or avatar fetch applies to it.
+	if isGroup {
+		p.hub.BroadcastNewMessage(instance.AccountID, map[string]interface{}{
+			"chat_id":      chat.ID.String(),
+			"message":      msg,
+			"chat_jid":     chatJID,
+			"sender_name":  senderName,
+			"is_from_me":   isFromMe,
+			"is_group":     true,
+			"unread_count": chat.UnreadCount + 1,
+		})
+		return
+	}
 
 	// Chat.GetOrCreate already creates or links the peer Contact. Reuse that
 	// account-scoped parent instead of upserting evt.Info.Sender: for outgoing
@@ -1678,6 +1801,14 @@ func (p *DevicePool) downloadAndStoreMediaWithSource(ctx context.Context, instan
 		log.Printf("[Media] Failed to download: %v", err)
 		return nil, err
 	}
+	// Objects without a usable extension or declared type would be served
+	// as application/octet-stream; detect the real type from the bytes.
+	mimetype = sniffMediaMimetype(mimetype, data)
+	if extension == "" || extension == ".bin" {
+		if ext := sniffedExtension(mimetype); ext != "" {
+			extension = ext
+		}
+	}
 	hashBytes := sha256.Sum256(data)
 	rawContentHash := fmt.Sprintf("%x", hashBytes[:])
 	contentHash := rawContentHash
@@ -1824,6 +1955,11 @@ func (p *DevicePool) handleReceipt(ctx context.Context, instance *DeviceInstance
 		}
 		return
 	}
+	// Our own read receipts mean the chat was read on the phone (or another
+	// linked device), so the web badge must clear as well.
+	if evt.IsFromMe && (evt.Type == types.ReceiptTypeRead || evt.Type == types.ReceiptTypeReadSelf) {
+		p.handleChatReadElsewhere(ctx, instance, evt.Chat, evt.Timestamp)
+	}
 	// Determine status from receipt type
 	var status string
 	switch evt.Type {
@@ -1876,21 +2012,52 @@ func (p *DevicePool) handleReceipt(ctx context.Context, instance *DeviceInstance
 	log.Printf("[Receipt] type=%s status=%s chat=%s msgs=%v", evt.Type, status, chatJID, evt.MessageIDs)
 
 	// Persist receipt status in database (only upgrade: sent→delivered→read)
-	if len(evt.MessageIDs) > 0 {
-		for _, msgID := range evt.MessageIDs {
-			if err := p.repos.Message.UpdateStatusUpgrade(ctx, instance.AccountID, chatJID, msgID, status, evt.Timestamp); err != nil {
-				log.Printf("[Receipt] Failed to update status for %s: %v", msgID, err)
-			}
+	stored := make(map[string]string, len(evt.MessageIDs))
+	for _, msgID := range evt.MessageIDs {
+		current, err := p.repos.Message.UpdateStatusUpgrade(ctx, instance.AccountID, chatJID, msgID, status, evt.Timestamp)
+		if err != nil {
+			log.Printf("[Receipt] Failed to update status for %s: %v", msgID, err)
+			continue
 		}
+		stored[msgID] = current
 	}
 
-	// Broadcast receipt status to frontend
-	p.hub.BroadcastToAccount(instance.AccountID, ws.EventMessageStatus, map[string]interface{}{
-		"message_ids": evt.MessageIDs,
-		"chat_jid":    chatJID,
-		"status":      status,
-		"timestamp":   evt.Timestamp,
-	})
+	// Broadcast the stored status so a late delivered receipt cannot turn a
+	// blue check grey again on any client.
+	for _, group := range groupReceiptStatuses(evt.MessageIDs, status, stored) {
+		p.hub.BroadcastToAccount(instance.AccountID, ws.EventMessageStatus, map[string]interface{}{
+			"message_ids": group.messageIDs,
+			"chat_jid":    chatJID,
+			"status":      group.status,
+			"timestamp":   evt.Timestamp,
+		})
+	}
+}
+
+type receiptStatusGroup struct {
+	status     string
+	messageIDs []string
+}
+
+// groupReceiptStatuses splits a receipt's message IDs by the status each one
+// ended up with. Messages not found in the database keep the receipt status.
+func groupReceiptStatuses(messageIDs []string, receiptStatus string, stored map[string]string) []receiptStatusGroup {
+	var groups []receiptStatusGroup
+	index := make(map[string]int)
+	for _, msgID := range messageIDs {
+		status := stored[msgID]
+		if status == "" {
+			status = receiptStatus
+		}
+		i, ok := index[status]
+		if !ok {
+			i = len(groups)
+			index[status] = i
+			groups = append(groups, receiptStatusGroup{status: status})
+		}
+		groups[i].messageIDs = append(groups[i].messageIDs, msgID)
+	}
+	return groups
 }
 
 // handleChatPresence processes typing/recording indicators from contacts
@@ -2534,6 +2701,9 @@ func (p *DevicePool) handlePollCreation(ctx context.Context, instance *DeviceIns
 	msg.PollOptions, _ = p.repos.Poll.GetOptions(ctx, msg.ID)
 
 	_ = p.repos.Chat.UpdateLastMessage(ctx, chat.ID, "📊 "+question, evt.Info.Timestamp, !isFromMe)
+	if !isFromMe {
+		p.BroadcastUnreadSummary(instance.AccountID)
+	}
 
 	p.hub.BroadcastNewMessage(instance.AccountID, map[string]interface{}{
 		"chat_id":      chat.ID.String(),
@@ -2761,6 +2931,23 @@ func (p *DevicePool) SendChatPresence(ctx context.Context, deviceID uuid.UUID, t
 	return instance.Client.SendChatPresence(ctx, jid, state, mediaType)
 }
 
+// SendPresence marks the device as online or offline for its contacts.
+func (p *DevicePool) SendPresence(ctx context.Context, deviceID uuid.UUID, available bool) error {
+	p.mu.RLock()
+	instance, exists := p.devices[deviceID]
+	p.mu.RUnlock()
+
+	if !exists || instance.Client == nil || !instance.Client.IsConnected() {
+		return fmt.Errorf("device not connected: %s", deviceID)
+	}
+
+	state := types.PresenceUnavailable
+	if available {
+		state = types.PresenceAvailable
+	}
+	return instance.Client.SendPresence(ctx, state)
+}
+
 // SendReadReceipt sends read receipts (blue ticks) for messages in a chat
 func (p *DevicePool) SendReadReceipt(ctx context.Context, deviceID uuid.UUID, chatJID string, senderJID string, messageIDs []string) error {
 	p.mu.RLock()
@@ -2904,6 +3091,69 @@ func (p *DevicePool) EditMessage(ctx context.Context, deviceID uuid.UUID, chatJI
 
 // SendMessage sends a text message
 func (p *DevicePool) SendMessage(ctx context.Context, deviceID uuid.UUID, to, body string) (*domain.Message, error) {
+	return p.sendTextMessage(ctx, deviceID, to, body, 0)
+}
+
+// forwardedContextInfo marks an outbound message as forwarded, which is what
+// makes WhatsApp show the "Reenviado" label on it. WhatsApp switches to
+// "Reenviado muchas veces" once the score reaches 5.
+func forwardedContextInfo(forwardingScore int) *waE2E.ContextInfo {
+	return &waE2E.ContextInfo{
+		IsForwarded:     proto.Bool(true),
+		ForwardingScore: proto.Uint32(uint32(forwardingScore)),
+	}
+}
+
+// nextForwardingScore is the score a forward of original carries: one more
+// than the times the original itself was forwarded.
+func nextForwardingScore(original *domain.Message) int {
+	if original.ForwardingScore > 0 {
+		return original.ForwardingScore + 1
+	}
+	if original.IsForwarded {
+		return 2
+	}
+	return 1
+}
+
+// isMediaMessageType reports whether messages of type t carry a media file.
+func isMediaMessageType(t string) bool {
+	switch t {
+	case domain.MessageTypeImage, domain.MessageTypeVideo, domain.MessageTypeGIF, domain.MessageTypeAudio,
+		domain.MessageTypePTT, domain.MessageTypeDocument, domain.MessageTypeSticker:
+		return true
+	}
+	return false
+}
+
+// sendTextMessage sends body as text. A forwardingScore above zero flags the
+// message as forwarded with that score.
+func (p *DevicePool) sendTextMessage(ctx context.Context, deviceID uuid.UUID, to, body string, forwardingScore int) (*domain.Message, error) {
+	// Create message
+	msg := &waE2E.Message{
+		Conversation: proto.String(body),
+	}
+	if forwardingScore > 0 {
+		// Conversation messages can't carry a ContextInfo
+		msg = &waE2E.Message{
+			ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+				Text:        proto.String(body),
+				ContextInfo: forwardedContextInfo(forwardingScore),
+			},
+		}
+	}
+	return p.sendOutgoingMessage(ctx, deviceID, to, msg, &domain.Message{
+		Body:            strPtr(body),
+		MessageType:     strPtr(domain.MessageTypeText),
+		IsForwarded:     forwardingScore > 0,
+		ForwardingScore: forwardingScore,
+	}, body, "SendMessage")
+}
+
+// sendOutgoingMessage sends msg to `to` and records it: record carries the
+// type-specific fields and is completed with the chat, sender and send
+// result, and preview becomes the chat's last message.
+func (p *DevicePool) sendOutgoingMessage(ctx context.Context, deviceID uuid.UUID, to string, msg *waE2E.Message, record *domain.Message, preview, label string) (*domain.Message, error) {
 	p.mu.RLock()
 	instance, exists := p.devices[deviceID]
 	p.mu.RUnlock()
@@ -2924,13 +3174,8 @@ func (p *DevicePool) SendMessage(ctx context.Context, deviceID uuid.UUID, to, bo
 		jid = types.NewJID(to, types.DefaultUserServer)
 	}
 
-	// Create message
-	msg := &waE2E.Message{
-		Conversation: proto.String(body),
-	}
-
 	// Send message
-	resp, sendJID, err := p.sendMessageWithLIDFallback(ctx, instance, jid, msg, "SendMessage")
+	resp, sendJID, err := p.sendMessageWithLIDFallback(ctx, instance, jid, msg, label)
 	if err != nil {
 		instance.mu.Lock()
 		instance.Metrics.SendErrorCount++
@@ -2949,7 +3194,7 @@ func (p *DevicePool) SendMessage(ctx context.Context, deviceID uuid.UUID, to, bo
 	if sendJID.Server == types.HiddenUserServer {
 		if pnJID, err := p.store.LIDMap.GetPNForLID(ctx, sendJID.ToNonAD()); err == nil && !pnJID.IsEmpty() {
 			normalizedJID = pnJID.User + "@s.whatsapp.net"
-			log.Printf("[SendMessage] Resolved LID %s -> %s", sendJID.ToNonAD().String(), normalizedJID)
+			log.Printf("[%s] Resolved LID %s -> %s", label, sendJID.ToNonAD().String(), normalizedJID)
 		}
 	}
 	chat, err := p.repos.Chat.GetOrCreate(ctx, instance.AccountID, instance.ID, normalizedJID, "")
@@ -2957,36 +3202,32 @@ func (p *DevicePool) SendMessage(ctx context.Context, deviceID uuid.UUID, to, bo
 		return nil, fmt.Errorf("failed to get/create chat: %w", err)
 	}
 
-	// Create message record
-	message := &domain.Message{
-		AccountID:   instance.AccountID,
-		DeviceID:    &instance.ID,
-		ChatID:      chat.ID,
-		MessageID:   resp.ID,
-		FromJID:     strPtr(instance.JID),
-		FromName:    strPtr("Me"),
-		Body:        strPtr(body),
-		MessageType: strPtr(domain.MessageTypeText),
-		IsFromMe:    true,
-		Status:      strPtr("sent"),
-		Timestamp:   resp.Timestamp,
-	}
+	// Complete message record
+	record.AccountID = instance.AccountID
+	record.DeviceID = &instance.ID
+	record.ChatID = chat.ID
+	record.MessageID = resp.ID
+	record.FromJID = strPtr(instance.JID)
+	record.FromName = strPtr("Me")
+	record.IsFromMe = true
+	record.Status = strPtr("sent")
+	record.Timestamp = resp.Timestamp
 
-	if err := p.repos.Message.Create(ctx, message); err != nil {
-		log.Printf("[SendMessage] Failed to save message: %v", err)
+	if err := p.repos.Message.Create(ctx, record); err != nil {
+		log.Printf("[%s] Failed to save message: %v", label, err)
 	}
 	p.invalidateChatCaches(instance.AccountID, chat.ID)
 
 	// Update chat
-	_ = p.repos.Chat.UpdateLastMessage(ctx, chat.ID, body, resp.Timestamp, false)
+	_ = p.repos.Chat.UpdateLastMessage(ctx, chat.ID, preview, resp.Timestamp, false)
 
 	// Broadcast to frontend
 	p.hub.BroadcastToAccount(instance.AccountID, ws.EventMessageSent, map[string]interface{}{
 		"chat_id": chat.ID.String(),
-		"message": message,
+		"message": record,
 	})
 
-	return message, nil
+	return record, nil
 }
 
 func (p *DevicePool) sendMessageWithLIDFallback(ctx context.Context, instance *DeviceInstance, jid types.JID, msg *waE2E.Message, label string) (whatsmeow.SendResponse, types.JID, error) {
@@ -3150,26 +3391,45 @@ func (p *DevicePool) SendReplyMessage(ctx context.Context, deviceID uuid.UUID, t
 	return message, nil
 }
 
-// ForwardMessage forwards a message to another chat
-func (p *DevicePool) ForwardMessage(ctx context.Context, deviceID uuid.UUID, to string, originalMsg *domain.Message) (*domain.Message, error) {
-	// If it has media, forward as media; otherwise forward as text
-	if originalMsg.MediaURL != nil && *originalMsg.MediaURL != "" && originalMsg.MessageType != nil {
-		body := ""
-		if originalMsg.Body != nil {
-			body = *originalMsg.Body
-		}
-		filename := ""
-		if originalMsg.MediaFilename != nil {
-			filename = *originalMsg.MediaFilename
-		}
-		return p.SendMediaMessageWithFilename(ctx, deviceID, to, body, *originalMsg.MediaURL, *originalMsg.MessageType, filename)
-	}
-
+// ForwardMessage forwards a message to another chat, flagged as forwarded
+// like WhatsApp does. Media is read back from storage and uploaded again, so
+// the copy doesn't depend on the original's WhatsApp media. When the original
+// media is gone only the caption is forwarded and mediaMissing is true; a
+// media message without caption then fails with ErrMediaGone.
+func (p *DevicePool) ForwardMessage(ctx context.Context, deviceID uuid.UUID, to string, originalMsg *domain.Message) (message *domain.Message, mediaMissing bool, err error) {
 	body := ""
 	if originalMsg.Body != nil {
 		body = *originalMsg.Body
 	}
-	return p.SendMessage(ctx, deviceID, to, body)
+	score := nextForwardingScore(originalMsg)
+	hasMedia := originalMsg.MediaURL != nil && *originalMsg.MediaURL != ""
+	if originalMsg.MediaDeleted || hasMedia || (originalMsg.MessageType != nil && isMediaMessageType(*originalMsg.MessageType)) {
+		mediaErr := fmt.Errorf("%w: media of message %s is no longer available", ErrMediaGone, originalMsg.MessageID)
+		if !originalMsg.MediaDeleted && hasMedia && originalMsg.MessageType != nil {
+			media, err := p.UploadMedia(ctx, deviceID, *originalMsg.MediaURL, *originalMsg.MessageType)
+			if err == nil {
+				filename := ""
+				if originalMsg.MediaFilename != nil {
+					filename = *originalMsg.MediaFilename
+				}
+				media.OriginalFilename = mediaDisplayFilename(filename, *originalMsg.MediaURL)
+				message, err = p.sendPreUploadedMediaMessage(ctx, deviceID, to, body, media, nil, score)
+				return message, false, err
+			}
+			if !errors.Is(err, ErrMediaGone) {
+				return nil, false, err
+			}
+			mediaErr = err
+		}
+		if strings.TrimSpace(body) == "" {
+			return nil, true, mediaErr
+		}
+		log.Printf("[ForwardMessage] Forwarding only the caption of %s: %v", originalMsg.MessageID, mediaErr)
+		mediaMissing = true
+	}
+
+	message, err = p.sendTextMessage(ctx, deviceID, to, body, score)
+	return message, mediaMissing, err
 }
 
 // SendReaction sends a reaction emoji to a message
@@ -3611,6 +3871,25 @@ func (p *DevicePool) UploadMedia(ctx context.Context, deviceID uuid.UUID, mediaU
 			return nil, fmt.Errorf("el GIF almacenado no tiene un formato compatible")
 		}
 	}
+	if mediaType == domain.MessageTypePTT {
+		if isOggOpus(mimetype, data) {
+			mimetype, originalMimetype = voiceNoteMimetype, voiceNoteMimetype
+		} else if converted, convErr := transcodeToVoiceNote(ctx, data); convErr != nil {
+			log.Printf("[UploadMedia] ⚠️ Voice note not transcoded to Ogg/Opus, sending %s as is: %v", mimetype, convErr)
+		} else {
+			data, mimetype = converted, voiceNoteMimetype
+			// Keep the chat copy in sync with what the recipient gets.
+			if storedURL, storeErr := p.storeVoiceNote(ctx, instance.AccountID, data); storeErr != nil {
+				log.Printf("[UploadMedia] ⚠️ Converted voice note not stored, keeping the original file: %v", storeErr)
+			} else {
+				mediaURL, originalMimetype = storedURL, voiceNoteMimetype
+			}
+		}
+	}
+	data, mimetype, err = MediaLimitsFromConfig(p.cfg).fitMediaLimit(mediaType, mimetype, data)
+	if err != nil {
+		return nil, err
+	}
 
 	// Determine the correct WhatsApp media type for upload
 	var waMediaType whatsmeow.MediaType
@@ -3619,7 +3898,7 @@ func (p *DevicePool) UploadMedia(ctx context.Context, deviceID uuid.UUID, mediaU
 		waMediaType = whatsmeow.MediaImage
 	case domain.MessageTypeVideo, domain.MessageTypeGIF:
 		waMediaType = whatsmeow.MediaVideo
-	case domain.MessageTypeAudio:
+	case domain.MessageTypeAudio, domain.MessageTypePTT:
 		waMediaType = whatsmeow.MediaAudio
 	case domain.MessageTypeDocument:
 		waMediaType = whatsmeow.MediaDocument
@@ -3685,7 +3964,7 @@ type outboundMediaQuote struct {
 // SendPreUploadedMediaMessage sends a pre-uploaded media to a recipient, without re-downloading/re-uploading.
 // Used by campaign worker to send the same media to many recipients efficiently.
 func (p *DevicePool) SendPreUploadedMediaMessage(ctx context.Context, deviceID uuid.UUID, to, caption string, media *PreUploadedMedia) (*domain.Message, error) {
-	return p.sendPreUploadedMediaMessage(ctx, deviceID, to, caption, media, nil)
+	return p.sendPreUploadedMediaMessage(ctx, deviceID, to, caption, media, nil, 0)
 }
 
 // SendPreUploadedMediaReplyMessage preserves WhatsApp's native reply context
@@ -3696,10 +3975,10 @@ func (p *DevicePool) SendPreUploadedMediaReplyMessage(ctx context.Context, devic
 		body:      quotedBody,
 		sender:    quotedSender,
 		isFromMe:  quotedIsFromMe,
-	})
+	}, 0)
 }
 
-func (p *DevicePool) sendPreUploadedMediaMessage(ctx context.Context, deviceID uuid.UUID, to, caption string, media *PreUploadedMedia, quote *outboundMediaQuote) (*domain.Message, error) {
+func (p *DevicePool) sendPreUploadedMediaMessage(ctx context.Context, deviceID uuid.UUID, to, caption string, media *PreUploadedMedia, quote *outboundMediaQuote, forwardingScore int) (*domain.Message, error) {
 	p.mu.RLock()
 	instance, exists := p.devices[deviceID]
 	p.mu.RUnlock()
@@ -3751,7 +4030,7 @@ func (p *DevicePool) sendPreUploadedMediaMessage(ctx context.Context, deviceID u
 				GifPlayback:   proto.Bool(media.MediaType == domain.MessageTypeGIF),
 			},
 		}
-	case domain.MessageTypeAudio:
+	case domain.MessageTypeAudio, domain.MessageTypePTT:
 		msg = &waE2E.Message{
 			AudioMessage: &waE2E.AudioMessage{
 				URL:           proto.String(media.URL),
@@ -3761,7 +4040,7 @@ func (p *DevicePool) sendPreUploadedMediaMessage(ctx context.Context, deviceID u
 				FileEncSHA256: media.FileEncSHA256,
 				FileSHA256:    media.FileSHA256,
 				FileLength:    proto.Uint64(media.FileLength),
-				PTT:           proto.Bool(true),
+				PTT:           proto.Bool(media.MediaType == domain.MessageTypePTT),
 			},
 		}
 	case domain.MessageTypeDocument:
@@ -3794,16 +4073,22 @@ func (p *DevicePool) sendPreUploadedMediaMessage(ctx context.Context, deviceID u
 	if msg == nil {
 		return nil, fmt.Errorf("unsupported media type: %s", media.MediaType)
 	}
+	var contextInfo *waE2E.ContextInfo
 	if quote != nil {
 		quotedParticipant := (*string)(nil)
 		if strings.TrimSpace(quote.sender) != "" {
 			quotedParticipant = proto.String(quote.sender)
 		}
-		contextInfo := &waE2E.ContextInfo{
+		contextInfo = &waE2E.ContextInfo{
 			StanzaID:      proto.String(quote.messageID),
 			Participant:   quotedParticipant,
 			QuotedMessage: &waE2E.Message{Conversation: proto.String(quote.body)},
 		}
+	}
+	if forwardingScore > 0 {
+		contextInfo = forwardedContextInfo(forwardingScore)
+	}
+	if contextInfo != nil {
 		switch {
 		case msg.ImageMessage != nil:
 			msg.ImageMessage.ContextInfo = contextInfo
@@ -3840,21 +4125,28 @@ func (p *DevicePool) sendPreUploadedMediaMessage(ctx context.Context, deviceID u
 	// Create message record
 	proxyMediaURL := p.publicToProxyURL(media.OriginalURL)
 	size := int64(media.FileLength)
+	recordType := media.MediaType
+	if recordType == domain.MessageTypePTT {
+		// Voice notes are stored and rendered like any other audio.
+		recordType = domain.MessageTypeAudio
+	}
 	message := &domain.Message{
-		AccountID:     instance.AccountID,
-		DeviceID:      &instance.ID,
-		ChatID:        chat.ID,
-		MessageID:     sendResp.ID,
-		FromJID:       strPtr(instance.JID),
-		FromName:      strPtr("Me"),
-		Body:          strPtr(caption),
-		MessageType:   strPtr(media.MediaType),
-		MediaURL:      strPtr(proxyMediaURL),
-		MediaMimetype: strPtr(media.OriginalMimetype),
-		MediaSize:     &size,
-		IsFromMe:      true,
-		Status:        strPtr("sent"),
-		Timestamp:     sendResp.Timestamp,
+		AccountID:       instance.AccountID,
+		DeviceID:        &instance.ID,
+		ChatID:          chat.ID,
+		MessageID:       sendResp.ID,
+		FromJID:         strPtr(instance.JID),
+		FromName:        strPtr("Me"),
+		Body:            strPtr(caption),
+		MessageType:     strPtr(recordType),
+		MediaURL:        strPtr(proxyMediaURL),
+		MediaMimetype:   strPtr(media.OriginalMimetype),
+		MediaSize:       &size,
+		IsFromMe:        true,
+		IsForwarded:     forwardingScore > 0,
+		Status:          strPtr("sent"),
+		Timestamp:       sendResp.Timestamp,
+		ForwardingScore: forwardingScore,
 	}
 	if media.MediaType == domain.MessageTypeDocument {
 		message.MediaFilename = strPtr(documentFilename)
@@ -3873,7 +4165,7 @@ func (p *DevicePool) sendPreUploadedMediaMessage(ctx context.Context, deviceID u
 
 	lastMsg := caption
 	if lastMsg == "" {
-		lastMsg = fmt.Sprintf("[%s]", media.MediaType)
+		lastMsg = fmt.Sprintf("[%s]", recordType)
 	}
 	_ = p.repos.Chat.UpdateLastMessage(ctx, chat.ID, lastMsg, sendResp.Timestamp, false)
 
@@ -3949,7 +4241,7 @@ func (p *DevicePool) SendContactMessage(ctx context.Context, deviceID uuid.UUID,
 	}
 
 	vcard := fmt.Sprintf("BEGIN:VCARD\nVERSION:3.0\nFN:%s\nTEL;type=CELL;type=VOICE;waid=%s:%s\nEND:VCARD",
-		contactName,
+		vcardEscaper.Replace(contactName),
 		strings.TrimPrefix(phone, "+"),
 		phone,
 	)
@@ -4011,6 +4303,40 @@ func (p *DevicePool) SendContactMessage(ctx context.Context, deviceID uuid.UUID,
 	return dbMsg, nil
 }
 
+// SendLocationMessage sends a map pin. Like incoming locations, the stored
+// body keeps the place name and address.
+func (p *DevicePool) SendLocationMessage(ctx context.Context, deviceID uuid.UUID, to string, latitude, longitude float64, name, address string) (*domain.Message, error) {
+	locMsg := &waE2E.LocationMessage{
+		DegreesLatitude:  proto.Float64(latitude),
+		DegreesLongitude: proto.Float64(longitude),
+	}
+	if name != "" {
+		locMsg.Name = proto.String(name)
+	}
+	if address != "" {
+		locMsg.Address = proto.String(address)
+	}
+	return p.sendOutgoingMessage(ctx, deviceID, to, &waE2E.Message{LocationMessage: locMsg}, &domain.Message{
+		Body:        strPtr(locationBody(name, address)),
+		MessageType: strPtr(domain.MessageTypeLocation),
+		Latitude:    &latitude,
+		Longitude:   &longitude,
+	}, "📍 Ubicación", "SendLocationMessage")
+}
+
+// locationBody is the stored body of a location message: its name and
+// address on separate lines, either of which may be missing.
+func locationBody(name, address string) string {
+	name, address = strings.TrimSpace(name), strings.TrimSpace(address)
+	switch {
+	case name == "" || name == address:
+		return address
+	case address == "":
+		return name
+	}
+	return name + "\n" + address
+}
+
 // GetDevice returns a device instance by ID
 func (p *DevicePool) GetDevice(deviceID uuid.UUID) *DeviceInstance {
 	p.mu.RLock()
@@ -4065,6 +4391,7 @@ func (p *DevicePool) DisconnectDevice(ctx context.Context, deviceID uuid.UUID) e
 	instance.mu.Lock()
 	instance.Status = domain.DeviceStatusDisconnected
 	instance.mu.Unlock()
+	instance.setQRCode("", "", domain.DeviceStatusDisconnected)
 
 	_ = p.repos.Device.UpdateStatus(ctx, deviceID, domain.DeviceStatusDisconnected)
 
@@ -4097,6 +4424,70 @@ func (p *DevicePool) ResetDevice(ctx context.Context, deviceID uuid.UUID) error
 	return nil
 }
 
+// LogoutDevice ends the device's WhatsApp session for good: it logs out on
+// WhatsApp, deletes the stored credentials and leaves the device logged_out.
+// Unlike DisconnectDevice, the session cannot be resumed and the next connect
+// requires a fresh QR scan.
+func (p *DevicePool) LogoutDevice(ctx context.Context, deviceID uuid.UUID) error {
+	device, err := p.repos.Device.GetByID(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if device == nil {
+		return fmt.Errorf("device not found: %s", deviceID)
+	}
+	var savedJID string
+	if device.JID != nil {
+		savedJID = strings.TrimSpace(*device.JID)
+	}
+
+	p.mu.Lock()
+	instance, exists := p.devices[deviceID]
+	if exists {
+		delete(p.devices, deviceID)
+	}
+	p.mu.Unlock()
+
+	if !exists {
+		instance = &DeviceInstance{ID: deviceID, AccountID: device.AccountID}
+	}
+	instance.mu.Lock()
+	if instance.reconnecting && instance.stopReconnect != nil {
+		close(instance.stopReconnect)
+		instance.stopReconnect = nil
+	}
+	instance.reconnecting = false
+	instance.Status = domain.DeviceStatusLoggedOut
+	instance.JID = ""
+	instance.QRCode = ""
+	client := instance.Client
+	instance.mu.Unlock()
+	instance.setQRCode("", "", domain.DeviceStatusLoggedOut)
+
+	label := fmt.Sprintf("device %s logout", deviceID)
+	if client != nil && client.Store != nil && client.Store.ID != nil {
+		p.logoutAndDeleteClientStore(ctx, client, label)
+	} else {
+		if client != nil {
+			client.Disconnect()
+		}
+		if savedJID != "" {
+			p.deleteStoredWhatsAppDevice(ctx, savedJID, label)
+		}
+	}
+
+	if err := p.repos.Device.UpdateJID(ctx, deviceID, "", ""); err != nil {
+		return err
+	}
+	if err := p.repos.Device.UpdateStatus(ctx, deviceID, domain.DeviceStatusLoggedOut); err != nil {
+		return err
+	}
+	p.hub.BroadcastDeviceStatus(device.AccountID, deviceID, domain.DeviceStatusLoggedOut, "")
+	p.recordDeviceEvent(instance, domain.DeviceEventLoggedOut, "manual")
+	log.Printf("[DevicePool] Device %s logged out — needs re-pairing via QR code", deviceID)
+	return nil
+}
+
 // DeleteDevice removes a device completely
 func (p *DevicePool) DeleteDevice(ctx context.Context, deviceID uuid.UUID) error {
 	device, _ := p.repos.Device.GetByID(ctx, deviceID)
@@ -4175,6 +4566,11 @@ func (p *DevicePool) Shutdown() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.stopHealth != nil {
+		close(p.stopHealth)
+		p.stopHealth = nil
+	}
+
 	for id, instance := range p.devices {
 		// Stop reconnect supervisor
 		instance.mu.Lock()
@@ -4247,17 +4643,7 @@ func (p *DevicePool) GetHealthSummary() []DeviceHealthSummary {
 
 	summaries := make([]DeviceHealthSummary, 0, len(p.devices))
 	for _, instance := range p.devices {
-		instance.mu.RLock()
-		connected := instance.Client != nil && instance.Client.IsConnected()
-		s := DeviceHealthSummary{
-			ID:        instance.ID,
-			JID:       instance.JID,
-			Status:    instance.Status,
-			Connected: connected,
-			Metrics:   instance.Metrics,
-		}
-		instance.mu.RUnlock()
-		summaries = append(summaries, s)
+		summaries = append(summaries, instance.healthSummary())
 	}
 	return summaries
 }
@@ -4286,3 +4672,27 @@ func extractPhoneFromVCard(vcard string) string {
 	}
 	return ""
 }
+
+// contactCardName returns the shared contact's display name, falling back to
+// the vCard FN line when the sender's client left it empty.
+func contactCardName(contactMsg *waE2E.ContactMessage) string {
+	if name := strings.TrimSpace(contactMsg.GetDisplayName()); name != "" {
+		return name
+	}
+	for _, line := range strings.Split(contactMsg.GetVcard(), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToUpper(line), "FN") {
+			if parts := strings.SplitN(line, ":", 2); len(parts) == 2 {
+				return vcardUnescaper.Replace(strings.TrimSpace(parts[1]))
+			}
+		}
+	}
+	return ""
+}
+
+// vcardUnescaper decodes vCard text escapes (\n, \,, \; and \\), including
+// the uppercase \N newline some clients write.
+var vcardUnescaper = strings.NewReplacer("\\n", "\n", "\\N", "\n", "\\,", ",", "\\;", ";", "\\\\", "\\")
+
+// vcardEscaper escapes text values so a name cannot break the vCard lines.
+var vcardEscaper = strings.NewReplacer("\\", "\\\\", ",", "\\,", ";", "\\;", "\r\n", "\\n", "\n", "\\n", "\r", "\\n")
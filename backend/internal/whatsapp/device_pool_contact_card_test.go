@@ -0,0 +1,39 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestContactCardNameFallsBackToVCard(t *testing.T) {
+	vcard := "BEGIN:VCARD\nVERSION:3.0\nFN:" + vcardEscaper.Replace("Pérez; Ana\nMaría") + "\nTEL;type=CELL;waid=51987654321:+51987654321\nEND:VCARD"
+	msg := &waE2E.ContactMessage{Vcard: proto.String(vcard)}
+	if got := contactCardName(msg); got != "Pérez; Ana\nMaría" {
+		t.Fatalf("unexpected name %q", got)
+	}
+	if got := extractPhoneFromVCard(vcard); got != "51987654321" {
+		t.Fatalf("unexpected phone %q", got)
+	}
+
+	msg.DisplayName = proto.String(" Ana ")
+	if got := contactCardName(msg); got != "Ana" {
+		t.Fatalf("display name should win, got %q", got)
+	}
+}
+
+func TestLocationBodyKeepsNameAndAddress(t *testing.T) {
+	cases := []struct{ name, address, want string }{
+		{"Oficina", "Av. Arequipa 123", "Oficina\nAv. Arequipa 123"},
+		{"Oficina", "", "Oficina"},
+		{"", "Av. Arequipa 123", "Av. Arequipa 123"},
+		{"Plaza", "Plaza", "Plaza"},
+		{"", "", ""},
+	}
+	for _, tc := range cases {
+		if got := locationBody(tc.name, tc.address); got != tc.want {
+			t.Fatalf("locationBody(%q, %q) = %q, want %q", tc.name, tc.address, got, tc.want)
+		}
+	}
+}
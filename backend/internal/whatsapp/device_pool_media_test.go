@@ -1,6 +1,13 @@
 package whatsapp
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
 
 func TestMediaDisplayFilename(t *testing.T) {
 	t.Parallel()
@@ -27,3 +34,46 @@ func TestMediaDisplayFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardedContextInfoMarksMessageAsForwarded(t *testing.T) {
+	t.Parallel()
+
+	info := forwardedContextInfo(1)
+	if !info.GetIsForwarded() || info.GetForwardingScore() != 1 {
+		t.Fatalf("forwardedContextInfo(1) = %+v, want a forwarded flag with score 1", info)
+	}
+}
+
+func TestNextForwardingScoreBuildsOnTheOriginal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		original domain.Message
+		want     int
+	}{
+		{name: "original message", original: domain.Message{}, want: 1},
+		{name: "forwarded without stored score", original: domain.Message{IsForwarded: true}, want: 2},
+		{name: "forwarded many times", original: domain.Message{IsForwarded: true, ForwardingScore: 5}, want: 6},
+	}
+	for _, tt := range tests {
+		if got := nextForwardingScore(&tt.original); got != tt.want {
+			t.Errorf("%s: nextForwardingScore() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestForwardMessageWithDeletedMediaAndNoCaptionFails(t *testing.T) {
+	t.Parallel()
+
+	// Deleting the media clears media_url, so only the flag and type remain.
+	original := &domain.Message{
+		MessageID:    "ABC123",
+		MessageType:  strPtr(domain.MessageTypeImage),
+		MediaDeleted: true,
+	}
+	message, mediaMissing, err := (&DevicePool{}).ForwardMessage(context.Background(), uuid.New(), "51999999999", original)
+	if !errors.Is(err, ErrMediaGone) || !mediaMissing || message != nil {
+		t.Fatalf("ForwardMessage() = (%v, %v, %v), want ErrMediaGone with mediaMissing", message, mediaMissing, err)
+	}
+}
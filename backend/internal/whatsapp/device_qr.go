@@ -0,0 +1,90 @@
+package whatsapp
+
+import (
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// QRUpdate is pushed to QR subscribers while a device pairs: a new Code each
+// time whatsmeow rotates it, then a final update when pairing ends.
+type QRUpdate struct {
+	Code   string
+	Image  string // PNG data URL of Code
+	Status string
+}
+
+// Done reports whether pairing is over and no further codes will follow.
+func (u QRUpdate) Done() bool {
+	return u.Code == "" && u.Status != domain.DeviceStatusConnecting
+}
+
+const qrSubscriberBuffer = 4
+
+// SubscribeQR streams QR updates for a device. The current code, if any, is
+// delivered first. ok is false when the device is not in the pool. Call
+// cancel when done.
+func (p *DevicePool) SubscribeQR(deviceID uuid.UUID) (updates <-chan QRUpdate, cancel func(), ok bool) {
+	p.mu.RLock()
+	instance, exists := p.devices[deviceID]
+	p.mu.RUnlock()
+	if !exists {
+		return nil, func() {}, false
+	}
+
+	ch := make(chan QRUpdate, qrSubscriberBuffer)
+	instance.mu.Lock()
+	if instance.qrSubs == nil {
+		instance.qrSubs = map[chan QRUpdate]struct{}{}
+	}
+	instance.qrSubs[ch] = struct{}{}
+	if instance.qrRaw != "" {
+		ch <- QRUpdate{Code: instance.qrRaw, Image: instance.QRCode, Status: instance.Status}
+	} else if instance.Status == domain.DeviceStatusConnected {
+		ch <- QRUpdate{Status: instance.Status}
+	}
+	instance.mu.Unlock()
+
+	return ch, func() {
+		instance.mu.Lock()
+		delete(instance.qrSubs, ch)
+		instance.mu.Unlock()
+	}, true
+}
+
+// GetQRCodeRaw returns the device's current pairing code as sent by
+// WhatsApp, or "" when it is not pairing.
+func (p *DevicePool) GetQRCodeRaw(deviceID uuid.UUID) string {
+	p.mu.RLock()
+	instance, exists := p.devices[deviceID]
+	p.mu.RUnlock()
+	if !exists {
+		return ""
+	}
+	instance.mu.RLock()
+	defer instance.mu.RUnlock()
+	return instance.qrRaw
+}
+
+// setQRCode records the rotated code and notifies subscribers. An empty code
+// ends pairing with the given status.
+func (instance *DeviceInstance) setQRCode(code, image, status string) {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	instance.qrRaw = code
+	update := QRUpdate{Code: code, Image: image, Status: status}
+	for ch := range instance.qrSubs {
+		select {
+		case ch <- update:
+		default:
+			// Slow reader: drop the stale update so the newest code gets through.
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- update:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func TestSubscribeQRPushesRotatedCodesUntilConnected(t *testing.T) {
+	id := uuid.New()
+	instance := &DeviceInstance{ID: id, Status: domain.DeviceStatusConnecting}
+	pool := &DevicePool{devices: map[uuid.UUID]*DeviceInstance{id: instance}}
+	instance.setQRCode("code-1", "img-1", domain.DeviceStatusConnecting)
+
+	updates, cancel, ok := pool.SubscribeQR(id)
+	if !ok {
+		t.Fatal("device not found")
+	}
+	defer cancel()
+	if got := <-updates; got.Code != "code-1" || got.Done() {
+		t.Fatalf("first update = %+v, want the current code", got)
+	}
+
+	instance.setQRCode("code-2", "img-2", domain.DeviceStatusConnecting)
+	if got := <-updates; got.Code != "code-2" || got.Image != "img-2" {
+		t.Fatalf("rotated update = %+v", got)
+	}
+	instance.setQRCode("", "", domain.DeviceStatusConnected)
+	if got := <-updates; !got.Done() || got.Status != domain.DeviceStatusConnected {
+		t.Fatalf("final update = %+v", got)
+	}
+	if pool.GetQRCodeRaw(id) != "" {
+		t.Fatal("raw code should be cleared once connected")
+	}
+
+	cancel()
+	instance.setQRCode("code-3", "img-3", domain.DeviceStatusConnecting)
+	select {
+	case got := <-updates:
+		t.Fatalf("cancelled subscriber received %+v", got)
+	default:
+	}
+}
+
+func TestSetQRCodeKeepsNewestForSlowReaders(t *testing.T) {
+	id := uuid.New()
+	instance := &DeviceInstance{ID: id, Status: domain.DeviceStatusConnecting}
+	pool := &DevicePool{devices: map[uuid.UUID]*DeviceInstance{id: instance}}
+	updates, cancel, _ := pool.SubscribeQR(id)
+	defer cancel()
+	for i := 0; i < qrSubscriberBuffer+3; i++ {
+		instance.setQRCode("code", "img", domain.DeviceStatusConnecting)
+	}
+	instance.setQRCode("", "", domain.DeviceStatusConnected)
+	var last QRUpdate
+	for len(updates) > 0 {
+		last = <-updates
+	}
+	if !last.Done() {
+		t.Fatalf("last buffered update = %+v, want the final status", last)
+	}
+}
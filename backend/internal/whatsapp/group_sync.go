@@ -0,0 +1,164 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/ws"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// SyncGroups fetches every group the device belongs to and stores its
+// metadata. It returns the number of groups stored.
+func (p *DevicePool) SyncGroups(ctx context.Context, deviceID uuid.UUID) (int, error) {
+	p.mu.RLock()
+	instance := p.devices[deviceID]
+	p.mu.RUnlock()
+	if instance == nil || instance.Client == nil || !instance.Client.IsLoggedIn() {
+		return 0, fmt.Errorf("device not connected: %s", deviceID)
+	}
+	groups, err := instance.Client.GetJoinedGroups(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list groups: %w", err)
+	}
+	stored := 0
+	for _, info := range groups {
+		if info == nil || info.JID.Server != types.GroupServer {
+			continue
+		}
+		if err := p.repos.Group.Upsert(ctx, groupFromInfo(instance.AccountID, instance.ID, info)); err != nil {
+			log.Printf("[Groups %s] Failed to store group %s: %v", instance.ID, info.JID, err)
+			continue
+		}
+		stored++
+	}
+	return stored, nil
+}
+
+// syncGroupsAfterConnect refreshes group metadata in the background once a
+// device comes online.
+func (p *DevicePool) syncGroupsAfterConnect(instance *DeviceInstance) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	n, err := p.SyncGroups(ctx, instance.ID)
+	if err != nil {
+		log.Printf("[Groups %s] Sync failed: %v", instance.ID, err)
+		return
+	}
+	log.Printf("[Groups %s] Synced %d groups", instance.ID, n)
+}
+
+// RefreshGroup reloads one group's metadata, participants included, from
+// WhatsApp through the given device and stores it.
+func (p *DevicePool) RefreshGroup(ctx context.Context, accountID, deviceID uuid.UUID, jid string) (*domain.WhatsAppGroup, error) {
+	p.mu.RLock()
+	instance := p.devices[deviceID]
+	p.mu.RUnlock()
+	if instance == nil || instance.Client == nil || !instance.Client.IsLoggedIn() {
+		return nil, fmt.Errorf("device not connected: %s", deviceID)
+	}
+	if instance.AccountID != accountID {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+	groupJID, err := types.ParseJID(strings.TrimSpace(jid))
+	if err != nil || groupJID.Server != types.GroupServer {
+		return nil, fmt.Errorf("invalid group JID: %s", jid)
+	}
+	return p.loadGroup(ctx, instance, groupJID)
+}
+
+func (p *DevicePool) loadGroup(ctx context.Context, instance *DeviceInstance, jid types.JID) (*domain.WhatsAppGroup, error) {
+	info, err := instance.Client.GetGroupInfo(ctx, jid.ToNonAD())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load group %s: %w", jid, err)
+	}
+	group := groupFromInfo(instance.AccountID, instance.ID, info)
+	if err := p.repos.Group.Upsert(ctx, group); err != nil {
+		return nil, fmt.Errorf("failed to store group %s: %w", jid, err)
+	}
+	return group, nil
+}
+
+// refreshGroup reloads one group's metadata from WhatsApp, logging failures.
+func (p *DevicePool) refreshGroup(ctx context.Context, instance *DeviceInstance, jid types.JID) {
+	if instance.Client == nil {
+		return
+	}
+	if _, err := p.loadGroup(ctx, instance, jid); err != nil {
+		log.Printf("[Groups %s] %v", instance.ID, err)
+	}
+}
+
+// handleGroupInfo refreshes a group after a subject, topic, setting or
+// membership change. The event only carries the delta, so the full info is
+// fetched again rather than patched. Open participant lists are told to
+// reload when membership or roles changed.
+func (p *DevicePool) handleGroupInfo(ctx context.Context, instance *DeviceInstance, evt *events.GroupInfo) {
+	p.refreshGroup(ctx, instance, evt.JID)
+	membersChanged := len(evt.Join) > 0 || len(evt.Leave) > 0 || len(evt.Promote) > 0 || len(evt.Demote) > 0
+	if membersChanged || evt.Name != nil {
+		p.hub.BroadcastToAccount(instance.AccountID, ws.EventGroupUpdate, map[string]interface{}{
+			"jid":                  evt.JID.ToNonAD().String(),
+			"participants_changed": membersChanged,
+		})
+	}
+}
+
+func (p *DevicePool) handleJoinedGroup(ctx context.Context, instance *DeviceInstance, evt *events.JoinedGroup) {
+	if err := p.repos.Group.Upsert(ctx, groupFromInfo(instance.AccountID, instance.ID, &evt.GroupInfo)); err != nil {
+		log.Printf("[Groups %s] Failed to store joined group %s: %v", instance.ID, evt.JID, err)
+	}
+}
+
+// groupChatName returns the stored subject of a group, or "" when it has not
+// been synced yet; in that case the metadata is fetched in the background.
+func (p *DevicePool) groupChatName(ctx context.Context, instance *DeviceInstance, jid types.JID) string {
+	group, err := p.repos.Group.GetByJID(ctx, instance.AccountID, jid.ToNonAD().String())
+	if err == nil && group != nil {
+		return group.Subject
+	}
+	go func() {
+		refreshCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		p.refreshGroup(refreshCtx, instance, jid)
+	}()
+	return ""
+}
+
+func groupFromInfo(accountID, deviceID uuid.UUID, info *types.GroupInfo) *domain.WhatsAppGroup {
+	group := &domain.WhatsAppGroup{
+		AccountID:        accountID,
+		DeviceID:         &deviceID,
+		JID:              info.JID.ToNonAD().String(),
+		Subject:          strings.TrimSpace(info.Name),
+		Topic:            strings.TrimSpace(info.Topic),
+		IsAnnounce:       info.IsAnnounce,
+		IsLocked:         info.IsLocked,
+		ParticipantCount: groupParticipantCount(info),
+		Participants:     make([]domain.WhatsAppGroupParticipant, 0, len(info.Participants)),
+	}
+	if !info.OwnerJID.IsEmpty() {
+		group.OwnerJID = info.OwnerJID.ToNonAD().String()
+	}
+	for _, participant := range info.Participants {
+		member := domain.WhatsAppGroupParticipant{
+			JID:          participant.JID.ToNonAD().String(),
+			Name:         strings.TrimSpace(participant.DisplayName),
+			IsAdmin:      participant.IsAdmin || participant.IsSuperAdmin,
+			IsSuperAdmin: participant.IsSuperAdmin,
+		}
+		if !participant.PhoneNumber.IsEmpty() {
+			member.Phone = participant.PhoneNumber.User
+		} else if participant.JID.Server == types.DefaultUserServer {
+			member.Phone = participant.JID.User
+		}
+		group.Participants = append(group.Participants, member)
+	}
+	return group
+}
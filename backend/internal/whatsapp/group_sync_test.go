@@ -0,0 +1,39 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestGroupFromInfo(t *testing.T) {
+	accountID, deviceID := uuid.New(), uuid.New()
+	info := &types.GroupInfo{
+		JID:       types.NewJID("120363000000000001", types.GroupServer),
+		OwnerJID:  types.NewJID("51999000111", types.DefaultUserServer),
+		GroupName: types.GroupName{Name: "  Ventas Lima "},
+		Participants: []types.GroupParticipant{
+			{JID: types.NewJID("51999000111", types.DefaultUserServer), IsSuperAdmin: true},
+			{
+				JID:         types.NewJID("123456789", types.HiddenUserServer),
+				PhoneNumber: types.NewJID("51999000222", types.DefaultUserServer),
+			},
+		},
+	}
+
+	group := groupFromInfo(accountID, deviceID, info)
+	if group.JID != "120363000000000001@g.us" || group.Subject != "Ventas Lima" {
+		t.Fatalf("group = %+v", group)
+	}
+	if group.OwnerJID != "51999000111@s.whatsapp.net" || group.ParticipantCount != 2 {
+		t.Fatalf("owner/count = %q/%d", group.OwnerJID, group.ParticipantCount)
+	}
+	owner, member := group.Participants[0], group.Participants[1]
+	if !owner.IsAdmin || !owner.IsSuperAdmin || owner.Phone != "51999000111" {
+		t.Fatalf("owner = %+v", owner)
+	}
+	if member.IsAdmin || member.Phone != "51999000222" || member.JID != "123456789@lid" {
+		t.Fatalf("member = %+v", member)
+	}
+}
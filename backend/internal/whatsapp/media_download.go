@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/storage"
 )
 
 const (
@@ -24,6 +25,10 @@ const (
 	mediaDownloadTimeout = 45 * time.Second
 )
 
+// ErrMediaGone is returned when a stored or remote media file no longer
+// exists, as opposed to a transient failure to read it.
+var ErrMediaGone = errors.New("el archivo original ya no está disponible")
+
 var nonPublicMediaPrefixes = []netip.Prefix{
 	// IPv4 special-use ranges that may otherwise report as global unicast.
 	netip.MustParsePrefix("0.0.0.0/8"),
@@ -350,6 +355,9 @@ func downloadRemoteMedia(ctx context.Context, rawURL string) ([]byte, string, er
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone {
+		return nil, "", fmt.Errorf("%w: remote media returned HTTP status %d", ErrMediaGone, response.StatusCode)
+	}
 	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
 		return nil, "", fmt.Errorf("remote media returned HTTP status %d", response.StatusCode)
 	}
@@ -383,6 +391,9 @@ func (p *DevicePool) loadMediaForUpload(ctx context.Context, accountID uuid.UUID
 	}
 
 	info, err := p.storage.GetFileInfo(ctx, objectKey)
+	if storage.IsNotFound(err) {
+		return nil, "", fmt.Errorf("%w: %s", ErrMediaGone, objectKey)
+	}
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to inspect media in storage: %w", err)
 	}
@@ -0,0 +1,144 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/naperu/clarin/internal/domain"
+	"github.com/naperu/clarin/internal/imageutil"
+	"github.com/naperu/clarin/pkg/config"
+)
+
+// MediaLimits are WhatsApp's per-type size ceilings for outbound media. They
+// are lower than the generic upload cap, so a file can be stored fine and still
+// be rejected by WhatsApp at send time.
+type MediaLimits struct {
+	ImageBytes     int64
+	VideoBytes     int64
+	AudioBytes     int64
+	DocumentBytes  int64
+	StickerBytes   int64
+	DownscaleImage bool
+}
+
+// DefaultMediaLimits mirrors the limits WhatsApp currently enforces.
+func DefaultMediaLimits() MediaLimits {
+	return MediaLimits{
+		ImageBytes:     5 << 20,
+		VideoBytes:     16 << 20,
+		AudioBytes:     16 << 20,
+		DocumentBytes:  100 << 20,
+		StickerBytes:   500 << 10,
+		DownscaleImage: true,
+	}
+}
+
+// MediaLimitsFromConfig applies configured overrides on top of the defaults.
+func MediaLimitsFromConfig(cfg *config.Config) MediaLimits {
+	limits := DefaultMediaLimits()
+	if cfg == nil {
+		return limits
+	}
+	if cfg.WhatsAppMaxImageMB > 0 {
+		limits.ImageBytes = int64(cfg.WhatsAppMaxImageMB) << 20
+	}
+	if cfg.WhatsAppMaxVideoMB > 0 {
+		limits.VideoBytes = int64(cfg.WhatsAppMaxVideoMB) << 20
+	}
+	if cfg.WhatsAppMaxAudioMB > 0 {
+		limits.AudioBytes = int64(cfg.WhatsAppMaxAudioMB) << 20
+	}
+	if cfg.WhatsAppMaxDocumentMB > 0 {
+		limits.DocumentBytes = int64(cfg.WhatsAppMaxDocumentMB) << 20
+	}
+	limits.DownscaleImage = cfg.WhatsAppDownscaleImages
+	return limits
+}
+
+// For returns the limit for a message media type, or 0 when unlimited.
+func (l MediaLimits) For(mediaType string) int64 {
+	switch mediaType {
+	case domain.MessageTypeImage:
+		return l.ImageBytes
+	case domain.MessageTypeVideo, domain.MessageTypeGIF:
+		return l.VideoBytes
+	case domain.MessageTypeAudio, domain.MessageTypePTT:
+		return l.AudioBytes
+	case domain.MessageTypeDocument:
+		return l.DocumentBytes
+	case domain.MessageTypeSticker:
+		return l.StickerBytes
+	}
+	return 0
+}
+
+// MediaTooLargeError reports media over WhatsApp's limit for its type.
+type MediaTooLargeError struct {
+	MediaType  string
+	SizeBytes  int64
+	LimitBytes int64
+}
+
+func (e *MediaTooLargeError) Error() string {
+	return fmt.Sprintf("el archivo (%s) supera el límite de WhatsApp para %s (%s)",
+		formatMediaBytes(e.SizeBytes), mediaTypeLabel(e.MediaType), formatMediaBytes(e.LimitBytes))
+}
+
+// IsMediaTooLarge unwraps err into a MediaTooLargeError when it is one.
+func IsMediaTooLarge(err error) (*MediaTooLargeError, bool) {
+	var tooLarge *MediaTooLargeError
+	if errors.As(err, &tooLarge) {
+		return tooLarge, true
+	}
+	return nil, false
+}
+
+// fitMediaLimit enforces the per-type limit before anything is uploaded to
+// WhatsApp. Oversized JPEG/PNG images are downscaled when enabled; everything
+// else fails with a MediaTooLargeError.
+func (l MediaLimits) fitMediaLimit(mediaType, mimetype string, data []byte) ([]byte, string, error) {
+	limit := l.For(mediaType)
+	size := int64(len(data))
+	if limit <= 0 || size <= limit {
+		return data, mimetype, nil
+	}
+	if mediaType == domain.MessageTypeImage && l.DownscaleImage && isDownscalableImage(mimetype) {
+		resized, err := imageutil.DownscaleToFit(data, limit)
+		if err == nil {
+			log.Printf("[UploadMedia] Downscaled image from %d to %d bytes to fit WhatsApp limit", size, len(resized))
+			return resized, "image/jpeg", nil
+		}
+		log.Printf("[UploadMedia] Image downscale failed: %v", err)
+	}
+	return nil, mimetype, &MediaTooLargeError{MediaType: mediaType, SizeBytes: size, LimitBytes: limit}
+}
+
+func isDownscalableImage(mimetype string) bool {
+	mimetype = strings.ToLower(strings.TrimSpace(strings.SplitN(mimetype, ";", 2)[0]))
+	return mimetype == "image/jpeg" || mimetype == "image/jpg" || mimetype == "image/png"
+}
+
+func mediaTypeLabel(mediaType string) string {
+	switch mediaType {
+	case domain.MessageTypeImage:
+		return "imágenes"
+	case domain.MessageTypeVideo, domain.MessageTypeGIF:
+		return "videos"
+	case domain.MessageTypeAudio, domain.MessageTypePTT:
+		return "audios"
+	case domain.MessageTypeDocument:
+		return "documentos"
+	case domain.MessageTypeSticker:
+		return "stickers"
+	}
+	return mediaType
+}
+
+func formatMediaBytes(n int64) string {
+	if n >= 1<<20 {
+		return fmt.Sprintf("%.1f MB", float64(n)/float64(1<<20))
+	}
+	return fmt.Sprintf("%d KB", (n+1023)/1024)
+}
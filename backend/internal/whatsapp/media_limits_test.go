@@ -0,0 +1,67 @@
+package whatsapp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/naperu/clarin/internal/domain"
+)
+
+func noisyPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFitMediaLimitRejectsOversizedVideo(t *testing.T) {
+	limits := MediaLimits{VideoBytes: 10}
+	_, _, err := limits.fitMediaLimit(domain.MessageTypeVideo, "video/mp4", make([]byte, 11))
+	tooLarge, ok := IsMediaTooLarge(err)
+	if !ok {
+		t.Fatalf("error = %v, want MediaTooLargeError", err)
+	}
+	if tooLarge.LimitBytes != 10 || tooLarge.SizeBytes != 11 || tooLarge.MediaType != domain.MessageTypeVideo {
+		t.Fatalf("unexpected error details: %+v", tooLarge)
+	}
+}
+
+func TestFitMediaLimitPassesMediaUnderLimit(t *testing.T) {
+	data := make([]byte, 10)
+	got, mimetype, err := DefaultMediaLimits().fitMediaLimit(domain.MessageTypeDocument, "application/pdf", data)
+	if err != nil || len(got) != 10 || mimetype != "application/pdf" {
+		t.Fatalf("got len=%d mimetype=%q err=%v", len(got), mimetype, err)
+	}
+}
+
+func TestFitMediaLimitDownscalesImages(t *testing.T) {
+	input := noisyPNG(t, 800, 600)
+	limit := int64(len(input) / 4)
+
+	limits := MediaLimits{ImageBytes: limit, DownscaleImage: true}
+	got, mimetype, err := limits.fitMediaLimit(domain.MessageTypeImage, "image/png", input)
+	if err != nil {
+		t.Fatalf("downscale failed: %v", err)
+	}
+	if int64(len(got)) > limit || mimetype != "image/jpeg" {
+		t.Fatalf("got %d bytes (%s), limit %d", len(got), mimetype, limit)
+	}
+
+	limits.DownscaleImage = false
+	if _, _, err := limits.fitMediaLimit(domain.MessageTypeImage, "image/png", input); err == nil {
+		t.Fatal("expected oversized image to be rejected when downscaling is disabled")
+	}
+}
@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// sniffedExtensions maps sniffed content types to the extension used for the
+// stored object, so the media proxy can serve it inline.
+var sniffedExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"audio/mpeg":      ".mp3",
+	"audio/ogg":       ".ogg",
+	"application/ogg": ".ogg",
+	"application/pdf": ".pdf",
+}
+
+func isGenericMimetype(mimetype string) bool {
+	switch strings.ToLower(strings.TrimSpace(mimetype)) {
+	case "", "application/octet-stream", "binary/octet-stream":
+		return true
+	}
+	return false
+}
+
+// sniffMediaMimetype returns declared unless it is empty or generic, in which
+// case the type is detected from the leading bytes of data. WhatsApp leaves
+// the mimetype out of some forwarded documents.
+func sniffMediaMimetype(declared string, data []byte) string {
+	if !isGenericMimetype(declared) {
+		return declared
+	}
+	detected := http.DetectContentType(data)
+	if i := strings.IndexByte(detected, ';'); i >= 0 {
+		detected = strings.TrimSpace(detected[:i])
+	}
+	if isGenericMimetype(detected) && declared != "" {
+		return declared
+	}
+	return detected
+}
+
+// sniffedExtension returns the extension for a detected mimetype, or "" when
+// it is not one the proxy serves inline.
+func sniffedExtension(mimetype string) string {
+	return sniffedExtensions[strings.ToLower(mimetype)]
+}
+
+// storedMediaMimetype prefers the type detected while storing the media over
+// a missing or generic declared one.
+func storedMediaMimetype(declared *string, stored *storedMediaResult) *string {
+	if stored == nil || isGenericMimetype(stored.ContentTyp) {
+		return declared
+	}
+	if declared == nil || isGenericMimetype(*declared) {
+		return strPtr(stored.ContentTyp)
+	}
+	return declared
+}
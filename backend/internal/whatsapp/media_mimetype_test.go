@@ -0,0 +1,38 @@
+package whatsapp
+
+import "testing"
+
+func TestSniffMediaMimetype(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n1 0 obj\n")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	cases := []struct {
+		declared string
+		data     []byte
+		want     string
+	}{
+		{"", pdf, "application/pdf"},
+		{"application/octet-stream", png, "image/png"},
+		{"application/vnd.ms-excel", pdf, "application/vnd.ms-excel"},
+		{"application/octet-stream", []byte{0x00, 0x01, 0x02}, "application/octet-stream"},
+	}
+	for _, tc := range cases {
+		if got := sniffMediaMimetype(tc.declared, tc.data); got != tc.want {
+			t.Fatalf("sniffMediaMimetype(%q) = %q, want %q", tc.declared, got, tc.want)
+		}
+	}
+	if ext := sniffedExtension("application/pdf"); ext != ".pdf" {
+		t.Fatalf("sniffedExtension(pdf) = %q", ext)
+	}
+}
+
+func TestStoredMediaMimetypeKeepsSpecificDeclaredType(t *testing.T) {
+	stored := &storedMediaResult{ContentTyp: "application/pdf"}
+	if got := storedMediaMimetype(strPtr(""), stored); got == nil || *got != "application/pdf" {
+		t.Fatalf("generic declared type was not replaced: %v", got)
+	}
+	declared := strPtr("image/jpeg")
+	if got := storedMediaMimetype(declared, stored); got != declared {
+		t.Fatalf("specific declared type was replaced: %v", *got)
+	}
+}
@@ -0,0 +1,121 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// voiceNoteMimetype is what WhatsApp clients expect for a PTT voice note.
+	voiceNoteMimetype         = "audio/ogg; codecs=opus"
+	maxVoiceNoteOutputBytes   = 16 * 1024 * 1024
+	voiceNoteTranscodeTimeout = 60 * time.Second
+)
+
+var voiceNoteTranscodeSlots = make(chan struct{}, 2)
+
+// isOggOpus reports whether the audio is already an Ogg container, which is
+// how WhatsApp carries Opus voice notes.
+func isOggOpus(mimetype string, data []byte) bool {
+	base := strings.ToLower(strings.TrimSpace(strings.SplitN(mimetype, ";", 2)[0]))
+	if base != "audio/ogg" && base != "audio/opus" && base != "application/ogg" {
+		return false
+	}
+	return len(data) >= 4 && bytes.Equal(data[:4], []byte("OggS"))
+}
+
+// transcodeToVoiceNote converts any audio FFmpeg understands into mono
+// Ogg/Opus so it renders as a voice note instead of an audio attachment.
+func transcodeToVoiceNote(parent context.Context, data []byte) ([]byte, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg no está disponible")
+	}
+	select {
+	case voiceNoteTranscodeSlots <- struct{}{}:
+		defer func() { <-voiceNoteTranscodeSlots }()
+	case <-parent.Done():
+		return nil, parent.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(parent, voiceNoteTranscodeTimeout)
+	defer cancel()
+	tempDir, err := os.MkdirTemp("", "clarin-ptt-*")
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo preparar la nota de voz")
+	}
+	defer os.RemoveAll(tempDir)
+	inputPath := filepath.Join(tempDir, "input")
+	outputPath := filepath.Join(tempDir, "output.ogg")
+	if err := os.WriteFile(inputPath, data, 0o600); err != nil {
+		return nil, fmt.Errorf("no se pudo preparar la nota de voz")
+	}
+
+	command := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-loglevel", "error", "-nostdin", "-y",
+		"-i", inputPath,
+		"-vn", "-ac", "1", "-ar", "48000",
+		"-c:a", "libopus", "-b:a", "32k", "-application", "voip",
+		"-f", "ogg", outputPath,
+	)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("la conversión de la nota de voz agotó el tiempo permitido")
+		}
+		message := strings.TrimSpace(stderr.String())
+		if len(message) > 300 {
+			message = message[:300]
+		}
+		if message == "" {
+			message = "FFmpeg no pudo procesar el audio"
+		}
+		return nil, fmt.Errorf("no se pudo convertir la nota de voz: %s", message)
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la nota de voz convertida")
+	}
+	defer file.Close()
+	result, err := io.ReadAll(io.LimitReader(file, maxVoiceNoteOutputBytes+1))
+	if err != nil || len(result) == 0 || len(result) > maxVoiceNoteOutputBytes {
+		return nil, fmt.Errorf("la nota de voz convertida supera 16 MB")
+	}
+	if !isOggOpus("audio/ogg", result) {
+		return nil, fmt.Errorf("la conversión no produjo un OGG válido")
+	}
+	return result, nil
+}
+
+// storeVoiceNote saves a transcoded voice note next to the account's other
+// media and returns its proxy URL, so the stored message plays the same Ogg
+// the recipient received.
+func (p *DevicePool) storeVoiceNote(ctx context.Context, accountID uuid.UUID, data []byte) (string, error) {
+	if p.storage == nil {
+		return "", fmt.Errorf("storage not configured")
+	}
+	filename := uuid.NewString() + ".ogg"
+	objectKey := fmt.Sprintf("%s/media/ogg/%s", accountID.String(), filename)
+	if _, err := p.storage.UploadObject(ctx, objectKey, data, voiceNoteMimetype); err != nil {
+		return "", err
+	}
+	if p.repos != nil {
+		_, _ = p.repos.DB().Exec(ctx, `
+			INSERT INTO storage_objects (account_id, object_key, media_type, content_type, filename, size_bytes, source, status, updated_at)
+			VALUES ($1, $2, 'ogg', $3, $4, $5, 'voice_note', 'active', NOW())
+			ON CONFLICT (account_id, object_key) DO UPDATE
+			SET size_bytes = EXCLUDED.size_bytes, content_type = EXCLUDED.content_type, status = 'active', updated_at = NOW()
+		`, accountID, objectKey, voiceNoteMimetype, filename, int64(len(data)))
+	}
+	return "/api/media/file/" + objectKey, nil
+}
@@ -0,0 +1,37 @@
+package whatsapp
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestIsOggOpus(t *testing.T) {
+	ogg := []byte("OggS\x00\x02rest")
+	if !isOggOpus("audio/ogg; codecs=opus", ogg) {
+		t.Fatal("ogg voice note not recognised")
+	}
+	if isOggOpus("audio/mpeg", ogg) {
+		t.Fatal("mp3 mimetype accepted as ogg")
+	}
+	if isOggOpus("audio/ogg", []byte("ID3\x04")) {
+		t.Fatal("non-ogg bytes accepted as ogg")
+	}
+}
+
+func TestTranscodeToVoiceNote(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	source, err := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error", "-f", "lavfi", "-i", "sine=frequency=440:duration=1", "-f", "wav", "-").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := transcodeToVoiceNote(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isOggOpus(voiceNoteMimetype, result) {
+		t.Fatalf("expected Ogg output, got %d bytes", len(result))
+	}
+}
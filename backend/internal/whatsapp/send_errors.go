@@ -0,0 +1,34 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// SendErrorStatus returns the status code WhatsApp answered a failed send
+// with: the error attribute of the message ack (whatsmeow reports it as
+// ErrServerReturnedError followed by the code, e.g. 475 when throttled) or
+// the code of an info query error. It returns 0 when err carries no status.
+func SendErrorStatus(err error) int {
+	var iqErr *whatsmeow.IQError
+	if errors.As(err, &iqErr) {
+		return iqErr.Code
+	}
+	if !errors.Is(err, whatsmeow.ErrServerReturnedError) {
+		return 0
+	}
+	msg := err.Error()
+	marker := whatsmeow.ErrServerReturnedError.Error()
+	i := strings.LastIndex(msg, marker)
+	if i < 0 {
+		return 0
+	}
+	var code int
+	if _, scanErr := fmt.Sscanf(msg[i+len(marker):], " %d", &code); scanErr != nil {
+		return 0
+	}
+	return code
+}
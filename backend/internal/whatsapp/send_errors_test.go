@@ -0,0 +1,27 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+)
+
+func TestSendErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "message ack error", err: fmt.Errorf("failed to send message: %w", fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 475)), want: 475},
+		{name: "info query error", err: fmt.Errorf("upload: %w", whatsmeow.ErrIQRateOverLimit), want: 429},
+		{name: "code only in text", err: errors.New("invalid phone 51947503429"), want: 0},
+		{name: "no error", err: nil, want: 0},
+	}
+	for _, tt := range tests {
+		if got := SendErrorStatus(tt.err); got != tt.want {
+			t.Errorf("%s: SendErrorStatus() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -41,3 +41,16 @@ func TestCanonicalStatusViewerJIDPrefersPhoneIdentity(t *testing.T) {
 		t.Fatalf("own identity was accepted as viewer: %q", got)
 	}
 }
+
+func TestGroupReceiptStatusesKeepsReadMessages(t *testing.T) {
+	groups := groupReceiptStatuses([]string{"A", "B", "C"}, "delivered", map[string]string{"A": "read", "B": "delivered"})
+	if len(groups) != 2 {
+		t.Fatalf("expected two groups, got %+v", groups)
+	}
+	if groups[0].status != "read" || len(groups[0].messageIDs) != 1 || groups[0].messageIDs[0] != "A" {
+		t.Fatalf("late delivered receipt downgraded a read message: %+v", groups[0])
+	}
+	if groups[1].status != "delivered" || len(groups[1].messageIDs) != 2 {
+		t.Fatalf("unexpected delivered group %+v", groups[1])
+	}
+}
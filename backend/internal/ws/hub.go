@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/websocket/v2"
@@ -15,11 +16,11 @@ const (
 	// Time allowed to write a message to the peer
 	writeWait = 10 * time.Second
 
-	// Time allowed to read the next pong message from the peer
-	pongWait = 60 * time.Second
+	// Default time allowed to read the next pong message from the peer
+	defaultPongWait = 60 * time.Second
 
-	// Send pings to peer with this interval (must be < pongWait)
-	pingInterval = 30 * time.Second
+	// Default interval between pings to the peer (must be < pongWait)
+	defaultPingInterval = 30 * time.Second
 
 	// Maximum WebSocket connections allowed per account
 	// Prevents memory/goroutine exhaustion from too many open tabs
@@ -43,6 +44,7 @@ const (
 	EventInteractionUpdate      = "interaction_update"
 	EventMessageRevoked         = "message_revoked"
 	EventMessageEdited          = "message_edited"
+	EventMessageStarred         = "message_starred"
 	EventEventParticipantUpdate = "event_participant_update"
 	EventHistorySyncComplete    = "history_sync_complete"
 	EventLogbookUpdate          = "logbook_update"
@@ -52,8 +54,15 @@ const (
 	EventTaskUpdate             = "task_update"
 	EventTaskReminder           = "task_reminder"
 	EventTaskOverdue            = "task_overdue"
+	EventParticipantReminder    = "participant_action_reminder"
 	EventCustomFieldDefUpdate   = "custom_field_def_update"
 	EventWhatsAppStatus         = "whatsapp_status"
+	EventUnreadSummary          = "unread_summary"
+	EventImportProgress         = "import_progress"
+	EventGroupUpdate            = "group_update"
+	EventLeadAssigned           = "lead_assigned"
+	EventChatAssigned           = "chat_assigned"
+	EventKommoSyncProgress      = "kommo_sync_progress"
 )
 
 // Message represents a WebSocket message
@@ -63,6 +72,14 @@ type Message struct {
 	DeviceID           string      `json:"device_id,omitempty"`
 	Data               interface{} `json:"data"`
 	RequiredPermission string      `json:"-"`
+	// UserID, when set, limits delivery to that user's sockets.
+	UserID uuid.UUID `json:"-"`
+
+	// recordScoped marks events about one chat or lead, whose assignee is
+	// checked against each client's assignment scope.
+	recordScoped     bool
+	assignee         *uuid.UUID
+	accountAggregate bool
 }
 
 // Client represents a connected WebSocket client
@@ -74,6 +91,35 @@ type Client struct {
 	Send        chan []byte
 	Hub         *Hub
 	Permissions map[string]bool
+
+	// AccountPermissions lists the additional accounts an aggregated-inbox
+	// socket follows, with the permissions the user holds in each of them.
+	// Permissions above always apply to AccountID.
+	AccountPermissions map[uuid.UUID]map[string]bool
+
+	// Visibility holds the assignment scope of every account where the user
+	// only sees assigned chats and leads; other accounts are unrestricted.
+	Visibility map[uuid.UUID]*domain.AssignmentScope
+
+	// subscription is set by the client's "subscribe" message and read by
+	// the hub loop; nil means every event.
+	subscription atomic.Pointer[Subscription]
+
+	// droppedFrames counts frames skipped because Send was full; the first
+	// one sets evicting.
+	droppedFrames atomic.Int64
+	evicting      atomic.Bool
+}
+
+// ClientStats describes one connected socket for monitoring.
+type ClientStats struct {
+	ID            string    `json:"id"`
+	AccountID     uuid.UUID `json:"account_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	Queued        int       `json:"queued"`
+	BufferSize    int       `json:"buffer_size"`
+	DroppedFrames int64     `json:"dropped_frames"`
+	Slow          bool      `json:"slow"`
 }
 
 func (c *Client) HasPermission(permission string) bool {
@@ -83,16 +129,54 @@ func (c *Client) HasPermission(permission string) bool {
 	return c.Permissions[domain.PermAll] || c.Permissions[permission]
 }
 
+// HasPermissionIn checks permission against the role the user holds in
+// accountID rather than the socket's primary account.
+func (c *Client) HasPermissionIn(accountID uuid.UUID, permission string) bool {
+	if c == nil || accountID == c.AccountID {
+		return c.HasPermission(permission)
+	}
+	perms, ok := c.AccountPermissions[accountID]
+	if !ok {
+		return false
+	}
+	return permission == "" || perms[domain.PermAll] || perms[permission]
+}
+
+// accounts returns every account the client receives events for.
+func (c *Client) accounts() []uuid.UUID {
+	ids := []uuid.UUID{c.AccountID}
+	for id := range c.AccountPermissions {
+		if id != c.AccountID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func clientCanReceive(client *Client, msg *Message) bool {
 	if client == nil || msg == nil {
 		return false
 	}
+	if msg.UserID != uuid.Nil && client.UserID != msg.UserID {
+		return false
+	}
+	if !client.subscription.Load().wants(msg) {
+		return false
+	}
+	if !allowedByScope(client, msg) {
+		return false
+	}
 	required := msg.RequiredPermission
 	// WhatsApp status payloads contain message text/caption and are always part
 	// of Chats, even if a future emitter forgets to annotate the event.
 	if msg.Event == EventWhatsAppStatus {
 		required = domain.PermChats
 	}
+	if msg.AccountID != "" {
+		if accountID, err := uuid.Parse(msg.AccountID); err == nil {
+			return client.HasPermissionIn(accountID, required)
+		}
+	}
 	return client.HasPermission(required)
 }
 
@@ -115,6 +199,24 @@ type Hub struct {
 
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
+
+	// onAccountPresence is told when an account gains its first connected
+	// client or loses its last one.
+	onAccountPresence func(accountID uuid.UUID, online bool)
+
+	// presence serializes onAccountPresence calls per account so a quick
+	// disconnect and reconnect cannot be applied out of order.
+	presenceMu sync.Mutex
+	presence   map[uuid.UUID]*accountPresence
+
+	// resolveAssignee looks up the record assignee of events sent to
+	// accounts with scoped clients.
+	resolveAssignee AssigneeResolver
+
+	// Heartbeat: each client is pinged every pingInterval and closed when
+	// nothing, pongs included, arrives within pongWait.
+	pingInterval time.Duration
+	pongWait     time.Duration
 }
 
 // NewHub creates a new Hub instance
@@ -125,9 +227,28 @@ func NewHub() *Hub {
 		broadcast:      make(chan *Message, 256),
 		register:       make(chan *Client),
 		unregister:     make(chan *Client),
+		pingInterval:   defaultPingInterval,
+		pongWait:       defaultPongWait,
 	}
 }
 
+// SetHeartbeat configures the ping interval and pong timeout used by clients
+// registered afterwards. Non-positive values keep the defaults, and an
+// interval that would not fit inside the timeout is halved to it.
+func (h *Hub) SetHeartbeat(pingInterval, pongWait time.Duration) {
+	if pongWait <= 0 {
+		pongWait = defaultPongWait
+	}
+	if pingInterval <= 0 {
+		pingInterval = defaultPingInterval
+	}
+	if pingInterval >= pongWait {
+		pingInterval = pongWait / 2
+	}
+	h.pingInterval = pingInterval
+	h.pongWait = pongWait
+}
+
 // Run starts the hub's main loop
 func (h *Hub) Run() {
 	for {
@@ -138,34 +259,38 @@ func (h *Hub) Run() {
 				h.accountClients[client.AccountID] = make(map[*Client]bool)
 			}
 			// Enforce connection limit per account — evict oldest if at capacity
+			var evicted []uuid.UUID
 			for len(h.accountClients[client.AccountID]) >= maxConnectionsPerAccount {
 				for old := range h.accountClients[client.AccountID] {
-					delete(h.clients, old)
-					delete(h.accountClients[client.AccountID], old)
-					close(old.Send)
+					evicted = append(evicted, h.removeClientLocked(old)...)
 					log.Printf("[WS Hub] Evicted client %s (account %s): connection limit %d reached", old.ID, client.AccountID, maxConnectionsPerAccount)
 					break
 				}
 			}
-			h.clients[client] = true
-			h.accountClients[client.AccountID][client] = true
+			online := h.addClientLocked(client)
+			// An account emptied by the eviction and refilled by this client
+			// never went offline.
+			var offline []uuid.UUID
+			for _, accountID := range evicted {
+				if len(h.accountClients[accountID]) == 0 {
+					offline = append(offline, accountID)
+				}
+			}
+			online = withoutAccounts(online, evicted)
 			count := len(h.accountClients[client.AccountID])
 			h.mu.Unlock()
+			h.notifyAccountPresence(offline, false)
+			h.notifyAccountPresence(online, true)
 			log.Printf("[WS Hub] Client registered: %s (Account: %s, connections: %d/%d)", client.ID, client.AccountID, count, maxConnectionsPerAccount)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
+			var offline []uuid.UUID
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				if accountClients, ok := h.accountClients[client.AccountID]; ok {
-					delete(accountClients, client)
-					if len(accountClients) == 0 {
-						delete(h.accountClients, client.AccountID)
-					}
-				}
-				close(client.Send)
+				offline = h.removeClientLocked(client)
 			}
 			h.mu.Unlock()
+			h.notifyAccountPresence(offline, false)
 			log.Printf("[WS Hub] Client unregistered: %s", client.ID)
 
 		case message := <-h.broadcast:
@@ -174,6 +299,131 @@ func (h *Hub) Run() {
 	}
 }
 
+// addClientLocked indexes client under its own account and every account it
+// follows in aggregated mode, returning the accounts that had no client
+// before. Callers hold h.mu.
+func (h *Hub) addClientLocked(client *Client) []uuid.UUID {
+	h.clients[client] = true
+	var online []uuid.UUID
+	for _, accountID := range client.accounts() {
+		if len(h.accountClients[accountID]) == 0 {
+			online = append(online, accountID)
+		}
+		if _, ok := h.accountClients[accountID]; !ok {
+			h.accountClients[accountID] = make(map[*Client]bool)
+		}
+		h.accountClients[accountID][client] = true
+	}
+	return online
+}
+
+// removeClientLocked drops client from every index it was registered under
+// and closes its send channel, returning the accounts left without clients.
+// Callers hold h.mu.
+func (h *Hub) removeClientLocked(client *Client) []uuid.UUID {
+	delete(h.clients, client)
+	var offline []uuid.UUID
+	for _, accountID := range client.accounts() {
+		if accountClients, ok := h.accountClients[accountID]; ok {
+			delete(accountClients, client)
+			if len(accountClients) == 0 {
+				delete(h.accountClients, accountID)
+				offline = append(offline, accountID)
+			}
+		}
+	}
+	close(client.Send)
+	return offline
+}
+
+// OnAccountPresence registers fn to run when an account's first client
+// connects (online=true) or its last one disconnects (online=false).
+func (h *Hub) OnAccountPresence(fn func(accountID uuid.UUID, online bool)) {
+	h.mu.Lock()
+	h.onAccountPresence = fn
+	h.mu.Unlock()
+}
+
+func (h *Hub) notifyAccountPresence(accountIDs []uuid.UUID, online bool) {
+	h.mu.RLock()
+	fn := h.onAccountPresence
+	h.mu.RUnlock()
+	if fn == nil {
+		return
+	}
+	h.presenceMu.Lock()
+	defer h.presenceMu.Unlock()
+	if h.presence == nil {
+		h.presence = make(map[uuid.UUID]*accountPresence)
+	}
+	for _, accountID := range accountIDs {
+		p := h.presence[accountID]
+		if p == nil {
+			p = &accountPresence{}
+			h.presence[accountID] = p
+		}
+		p.want = online
+		if !p.running {
+			p.running = true
+			go h.runAccountPresence(accountID, p, fn)
+		}
+	}
+}
+
+// accountPresence tracks the presence last applied to an account and the
+// one most recently requested. Guarded by Hub.presenceMu.
+type accountPresence struct {
+	running bool
+	applied bool
+	sent    bool
+	want    bool
+}
+
+// runAccountPresence applies the requested presence of one account until it
+// settles. Only one runs per account, so calls never overlap and the final
+// call always carries the latest state; intermediate flips are skipped.
+func (h *Hub) runAccountPresence(accountID uuid.UUID, p *accountPresence, fn func(accountID uuid.UUID, online bool)) {
+	for {
+		h.presenceMu.Lock()
+		if p.applied && p.sent == p.want {
+			p.running = false
+			if !p.sent {
+				delete(h.presence, accountID)
+			}
+			h.presenceMu.Unlock()
+			return
+		}
+		want := p.want
+		h.presenceMu.Unlock()
+
+		fn(accountID, want)
+
+		h.presenceMu.Lock()
+		p.applied, p.sent = true, want
+		h.presenceMu.Unlock()
+	}
+}
+
+func withoutAccounts(accountIDs, exclude []uuid.UUID) []uuid.UUID {
+	if len(exclude) == 0 {
+		return accountIDs
+	}
+	kept := accountIDs[:0]
+	for _, accountID := range accountIDs {
+		excluded := false
+		for _, other := range exclude {
+			if accountID == other {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, accountID)
+		}
+	}
+	return kept
+}
+
 // broadcastMessage sends a message to relevant clients
 func (h *Hub) broadcastMessage(msg *Message) {
 	data, err := json.Marshal(msg)
@@ -194,14 +444,7 @@ func (h *Hub) broadcastMessage(msg *Message) {
 					if !clientCanReceive(client, msg) {
 						continue
 					}
-					select {
-					case client.Send <- data:
-					default:
-						// Client buffer full, remove it
-						go func(c *Client) {
-							h.unregister <- c
-						}(client)
-					}
+					h.deliver(client, data)
 				}
 			}
 		}
@@ -213,14 +456,32 @@ func (h *Hub) broadcastMessage(msg *Message) {
 		if !clientCanReceive(client, msg) {
 			continue
 		}
-		select {
-		case client.Send <- data:
-		default:
-			go func(c *Client) {
-				h.unregister <- c
-			}(client)
-		}
+		h.deliver(client, data)
+	}
+}
+
+// deliver queues data for client without blocking the broadcast loop. A full
+// buffer drops the frame, and a client that missed an event can no longer
+// trust its state, so it is unregistered on the first drop: that closes its
+// socket and the browser reconnects and reloads state. A stalled tab never
+// holds back delivery to everyone else.
+func (h *Hub) deliver(client *Client, data []byte) {
+	if client.evicting.Load() {
+		return
+	}
+	select {
+	case client.Send <- data:
+		return
+	default:
+	}
+	client.droppedFrames.Add(1)
+	if !client.evicting.CompareAndSwap(false, true) {
+		return
 	}
+	log.Printf("[WS Hub] Disconnecting slow client %s (account %s): %d frames dropped", client.ID, client.AccountID, client.droppedFrames.Load())
+	go func() {
+		h.unregister <- client
+	}()
 }
 
 // Register adds a client to the hub
@@ -240,22 +501,37 @@ func (h *Hub) Broadcast(msg *Message) {
 
 // BroadcastToAccount sends a message to all clients of a specific account
 func (h *Hub) BroadcastToAccount(accountID uuid.UUID, event string, data interface{}) {
-	h.broadcast <- &Message{
+	msg := &Message{
 		Event:     event,
 		AccountID: accountID.String(),
 		Data:      data,
 	}
+	h.scopeMessage(accountID, msg)
+	h.broadcast <- msg
 }
 
 // BroadcastToAccountWithPermission sends sensitive module payloads only to
 // sockets whose effective account role includes the required permission.
 func (h *Hub) BroadcastToAccountWithPermission(accountID uuid.UUID, permission, event string, data interface{}) {
-	h.broadcast <- &Message{
+	msg := &Message{
 		Event:              event,
 		AccountID:          accountID.String(),
 		Data:               data,
 		RequiredPermission: permission,
 	}
+	h.scopeMessage(accountID, msg)
+	h.broadcast <- msg
+}
+
+// SendToUser delivers an event only to userID's sockets in accountID, for
+// notifications that concern one agent rather than the whole account.
+func (h *Hub) SendToUser(accountID, userID uuid.UUID, event string, data interface{}) {
+	h.broadcast <- &Message{
+		Event:     event,
+		AccountID: accountID.String(),
+		Data:      data,
+		UserID:    userID,
+	}
 }
 
 // BroadcastToAll sends a message to all connected clients across all accounts
@@ -305,6 +581,26 @@ func (h *Hub) GetAccountClientCount(accountID uuid.UUID) int {
 	return 0
 }
 
+// ClientStats reports queue depth and dropped frames for every connected
+// client.
+func (h *Hub) ClientStats() []ClientStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	stats := make([]ClientStats, 0, len(h.clients))
+	for client := range h.clients {
+		stats = append(stats, ClientStats{
+			ID:            client.ID,
+			AccountID:     client.AccountID,
+			UserID:        client.UserID,
+			Queued:        len(client.Send),
+			BufferSize:    cap(client.Send),
+			DroppedFrames: client.droppedFrames.Load(),
+			Slow:          client.evicting.Load(),
+		})
+	}
+	return stats
+}
+
 // ReadPump reads messages from the WebSocket connection
 func (c *Client) ReadPump() {
 	defer func() {
@@ -312,7 +608,9 @@ func (c *Client) ReadPump() {
 		c.Conn.Close()
 	}()
 
-	// Set read deadline — reset on every pong
+	// Set read deadline — reset on every pong or message, so a peer that
+	// stops answering pings is closed and unregistered.
+	pongWait := c.Hub.pongWait
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.Conn.SetPongHandler(func(string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -327,6 +625,7 @@ func (c *Client) ReadPump() {
 			}
 			break
 		}
+		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 
 		// Handle incoming messages from client
 		var msg Message
@@ -342,7 +641,7 @@ func (c *Client) ReadPump() {
 
 // WritePump writes messages to the WebSocket connection
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(pingInterval)
+	ticker := time.NewTicker(c.Hub.pingInterval)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -382,6 +681,18 @@ func (c *Client) handleMessage(msg *Message) {
 	case "ping":
 		// Respond to ping with pong
 		c.Send <- []byte(`{"event":"pong"}`)
+	case EventSubscribe:
+		sub, err := parseSubscription(msg.Data)
+		if err != nil {
+			log.Printf("[WS Client] Invalid subscription from %s: %v", c.ID, err)
+			return
+		}
+		c.subscription.Store(sub)
+		ack, _ := json.Marshal(&Message{Event: EventSubscribed, Data: msg.Data})
+		select {
+		case c.Send <- ack:
+		default:
+		}
 	case "subscribe_chat", "unsubscribe_chat":
 		// Acknowledged — with shared WS singleton, server-side filtering
 		// is not applied (one connection serves multiple UI components).
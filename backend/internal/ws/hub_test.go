@@ -1,8 +1,11 @@
 package ws
 
 import (
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/naperu/clarin/internal/domain"
 )
 
@@ -28,3 +31,251 @@ func TestClientCanReceiveSensitiveEvents(t *testing.T) {
 		t.Fatal("ordinary account event was unexpectedly denied")
 	}
 }
+
+func TestClientCanReceiveUsesRoleOfMessageAccount(t *testing.T) {
+	primary, agentAccount, other := uuid.New(), uuid.New(), uuid.New()
+	client := &Client{
+		AccountID:   primary,
+		Permissions: map[string]bool{domain.PermAll: true},
+		AccountPermissions: map[uuid.UUID]map[string]bool{
+			agentAccount: {domain.PermChats: true},
+		},
+	}
+
+	if !clientCanReceive(client, &Message{AccountID: primary.String(), RequiredPermission: domain.PermReports}) {
+		t.Fatal("admin of the primary account was denied an event")
+	}
+	if !clientCanReceive(client, &Message{AccountID: agentAccount.String(), Event: EventWhatsAppStatus}) {
+		t.Fatal("chat event was denied in an aggregated account that grants Chats")
+	}
+	if clientCanReceive(client, &Message{AccountID: agentAccount.String(), RequiredPermission: domain.PermReports}) {
+		t.Fatal("primary account role leaked into an aggregated account")
+	}
+	if clientCanReceive(client, &Message{AccountID: other.String()}) {
+		t.Fatal("client received an event from an account it does not follow")
+	}
+}
+
+func TestAggregatedClientIsIndexedUnderEveryAccount(t *testing.T) {
+	h := NewHub()
+	primary, extra := uuid.New(), uuid.New()
+	client := &Client{
+		AccountID:          primary,
+		Send:               make(chan []byte, 1),
+		AccountPermissions: map[uuid.UUID]map[string]bool{extra: {domain.PermChats: true}},
+	}
+
+	h.mu.Lock()
+	h.addClientLocked(client)
+	if !h.accountClients[primary][client] || !h.accountClients[extra][client] {
+		h.mu.Unlock()
+		t.Fatal("aggregated client was not indexed under every followed account")
+	}
+	h.removeClientLocked(client)
+	h.mu.Unlock()
+
+	if len(h.accountClients) != 0 || len(h.clients) != 0 {
+		t.Fatalf("client left behind in hub indexes: %d accounts, %d clients", len(h.accountClients), len(h.clients))
+	}
+	if _, open := <-client.Send; open {
+		t.Fatal("send channel was not closed")
+	}
+}
+
+func TestAccountPresenceTransitions(t *testing.T) {
+	h := NewHub()
+	account := uuid.New()
+	first := &Client{AccountID: account, Send: make(chan []byte, 1)}
+	second := &Client{AccountID: account, Send: make(chan []byte, 1)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if online := h.addClientLocked(first); len(online) != 1 || online[0] != account {
+		t.Fatalf("first client did not bring the account online: %v", online)
+	}
+	if online := h.addClientLocked(second); len(online) != 0 {
+		t.Fatalf("second client reported the account online again: %v", online)
+	}
+	if offline := h.removeClientLocked(first); len(offline) != 0 {
+		t.Fatalf("account reported offline while a client remains: %v", offline)
+	}
+	if offline := h.removeClientLocked(second); len(offline) != 1 || offline[0] != account {
+		t.Fatalf("last client did not take the account offline: %v", offline)
+	}
+}
+
+func TestAccountPresenceCallsAreSerialized(t *testing.T) {
+	h := NewHub()
+	account := uuid.New()
+	release := make(chan struct{})
+	calls := make(chan bool, 8)
+	var running, overlapped int32
+	h.OnAccountPresence(func(_ uuid.UUID, online bool) {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		calls <- online
+		<-release
+		atomic.AddInt32(&running, -1)
+	})
+
+	h.notifyAccountPresence([]uuid.UUID{account}, true)
+	if got := <-calls; !got {
+		t.Fatal("first call did not bring the account online")
+	}
+	// Flips queued while the first call runs collapse into the latest one.
+	h.notifyAccountPresence([]uuid.UUID{account}, false)
+	h.notifyAccountPresence([]uuid.UUID{account}, true)
+	h.notifyAccountPresence([]uuid.UUID{account}, false)
+	close(release)
+	select {
+	case got := <-calls:
+		if got {
+			t.Fatal("account left online after its last client disconnected")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued presence was never applied")
+	}
+	select {
+	case got := <-calls:
+		t.Fatalf("unexpected extra presence call: %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if atomic.LoadInt32(&overlapped) != 0 {
+		t.Fatal("presence calls for one account overlapped")
+	}
+}
+
+func TestClientCanReceiveUserScopedEvent(t *testing.T) {
+	accountID, agent, other := uuid.New(), uuid.New(), uuid.New()
+	msg := &Message{Event: EventTaskReminder, AccountID: accountID.String(), UserID: agent}
+
+	if !clientCanReceive(&Client{AccountID: accountID, UserID: agent}, msg) {
+		t.Fatal("assignee did not receive their own event")
+	}
+	if clientCanReceive(&Client{AccountID: accountID, UserID: other}, msg) {
+		t.Fatal("another agent of the account received a user-scoped event")
+	}
+	msg.UserID = uuid.Nil
+	if !clientCanReceive(&Client{AccountID: accountID, UserID: other}, msg) {
+		t.Fatal("account-wide event was limited to one user")
+	}
+}
+
+func TestClientCanReceiveAppliesAssignmentScope(t *testing.T) {
+	accountID, agent, other := uuid.New(), uuid.New(), uuid.New()
+	scoped := &Client{
+		AccountID:   accountID,
+		UserID:      agent,
+		Permissions: map[string]bool{domain.PermAll: true},
+		Visibility:  map[uuid.UUID]*domain.AssignmentScope{accountID: {UserID: agent}},
+	}
+	unscoped := &Client{AccountID: accountID, UserID: other, Permissions: map[string]bool{domain.PermAll: true}}
+
+	own := &Message{Event: EventNewMessage, AccountID: accountID.String(), recordScoped: true, assignee: &agent}
+	foreign := &Message{Event: EventNewMessage, AccountID: accountID.String(), recordScoped: true, assignee: &other}
+	unassigned := &Message{Event: EventNewMessage, AccountID: accountID.String(), recordScoped: true}
+	if !clientCanReceive(scoped, own) {
+		t.Fatal("agent did not receive an event about their own chat")
+	}
+	if clientCanReceive(scoped, foreign) || clientCanReceive(scoped, unassigned) {
+		t.Fatal("scoped agent received an event about a chat outside their scope")
+	}
+	if !clientCanReceive(unscoped, foreign) {
+		t.Fatal("unrestricted client lost a record event")
+	}
+
+	aggregate := &Message{Event: EventUnreadSummary, AccountID: accountID.String(), accountAggregate: true}
+	if clientCanReceive(scoped, aggregate) || !clientCanReceive(unscoped, aggregate) {
+		t.Fatal("account-wide aggregate was not limited to unrestricted clients")
+	}
+	personal := &Message{Event: EventUnreadSummary, AccountID: accountID.String(), UserID: agent}
+	if !clientCanReceive(scoped, personal) {
+		t.Fatal("scoped agent did not receive their own aggregate")
+	}
+}
+
+func TestBroadcastResolvesAssigneeOnlyForScopedAccounts(t *testing.T) {
+	h := NewHub()
+	open, restricted, agent := uuid.New(), uuid.New(), uuid.New()
+	h.addClientLocked(&Client{AccountID: open, Send: make(chan []byte, 1)})
+	h.addClientLocked(&Client{
+		AccountID:  restricted,
+		UserID:     agent,
+		Send:       make(chan []byte, 1),
+		Visibility: map[uuid.UUID]*domain.AssignmentScope{restricted: {UserID: agent}},
+	})
+	calls := 0
+	h.SetAssigneeResolver(func(uuid.UUID, interface{}) (*uuid.UUID, bool) {
+		calls++
+		return &agent, true
+	})
+
+	msg := &Message{Data: map[string]string{"chat_id": "x"}}
+	h.scopeMessage(open, msg)
+	if calls != 0 || msg.recordScoped {
+		t.Fatal("assignee was resolved for an account without scoped clients")
+	}
+	h.scopeMessage(restricted, msg)
+	if calls != 1 || !msg.recordScoped || msg.assignee == nil || *msg.assignee != agent {
+		t.Fatalf("assignee not resolved for a scoped account: calls=%d msg=%+v", calls, msg)
+	}
+	if scopes := h.RestrictedScopes(restricted); len(scopes) != 1 || scopes[0].UserID != agent {
+		t.Fatalf("RestrictedScopes = %+v", scopes)
+	}
+}
+
+func TestSlowClientIsUnregisteredOnFirstDrop(t *testing.T) {
+	h := NewHub()
+	client := &Client{ID: "slow", AccountID: uuid.New(), Send: make(chan []byte, 1)}
+
+	h.deliver(client, []byte("first"))
+	select {
+	case <-h.unregister:
+		t.Fatal("client unregistered while its buffer had room")
+	default:
+	}
+
+	h.deliver(client, []byte("dropped"))
+	select {
+	case got := <-h.unregister:
+		if got != client {
+			t.Fatalf("unregistered %v, want the slow client", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("slow client was not unregistered")
+	}
+}
+
+func TestEvictingClientGetsNoFurtherFrames(t *testing.T) {
+	h := NewHub()
+	client := &Client{Send: make(chan []byte, 1)}
+	h.deliver(client, []byte("a"))
+	h.deliver(client, []byte("b"))
+	<-h.unregister
+	<-client.Send
+	h.deliver(client, []byte("c"))
+	if queued := len(client.Send); queued != 0 {
+		t.Fatalf("evicting client received %d more frames", queued)
+	}
+	if dropped := client.droppedFrames.Load(); dropped != 1 {
+		t.Fatalf("dropped frames = %d, want 1", dropped)
+	}
+	select {
+	case <-h.unregister:
+		t.Fatal("client unregistered twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSetHeartbeatKeepsPingInsidePongWait(t *testing.T) {
+	h := NewHub()
+	h.SetHeartbeat(0, 0)
+	if h.pingInterval != defaultPingInterval || h.pongWait != defaultPongWait {
+		t.Fatalf("defaults = %v/%v, want %v/%v", h.pingInterval, h.pongWait, defaultPingInterval, defaultPongWait)
+	}
+	h.SetHeartbeat(20*time.Second, 10*time.Second)
+	if h.pingInterval != 5*time.Second || h.pongWait != 10*time.Second {
+		t.Fatalf("heartbeat = %v/%v, want 5s/10s", h.pingInterval, h.pongWait)
+	}
+}
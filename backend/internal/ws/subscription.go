@@ -0,0 +1,108 @@
+package ws
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Client → hub control messages for per-socket filtering.
+const (
+	EventSubscribe  = "subscribe"
+	EventSubscribed = "subscribed"
+)
+
+// Subscription narrows which frames a client receives. A nil Subscription,
+// or one with empty sets, means everything, so clients that never subscribe
+// keep the old behavior.
+type Subscription struct {
+	// Events limits delivery to these event types.
+	Events map[string]bool
+	// ChatIDs limits events that carry a chat_id to these chats; events not
+	// tied to a chat are unaffected.
+	ChatIDs map[string]bool
+}
+
+// subscriptionRequest is the payload of a "subscribe" message:
+//
+//	{"event":"subscribe","data":{"events":["new_message"],"chat_ids":["<uuid>"]}}
+//
+// Sending it with empty lists resets the socket to receive everything.
+type subscriptionRequest struct {
+	Events  []string `json:"events"`
+	ChatIDs []string `json:"chat_ids"`
+}
+
+// alwaysDelivered events bypass subscriptions: the UI depends on them
+// regardless of which page subscribed.
+var alwaysDelivered = map[string]bool{
+	EventVersionUpdate: true,
+	EventSubscribed:    true,
+}
+
+func parseSubscription(data interface{}) (*Subscription, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var req subscriptionRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, err
+	}
+	sub := &Subscription{}
+	for _, event := range req.Events {
+		if event = strings.TrimSpace(event); event != "" {
+			if sub.Events == nil {
+				sub.Events = map[string]bool{}
+			}
+			sub.Events[event] = true
+		}
+	}
+	for _, chatID := range req.ChatIDs {
+		id, err := uuid.Parse(strings.TrimSpace(chatID))
+		if err != nil {
+			continue
+		}
+		if sub.ChatIDs == nil {
+			sub.ChatIDs = map[string]bool{}
+		}
+		sub.ChatIDs[id.String()] = true
+	}
+	if sub.Events == nil && sub.ChatIDs == nil {
+		return nil, nil
+	}
+	return sub, nil
+}
+
+// wants reports whether a frame passes the subscription.
+func (s *Subscription) wants(msg *Message) bool {
+	if s == nil || alwaysDelivered[msg.Event] {
+		return true
+	}
+	if s.Events != nil && !s.Events[msg.Event] {
+		return false
+	}
+	if s.ChatIDs != nil {
+		if chatID := messageChatID(msg.Data); chatID != "" && !s.ChatIDs[chatID] {
+			return false
+		}
+	}
+	return true
+}
+
+// messageChatID extracts the chat_id most chat events carry in their payload.
+func messageChatID(data interface{}) string {
+	switch payload := data.(type) {
+	case map[string]interface{}:
+		switch id := payload["chat_id"].(type) {
+		case string:
+			return id
+		case uuid.UUID:
+			return id.String()
+		}
+	case map[string]string:
+		return payload["chat_id"]
+	}
+	return ""
+}
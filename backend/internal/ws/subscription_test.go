@@ -0,0 +1,49 @@
+package ws
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSubscriptionFiltersEventsAndChats(t *testing.T) {
+	chatA, chatB := uuid.New().String(), uuid.New().String()
+	sub, err := parseSubscription(map[string]interface{}{
+		"events":   []string{EventNewMessage, EventLeadUpdate},
+		"chat_ids": []string{chatA, "not-a-uuid"},
+	})
+	if err != nil || sub == nil {
+		t.Fatalf("parseSubscription = %v, %v", sub, err)
+	}
+
+	cases := []struct {
+		msg  *Message
+		want bool
+	}{
+		{&Message{Event: EventNewMessage, Data: map[string]interface{}{"chat_id": chatA}}, true},
+		{&Message{Event: EventNewMessage, Data: map[string]interface{}{"chat_id": chatB}}, false},
+		{&Message{Event: EventLeadUpdate, Data: map[string]interface{}{"action": "created"}}, true},
+		{&Message{Event: EventTyping, Data: map[string]interface{}{"chat_id": chatA}}, false},
+		{&Message{Event: EventVersionUpdate}, true},
+	}
+	for _, tc := range cases {
+		if got := sub.wants(tc.msg); got != tc.want {
+			t.Fatalf("wants(%s %v) = %v, want %v", tc.msg.Event, tc.msg.Data, got, tc.want)
+		}
+	}
+}
+
+func TestEmptySubscriptionReceivesEverything(t *testing.T) {
+	sub, err := parseSubscription(map[string]interface{}{"events": []string{}})
+	if err != nil || sub != nil {
+		t.Fatalf("empty subscription = %v, %v; want nil", sub, err)
+	}
+	client := &Client{}
+	if !clientCanReceive(client, &Message{Event: EventNewMessage, Data: map[string]interface{}{"chat_id": uuid.NewString()}}) {
+		t.Fatal("client without a subscription was filtered")
+	}
+	client.subscription.Store(&Subscription{Events: map[string]bool{EventLeadUpdate: true}})
+	if clientCanReceive(client, &Message{Event: EventNewMessage}) {
+		t.Fatal("subscribed client received an unsubscribed event")
+	}
+}
@@ -0,0 +1,102 @@
+package ws
+
+import (
+	"github.com/google/uuid"
+	"github.com/naperu/clarin/internal/domain"
+)
+
+// AssigneeResolver returns the assignee of the chat or lead an event payload
+// is about. found is false when the payload is not tied to a single record,
+// in which case the event is delivered as usual.
+type AssigneeResolver func(accountID uuid.UUID, data interface{}) (assignee *uuid.UUID, found bool)
+
+// SetAssigneeResolver installs the lookup used to keep record events away
+// from agents limited to their assigned chats and leads.
+func (h *Hub) SetAssigneeResolver(fn AssigneeResolver) {
+	h.mu.Lock()
+	h.resolveAssignee = fn
+	h.mu.Unlock()
+}
+
+// scopeIn returns the assignment scope limiting the client in accountID, or
+// nil when it sees every record there.
+func (c *Client) scopeIn(accountID uuid.UUID) *domain.AssignmentScope {
+	if c == nil {
+		return nil
+	}
+	return c.Visibility[accountID]
+}
+
+// allowedByScope applies the client's assignment scope in the message's
+// account. Events addressed to the client's user were already scoped by the
+// sender.
+func allowedByScope(client *Client, msg *Message) bool {
+	if msg.AccountID == "" || (msg.UserID != uuid.Nil && msg.UserID == client.UserID) {
+		return true
+	}
+	accountID, err := uuid.Parse(msg.AccountID)
+	if err != nil {
+		return true
+	}
+	scope := client.scopeIn(accountID)
+	if scope == nil {
+		return true
+	}
+	if msg.accountAggregate {
+		return false
+	}
+	return !msg.recordScoped || scope.Allows(msg.assignee)
+}
+
+// scopeMessage resolves the record assignee of msg when accountID has
+// clients limited by an assignment scope. It runs on the caller's goroutine
+// so lookups never stall the hub loop.
+func (h *Hub) scopeMessage(accountID uuid.UUID, msg *Message) {
+	h.mu.RLock()
+	resolve := h.resolveAssignee
+	restricted := false
+	if resolve != nil {
+		for client := range h.accountClients[accountID] {
+			if client.scopeIn(accountID) != nil {
+				restricted = true
+				break
+			}
+		}
+	}
+	h.mu.RUnlock()
+	if !restricted {
+		return
+	}
+	msg.assignee, msg.recordScoped = resolve(accountID, msg.Data)
+}
+
+// RestrictedScopes returns one assignment scope per user connected to
+// accountID under restrict_to_assigned, so account-wide aggregates can be
+// recomputed for each of them.
+func (h *Hub) RestrictedScopes(accountID uuid.UUID) []*domain.AssignmentScope {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	seen := make(map[uuid.UUID]bool)
+	var scopes []*domain.AssignmentScope
+	for client := range h.accountClients[accountID] {
+		scope := client.scopeIn(accountID)
+		if scope == nil || seen[client.UserID] {
+			continue
+		}
+		seen[client.UserID] = true
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// BroadcastAccountAggregate sends a payload computed over every record of
+// the account. Clients limited by an assignment scope are skipped; the
+// sender delivers them their own copy with SendToUser.
+func (h *Hub) BroadcastAccountAggregate(accountID uuid.UUID, event string, data interface{}) {
+	h.broadcast <- &Message{
+		Event:            event,
+		AccountID:        accountID.String(),
+		Data:             data,
+		accountAggregate: true,
+	}
+}
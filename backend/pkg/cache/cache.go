@@ -37,6 +37,12 @@ func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Dura
 	return c.client.Set(ctx, key, value, ttl).Err()
 }
 
+// SetNX stores value under key only when the key does not exist yet and
+// reports whether it did.
+func (c *Cache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, value, ttl).Result()
+}
+
 func (c *Cache) IncrWithTTL(ctx context.Context, key string, ttl time.Duration) (int64, error) {
 	pipe := c.client.TxPipeline()
 	incr := pipe.Incr(ctx, key)
@@ -66,6 +72,39 @@ func (c *Cache) DelPattern(ctx context.Context, pattern string) error {
 	return nil
 }
 
+// SAdd adds members to a set and refreshes the set TTL.
+func (c *Cache) SAdd(ctx context.Context, key string, ttl time.Duration, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(members))
+	for i, m := range members {
+		values[i] = m
+	}
+	pipe := c.client.TxPipeline()
+	pipe.SAdd(ctx, key, values...)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// SMembers returns all members of a set (empty when the key does not exist).
+func (c *Cache) SMembers(ctx context.Context, key string) ([]string, error) {
+	return c.client.SMembers(ctx, key).Result()
+}
+
+// SRem removes members from a set.
+func (c *Cache) SRem(ctx context.Context, key string, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(members))
+	for i, m := range members {
+		values[i] = m
+	}
+	return c.client.SRem(ctx, key, values...).Err()
+}
+
 // Ping checks Redis connectivity
 func (c *Cache) Ping(ctx context.Context) error {
 	return c.client.Ping(ctx).Err()
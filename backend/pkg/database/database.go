@@ -733,6 +733,14 @@ func Migrate(db *pgxpool.Pool) error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_quick_reply_attachments_qr ON quick_reply_attachments(quick_reply_id)`,
 
+		// Quick reply usage, for most-used-first ordering
+		`ALTER TABLE quick_replies ADD COLUMN IF NOT EXISTS use_count INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE quick_replies ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ`,
+
+		// Personal quick replies (owner_id NULL = shared with the account)
+		`ALTER TABLE quick_replies ADD COLUMN IF NOT EXISTS owner_id UUID REFERENCES users(id) ON DELETE CASCADE`,
+		`CREATE INDEX IF NOT EXISTS idx_quick_replies_owner ON quick_replies(account_id, owner_id)`,
+
 		// Lead pipeline linkage
 		`ALTER TABLE leads ADD COLUMN IF NOT EXISTS pipeline_id UUID REFERENCES pipelines(id) ON DELETE SET NULL`,
 		`ALTER TABLE leads ADD COLUMN IF NOT EXISTS stage_id UUID REFERENCES pipeline_stages(id) ON DELETE SET NULL`,
@@ -789,6 +797,8 @@ func Migrate(db *pgxpool.Pool) error {
 		`CREATE UNIQUE INDEX IF NOT EXISTS uq_integration_instances_provider_name ON integration_instances(provider, name)`,
 		`CREATE INDEX IF NOT EXISTS idx_integration_instances_provider_active ON integration_instances(provider, is_active)`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS uq_integration_instances_webhook_secret ON integration_instances(provider, webhook_secret) WHERE webhook_secret <> ''`,
+		// OAuth access token expiry; NULL means unknown (read from the token).
+		`ALTER TABLE integration_instances ADD COLUMN IF NOT EXISTS token_expires_at TIMESTAMPTZ`,
 		`CREATE TABLE IF NOT EXISTS integration_instance_accounts (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			integration_instance_id UUID NOT NULL REFERENCES integration_instances(id) ON DELETE CASCADE,
@@ -805,6 +815,22 @@ func Migrate(db *pgxpool.Pool) error {
 		`CREATE INDEX IF NOT EXISTS idx_kommo_connected_pipelines_instance ON kommo_connected_pipelines(integration_instance_id) WHERE integration_instance_id IS NOT NULL`,
 		`ALTER TABLE kommo_connected_pipelines DROP CONSTRAINT IF EXISTS kommo_connected_pipelines_account_id_kommo_pipeline_id_key`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS uq_kommo_connected_pipelines_instance_account_pipeline ON kommo_connected_pipelines(COALESCE(integration_instance_id, '00000000-0000-0000-0000-000000000000'::uuid), account_id, kommo_pipeline_id)`,
+		// Kommo incremental sync cursor: last successful lead sync per account.
+		`CREATE TABLE IF NOT EXISTS kommo_sync_state (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			integration_instance_id UUID REFERENCES integration_instances(id) ON DELETE CASCADE,
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			last_synced_at TIMESTAMPTZ,
+			last_full_sync_at TIMESTAMPTZ,
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uq_kommo_sync_state_instance_account ON kommo_sync_state(COALESCE(integration_instance_id, '00000000-0000-0000-0000-000000000000'::uuid), account_id)`,
+		`INSERT INTO kommo_sync_state (integration_instance_id, account_id, last_synced_at)
+			SELECT integration_instance_id, account_id, MIN(last_synced_at)
+			FROM kommo_connected_pipelines
+			WHERE enabled = TRUE AND last_synced_at IS NOT NULL
+			GROUP BY integration_instance_id, account_id
+			ON CONFLICT DO NOTHING`,
 
 		// Anti-loop: track last push timestamp to detect echoes from Kommo poller
 		`ALTER TABLE leads ADD COLUMN IF NOT EXISTS kommo_last_pushed_at BIGINT DEFAULT 0`,
@@ -923,6 +949,8 @@ func Migrate(db *pgxpool.Pool) error {
 		// WhatsApp extended message fields
 		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS is_revoked BOOLEAN DEFAULT FALSE`,
 		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS is_view_once BOOLEAN DEFAULT FALSE`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS is_forwarded BOOLEAN DEFAULT FALSE`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarding_score INTEGER DEFAULT 0`,
 		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS media_deleted BOOLEAN DEFAULT FALSE`,
 		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS media_deleted_at TIMESTAMPTZ`,
 		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION`,
@@ -2634,9 +2662,67 @@ func Migrate(db *pgxpool.Pool) error {
 			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_csv_import_logs_account_created ON csv_import_logs(account_id, created_at DESC)`,
+		`ALTER TABLE csv_import_logs ADD COLUMN IF NOT EXISTS headers JSONB NOT NULL DEFAULT '[]'::jsonb`,
+		`ALTER TABLE csv_import_logs ADD COLUMN IF NOT EXISTS failed_rows JSONB NOT NULL DEFAULT '[]'::jsonb`,
+		`ALTER TABLE csv_import_logs ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'completed'`,
+		`ALTER TABLE csv_import_logs ADD COLUMN IF NOT EXISTS processed_rows INT NOT NULL DEFAULT 0`,
+		`ALTER TABLE csv_import_logs ADD COLUMN IF NOT EXISTS error TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE csv_import_logs ADD COLUMN IF NOT EXISTS summary JSONB NOT NULL DEFAULT '{}'::jsonb`,
+		`ALTER TABLE csv_import_logs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()`,
+		// Running jobs renew updated_at every minute; one silent for five
+		// minutes died with its instance and can never finish.
+		`UPDATE csv_import_logs SET status = 'failed', error = 'interrumpida por reinicio del servidor', updated_at = NOW() WHERE status IN ('queued', 'running') AND updated_at < NOW() - INTERVAL '5 minutes'`,
 
 		// Three-way merge baseline for tag sync (Clarin ↔ Kommo)
 		`ALTER TABLE leads ADD COLUMN IF NOT EXISTS kommo_synced_tags TEXT[] DEFAULT '{}'`,
+		// Kommo conflict policy (see kommo.ConflictPolicy): kommo_pulled_at marks
+		// the last time Kommo values were applied to the lead.
+		`ALTER TABLE leads ADD COLUMN IF NOT EXISTS kommo_pulled_at TIMESTAMPTZ`,
+		// clarin_edited_at marks the last local edit of a conflict-relevant field.
+		// Archive, block, merge and identity writes also bump updated_at, so the
+		// trigger only stamps changes to the fields kommo.ConflictPolicy covers,
+		// and skips Kommo pulls (they move kommo_pulled_at). Names cleared by the
+		// contact identity sync are not edits.
+		`ALTER TABLE leads ADD COLUMN IF NOT EXISTS clarin_edited_at TIMESTAMPTZ`,
+		`CREATE OR REPLACE FUNCTION stamp_lead_clarin_edit()
+		RETURNS TRIGGER
+		LANGUAGE plpgsql
+		AS $$
+		BEGIN
+			IF NEW.kommo_pulled_at IS NOT DISTINCT FROM OLD.kommo_pulled_at AND (
+				(NEW.name IS NOT NULL AND NEW.name IS DISTINCT FROM OLD.name) OR
+				NEW.pipeline_id IS DISTINCT FROM OLD.pipeline_id OR
+				NEW.stage_id IS DISTINCT FROM OLD.stage_id OR
+				NEW.status IS DISTINCT FROM OLD.status OR
+				NEW.closed_at IS DISTINCT FROM OLD.closed_at OR
+				NEW.close_reason IS DISTINCT FROM OLD.close_reason OR
+				NEW.custom_fields IS DISTINCT FROM OLD.custom_fields
+			) THEN
+				NEW.clarin_edited_at := NOW();
+			END IF;
+			RETURN NEW;
+		END
+		$$`,
+		`CREATE OR REPLACE TRIGGER trg_leads_stamp_clarin_edit
+		BEFORE UPDATE OF name,pipeline_id,stage_id,status,closed_at,close_reason,custom_fields
+		ON leads
+		FOR EACH ROW
+		EXECUTE FUNCTION stamp_lead_clarin_edit()`,
+		`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS kommo_conflict_policy VARCHAR(20) NOT NULL DEFAULT 'kommo_wins'`,
+		`DO $$ BEGIN
+			ALTER TABLE accounts ADD CONSTRAINT accounts_kommo_conflict_policy_check CHECK (kommo_conflict_policy IN ('kommo_wins','clarin_wins','newest_wins'));
+		EXCEPTION WHEN duplicate_object THEN NULL; END $$`,
+		// Kommo custom field mappings: leads.custom_fields key ↔ Kommo lead field.
+		`CREATE TABLE IF NOT EXISTS kommo_field_mappings (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			clarin_key VARCHAR(100) NOT NULL,
+			kommo_field_id BIGINT NOT NULL,
+			field_type VARCHAR(30) NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			UNIQUE(account_id, clarin_key),
+			UNIQUE(account_id, kommo_field_id)
+		)`,
 		// Bootstrap: initialize baseline from current tags for existing Kommo-linked leads
 		`UPDATE leads SET kommo_synced_tags = COALESCE(tags, '{}') WHERE kommo_id IS NOT NULL AND (kommo_synced_tags IS NULL OR kommo_synced_tags = '{}')`,
 
@@ -3747,6 +3833,20 @@ func Migrate(db *pgxpool.Pool) error {
 			OLD.ocupacion IS DISTINCT FROM NEW.ocupacion
 		)
 			EXECUTE FUNCTION sync_contact_identity_snapshots()`,
+
+		// Participant next-action reminders: reminded_for holds the due date that
+		// was reminded, so rescheduling the action arms a new reminder.
+		`ALTER TABLE event_participants ADD COLUMN IF NOT EXISTS next_action_reminded_at TIMESTAMPTZ`,
+		`ALTER TABLE event_participants ADD COLUMN IF NOT EXISTS next_action_reminded_for TIMESTAMPTZ`,
+
+		// Recurring events: each occurrence links to the one materialized after
+		// it; recurrence_materialized_at marks the occurrence as handled.
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_rule TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_copy_participants BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_parent_id UUID REFERENCES events(id) ON DELETE SET NULL`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_next_id UUID REFERENCES events(id) ON DELETE SET NULL`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_materialized_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_events_recurrence_due ON events(event_date) WHERE recurrence_rule <> '' AND recurrence_materialized_at IS NULL`,
 	}
 	migrations = append(migrations, surveyTemplateInstanceMigrations()...)
 
@@ -3929,6 +4029,248 @@ func SeedAdmin(db *pgxpool.Pool, cfg *config.Config) error {
 	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_api_keys_account_id ON api_keys(account_id)`)
 	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash)`)
 
+	// ─── Chat-scoped bot reply tokens ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS chat_reply_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			chat_id UUID NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+			label TEXT NOT NULL DEFAULT '',
+			token_hash TEXT NOT NULL,
+			token_prefix TEXT NOT NULL DEFAULT '',
+			created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			revoked_at TIMESTAMPTZ,
+			last_used_at TIMESTAMPTZ,
+			use_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_reply_tokens_hash ON chat_reply_tokens(token_hash)`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_chat_reply_tokens_chat ON chat_reply_tokens(account_id, chat_id, created_at DESC)`)
+
+	// ─── Campaign start confirmation threshold (0 disables the guard) ───
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS campaign_confirm_threshold INT NOT NULL DEFAULT 500`)
+
+	// ─── Assignment-scoped visibility: agents only list their leads/chats ───
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS restrict_to_assigned BOOLEAN NOT NULL DEFAULT FALSE`)
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS agents_see_unassigned BOOLEAN NOT NULL DEFAULT TRUE`)
+	_, _ = db.Exec(ctx, `ALTER TABLE chats ADD COLUMN IF NOT EXISTS assigned_to UUID REFERENCES users(id) ON DELETE SET NULL`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_chats_account_assigned ON chats(account_id, assigned_to)`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_leads_account_assigned ON leads(account_id, assigned_to)`)
+
+	// ─── Audit trail of super-admin mutations ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			actor_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+			actor_username TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			target_type TEXT NOT NULL DEFAULT '',
+			target_id TEXT NOT NULL DEFAULT '',
+			method VARCHAR(10) NOT NULL,
+			path TEXT NOT NULL,
+			status INT NOT NULL,
+			ip_hash TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at DESC)`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_audit_log_actor_created ON audit_log(actor_user_id, created_at DESC)`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_audit_log_action_created ON audit_log(action, created_at DESC)`)
+
+	// ─── API key owner and role restriction ───
+	_, _ = db.Exec(ctx, `ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE CASCADE`)
+	_, _ = db.Exec(ctx, `ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'admin'`)
+	_, _ = db.Exec(ctx, `ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS role_id UUID REFERENCES roles(id) ON DELETE SET NULL`)
+
+	// ─── Keyword auto-tag rules for inbound messages ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS auto_tag_rules (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			name TEXT NOT NULL DEFAULT '',
+			pattern TEXT NOT NULL,
+			match_mode VARCHAR(20) NOT NULL DEFAULT 'contains',
+			tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_auto_tag_rules_account ON auto_tag_rules(account_id) WHERE is_active`)
+
+	// ─── Optional SMS fallback for campaign recipients without WhatsApp ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS account_sms_settings (
+			account_id UUID PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+			provider VARCHAR(30) NOT NULL DEFAULT 'twilio',
+			enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			twilio_account_sid TEXT NOT NULL DEFAULT '',
+			twilio_auth_token TEXT NOT NULL DEFAULT '',
+			from_number TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `ALTER TABLE campaign_recipients ADD COLUMN IF NOT EXISTS channel VARCHAR(20) NOT NULL DEFAULT 'whatsapp'`)
+
+	// ─── Campaign analytics: when each recipient left pending (sent_at only covers sends) ───
+	_, _ = db.Exec(ctx, `ALTER TABLE campaign_recipients ADD COLUMN IF NOT EXISTS processed_at TIMESTAMPTZ`)
+
+	// ─── Campaign auto-retry: transient send failures are requeued with backoff ───
+	_, _ = db.Exec(ctx, `ALTER TABLE campaign_recipients ADD COLUMN IF NOT EXISTS retry_count INT NOT NULL DEFAULT 0`)
+	_, _ = db.Exec(ctx, `ALTER TABLE campaign_recipients ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMPTZ`)
+
+	// ─── In-chat message search: trigram indexes back body/media_filename ILIKE '%term%' ───
+	if _, err := db.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS pg_trgm`); err != nil {
+		log.Printf("[MIGRATE] Warning: pg_trgm unavailable, message search will scan: %v", err)
+	} else {
+		createIndexConcurrently(ctx, db, "idx_messages_body_trgm",
+			`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_messages_body_trgm ON messages USING gin (body gin_trgm_ops) WHERE body IS NOT NULL AND body <> ''`)
+		createIndexConcurrently(ctx, db, "idx_messages_media_filename_trgm",
+			`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_messages_media_filename_trgm ON messages USING gin (media_filename gin_trgm_ops) WHERE media_filename IS NOT NULL AND media_filename <> ''`)
+	}
+
+	// ─── Chat history order: (timestamp, arrival, id), matching GetByChatID ───
+	createIndexConcurrently(ctx, db, "idx_messages_chat_history_order",
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_messages_chat_history_order ON messages(chat_id, timestamp DESC, (COALESCE(created_at, timestamp)) DESC, id DESC)`)
+
+	// ─── Starred messages ───
+	_, _ = db.Exec(ctx, `ALTER TABLE messages ADD COLUMN IF NOT EXISTS starred BOOLEAN NOT NULL DEFAULT FALSE`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_messages_chat_starred ON messages(chat_id, timestamp DESC) WHERE starred`)
+
+	// ─── Message edits: when the body was last replaced ───
+	_, _ = db.Exec(ctx, `ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ`)
+
+	// ─── Account-wide campaign pacing limits (NULL = no limit) ───
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS campaign_min_delay_seconds INT`)
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS campaign_max_batch_size INT`)
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS campaign_min_batch_pause_minutes INT`)
+
+	// ─── Campaign quiet hours ("HH:MM" in the account timezone; NULL = off) ───
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS quiet_hours_start TEXT`)
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS quiet_hours_end TEXT`)
+	_, _ = db.Exec(ctx, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'America/Lima'`)
+
+	// ─── Outbound webhooks: per-account endpoint and delivery log ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS account_webhooks (
+			account_id UUID PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+			url TEXT NOT NULL DEFAULT '',
+			secret TEXT NOT NULL DEFAULT '',
+			events TEXT[] NOT NULL DEFAULT '{}',
+			enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			event VARCHAR(50) NOT NULL,
+			url TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INT NOT NULL DEFAULT 0,
+			response_status INT,
+			error TEXT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			delivered_at TIMESTAMPTZ
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_account ON webhook_deliveries(account_id, created_at DESC)`)
+	// Pending deliveries are retried from the table, so retries survive restarts.
+	_, _ = db.Exec(ctx, `ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ`)
+	_, _ = db.Exec(ctx, `UPDATE webhook_deliveries SET next_attempt_at = created_at WHERE status = 'pending' AND next_attempt_at IS NULL`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending'`)
+
+	// ─── Contact trash (soft delete, purged after 30 days) ───
+	_, _ = db.Exec(ctx, `ALTER TABLE contacts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`)
+	_, _ = db.Exec(ctx, `ALTER TABLE contacts ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_contacts_deleted ON contacts(account_id, deleted_at) WHERE deleted_at IS NOT NULL`)
+
+	// ─── Lead stage-change history (lead timeline) ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS lead_stage_changes (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			lead_id UUID NOT NULL REFERENCES leads(id) ON DELETE CASCADE,
+			from_stage_id UUID REFERENCES pipeline_stages(id) ON DELETE SET NULL,
+			to_stage_id UUID REFERENCES pipeline_stages(id) ON DELETE SET NULL,
+			from_stage_name TEXT NOT NULL DEFAULT '',
+			to_stage_name TEXT NOT NULL DEFAULT '',
+			changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_lead_stage_changes_lead ON lead_stage_changes(lead_id, created_at DESC)`)
+
+	// ─── Lead scoring: per-account rules and cached score ───
+	_, _ = db.Exec(ctx, `ALTER TABLE leads ADD COLUMN IF NOT EXISTS score INTEGER NOT NULL DEFAULT 0`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_leads_account_score ON leads(account_id, score DESC)`)
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS lead_scoring_rules (
+			account_id UUID PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+			rules JSONB NOT NULL DEFAULT '[]',
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+
+	// ─── Device connection history ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS device_events (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			device_id UUID NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+			event_type VARCHAR(30) NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_device_events_device_created ON device_events(device_id, created_at DESC)`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_device_events_created ON device_events(created_at)`)
+
+	// ─── WhatsApp group metadata ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS whatsapp_groups (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			device_id UUID REFERENCES devices(id) ON DELETE SET NULL,
+			jid VARCHAR(100) NOT NULL,
+			subject TEXT NOT NULL DEFAULT '',
+			topic TEXT NOT NULL DEFAULT '',
+			owner_jid VARCHAR(100) NOT NULL DEFAULT '',
+			is_announce BOOLEAN NOT NULL DEFAULT FALSE,
+			is_locked BOOLEAN NOT NULL DEFAULT FALSE,
+			participants JSONB NOT NULL DEFAULT '[]',
+			participant_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE(account_id, jid)
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_whatsapp_groups_device ON whatsapp_groups(device_id)`)
+
+	// ─── Lead intake webhook tokens ───
+	_, _ = db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS lead_webhook_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			label TEXT NOT NULL DEFAULT '',
+			token_hash TEXT NOT NULL,
+			token_prefix TEXT NOT NULL DEFAULT '',
+			created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			revoked_at TIMESTAMPTZ,
+			last_used_at TIMESTAMPTZ,
+			use_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	_, _ = db.Exec(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS idx_lead_webhook_tokens_hash ON lead_webhook_tokens(token_hash)`)
+	_, _ = db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_lead_webhook_tokens_account ON lead_webhook_tokens(account_id, created_at DESC)`)
+
 	// ─── Global MCP clients, sessions and audit trail ───
 	_, _ = db.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS mcp_clients (
@@ -4416,3 +4758,28 @@ func seedTemplateSurveysForAccount(ctx context.Context, db *pgxpool.Pool, accoun
 
 	return seedCanonicalSurveyTemplatesForAccount(ctx, db, accountID, templates)
 }
+
+// createIndexConcurrently runs a CREATE INDEX CONCURRENTLY IF NOT EXISTS
+// statement and logs a failure instead of aborting startup. A failed
+// concurrent build leaves an INVALID index that IF NOT EXISTS would keep
+// forever, so one left by a previous boot is dropped and rebuilt.
+func createIndexConcurrently(ctx context.Context, db *pgxpool.Pool, name, statement string) {
+	var invalid bool
+	if err := db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+			WHERE c.relname = $1 AND NOT i.indisvalid
+		)
+	`, name).Scan(&invalid); err != nil {
+		log.Printf("[MIGRATE] Warning: failed to check index %s: %v", name, err)
+	} else if invalid {
+		log.Printf("[MIGRATE] Rebuilding invalid index %s", name)
+		if _, err := db.Exec(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+name); err != nil {
+			log.Printf("[MIGRATE] Warning: failed to drop invalid index %s: %v", name, err)
+			return
+		}
+	}
+	if _, err := db.Exec(ctx, statement); err != nil {
+		log.Printf("[MIGRATE] Warning: failed to create index %s: %v", name, err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package webhook implements the HMAC-SHA256 signing scheme shared by every
+// webhook Clarin sends or receives.
+//
+// A signed delivery carries three headers:
+//
+//	X-Clarin-Timestamp: unix seconds when the delivery was signed
+//	X-Clarin-Nonce:     monotonic per-signer value, unique for every delivery
+//	X-Clarin-Signature: v1=<hex(HMAC-SHA256(secret, timestamp + "." + nonce + "." + body))>
+//
+// Receivers recompute the signature with the per-webhook secret, reject
+// timestamps outside a tolerance window and reject nonces already seen inside
+// that window, so a captured delivery cannot be replayed.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HeaderTimestamp  = "X-Clarin-Timestamp"
+	HeaderNonce      = "X-Clarin-Nonce"
+	HeaderSignature  = "X-Clarin-Signature"
+	SignatureVersion = "v1"
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature headers")
+	ErrInvalidTimestamp = errors.New("webhook: invalid timestamp")
+	ErrStaleTimestamp   = errors.New("webhook: timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("webhook: signature mismatch")
+	ErrReplayedNonce    = errors.New("webhook: nonce already used")
+)
+
+// Headers is the signed envelope of a single delivery.
+type Headers struct {
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+// Map returns the headers keyed by their HTTP names.
+func (h Headers) Map() map[string]string {
+	return map[string]string{
+		HeaderTimestamp: h.Timestamp,
+		HeaderNonce:     h.Nonce,
+		HeaderSignature: h.Signature,
+	}
+}
+
+// ComputeSignature returns the versioned signature for a delivery.
+func ComputeSignature(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write([]byte(nonce))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write(body)
+	return SignatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidBodySignature checks a provider-style "sha256=<hex>" header computed
+// over the raw body only (Meta Cloud API and similar providers).
+func ValidBodySignature(secret, signatureHeader string, body []byte) bool {
+	const prefix = "sha256="
+	if secret == "" || !strings.HasPrefix(signatureHeader, prefix) {
+		return false
+	}
+	provided, err := hex.DecodeString(strings.TrimPrefix(signatureHeader, prefix))
+	if err != nil || len(provided) != sha256.Size {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return hmac.Equal(provided, mac.Sum(nil))
+}
+
+// Signer signs outbound deliveries. Nonces are a random per-process prefix
+// plus a strictly increasing counter, so they never repeat across restarts.
+type Signer struct {
+	secret  string
+	prefix  string
+	counter atomic.Uint64
+	now     func() time.Time
+}
+
+func NewSigner(secret string) *Signer {
+	buf := make([]byte, 6)
+	_, _ = rand.Read(buf)
+	return &Signer{secret: secret, prefix: hex.EncodeToString(buf), now: time.Now}
+}
+
+// Sign returns the headers for body.
+func (s *Signer) Sign(body []byte) Headers {
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	nonce := fmt.Sprintf("%s-%d", s.prefix, s.counter.Add(1))
+	return Headers{
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: ComputeSignature(s.secret, timestamp, nonce, body),
+	}
+}
+
+// NonceStore remembers nonces for the tolerance window.
+type NonceStore interface {
+	// Remember records key and reports false when it was already recorded.
+	Remember(key string, ttl time.Duration) bool
+}
+
+// MemoryNonceStore is a process-local NonceStore.
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{entries: make(map[string]time.Time)}
+}
+
+func (m *MemoryNonceStore) Remember(key string, ttl time.Duration) bool {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if exp, ok := m.entries[key]; ok && now.Before(exp) {
+		return false
+	}
+	m.entries[key] = now.Add(ttl)
+	if len(m.entries) > 10000 {
+		for k, exp := range m.entries {
+			if now.After(exp) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	return true
+}
+
+// KeyStore is a key-value store shared by every Clarin instance, such as
+// the Redis cache.
+type KeyStore interface {
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+}
+
+// SharedNonceStore is a NonceStore backed by a KeyStore, so a delivery
+// replayed against another instance is rejected too. A store error counts as
+// a replay: the delivery is refused and the sender retries it.
+type SharedNonceStore struct {
+	store KeyStore
+}
+
+func NewSharedNonceStore(store KeyStore) *SharedNonceStore {
+	return &SharedNonceStore{store: store}
+}
+
+func (s *SharedNonceStore) Remember(key string, ttl time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	stored, err := s.store.SetNX(ctx, "webhook:nonce:"+key, []byte("1"), ttl)
+	if err != nil {
+		log.Printf("[WEBHOOK] nonce store unavailable: %v", err)
+		return false
+	}
+	return stored
+}
+
+// Verifier validates inbound signed deliveries.
+type Verifier struct {
+	Tolerance time.Duration
+	Nonces    NonceStore
+	now       func() time.Time
+}
+
+func NewVerifier(tolerance time.Duration, nonces NonceStore) *Verifier {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if nonces == nil {
+		nonces = NewMemoryNonceStore()
+	}
+	return &Verifier{Tolerance: tolerance, Nonces: nonces, now: time.Now}
+}
+
+// Verify checks headers against body for the given secret.
+func (v *Verifier) Verify(secret string, headers Headers, body []byte) error {
+	if headers.Timestamp == "" || headers.Nonce == "" || headers.Signature == "" || secret == "" {
+		return ErrMissingSignature
+	}
+	ts, err := strconv.ParseInt(headers.Timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	skew := v.now().Sub(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > v.Tolerance {
+		return ErrStaleTimestamp
+	}
+	expected := ComputeSignature(secret, headers.Timestamp, headers.Nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(headers.Signature)) {
+		return ErrInvalidSignature
+	}
+	// Only remember nonces from authentic deliveries so forged requests cannot
+	// poison the store. Keep them for both sides of the tolerance window.
+	if !v.Nonces.Remember(secretScope(secret)+":"+headers.Nonce, 2*v.Tolerance) {
+		return ErrReplayedNonce
+	}
+	return nil
+}
+
+func secretScope(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
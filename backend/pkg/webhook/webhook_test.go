@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignerVerifierRoundTrip(t *testing.T) {
+	signer := NewSigner("secret")
+	verifier := NewVerifier(time.Minute, nil)
+	body := []byte(`{"event":"message.received"}`)
+
+	headers := signer.Sign(body)
+	if err := verifier.Verify("secret", headers, body); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := verifier.Verify("secret", headers, body); !errors.Is(err, ErrReplayedNonce) {
+		t.Fatalf("replayed Verify() error = %v, want ErrReplayedNonce", err)
+	}
+	if err := verifier.Verify("other", signer.Sign(body), body); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("wrong secret Verify() error = %v, want ErrInvalidSignature", err)
+	}
+	if err := verifier.Verify("secret", signer.Sign(body), []byte(`{}`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("tampered body Verify() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestSignerNoncesAreMonotonic(t *testing.T) {
+	signer := NewSigner("secret")
+	first := signer.Sign(nil).Nonce
+	second := signer.Sign(nil).Nonce
+	if first == second {
+		t.Fatalf("nonces repeated: %q", first)
+	}
+}
+
+func TestVerifierRejectsStaleTimestamp(t *testing.T) {
+	verifier := NewVerifier(time.Minute, nil)
+	body := []byte("payload")
+	ts := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+	headers := Headers{Timestamp: ts, Nonce: "n-1", Signature: ComputeSignature("secret", ts, "n-1", body)}
+	if err := verifier.Verify("secret", headers, body); !errors.Is(err, ErrStaleTimestamp) {
+		t.Fatalf("Verify() error = %v, want ErrStaleTimestamp", err)
+	}
+	if err := verifier.Verify("secret", Headers{}, body); !errors.Is(err, ErrMissingSignature) {
+		t.Fatalf("Verify() error = %v, want ErrMissingSignature", err)
+	}
+}
+
+func TestValidBodySignature(t *testing.T) {
+	body := []byte("hello")
+	// HMAC-SHA256("key", "hello")
+	header := "sha256=9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b"
+	if !ValidBodySignature("key", header, body) {
+		t.Fatal("expected valid signature")
+	}
+	if ValidBodySignature("key", "sha256=00", body) || ValidBodySignature("", header, body) {
+		t.Fatal("expected invalid signature")
+	}
+}
+
+type fakeKeyStore struct {
+	keys map[string]bool
+	err  error
+}
+
+func (f *fakeKeyStore) SetNX(_ context.Context, key string, _ []byte, _ time.Duration) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	if f.keys[key] {
+		return false, nil
+	}
+	f.keys[key] = true
+	return true, nil
+}
+
+func TestSharedNonceStoreRejectsReplaysAndStoreErrors(t *testing.T) {
+	store := &fakeKeyStore{keys: map[string]bool{}}
+	nonces := NewSharedNonceStore(store)
+	if !nonces.Remember("n1", time.Minute) {
+		t.Fatal("first delivery was rejected")
+	}
+	if nonces.Remember("n1", time.Minute) {
+		t.Fatal("replayed nonce was accepted")
+	}
+	store.err = errors.New("redis down")
+	if nonces.Remember("n2", time.Minute) {
+		t.Fatal("nonce accepted while the shared store was unavailable")
+	}
+}
@@ -687,6 +687,8 @@ export default function EventDetailPage() {
   const [stageLayoutError, setStageLayoutError] = useState('')
   const [draggedStageId, setDraggedStageId] = useState<string | null>(null)
   const [duplicatingEvent, setDuplicatingEvent] = useState(false)
+  const [importingParticipants, setImportingParticipants] = useState(false)
+  const participantImportInputRef = useRef<HTMLInputElement>(null)
 
   // Google Sync
   const [showGoogleSyncModal, setShowGoogleSyncModal] = useState(false)
@@ -1517,6 +1519,42 @@ export default function EventDetailPage() {
     }
   }, [duplicatingEvent, eventId, router])
 
+  const handleImportParticipantsFile = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const file = e.target.files?.[0]
+    e.target.value = ''
+    if (!file || importingParticipants) return
+    setImportingParticipants(true)
+    try {
+      const formData = new FormData()
+      formData.append('file', file)
+      const res = await fetch(`/api/events/${eventId}/participants/import`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${getToken()}` },
+        body: formData,
+      })
+      const data = await res.json()
+      if (!res.ok || !data.success) {
+        alert(data.error || 'No se pudo importar el archivo.')
+        return
+      }
+      const added = Number(data.added || 0)
+      if (added > 0) {
+        fetchParticipantsPaginated()
+        fetchEvent()
+      }
+      const lines = [`${added} participante${added !== 1 ? 's añadidos' : ' añadido'}.`]
+      if (data.duplicates > 0) lines.push(`${data.duplicates} omitido${data.duplicates !== 1 ? 's' : ''} por estar duplicado${data.duplicates !== 1 ? 's' : ''}.`)
+      if (data.skipped > 0) lines.push(`${data.skipped} fila${data.skipped !== 1 ? 's' : ''} sin teléfono ni email válido o rechazada${data.skipped !== 1 ? 's' : ''}.`)
+      if (data.partial) lines.push(data.error || `${data.not_processed} filas no se procesaron.`)
+      alert(lines.join('\n'))
+    } catch (err) {
+      console.error('[ImportEventParticipants]', err)
+      alert('No se pudo importar el archivo.')
+    } finally {
+      setImportingParticipants(false)
+    }
+  }
+
   // ─── Add Participants ────────────────────────────────────────────────────────
   const handleAddFromSelector = async (selected: SelectedPerson[]) => {
     if (selected.length === 0 || addingParticipant) return
@@ -2977,6 +3015,13 @@ export default function EventDetailPage() {
             <ChevronDown className={`w-3.5 h-3.5 transition-transform ${showMoreMenu ? 'rotate-180' : ''}`} />
           </button>
 
+          <input
+            ref={participantImportInputRef}
+            type="file"
+            accept=".csv,.xlsx,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+            className="hidden"
+            onChange={handleImportParticipantsFile}
+          />
           {showMoreMenu && (
             <div className={`${isCompactLayout ? 'fixed inset-x-3 bottom-[max(.75rem,env(safe-area-inset-bottom))] max-h-[calc(var(--app-height)-5rem)] overflow-y-auto rounded-2xl' : 'absolute right-0 top-full mt-1.5 w-56 rounded-xl overflow-hidden'} bg-white border border-slate-200 shadow-xl z-[70] py-1`}>
               {/* 1. Agregar contacto */}
@@ -3035,6 +3080,17 @@ export default function EventDetailPage() {
                 </span>
               </button>
 
+              {/* Importar CSV */}
+              <button
+                onClick={() => { setShowMoreMenu(false); participantImportInputRef.current?.click() }}
+                disabled={eventHasMembershipRules || eventIsReadOnly || importingParticipants}
+                title={eventIsReadOnly ? 'El evento está cerrado' : eventHasMembershipRules ? 'Disponible solo para eventos sin reglas' : 'Agregar participantes desde un CSV o Excel'}
+                className="w-full min-h-11 flex items-center gap-3 px-4 py-2.5 text-left text-sm text-slate-700 hover:bg-slate-50 transition-colors disabled:cursor-not-allowed disabled:text-slate-400 disabled:hover:bg-white"
+              >
+                {importingParticipants ? <Loader2 className="w-4 h-4 text-slate-400 animate-spin" /> : <FileSpreadsheet className="w-4 h-4 text-slate-400" />}
+                Importar CSV
+              </button>
+
               {/* 5. Editar etapas */}
               <button
                 onClick={beginStageEditMode}
@@ -88,6 +88,8 @@ interface Event {
   rule_revision?: number
   tag_formula?: string
   tag_formula_type?: string
+  recurrence_rule?: string
+  recurrence_copy_participants?: boolean
   folder_id?: string | null
   created_at: string
   total_participants: number
@@ -118,6 +120,14 @@ const STATUS_OPTIONS = [
   { value: 'cancelled', label: 'Cancelado', desc: 'Evento eliminado, sin sincronizar', color: 'bg-red-100 text-red-700', syncing: false },
 ]
 
+// Values are the canonical rules stored by the API.
+const RECURRENCE_OPTIONS = [
+  { value: 'FREQ=DAILY;INTERVAL=1', label: 'Cada día' },
+  { value: 'FREQ=WEEKLY;INTERVAL=1', label: 'Cada semana' },
+  { value: 'FREQ=WEEKLY;INTERVAL=2', label: 'Cada 2 semanas' },
+  { value: 'FREQ=MONTHLY;INTERVAL=1', label: 'Cada mes' },
+]
+
 const COLOR_OPTIONS = [
   '#3b82f6', '#10b981', '#f59e0b', '#ef4444', '#8b5cf6', '#ec4899',
   '#06b6d4', '#f97316', '#14b8a6', '#6366f1',
@@ -179,6 +189,7 @@ export default function EventsPage() {
     name: '', description: '', event_date: '', event_end: '', location: '', color: '#3b82f6', status: 'active',
     tag_ids: [] as string[], formula_mode: 'OR' as 'AND' | 'OR', include_tag_ids: [] as string[], exclude_tag_ids: [] as string[],
     tag_formula: '', tag_formula_type: 'simple' as 'simple' | 'advanced',
+    recurrence_rule: '', recurrence_copy_participants: false,
   })
 
   // Tags for auto-sync
@@ -506,7 +517,7 @@ export default function EventsPage() {
   // ─── Event CRUD ─────────────────────────────────────────────────────────────
 
   const resetEventForm = () => {
-    setFormData({ name: '', description: '', event_date: '', event_end: '', location: '', color: '#3b82f6', status: 'active', tag_ids: [], formula_mode: 'OR', include_tag_ids: [], exclude_tag_ids: [], tag_formula: '', tag_formula_type: 'simple' })
+    setFormData({ name: '', description: '', event_date: '', event_end: '', location: '', color: '#3b82f6', status: 'active', tag_ids: [], formula_mode: 'OR', include_tag_ids: [], exclude_tag_ids: [], tag_formula: '', tag_formula_type: 'simple', recurrence_rule: '', recurrence_copy_participants: false })
     setShowTagDropdown(false)
     setTagSearch('')
     setFormulaIsValid(true)
@@ -556,6 +567,8 @@ export default function EventsPage() {
       exclude_tag_ids: excludeIds,
       tag_formula: tagFormula,
       tag_formula_type: tagFormulaType,
+      recurrence_rule: ev.recurrence_rule || '',
+      recurrence_copy_participants: !!ev.recurrence_copy_participants,
     })
     setEditEvent(ev)
   }
@@ -885,6 +898,28 @@ export default function EventsPage() {
                     className="w-full px-3 py-2.5 border border-slate-300 rounded-lg focus:ring-2 focus:ring-emerald-500 text-slate-900 text-sm" />
                 </div>
               </div>
+              <div className="grid grid-cols-1 gap-4 sm:grid-cols-2">
+                <div>
+                  <label className="block text-sm font-medium text-slate-700 mb-1">Repetir</label>
+                  <select value={formData.recurrence_rule}
+                    onChange={e => setFormData({ ...formData, recurrence_rule: e.target.value })}
+                    className="w-full px-3 py-2.5 border border-slate-300 rounded-lg focus:ring-2 focus:ring-emerald-500 text-slate-900 text-sm">
+                    <option value="">No se repite</option>
+                    {RECURRENCE_OPTIONS.map(o => <option key={o.value} value={o.value}>{o.label}</option>)}
+                    {formData.recurrence_rule && !RECURRENCE_OPTIONS.some(o => o.value === formData.recurrence_rule) && (
+                      <option value={formData.recurrence_rule}>{formData.recurrence_rule}</option>
+                    )}
+                  </select>
+                </div>
+                {formData.recurrence_rule && (
+                  <label className="flex items-center gap-2 text-sm text-slate-700 sm:mt-7">
+                    <input type="checkbox" checked={formData.recurrence_copy_participants}
+                      onChange={e => setFormData({ ...formData, recurrence_copy_participants: e.target.checked })}
+                      className="rounded border-slate-300 text-emerald-600 focus:ring-emerald-500" />
+                    Invitar a los mismos participantes en la siguiente fecha
+                  </label>
+                )}
+              </div>
               <div>
                 <label className="block text-sm font-medium text-slate-700 mb-1">Ubicación</label>
                 <input value={formData.location} onChange={e => setFormData({ ...formData, location: e.target.value })}
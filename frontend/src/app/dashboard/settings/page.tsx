@@ -1062,8 +1062,8 @@ export default function SettingsPage() {
   const [notifSettings, setNotifSettings] = useState<NotificationSettings | null>(null)
   const [notifPermission, setNotifPermission] = useState<NotificationPermission>('default')
   const { refreshSettings: refreshProviderSettings } = useNotifications()
-  const [quickReplies, setQuickReplies] = useState<{ id: string; shortcut: string; title: string; body: string; media_url: string; media_type: string; media_filename: string; attachments: { id?: string; media_url: string; media_type: string; media_filename: string; caption: string; position: number }[] }[]>([])
-  const [editingQR, setEditingQR] = useState<{ id?: string; shortcut: string; title: string; body: string; media_url: string; media_type: string; media_filename: string; attachments: { id?: string; media_url: string; media_type: string; media_filename: string; caption: string; position: number }[] } | null>(null)
+  const [quickReplies, setQuickReplies] = useState<{ id: string; owner_id?: string; shortcut: string; title: string; body: string; media_url: string; media_type: string; media_filename: string; attachments: { id?: string; media_url: string; media_type: string; media_filename: string; caption: string; position: number }[] }[]>([])
+  const [editingQR, setEditingQR] = useState<{ id?: string; personal?: boolean; shortcut: string; title: string; body: string; media_url: string; media_type: string; media_filename: string; attachments: { id?: string; media_url: string; media_type: string; media_filename: string; caption: string; position: number }[] } | null>(null)
   const [savingQR, setSavingQR] = useState(false)
   const [uploadingQRMedia, setUploadingQRMedia] = useState(false)
   const [integrationView, setIntegrationView] = useState<'list' | 'google'>('list')
@@ -1456,6 +1456,7 @@ export default function SettingsPage() {
           media_url: editingQR.media_url || '',
           media_type: editingQR.media_type || '',
           media_filename: editingQR.media_filename || '',
+          personal: !!editingQR.personal,
           attachments: editingQR.attachments.map((a, i) => ({
             media_url: a.media_url,
             media_type: a.media_type,
@@ -3171,6 +3172,18 @@ export default function SettingsPage() {
                     </div>
                   )}
 
+                  {!editingQR.id && (
+                    <label className="flex items-center gap-2 text-xs text-slate-600">
+                      <input
+                        type="checkbox"
+                        checked={!!editingQR.personal}
+                        onChange={e => setEditingQR({ ...editingQR, personal: e.target.checked })}
+                        className="rounded border-slate-300 text-emerald-600 focus:ring-emerald-500"
+                      />
+                      Solo para mí (respuesta personal, no visible para el resto del equipo)
+                    </label>
+                  )}
+
                   <div className="flex justify-end gap-2">
                     <button
                       onClick={() => setEditingQR(null)}
@@ -3205,6 +3218,9 @@ export default function SettingsPage() {
                         /{qr.shortcut}
                       </span>
                       <div className="flex-1 min-w-0">
+                        {qr.owner_id && (
+                          <span className="inline-block px-1.5 py-0.5 mb-1 bg-slate-200 text-slate-600 text-[10px] rounded">Personal</span>
+                        )}
                         {qr.title && <p className="text-sm font-medium text-slate-900">{qr.title}</p>}
                         {qr.attachments && qr.attachments.length > 0 && (
                           <div className="flex items-center gap-1.5 mb-1 flex-wrap">
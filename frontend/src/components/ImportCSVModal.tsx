@@ -44,6 +44,7 @@ interface ImportSummary {
   duplicate_contact_leads: number
   invalid_rows: number
   duplicate_policy?: string
+  existing_mode?: string
   safe_mode: boolean
   incoming_destination?: string
   rows?: ImportPreviewRow[] | null
@@ -140,6 +141,7 @@ const normalizeImportSummary = (summary?: Partial<ImportSummary> | null): Import
 export default function ImportCSVModal({ open, onClose, onSuccess, defaultType = 'leads' }: ImportCSVModalProps) {
   const [file, setFile] = useState<File | null>(null)
   const [importTagMode, setImportTagMode] = useState<'none' | 'custom'>('none')
+  const [existingMode, setExistingMode] = useState<'skip' | 'update_existing'>('skip')
   const [importTag, setImportTag] = useState('')
   const [tagSearch, setTagSearch] = useState('')
   const [tagOptions, setTagOptions] = useState<ImportTagOption[]>([])
@@ -147,6 +149,7 @@ export default function ImportCSVModal({ open, onClose, onSuccess, defaultType =
   const [newTagColor, setNewTagColor] = useState(TAG_PRESET_COLORS[6])
   const [previewing, setPreviewing] = useState(false)
   const [uploading, setUploading] = useState(false)
+  const [importProgress, setImportProgress] = useState<{ processed: number; total: number } | null>(null)
   const [preview, setPreview] = useState<ImportSummary | null>(null)
   const [result, setResult] = useState<ImportSummary | null>(null)
   const [error, setError] = useState('')
@@ -157,6 +160,7 @@ export default function ImportCSVModal({ open, onClose, onSuccess, defaultType =
   useEffect(() => {
     if (!open) return
     setImportTagMode('none')
+    setExistingMode('skip')
     setImportTag('')
     setTagSearch('')
     setNewTagColor(TAG_PRESET_COLORS[6])
@@ -284,6 +288,7 @@ export default function ImportCSVModal({ open, onClose, onSuccess, defaultType =
     if (defaultType !== 'contacts' && importTagMode === 'custom' && importTag.trim()) {
       formData.append('import_tag', importTag.trim())
     }
+    formData.append('existing_mode', existingMode)
     return formData
   }
 
@@ -365,17 +370,34 @@ export default function ImportCSVModal({ open, onClose, onSuccess, defaultType =
         body: formData,
       })
       const data = await res.json()
-      if (data.success) {
-        setResult(normalizeImportSummary(data.summary))
+      if (!data.success) {
+        setError(data.error || 'Error desconocido')
+        return
+      }
+      // The import runs as a background job; poll until it finishes.
+      let job = data
+      while (job.success && job.job_id && job.status !== 'completed' && job.status !== 'failed') {
+        await new Promise(resolve => setTimeout(resolve, 1500))
+        const jobRes = await fetch(`/api/import/${data.job_id}`, {
+          headers: { Authorization: `Bearer ${token}` },
+        })
+        job = await jobRes.json()
+        if (job.success && job.total > 0) {
+          setImportProgress({ processed: job.processed, total: job.total })
+        }
+      }
+      if (job.success && job.status === 'completed') {
+        setResult(normalizeImportSummary(job.summary))
         setPreview(null)
         onSuccess()
       } else {
-        setError(data.error || 'Error desconocido')
+        setError(job.error || 'Error desconocido')
       }
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Error de conexión')
     } finally {
       setUploading(false)
+      setImportProgress(null)
     }
   }
 
@@ -387,6 +409,7 @@ export default function ImportCSVModal({ open, onClose, onSuccess, defaultType =
     setPreviewing(false)
     setUploading(false)
     setImportTagMode('none')
+    setExistingMode('skip')
     setImportTag('')
     setTagSearch('')
     setNewTagColor(TAG_PRESET_COLORS[6])
@@ -608,7 +631,7 @@ export default function ImportCSVModal({ open, onClose, onSuccess, defaultType =
               </button>
               <button onClick={handleUpload} disabled={uploading || preview.total_rows === 0} className="min-h-11 flex-1 rounded-xl bg-green-600 px-4 py-2.5 text-sm font-medium text-white transition hover:bg-green-700 disabled:opacity-50">
                 {uploading ? (
-                  <span className="flex items-center justify-center gap-2"><Loader2 className="w-4 h-4 animate-spin" />Importando...</span>
+                  <span className="flex items-center justify-center gap-2"><Loader2 className="w-4 h-4 animate-spin" />{importProgress ? `Importando ${importProgress.processed}/${importProgress.total}...` : 'Importando...'}</span>
                 ) : (
                   preview.new_opportunities > 0 ? `Crear ${preview.new_opportunities} oportunidades` : 'Procesar importación'
                 )}
@@ -677,6 +700,39 @@ export default function ImportCSVModal({ open, onClose, onSuccess, defaultType =
               <p><span className="text-gray-500 font-medium">Seguro:</span> las oportunidades elegibles se crean siempre; si el contacto ya tiene una abierta se omite, y los IDs Kommo existentes sólo pueden actualizar etiquetas dentro de 24h</p>
             </div>
 
+            <div className="rounded-xl border border-slate-200 p-3.5">
+              <label className="text-xs font-semibold text-slate-500 uppercase">Registros existentes</label>
+              <div className="mt-2 grid grid-cols-2 gap-2">
+                <button
+                  type="button"
+                  onClick={() => { setExistingMode('skip'); setPreview(null) }}
+                  className={`rounded-lg border px-3 py-2 text-sm font-medium transition ${
+                    existingMode === 'skip'
+                      ? 'border-emerald-200 bg-emerald-50 text-emerald-700'
+                      : 'border-slate-200 text-slate-600 hover:bg-slate-50'
+                  }`}
+                >
+                  Omitir
+                </button>
+                <button
+                  type="button"
+                  onClick={() => { setExistingMode('update_existing'); setPreview(null) }}
+                  className={`rounded-lg border px-3 py-2 text-sm font-medium transition ${
+                    existingMode === 'update_existing'
+                      ? 'border-emerald-200 bg-emerald-50 text-emerald-700'
+                      : 'border-slate-200 text-slate-600 hover:bg-slate-50'
+                  }`}
+                >
+                  Actualizar
+                </button>
+              </div>
+              {existingMode === 'update_existing' && (
+                <p className="mt-2 text-xs text-slate-500">
+                  Se sobrescriben nombre, apellido, email, empresa, DNI, fecha de nacimiento, título y notas con los valores no vacíos del archivo, y se agregan sus etiquetas. Se conservan teléfono, etapa, responsable, estado y campos personalizados.
+                </p>
+              )}
+            </div>
+
             {defaultType !== 'contacts' && (
               <div className="rounded-xl border border-slate-200 p-3.5">
                 <label className="text-xs font-semibold text-slate-500 uppercase">Etiqueta para nuevos leads importados</label>
@@ -71,6 +71,15 @@ export default function NotificationProvider({ accountId, children }: Props) {
       if (msg.event === 'task_overdue') {
         handleTaskOverdue(msg.data as { title?: string; due_at?: string })
       }
+      if (msg.event === 'participant_action_reminder') {
+        handleParticipantReminder(msg.data as { event_id?: string; event_name?: string; name?: string; next_action?: string; next_action_date?: string })
+      }
+      if (msg.event === 'lead_assigned') {
+        handleLeadAssigned(msg.data as { lead_id?: string; title?: string })
+      }
+      if (msg.event === 'chat_assigned') {
+        handleChatAssigned(msg.data as { chat_id?: string })
+      }
     })
 
     return () => {
@@ -136,6 +145,45 @@ export default function NotificationProvider({ accountId, children }: Props) {
     }
   }, [])
 
+  const handleParticipantReminder = useCallback((data: { event_id?: string; event_name?: string; name?: string; next_action?: string; next_action_date?: string }) => {
+    const s = settingsRef.current
+    if (s?.sound_enabled && s.sound_type !== 'none') {
+      playNotificationSound(s.sound_type, s.sound_volume)
+    }
+    if (s?.browser_notifications) {
+      const dueStr = data.next_action_date ? new Date(data.next_action_date).toLocaleTimeString('es', { hour: '2-digit', minute: '2-digit' }) : ''
+      const action = data.next_action || 'Próxima acción'
+      const body = `${action}${data.name ? ` — ${data.name}` : ''}${dueStr ? ` (${dueStr})` : ''}`
+      showBrowserNotification(`⏰ ${data.event_name || 'Recordatorio de evento'}`, body, () => {
+        window.location.href = data.event_id ? `/dashboard/events/${data.event_id}` : '/dashboard/events'
+      })
+    }
+  }, [])
+
+  const handleLeadAssigned = useCallback((data: { lead_id?: string; title?: string }) => {
+    const s = settingsRef.current
+    if (s?.sound_enabled && s.sound_type !== 'none') {
+      playNotificationSound(s.sound_type, s.sound_volume)
+    }
+    if (s?.browser_notifications) {
+      showBrowserNotification('👤 Lead asignado', data.title || 'Se te asignó un lead', () => {
+        window.location.href = data.lead_id ? `/dashboard/leads?lead_id=${data.lead_id}` : '/dashboard/leads'
+      })
+    }
+  }, [])
+
+  const handleChatAssigned = useCallback((data: { chat_id?: string }) => {
+    const s = settingsRef.current
+    if (s?.sound_enabled && s.sound_type !== 'none') {
+      playNotificationSound(s.sound_type, s.sound_volume)
+    }
+    if (s?.browser_notifications) {
+      showBrowserNotification('💬 Chat asignado', 'Se te asignó una conversación', () => {
+        window.location.href = data.chat_id ? `/dashboard/chats?open=${data.chat_id}` : '/dashboard/chats'
+      })
+    }
+  }, [])
+
   return (
     <NotificationContext.Provider value={{ settings, refreshSettings }}>
       {children}
@@ -1147,6 +1147,12 @@ export default function ChatPanel({ chatId, deviceId, device, initialChat, onClo
               m.message_id === editedMsgId ? { ...m, body: newBody, is_edited: true } : m
             ))
           }
+        } else if (eventType === 'message_starred' && payload) {
+          if (chat && payload.chat_id === chat.id) {
+            updateMessages(prev => prev.map(m =>
+              m.id === payload.id ? { ...m, starred: !!payload.starred } : m
+            ))
+          }
         } else if ((eventType === 'typing' || eventType === 'presence') && payload) {
           // Typing/presence indicator from contact
           if (chat && payload.jid === chat.jid) {
@@ -2023,8 +2029,35 @@ export default function ChatPanel({ chatId, deviceId, device, initialChat, onClo
      }
   }
 
-  const handleQuickReplySelect = (reply: any) => {
+  // Resolves {{variables}} in the reply against this chat's contact.
+  const renderQuickReply = async (reply: any) => {
+     const texts = [reply.body, ...(reply.attachments || []).map((att: any) => att.caption)]
+     if (!reply.id || !chat?.id || !texts.some((text: string | undefined) => text?.includes('{{'))) return reply
+     try {
+         const token = localStorage.getItem('token')
+         const res = await fetch(`/api/quick-replies/${reply.id}/render?chat_id=${chat.id}`, {
+             headers: { Authorization: `Bearer ${token}` }
+         })
+         const data = await res.json()
+         if (data.success && data.quick_reply) return data.quick_reply
+     } catch {}
+     return reply
+  }
+
+  // Counts a use so the most used replies are listed first.
+  const markQuickReplyUsed = (reply: any) => {
+     if (!reply.id) return
+     const token = localStorage.getItem('token')
+     fetch(`/api/quick-replies/${reply.id}/used`, {
+         method: 'POST',
+         headers: { Authorization: `Bearer ${token}` }
+     }).catch(() => {})
+  }
+
+  const handleQuickReplySelect = async (selected: any) => {
      const textBeforeCommand = messageText.replace(/\/[\w-]*$/, '')
+     const reply = await renderQuickReply(selected)
+     markQuickReplyUsed(selected)
 
      // Multi-attachment support
      if (reply.attachments && reply.attachments.length > 0) {
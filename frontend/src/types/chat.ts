@@ -54,6 +54,7 @@ export interface Message {
   is_read: boolean
   is_revoked?: boolean
   is_edited?: boolean
+  starred?: boolean
   is_view_once?: boolean
   status: string
   timestamp: string